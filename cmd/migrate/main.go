@@ -0,0 +1,143 @@
+// Package main applies and rolls back the versioned database migrations of internal/db, run
+// with goose
+//
+// Usage:
+//
+//	migrate up [N]          apply the pending migrations, or the next N
+//	migrate down [N]        roll back the last migration, or the last N
+//	migrate status          list the migrations and whether they are applied
+//	migrate force VERSION   record VERSION as applied without running it
+//	migrate forget VERSION  remove the record of VERSION without rolling it back
+//	migrate create NAME     write an empty file for a new migration
+//
+// It connects to DATABASE_URL, like the server.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: migrate <command> [argument]
+
+  up [N]          apply the pending migrations, or the next N
+  down [N]        roll back the last migration, or the last N
+  status          list the migrations and whether they are applied
+  force VERSION   record VERSION as applied without running it
+  forget VERSION  remove the record of VERSION without rolling it back
+  create NAME     write an empty file for a new migration`
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	// Creating files needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			log.Fatal("create needs the migration name")
+		}
+		paths, err := db.CreateMigration(db.MigrationsDir, args[0])
+		if err != nil {
+			log.Fatal("Error creating migration: ", err)
+		}
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return
+	}
+
+	database, err := db.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close(database)
+
+	switch command {
+	case "up":
+		migrations, err := db.MigrateUp(database, intArg(args, 0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		for _, migration := range migrations {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		migrations, err := db.MigrateDown(database, intArg(args, 1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		for _, migration := range migrations {
+			fmt.Printf("Rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := db.GetMigrationStatus(database)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Missing {
+				state += " (not in this binary)"
+			}
+			fmt.Printf("%6d  %-40s %s\n", status.Version, status.Name, state)
+		}
+	case "force":
+		if err := db.ForceMigration(database, versionArg(args)); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Migration recorded as applied")
+	case "forget":
+		if err := db.ForgetMigration(database, versionArg(args)); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Migration record removed")
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// intArg reads the optional positive count argument
+func intArg(args []string, fallback int) int {
+	if len(args) == 0 {
+		return fallback
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid count %q", args[0])
+	}
+	return n
+}
+
+// versionArg reads the required version argument
+func versionArg(args []string) int64 {
+	if len(args) != 1 {
+		log.Fatal("a migration version is required")
+	}
+	version, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || version <= 0 {
+		log.Fatalf("invalid version %q", args[0])
+	}
+	return version
+}
@@ -20,16 +20,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/MarceloPetrucio/go-scalar-api-reference"
 	"github.com/Osminalx/fluxio/docs"
 	"github.com/Osminalx/fluxio/internal/api"
 	"github.com/Osminalx/fluxio/internal/auth"
 	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/jobs"
 	"github.com/Osminalx/fluxio/internal/middleware"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/telemetry"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/joho/godotenv"
 )
@@ -56,44 +64,47 @@ import (
 // @description Ingresa "Bearer" seguido de un espacio y el token JWT
 
 // handleIncomeRoutes maneja el enrutamiento para los endpoints de income
-func handleIncomeRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleIncomeRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/incomes":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllIncomesHandler(w, r)
+			rt.handlers.GetAllIncomesHandler(w, r)
 		case http.MethodPost:
-			api.CreateIncomeHandler(w, r)
+			rt.handlers.CreateIncomeHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/incomes/active":
 		if r.Method == http.MethodGet {
-			api.GetActiveIncomesHandler(w, r)
+			rt.handlers.GetActiveIncomesHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/incomes/deleted":
-		if r.Method == http.MethodGet {
-			api.GetDeletedIncomesHandler(w, r)
-		} else {
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetDeletedIncomesHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.EmptyTrashHandler(w, r)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/incomes/") && strings.HasSuffix(path, "/restore"):
 		if r.Method == http.MethodPost {
-			api.RestoreIncomeHandler(w, r)
+			rt.handlers.RestoreIncomeHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/incomes/") && strings.HasSuffix(path, "/status"):
 		if r.Method == http.MethodPatch {
-			api.ChangeIncomeStatusHandler(w, r)
+			rt.handlers.ChangeIncomeStatusHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -102,11 +113,11 @@ func handleIncomeRoutes(w http.ResponseWriter, r *http.Request) {
 		// Endpoints con ID individual: /api/v1/incomes/{id}
 		switch r.Method {
 		case http.MethodGet:
-			api.GetIncomeByIDHandler(w, r)
+			rt.handlers.GetIncomeByIDHandler(w, r)
 		case http.MethodPatch:
-			api.UpdateIncomeHandler(w, r)
+			rt.handlers.UpdateIncomeHandler(w, r)
 		case http.MethodDelete:
-			api.DeleteIncomeHandler(w, r)
+			rt.handlers.DeleteIncomeHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -116,80 +127,146 @@ func handleIncomeRoutes(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// routes dispatches the requests of a resource to the handlers, all backed by the same services
+type routes struct {
+	services *services.Services
+	handlers *api.Handlers
+	expenses *api.ExpenseHandler
+	budgets  *api.BudgetHandler
+}
+
 // handleExpenseRoutes manages routing for expense endpoints
-func handleExpenseRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/expenses":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllExpensesHandler(w, r)
+			rt.expenses.GetAll(w, r)
 		case http.MethodPost:
-			api.CreateExpenseHandler(w, r)
+			rt.expenses.Create(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/expenses/active":
 		if r.Method == http.MethodGet {
-			api.GetActiveExpensesHandler(w, r)
+			rt.expenses.GetActive(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/expenses/deleted":
-		if r.Method == http.MethodGet {
-			api.GetDeletedExpensesHandler(w, r)
-		} else {
+		switch r.Method {
+		case http.MethodGet:
+			rt.expenses.GetDeleted(w, r)
+		case http.MethodDelete:
+			rt.handlers.EmptyTrashHandler(w, r)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/expenses/date-range":
 		if r.Method == http.MethodGet {
-			api.GetExpensesByDateRangeHandler(w, r)
+			rt.expenses.GetByDateRange(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/expenses/monthly":
 		if r.Method == http.MethodGet {
-			api.GetMonthlyExpensesHandler(w, r)
+			rt.expenses.GetMonthly(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/expenses/summary":
 		if r.Method == http.MethodGet {
-			api.GetExpensesSummaryHandler(w, r)
+			rt.handlers.GetExpensesSummaryHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/expenses/search":
+		if r.Method == http.MethodGet {
+			rt.handlers.SearchExpensesHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/expenses/bulk":
+		if r.Method == http.MethodPatch {
+			rt.handlers.BulkEditExpensesHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/expenses/scan":
+		if r.Method == http.MethodPost {
+			rt.handlers.ScanReceiptHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/expenses/category/"):
 		if r.Method == http.MethodGet {
-			api.GetExpensesByCategoryHandler(w, r)
+			rt.expenses.GetByCategory(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/expenses/bank-account/"):
 		if r.Method == http.MethodGet {
-			api.GetExpensesByBankAccountHandler(w, r)
+			rt.expenses.GetByBankAccount(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.HasSuffix(path, "/restore"):
 		if r.Method == http.MethodPost {
-			api.RestoreExpenseHandler(w, r)
+			rt.expenses.Restore(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.HasSuffix(path, "/status"):
 		if r.Method == http.MethodPatch {
-			api.ChangeExpenseStatusHandler(w, r)
+			rt.expenses.ChangeStatus(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.HasSuffix(path, "/refunds"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetExpenseRefundsHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.HasSuffix(path, "/attachments"):
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetExpenseAttachmentsHandler(w, r)
+		case http.MethodPost:
+			rt.handlers.UploadExpenseAttachmentHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.Contains(path, "/attachments/"):
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.DownloadExpenseAttachmentHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.DeleteExpenseAttachmentHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/expenses/") && strings.HasSuffix(path, "/provenance"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetExpenseProvenanceHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -197,11 +274,11 @@ func handleExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/expenses/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetExpenseByIDHandler(w, r)
+			rt.expenses.GetByID(w, r)
 		case http.MethodPatch:
-			api.UpdateExpenseHandler(w, r)
+			rt.expenses.Update(w, r)
 		case http.MethodDelete:
-			api.DeleteExpenseHandler(w, r)
+			rt.expenses.Delete(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -213,44 +290,61 @@ func handleExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 
 
 // handleBankAccountRoutes manages routing for bank account endpoints
-func handleBankAccountRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleBankAccountRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/bank-accounts":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllBankAccountsHandler(w, r)
+			rt.handlers.GetAllBankAccountsHandler(w, r)
 		case http.MethodPost:
-			api.CreateBankAccountHandler(w, r)
+			rt.handlers.CreateBankAccountHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/bank-accounts/active":
 		if r.Method == http.MethodGet {
-			api.GetActiveBankAccountsHandler(w, r)
+			rt.handlers.GetActiveBankAccountsHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/bank-accounts/deleted":
-		if r.Method == http.MethodGet {
-			api.GetDeletedBankAccountsHandler(w, r)
-		} else {
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetDeletedBankAccountsHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.EmptyTrashHandler(w, r)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/bank-accounts/") && strings.HasSuffix(path, "/restore"):
 		if r.Method == http.MethodPost {
-			api.RestoreBankAccountHandler(w, r)
+			rt.handlers.RestoreBankAccountHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/bank-accounts/") && strings.HasSuffix(path, "/available-balance"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetAvailableBalanceHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/bank-accounts/") && strings.HasSuffix(path, "/delete-impact"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetBankAccountDeleteImpactHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/bank-accounts/") && strings.HasSuffix(path, "/status"):
 		if r.Method == http.MethodPatch {
-			api.ChangeBankAccountStatusHandler(w, r)
+			rt.handlers.ChangeBankAccountStatusHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -258,11 +352,11 @@ func handleBankAccountRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/bank-accounts/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetBankAccountByIDHandler(w, r)
+			rt.handlers.GetBankAccountByIDHandler(w, r)
 		case http.MethodPatch:
-			api.UpdateBankAccountHandler(w, r)
+			rt.handlers.UpdateBankAccountHandler(w, r)
 		case http.MethodDelete:
-			api.DeleteBankAccountHandler(w, r)
+			rt.handlers.DeleteBankAccountHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -273,30 +367,72 @@ func handleBankAccountRoutes(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleFixedExpenseRoutes manages routing for fixed expense endpoints
-func handleFixedExpenseRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleFixedExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/fixed-expenses":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllFixedExpensesHandler(w, r)
+			rt.handlers.GetAllFixedExpensesHandler(w, r)
 		case http.MethodPost:
-			api.CreateFixedExpenseHandler(w, r)
+			rt.handlers.CreateFixedExpenseHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/fixed-expenses/calendar":
 		if r.Method == http.MethodGet {
-			api.GetFixedExpensesCalendarHandler(w, r)
+			rt.handlers.GetFixedExpensesCalendarHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/fixed-expenses/reconciliation":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetFixedExpensesReconciliationHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/fixed-expenses/process":
 		if r.Method == http.MethodPost {
-			api.ProcessFixedExpensesHandler(w, r)
+			rt.handlers.ProcessFixedExpensesHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/fixed-expenses/runs":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetFixedExpenseRunsHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/fixed-expenses/drift":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetFixedExpenseDriftsHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/fixed-expenses/") && strings.Contains(path, "/occurrences/") && strings.HasSuffix(path, "/confirm"):
+		if r.Method == http.MethodPost {
+			rt.handlers.ConfirmFixedExpenseOccurrenceHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/fixed-expenses/") && strings.Contains(path, "/occurrences/") && strings.HasSuffix(path, "/skip"):
+		if r.Method == http.MethodPost {
+			rt.handlers.SkipFixedExpenseOccurrenceHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/fixed-expenses/") && strings.HasSuffix(path, "/drift/accept"):
+		if r.Method == http.MethodPost {
+			rt.handlers.AcceptFixedExpenseDriftHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -304,11 +440,11 @@ func handleFixedExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/fixed-expenses/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetFixedExpenseByIDHandler(w, r)
+			rt.handlers.GetFixedExpenseByIDHandler(w, r)
 		case http.MethodPatch:
-			api.UpdateFixedExpenseHandler(w, r)
+			rt.handlers.UpdateFixedExpenseHandler(w, r)
 		case http.MethodDelete:
-			api.DeleteFixedExpenseHandler(w, r)
+			rt.handlers.DeleteFixedExpenseHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -320,44 +456,81 @@ func handleFixedExpenseRoutes(w http.ResponseWriter, r *http.Request) {
 
 
 // handleGoalRoutes manages routing for goal endpoints
-func handleGoalRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleGoalRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/goals":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllGoalsHandler(w, r)
+			rt.handlers.GetAllGoalsHandler(w, r)
 		case http.MethodPost:
-			api.CreateGoalHandler(w, r)
+			rt.handlers.CreateGoalHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/goals/active":
 		if r.Method == http.MethodGet {
-			api.GetActiveGoalsHandler(w, r)
+			rt.handlers.GetActiveGoalsHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/goals/deleted":
-		if r.Method == http.MethodGet {
-			api.GetDeletedGoalsHandler(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetDeletedGoalsHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.EmptyTrashHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/goals/priorities":
+		if r.Method == http.MethodPut {
+			rt.handlers.UpdateGoalPrioritiesHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
+	case path == "/api/v1/goals/waterfall":
+		rt.handlers.GoalWaterfallHandler(w, r)
+	
+	case path == "/api/v1/goals/waterfall/settings":
+		rt.handlers.GoalWaterfallSettingsHandler(w, r)
+	
 	case strings.HasPrefix(path, "/api/v1/goals/") && strings.HasSuffix(path, "/restore"):
 		if r.Method == http.MethodPost {
-			api.RestoreGoalHandler(w, r)
+			rt.handlers.RestoreGoalHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/goals/") && strings.HasSuffix(path, "/milestones"):
+		if r.Method == http.MethodGet || r.Method == http.MethodPost {
+			rt.handlers.GoalMilestonesHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/goals/") && strings.HasSuffix(path, "/projection"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetGoalProjectionHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/goals/") && strings.HasSuffix(path, "/contributions"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetGoalContributionsHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/goals/") && strings.HasSuffix(path, "/status"):
 		if r.Method == http.MethodPatch {
-			api.ChangeGoalStatusHandler(w, r)
+			rt.handlers.ChangeGoalStatusHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -365,11 +538,404 @@ func handleGoalRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/goals/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetGoalByIDHandler(w, r)
+			rt.handlers.GetGoalByIDHandler(w, r)
+		case http.MethodPatch:
+			rt.handlers.UpdateGoalHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.DeleteGoalHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleAccountGroupRoutes manages routing for account group endpoints
+func (rt *routes) handleAccountGroupRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/account-groups":
+		rt.handlers.AccountGroupsHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/account-groups/") && strings.Contains(path, "/accounts"):
+		rt.handlers.AccountGroupMembersHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/account-groups/"):
+		rt.handlers.AccountGroupHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleTagRoutes manages routing for tag endpoints
+func (rt *routes) handleTagRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/tags":
+		rt.handlers.TagsHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/tags/"):
+		rt.handlers.TagHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleSubProfileRoutes manages routing for sub-profile endpoints
+func (rt *routes) handleSubProfileRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/sub-profiles":
+		rt.handlers.SubProfilesHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/sub-profiles/") && strings.HasSuffix(path, "/allowance"):
+		rt.handlers.SubProfileAllowanceHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/sub-profiles/"):
+		rt.handlers.SubProfileHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleExpenseApprovalRoutes manages routing for expense approval endpoints
+func (rt *routes) handleExpenseApprovalRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/expense-approvals":
+		rt.handlers.GetExpenseApprovalsHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/expense-approvals/") &&
+		(strings.HasSuffix(path, "/approve") || strings.HasSuffix(path, "/reject")):
+		rt.handlers.DecideExpenseApprovalHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleWebhookRoutes manages routing for webhook delivery endpoints
+func (rt *routes) handleWebhookRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/webhooks/deliveries":
+		rt.handlers.GetFailedDeliveriesHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/webhooks/deliveries/"):
+		rt.handlers.GetDeliveryHandler(w, r)
+	
+	case strings.HasSuffix(path, "/redeliver"):
+		rt.handlers.RedeliverHandler(w, r)
+	
+	case path == "/api/v1/webhooks":
+		rt.handlers.WebhooksHandler(w, r)
+	
+	case strings.HasSuffix(path, "/deliveries"):
+		rt.handlers.GetWebhookDeliveriesHandler(w, r)
+	
+	case strings.HasSuffix(path, "/retry"):
+		rt.handlers.RetryWebhookDeliveryHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/webhooks/"):
+		rt.handlers.WebhookHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleNotificationRoutes manages routing for notification endpoints
+func (rt *routes) handleNotificationRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/notifications":
+		rt.handlers.GetNotificationsHandler(w, r)
+	
+	case path == "/api/v1/notifications/settings":
+		rt.handlers.NotificationSettingsHandler(w, r)
+	
+	case path == "/api/v1/notifications/push-config":
+		api.GetPushConfigHandler(w, r)
+	
+	case path == "/api/v1/notifications/push-subscriptions":
+		rt.handlers.PushSubscriptionsHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/notifications/push-subscriptions/"):
+		rt.handlers.DeletePushSubscriptionHandler(w, r)
+	
+	case strings.HasSuffix(path, "/delivered"):
+		rt.handlers.MarkNotificationDeliveredHandler(w, r)
+	
+	case strings.HasSuffix(path, "/acknowledge"):
+		rt.handlers.AcknowledgeNotificationHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleTripRoutes manages routing for trip endpoints
+func (rt *routes) handleTripRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/trips":
+		rt.handlers.TripsHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/trips/") && strings.HasSuffix(path, "/summary"):
+		rt.handlers.GetTripSummaryHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/trips/") && strings.Contains(path, "/expenses"):
+		rt.handlers.TripExpensesHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/trips/"):
+		rt.handlers.TripHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleImportRoutes manages routing for bank transaction imports
+func (rt *routes) handleImportRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/import":
+		rt.handlers.CreateImportHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/import/") && strings.HasSuffix(path, "/resolve"):
+		rt.handlers.ResolveImportMatchHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/import/"):
+		rt.handlers.GetImportHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleBankConnectionRoutes manages routing for open-banking bank connections
+func (rt *routes) handleBankConnectionRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/bank-connections":
+		rt.handlers.BankConnectionsHandler(w, r)
+	
+	case path == "/api/v1/bank-connections/link-token":
+		api.CreateBankLinkTokenHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/bank-connections/") && strings.Contains(path, "/accounts/"):
+		rt.handlers.LinkBankConnectionAccountHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/bank-connections/") && strings.HasSuffix(path, "/sync"):
+		rt.handlers.SyncBankConnectionHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/bank-connections/"):
+		rt.handlers.DisconnectBankConnectionHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleExportRoutes manages routing for user data exports
+func (rt *routes) handleExportRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/export":
+		rt.handlers.ExportDataHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/export/jobs/") && strings.HasSuffix(path, "/download"):
+		rt.handlers.DownloadDataExportHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/export/jobs/"):
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.DataExportHandler(w, r)
+		case http.MethodDelete:
+			rt.handlers.CancelDataExportHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleJobRoutes manages routing for the background jobs of a user. Exports are the only ones;
+// reports run within their request and stop when the client goes away
+func (rt *routes) handleJobRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rt.handlers.DataExportHandler(w, r)
+	case http.MethodDelete:
+		rt.handlers.CancelDataExportHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOAuthRoutes manages routing for sign-in with external identity providers
+func (rt *routes) handleOAuthRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case strings.HasSuffix(path, "/start"):
+		rt.handlers.OAuthStartHandler(w, r)
+	
+	case strings.HasSuffix(path, "/callback"):
+		rt.handlers.OAuthCallbackHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleAPIKeyRoutes manages routing for API key endpoints
+func (rt *routes) handleAPIKeyRoutes(w http.ResponseWriter, r *http.Request) {
+	// /api/v1/auth/api-keys serves the same endpoints under the auth namespace
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/auth/api-keys"); ok {
+		r = r.Clone(r.Context())
+		r.URL.Path = "/api/v1/api-keys" + rest
+	}
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/api-keys":
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetAPIKeysHandler(w, r)
+		case http.MethodPost:
+			rt.handlers.CreateAPIKeyHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/api-keys/") && strings.HasSuffix(path, "/usage"):
+		if r.Method == http.MethodGet {
+			rt.handlers.GetAPIKeyUsageHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/api-keys/"):
+		if r.Method == http.MethodDelete {
+			rt.handlers.RevokeAPIKeyHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleBudgetRoutes manages routing for budget endpoints
+func (rt *routes) handleBudgetRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/budgets":
+		switch r.Method {
+		case http.MethodGet:
+			rt.budgets.GetAll(w, r)
+		case http.MethodPost:
+			rt.budgets.Create(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/budgets/plan":
+		rt.handlers.PlanBudgetsHandler(w, r)
+	
+	case path == "/api/v1/budgets/compliance":
+		rt.handlers.GetBudgetComplianceHandler(w, r)
+	
+	case path == "/api/v1/budgets/compliance/backfill":
+		rt.handlers.BackfillBudgetComplianceHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.HasSuffix(path, "/categories"):
+		rt.handlers.GetCategoryBudgetReportHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.Contains(path, "/categories/"):
+		rt.handlers.CategoryBudgetHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.HasSuffix(path, "/close"):
+		rt.handlers.CloseMonthHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.HasSuffix(path, "/reopen"):
+		rt.handlers.ReopenMonthHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.HasSuffix(path, "/close-report"):
+		rt.handlers.GetMonthCloseReportHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/") && strings.HasSuffix(path, "/restore"):
+		if r.Method == http.MethodPost {
+			rt.budgets.Restore(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/budgets/"):
+		switch r.Method {
+		case http.MethodGet:
+			rt.budgets.GetByID(w, r)
 		case http.MethodPatch:
-			api.UpdateGoalHandler(w, r)
+			rt.budgets.Update(w, r)
+		case http.MethodDelete:
+			rt.budgets.Delete(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleTransferRoutes manages routing for transfer endpoints
+func (rt *routes) handleTransferRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/transfers":
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetAllTransfersHandler(w, r)
+		case http.MethodPost:
+			rt.handlers.CreateTransferHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/transfers/") && strings.HasSuffix(path, "/goal"):
+		if r.Method == http.MethodPut {
+			rt.handlers.SetTransferGoalHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/transfers/"):
+		switch r.Method {
+		case http.MethodGet:
+			rt.handlers.GetTransferByIDHandler(w, r)
 		case http.MethodDelete:
-			api.DeleteGoalHandler(w, r)
+			rt.handlers.DeleteTransferHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -380,58 +946,79 @@ func handleGoalRoutes(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleUserCategoryRoutes manages routing for user category endpoints
-func handleUserCategoryRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleUserCategoryRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/user-categories":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetUserCategories(w, r)
+			rt.handlers.GetUserCategories(w, r)
 		case http.MethodPost:
-			api.CreateUserCategory(w, r)
+			rt.handlers.CreateUserCategory(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/user-categories/grouped":
 		if r.Method == http.MethodGet {
-			api.GetUserCategoriesGroupedByType(w, r)
+			rt.handlers.GetUserCategoriesGroupedByType(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/user-categories/defaults":
 		if r.Method == http.MethodPost {
-			api.CreateDefaultUserCategories(w, r)
+			rt.handlers.CreateDefaultUserCategories(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/user-categories/appearance":
+		if r.Method == http.MethodPut {
+			rt.handlers.BulkUpdateUserCategoryAppearance(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/user-categories/icons":
+		if r.Method == http.MethodGet {
+			api.GetCategoryIcons(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/user-categories/stats":
 		if r.Method == http.MethodGet {
-			api.GetUserCategoryStats(w, r)
+			rt.handlers.GetUserCategoryStats(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/user-categories/expense-type/"):
 		if r.Method == http.MethodGet {
-			api.GetUserCategoriesByExpenseType(w, r)
+			rt.handlers.GetUserCategoriesByExpenseType(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/user-categories/expense-type-name/"):
 		if r.Method == http.MethodGet {
-			api.GetUserCategoriesByExpenseTypeName(w, r)
+			rt.handlers.GetUserCategoriesByExpenseTypeName(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case strings.HasPrefix(path, "/api/v1/user-categories/") && strings.HasSuffix(path, "/restore"):
 		if r.Method == http.MethodPost {
-			api.RestoreUserCategory(w, r)
+			rt.handlers.RestoreUserCategory(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/user-categories/") && strings.HasSuffix(path, "/cap"):
+		if r.Method == http.MethodPut {
+			rt.handlers.SetUserCategoryCap(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -439,11 +1026,11 @@ func handleUserCategoryRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/user-categories/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetUserCategoryByID(w, r)
+			rt.handlers.GetUserCategoryByID(w, r)
 		case http.MethodPut:
-			api.UpdateUserCategory(w, r)
+			rt.handlers.UpdateUserCategory(w, r)
 		case http.MethodDelete:
-			api.SoftDeleteUserCategory(w, r)
+			rt.handlers.SoftDeleteUserCategory(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -457,7 +1044,7 @@ func handleUserCategoryRoutes(w http.ResponseWriter, r *http.Request) {
 // Use /api/v1/user-categories/grouped to get categories organized by expense type
 
 // handleSetupRoutes manages routing for system setup endpoints
-func handleSetupRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleSetupRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
@@ -470,7 +1057,7 @@ func handleSetupRoutes(w http.ResponseWriter, r *http.Request) {
 	
 	case path == "/api/v1/setup/user":
 		if r.Method == http.MethodPost {
-			api.SetupNewUser(w, r)
+			rt.handlers.SetupNewUser(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -488,37 +1075,43 @@ func handleSetupRoutes(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleReminderRoutes manages routing for reminder endpoints
-func handleReminderRoutes(w http.ResponseWriter, r *http.Request) {
+func (rt *routes) handleReminderRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	
 	switch {
 	case path == "/api/v1/reminders":
 		switch r.Method {
 		case http.MethodGet:
-			api.GetAllRemindersHandler(w, r)
+			rt.handlers.GetAllRemindersHandler(w, r)
 		case http.MethodPost:
-			api.CreateReminderHandler(w, r)
+			rt.handlers.CreateReminderHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
 	case path == "/api/v1/reminders/overdue":
 		if r.Method == http.MethodGet {
-			api.GetOverdueRemindersHandler(w, r)
+			rt.handlers.GetOverdueRemindersHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
+	case path == "/api/v1/reminders/deleted":
+		rt.handlers.EmptyTrashHandler(w, r)
+	
 	case path == "/api/v1/reminders/stats":
 		if r.Method == http.MethodGet {
-			api.GetReminderStatsHandler(w, r)
+			rt.handlers.GetReminderStatsHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	
+	case strings.HasPrefix(path, "/api/v1/reminders/") && strings.HasSuffix(path, "/deliveries"):
+		rt.handlers.GetReminderDeliveriesHandler(w, r)
+	
 	case strings.HasPrefix(path, "/api/v1/reminders/") && strings.HasSuffix(path, "/complete"):
 		if r.Method == http.MethodPost {
-			api.CompleteReminderHandler(w, r)
+			rt.handlers.CompleteReminderHandler(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -526,11 +1119,11 @@ func handleReminderRoutes(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(path, "/api/v1/reminders/"):
 		switch r.Method {
 		case http.MethodGet:
-			api.GetReminderByIDHandler(w, r)
+			rt.handlers.GetReminderByIDHandler(w, r)
 		case http.MethodPatch:
-			api.UpdateReminderHandler(w, r)
+			rt.handlers.UpdateReminderHandler(w, r)
 		case http.MethodDelete:
-			api.DeleteReminderHandler(w, r)
+			rt.handlers.DeleteReminderHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -540,6 +1133,80 @@ func handleReminderRoutes(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleAdminRoutes manages routing for administrative endpoints
+func (rt *routes) handleAdminRoutes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	
+	switch {
+	case path == "/api/v1/admin/analytics/endpoints":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetEndpointUsageReportHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/analytics/features":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetFeatureAdoptionReportHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/analytics/deprecations":
+		if r.Method == http.MethodGet {
+			rt.handlers.GetDeprecatedFieldReportHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/audit/verify":
+		if r.Method == http.MethodGet {
+			rt.handlers.VerifyAuditChainHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/maintenance":
+		if r.Method == http.MethodGet || r.Method == http.MethodPut {
+			rt.handlers.MaintenanceModeHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/stats":
+		if r.Method == http.MethodGet {
+			rt.handlers.SystemStatsHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case path == "/api/v1/admin/users":
+		if r.Method == http.MethodGet {
+			rt.handlers.AdminUsersHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/admin/users/") && (strings.HasSuffix(path, "/lock") || strings.HasSuffix(path, "/unlock")):
+		rt.handlers.LockUserHandler(w, r)
+	
+	case strings.HasPrefix(path, "/api/v1/admin/users/") && strings.HasSuffix(path, "/role"):
+		rt.handlers.SetUserRoleHandler(w, r)
+	
+	case path == "/api/v1/admin/jobs":
+		if r.Method == http.MethodGet {
+			api.AdminJobsHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	
+	case strings.HasPrefix(path, "/api/v1/admin/jobs/") && strings.HasSuffix(path, "/run"):
+		api.RunJobHandler(w, r)
+	
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
 
 func main() {
 	// Load environment variables
@@ -555,11 +1222,40 @@ func main() {
 	docs.SwaggerInfo.BasePath = "/"
 	docs.SwaggerInfo.Schemes = []string{"http"}
 
+	// Start exporting traces before anything is traced
+	telemetry.Init()
+
+	// A zero or garbled AUTH_RATE_LIMIT_* would otherwise quietly fall back to the default
+	if err := services.ValidateAuthRateLimits(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Connect to database
 	logger.Info("🗄️  Conectando a la base de datos...")
-	db.Connect()
+	database := db.Connect()
 	logger.Info("✅ Conectado a Postgres con GORM")
 
+	// Writes to the data behind summaries invalidate their cache, whichever service makes them
+	if err := database.Use(services.SummaryCachePlugin{}); err != nil {
+		log.Fatal("Error registering summary cache plugin:", err)
+	}
+	// Closed months stay as they were snapshotted, whichever service writes to them
+	if err := database.Use(services.MonthLockPlugin{}); err != nil {
+		log.Fatal("Error registering month lock plugin:", err)
+	}
+
+	// Services and the handlers using them are wired to the database here
+	svc := services.New(database)
+	rt := &routes{
+		services: svc,
+		handlers: api.NewHandlers(svc),
+		expenses: api.NewExpenseHandler(svc),
+		budgets:  api.NewBudgetHandler(svc),
+	}
+
+	// MAINTENANCE_MODE=true turns read-only mode on for every instance, not just this one
+	svc.EnableMaintenanceModeFromEnv()
+
 	// Create main router
 	mux := http.NewServeMux()
 	
@@ -567,88 +1263,319 @@ func main() {
 
 	// API v1 routes - PUBLIC (no authentication required)
 	mux.HandleFunc("/api/v1/hello", api.HelloHandler)
-	mux.HandleFunc("/api/v1/auth/login", api.LoginHandler)
-	mux.HandleFunc("/api/v1/auth/register", api.RegisterHandler)
-	mux.HandleFunc("/api/v1/auth/refresh", api.RefreshTokenHandler)
-	mux.HandleFunc("/api/v1/auth/logout", api.LogoutHandler)
-	mux.HandleFunc("/api/v1/auth/logout-all", api.LogoutAllHandler)
+	mux.Handle("/api/v1/auth/login", middleware.AuthRateLimitMiddleware(http.HandlerFunc(rt.handlers.LoginHandler)))
+	mux.HandleFunc("/api/v1/auth/login/verify", rt.handlers.VerifyLoginHandler)
+	mux.Handle("/api/v1/auth/register", middleware.AuthRateLimitMiddleware(http.HandlerFunc(rt.handlers.RegisterHandler)))
+	mux.Handle("/api/v1/auth/refresh", middleware.AuthRateLimitMiddleware(http.HandlerFunc(rt.handlers.RefreshTokenHandler)))
+	mux.Handle("/api/v1/auth/oauth/", middleware.AuthRateLimitMiddleware(http.HandlerFunc(rt.handleOAuthRoutes)))
+	mux.HandleFunc("/api/v1/auth/logout", rt.handlers.LogoutHandler)
+	mux.HandleFunc("/api/v1/auth/logout-all", rt.handlers.LogoutAllHandler)
 	
 	// Setup endpoints - PUBLIC (system initialization)
-	mux.HandleFunc("/api/v1/setup/", handleSetupRoutes)
+	mux.HandleFunc("/api/v1/setup/", rt.handleSetupRoutes)
+	
+	// Open-banking provider notifications - PUBLIC (only flag connections for the sync job)
+	mux.HandleFunc("/api/v1/open-banking/webhook", rt.handlers.OpenBankingWebhookHandler)
 
 
 	// API v1 routes - PROTECTED (require authentication)
 	protectedMux := http.NewServeMux()
 	
 	// Auth endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/auth/me", api.MeHandler)
+	protectedMux.HandleFunc("/api/v1/auth/me", rt.handlers.AccountHandler)
+	protectedMux.HandleFunc("/api/v1/auth/me/restore", rt.handlers.RestoreAccountHandler)
+	protectedMux.HandleFunc("/api/v1/auth/sessions", rt.handlers.GetSessionsHandler)
+	protectedMux.HandleFunc("/api/v1/auth/sessions/", rt.handlers.RevokeSessionHandler)
 	
 	// Income endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/incomes", handleIncomeRoutes)
-	protectedMux.HandleFunc("/api/v1/incomes/", handleIncomeRoutes)
+	protectedMux.HandleFunc("/api/v1/incomes", rt.handleIncomeRoutes)
+	protectedMux.HandleFunc("/api/v1/incomes/", rt.handleIncomeRoutes)
 	
 	// Expense endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/expenses", handleExpenseRoutes)
-	protectedMux.HandleFunc("/api/v1/expenses/", handleExpenseRoutes)
+	protectedMux.HandleFunc("/api/v1/expenses", rt.handleExpenseRoutes)
+	protectedMux.HandleFunc("/api/v1/expenses/", rt.handleExpenseRoutes)
 	
 	// Budget endpoints - PROTECTED
-	// protectedMux.HandleFunc("/api/v1/budgets", handleBudgetRoutes)
-	// protectedMux.HandleFunc("/api/v1/budgets/", handleBudgetRoutes)
+	protectedMux.HandleFunc("/api/v1/budgets", rt.handleBudgetRoutes)
+	protectedMux.HandleFunc("/api/v1/budgets/", rt.handleBudgetRoutes)
 	
 	// Bank Account endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/bank-accounts", handleBankAccountRoutes)
-	protectedMux.HandleFunc("/api/v1/bank-accounts/", handleBankAccountRoutes)
+	protectedMux.HandleFunc("/api/v1/bank-accounts", rt.handleBankAccountRoutes)
+	protectedMux.HandleFunc("/api/v1/bank-accounts/", rt.handleBankAccountRoutes)
 	
 	// Fixed Expense endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/fixed-expenses", handleFixedExpenseRoutes)
-	protectedMux.HandleFunc("/api/v1/fixed-expenses/", handleFixedExpenseRoutes)
+	protectedMux.HandleFunc("/api/v1/fixed-expenses", rt.handleFixedExpenseRoutes)
+	protectedMux.HandleFunc("/api/v1/fixed-expenses/", rt.handleFixedExpenseRoutes)
+	
+	// Transfer endpoints - PROTECTED
+	protectedMux.HandleFunc("/api/v1/transfers", rt.handleTransferRoutes)
+	protectedMux.HandleFunc("/api/v1/transfers/", rt.handleTransferRoutes)
 	
 	// Budget History endpoints - PROTECTED
 	// protectedMux.HandleFunc("/api/v1/budget-history", handleBudgetHistoryRoutes)
 	// protectedMux.HandleFunc("/api/v1/budget-history/", handleBudgetHistoryRoutes)
 	
 	// Goal endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/goals", handleGoalRoutes)
-	protectedMux.HandleFunc("/api/v1/goals/", handleGoalRoutes)
+	protectedMux.HandleFunc("/api/v1/goals", rt.handleGoalRoutes)
+	protectedMux.HandleFunc("/api/v1/goals/", rt.handleGoalRoutes)
 	
 	// User Category endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/user-categories", handleUserCategoryRoutes)
-	protectedMux.HandleFunc("/api/v1/user-categories/", handleUserCategoryRoutes)
+	protectedMux.HandleFunc("/api/v1/user-categories", rt.handleUserCategoryRoutes)
+	protectedMux.HandleFunc("/api/v1/user-categories/", rt.handleUserCategoryRoutes)
 	
 	// Reminder endpoints - PROTECTED
-	protectedMux.HandleFunc("/api/v1/reminders", handleReminderRoutes)
-	protectedMux.HandleFunc("/api/v1/reminders/", handleReminderRoutes)
+	protectedMux.HandleFunc("/api/v1/reminders", rt.handleReminderRoutes)
+	protectedMux.HandleFunc("/api/v1/reminders/", rt.handleReminderRoutes)
 	
+	// Usage analytics preference - PROTECTED
+	protectedMux.HandleFunc("/api/v1/analytics/preference", rt.handlers.AnalyticsPreferenceHandler)
+	
+	// Rolling spending velocity - PROTECTED
+	protectedMux.HandleFunc("/api/v1/analytics/velocity", rt.handlers.GetSpendingVelocityHandler)
+	
+	// Chart-ready time series - PROTECTED
+	protectedMux.HandleFunc("/api/v1/analytics/series", rt.handlers.GetAnalyticsSeriesHandler)
+	
+	// Multi-year comparison report - PROTECTED
+	protectedMux.HandleFunc("/api/v1/reports/yearly-comparison", rt.handlers.GetYearlyComparisonHandler)
+	
+	// Monthly cash-flow statement - PROTECTED
+	protectedMux.HandleFunc("/api/v1/reports/cash-flow", rt.handlers.GetCashFlowHandler)
+	
+	// Monthly spending trends - PROTECTED
+	protectedMux.HandleFunc("/api/v1/reports/trends", rt.handlers.GetSpendingTrendsHandler)
+	
+	// Monthly financial health score - PROTECTED
+	protectedMux.HandleFunc("/api/v1/reports/score", rt.handlers.GetFinancialScoreHandler)
+	
+	// Assistant context snapshot - PROTECTED
+	protectedMux.HandleFunc("/api/v1/assistant/context", rt.handlers.GetAssistantContextHandler)
+	
+	// Soft-delete retention - PROTECTED
+	protectedMux.HandleFunc("/api/v1/retention/policies", rt.handlers.RetentionPoliciesHandler)
+	protectedMux.HandleFunc("/api/v1/retention/upcoming-purges", rt.handlers.GetUpcomingPurgesHandler)
+	
+	// Notifications and their settings - PROTECTED
+	protectedMux.HandleFunc("/api/v1/notifications", rt.handleNotificationRoutes)
+	protectedMux.HandleFunc("/api/v1/notifications/", rt.handleNotificationRoutes)
+	
+	// Server-driven UI configuration - PROTECTED
+	protectedMux.HandleFunc("/api/v1/ui/dashboard-config", rt.handlers.DashboardConfigHandler)
+	
+	// Dashboard read model and its change stream - PROTECTED
+	protectedMux.HandleFunc("/api/v1/dashboard", rt.handlers.GetDashboardHandler)
+	protectedMux.HandleFunc("/api/v1/dashboard/stream", rt.handlers.StreamDashboardHandler)
+	
+	// Live updates of the user's changes - PROTECTED
+	protectedMux.HandleFunc("/api/v1/stream", rt.handlers.StreamLiveEventsHandler)
+	protectedMux.HandleFunc("/api/v1/feed", rt.handlers.GetFeedHandler)
+	
+	// Offline-first sync of mobile clients - PROTECTED
+	protectedMux.HandleFunc("/api/v1/sync", rt.handlers.SyncHandler)
+	
+	// Currency metadata and the user's currency - PROTECTED
+	protectedMux.HandleFunc("/api/v1/currencies", rt.handlers.GetCurrenciesHandler)
+	protectedMux.HandleFunc("/api/v1/users/me/currency", rt.handlers.UserCurrencyHandler)
+	
+	// Enumerations with localized labels - PROTECTED
+	protectedMux.HandleFunc("/api/v1/meta/enums", api.GetEnumsHandler)
+	
+	// Account anonymization - PROTECTED
+	protectedMux.HandleFunc("/api/v1/users/me/anonymize", rt.handlers.AnonymizeUserHandler)
+	
+	// Security events - PROTECTED
+	protectedMux.HandleFunc("/api/v1/security/events", rt.handlers.GetSecurityEventsHandler)
+	
+	// Account groups - PROTECTED
+	protectedMux.HandleFunc("/api/v1/account-groups", rt.handleAccountGroupRoutes)
+	protectedMux.HandleFunc("/api/v1/account-groups/", rt.handleAccountGroupRoutes)
+	
+	// Tags - PROTECTED
+	protectedMux.HandleFunc("/api/v1/tags", rt.handleTagRoutes)
+	protectedMux.HandleFunc("/api/v1/tags/", rt.handleTagRoutes)
+	
+	// Data quality report - PROTECTED
+	protectedMux.HandleFunc("/api/v1/data-quality", rt.handlers.DataQualityHandler)
+	
+	// Trips - PROTECTED
+	protectedMux.HandleFunc("/api/v1/trips", rt.handleTripRoutes)
+	protectedMux.HandleFunc("/api/v1/trips/", rt.handleTripRoutes)
+	
+	// Bank transaction imports - PROTECTED
+	protectedMux.HandleFunc("/api/v1/import", rt.handleImportRoutes)
+	protectedMux.HandleFunc("/api/v1/import/", rt.handleImportRoutes)
+	protectedMux.HandleFunc("/api/v1/bank-connections", rt.handleBankConnectionRoutes)
+	protectedMux.HandleFunc("/api/v1/bank-connections/", rt.handleBankConnectionRoutes)
+	protectedMux.HandleFunc("/api/v1/export", rt.handleExportRoutes)
+	protectedMux.HandleFunc("/api/v1/export/", rt.handleExportRoutes)
+	protectedMux.HandleFunc("/api/v1/jobs/", rt.handleJobRoutes)
+	
+	// API keys - PROTECTED
+	protectedMux.HandleFunc("/api/v1/api-keys", rt.handleAPIKeyRoutes)
+	protectedMux.HandleFunc("/api/v1/api-keys/", rt.handleAPIKeyRoutes)
+	protectedMux.HandleFunc("/api/v1/auth/api-keys", rt.handleAPIKeyRoutes)
+	protectedMux.HandleFunc("/api/v1/auth/api-keys/", rt.handleAPIKeyRoutes)
+	
+	// Allowance sub-profiles and their expense approvals - PROTECTED
+	protectedMux.HandleFunc("/api/v1/sub-profiles", rt.handleSubProfileRoutes)
+	protectedMux.HandleFunc("/api/v1/sub-profiles/", rt.handleSubProfileRoutes)
+	protectedMux.HandleFunc("/api/v1/expense-approvals", rt.handleExpenseApprovalRoutes)
+	protectedMux.HandleFunc("/api/v1/expense-approvals/", rt.handleExpenseApprovalRoutes)
+	
+	// Failed event deliveries (dead letters) and replay - PROTECTED
+	protectedMux.HandleFunc("/api/v1/webhooks", rt.handleWebhookRoutes)
+	protectedMux.HandleFunc("/api/v1/webhooks/", rt.handleWebhookRoutes)
+	
+	// Sandbox tenants with a virtual clock - PROTECTED
+	protectedMux.HandleFunc("/api/v1/sandbox", rt.handlers.SandboxesHandler)
+	protectedMux.HandleFunc("/api/v1/sandbox/clock", rt.handlers.GetSandboxClockHandler)
+	protectedMux.HandleFunc("/api/v1/sandbox/advance-time", rt.handlers.AdvanceSandboxTimeHandler)
+	
+	// Entity lookup by UUID for deep links - PROTECTED
+	protectedMux.HandleFunc("/api/v1/resolve/", rt.handlers.ResolveEntityHandler)
+	
+	// Spending insights and next-month forecast - PROTECTED
+	protectedMux.HandleFunc("/api/v1/insights", rt.handlers.GetSpendingInsightsHandler)
+	protectedMux.HandleFunc("/api/v1/insights/forecast", rt.handlers.GetSpendingForecastHandler)
+	
+	// Anonymous spending benchmarks (opt-in) - PROTECTED
+	protectedMux.HandleFunc("/api/v1/insights/benchmarks", rt.handlers.GetSpendingBenchmarksHandler)
+	protectedMux.HandleFunc("/api/v1/insights/benchmarks/opt-in", rt.handlers.BenchmarkOptInHandler)
+	
+	// Budget review cadence and status - PROTECTED
+	protectedMux.HandleFunc("/api/v1/insights/budget-review", rt.handlers.BudgetReviewHandler)
+	
+	// Confirmation step for large expenses and transfers - PROTECTED
+	protectedMux.HandleFunc("/api/v1/preferences/confirmation-thresholds", rt.handlers.ConfirmationThresholdsHandler)
+	protectedMux.HandleFunc("/api/v1/preferences/timezone", rt.handlers.TimezoneSettingsHandler)
+	
+	// All of the user's settings in one place - PROTECTED
+	protectedMux.HandleFunc("/api/v1/settings", rt.handlers.SettingsHandler)
+	
+	// Bank holiday calendar of scheduled items - PROTECTED
+	protectedMux.HandleFunc("/api/v1/holidays/settings", rt.handlers.HolidaySettingsHandler)
+	protectedMux.HandleFunc("/api/v1/holidays/upcoming", rt.handlers.GetUpcomingHolidaysHandler)
+	
+	// Admin endpoints - PROTECTED (require admin)
+	protectedMux.Handle("/api/v1/admin/", auth.AdminMiddleware(svc, http.HandlerFunc(rt.handleAdminRoutes)))
+	
+	// Protected routes record aggregate usage analytics (and which clients still get deprecated
+	// fields) after authentication; sub-profiles only reach their own expenses and goals, and
+	// each user runs a limited number of reports at once. GETs get ETags and writes honor If-Match
+	protectedHandler := auth.AuthMiddleware(svc, auth.SubProfileMiddleware(svc, middleware.UsageAnalyticsMiddleware(
+		middleware.DeprecationTelemetryMiddleware(middleware.ConcurrencyLimitMiddleware(
+			middleware.ConditionalRequestMiddleware(protectedMux))))))
+	svc.StartUsageAnalyticsFlusher(time.Minute)
+	services.RegisterEventHandler("dashboard", svc.ProjectDashboardEvent)
+	services.RegisterEventHandler("webhooks", svc.QueueWebhookDeliveries)
+	services.RegisterEventHandler("notifications", svc.DispatchNotificationEvent)
+	services.RegisterEventHandler("live", svc.NotifyLiveEvent)
+	svc.StartLiveEventListener()
+	svc.StartUserCacheListener()
+	svc.StartOutboxDispatcher(5 * time.Second)
+	svc.StartWebhookSender(10 * time.Second)
+	
+	// Maintenance jobs; admins can also trigger them through /api/v1/admin/jobs
+	jobs.Register("deleted-records-purge", time.Hour, svc.PurgeExpiredDeletedRecords)
+	jobs.Register("dead-letter-purge", time.Hour, svc.PurgeExpiredDeadLetters)
+	jobs.Register("used-confirm-token-purge", time.Hour, svc.PurgeUsedConfirmTokens)
+	jobs.RegisterAtStartup("budget-compliance-backfill", 6*time.Hour, svc.BackfillAllBudgetCompliance)
+	jobs.Register("data-quality-reports", 6*time.Hour, svc.GenerateDueDataQualityReports)
+	jobs.Register("fixed-expense-drift-checks", 24*time.Hour, svc.CheckFixedExpenseDrifts)
+	jobs.Register("budget-review-reminders", time.Hour, svc.CreateBudgetReviewReminders)
+	jobs.Register("month-close", time.Hour, svc.CloseDueMonths)
+	jobs.Register("reminder-notifications", 15*time.Minute, svc.SendReminderNotifications)
+	jobs.Register("goal-interest-accrual", 6*time.Hour, svc.PostGoalInterest)
+	jobs.Register("bank-connection-sync", 5*time.Minute, svc.SyncDueBankConnections)
+	jobs.Register("data-exports", time.Minute, svc.RunDataExports)
+	jobs.Register("data-export-purge", time.Hour, svc.PurgeExpiredDataExports)
+	jobs.Register("account-erasure", time.Hour, svc.EraseDueAccounts)
+	jobs.Start(svc)
 	
 	// Apply auth middleware to protected API v1 routes
-	mux.Handle("/api/v1/protected/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/auth/me", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/incomes", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/incomes/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/expenses", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/expenses/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/bank-accounts", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/bank-accounts/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/fixed-expenses", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/fixed-expenses/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/goals", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/goals/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/user-categories", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/user-categories/", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/reminders", auth.AuthMiddleware(protectedMux))
-	mux.Handle("/api/v1/reminders/", auth.AuthMiddleware(protectedMux))
-
-	// Serve swagger.json file
+	mux.Handle("/api/v1/protected/", protectedHandler)
+	mux.Handle("/api/v1/auth/me", protectedHandler)
+	mux.Handle("/api/v1/auth/me/restore", protectedHandler)
+	mux.Handle("/api/v1/auth/sessions", protectedHandler)
+	mux.Handle("/api/v1/auth/sessions/", protectedHandler)
+	mux.Handle("/api/v1/incomes", protectedHandler)
+	mux.Handle("/api/v1/incomes/", protectedHandler)
+	mux.Handle("/api/v1/expenses", protectedHandler)
+	mux.Handle("/api/v1/expenses/", protectedHandler)
+	mux.Handle("/api/v1/bank-accounts", protectedHandler)
+	mux.Handle("/api/v1/bank-accounts/", protectedHandler)
+	mux.Handle("/api/v1/fixed-expenses", protectedHandler)
+	mux.Handle("/api/v1/fixed-expenses/", protectedHandler)
+	mux.Handle("/api/v1/goals", protectedHandler)
+	mux.Handle("/api/v1/goals/", protectedHandler)
+	mux.Handle("/api/v1/transfers", protectedHandler)
+	mux.Handle("/api/v1/transfers/", protectedHandler)
+	mux.Handle("/api/v1/budgets", protectedHandler)
+	mux.Handle("/api/v1/budgets/", protectedHandler)
+	mux.Handle("/api/v1/user-categories", protectedHandler)
+	mux.Handle("/api/v1/user-categories/", protectedHandler)
+	mux.Handle("/api/v1/reminders", protectedHandler)
+	mux.Handle("/api/v1/reminders/", protectedHandler)
+	mux.Handle("/api/v1/analytics/", protectedHandler)
+	mux.Handle("/api/v1/dashboard", protectedHandler)
+	mux.Handle("/api/v1/dashboard/", protectedHandler)
+	mux.Handle("/api/v1/stream", protectedHandler)
+	mux.Handle("/api/v1/feed", protectedHandler)
+	mux.Handle("/api/v1/sync", protectedHandler)
+	mux.Handle("/api/v1/reports/", protectedHandler)
+	mux.Handle("/api/v1/assistant/", protectedHandler)
+	mux.Handle("/api/v1/retention/", protectedHandler)
+	mux.Handle("/api/v1/notifications", protectedHandler)
+	mux.Handle("/api/v1/notifications/", protectedHandler)
+	mux.Handle("/api/v1/ui/", protectedHandler)
+	mux.Handle("/api/v1/insights", protectedHandler)
+	mux.Handle("/api/v1/insights/", protectedHandler)
+	mux.Handle("/api/v1/security/", protectedHandler)
+	mux.Handle("/api/v1/users/", protectedHandler)
+	mux.Handle("/api/v1/currencies", protectedHandler)
+	mux.Handle("/api/v1/meta/", protectedHandler)
+	mux.Handle("/api/v1/holidays/", protectedHandler)
+	mux.Handle("/api/v1/preferences/", protectedHandler)
+	mux.Handle("/api/v1/settings", protectedHandler)
+	mux.Handle("/api/v1/account-groups", protectedHandler)
+	mux.Handle("/api/v1/data-quality", protectedHandler)
+	mux.Handle("/api/v1/trips", protectedHandler)
+	mux.Handle("/api/v1/trips/", protectedHandler)
+	mux.Handle("/api/v1/account-groups/", protectedHandler)
+	mux.Handle("/api/v1/tags", protectedHandler)
+	mux.Handle("/api/v1/tags/", protectedHandler)
+	mux.Handle("/api/v1/import", protectedHandler)
+	mux.Handle("/api/v1/import/", protectedHandler)
+	mux.Handle("/api/v1/api-keys", protectedHandler)
+	mux.Handle("/api/v1/api-keys/", protectedHandler)
+	mux.Handle("/api/v1/auth/api-keys", protectedHandler)
+	mux.Handle("/api/v1/auth/api-keys/", protectedHandler)
+	mux.Handle("/api/v1/sub-profiles", protectedHandler)
+	mux.Handle("/api/v1/sub-profiles/", protectedHandler)
+	mux.Handle("/api/v1/expense-approvals", protectedHandler)
+	mux.Handle("/api/v1/expense-approvals/", protectedHandler)
+	mux.Handle("/api/v1/webhooks", protectedHandler)
+	mux.Handle("/api/v1/webhooks/", protectedHandler)
+	mux.Handle("/api/v1/bank-connections", protectedHandler)
+	mux.Handle("/api/v1/bank-connections/", protectedHandler)
+	mux.Handle("/api/v1/export", protectedHandler)
+	mux.Handle("/api/v1/export/", protectedHandler)
+	mux.Handle("/api/v1/jobs/", protectedHandler)
+	mux.Handle("/api/v1/sandbox", protectedHandler)
+	mux.Handle("/api/v1/sandbox/", protectedHandler)
+	mux.Handle("/api/v1/resolve/", protectedHandler)
+	mux.Handle("/api/v1/admin/", protectedHandler)
+
+	// Serve the OpenAPI specs embedded at build time
 	mux.HandleFunc("/docs/swagger.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		http.ServeFile(w, r, "docs/swagger.json")
+		api.ServeSpec(w, r, docs.CurrentSpecVersion)
 	})
+	mux.HandleFunc("/docs/", api.DocsHandler) // /docs/{version}/swagger.json, /docs/versions, /docs/diff
 
 	// Scalar API Reference (public access - no versioning needed)
 	mux.HandleFunc("/reference", func(w http.ResponseWriter, r *http.Request) {
 		htmlContent, err := scalar.ApiReferenceHTML(&scalar.Options{
-			SpecURL: "http://localhost:8080/docs/swagger.json",
+			SpecURL: "http://localhost:8080/docs/" + docs.CurrentSpecVersion + "/swagger.json",
 			CustomOptions: scalar.CustomOptions{
 				PageTitle: "Fluxio API Documentation",
 			},
@@ -672,6 +1599,11 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"healthy","version":"1.0"}`))
 	})
+	mux.HandleFunc("/health/live", api.LivenessHandler)
+	mux.HandleFunc("/health/ready", rt.handlers.ReadinessHandler)
+
+	// Prometheus metrics (no versioning), protected by METRICS_TOKEN when set
+	mux.HandleFunc("/metrics", telemetry.MetricsHandler)
 
 	logger.Info("🚀 Server started on port: 8080")
 	logger.Info("  GET  /reference - Scalar API Documentation")
@@ -682,10 +1614,61 @@ func main() {
 		"http://localhost:3000",
 	}
 	
-	handler := middleware.RestrictedCORSMiddleware(allowedOrigins)(middleware.LoggingMiddleware(mux))
+	// Maintenance mode turns the whole API read-only before any handler runs; telemetry wraps
+	// logging so the trace covers the whole request, and both see the request ID
+	handler := middleware.RestrictedCORSMiddleware(allowedOrigins)(middleware.RequestIDMiddleware(
+		middleware.TelemetryMiddleware(middleware.LoggingMiddleware(middleware.MaintenanceMiddleware(svc, mux)))))
 	
-	err := http.ListenAndServe(":8080", handler)
-	if err != nil {
+	server := &http.Server{
+		Addr:              ":8080",
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// Shutdown doesn't cancel request contexts, so event streams are told to end apart
+	server.RegisterOnShutdown(services.CloseStreams)
+
+	// SIGTERM (e.g. from Kubernetes) and Ctrl+C start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
 		logger.Fatal("Error al iniciar el servidor: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+	rt.shutdown(server)
+}
+
+// shutdown stops taking requests, lets the in-flight ones and the running jobs finish within
+// the shutdown timeout, writes the buffered usage and spans, and closes the database pool
+func (rt *routes) shutdown(server *http.Server) {
+	logger.Info("🛑 Shutting down, draining in-flight requests...")
+	services.MarkShuttingDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), services.ShutdownTimeout())
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Error draining HTTP requests: %v", err)
+	}
+	// Jobs get their own deadline, so slow requests don't leave them none
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), services.ShutdownTimeout())
+	defer cancelJobs()
+	if err := jobs.Stop(jobsCtx); err != nil {
+		logger.Error("Error waiting for running jobs: %v", err)
+	}
+	rt.services.FlushAllUsage()
+	if err := telemetry.Shutdown(ctx); err != nil {
+		logger.Error("Error exporting the last spans: %v", err)
+	}
+	if err := db.Close(rt.services.DB()); err != nil {
+		logger.Error("Error closing the database pool: %v", err)
 	}
+	logger.Info("👋 Server stopped")
 }
\ No newline at end of file
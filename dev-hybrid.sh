@@ -50,7 +50,7 @@ go mod tidy
 
 # Generate Swagger docs
 echo -e "${BLUE}📚 Generando documentación Swagger...${NC}"
-swag init -g cmd/server/main.go
+go generate ./docs
 
 # Build the application
 echo -e "${BLUE}🔨 Construyendo la aplicación...${NC}"
@@ -0,0 +1,50 @@
+package docs
+
+import (
+	"embed"
+	"sort"
+	"strings"
+)
+
+// The spec is generated from the handler annotations and committed; CI fails when it is stale
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.6 init -d ../cmd/server,../internal/api -g main.go --parseInternal --parseDependency --overridesFile ../.swaggo -o .
+
+// CurrentSpecVersion names the spec generated from the code being built. The specs are
+// embedded when the binary is built, so go generate ./docs must run after the annotations change
+const CurrentSpecVersion = "v1"
+
+//go:embed swagger.json
+var currentSpec []byte
+
+// releasedSpecs are frozen copies of the spec of each release, named <version>.json, kept so
+// client teams can diff against what they integrated with
+//
+//go:embed versions/*.json
+var releasedSpecs embed.FS
+
+// Spec returns the OpenAPI document of a version: CurrentSpecVersion or a released one
+func Spec(version string) ([]byte, bool) {
+	if version == CurrentSpecVersion {
+		return currentSpec, true
+	}
+	if version == "" || strings.ContainsAny(version, `/\`) {
+		return nil, false
+	}
+	spec, err := releasedSpecs.ReadFile("versions/" + version + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return spec, true
+}
+
+// SpecVersions lists the available spec versions, the current one first
+func SpecVersions() []string {
+	versions := []string{CurrentSpecVersion}
+	entries, _ := releasedSpecs.ReadDir("versions")
+	released := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		released = append(released, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(released)))
+	return append(versions, released...)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type DeleteAccountRequest struct {
+	Password string `json:"password" example:"password123"` // Current password, to confirm
+}
+
+// AccountHandler handles GET (profile) and DELETE (account deletion) on /api/v1/auth/me
+func (h *Handlers) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.MeHandler(w, r)
+	case http.MethodDelete:
+		h.DeleteAccountHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DeleteAccountHandler godoc
+// @Summary Delete the current account
+// @Description Schedules the erasure of the account once the grace period (ACCOUNT_DELETION_GRACE_DAYS, 30 by default) is over. Every session and API key is revoked right away. Logging in again and calling POST /api/v1/auth/me/restore during the grace period keeps the account; afterwards every record of the user is deleted for good. Asking again keeps the first date.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body DeleteAccountRequest true "Password confirmation"
+// @Success 202 {object} dto.AccountDeletion
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys can't manage the account"
+// @Failure 422 {string} string "Invalid password"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/auth/me [delete]
+func (h *Handlers) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, isAPIKey := r.Context().Value("apiKeyID").(string); isAPIKey {
+		http.Error(w, "API keys can't manage the account", http.StatusForbidden)
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.services.ScheduleAccountDeletion(userID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			logger.Error("Error deleting account of user %s: %v", userID, err)
+			http.Error(w, "Error scheduling account deletion", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// RestoreAccountHandler godoc
+// @Summary Cancel the account deletion
+// @Description Keeps an account whose deletion is still in its grace period. API keys revoked by the deletion stay revoked.
+// @Tags auth
+// @Security bearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys can't manage the account"
+// @Failure 409 {string} string "No account deletion is scheduled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/auth/me/restore [post]
+func (h *Handlers) RestoreAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, isAPIKey := r.Context().Value("apiKeyID").(string); isAPIKey {
+		http.Error(w, "API keys can't manage the account", http.StatusForbidden)
+		return
+	}
+
+	if err := h.services.CancelAccountDeletion(userID); err != nil {
+		if errors.Is(err, services.ErrNoAccountDeletion) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, "Error restoring account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// Request and response structures
+type AccountGroupRequest struct {
+	Name string `json:"name" example:"Liquid"`
+}
+
+type AccountGroupMembersRequest struct {
+	BankAccountIDs []string `json:"bank_account_ids" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+type AccountGroupsListResponse struct {
+	Groups []dto.AccountGroup `json:"groups"`
+	Count  int                `json:"count" example:"2"`
+}
+
+// accountGroupFilter returns the accounts of the group given in the group_id query parameter,
+// or nil without one. It writes the error response and returns false if the group doesn't exist
+func (h *Handlers) accountGroupFilter(w http.ResponseWriter, r *http.Request, userID string) ([]uuid.UUID, bool) {
+	groupID := r.URL.Query().Get("group_id")
+	if groupID == "" {
+		return nil, true
+	}
+
+	members, err := h.services.GetAccountGroupAccountIDs(userID, groupID)
+	if err != nil {
+		http.Error(w, "Account group not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	accounts := make([]uuid.UUID, 0, len(members))
+	for id := range members {
+		accounts = append(accounts, id)
+	}
+	return accounts, true
+}
+
+// writeAccountGroupError maps account group service errors to responses
+func writeAccountGroupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccountGroupExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing account group", http.StatusInternalServerError)
+	}
+}
+
+// AccountGroupsHandler godoc
+// @Summary List or create account groups
+// @Description GET lists the account groups of the user with their accounts and total balance. POST creates an empty group.
+// @Tags account-groups
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body AccountGroupRequest false "Group name (POST)"
+// @Success 200 {object} AccountGroupsListResponse
+// @Success 201 {object} dto.AccountGroup
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "An account group with this name already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/account-groups [get]
+// @Router /api/v1/account-groups [post]
+func (h *Handlers) AccountGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		groups, err := h.services.GetAccountGroups(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving account groups", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AccountGroupsListResponse{Groups: groups, Count: len(groups)})
+
+	case http.MethodPost:
+		var req AccountGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		group, err := h.services.CreateAccountGroup(userID, req.Name)
+		if err != nil {
+			writeAccountGroupError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(group)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AccountGroupHandler godoc
+// @Summary Get, rename or delete an account group
+// @Description GET returns the group with its accounts and total balance, PATCH renames it and DELETE removes it (the accounts are kept)
+// @Tags account-groups
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Account group ID"
+// @Param request body AccountGroupRequest false "New name (PATCH)"
+// @Success 200 {object} dto.AccountGroup
+// @Success 204 "Deleted"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Account group not found"
+// @Failure 409 {string} string "An account group with this name already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/account-groups/{id} [get]
+// @Router /api/v1/account-groups/{id} [patch]
+// @Router /api/v1/account-groups/{id} [delete]
+func (h *Handlers) AccountGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := extractIDFromPath(r.URL.Path, "/api/v1/account-groups/")
+	if groupID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var group *dto.AccountGroup
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		group, err = h.services.GetAccountGroup(userID, groupID)
+
+	case http.MethodPatch:
+		var req AccountGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		group, err = h.services.RenameAccountGroup(userID, groupID, req.Name)
+
+	case http.MethodDelete:
+		if err := h.services.DeleteAccountGroup(userID, groupID); err != nil {
+			writeAccountGroupError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeAccountGroupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// AccountGroupMembersHandler godoc
+// @Summary Add or remove accounts of a group
+// @Description POST /account-groups/{id}/accounts adds bank accounts to the group; DELETE /account-groups/{id}/accounts/{account_id} removes one
+// @Tags account-groups
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Account group ID"
+// @Param account_id path string false "Bank account ID (DELETE)"
+// @Param request body AccountGroupMembersRequest false "Accounts to add (POST)"
+// @Success 200 {object} dto.AccountGroup
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Account group or bank account not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/account-groups/{id}/accounts [post]
+// @Router /api/v1/account-groups/{id}/accounts/{account_id} [delete]
+func (h *Handlers) AccountGroupMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/account-groups/{id}/accounts[/{account_id}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/account-groups/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] != "accounts" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	groupID := parts[0]
+
+	var group *dto.AccountGroup
+	var err error
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2:
+		var req AccountGroupMembersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		group, err = h.services.AddAccountsToGroup(userID, groupID, req.BankAccountIDs)
+
+	case r.Method == http.MethodDelete && len(parts) == 3 && parts[2] != "":
+		group, err = h.services.RemoveAccountFromGroup(userID, groupID, parts[2])
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeAccountGroupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/jobs"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type AdminUserResponse struct {
+	ID        string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email     string  `json:"email" example:"user@example.com"`
+	Name      string  `json:"name" example:"John Doe"`
+	Status    string  `json:"status" example:"active"`
+	Role      string  `json:"role" example:"user"` // Effective role, admin for ADMIN_EMAILS too
+	Currency  string  `json:"currency" example:"USD"`
+	LastLogin *string `json:"last_login,omitempty" example:"2024-01-15T10:30:00Z"`
+	CreatedAt string  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+type AdminUsersListResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	Count int                 `json:"count" example:"20"`
+	*PageResponse
+}
+
+type LockUserRequest struct {
+	Reason string `json:"reason,omitempty" example:"Suspicious activity"`
+}
+
+type SetUserRoleRequest struct {
+	Role string `json:"role" example:"admin"` // user or admin
+}
+
+type AdminJobsListResponse struct {
+	Jobs []jobs.Status `json:"jobs"`
+}
+
+type RunJobResponse struct {
+	Job     string `json:"job" example:"deleted-records-purge"`
+	Started bool   `json:"started" example:"true"`
+}
+
+func convertAdminUserToResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		Name:      user.Name,
+		Status:    user.Status.String(),
+		Role:      services.EffectiveRole(user),
+		Currency:  user.Currency,
+		LastLogin: formatOptionalTime(user.LastLogin),
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// writeAdminError maps admin service errors to responses
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing admin request", http.StatusInternalServerError)
+	}
+}
+
+// AdminUsersHandler godoc
+// @Summary List users (admin)
+// @Description Lists the users of the instance, newest first, with their status and effective role. Sandbox tenants are left out.
+// @Tags admin
+// @Produce json
+// @Security bearerAuth
+// @Param q query string false "Part of the email or name"
+// @Param status query string false "Only users with this status (e.g. active, locked)"
+// @Param role query string false "Only users with this stored role: user or admin"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} AdminUsersListResponse
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/users [get]
+func (h *Handlers) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	users, info, err := h.services.ListUsers(services.AdminUserFilter{
+		Query:  query.Get("q"),
+		Status: query.Get("status"),
+		Role:   query.Get("role"),
+	}, page)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	responses := make([]AdminUserResponse, len(users))
+	for i := range users {
+		responses[i] = convertAdminUserToResponse(&users[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminUsersListResponse{Users: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
+
+// LockUserHandler godoc
+// @Summary Lock or unlock a user (admin)
+// @Description POST .../lock locks the account and revokes all its sessions, so the user is logged out everywhere and their API keys stop working. POST .../unlock gives access back; the user has to log in again. Admins can't lock themselves.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "User ID"
+// @Param request body LockUserRequest false "Why the account is locked (lock only)"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Invalid user or status"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/users/{id}/lock [post]
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *Handlers) LockUserHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := extractIDFromPath(r.URL.Path, "/api/v1/admin/users/")
+	var user *models.User
+	var err error
+	if strings.HasSuffix(r.URL.Path, "/unlock") {
+		user, err = h.services.UnlockUser(adminID, userID)
+	} else {
+		var req LockUserRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logger.Error("Error decoding request body: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		user, err = h.services.LockUser(adminID, userID, req.Reason)
+	}
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertAdminUserToResponse(user))
+}
+
+// SetUserRoleHandler godoc
+// @Summary Change the role of a user (admin)
+// @Description Promotes a user to admin or demotes them to user. Users listed in ADMIN_EMAILS stay admins whatever their stored role. A demoted admin loses admin access on their next request. Admins can't change their own role.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "User ID"
+// @Param request body SetUserRoleRequest true "New role"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Invalid role or user"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/users/{id}/role [put]
+func (h *Handlers) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.services.SetUserRole(adminID, extractIDFromPath(r.URL.Path, "/api/v1/admin/users/"), req.Role)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertAdminUserToResponse(user))
+}
+
+// SystemStatsHandler godoc
+// @Summary System-wide stats (admin)
+// @Description Counts users by status and role, new and active users of the last 30 days, visible expenses, incomes and bank accounts of every user, and the background work waiting in the outbox and the webhook queue.
+// @Tags admin
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} dto.SystemStats
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/stats [get]
+func (h *Handlers) SystemStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.services.GetSystemStats()
+	if err != nil {
+		http.Error(w, "Error getting system stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// AdminJobsHandler godoc
+// @Summary List maintenance jobs (admin)
+// @Description Lists the periodic maintenance jobs of this instance with their interval and last run. Jobs skip their scheduled runs while maintenance mode is on.
+// @Tags admin
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} AdminJobsListResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /api/v1/admin/jobs [get]
+func AdminJobsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminJobsListResponse{Jobs: jobs.List()})
+}
+
+// RunJobHandler godoc
+// @Summary Trigger a maintenance job (admin)
+// @Description Starts a run of the job in the background, even in maintenance mode; GET /api/v1/admin/jobs shows its outcome. Returns 409 while a run of the job is still going.
+// @Tags admin
+// @Produce json
+// @Security bearerAuth
+// @Param name path string true "Job name"
+// @Success 202 {object} RunJobResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Job not found"
+// @Failure 409 {string} string "Job is already running"
+// @Failure 503 {string} string "Server is shutting down"
+// @Router /api/v1/admin/jobs/{name}/run [post]
+func RunJobHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := extractIDFromPath(r.URL.Path, "/api/v1/admin/jobs/")
+	if err := jobs.Run(name); err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrUnknownJob):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, jobs.ErrJobRunning):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, jobs.ErrStopped):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			http.Error(w, "Error starting job", http.StatusInternalServerError)
+		}
+		return
+	}
+	logger.Info("Job %s triggered by admin %s", name, adminID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(RunJobResponse{Job: name, Started: true})
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name" example:"Home automation"`
+	Scope string `json:"scope,omitempty" example:"read_only"` // read_only, write_expenses or full (the default)
+}
+
+type APIKeyResponse struct {
+	ID         string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name       string  `json:"name" example:"Home automation"`
+	Prefix     string  `json:"prefix" example:"fx_1a2b3c4d"`
+	Scope      string  `json:"scope" example:"read_only"`
+	Key        string  `json:"key,omitempty" example:"fx_1a2b3c4d..."` // Only returned once, at creation
+	LastUsedAt *string `json:"last_used_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	RevokedAt  *string `json:"revoked_at,omitempty" example:"2024-01-20T10:30:00Z"`
+	CreatedAt  string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+type APIKeysListResponse struct {
+	Keys  []APIKeyResponse `json:"keys"`
+	Count int              `json:"count" example:"2"`
+}
+
+// Helper function to convert model to response
+func convertAPIKeyToResponse(key *models.APIKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scope:     key.Scope,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+
+	if key.LastUsedAt != nil {
+		lastUsedAt := key.LastUsedAt.Format(time.RFC3339)
+		response.LastUsedAt = &lastUsedAt
+	}
+	if key.RevokedAt != nil {
+		revokedAt := key.RevokedAt.Format(time.RFC3339)
+		response.RevokedAt = &revokedAt
+	}
+
+	return response
+}
+
+// requireSessionAuth rejects requests authenticated with an API key, so a leaked key
+// can't be used to mint or revoke other keys
+func requireSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if _, isAPIKey := r.Context().Value("apiKeyID").(string); isAPIKey {
+		http.Error(w, "API keys can't manage API keys", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// CreateAPIKeyHandler godoc
+// @Summary Create an API key
+// @Description Creates an API key for scripts and integrations. The key is only returned in this response; send it as a Bearer token or in the X-API-Key header. read_only keys can only read, write_expenses keys can also change expenses, full keys can do everything except managing the account and API keys. Also available at /api/v1/auth/api-keys.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateAPIKeyRequest true "Key name and scope"
+// @Success 201 {object} APIKeyResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys can't manage API keys"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/api-keys [post]
+func (h *Handlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !requireSessionAuth(w, r) {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	key, raw, err := h.services.CreateAPIKey(userID, req.Name, req.Scope)
+	if err != nil {
+		if strings.Contains(err.Error(), "required") || strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error creating API key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := convertAPIKeyToResponse(key)
+	response.Key = raw
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAPIKeysHandler godoc
+// @Summary List API keys
+// @Description Lists the API keys of the authenticated user, including revoked ones
+// @Tags api-keys
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} APIKeysListResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/api-keys [get]
+func (h *Handlers) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.services.GetAPIKeys(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving API keys", http.StatusInternalServerError)
+		return
+	}
+
+	response := APIKeysListResponse{Keys: make([]APIKeyResponse, 0, len(keys)), Count: len(keys)}
+	for i := range keys {
+		response.Keys = append(response.Keys, convertAPIKeyToResponse(&keys[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeAPIKeyHandler godoc
+// @Summary Revoke an API key
+// @Description Permanently disables an API key
+// @Tags api-keys
+// @Security bearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys can't manage API keys"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/api-keys/{id} [delete]
+func (h *Handlers) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !requireSessionAuth(w, r) {
+		return
+	}
+
+	keyID := extractIDFromPath(r.URL.Path, "/api/v1/api-keys/")
+	if err := h.services.RevokeAPIKey(userID, keyID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "API key not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error revoking API key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAPIKeyUsageHandler godoc
+// @Summary Get API key usage
+// @Description Returns the request count, last use and per-endpoint and per-day breakdown of an API key, to detect leaked keys
+// @Tags api-keys
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "API key ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param end_date query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} dto.APIKeyUsage
+// @Failure 400 {string} string "Invalid date format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/api-keys/{id}/usage [get]
+func (h *Handlers) GetAPIKeyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID := extractIDFromPath(r.URL.Path, "/api/v1/api-keys/")
+
+	startDate, endDate, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	usage, err := h.services.GetAPIKeyUsage(userID, keyID, startDate, endDate)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "API key not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error retrieving API key usage", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
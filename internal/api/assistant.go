@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+var assistantSections = map[string]bool{
+	services.AssistantSectionBalances: true,
+	services.AssistantSectionBudgets:  true,
+	services.AssistantSectionBills:    true,
+	services.AssistantSectionGoals:    true,
+}
+
+var assistantRedactFields = map[string]bool{
+	services.AssistantRedactNames:   true,
+	services.AssistantRedactAmounts: true,
+}
+
+// parseCSVSet splits a comma separated query value and checks every item against allowed
+func parseCSVSet(value string, allowed map[string]bool) (map[string]bool, string) {
+	set := make(map[string]bool)
+	if value == "" {
+		return set, ""
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !allowed[item] {
+			return nil, item
+		}
+		set[item] = true
+	}
+
+	return set, ""
+}
+
+// GetAssistantContextHandler godoc
+// @Summary Financial snapshot for assistant integrations
+// @Description Returns a compact, structured snapshot of balances, remaining category budgets, upcoming bills and goals, meant as grounding context for LLM-based assistants. Names and amounts can be redacted.
+// @Tags assistant
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param sections query string false "Comma separated sections: balances,budgets,bills,goals (default all)"
+// @Param redact query string false "Comma separated fields to redact: names,amounts"
+// @Param bills_days query int false "Days ahead for upcoming bills (1-90, default 30)"
+// @Success 200 {object} services.AssistantContext
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/assistant/context [get]
+func (h *Handlers) GetAssistantContextHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sections, invalid := parseCSVSet(r.URL.Query().Get("sections"), assistantSections)
+	if invalid != "" {
+		http.Error(w, "Invalid section: "+invalid, http.StatusBadRequest)
+		return
+	}
+
+	redact, invalid := parseCSVSet(r.URL.Query().Get("redact"), assistantRedactFields)
+	if invalid != "" {
+		http.Error(w, "Invalid redact field: "+invalid, http.StatusBadRequest)
+		return
+	}
+
+	billsDays := 30
+	if daysStr := r.URL.Query().Get("bills_days"); daysStr != "" {
+		days, err := parseIntParam(daysStr)
+		if err != nil || days < 1 || days > 90 {
+			http.Error(w, "Invalid bills_days parameter (1-90)", http.StatusBadRequest)
+			return
+		}
+		billsDays = days
+	}
+
+	snapshot, err := h.services.GetAssistantContext(userID, services.AssistantContextOptions{
+		Sections:  sections,
+		Redact:    redact,
+		BillsDays: billsDays,
+	})
+	if err != nil {
+		http.Error(w, "Error building assistant context", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(snapshot)
+}
@@ -2,12 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/middleware"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
 )
 
 type LoginRequest struct {
@@ -22,8 +25,59 @@ type RegisterRequest struct {
 }
 
 type AuthResponse struct {
-	Token string      `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	User  models.User `json:"user"`
+	Token        string      `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string      `json:"refresh_token" example:"9f86d081884c7d65..."` // Single use, rotated by /api/v1/auth/refresh
+	ExpiresIn    int64       `json:"expires_in" example:"900"`                    // Seconds until the access token expires
+	User         models.User `json:"user"`
+}
+
+// newAuthResponse starts a session for the user on the requesting device
+func (h *Handlers) newAuthResponse(r *http.Request, user *models.User) (*AuthResponse, error) {
+	tokens, err := h.services.GenerateTokenPair(user, loginContextFromRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	}, nil
+}
+
+// StepUpRequiredResponse is returned with 202 when a login needs an email code
+type StepUpRequiredResponse struct {
+	StepUpRequired bool     `json:"step_up_required" example:"true"`
+	ChallengeID    string   `json:"challenge_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Method         string   `json:"method" example:"email_code"`
+	ExpiresAt      string   `json:"expires_at" example:"2024-01-15T10:40:00Z"`
+	Reasons        []string `json:"reasons" example:"new_device,new_network"`
+}
+
+type VerifyLoginRequest struct {
+	ChallengeID string `json:"challenge_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Code        string `json:"code" example:"123456"`
+}
+
+// loginContextFromRequest reads the IP, country and user agent of a login. The country header
+// is only believed when a trusted proxy set it, otherwise it could be spoofed to look like a
+// known network
+func loginContextFromRequest(r *http.Request) services.LoginContext {
+	login := services.LoginContext{
+		IPAddress: middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	if middleware.FromTrustedProxy(r) {
+		for _, header := range []string{"CF-IPCountry", "X-Country-Code"} {
+			if country := strings.TrimSpace(r.Header.Get(header)); len(country) == 2 {
+				login.Country = country
+				break
+			}
+		}
+	}
+
+	return login
 }
 
 // LoginHandler godoc
@@ -34,11 +88,13 @@ type AuthResponse struct {
 // @Produce json
 // @Param request body LoginRequest true "Credenciales de login"
 // @Success 200 {object} AuthResponse
+// @Success 202 {object} StepUpRequiredResponse "Login inusual: se requiere el código enviado por email"
 // @Failure 400 {string} string "Cuerpo de solicitud inválido"
 // @Failure 401 {string} string "Credenciales inválidas"
+// @Failure 403 {string} string "Cuenta bloqueada"
 // @Failure 500 {string} string "Error interno del servidor"
 // @Router /api/v1/auth/login [post]
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -50,26 +106,106 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := services.GetUserByEmail(req.Email)
+	user, err := h.services.GetUserByEmail(req.Email)
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	login := loginContextFromRequest(r)
 	if !services.CheckPassword(req.Password, user.Password) {
+		h.services.RecordFailedLogin(user.ID, login)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := services.GenerateToken(user)
+	// Locked accounts can't start sessions until an admin unlocks them
+	if !user.IsAccessible() {
+		http.Error(w, "User account is not accessible", http.StatusForbidden)
+		return
+	}
+
+	// Logins that deviate from the user's history need an email code before getting a token
+	risk, err := h.services.AssessLoginRisk(user.ID, login)
+	if err != nil {
+		http.Error(w, "Error checking login", http.StatusInternalServerError)
+		return
+	}
+	if risk.StepUpRequired {
+		challenge, err := h.services.CreateLoginChallenge(user, login, risk)
+		if err != nil {
+			http.Error(w, "Error creating login challenge", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(StepUpRequiredResponse{
+			StepUpRequired: true,
+			ChallengeID:    challenge.ID.String(),
+			Method:         "email_code",
+			ExpiresAt:      challenge.ExpiresAt.Format(time.RFC3339),
+			Reasons:        risk.Reasons,
+		})
+		return
+	}
+
+	if err := h.services.RecordSuccessfulLogin(user.ID, models.SecurityEventLogin, login, risk); err != nil {
+		logger.Error("Error recording login for user %s: %v", user.ID, err)
+	}
+
+	response, err := h.newAuthResponse(r, user)
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
-		User:  *user,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifyLoginHandler godoc
+// @Summary Verificar un login inusual
+// @Description Completa un login que requirió verificación adicional con el código enviado por email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyLoginRequest true "Desafío y código"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {string} string "Cuerpo de solicitud inválido"
+// @Failure 401 {string} string "Código inválido o expirado"
+// @Failure 500 {string} string "Error interno del servidor"
+// @Router /api/v1/auth/login/verify [post]
+func (h *Handlers) VerifyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req VerifyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeID == "" || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.services.VerifyLoginChallenge(req.ChallengeID, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLoginChallenge) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Error verifying login", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !user.IsAccessible() {
+		http.Error(w, "User account is not accessible", http.StatusForbidden)
+		return
+	}
+
+	response, err := h.newAuthResponse(r, user)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -88,7 +224,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 409 {string} string "Usuario ya existe"
 // @Failure 500 {string} string "Error interno del servidor"
 // @Router /api/v1/auth/register [post]
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -101,7 +237,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user already exists
-	existingUser, _ := services.GetUserByEmail(req.Email)
+	existingUser, _ := h.services.GetUserByEmail(req.Email)
 	if existingUser != nil {
 		http.Error(w, "User already exists", http.StatusConflict)
 		return
@@ -120,23 +256,17 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		Name:     req.Name,
 	}
 
-	result := db.DB.Create(&user)
-	if result.Error != nil {
+	if err := h.services.CreateUser(&user); err != nil {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
-	token, err := services.GenerateToken(&user)
+	response, err := h.newAuthResponse(r, &user)
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
-		User:  user,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -146,8 +276,11 @@ type UserProfileResponse struct {
 	ID        string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Email     string `json:"email" example:"user@example.com"`
 	Name      string `json:"name" example:"John Doe"`
+	Role      string `json:"role" example:"user"` // user or admin
 	CreatedAt string `json:"createdAt" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt string `json:"updatedAt" example:"2023-12-01T00:00:00Z"`
+	// Set while a requested account deletion is in its grace period
+	DeletionScheduledAt *string `json:"deletionScheduledAt,omitempty" example:"2024-01-31T00:00:00Z"`
 }
 
 // MeHandler godoc
@@ -162,7 +295,7 @@ type UserProfileResponse struct {
 // @Failure 404 {string} string "Usuario no encontrado"
 // @Failure 500 {string} string "Error interno del servidor"
 // @Router /api/v1/auth/me [get]
-func MeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) MeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -176,7 +309,7 @@ func MeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user from database
-	user, err := services.GetUserByID(userID)
+	user, err := h.services.GetUserByID(userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -193,9 +326,14 @@ func MeHandler(w http.ResponseWriter, r *http.Request) {
 		ID:        user.ID.String(),
 		Email:     user.Email,
 		Name:      user.Name,
+		Role:      services.EffectiveRole(user),
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
 	}
+	if user.DeletionScheduledAt != nil {
+		scheduledAt := user.DeletionScheduledAt.Format(time.RFC3339)
+		response.DeletionScheduledAt = &scheduledAt
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
-    "time"
+	"time"
 
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
@@ -13,30 +15,44 @@ import (
 
 // Request and response structures
 type CreateBankAccountRequest struct {
-	AccountName string  `json:"account_name" example:"Main Checking Account"`
-	Balance     float64 `json:"balance" example:"2500.00"`
+	AccountName   string  `json:"account_name" example:"Main Checking Account"`
+	Balance       models.Money `json:"balance" example:"2500.00"`
+	ManualBalance bool    `json:"manual_balance" example:"false"` // Expenses, incomes and transfers don't move the balance
 }
 
 type UpdateBankAccountRequest struct {
-	AccountName *string  `json:"account_name,omitempty" example:"Updated Account Name"`
-	Balance     *float64 `json:"balance,omitempty" example:"3000.00"`
+	AccountName   *string  `json:"account_name,omitempty" example:"Updated Account Name"`
+	Balance       *models.Money `json:"balance,omitempty" example:"3000.00"`
+	ManualBalance *bool    `json:"manual_balance,omitempty" example:"true"`
+	Version       *int64   `json:"version,omitempty" example:"3"` // Version the change is based on; required unless If-Match is sent
 }
 
 type BankAccountFullResponse struct {
 	ID              string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	AccountName     string  `json:"account_name" example:"Main Checking Account"`
-	Balance         float64 `json:"balance" example:"2500.00"`
-    CommittedFixedExpensesMonth float64 `json:"committed_fixed_expenses_month" example:"1200.00"`
-    RealBalance     float64 `json:"real_balance" example:"1300.00"`
+	Balance         models.Money `json:"balance" example:"2500.00"`
+	ManualBalance   bool    `json:"manual_balance" example:"false"` // The balance is kept by hand instead of following expenses, incomes and transfers
+    CommittedFixedExpensesMonth models.Money `json:"committed_fixed_expenses_month" example:"1200.00"`
+    RealBalance     models.Money `json:"real_balance" example:"1300.00"`
+	Version         int64   `json:"version" example:"3"` // Send it back when updating the account
 	Status          string  `json:"status" example:"active"`
 	StatusChangedAt *string `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	AllowedStatuses []string `json:"allowed_statuses" example:"suspended,archived,locked,deleted"` // Statuses it can be changed to
 	CreatedAt       string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
 	UpdatedAt       string  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
 }
 
+// BankAccountInUseResponse is returned with 409 when records still use the account being deleted
+type BankAccountInUseResponse struct {
+	Error   string                      `json:"error" example:"account_in_use"`
+	Message string                      `json:"message" example:"Records still use this bank account. Resend with cascade=true to archive them with it"`
+	Impact  dto.BankAccountDeleteImpact `json:"impact"`
+}
+
 type BankAccountsListResponse struct {
 	BankAccounts []BankAccountFullResponse `json:"bank_accounts"`
 	Count        int                       `json:"count" example:"3"`
+	*PageResponse
 }
 
 // Helper function to convert model to response
@@ -45,15 +61,18 @@ func convertBankAccountToResponse(bankAccount *models.BankAccount) BankAccountFu
 		ID:          bankAccount.ID.String(),
 		AccountName: bankAccount.AccountName,
 		Balance:     bankAccount.Balance,
+		ManualBalance: bankAccount.ManualBalance,
         CommittedFixedExpensesMonth: 0,
         RealBalance: 0,
+		Version:     bankAccount.Version,
 		Status:      string(bankAccount.Status),
-		CreatedAt:   bankAccount.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   bankAccount.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		AllowedStatuses: allowedStatuses(models.BankAccountStatusMachine, bankAccount.Status),
+		CreatedAt:   bankAccount.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   bankAccount.UpdatedAt.Format(time.RFC3339),
 	}
 	
 	if bankAccount.StatusChangedAt != nil {
-		statusChangedAt := bankAccount.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+		statusChangedAt := bankAccount.StatusChangedAt.Format(time.RFC3339)
 		response.StatusChangedAt = &statusChangedAt
 	}
 	
@@ -73,7 +92,7 @@ func convertBankAccountToResponse(bankAccount *models.BankAccount) BankAccountFu
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts [post]
-func CreateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -106,12 +125,13 @@ func CreateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create the model
 	bankAccount := &models.BankAccount{
-		AccountName: req.AccountName,
-		Balance:     req.Balance,
+		AccountName:   req.AccountName,
+		Balance:       req.Balance,
+		ManualBalance: req.ManualBalance,
 	}
 
 	// Create in the database
-	if err := services.CreateBankAccount(userID, bankAccount); err != nil {
+	if err := h.services.CreateBankAccount(userID, bankAccount); err != nil {
 		logger.Error("Error creating bank account: %v", err)
 		http.Error(w, "Error creating bank account", http.StatusInternalServerError)
 		return
@@ -119,8 +139,8 @@ func CreateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 
     // Convert to response and compute committed/real balance for current month
     response := convertBankAccountToResponse(bankAccount)
-    now := time.Now().UTC()
-    committed, err := services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
+    now := h.services.UserNow(userID)
+    committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
     if err == nil {
         response.CommittedFixedExpensesMonth = committed
         response.RealBalance = response.Balance - committed
@@ -145,7 +165,7 @@ func CreateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Bank account not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/{id} [get]
-func GetBankAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetBankAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -165,7 +185,7 @@ func GetBankAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the bank account
-	bankAccount, err := services.GetBankAccountByID(userID, id)
+	bankAccount, err := h.services.GetBankAccountByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting bank account: %v", err)
 		http.Error(w, "Bank account not found", http.StatusNotFound)
@@ -173,15 +193,14 @@ func GetBankAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
     response := convertBankAccountToResponse(bankAccount)
-    now := time.Now().UTC()
-    committed, err := services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
+    now := h.services.UserNow(userID)
+    committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
     if err == nil {
         response.CommittedFixedExpensesMonth = committed
         response.RealBalance = response.Balance - committed
     }
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeVersionedJSON(w, bankAccount.Version, response)
 }
 
 // GetAllBankAccountsHandler godoc
@@ -192,11 +211,18 @@ func GetBankAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security bearerAuth
 // @Param include_deleted query boolean false "Include deleted bank accounts"
+// @Param group_id query string false "Only accounts in this account group"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} BankAccountsListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/bank-accounts [get]
-func GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -208,11 +234,25 @@ func GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listNotModified(w, r, h.services, userID, "bank_accounts") {
+		return
+	}
+
 	// Check parameter to include deleted
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	accounts, ok := h.accountGroupFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
 	// Get bank accounts
-	bankAccounts, err := services.GetAllBankAccounts(userID, includeDeleted)
+	bankAccounts, info, err := h.services.GetAllBankAccounts(userID, includeDeleted, accounts, page)
 	if err != nil {
 		logger.Error("Error getting bank accounts: %v", err)
 		http.Error(w, "Error retrieving bank accounts", http.StatusInternalServerError)
@@ -221,10 +261,10 @@ func GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 
     // Convert to response and compute per-account committed/real
     bankAccountResponses := make([]BankAccountFullResponse, len(bankAccounts))
-    now := time.Now().UTC()
+    now := h.services.UserNow(userID)
     for i, bankAccount := range bankAccounts {
         resp := convertBankAccountToResponse(&bankAccount)
-        committed, err := services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
+        committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, bankAccount.ID.String(), now.Year(), now.Month())
         if err == nil {
             resp.CommittedFixedExpensesMonth = committed
             resp.RealBalance = resp.Balance - committed
@@ -235,6 +275,7 @@ func GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	response := BankAccountsListResponse{
 		BankAccounts: bankAccountResponses,
 		Count:        len(bankAccountResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -248,11 +289,16 @@ func GetAllBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param group_id query string false "Only accounts in this account group"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} BankAccountsListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/active [get]
-func GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -264,7 +310,17 @@ func GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bankAccounts, err := services.GetActiveBankAccounts(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	accounts, ok := h.accountGroupFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	bankAccounts, info, err := h.services.GetActiveBankAccounts(userID, accounts, page)
 	if err != nil {
 		logger.Error("Error getting active bank accounts: %v", err)
 		http.Error(w, "Error retrieving active bank accounts", http.StatusInternalServerError)
@@ -272,10 +328,10 @@ func GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
     bankAccountResponses := make([]BankAccountFullResponse, len(bankAccounts))
-    now := time.Now().UTC()
+    now := h.services.UserNow(userID)
     for i := range bankAccounts {
         resp := convertBankAccountToResponse(&bankAccounts[i])
-        committed, err := services.GetCommittedFixedExpensesForAccount(userID, bankAccounts[i].ID.String(), now.Year(), now.Month())
+        committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, bankAccounts[i].ID.String(), now.Year(), now.Month())
         if err == nil {
             resp.CommittedFixedExpensesMonth = committed
             resp.RealBalance = resp.Balance - committed
@@ -286,6 +342,7 @@ func GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	response := BankAccountsListResponse{
 		BankAccounts: bankAccountResponses,
 		Count:        len(bankAccountResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -299,11 +356,15 @@ func GetActiveBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} BankAccountsListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/deleted [get]
-func GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -315,7 +376,13 @@ func GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bankAccounts, err := services.GetDeletedBankAccounts(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bankAccounts, info, err := h.services.GetDeletedBankAccounts(userID, page)
 	if err != nil {
 		logger.Error("Error getting deleted bank accounts: %v", err)
 		http.Error(w, "Error retrieving deleted bank accounts", http.StatusInternalServerError)
@@ -323,10 +390,10 @@ func GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
     bankAccountResponses := make([]BankAccountFullResponse, len(bankAccounts))
-    now := time.Now().UTC()
+    now := h.services.UserNow(userID)
     for i := range bankAccounts {
         resp := convertBankAccountToResponse(&bankAccounts[i])
-        committed, err := services.GetCommittedFixedExpensesForAccount(userID, bankAccounts[i].ID.String(), now.Year(), now.Month())
+        committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, bankAccounts[i].ID.String(), now.Year(), now.Month())
         if err == nil {
             resp.CommittedFixedExpensesMonth = committed
             resp.RealBalance = resp.Balance - committed
@@ -337,6 +404,7 @@ func GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	response := BankAccountsListResponse{
 		BankAccounts: bankAccountResponses,
 		Count:        len(bankAccountResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -352,13 +420,16 @@ func GetDeletedBankAccountsHandler(w http.ResponseWriter, r *http.Request) {
 // @Security bearerAuth
 // @Param id path string true "Bank Account ID"
 // @Param request body UpdateBankAccountRequest true "Data to update"
+// @Param If-Match header string false "ETag of the account as read; can replace version"
 // @Success 200 {object} BankAccountResponse
-// @Failure 400 {string} string "Invalid request body"
+// @Failure 400 {string} string "Invalid request body or missing version"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Bank account not found"
+// @Failure 409 {object} VersionConflictResponse{current=BankAccountFullResponse} "The account changed since that version"
+// @Failure 412 {string} string "If-Match doesn't match the current account"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/{id} [patch]
-func UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -384,7 +455,7 @@ func UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current bank account to use as base for updates
-	currentBankAccount, err := services.GetBankAccountByID(userID, id)
+	currentBankAccount, err := h.services.GetBankAccountByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting current bank account: %v", err)
 		http.Error(w, "Bank account not found", http.StatusNotFound)
@@ -393,8 +464,9 @@ func UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create model with the fields to update (start with current values)
 	bankAccount := &models.BankAccount{
-		AccountName: currentBankAccount.AccountName,
-		Balance:     currentBankAccount.Balance,
+		AccountName:   currentBankAccount.AccountName,
+		Balance:       currentBankAccount.Balance,
+		ManualBalance: currentBankAccount.ManualBalance,
 	}
 
 	// Apply updates if provided
@@ -414,8 +486,24 @@ func UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		bankAccount.Balance = *req.Balance
 	}
 
+	if req.ManualBalance != nil {
+		bankAccount.ManualBalance = *req.ManualBalance
+	}
+
+	bankAccount.Version, err = requestVersion(r, req.Version, currentBankAccount.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Update in the database
-	updatedBankAccount, err := services.PatchBankAccount(userID, id, bankAccount)
+	updatedBankAccount, err := h.services.PatchBankAccount(userID, id, bankAccount)
+	if errors.Is(err, services.ErrVersionConflict) {
+		if current, err := h.services.GetBankAccountByID(userID, id); err == nil {
+			writeVersionConflict(w, req.Version, convertBankAccountToResponse(current))
+			return
+		}
+	}
 	if err != nil {
 		logger.Error("Error updating bank account: %v", err)
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
@@ -427,32 +515,33 @@ func UpdateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
     response := convertBankAccountToResponse(updatedBankAccount)
-    now := time.Now().UTC()
-    committed, err := services.GetCommittedFixedExpensesForAccount(userID, updatedBankAccount.ID.String(), now.Year(), now.Month())
+    now := h.services.UserNow(userID)
+    committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, updatedBankAccount.ID.String(), now.Year(), now.Month())
     if err == nil {
         response.CommittedFixedExpensesMonth = committed
         response.RealBalance = response.Balance - committed
     }
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeVersionedJSON(w, updatedBankAccount.Version, response)
 }
 
 // DeleteBankAccountHandler godoc
 // @Summary Delete a bank account (soft delete)
-// @Description Marks a bank account as deleted without permanently deleting it
+// @Description Marks a bank account as deleted without permanently deleting it. While expenses, incomes, transfers or fixed expenses still use the account the deletion is refused with 409 and the records in the way; with cascade=true they are archived along with the account, and come back when it is restored. See /api/v1/bank-accounts/{id}/delete-impact for a dry run
 // @Tags bank_account
 // @Accept json
 // @Produce json
 // @Security bearerAuth
 // @Param id path string true "Bank Account ID"
-// @Success 200 {object} BankAccountResponse
+// @Param cascade query bool false "Archive the records using the account"
+// @Success 204 "No Content"
 // @Failure 400 {string} string "Invalid ID"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Bank account not found"
+// @Failure 409 {object} BankAccountInUseResponse "Records still use the account, or status transition not allowed"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/{id} [delete]
-func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -470,9 +559,15 @@ func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := services.SoftDeleteBankAccount(userID, id); err != nil {
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if err := h.services.SoftDeleteBankAccount(userID, id, cascade); err != nil {
 		logger.Error("Error deleting bank account: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
+		if writeBankAccountInUse(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
 			http.Error(w, "Bank account not found or already deleted", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error deleting bank account", http.StatusInternalServerError)
@@ -483,6 +578,68 @@ func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// writeBankAccountInUse answers 409 with the records using the account when err is a
+// *services.BankAccountInUseError, and reports whether it did
+func writeBankAccountInUse(w http.ResponseWriter, err error) bool {
+	var inUse *services.BankAccountInUseError
+	if !errors.As(err, &inUse) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(BankAccountInUseResponse{
+		Error:   "account_in_use",
+		Message: "Records still use this bank account. Resend with cascade=true to archive them with it",
+		Impact:  *inUse.Impact,
+	})
+	return true
+}
+
+// GetBankAccountDeleteImpactHandler godoc
+// @Summary Preview deleting a bank account
+// @Description Dry run of a delete: counts the expenses (split ones included), incomes, transfers and fixed expenses that still use the account. When there are any, deleting needs cascade=true, which archives them with the account
+// @Tags bank_account
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Bank Account ID"
+// @Success 200 {object} dto.BankAccountDeleteImpact
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank account not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/bank-accounts/{id}/delete-impact [get]
+func (h *Handlers) GetBankAccountDeleteImpactHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/bank-accounts/")
+	if id == "" {
+		http.Error(w, "Invalid bank account ID", http.StatusBadRequest)
+		return
+	}
+
+	impact, err := h.services.GetBankAccountDeleteImpact(userID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Bank account not found or already deleted", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error getting delete impact", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impact)
+}
+
 // RestoreBankAccountHandler godoc
 // @Summary Restore a bank account to active status
 // @Description Restores a previously deleted, archived, or locked bank account to active status
@@ -497,7 +654,7 @@ func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Bank account not found or not restorable"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/{id}/restore [post]
-func RestoreBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RestoreBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -515,7 +672,7 @@ func RestoreBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	restoredAccount, err := services.RestoreBankAccount(userID, id)
+	restoredAccount, err := h.services.RestoreBankAccount(userID, id)
 	if err != nil {
 		logger.Error("Error restoring bank account: %v", err)
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not restorable") || strings.Contains(err.Error(), "access denied") {
@@ -527,8 +684,8 @@ func RestoreBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := convertBankAccountToResponse(restoredAccount)
-    now := time.Now().UTC()
-    committed, err := services.GetCommittedFixedExpensesForAccount(userID, restoredAccount.ID.String(), now.Year(), now.Month())
+    now := h.services.UserNow(userID)
+    committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, restoredAccount.ID.String(), now.Year(), now.Month())
     if err == nil {
         response.CommittedFixedExpensesMonth = committed
         response.RealBalance = response.Balance - committed
@@ -551,9 +708,10 @@ func RestoreBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Bank account not found"
+// @Failure 409 {string} string "Status transition not allowed"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/bank-accounts/{id}/status [patch]
-func ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -586,9 +744,14 @@ func ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert string to Status
 	status := models.Status(req.Status)
 
-	if err := services.ChangeAccountStatus(userID, id, status, req.Reason); err != nil {
+	if err := h.services.ChangeAccountStatus(userID, id, status, req.Reason); err != nil {
 		logger.Error("Error changing bank account status: %v", err)
-		if strings.Contains(err.Error(), "invalid status") {
+		if writeBankAccountInUse(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "invalid status") {
 			http.Error(w, "Invalid status", http.StatusBadRequest)
 		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Bank account not found", http.StatusNotFound)
@@ -599,7 +762,7 @@ func ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the updated bank account to return to the frontend
-	updatedBankAccount, err := services.GetBankAccountByID(userID, id)
+	updatedBankAccount, err := h.services.GetBankAccountByID(userID, id)
 	if err != nil {
 		logger.Error("Error retrieving updated bank account: %v", err)
 		http.Error(w, "Error retrieving updated bank account", http.StatusInternalServerError)
@@ -607,8 +770,8 @@ func ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
     response := convertBankAccountToResponse(updatedBankAccount)
-    now := time.Now().UTC()
-    committed, err := services.GetCommittedFixedExpensesForAccount(userID, updatedBankAccount.ID.String(), now.Year(), now.Month())
+    now := h.services.UserNow(userID)
+    committed, err := h.services.GetCommittedFixedExpensesForAccount(userID, updatedBankAccount.ID.String(), now.Year(), now.Month())
     if err == nil {
         response.CommittedFixedExpensesMonth = committed
         response.RealBalance = response.Balance - committed
@@ -621,3 +784,60 @@ func ChangeBankAccountStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 
 
+
+// GetAvailableBalanceHandler godoc
+// @Summary Get the available balance of a bank account
+// @Description Returns the booked balance, pending holds (fixed expenses already due but not yet processed), fixed expenses due within the next N days, and the resulting available amount
+// @Tags bank_account
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Bank Account ID"
+// @Param days query int false "Days of upcoming commitments to include (default 30, max 365)"
+// @Success 200 {object} dto.AvailableBalance
+// @Failure 400 {string} string "Invalid days"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank account not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/bank-accounts/{id}/available-balance [get]
+func (h *Handlers) GetAvailableBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/bank-accounts/")
+	if id == "" {
+		http.Error(w, "Invalid bank account ID", http.StatusBadRequest)
+		return
+	}
+
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := parseIntParam(daysStr)
+		if err != nil || parsed < 0 || parsed > services.MaxAvailableBalanceDays {
+			http.Error(w, "Invalid days. Must be between 0 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	balance, err := h.services.GetAvailableBalance(userID, id, days)
+	if err != nil {
+		logger.Error("Error getting available balance: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Bank account not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error calculating available balance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/openbanking"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// maxOpenBankingWebhookBytes bounds the body read from provider notifications
+const maxOpenBankingWebhookBytes = 64 << 10
+
+// Request and response structures
+type ConnectBankRequest struct {
+	PublicToken     string  `json:"public_token" example:"public-sandbox-b0e2c4ee-a763-4df5-bfe9-46a46bce993d"` // Returned by the provider's login flow
+	CategoryID      string  `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174001"`                 // For expenses created from synced transactions
+	InstitutionName *string `json:"institution_name,omitempty" example:"Chase"`
+}
+
+type LinkBankConnectionAccountRequest struct {
+	BankAccountID *string `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"` // null stops importing the account
+}
+
+// writeBankConnectionError maps bank connection service errors to responses
+func writeBankConnectionError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, openbanking.ErrNotConfigured):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, openbanking.ErrLoginRequired):
+		http.Error(w, "Bank login required: link the bank again", http.StatusConflict)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error "+action, http.StatusInternalServerError)
+	}
+}
+
+// CreateBankLinkTokenHandler godoc
+// @Summary Create a bank link token
+// @Description Starts linking a bank: the client opens the open-banking provider's login flow with the returned token and sends the public token it ends with to POST /api/v1/bank-connections
+// @Tags bank-connections
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} dto.BankLinkToken
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Open banking is not configured"
+// @Router /api/v1/bank-connections/link-token [post]
+func CreateBankLinkTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := services.CreateBankLinkToken(r.Context(), userID)
+	if err != nil {
+		writeBankConnectionError(w, err, "creating link token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// BankConnectionsHandler handles GET (list) and POST (connect) on /api/v1/bank-connections
+func (h *Handlers) BankConnectionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetBankConnectionsHandler(w, r)
+	case http.MethodPost:
+		h.ConnectBankHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetBankConnectionsHandler godoc
+// @Summary List bank connections
+// @Description Returns the user's linked banks with their accounts and the Fluxio bank account each one is imported into
+// @Tags bank-connections
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {array} models.BankConnection
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/bank-connections [get]
+func (h *Handlers) GetBankConnectionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	connections, err := h.services.GetBankConnections(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving bank connections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connections)
+}
+
+// ConnectBankHandler godoc
+// @Summary Connect a bank
+// @Description Exchanges the public token of the provider's login flow for a connection and lists its accounts. Link each account to a Fluxio bank account to have its transactions imported: money out becomes expenses in category_id (or matches with manual ones), money in becomes incomes, all tagged "imported". Linking the same bank login again refreshes the connection.
+// @Tags bank-connections
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body ConnectBankRequest true "Public token"
+// @Success 201 {object} models.BankConnection
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Category not found"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Open banking is not configured"
+// @Router /api/v1/bank-connections [post]
+func (h *Handlers) ConnectBankHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ConnectBankRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	connection, err := h.services.ConnectBank(r.Context(), userID, req.PublicToken, req.CategoryID, req.InstitutionName)
+	if err != nil {
+		writeBankConnectionError(w, err, "connecting bank")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(connection)
+}
+
+// LinkBankConnectionAccountHandler godoc
+// @Summary Link a connected bank account
+// @Description Sets the Fluxio bank account the transactions of a connected account are imported into. null stops importing them; records already imported are kept.
+// @Tags bank-connections
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Bank connection ID"
+// @Param accountId path string true "Connected account ID"
+// @Param request body LinkBankConnectionAccountRequest true "Fluxio bank account"
+// @Success 200 {object} models.BankConnectionAccount
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank connection, account or bank account not found"
+// @Failure 409 {string} string "Bank account already linked to another connected account"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/bank-connections/{id}/accounts/{accountId} [patch]
+func (h *Handlers) LinkBankConnectionAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/bank-connections/{id}/accounts/{accountId}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/bank-connections/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] != "accounts" || parts[2] == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req LinkBankConnectionAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.services.LinkBankConnectionAccount(userID, parts[0], parts[2], req.BankAccountID)
+	if err != nil {
+		writeBankConnectionError(w, err, "linking account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// SyncBankConnectionHandler godoc
+// @Summary Sync a bank connection
+// @Description Pulls the transactions added since the last sync right away instead of waiting for the sync job. Pending transactions wait until they post; records created from transactions the bank drops are deleted.
+// @Tags bank-connections
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Bank connection ID"
+// @Success 200 {object} dto.BankSyncResult
+// @Failure 400 {string} string "A sync is already running"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank connection not found"
+// @Failure 409 {string} string "Bank login required"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Open banking is not configured"
+// @Router /api/v1/bank-connections/{id}/sync [post]
+func (h *Handlers) SyncBankConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	connectionID := extractIDFromPath(r.URL.Path, "/api/v1/bank-connections/")
+	if connectionID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.services.SyncBankConnection(r.Context(), userID, connectionID)
+	if err != nil {
+		writeBankConnectionError(w, err, "syncing bank connection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DisconnectBankConnectionHandler godoc
+// @Summary Disconnect a bank
+// @Description Revokes the connection at the provider and stops syncing it. Records already imported are kept.
+// @Tags bank-connections
+// @Security bearerAuth
+// @Param id path string true "Bank connection ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank connection not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/bank-connections/{id} [delete]
+func (h *Handlers) DisconnectBankConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	connectionID := extractIDFromPath(r.URL.Path, "/api/v1/bank-connections/")
+	if connectionID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.DisconnectBankConnection(r.Context(), userID, connectionID); err != nil {
+		writeBankConnectionError(w, err, "disconnecting bank")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OpenBankingWebhookHandler godoc
+// @Summary Receive open-banking notifications
+// @Description Called by the open-banking provider when a bank login has new transactions or needs the user to log in again. Notifications only flag the connection for the sync job, which asks the provider what changed.
+// @Tags bank-connections
+// @Accept json
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid notification"
+// @Failure 503 {string} string "Open banking is not configured"
+// @Router /api/v1/open-banking/webhook [post]
+func (h *Handlers) OpenBankingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOpenBankingWebhookBytes))
+	if err != nil {
+		http.Error(w, "Invalid notification", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.HandleOpenBankingWebhook(body); err != nil {
+		if errors.Is(err, openbanking.ErrNotConfigured) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		logger.Warn("Rejected open banking webhook: %v", err)
+		http.Error(w, "Invalid notification", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,651 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// BudgetHandler serves the budget endpoints with the services it is given
+type BudgetHandler struct {
+	services *services.Services
+}
+
+// NewBudgetHandler returns the budget endpoints backed by the services
+func NewBudgetHandler(svc *services.Services) *BudgetHandler {
+	return &BudgetHandler{services: svc}
+}
+
+// Request and response structures
+type CreateBudgetRequest struct {
+	MonthYear     string       `json:"month_year" example:"2024-01"`
+	NeedsBudget   models.Money `json:"needs_budget" example:"1500.00"`
+	WantsBudget   models.Money `json:"wants_budget" example:"900.00"`
+	SavingsBudget models.Money `json:"savings_budget" example:"600.00"`
+}
+
+type UpdateBudgetRequest struct {
+	NeedsBudget   *models.Money `json:"needs_budget,omitempty" example:"1600.00"`
+	WantsBudget   *models.Money `json:"wants_budget,omitempty" example:"800.00"`
+	SavingsBudget *models.Money `json:"savings_budget,omitempty" example:"600.00"`
+	Version       *int64        `json:"version,omitempty" example:"3"` // Version the change is based on; required unless If-Match is sent
+}
+
+type BudgetResponse struct {
+	ID              string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	MonthYear       string       `json:"month_year" example:"2024-01"`
+	NeedsBudget     models.Money `json:"needs_budget" example:"1500.00"`
+	WantsBudget     models.Money `json:"wants_budget" example:"900.00"`
+	SavingsBudget   models.Money `json:"savings_budget" example:"600.00"`
+	TotalBudget     models.Money `json:"total_budget" example:"3000.00"`
+	Version         int64        `json:"version" example:"3"` // Send it back when updating the budget
+	Status          string       `json:"status" example:"active"`
+	StatusChangedAt *string      `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	CreatedAt       string       `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt       string       `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+
+	CategoryBudgets []CategoryBudgetResponse `json:"category_budgets"` // Optional category lines
+}
+
+type BudgetsListResponse struct {
+	Budgets []BudgetResponse `json:"budgets"`
+	Count   int              `json:"count" example:"12"`
+	*PageResponse
+}
+
+type BudgetPlanTemplate struct {
+	NeedsBudget   models.Money `json:"needs_budget" example:"1500.00"`
+	WantsBudget   models.Money `json:"wants_budget" example:"900.00"`
+	SavingsBudget models.Money `json:"savings_budget" example:"600.00"`
+}
+
+type BudgetPlanRequest struct {
+	StartMonth string              `json:"start_month,omitempty" example:"2024-02"` // Defaults to next month
+	Months     int                 `json:"months" example:"12"`
+	Source     string              `json:"source" example:"template" enums:"template,suggested"`
+	Template   *BudgetPlanTemplate `json:"template,omitempty"`
+	OnConflict string              `json:"on_conflict,omitempty" example:"fail" enums:"fail,skip,overwrite"`
+	Confirm    bool                `json:"confirm" example:"false"` // false only previews the plan
+}
+
+// parseMonth parses a YYYY-MM month
+func parseMonth(monthStr string) (time.Time, error) {
+	return time.Parse("2006-01", monthStr)
+}
+
+// Helper function to convert model to response
+func convertBudgetToResponse(budget *models.Budget) BudgetResponse {
+	response := BudgetResponse{
+		ID:            budget.ID.String(),
+		MonthYear:     budget.MonthYear.Format("2006-01"),
+		NeedsBudget:   budget.NeedsBudget,
+		WantsBudget:   budget.WantsBudget,
+		SavingsBudget: budget.SavingsBudget,
+		TotalBudget:   budget.Total(),
+		Version:       budget.Version,
+		Status:        string(budget.Status),
+		CreatedAt:     budget.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     budget.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if budget.StatusChangedAt != nil {
+		statusChangedAt := budget.StatusChangedAt.Format(time.RFC3339)
+		response.StatusChangedAt = &statusChangedAt
+	}
+
+	response.CategoryBudgets = make([]CategoryBudgetResponse, len(budget.CategoryBudgets))
+	for i := range budget.CategoryBudgets {
+		response.CategoryBudgets[i] = convertCategoryBudgetToResponse(&budget.CategoryBudgets[i])
+	}
+
+	return response
+}
+
+// isBudgetValidationError reports whether a budget service error is caused by the input
+func isBudgetValidationError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "cannot be negative") || strings.Contains(msg, "must be positive") ||
+		strings.Contains(msg, "invalid") || strings.Contains(msg, "must be between") ||
+		strings.Contains(msg, "required") || strings.Contains(msg, "not enough data")
+}
+
+// Create godoc
+// @Summary Create a budget
+// @Description Creates the 50/30/20 budget of a month for the authenticated user
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateBudgetRequest true "Budget data"
+// @Success 201 {object} BudgetResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "A budget already exists for this month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets [post]
+func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseMonth(req.MonthYear)
+	if err != nil {
+		http.Error(w, "Invalid month_year format, use YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	budget := &models.Budget{
+		MonthYear:     month,
+		NeedsBudget:   req.NeedsBudget,
+		WantsBudget:   req.WantsBudget,
+		SavingsBudget: req.SavingsBudget,
+	}
+
+	if err := h.services.Budgets.Create(userID, budget); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if isBudgetValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error creating budget", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(convertBudgetToResponse(budget))
+}
+
+// GetAll godoc
+// @Summary Get all budgets
+// @Description Gets the budgets of the authenticated user, newest month first
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param year query int false "Only budgets of this year"
+// @Param include_deleted query bool false "Include deleted budgets"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} BudgetsListResponse
+// @Failure 400 {string} string "Invalid year or pagination parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
+// @Router /api/v1/budgets [get]
+func (h *BudgetHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if listNotModified(w, r, h.services, userID, "budgets", "category_budgets") {
+		return
+	}
+
+	var year *int
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		parsed, err := parseIntParam(yearStr)
+		if err != nil {
+			http.Error(w, "Invalid year parameter", http.StatusBadRequest)
+			return
+		}
+		year = &parsed
+	}
+	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budgets, info, err := h.services.Budgets.GetAll(userID, year, includeDeleted, page)
+	if err != nil {
+		http.Error(w, "Error retrieving budgets", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]BudgetResponse, len(budgets))
+	for i, budget := range budgets {
+		responses[i] = convertBudgetToResponse(&budget)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BudgetsListResponse{Budgets: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
+
+// GetByID godoc
+// @Summary Get a budget by ID
+// @Description Gets a specific budget of the authenticated user
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Success 200 {object} BudgetResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found"
+// @Router /api/v1/budgets/{id} [get]
+func (h *BudgetHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/budgets/")
+	if id == "" {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.services.Budgets.GetByID(userID, id)
+	if err != nil {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	writeVersionedJSON(w, budget.Version, convertBudgetToResponse(budget))
+}
+
+// Update godoc
+// @Summary Update a budget
+// @Description Partially updates the amounts of a budget of the authenticated user
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Param request body UpdateBudgetRequest true "Fields to update"
+// @Param If-Match header string false "ETag of the budget as read; can replace version"
+// @Success 200 {object} BudgetResponse
+// @Failure 400 {string} string "Invalid request body or missing version"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found"
+// @Failure 409 {object} VersionConflictResponse{current=BudgetResponse} "The budget changed since that version"
+// @Failure 412 {string} string "If-Match doesn't match the current budget"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{id} [patch]
+func (h *BudgetHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/budgets/")
+	if id == "" {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existingBudget, err := h.services.Budgets.GetByID(userID, id)
+	if err != nil {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	// Start from the current amounts and apply the provided fields
+	budget := *existingBudget
+	if req.NeedsBudget != nil {
+		budget.NeedsBudget = *req.NeedsBudget
+	}
+	if req.WantsBudget != nil {
+		budget.WantsBudget = *req.WantsBudget
+	}
+	if req.SavingsBudget != nil {
+		budget.SavingsBudget = *req.SavingsBudget
+	}
+	budget.Version, err = requestVersion(r, req.Version, existingBudget.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedBudget, err := h.services.Budgets.Patch(userID, id, &budget)
+	if errors.Is(err, services.ErrVersionConflict) {
+		if current, err := h.services.Budgets.GetByID(userID, id); err == nil {
+			writeVersionConflict(w, req.Version, convertBudgetToResponse(current))
+			return
+		}
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Budget not found", http.StatusNotFound)
+		} else if isBudgetValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error updating budget", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeVersionedJSON(w, updatedBudget.Version, convertBudgetToResponse(updatedBudget))
+}
+
+// Delete godoc
+// @Summary Delete a budget
+// @Description Soft deletes a budget of the authenticated user
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{id} [delete]
+func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/budgets/")
+	if id == "" {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Budgets.SoftDelete(userID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Budget not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error deleting budget", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore godoc
+// @Summary Restore a budget
+// @Description Restores a deleted budget of the authenticated user if its month has no other budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Success 200 {object} BudgetResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found"
+// @Failure 409 {string} string "A budget already exists for this month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{id}/restore [post]
+func (h *BudgetHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/budgets/")
+	if id == "" {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.services.Budgets.Restore(userID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Budget not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error restoring budget", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertBudgetToResponse(budget))
+}
+
+// PlanBudgetsHandler godoc
+// @Summary Plan budgets for several months
+// @Description Previews (confirm=false) or creates (confirm=true) the budgets of the next N months from a template or a suggestion. Creation is transactional; months that already have a budget fail the plan, are skipped or overwritten according to on_conflict.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body BudgetPlanRequest true "Plan options"
+// @Success 200 {object} dto.BudgetPlan "Preview"
+// @Success 201 {object} dto.BudgetPlan "Applied plan"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {object} dto.BudgetPlan "Plan conflicts with existing budgets"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/plan [post]
+func (h *Handlers) PlanBudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BudgetPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startMonth := models.MonthStart(h.services.UserNow(userID)).AddDate(0, 1, 0)
+	if req.StartMonth != "" {
+		parsed, err := parseMonth(req.StartMonth)
+		if err != nil {
+			http.Error(w, "Invalid start_month format, use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		startMonth = parsed
+	}
+
+	opts := services.BudgetPlanOptions{
+		StartMonth: startMonth,
+		Months:     req.Months,
+		Source:     req.Source,
+		OnConflict: req.OnConflict,
+	}
+	if req.Template != nil {
+		opts.Template = &models.Budget{
+			NeedsBudget:   req.Template.NeedsBudget,
+			WantsBudget:   req.Template.WantsBudget,
+			SavingsBudget: req.Template.SavingsBudget,
+		}
+	}
+
+	var plan *dto.BudgetPlan
+	var err error
+	status := http.StatusOK
+	if req.Confirm {
+		plan, err = h.services.ApplyBudgetPlan(userID, opts)
+		status = http.StatusCreated
+	} else {
+		plan, err = h.services.PreviewBudgetPlan(userID, opts)
+	}
+
+	if err != nil {
+		if errors.Is(err, services.ErrBudgetPlanConflict) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(plan)
+		} else if isBudgetValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error planning budgets", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GetBudgetComplianceHandler godoc
+// @Summary Get monthly budget compliance history
+// @Description Returns the stored compliance results of closed months (spent vs time-weighted budget per line, plus each category budget line) for long-term adherence charts. Defaults to the last 12 months. With year and month, returns that month's budget vs actual instead (dto.MonthlyBudgetCompliance), computed live so the current month shows spending to date.
+// @Tags budgets
+// @Produce json
+// @Security bearerAuth
+// @Param from query string false "First month (YYYY-MM)"
+// @Param to query string false "Last month (YYYY-MM)"
+// @Param year query int false "Year of a single month (with month)"
+// @Param month query int false "Month (1-12, with year)"
+// @Success 200 {object} dto.BudgetComplianceHistory
+// @Failure 400 {string} string "Invalid month format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found for this month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/compliance [get]
+func (h *Handlers) GetBudgetComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has("year") || query.Has("month") {
+		h.getMonthlyBudgetCompliance(w, r, userID)
+		return
+	}
+
+	to := models.MonthStart(h.services.UserNow(userID)).AddDate(0, -1, 0)
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := parseMonth(value)
+		if err != nil {
+			http.Error(w, "Invalid to format, use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, -11, 0)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := parseMonth(value)
+		if err != nil {
+			http.Error(w, "Invalid from format, use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.services.GetBudgetCompliance(userID, from, to)
+	if err != nil {
+		http.Error(w, "Error retrieving budget compliance", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// getMonthlyBudgetCompliance serves GET /api/v1/budgets/compliance?year=&month=, the budget of
+// one month against its actual spending
+func (h *Handlers) getMonthlyBudgetCompliance(w http.ResponseWriter, r *http.Request, userID string) {
+	year, err := parseIntParam(r.URL.Query().Get("year"))
+	if err != nil || year < 1 {
+		http.Error(w, "Invalid year parameter", http.StatusBadRequest)
+		return
+	}
+	month, err := parseIntParam(r.URL.Query().Get("month"))
+	if err != nil || month < 1 || month > 12 {
+		http.Error(w, "Invalid month parameter (must be 1-12)", http.StatusBadRequest)
+		return
+	}
+
+	compliance, err := h.services.ValidateMonthlyBudgetCompliance(userID, year, time.Month(month))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Budget not found for this month", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error retrieving budget compliance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(compliance)
+}
+
+// BackfillBudgetComplianceHandler godoc
+// @Summary Backfill monthly budget compliance
+// @Description Computes and stores the compliance of every past month with a budget, applying each budget revision for the part of the month it was in force. Months already stored are skipped unless recompute=true (e.g. after editing old expenses).
+// @Tags budgets
+// @Produce json
+// @Security bearerAuth
+// @Param recompute query bool false "Recompute months that are already stored"
+// @Success 200 {object} dto.BudgetComplianceBackfill
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/compliance/backfill [post]
+func (h *Handlers) BackfillBudgetComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	recompute := r.URL.Query().Get("recompute") == "true"
+	result, err := h.services.BackfillBudgetCompliance(userID, recompute)
+	if err != nil {
+		http.Error(w, "Error backfilling budget compliance", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// ReopenMonthRequest tells why a closed month is opened again
+type ReopenMonthRequest struct {
+	Reason *string `json:"reason,omitempty" example:"Late receipt from the card statement"`
+}
+
+// writeMonthClosed answers 409 when err is about an expense in a closed month, reporting
+// whether it did
+func writeMonthClosed(w http.ResponseWriter, err error) bool {
+	var closedErr *services.MonthClosedError
+	if !errors.As(err, &closedErr) {
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusConflict)
+	return true
+}
+
+// closeMonthFromPath reads the month of /api/v1/budgets/{month}/...
+func closeMonthFromPath(w http.ResponseWriter, r *http.Request) (string, time.Time, bool) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", time.Time{}, false
+	}
+	month, err := parseMonth(extractIDFromPath(r.URL.Path, "/api/v1/budgets/"))
+	if err != nil {
+		http.Error(w, "Invalid month format, use YYYY-MM", http.StatusBadRequest)
+		return "", time.Time{}, false
+	}
+	return userID, month, true
+}
+
+// CloseMonthHandler godoc
+// @Summary Close a month
+// @Description Freezes a past month: its budget compliance is stored and the final actuals against the budget are snapshotted into the close report. Expenses dated in the month can't be created, edited, deleted or restored until it's reopened. Months are also closed automatically a few days after they end when they have a budget (MONTH_CLOSE_GRACE_DAYS, 5 by default). Closing a reopened month takes a new snapshot
+// @Tags budgets
+// @Produce json
+// @Security bearerAuth
+// @Param month path string true "Month (YYYY-MM)"
+// @Success 201 {object} models.BudgetClose
+// @Failure 400 {string} string "Invalid month"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Month already closed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{month}/close [post]
+func (h *Handlers) CloseMonthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, month, ok := closeMonthFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.services.CloseMonth(userID, month, models.BudgetCloseManual)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err.Error() == "month is already closed":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Error closing month", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ReopenMonthHandler godoc
+// @Summary Reopen a closed month
+// @Description Unlocks the expenses of a closed month. The close report keeps the snapshot, with reopened_at set, until the month is closed again; the scheduled close leaves reopened months alone
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param month path string true "Month (YYYY-MM)"
+// @Param request body ReopenMonthRequest false "Reason for reopening"
+// @Success 200 {object} models.BudgetClose
+// @Failure 400 {string} string "Invalid month or request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Month not closed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{month}/reopen [post]
+func (h *Handlers) ReopenMonthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, month, ok := closeMonthFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req ReopenMonthRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.services.ReopenMonth(userID, month, req.Reason)
+	if err != nil {
+		if errors.Is(err, services.ErrMonthNotClosed) {
+			http.Error(w, "Month not closed", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error reopening month", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetMonthCloseReportHandler godoc
+// @Summary Closing report of a month
+// @Description Returns the snapshot taken when the month was last closed: budget and spending per line and category, total income, total spent, net and expense count. reopened_at is set while the month is open again
+// @Tags budgets
+// @Produce json
+// @Security bearerAuth
+// @Param month path string true "Month (YYYY-MM)"
+// @Success 200 {object} models.BudgetClose
+// @Failure 400 {string} string "Invalid month"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Month not closed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{month}/close-report [get]
+func (h *Handlers) GetMonthCloseReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, month, ok := closeMonthFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.services.GetMonthCloseReport(userID, month)
+	if err != nil {
+		if errors.Is(err, services.ErrMonthNotClosed) {
+			http.Error(w, "Month not closed", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error getting close report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetCashFlowHandler godoc
+// @Summary Monthly cash-flow statement
+// @Description Combines the incomes, expenses, fixed expenses and transfers of a month: opening balances, inflows with the income broken down by source (salary, freelance, dividends...), outflows by expense type, net change and closing balances per bank account. Amounts are cash basis, so expenses count in full and refunds are inflows of the month they were received. Fixed expenses already posted are part of the outflows; pending ones are due later in the month. Accounts kept by hand have no balances, and incomes without an account count as inflows of none. Defaults to the current month.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param year query int false "Year, e.g. 2024"
+// @Param month query int false "Month (1-12)"
+// @Success 200 {object} dto.CashFlowReport
+// @Failure 400 {string} string "Invalid year or month"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/reports/cash-flow [get]
+func (h *Handlers) GetCashFlowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := h.services.UserNow(userID)
+	year, month := now.Year(), int(now.Month())
+	if value := r.URL.Query().Get("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid month", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	report, err := h.services.GetMonthlyCashFlow(r.Context(), userID, year, month)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// The client went away; nothing left to answer
+			logger.Info("Cash flow report cancelled for user %s", userID)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error calculating cash flow", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
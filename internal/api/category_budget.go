@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type SetCategoryBudgetRequest struct {
+	Amount models.Money `json:"amount" example:"250.00"`
+}
+
+type CategoryBudgetResponse struct {
+	CategoryID   string       `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	CategoryName string       `json:"category_name" example:"Groceries"`
+	ExpenseType  string       `json:"expense_type" example:"needs"`
+	Amount       models.Money `json:"amount" example:"250.00"`
+}
+
+func convertCategoryBudgetToResponse(line *models.CategoryBudget) CategoryBudgetResponse {
+	return CategoryBudgetResponse{
+		CategoryID:   line.CategoryID.String(),
+		CategoryName: line.Category.Name,
+		ExpenseType:  string(line.Category.ExpenseType),
+		Amount:       line.Amount,
+	}
+}
+
+// writeCategoryBudgetError maps category budget service errors to responses
+func writeCategoryBudgetError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing category budget", http.StatusInternalServerError)
+	}
+}
+
+// GetCategoryBudgetReportHandler godoc
+// @Summary Category budget lines vs actual spending
+// @Description Compares each category line of a monthly budget with the net spending of the category in that month. Expenses of trips left out of the budget don't count.
+// @Tags budgets
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Success 200 {object} dto.CategoryBudgetReport
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{id}/categories [get]
+func (h *Handlers) GetCategoryBudgetReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.services.GetCategoryBudgetReport(userID, extractIDFromPath(r.URL.Path, "/api/v1/budgets/"))
+	if err != nil {
+		writeCategoryBudgetError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// CategoryBudgetHandler godoc
+// @Summary Set or remove a category budget line
+// @Description PUT sets the budget of a category in a monthly budget, creating the line when missing (201) or updating it (200). DELETE removes the line. Category lines are optional and sit on top of the needs/wants/savings lines; monthly compliance only counts a month as within budget when every category line is within too.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Budget ID"
+// @Param category_id path string true "Category ID"
+// @Param request body SetCategoryBudgetRequest false "Amount of the line (PUT only)"
+// @Success 200 {object} CategoryBudgetResponse
+// @Success 201 {object} CategoryBudgetResponse
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid amount or category ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Budget, category or line not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/budgets/{id}/categories/{category_id} [put]
+// @Router /api/v1/budgets/{id}/categories/{category_id} [delete]
+func (h *Handlers) CategoryBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	budgetID := extractIDFromPath(r.URL.Path, "/api/v1/budgets/")
+	categoryID := extractIDFromPath(r.URL.Path, "/api/v1/budgets/"+budgetID+"/categories/")
+
+	switch r.Method {
+	case http.MethodPut:
+		var req SetCategoryBudgetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		line, created, err := h.services.SetCategoryBudget(userID, budgetID, categoryID, req.Amount)
+		if err != nil {
+			writeCategoryBudgetError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(convertCategoryBudgetToResponse(line))
+
+	case http.MethodDelete:
+		if err := h.services.DeleteCategoryBudget(userID, budgetID, categoryID); err != nil {
+			writeCategoryBudgetError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"strings"
 	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
 )
 
 // Common request structures
@@ -20,11 +22,21 @@ type ErrorResponse struct {
 type BankAccountResponse struct {
 	ID          string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	AccountName string  `json:"account_name" example:"Main Checking"`
-	Balance     float64 `json:"balance" example:"2500.00"`
+	Balance     models.Money `json:"balance" example:"2500.00"`
 }
 
 // Common helper functions
 
+// allowedStatuses lists the statuses a record can be changed to from its current one
+func allowedStatuses(machine *models.StatusMachine, current models.Status) []string {
+	next := machine.AllowedNext(current)
+	statuses := make([]string, len(next))
+	for i, status := range next {
+		statuses[i] = status.String()
+	}
+	return statuses
+}
+
 // parseDate parses a date in format YYYY-MM-DD
 func parseDate(dateStr string) (time.Time, error) {
 	const layout = "2006-01-02"
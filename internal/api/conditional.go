@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/middleware"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// listNotModified sets the ETag of a list response from the state of the user's rows in tables
+// (the listed records and the ones embedded in them) and of their settings, which shape how
+// amounts and dates are listed, as stored by svc, so it changes with any write or deletion.
+// When the request's If-None-Match lists it, it answers 304 and returns true. Last-Modified is
+// sent for information only: a second-resolution timestamp misses writes in the same second and
+// deletions, so If-Modified-Since never gets a 304
+func listNotModified(w http.ResponseWriter, r *http.Request, svc *services.Services, userID string, tables ...string) bool {
+	state, err := svc.GetListState(userID, append(tables, "user_preferences")...)
+	if err != nil {
+		logger.Warn("Can't get the state of %v: %v", tables, err)
+		return false
+	}
+
+	if state.LastModified != nil {
+		w.Header().Set("Last-Modified", state.LastModified.UTC().Format(http.TimeFormat))
+	}
+	etag := middleware.ListETag(r.URL.RawQuery, state.Version())
+	w.Header().Set("ETag", etag)
+	if !middleware.NoneMatch(r, etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// VersionConflictResponse is sent with 409 Conflict when an update is based on an outdated
+// version of the record
+type VersionConflictResponse struct {
+	Error   string      `json:"error" example:"version conflict: the record was changed since it was read"`
+	Current interface{} `json:"current"` // The record as it is now, including its version
+}
+
+// writeVersionConflict answers an update that lost the optimistic lock: 409 with the current
+// record when the version came in the body, 412 when it came from If-Match, like the check
+// ConditionalRequestMiddleware does before the update
+func writeVersionConflict(w http.ResponseWriter, bodyVersion *int64, current interface{}) {
+	if bodyVersion == nil {
+		http.Error(w, "Precondition failed: the resource changed since it was read", http.StatusPreconditionFailed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(VersionConflictResponse{Error: services.ErrVersionConflict.Error(), Current: current})
+}
+
+// writeVersionedJSON writes a record that has an optimistic-lock version with an ETag carrying
+// that version (see middleware.VersionedETag)
+func writeVersionedJSON(w http.ResponseWriter, version int64, record interface{}) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		logger.Error("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", middleware.VersionedETag(version, body))
+	w.Write(body)
+}
+
+// requestVersion returns the version an update is based on: the one in the body or the one in
+// the If-Match ETag. The ETag was already compared with the current one by
+// ConditionalRequestMiddleware, but the record can change after that check, so the update
+// must still be locked on the version the client read. "*" matches whatever is current
+func requestVersion(r *http.Request, version *int64, current int64) (int64, error) {
+	if version != nil {
+		if *version < 1 {
+			return 0, errors.New("invalid version: must be positive")
+		}
+		return *version, nil
+	}
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, errors.New("invalid request: version is required, in the body or through If-Match")
+	}
+	if ifMatch == "*" {
+		return current, nil
+	}
+	if etagVersion, ok := middleware.ETagVersion(ifMatch); ok {
+		return etagVersion, nil
+	}
+	return 0, errors.New("invalid If-Match: must be a single ETag of the record")
+}
@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/api"
+	"github.com/Osminalx/fluxio/internal/middleware"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+// TestSecondWriterWithSameETagFails has two clients update an account with the ETag they both
+// read. The second update must fail with 412 whether it arrives after the first one or slips
+// past the middleware's check before the first one commits
+func TestSecondWriterWithSameETagFails(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(100))
+	path := "/api/v1/bank-accounts/" + account.ID.String()
+
+	handlers := api.NewHandlers(h.Services)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetBankAccountByIDHandler(w, r)
+		default:
+			handlers.UpdateBankAccountHandler(w, r)
+		}
+	})
+	conditional := middleware.ConditionalRequestMiddleware(handler)
+	serve := func(h http.Handler, method, body, ifMatch string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request = request.WithContext(context.WithValue(request.Context(), "userID", user.ID.String()))
+		if ifMatch != "" {
+			request.Header.Set("If-Match", ifMatch)
+		}
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	read := serve(conditional, http.MethodGet, "", "")
+	etag := read.Header().Get("ETag")
+	if read.Code != http.StatusOK || etag == "" {
+		t.Fatalf("reading the account: %d with ETag %q", read.Code, etag)
+	}
+
+	if first := serve(conditional, http.MethodPatch, `{"account_name":"First"}`, etag); first.Code != http.StatusOK {
+		t.Fatalf("first writer got %d: %s", first.Code, first.Body)
+	}
+	if second := serve(conditional, http.MethodPatch, `{"account_name":"Second"}`, etag); second.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second writer after the first got %d, want 412", second.Code)
+	}
+	// As if the second writer passed the middleware's check before the first one committed
+	if second := serve(handler, http.MethodPatch, `{"account_name":"Second"}`, etag); second.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second writer racing the first got %d, want 412", second.Code)
+	}
+
+	current := serve(conditional, http.MethodGet, "", "")
+	if !strings.Contains(current.Body.String(), `"First"`) {
+		t.Fatalf("account after both writers: %s", current.Body)
+	}
+}
+
+// TestListETagFollowsWritesAndDeletions checks that a list's If-None-Match only gets 304 while
+// nothing changed, including the writes a second-resolution Last-Modified misses
+func TestListETagFollowsWritesAndDeletions(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	handlers := api.NewHandlers(h.Services)
+	conditional := middleware.ConditionalRequestMiddleware(http.HandlerFunc(handlers.GetAllBankAccountsHandler))
+
+	cases := []struct {
+		name       string
+		change     func(t *testing.T, user *models.User, account *models.BankAccount)
+		wantStatus int
+	}{
+		{
+			name:       "unchanged list",
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "write in the same second as the latest one",
+			change: func(t *testing.T, user *models.User, account *models.BankAccount) {
+				if err := h.DB.Exec("UPDATE bank_accounts SET updated_at = updated_at + interval '500 milliseconds' WHERE id = ?", account.ID).Error; err != nil {
+					t.Fatalf("updating account: %v", err)
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "hard deletion",
+			change: func(t *testing.T, user *models.User, account *models.BankAccount) {
+				if err := h.Services.HardDeleteBankAccount(user.ID.String(), account.ID.String()); err != nil {
+					t.Fatalf("deleting account: %v", err)
+				}
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			h.CreateBankAccount(t, user, models.NewMoney(100))
+			account := h.CreateBankAccount(t, user, models.NewMoney(50))
+			// Both accounts last changed at the same whole second
+			if err := h.DB.Exec("UPDATE bank_accounts SET updated_at = date_trunc('second', now()) - interval '1 hour' WHERE user_id = ?", user.ID).Error; err != nil {
+				t.Fatalf("settling accounts: %v", err)
+			}
+			list := func(ifNoneMatch string) *httptest.ResponseRecorder {
+				request := httptest.NewRequest(http.MethodGet, "/api/v1/bank-accounts", nil)
+				request = request.WithContext(context.WithValue(request.Context(), "userID", user.ID.String()))
+				if ifNoneMatch != "" {
+					request.Header.Set("If-None-Match", ifNoneMatch)
+				}
+				recorder := httptest.NewRecorder()
+				conditional.ServeHTTP(recorder, request)
+				return recorder
+			}
+
+			read := list("")
+			etag := read.Header().Get("ETag")
+			if read.Code != http.StatusOK || etag == "" {
+				t.Fatalf("listing accounts: %d with ETag %q", read.Code, etag)
+			}
+			if tc.change != nil {
+				tc.change(t, user, account)
+			}
+
+			again := list(etag)
+			if again.Code != tc.wantStatus {
+				t.Fatalf("listing with If-None-Match got %d, want %d", again.Code, tc.wantStatus)
+			}
+			if changed := again.Header().Get("ETag") != etag; changed != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("ETag went from %s to %s", etag, again.Header().Get("ETag"))
+			}
+		})
+	}
+}
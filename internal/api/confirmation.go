@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// ConfirmationRequiredResponse is returned with 428 when an entry is above the user's
+// confirmation threshold
+type ConfirmationRequiredResponse struct {
+	Error        string       `json:"error" example:"confirmation_required"`
+	Message      string       `json:"message" example:"This amount is above your confirmation threshold. Resend the same request with confirm_token to create it"`
+	Kind         string       `json:"kind" example:"expense"`
+	Amount       models.Money `json:"amount" example:"15000.00"`
+	Threshold    models.Money `json:"threshold" example:"5000.00"`
+	ConfirmToken string       `json:"confirm_token" example:"1705314600.0b6f4d1e-8a5c-4f3e-9d2a-6c1b7e4a9f30.5f2b..."` // Confirms this entry once
+	ExpiresAt    string       `json:"expires_at" example:"2024-01-15T10:40:00Z"`
+}
+
+// writeConfirmationRequired answers with the confirm token when err asks for a confirmation
+// and reports whether it did
+func writeConfirmationRequired(w http.ResponseWriter, err error) bool {
+	var confirmErr *services.ConfirmationRequiredError
+	if !errors.As(err, &confirmErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionRequired)
+	json.NewEncoder(w).Encode(ConfirmationRequiredResponse{
+		Error:        "confirmation_required",
+		Message:      "This amount is above your confirmation threshold. Resend the same request with confirm_token to create it",
+		Kind:         confirmErr.Kind,
+		Amount:       confirmErr.Amount,
+		Threshold:    confirmErr.Threshold,
+		ConfirmToken: confirmErr.Token,
+		ExpiresAt:    confirmErr.ExpiresAt.Format(time.RFC3339),
+	})
+	return true
+}
+
+// writeConfirmTokenUsed answers a write whose confirm token was spent by another one in the
+// meantime as if it came without one: 428 with a new token, which confirm asks for. It reports
+// whether err was that
+func writeConfirmTokenUsed(w http.ResponseWriter, err error, confirm func() error) bool {
+	if !errors.Is(err, services.ErrConfirmTokenUsed) {
+		return false
+	}
+	if !writeConfirmationRequired(w, confirm()) {
+		http.Error(w, "Confirm token already used", http.StatusConflict)
+	}
+	return true
+}
+
+// ConfirmationThresholdsHandler godoc
+// @Summary Get or set the confirmation thresholds
+// @Description GET returns the amounts above which creating an expense or a transfer needs a confirmation step. PUT replaces both; null turns the confirmation off for that kind of entry
+// @Tags preferences
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body dto.ConfirmationThresholds false "Thresholds (PUT only)"
+// @Success 200 {object} dto.ConfirmationThresholds
+// @Failure 400 {string} string "Invalid threshold"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/preferences/confirmation-thresholds [get]
+// @Router /api/v1/preferences/confirmation-thresholds [put]
+func (h *Handlers) ConfirmationThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		thresholds, err := h.services.GetConfirmationThresholds(userID)
+		if err != nil {
+			http.Error(w, "Error getting confirmation thresholds", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(thresholds)
+
+	case http.MethodPut:
+		var req dto.ConfirmationThresholds
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		thresholds, err := h.services.SetConfirmationThresholds(userID, req)
+		if err != nil {
+			logger.Error("Error updating confirmation thresholds: %v", err)
+			if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating confirmation thresholds", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(thresholds)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
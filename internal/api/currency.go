@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CurrenciesListResponse struct {
+	Currencies []models.Currency `json:"currencies"`
+	Count      int               `json:"count" example:"10"`
+}
+
+type SetUserCurrencyRequest struct {
+	Currency string `json:"currency" example:"JPY"`
+}
+
+// GetCurrenciesHandler godoc
+// @Summary List currencies
+// @Description Lists the supported currencies with their minor units (decimals to display) and rounding rule
+// @Tags currencies
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} CurrenciesListResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Router /api/v1/currencies [get]
+func (h *Handlers) GetCurrenciesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currencies := h.services.GetCurrencies()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CurrenciesListResponse{Currencies: currencies, Count: len(currencies)})
+}
+
+// UserCurrencyHandler godoc
+// @Summary Get or set the user's currency
+// @Description GET returns the currency the user's amounts are rounded and displayed in. PUT changes it; stored amounts are not converted.
+// @Tags currencies
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body SetUserCurrencyRequest false "ISO 4217 code (PUT only)"
+// @Success 200 {object} models.Currency
+// @Failure 400 {string} string "Invalid currency"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/users/me/currency [get]
+// @Router /api/v1/users/me/currency [put]
+func (h *Handlers) UserCurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var currency *models.Currency
+	switch r.Method {
+	case http.MethodGet:
+		currency = h.services.GetUserCurrency(userID)
+
+	case http.MethodPut:
+		var req SetUserCurrencyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		currency, err = h.services.SetUserCurrency(userID, req.Currency)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if strings.Contains(err.Error(), "not found") {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, "Error updating currency", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currency)
+}
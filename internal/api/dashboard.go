@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// dashboardStreamCheckInterval is how often a stream checks the stored dashboard, catching
+// refreshes made by other instances, and keeps the connection alive
+const dashboardStreamCheckInterval = 15 * time.Second
+
+// GetDashboardHandler godoc
+// @Summary Get the dashboard
+// @Description Returns the figures of the dashboard widgets for the current month: income, spend and budget per expense type, the fixed expenses due in the next 7 days and the savings goals. They are kept up to date as expenses, incomes, budgets and goal fundings are recorded, so reading them is cheap; version increases with every change
+// @Tags dashboard
+// @Produce json
+// @Security bearerAuth
+// @Param group_id query string false "Only count spend, income and upcoming bills of the accounts in this account group"
+// @Success 200 {object} dto.DashboardState
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Account group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/dashboard [get]
+func (h *Handlers) GetDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, ok := h.accountGroupFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	state, err := h.services.GetAccountsDashboardState(userID, accounts)
+	if err != nil {
+		http.Error(w, "Error getting dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// writeDashboardEvent sends the state as a server-sent "dashboard" event
+func writeDashboardEvent(w http.ResponseWriter, controller *http.ResponseController, state *dto.DashboardState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: dashboard\ndata: %s\n\n", state.Version, data); err != nil {
+		return err
+	}
+	return controller.Flush()
+}
+
+// StreamDashboardHandler godoc
+// @Summary Follow dashboard changes
+// @Description Server-sent events stream. Sends the dashboard as a "dashboard" event right away and again whenever it changes, with the version as the event ID. Comments are sent periodically to keep the connection open
+// @Tags dashboard
+// @Produce text/event-stream
+// @Security bearerAuth
+// @Param group_id query string false "Only count spend, income and upcoming bills of the accounts in this account group"
+// @Success 200 {object} dto.DashboardState
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Account group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/dashboard/stream [get]
+func (h *Handlers) StreamDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, ok := h.accountGroupFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// Subscribe before reading the state so no refresh slips in between
+	updates, cancel := services.SubscribeDashboard(userID)
+	defer cancel()
+
+	state, err := h.services.GetAccountsDashboardState(userID, accounts)
+	if err != nil {
+		http.Error(w, "Error getting dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := writeDashboardEvent(w, controller, state); err != nil {
+		logger.Warn("Dashboard stream of user %s can't be flushed: %v", userID, err)
+		return
+	}
+	sent := state.Version
+
+	ticker := time.NewTicker(dashboardStreamCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-services.StreamsClosing():
+			return
+		case <-updates:
+		case <-ticker.C:
+		}
+
+		state, err := h.services.GetAccountsDashboardState(userID, accounts)
+		if err != nil {
+			continue
+		}
+		if state.Version == sent {
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+			continue
+		}
+		if err := writeDashboardEvent(w, controller, state); err != nil {
+			return
+		}
+		sent = state.Version
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// DashboardConfigHandler godoc
+// @Summary Get or customize the dashboard layout
+// @Description GET returns the ordered dashboard widgets of the authenticated user, disabled ones included. PUT stores a new order and visibility; widgets left out keep their default state after the listed ones. DELETE restores the default layout.
+// @Tags ui
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body services.DashboardPreferences false "Widgets in the desired order (PUT only)"
+// @Success 200 {object} services.DashboardConfig
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/ui/dashboard-config [get]
+// @Router /api/v1/ui/dashboard-config [put]
+// @Router /api/v1/ui/dashboard-config [delete]
+func (h *Handlers) DashboardConfigHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var config *services.DashboardConfig
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		config, err = h.services.GetDashboardConfig(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving dashboard config", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPut:
+		var req services.DashboardPreferences
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		config, err = h.services.UpdateDashboardConfig(userID, &req)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating dashboard config", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	case http.MethodDelete:
+		config, err = h.services.ResetDashboardConfig(userID)
+		if err != nil {
+			http.Error(w, "Error resetting dashboard config", http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
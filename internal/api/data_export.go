@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type DataExportResponse struct {
+	models.DataExport
+	StatusURL   string  `json:"status_url" example:"/api/v1/export/jobs/123e4567-e89b-12d3-a456-426614174000"`
+	DownloadURL *string `json:"download_url,omitempty" example:"/api/v1/export/jobs/123e4567-e89b-12d3-a456-426614174000/download"` // Once completed
+}
+
+func newDataExportResponse(export *models.DataExport) DataExportResponse {
+	response := DataExportResponse{
+		DataExport: *export,
+		StatusURL:  "/api/v1/export/jobs/" + export.ID.String(),
+	}
+	if export.Status == models.DataExportCompleted {
+		download := response.StatusURL + "/download"
+		response.DownloadURL = &download
+	}
+	return response
+}
+
+// ExportDataHandler godoc
+// @Summary Export all user data
+// @Description Downloads every record of the user that wasn't deleted (bank accounts, expenses, incomes, transfers, budgets and goals) for backups or to move to another service. format=csv (default) gives a ZIP with one CSV file per kind of record, format=json a single JSON document. Accounts above the size exported within a request (EXPORT_SYNC_MAX_RECORDS), or any account with async=true, get a background job instead: the response is 202 with the job, whose status_url tells when the file can be downloaded. Files of background exports are kept for EXPORT_RETENTION_HOURS.
+// @Tags export
+// @Produce application/zip
+// @Produce json
+// @Security bearerAuth
+// @Param format query string false "csv (default) or json"
+// @Param async query bool false "Always generate in the background"
+// @Success 200 {file} file "The export"
+// @Success 202 {object} DataExportResponse
+// @Failure 400 {string} string "Invalid format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/export [get]
+func (h *Handlers) ExportDataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.DataExportCSV
+	}
+	background := r.URL.Query().Get("async") == "true"
+
+	export, err := h.services.PrepareUserExport(userID, format, background)
+	if err != nil && !errors.Is(err, services.ErrExportTooLarge) {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error preparing export", http.StatusInternalServerError)
+		}
+		return
+	}
+	if export != nil {
+		response := newDataExportResponse(export)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", response.StatusURL)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Content-Type", services.ExportContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+services.ExportFileName(format)+`"`)
+	if err := h.services.WriteUserExport(r.Context(), w, userID, format); err != nil {
+		// The response already started, so the client only sees a truncated file
+		logger.Error("Error writing export for user %s: %v", userID, err)
+	}
+}
+
+// exportJobIDFromPath takes the export ID from /api/v1/export/jobs/{id} or /api/v1/jobs/{id}
+func exportJobIDFromPath(path string) string {
+	if id := extractIDFromPath(path, "/api/v1/jobs/"); id != "" {
+		return id
+	}
+	return extractIDFromPath(path, "/api/v1/export/jobs/")
+}
+
+// DataExportHandler godoc
+// @Summary Get a background export
+// @Description Returns the status of a background export: pending, running, completed (download_url is set), failed or cancelled
+// @Tags export
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} DataExportResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Export not found"
+// @Router /api/v1/export/jobs/{id} [get]
+// @Router /api/v1/jobs/{id} [get]
+func (h *Handlers) DataExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID := exportJobIDFromPath(r.URL.Path)
+	if exportID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.services.GetDataExport(userID, exportID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newDataExportResponse(export))
+}
+
+// CancelDataExportHandler godoc
+// @Summary Cancel a background export
+// @Description Cancels a pending or running background export. A running export stops and the file it was writing is deleted; the export is returned with status cancelled
+// @Tags export
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} DataExportResponse
+// @Failure 400 {string} string "Export already finished"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Export not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/export/jobs/{id} [delete]
+// @Router /api/v1/jobs/{id} [delete]
+func (h *Handlers) CancelDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID := exportJobIDFromPath(r.URL.Path)
+	if exportID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.services.CancelDataExport(userID, exportID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error cancelling export", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newDataExportResponse(export))
+}
+
+// DownloadDataExportHandler godoc
+// @Summary Download a background export
+// @Description Downloads the file of a completed background export
+// @Tags export
+// @Produce application/zip
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {file} file "The export"
+// @Failure 400 {string} string "Export not completed"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Export not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/export/jobs/{id}/download [get]
+func (h *Handlers) DownloadDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID := extractIDFromPath(r.URL.Path, "/api/v1/export/jobs/")
+	if exportID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	export, content, err := h.services.OpenDataExport(r.Context(), userID, exportID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error reading export", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", services.ExportContentType(export.Format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+services.ExportFileName(export.Format)+`"`)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Warn("Error sending export %s: %v", export.ID, err)
+	}
+}
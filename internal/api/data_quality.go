@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+)
+
+// DataQualityHandler godoc
+// @Summary Get or refresh the data quality report
+// @Description GET returns the latest weekly data quality report (uncategorized expenses, expenses missing an account, records pending for over two weeks and accounts with a negative balance), generating it if the user has none. POST runs the checks again right away.
+// @Tags data-quality
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} dto.DataQualityReport
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/data-quality [get]
+// @Router /api/v1/data-quality [post]
+func (h *Handlers) DataQualityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var report *dto.DataQualityReport
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		report, err = h.services.GetDataQualityReport(userID)
+	case http.MethodPost:
+		report, err = h.services.GenerateDataQualityReport(userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error retrieving data quality report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// Responses with fields tagged `deprecated:"..."`. Their use is counted per client so the
+// fields can be dropped in /api/v2 once no client gets them anymore
+func init() {
+	services.RegisterDeprecatedFields(http.MethodGet, "/api/v1/expenses/summary", ExpenseSummaryResponse{})
+}
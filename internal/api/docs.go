@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/docs"
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// SpecVersionsResponse lists the spec versions served under /docs/{version}/swagger.json
+type SpecVersionsResponse struct {
+	Current  string   `json:"current"`
+	Versions []string `json:"versions"`
+}
+
+// ServeSpec writes the OpenAPI document of a version, gzipped when the client accepts it.
+// Clients revalidate with If-None-Match
+func ServeSpec(w http.ResponseWriter, r *http.Request, version string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spec, err := services.GetAPISpec(version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", spec.ETag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if r.Header.Get("If-None-Match") == spec.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := spec.Body
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		body = spec.Gzipped
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(body)
+	}
+}
+
+// DocsHandler routes /docs/versions, /docs/diff and /docs/{version}/swagger.json
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/docs/")
+	switch {
+	case path == "versions":
+		GetSpecVersionsHandler(w, r)
+	case path == "diff":
+		GetSpecDiffHandler(w, r)
+	case strings.HasSuffix(path, "/swagger.json"):
+		ServeSpec(w, r, strings.TrimSuffix(path, "/swagger.json"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetSpecVersionsHandler godoc
+// @Summary List API spec versions
+// @Description Lists the OpenAPI spec versions served under /docs/{version}/swagger.json: the current one and the spec of each release
+// @Tags docs
+// @Produce json
+// @Success 200 {object} SpecVersionsResponse
+// @Router /docs/versions [get]
+func GetSpecVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(SpecVersionsResponse{
+		Current:  docs.CurrentSpecVersion,
+		Versions: docs.SpecVersions(),
+	})
+}
+
+// GetSpecDiffHandler godoc
+// @Summary Diff two API spec versions
+// @Description Lists the operations and models added, removed or changed between two spec versions, and whether the change breaks clients
+// @Tags docs
+// @Produce json
+// @Param from query string true "Version to compare from, e.g. 1.0"
+// @Param to query string false "Version to compare to; defaults to the current one"
+// @Success 200 {object} services.SpecDiff
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Spec version not found"
+// @Router /docs/diff [get]
+func GetSpecDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		http.Error(w, "from is required", http.StatusBadRequest)
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = docs.CurrentSpecVersion
+	}
+
+	diff, err := services.DiffAPISpecs(from, to)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(diff)
+}
@@ -2,48 +2,95 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 )
 
+// ExpenseHandler serves the expense endpoints with the services it is given
+type ExpenseHandler struct {
+	services *services.Services
+}
+
+// NewExpenseHandler returns the expense endpoints backed by the services
+func NewExpenseHandler(svc *services.Services) *ExpenseHandler {
+	return &ExpenseHandler{services: svc}
+}
+
 // Request and response structures
 type CreateExpenseRequest struct {
+	CategoryID    string                     `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        models.Money                    `json:"amount" example:"150.75"`
+	Date          string                     `json:"date" example:"2024-01-15"`
+	BankAccountID string                     `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Not needed when allocations are given
+	Description   *string                    `json:"description,omitempty" example:"Grocery shopping"`
+	OverrideCap   bool                       `json:"override_cap,omitempty" example:"false"` // Go through a hard category cap (audited)
+	Allocations   []ExpenseAllocationRequest `json:"allocations,omitempty"`                  // Split the expense across accounts; must add up to amount
+	ConfirmToken  string                     `json:"confirm_token,omitempty"`                // From the 428 response of an amount above the confirmation threshold
+	Tags          []string                   `json:"tags,omitempty" example:"vacation,work"` // Free-form; tags the user doesn't have yet are created
+}
+
+// ExpenseAllocationRequest is the portion of a split expense paid from one account
+type ExpenseAllocationRequest struct {
+	BankAccountID string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        models.Money `json:"amount" example:"50.00"`
+}
+
+type ExpenseAllocationResponse struct {
+	ID            string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	BankAccountID string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        models.Money `json:"amount" example:"50.00"`
+}
+
+// CategoryCapExceededResponse is returned with 422 when an expense hits a hard category cap
+type CategoryCapExceededResponse struct {
+	Error           string  `json:"error" example:"category_cap_exceeded"`
+	Message         string  `json:"message" example:"This expense would exceed the monthly cap of the category"`
 	CategoryID      string  `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Amount          float64 `json:"amount" example:"150.75"`
-	Date            string  `json:"date" example:"2024-01-15"`
-	BankAccountID   string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Description     *string `json:"description,omitempty" example:"Grocery shopping"`
+	MonthlyCap      models.Money `json:"monthly_cap" example:"500.00"`
+	Spent           models.Money `json:"spent" example:"420.00"`
+	Attempted       models.Money `json:"attempted" example:"150.75"`
+	Remaining       models.Money `json:"remaining" example:"80.00"`
+	OverrideAllowed bool    `json:"override_allowed" example:"true"`
 }
 
 type UpdateExpenseRequest struct {
 	CategoryID      *string  `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Amount          *float64 `json:"amount,omitempty" example:"175.50"`
+	Amount          *models.Money `json:"amount,omitempty" example:"175.50"`
 	Date            *string  `json:"date,omitempty" example:"2024-01-16"`
 	BankAccountID   *string  `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Description     *string  `json:"description,omitempty" example:"Updated description"`
+	Tags            []string `json:"tags,omitempty" example:"vacation"` // Replaces the current tags; [] removes them
 }
 
 
 
 type ExpenseResponse struct {
-	ID              string             `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	CategoryID      string             `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Amount          float64            `json:"amount" example:"150.75"`
-	Date            string             `json:"date" example:"2024-01-15"`
-	BankAccountID   string             `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Description     *string            `json:"description,omitempty" example:"Grocery shopping"`
-	Status          string             `json:"status" example:"active"`
-	StatusChangedAt *string            `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
-	CreatedAt       string             `json:"created_at" example:"2024-01-15T10:30:00Z"`
-	UpdatedAt       string             `json:"updated_at" example:"2024-01-15T10:30:00Z"`
-	Category        *CategoryResponse  `json:"category,omitempty"`
-	BankAccount     *BankAccountResponse `json:"bank_account,omitempty"`
+	ID              string                      `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	CategoryID      string                      `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount          models.Money                     `json:"amount" example:"150.75"`
+	Date            string                      `json:"date" example:"2024-01-15"`
+	BankAccountID   string                      `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Description     *string                     `json:"description,omitempty" example:"Grocery shopping"`
+	Status          string                      `json:"status" example:"active"`
+	StatusChangedAt *string                     `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	AllowedStatuses []string                    `json:"allowed_statuses" example:"pending,suspended,archived,locked,deleted"` // Statuses it can be changed to
+	CreatedAt       string                      `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt       string                      `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+	Category        *CategoryResponse           `json:"category,omitempty"`
+	BankAccount     *BankAccountResponse        `json:"bank_account,omitempty"`
+	Allocations     []ExpenseAllocationResponse `json:"allocations,omitempty"`                      // Only for split expenses
+	Attachments     []ExpenseAttachmentResponse `json:"attachments,omitempty"`                      // Receipts, without their content
+	AllocatedAmount *models.Money                    `json:"allocated_amount,omitempty" example:"50.00"` // Portion paid from the filtered account
+	Tags            []TagResponse               `json:"tags,omitempty"`
 }
 
 type CategoryResponse struct {
@@ -62,27 +109,47 @@ type ExpenseTypeResponse struct {
 type ExpensesListResponse struct {
 	Expenses []ExpenseResponse `json:"expenses"`
 	Count    int               `json:"count" example:"5"`
+	*PageResponse
 }
 
 type ExpenseSummaryResponse struct {
-	TotalAmount     float64                    `json:"total_amount" example:"1250.75"`
+	TotalAmount     models.Money                    `json:"total_amount" example:"1250.75"`
 	TotalCount      int64                      `json:"total_count" example:"25"`
-	AverageAmount   float64                    `json:"average_amount" example:"50.03"`
+	AverageAmount   models.Money                    `json:"average_amount" example:"50.03"`
 	ByExpenseType   []ExpensesByTypeResponse   `json:"by_expense_type"`
-	TopCategories   []ExpensesByCategoryResponse `json:"top_categories"`
+	GroupBy         string                     `json:"group_by" example:"category" enums:"category,account,payee,tag"`
+	TopGroups       []ExpensesByGroupResponse  `json:"top_groups"`
+	TopCategories   []ExpensesByCategoryResponse `json:"top_categories,omitempty" deprecated:"use top_groups"` // Only when grouping by category
 }
 
 type ExpensesByTypeResponse struct {
 	ExpenseTypeName string  `json:"expense_type_name" example:"Needs"`
-	TotalAmount     float64 `json:"total_amount" example:"625.00"`
-	Count           int64   `json:"count" example:"15"`
+	TotalAmount     models.Money `json:"total_amount" example:"625.00"`
+	Count           *int64  `json:"count,omitempty" example:"15"`
+}
+
+type ExpensesByGroupResponse struct {
+	Key             string  `json:"key" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name            string  `json:"name" example:"Food"`
+	ExpenseTypeName string  `json:"expense_type_name,omitempty" example:"Needs"`
+	TotalAmount     models.Money `json:"total_amount" example:"325.50"`
+	Count           *int64  `json:"count,omitempty" example:"8"`
 }
 
 type ExpensesByCategoryResponse struct {
 	CategoryName    string  `json:"category_name" example:"Food"`
 	ExpenseTypeName string  `json:"expense_type_name" example:"Needs"`
-	TotalAmount     float64 `json:"total_amount" example:"325.50"`
-	Count           int64   `json:"count" example:"8"`
+	TotalAmount     models.Money `json:"total_amount" example:"325.50"`
+	Count           *int64  `json:"count,omitempty" example:"8"`
+}
+
+type ExpenseRefundsResponse struct {
+	ExpenseID       string           `json:"expense_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ExpenseAmount   models.Money          `json:"expense_amount" example:"150.75"`
+	RefundedAmount  models.Money          `json:"refunded_amount" example:"50.00"`
+	RemainingAmount models.Money          `json:"remaining_amount" example:"100.75"`
+	Refunds         []IncomeResponse `json:"refunds"`
+	Count           int              `json:"count" example:"1"`
 }
 
 type DateRangeRequest struct {
@@ -100,12 +167,13 @@ func convertExpenseToResponse(expense *models.Expense) ExpenseResponse {
 		BankAccountID: expense.BankAccountID.String(),
 		Description:   expense.Description,
 		Status:        string(expense.Status),
-		CreatedAt:     expense.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:     expense.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:     expense.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     expense.UpdatedAt.Format(time.RFC3339),
 	}
+	response.AllowedStatuses = allowedStatuses(models.ExpenseStatusMachine, expense.Status)
 	
 	if expense.StatusChangedAt != nil {
-		statusChangedAt := expense.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+		statusChangedAt := expense.StatusChangedAt.Format(time.RFC3339)
 		response.StatusChangedAt = &statusChangedAt
 	}
 	
@@ -136,10 +204,26 @@ func convertExpenseToResponse(expense *models.Expense) ExpenseResponse {
 		}
 	}
 	
+	// Include the split across accounts, if any
+	for _, allocation := range expense.Allocations {
+		response.Allocations = append(response.Allocations, ExpenseAllocationResponse{
+			ID:            allocation.ID.String(),
+			BankAccountID: allocation.BankAccountID.String(),
+			Amount:        allocation.Amount,
+		})
+	}
+	response.AllocatedAmount = expense.AllocatedAmount
+	
+	// Include the receipts, if loaded
+	for i := range expense.Attachments {
+		response.Attachments = append(response.Attachments, convertExpenseAttachmentToResponse(&expense.Attachments[i]))
+	}
+	response.Tags = convertTagsToResponse(expense.Tags)
+	
 	return response
 }
 
-// CreateExpenseHandler godoc
+// Create godoc
 // @Summary Create a new expense
 // @Description Creates a new expense for the authenticated user
 // @Tags expense
@@ -148,11 +232,15 @@ func convertExpenseToResponse(expense *models.Expense) ExpenseResponse {
 // @Security bearerAuth
 // @Param request body CreateExpenseRequest true "Expense data"
 // @Success 201 {object} ExpenseResponse
+// @Success 202 {object} ExpenseApprovalResponse "Expense of a sub-profile held for the approval of the parent"
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Expense in a closed month"
+// @Failure 422 {object} CategoryCapExceededResponse "Hard category cap exceeded, retry with override_cap"
+// @Failure 428 {object} ConfirmationRequiredResponse "Amount above the confirmation threshold, resend with confirm_token; a token confirms a single entry"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses [post]
-func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -178,8 +266,8 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.CategoryID == "" || req.BankAccountID == "" || req.Date == "" {
-		http.Error(w, "Category ID, Bank Account ID, and Date are required", http.StatusBadRequest)
+	if req.CategoryID == "" || (req.BankAccountID == "" && len(req.Allocations) == 0) || req.Date == "" {
+		http.Error(w, "Category ID, Bank Account ID (or allocations), and Date are required", http.StatusBadRequest)
 		return
 	}
 
@@ -187,6 +275,7 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	expense := &models.Expense{
 		Amount:      req.Amount,
 		Description: req.Description,
+		Tags:        tagsFromNames(req.Tags),
 	}
 
 	// Parse UUIDs
@@ -197,7 +286,19 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		expense.CategoryID = categoryUUID
 	}
 
-	if bankAccountUUID, err := uuid.Parse(req.BankAccountID); err != nil {
+	if len(req.Allocations) > 0 {
+		for _, allocation := range req.Allocations {
+			bankAccountUUID, err := uuid.Parse(allocation.BankAccountID)
+			if err != nil {
+				http.Error(w, "Invalid bank account ID format in allocations", http.StatusBadRequest)
+				return
+			}
+			expense.Allocations = append(expense.Allocations, models.ExpenseAllocation{
+				BankAccountID: bankAccountUUID,
+				Amount:        allocation.Amount,
+			})
+		}
+	} else if bankAccountUUID, err := uuid.Parse(req.BankAccountID); err != nil {
 		http.Error(w, "Invalid bank account ID format", http.StatusBadRequest)
 		return
 	} else {
@@ -212,10 +313,46 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		expense.Date = date
 	}
 
+	// Amounts above the user's threshold are created once confirmed
+	if err := h.services.RequireExpenseConfirmation(userID, expense, req.ConfirmToken); err != nil {
+		if !writeConfirmationRequired(w, err) {
+			http.Error(w, "Error creating expense", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	// Create in the database
-	if err := services.CreateExpense(userID, expense); err != nil {
-		logger.Error("Error creating expense: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not active") {
+	if err := h.services.Expenses.Create(r.Context(), userID, expense, req.OverrideCap); err != nil {
+		logger.ErrorContext(r.Context(), "Error creating expense: %v", err)
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if writeConfirmTokenUsed(w, err, func() error {
+			return h.services.RequireExpenseConfirmation(userID, expense, req.ConfirmToken)
+		}) {
+			return
+		}
+		var capErr *services.CategoryCapExceededError
+		var approvalErr *services.ExpenseApprovalRequiredError
+		if errors.As(err, &approvalErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(convertExpenseApprovalToResponse(&approvalErr.Approval))
+		} else if errors.As(err, &capErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(CategoryCapExceededResponse{
+				Error:           "category_cap_exceeded",
+				Message:         "This expense would exceed the monthly cap of the category",
+				CategoryID:      capErr.CategoryID.String(),
+				MonthlyCap:      capErr.Cap,
+				Spent:           capErr.Spent,
+				Attempted:       capErr.Attempted,
+				Remaining:       capErr.Remaining(),
+				OverrideAllowed: true,
+			})
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not active") ||
+			strings.Contains(err.Error(), "allocation") || strings.HasPrefix(err.Error(), "invalid ") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		} else {
 			http.Error(w, "Error creating expense", http.StatusInternalServerError)
@@ -224,7 +361,7 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the created expense with relations
-	createdExpense, err := services.GetExpenseByID(userID, expense.ID.String())
+	createdExpense, err := h.services.Expenses.GetByID(userID, expense.ID.String())
 	if err != nil {
 		// If we can't get the full expense, return the basic one
 		createdExpense = expense
@@ -238,7 +375,7 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetExpenseByIDHandler godoc
+// GetByID godoc
 // @Summary Get an expense by ID
 // @Description Gets a specific expense for the authenticated user by their ID
 // @Tags expense
@@ -252,7 +389,7 @@ func CreateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Expense not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/{id} [get]
-func GetExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -272,7 +409,7 @@ func GetExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the expense
-	expense, err := services.GetExpenseByID(userID, id)
+	expense, err := h.services.Expenses.GetByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting expense: %v", err)
 		http.Error(w, "Expense not found", http.StatusNotFound)
@@ -285,7 +422,7 @@ func GetExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetAllExpensesHandler godoc
+// GetAll godoc
 // @Summary Get all expenses
 // @Description Gets all expenses for the authenticated user with option to include deleted
 // @Tags expense
@@ -293,11 +430,19 @@ func GetExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security bearerAuth
 // @Param include_deleted query boolean false "Include deleted expenses"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} ExpensesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/expenses [get]
-func GetAllExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -309,14 +454,33 @@ func GetAllExpensesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listNotModified(w, r, h.services, userID, "expenses", "categories", "bank_accounts", "tags") {
+		return
+	}
+
 	// Check parameter to include deleted
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get expenses
-	expenses, err := services.GetAllExpenses(userID, includeDeleted)
+	expenses, info, err := h.services.Expenses.GetAll(userID, includeDeleted, tags, page)
 	if err != nil {
 		logger.Error("Error getting expenses: %v", err)
-		http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -327,26 +491,33 @@ func GetAllExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ExpensesListResponse{
-		Expenses: expenseResponses,
-		Count:    len(expenseResponses),
+		Expenses:     expenseResponses,
+		Count:        len(expenseResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetActiveExpensesHandler godoc
+// GetActive godoc
 // @Summary Get active expenses
 // @Description Gets all active expenses for the authenticated user
 // @Tags expense
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} ExpensesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/active [get]
-func GetActiveExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetActive(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -358,10 +529,25 @@ func GetActiveExpensesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expenses, err := services.GetActiveExpenses(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expenses, info, err := h.services.Expenses.GetActive(userID, tags, page)
 	if err != nil {
 		logger.Error("Error getting active expenses: %v", err)
-		http.Error(w, "Error retrieving active expenses", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving active expenses", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -371,26 +557,33 @@ func GetActiveExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ExpensesListResponse{
-		Expenses: expenseResponses,
-		Count:    len(expenseResponses),
+		Expenses:     expenseResponses,
+		Count:        len(expenseResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetDeletedExpensesHandler godoc
+// GetDeleted godoc
 // @Summary Get deleted expenses
 // @Description Gets all deleted expenses for the authenticated user
 // @Tags expense
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} ExpensesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/deleted [get]
-func GetDeletedExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetDeleted(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -402,10 +595,25 @@ func GetDeletedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expenses, err := services.GetDeletedExpenses(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expenses, info, err := h.services.Expenses.GetDeleted(userID, tags, page)
 	if err != nil {
 		logger.Error("Error getting deleted expenses: %v", err)
-		http.Error(w, "Error retrieving deleted expenses", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving deleted expenses", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -415,15 +623,16 @@ func GetDeletedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ExpensesListResponse{
-		Expenses: expenseResponses,
-		Count:    len(expenseResponses),
+		Expenses:     expenseResponses,
+		Count:        len(expenseResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// UpdateExpenseHandler godoc
+// Update godoc
 // @Summary Update an expense
 // @Description Updates partially an expense for the authenticated user
 // @Tags expense
@@ -436,9 +645,10 @@ func GetDeletedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Expense not found"
+// @Failure 409 {string} string "Expense in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/{id} [patch]
-func UpdateExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -505,14 +715,23 @@ func UpdateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		expense.Description = req.Description
 	}
 
+	if req.Tags != nil {
+		expense.Tags = tagsFromNames(req.Tags)
+	}
+
 	// Update in the database
-	updatedExpense, err := services.PatchExpense(userID, id, expense)
+	updatedExpense, err := h.services.Expenses.Patch(userID, id, expense)
 	if err != nil {
 		logger.Error("Error updating expense: %v", err)
+		if writeMonthClosed(w, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Expense not found", http.StatusNotFound)
-		} else if strings.Contains(err.Error(), "not active") {
+		} else if strings.Contains(err.Error(), "not active") || strings.HasPrefix(err.Error(), "invalid ") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if errors.Is(err, services.ErrSplitExpenseLedgerChange) {
+			http.Error(w, err.Error(), http.StatusConflict)
 		} else {
 			http.Error(w, "Error updating expense", http.StatusInternalServerError)
 		}
@@ -525,7 +744,7 @@ func UpdateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// DeleteExpenseHandler godoc
+// Delete godoc
 // @Summary Delete an expense (soft delete)
 // @Description Marks an expense as deleted without permanently deleting it
 // @Tags expense
@@ -537,9 +756,10 @@ func UpdateExpenseHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid ID"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Expense not found"
+// @Failure 409 {string} string "Status transition not allowed or expense in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/{id} [delete]
-func DeleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -557,9 +777,14 @@ func DeleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := services.SoftDeleteExpense(userID, id); err != nil {
+	if err := h.services.Expenses.SoftDelete(userID, id); err != nil {
 		logger.Error("Error deleting expense: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
 			http.Error(w, "Expense not found or already deleted", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error deleting expense", http.StatusInternalServerError)
@@ -570,7 +795,7 @@ func DeleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// RestoreExpenseHandler godoc
+// Restore godoc
 // @Summary Restore a deleted expense
 // @Description Restores a previously deleted expense (soft delete)
 // @Tags expense
@@ -582,9 +807,10 @@ func DeleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid ID"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Expense not found or not deleted"
+// @Failure 409 {string} string "Expense in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/{id}/restore [post]
-func RestoreExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) Restore(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -602,9 +828,12 @@ func RestoreExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	restoredExpense, err := services.RestoreExpense(userID, id)
+	restoredExpense, err := h.services.Expenses.Restore(userID, id)
 	if err != nil {
 		logger.Error("Error restoring expense: %v", err)
+		if writeMonthClosed(w, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not deleted") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if strings.Contains(err.Error(), "not active") {
@@ -621,7 +850,7 @@ func RestoreExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// ChangeExpenseStatusHandler godoc
+// ChangeStatus godoc
 // @Summary Change the status of an expense
 // @Description Changes the status of an expense (active, inactive, deleted, etc.)
 // @Tags expense
@@ -634,9 +863,10 @@ func RestoreExpenseHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Expense not found"
+// @Failure 409 {string} string "Status transition not allowed or expense in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/{id}/status [patch]
-func ChangeExpenseStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -669,10 +899,15 @@ func ChangeExpenseStatusHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert string to Status
 	status := models.Status(req.Status)
 
-	updatedExpense, err := services.ChangeExpenseStatus(userID, id, status, req.Reason)
+	updatedExpense, err := h.services.Expenses.ChangeStatus(userID, id, status, req.Reason)
 	if err != nil {
 		logger.Error("Error changing expense status: %v", err)
-		if strings.Contains(err.Error(), "invalid status") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "invalid status") {
 			http.Error(w, "Invalid status", http.StatusBadRequest)
 		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Expense not found", http.StatusNotFound)
@@ -690,7 +925,7 @@ func ChangeExpenseStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 // === ADDITIONAL ENDPOINTS FOR SPECIALIZED QUERIES ===
 
-// GetExpensesByDateRangeHandler godoc
+// GetByDateRange godoc
 // @Summary Get expenses by date range
 // @Description Gets expenses for the authenticated user within a date range
 // @Tags expense
@@ -705,7 +940,7 @@ func ChangeExpenseStatusHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/date-range [get]
-func GetExpensesByDateRangeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetByDateRange(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -739,7 +974,7 @@ func GetExpensesByDateRangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expenses, err := services.GetExpensesByDateRange(userID, startDate, endDate, includeDeleted)
+	expenses, err := h.services.Expenses.GetByDateRange(userID, startDate, endDate, includeDeleted)
 	if err != nil {
 		logger.Error("Error getting expenses by date range: %v", err)
 		http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
@@ -760,7 +995,7 @@ func GetExpensesByDateRangeHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetExpensesByCategoryHandler godoc
+// GetByCategory godoc
 // @Summary Get expenses by category
 // @Description Gets expenses for the authenticated user filtered by category
 // @Tags expense
@@ -774,7 +1009,7 @@ func GetExpensesByDateRangeHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/category/{category_id} [get]
-func GetExpensesByCategoryHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetByCategory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -794,7 +1029,7 @@ func GetExpensesByCategoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
-	expenses, err := services.GetExpensesByCategory(userID, categoryID, includeDeleted)
+	expenses, err := h.services.Expenses.GetByCategory(userID, categoryID, includeDeleted)
 	if err != nil {
 		logger.Error("Error getting expenses by category: %v", err)
 		http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
@@ -815,7 +1050,7 @@ func GetExpensesByCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetExpensesByBankAccountHandler godoc
+// GetByBankAccount godoc
 // @Summary Get expenses by bank account
 // @Description Gets expenses for the authenticated user filtered by bank account
 // @Tags expense
@@ -829,7 +1064,7 @@ func GetExpensesByCategoryHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/bank-account/{bank_account_id} [get]
-func GetExpensesByBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetByBankAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -849,7 +1084,7 @@ func GetExpensesByBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
-	expenses, err := services.GetExpensesByBankAccount(userID, bankAccountID, includeDeleted)
+	expenses, err := h.services.Expenses.GetByBankAccount(userID, bankAccountID, includeDeleted)
 	if err != nil {
 		logger.Error("Error getting expenses by bank account: %v", err)
 		http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
@@ -870,7 +1105,7 @@ func GetExpensesByBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetMonthlyExpensesHandler godoc
+// GetMonthly godoc
 // @Summary Get monthly expenses
 // @Description Gets expenses for the authenticated user for a specific month
 // @Tags expense
@@ -885,7 +1120,7 @@ func GetExpensesByBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/monthly [get]
-func GetMonthlyExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ExpenseHandler) GetMonthly(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -919,7 +1154,7 @@ func GetMonthlyExpensesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expenses, err := services.GetMonthlyExpenses(userID, year, month, includeDeleted)
+	expenses, err := h.services.Expenses.GetMonthly(userID, year, month, includeDeleted)
 	if err != nil {
 		logger.Error("Error getting monthly expenses: %v", err)
 		http.Error(w, "Error retrieving expenses", http.StatusInternalServerError)
@@ -940,6 +1175,58 @@ func GetMonthlyExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// convertExpenseSummaryToResponse converts a service summary, dropping counts when not requested
+func convertExpenseSummaryToResponse(summary *dto.ExpenseSummary, includeCounts bool) ExpenseSummaryResponse {
+	count := func(value int64) *int64 {
+		if !includeCounts {
+			return nil
+		}
+		return &value
+	}
+
+	response := ExpenseSummaryResponse{
+		TotalAmount:   summary.TotalAmount,
+		TotalCount:    summary.TotalCount,
+		AverageAmount: summary.AverageAmount,
+		ByExpenseType: make([]ExpensesByTypeResponse, len(summary.ByExpenseType)),
+		GroupBy:       summary.GroupBy,
+		TopGroups:     make([]ExpensesByGroupResponse, len(summary.TopGroups)),
+	}
+
+	for i, item := range summary.ByExpenseType {
+		response.ByExpenseType[i] = ExpensesByTypeResponse{
+			ExpenseTypeName: item.ExpenseTypeName,
+			TotalAmount:     item.TotalAmount,
+			Count:           count(item.Count),
+		}
+	}
+
+	for i, item := range summary.TopGroups {
+		response.TopGroups[i] = ExpensesByGroupResponse{
+			Key:             item.Key,
+			Name:            item.Name,
+			ExpenseTypeName: item.ExpenseTypeName,
+			TotalAmount:     item.TotalAmount,
+			Count:           count(item.Count),
+		}
+	}
+
+	// Keep top_categories for clients that predate group_by
+	if summary.GroupBy == string(services.SummaryGroupByCategory) {
+		response.TopCategories = make([]ExpensesByCategoryResponse, len(summary.TopGroups))
+		for i, item := range summary.TopGroups {
+			response.TopCategories[i] = ExpensesByCategoryResponse{
+				CategoryName:    item.Name,
+				ExpenseTypeName: item.ExpenseTypeName,
+				TotalAmount:     item.TotalAmount,
+				Count:           count(item.Count),
+			}
+		}
+	}
+
+	return response
+}
+
 // GetExpensesSummaryHandler godoc
 // @Summary Get expenses summary
 // @Description Gets expenses summary for the authenticated user within a date range
@@ -949,12 +1236,15 @@ func GetMonthlyExpensesHandler(w http.ResponseWriter, r *http.Request) {
 // @Security bearerAuth
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param top_n query int false "Number of top groups to return (1-100, default 10)"
+// @Param group_by query string false "Grouping of the top entries: category, account, payee or tag (default category). By tag, an expense counts in each of its tags and untagged ones are left out"
+// @Param include_counts query bool false "Include expense counts per group (default true)"
 // @Success 200 {object} ExpenseSummaryResponse
 // @Failure 400 {string} string "Invalid date parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/expenses/summary [get]
-func GetExpensesSummaryHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetExpensesSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -987,56 +1277,103 @@ func GetExpensesSummaryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := services.GetExpensesSummaryByPeriod(userID, startDate, endDate)
+	opts := services.DefaultExpenseSummaryOptions()
+
+	if topNStr := r.URL.Query().Get("top_n"); topNStr != "" {
+		topN, err := parseIntParam(topNStr)
+		if err != nil || topN < 1 || topN > 100 {
+			http.Error(w, "Invalid top_n parameter (1-100)", http.StatusBadRequest)
+			return
+		}
+		opts.TopN = topN
+	}
+
+	if groupBy := r.URL.Query().Get("group_by"); groupBy != "" {
+		if !services.IsValidSummaryGroupBy(groupBy) {
+			http.Error(w, "Invalid group_by parameter. Must be one of: category, account, payee, tag", http.StatusBadRequest)
+			return
+		}
+		opts.GroupBy = services.SummaryGroupBy(groupBy)
+	}
+
+	if includeCountsStr := r.URL.Query().Get("include_counts"); includeCountsStr != "" {
+		includeCounts, err := strconv.ParseBool(includeCountsStr)
+		if err != nil {
+			http.Error(w, "Invalid include_counts parameter", http.StatusBadRequest)
+			return
+		}
+		opts.IncludeCounts = includeCounts
+	}
+
+	summary, err := h.services.GetExpensesSummaryByPeriod(userID, startDate, endDate, opts)
 	if err != nil {
 		logger.Error("Error getting expenses summary: %v", err)
 		http.Error(w, "Error retrieving summary", http.StatusInternalServerError)
 		return
 	}
 
-	// Convert the map response to structured response
-	response := ExpenseSummaryResponse{
-		TotalAmount:   summary["total_amount"].(float64),
-		TotalCount:    summary["total_count"].(int64),
-		AverageAmount: summary["average_amount"].(float64),
-	}
-
-	// Convert by expense type
-	if byExpenseType, ok := summary["by_expense_type"].([]struct {
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
-		Count           int64   `json:"count"`
-	}); ok {
-		response.ByExpenseType = make([]ExpensesByTypeResponse, len(byExpenseType))
-		for i, item := range byExpenseType {
-			response.ByExpenseType[i] = ExpensesByTypeResponse{
-				ExpenseTypeName: item.ExpenseTypeName,
-				TotalAmount:     item.TotalAmount,
-				Count:           item.Count,
-			}
-		}
+	response := convertExpenseSummaryToResponse(summary, opts.IncludeCounts)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+
+
+// GetExpenseRefundsHandler godoc
+// @Summary Get refunds of an expense
+// @Description Gets the incomes linked as refunds to an expense of the authenticated user
+// @Tags expense
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Success 200 {object} ExpenseRefundsResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Expense not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/refunds [get]
+func (h *Handlers) GetExpenseRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Convert top categories
-	if topCategories, ok := summary["top_categories"].([]struct {
-		CategoryName    string  `json:"category_name"`
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
-		Count           int64   `json:"count"`
-	}); ok {
-		response.TopCategories = make([]ExpensesByCategoryResponse, len(topCategories))
-		for i, item := range topCategories {
-			response.TopCategories[i] = ExpensesByCategoryResponse{
-				CategoryName:    item.CategoryName,
-				ExpenseTypeName: item.ExpenseTypeName,
-				TotalAmount:     item.TotalAmount,
-				Count:           item.Count,
-			}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/expenses/")
+	if id == "" {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, refunds, err := h.services.GetExpenseRefunds(userID, id)
+	if err != nil {
+		logger.Error("Error getting expense refunds: %v", err)
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	response := ExpenseRefundsResponse{
+		ExpenseID:     expense.ID.String(),
+		ExpenseAmount: expense.Amount,
+		Refunds:       make([]IncomeResponse, len(refunds)),
+		Count:         len(refunds),
+	}
+
+	for i, refund := range refunds {
+		response.Refunds[i] = convertIncomeToResponse(&refund)
+		if refund.Status.IsAccessible() {
+			response.RefundedAmount += refund.Amount
 		}
 	}
+	response.RemainingAmount = expense.Amount - response.RefundedAmount
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
-
-
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type ExpenseAttachmentResponse struct {
+	ID          string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	FileName    string `json:"file_name" example:"receipt.jpg"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	Size        int64  `json:"size" example:"245760"` // Bytes
+	URL         string `json:"url" example:"/api/v1/expenses/123e4567-e89b-12d3-a456-426614174000/attachments/123e4567-e89b-12d3-a456-426614174001"`
+	CreatedAt   string `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+type ExpenseAttachmentsListResponse struct {
+	Attachments []ExpenseAttachmentResponse `json:"attachments"`
+	Count       int                         `json:"count" example:"2"`
+}
+
+func convertExpenseAttachmentToResponse(attachment *models.ExpenseAttachment) ExpenseAttachmentResponse {
+	return ExpenseAttachmentResponse{
+		ID:          attachment.ID.String(),
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		URL:         "/api/v1/expenses/" + attachment.ExpenseID.String() + "/attachments/" + attachment.ID.String(),
+		CreatedAt:   attachment.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// extractAttachmentIDs reads the expense and attachment IDs of /api/v1/expenses/{id}/attachments/{attachment_id}
+func extractAttachmentIDs(path string) (expenseID string, attachmentID string) {
+	expenseID = extractIDFromPath(path, "/api/v1/expenses/")
+	attachmentID = extractIDFromPath(path, "/api/v1/expenses/"+expenseID+"/attachments/")
+	return expenseID, attachmentID
+}
+
+// writeAttachmentError maps attachment service errors to status codes
+func writeAttachmentError(w http.ResponseWriter, err error) {
+	switch {
+	case writeMonthClosed(w, err):
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// UploadExpenseAttachmentHandler godoc
+// @Summary Upload a receipt
+// @Description Attaches a receipt to an expense: a JPEG, PNG or WebP image or a PDF, up to ATTACHMENT_MAX_MB (10 MB by default) and 10 files per expense. The type is detected from the content
+// @Tags expense
+// @Accept multipart/form-data
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Param file formData file true "Receipt image or PDF"
+// @Success 201 {object} ExpenseAttachmentResponse
+// @Failure 400 {string} string "Invalid file"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Expense not found"
+// @Failure 413 {string} string "File too large"
+// @Failure 409 {string} string "Expense in a closed month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/attachments [post]
+func (h *Handlers) UploadExpenseAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID := extractIDFromPath(r.URL.Path, "/api/v1/expenses/")
+	if expenseID == "" {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxAttachmentBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "A multipart file field named file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.services.CreateExpenseAttachment(r.Context(), userID, expenseID, header.Filename, file, header.Size)
+	if err != nil {
+		logger.Error("Error uploading expense attachment: %v", err)
+		if strings.Contains(err.Error(), "larger than") {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeAttachmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(convertExpenseAttachmentToResponse(attachment))
+}
+
+// GetExpenseAttachmentsHandler godoc
+// @Summary List the receipts of an expense
+// @Description Lists the attachments of an expense, oldest first
+// @Tags expense
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Success 200 {object} ExpenseAttachmentsListResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Expense not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/attachments [get]
+func (h *Handlers) GetExpenseAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID := extractIDFromPath(r.URL.Path, "/api/v1/expenses/")
+	if expenseID == "" {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.services.GetExpenseAttachments(userID, expenseID)
+	if err != nil {
+		logger.Error("Error getting expense attachments: %v", err)
+		writeAttachmentError(w, err)
+		return
+	}
+
+	response := ExpenseAttachmentsListResponse{
+		Attachments: make([]ExpenseAttachmentResponse, len(attachments)),
+		Count:       len(attachments),
+	}
+	for i := range attachments {
+		response.Attachments[i] = convertExpenseAttachmentToResponse(&attachments[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DownloadExpenseAttachmentHandler godoc
+// @Summary Download a receipt
+// @Description Returns the file of an attachment with its content type
+// @Tags expense
+// @Produce octet-stream
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Param attachment_id path string true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Attachment not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/attachments/{attachment_id} [get]
+func (h *Handlers) DownloadExpenseAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID, attachmentID := extractAttachmentIDs(r.URL.Path)
+	if expenseID == "" || attachmentID == "" {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := h.services.OpenExpenseAttachment(r.Context(), userID, expenseID, attachmentID)
+	if err != nil {
+		logger.Error("Error opening expense attachment: %v", err)
+		writeAttachmentError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Warn("Error sending attachment %s: %v", attachment.ID, err)
+	}
+}
+
+// DeleteExpenseAttachmentHandler godoc
+// @Summary Delete a receipt
+// @Description Removes an attachment and its file
+// @Tags expense
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Param attachment_id path string true "Attachment ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Attachment not found"
+// @Failure 409 {string} string "Expense in a closed month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/attachments/{attachment_id} [delete]
+func (h *Handlers) DeleteExpenseAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID, attachmentID := extractAttachmentIDs(r.URL.Path)
+	if expenseID == "" || attachmentID == "" {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.DeleteExpenseAttachment(r.Context(), userID, expenseID, attachmentID); err != nil {
+		logger.Error("Error deleting expense attachment: %v", err)
+		writeAttachmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
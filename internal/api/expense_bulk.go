@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request structures
+type ExpenseBulkFilterRequest struct {
+	IDs                 []string `json:"ids,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Either IDs or the query fields below
+	CategoryID          *string  `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	BankAccountID       *string  `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ImportBatchID       *string  `json:"import_batch_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	DescriptionContains *string  `json:"description_contains,omitempty" example:"uber"`
+	From                *string  `json:"from,omitempty" example:"2024-01-01"`
+	To                  *string  `json:"to,omitempty" example:"2024-01-31"`
+}
+
+type ExpenseBulkUpdateRequest struct {
+	CategoryID    *string `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	BankAccountID *string `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+type ExpenseBulkEditRequest struct {
+	Filter ExpenseBulkFilterRequest `json:"filter"`
+	Update ExpenseBulkUpdateRequest `json:"update"`
+	DryRun bool                     `json:"dry_run" example:"true"` // Only preview the changes
+}
+
+// BulkEditExpensesHandler godoc
+// @Summary Bulk edit expenses
+// @Description Sets the category and/or bank account of every expense matched by the filter, all in one transaction. The filter is either a list of IDs or a query (category, bank account, import batch, description text and date range, combined with AND). Moving expenses to another account moves their amounts between the balances; split expenses can't be moved. With dry_run the matched expenses and their new values are returned without saving anything.
+// @Tags expense
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body ExpenseBulkEditRequest true "Filter, update and dry run flag"
+// @Success 200 {object} dto.ExpenseBulkEdit
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Category or bank account not found"
+// @Failure 409 {string} string "A split expense can't be moved to another account, or an expense is in a closed month"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/bulk [patch]
+func (h *Handlers) BulkEditExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ExpenseBulkEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	filter := services.ExpenseBulkFilter{
+		IDs:                 req.Filter.IDs,
+		CategoryID:          req.Filter.CategoryID,
+		BankAccountID:       req.Filter.BankAccountID,
+		ImportBatchID:       req.Filter.ImportBatchID,
+		DescriptionContains: req.Filter.DescriptionContains,
+	}
+	var err error
+	if filter.From, err = parseOptionalDate(req.Filter.From); err != nil {
+		http.Error(w, "Invalid from date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseOptionalDate(req.Filter.To); err != nil {
+		http.Error(w, "Invalid to date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	update := services.ExpenseBulkUpdate{
+		CategoryID:    req.Update.CategoryID,
+		BankAccountID: req.Update.BankAccountID,
+	}
+	result, err := h.services.BulkEditExpenses(userID, filter, update, req.DryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSplitExpenseLedgerChange):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case writeMonthClosed(w, err):
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error updating expenses", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// listParam reads a list query parameter given comma separated, repeated, or both
+func listParam(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// SearchExpensesHandler godoc
+// @Summary Search expenses
+// @Description Finds the expenses matching every given filter at once. q matches whole words of the description or any part of it, case insensitive. Lists accept comma separated or repeated values and match any of them. Without status only visible expenses are searched.
+// @Tags expense
+// @Produce json
+// @Security bearerAuth
+// @Param q query string false "Free text over the description (max 200 characters)"
+// @Param min_amount query number false "Smallest amount"
+// @Param max_amount query number false "Largest amount"
+// @Param category_ids query string false "Comma separated category IDs"
+// @Param bank_account_ids query string false "Comma separated bank account IDs; split expenses match any account they were paid from"
+// @Param status query string false "Comma separated statuses, e.g. active,archived"
+// @Param from query string false "Expenses from this date (YYYY-MM-DD)"
+// @Param to query string false "Expenses up to this date (YYYY-MM-DD)"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
+// @Param sort query string false "relevance (default with q), date_desc (default), date_asc, amount_desc or amount_asc"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} ExpensesListResponse
+// @Failure 400 {string} string "Invalid search parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/search [get]
+func (h *Handlers) SearchExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	search := services.ExpenseSearch{
+		Query:          query.Get("q"),
+		CategoryIDs:    listParam(query["category_ids"]),
+		BankAccountIDs: listParam(query["bank_account_ids"]),
+		Statuses:       listParam(query["status"]),
+		Tags:           tags,
+		Sort:           query.Get("sort"),
+	}
+	for name, target := range map[string]**models.Money{"min_amount": &search.MinAmount, "max_amount": &search.MaxAmount} {
+		if value := query.Get(name); value != "" {
+			amount, err := models.ParseMoney(value)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*target = &amount
+		}
+	}
+	for name, target := range map[string]**time.Time{"from": &search.From, "to": &search.To} {
+		if value := query.Get(name); value != "" {
+			date, err := parseDate(value)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" format, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*target = &date
+		}
+	}
+
+	expenses, info, err := h.services.SearchExpenses(userID, search, page)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error searching expenses", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	responses := make([]ExpenseResponse, len(expenses))
+	for i := range expenses {
+		responses[i] = convertExpenseToResponse(&expenses[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExpensesListResponse{Expenses: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
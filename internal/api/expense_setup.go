@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/services"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 )
@@ -15,9 +16,9 @@ type ExpenseTypeInfo struct {
 }
 
 type SystemOverviewResponse struct {
-	ExpenseTypesCount int                    `json:"expense_types_count" example:"3"`
-	ExpenseTypes      []ExpenseTypeInfo      `json:"expense_types"`
-	SystemInfo        map[string]interface{} `json:"system_info"`
+	ExpenseTypesCount int               `json:"expense_types_count" example:"3"`
+	ExpenseTypes      []ExpenseTypeInfo `json:"expense_types"`
+	SystemInfo        dto.SystemInfo    `json:"system_info"`
 }
 
 // @Summary Initialize expense system
@@ -53,10 +54,10 @@ func InitializeExpenseSystem(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} SuccessResponse
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/setup/user [post]
-func SetupNewUser(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) SetupNewUser(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	err := services.SetupNewUser(userID)
+	err := h.services.SetupNewUser(userID)
 	if err != nil {
 		logger.Error("Error setting up new user: %v", err)
 		http.Error(w, "Error setting up user", http.StatusInternalServerError)
@@ -87,20 +88,18 @@ func GetSystemOverview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get expense types from overview (already formatted)
-	expenseTypesRaw := overview["expense_types"].([]map[string]string)
-	var expenseTypes []ExpenseTypeInfo
-	for _, et := range expenseTypesRaw {
-		expenseTypes = append(expenseTypes, ExpenseTypeInfo{
-			Value: et["value"],
-			Name:  et["name"],
-		})
+	expenseTypes := make([]ExpenseTypeInfo, len(overview.ExpenseTypes))
+	for i, et := range overview.ExpenseTypes {
+		expenseTypes[i] = ExpenseTypeInfo{
+			Value: et.Value,
+			Name:  et.Name,
+		}
 	}
 
 	response := SystemOverviewResponse{
-		ExpenseTypesCount: overview["expense_types_count"].(int),
+		ExpenseTypesCount: overview.ExpenseTypesCount,
 		ExpenseTypes:      expenseTypes,
-		SystemInfo:        overview["system_info"].(map[string]interface{}),
+		SystemInfo:        overview.SystemInfo,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetFeedHandler godoc
+// @Summary Get the transaction feed
+// @Description Returns the user's active expenses, incomes and transfers as one list, newest first (by date, then by when they were recorded). Every item has the same envelope: type, amount, date, account, and the category for expenses or the destination account for transfers. Pages are keyed on the last item returned, so records added while the user scrolls don't repeat or skip items
+// @Tags feed
+// @Produce json
+// @Security bearerAuth
+// @Param limit query int false "Page size, up to 500 (default 50)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param type query string false "Comma-separated item types to include: expense, income, transfer (default all)"
+// @Success 200 {object} dto.FeedPage
+// @Failure 400 {string} string "Invalid limit, cursor or type"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/feed [get]
+func (h *Handlers) GetFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	req := services.FeedRequest{Cursor: query.Get("cursor")}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit: must be between 1 and "+strconv.Itoa(services.MaxPageLimit), http.StatusBadRequest)
+			return
+		}
+		req.Limit = limit
+	}
+	if types := query.Get("type"); types != "" {
+		for _, itemType := range strings.Split(types, ",") {
+			if itemType = strings.TrimSpace(itemType); itemType != "" {
+				req.Types = append(req.Types, itemType)
+			}
+		}
+	}
+
+	page, err := h.services.GetFeed(userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Error("Error getting feed: %v", err)
+		http.Error(w, "Error getting feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetFinancialScoreHandler godoc
+// @Summary Monthly financial health score
+// @Description Rates a month from 0 to 100 for a single gauge, with the breakdown behind it: savings rate (income not spent on needs or wants, full marks at 20%), 50/30/20 adherence (points past the needs, wants and savings targets), fixed expense load (scheduled fixed expenses against income, full marks up to 35%) and budget volatility (variation of the budget totals over the last 6 months, revisions included). Components without data, e.g. a month without income, are left out and the others weighed up. income_sources breaks the income down by source. Defaults to the current month.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param month query string false "Month (YYYY-MM)"
+// @Success 200 {object} dto.FinancialScore
+// @Failure 400 {string} string "Invalid month format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/reports/score [get]
+func (h *Handlers) GetFinancialScoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	month := h.services.UserNow(userID)
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := parseMonth(value)
+		if err != nil {
+			http.Error(w, "Invalid month format, use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	score, err := h.services.GetFinancialScore(r.Context(), userID, month)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// The client went away; nothing left to answer
+			logger.Info("Financial score cancelled for user %s", userID)
+			return
+		}
+		http.Error(w, "Error calculating financial score", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(score)
+}
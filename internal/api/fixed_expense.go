@@ -16,33 +16,36 @@ import (
 // Request and response structures
 type CreateFixedExpenseRequest struct {
 	Name           string  `json:"name" example:"Monthly Rent"`
-	Amount         float64 `json:"amount" example:"1200.00"`
+	Amount         models.Money `json:"amount" example:"1200.00"`
 	DueDate        string  `json:"due_date" example:"2024-01-15"` // Day of month for recurring expenses
 	CategoryID     *string `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
 	BankAccountID  string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	IsRecurring    *bool   `json:"is_recurring,omitempty" example:"true"`
 	RecurrenceType *string `json:"recurrence_type,omitempty" example:"monthly"` // monthly, yearly
+	SkipHolidays   bool    `json:"skip_holidays" example:"false"`               // Post on the next business day when due on a weekend or bank holiday
 }
 
 type UpdateFixedExpenseRequest struct {
 	Name           *string  `json:"name,omitempty" example:"Updated Rent"`
-	Amount         *float64 `json:"amount,omitempty" example:"1300.00"`
+	Amount         *models.Money `json:"amount,omitempty" example:"1300.00"`
 	DueDate        *string  `json:"due_date,omitempty" example:"2024-01-20"`
 	CategoryID     *string  `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
 	BankAccountID  *string  `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
 	IsRecurring    *bool    `json:"is_recurring,omitempty" example:"true"`
 	RecurrenceType *string  `json:"recurrence_type,omitempty" example:"monthly"`
+	SkipHolidays   *bool    `json:"skip_holidays,omitempty" example:"true"`
 }
 
 type FixedExpenseResponse struct {
 	ID             string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Name           string  `json:"name" example:"Monthly Rent"`
-	Amount         float64 `json:"amount" example:"1200.00"`
+	Amount         models.Money `json:"amount" example:"1200.00"`
 	DueDate        string  `json:"due_date" example:"2024-01-15"`
 	CategoryID     *string `json:"category_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
 	BankAccountID  string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	IsRecurring    bool    `json:"is_recurring" example:"true"`
 	RecurrenceType string  `json:"recurrence_type" example:"monthly"`
+	SkipHolidays   bool    `json:"skip_holidays" example:"false"` // Posted on the next business day of the user's holiday calendar
 	Status         string  `json:"status" example:"active"`
 	CreatedAt      string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
 	UpdatedAt      string  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
@@ -52,6 +55,31 @@ type FixedExpenseResponse struct {
 type FixedExpensesListResponse struct {
 	FixedExpenses []FixedExpenseResponse `json:"fixed_expenses"`
 	Count         int                    `json:"count" example:"5"`
+	*PageResponse
+}
+
+type FixedExpenseReconciliationItemResponse struct {
+	FixedExpense FixedExpenseResponse `json:"fixed_expense"`
+	DueDate      string               `json:"due_date" example:"2024-01-15"`
+	Status       string               `json:"status" example:"matched" enums:"matched,duplicate,missed,pending"`
+	Matches      []ExpenseResponse    `json:"matches"`
+}
+
+type FixedExpenseReconciliationResponse struct {
+	Year            int                                      `json:"year" example:"2024"`
+	Month           int                                      `json:"month" example:"1"`
+	AmountTolerance float64                                  `json:"amount_tolerance_percent" example:"5"`
+	DateTolerance   int                                      `json:"date_tolerance_days" example:"3"`
+	Items           []FixedExpenseReconciliationItemResponse `json:"items"`
+	MatchedCount    int                                      `json:"matched_count" example:"3"`
+	MissedCount     int                                      `json:"missed_count" example:"1"`
+	DuplicateCount  int                                      `json:"duplicate_count" example:"0"`
+	PendingCount    int                                      `json:"pending_count" example:"2"`
+}
+
+type ProcessFixedExpensesResponse struct {
+	Message   string `json:"message" example:"Fixed expenses processed successfully"`
+	Timestamp string `json:"timestamp" example:"2024-01-15T00:00:00Z"`
 }
 
 // Helper function to convert model to response
@@ -64,9 +92,10 @@ func convertFixedExpenseToResponse(fixedExpense *models.FixedExpense) FixedExpen
 		BankAccountID:  fixedExpense.BankAccountID.String(),
 		IsRecurring:    fixedExpense.IsRecurring,
 		RecurrenceType: fixedExpense.RecurrenceType,
+		SkipHolidays:   fixedExpense.SkipHolidays,
 		Status:         string(fixedExpense.Status),
-		CreatedAt:      fixedExpense.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:      fixedExpense.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:      fixedExpense.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      fixedExpense.UpdatedAt.Format(time.RFC3339),
 		NextDueDate:    fixedExpense.NextDueDate.Format("2006-01-02"),
 	}
 	
@@ -91,7 +120,7 @@ func convertFixedExpenseToResponse(fixedExpense *models.FixedExpense) FixedExpen
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/fixed-expenses [post]
-func CreateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -151,6 +180,7 @@ func CreateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		Amount:        req.Amount,
 		DueDate:       dueDate,
 		BankAccountID: bankAccountID,
+		SkipHolidays:  req.SkipHolidays,
 	}
 	
 	// Set defaults for new fields
@@ -177,7 +207,7 @@ func CreateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create in the database
-	createdFixedExpense, err := services.CreateFixedExpense(userID, fixedExpense)
+	createdFixedExpense, err := h.services.CreateFixedExpense(userID, fixedExpense)
 	if err != nil {
 		logger.Error("Error creating fixed expense: %v", err)
 		http.Error(w, "Error creating fixed expense", http.StatusInternalServerError)
@@ -205,7 +235,7 @@ func CreateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Fixed expense not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/fixed-expenses/{id} [get]
-func GetFixedExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetFixedExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -223,7 +253,7 @@ func GetFixedExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fixedExpense, err := services.GetFixedExpenseByID(userID, id)
+	fixedExpense, err := h.services.GetFixedExpenseByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting fixed expense: %v", err)
 		http.Error(w, "Fixed expense not found", http.StatusNotFound)
@@ -244,11 +274,17 @@ func GetFixedExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security bearerAuth
 // @Param include_deleted query boolean false "Include deleted fixed expenses"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
 // @Success 200 {object} FixedExpensesListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/fixed-expenses [get]
-func GetAllFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetAllFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -260,9 +296,19 @@ func GetAllFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listNotModified(w, r, h.services, userID, "fixed_expenses", "categories", "bank_accounts") {
+		return
+	}
+
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
-	fixedExpenses, err := services.GetFixedExpenses(userID, includeDeleted)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fixedExpenses, info, err := h.services.GetFixedExpenses(userID, includeDeleted, page)
 	if err != nil {
 		logger.Error("Error getting fixed expenses: %v", err)
 		http.Error(w, "Error retrieving fixed expenses", http.StatusInternalServerError)
@@ -277,6 +323,7 @@ func GetAllFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	response := FixedExpensesListResponse{
 		FixedExpenses: fixedExpenseResponses,
 		Count:         len(fixedExpenseResponses),
+		PageResponse:  newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -298,7 +345,7 @@ func GetAllFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Fixed expense not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/fixed-expenses/{id} [patch]
-func UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -324,7 +371,7 @@ func UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current fixed expense for base values
-	currentFixedExpense, err := services.GetFixedExpenseByID(userID, id)
+	currentFixedExpense, err := h.services.GetFixedExpenseByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting current fixed expense: %v", err)
 		http.Error(w, "Fixed expense not found", http.StatusNotFound)
@@ -333,9 +380,10 @@ func UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create model with updates
 	fixedExpense := models.FixedExpense{
-		Name:    currentFixedExpense.Name,
-		Amount:  currentFixedExpense.Amount,
-		DueDate: currentFixedExpense.DueDate,
+		Name:         currentFixedExpense.Name,
+		Amount:       currentFixedExpense.Amount,
+		DueDate:      currentFixedExpense.DueDate,
+		SkipHolidays: currentFixedExpense.SkipHolidays,
 	}
 
 	if req.Name != nil {
@@ -367,8 +415,12 @@ func UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		fixedExpense.DueDate = dueDate
 	}
 
+	if req.SkipHolidays != nil {
+		fixedExpense.SkipHolidays = *req.SkipHolidays
+	}
+
 	// Update in the database
-	updatedFixedExpense, err := services.UpdateFixedExpense(userID, id, fixedExpense)
+	updatedFixedExpense, err := h.services.UpdateFixedExpense(userID, id, fixedExpense)
 	if err != nil {
 		logger.Error("Error updating fixed expense: %v", err)
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "deleted") {
@@ -387,19 +439,19 @@ func UpdateFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetFixedExpensesCalendarHandler godoc
 // @Summary Get fixed expenses calendar for a specific month
-// @Description Returns all fixed expenses that apply to a specific month/year
+// @Description Returns all fixed expenses that apply to a specific month/year, with the occurrence of each in the month: paid, skipped, or pending while not confirmed yet
 // @Tags fixed_expense
 // @Accept json
 // @Produce json
 // @Security bearerAuth
 // @Param year query int true "Year (e.g., 2024)"
 // @Param month query int true "Month (1-12)"
-// @Success 200 {object} FixedExpensesListResponse
+// @Success 200 {object} FixedExpensesCalendarResponse
 // @Failure 400 {string} string "Invalid parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/fixed-expenses/calendar [get]
-func GetFixedExpensesCalendarHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetFixedExpensesCalendarHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -436,7 +488,7 @@ func GetFixedExpensesCalendarHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get fixed expenses for this month
-	fixedExpenses, err := services.GetFixedExpensesForMonth(userID, year, time.Month(month))
+	fixedExpenses, err := h.services.GetFixedExpensesForMonth(userID, year, time.Month(month))
 	if err != nil {
 		logger.Error("Error getting fixed expenses for calendar: %v", err)
 		http.Error(w, "Error retrieving fixed expenses", http.StatusInternalServerError)
@@ -454,9 +506,10 @@ func GetFixedExpensesCalendarHandler(w http.ResponseWriter, r *http.Request) {
 			DueDate:        dueDateForMonth.Format("2006-01-02"),
 			IsRecurring:    expense.IsRecurring,
 			RecurrenceType: expense.RecurrenceType,
+			SkipHolidays:   expense.SkipHolidays,
 			Status:         string(expense.Status),
-			CreatedAt:      expense.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt:      expense.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			CreatedAt:      expense.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:      expense.UpdatedAt.Format(time.RFC3339),
 		}
 		
 		if expense.CategoryID != nil {
@@ -465,9 +518,133 @@ func GetFixedExpensesCalendarHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response := FixedExpensesListResponse{
+	occurrences, err := h.services.GetFixedExpenseOccurrencesForMonth(userID, year, time.Month(month))
+	if err != nil {
+		logger.Error("Error getting fixed expense occurrences for calendar: %v", err)
+		http.Error(w, "Error retrieving fixed expenses", http.StatusInternalServerError)
+		return
+	}
+	occurrenceResponses := make([]FixedExpenseOccurrenceResponse, len(occurrences))
+	for i := range occurrences {
+		occurrenceResponses[i] = convertFixedExpenseOccurrenceToResponse(&occurrences[i])
+	}
+
+	response := FixedExpensesCalendarResponse{
 		FixedExpenses: responses,
 		Count:         len(responses),
+		Occurrences:   occurrenceResponses,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFixedExpensesReconciliationHandler godoc
+// @Summary Reconcile fixed expenses with actual payments
+// @Description Pairs the fixed expenses of a month with recorded expenses and flags missed or duplicate payments
+// @Tags fixed_expense
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param year query int true "Year (e.g., 2024)"
+// @Param month query int true "Month (1-12)"
+// @Param amount_tolerance query number false "Allowed amount deviation in percent (default 5)"
+// @Param date_tolerance query int false "Allowed distance from the due date in days (default 3)"
+// @Success 200 {object} FixedExpenseReconciliationResponse
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/reconciliation [get]
+func (h *Handlers) GetFixedExpensesReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	yearStr := r.URL.Query().Get("year")
+	monthStr := r.URL.Query().Get("month")
+
+	if yearStr == "" || monthStr == "" {
+		http.Error(w, "year and month parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	year, err := parseIntParam(yearStr)
+	if err != nil {
+		http.Error(w, "Invalid year parameter", http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseIntParam(monthStr)
+	if err != nil || month < 1 || month > 12 {
+		http.Error(w, "Invalid month parameter (must be 1-12)", http.StatusBadRequest)
+		return
+	}
+
+	tolerance := services.DefaultReconciliationTolerance()
+
+	if amountStr := r.URL.Query().Get("amount_tolerance"); amountStr != "" {
+		amount, err := strconv.ParseFloat(amountStr, 64)
+		if err != nil || amount < 0 || amount > 100 {
+			http.Error(w, "Invalid amount_tolerance (must be 0-100)", http.StatusBadRequest)
+			return
+		}
+		tolerance.AmountPercent = amount
+	}
+
+	if daysStr := r.URL.Query().Get("date_tolerance"); daysStr != "" {
+		days, err := parseIntParam(daysStr)
+		if err != nil || days < 0 || days > 31 {
+			http.Error(w, "Invalid date_tolerance (must be 0-31)", http.StatusBadRequest)
+			return
+		}
+		tolerance.Days = days
+	}
+
+	reconciliation, err := h.services.GetFixedExpenseReconciliation(userID, year, time.Month(month), tolerance)
+	if err != nil {
+		logger.Error("Error reconciling fixed expenses: %v", err)
+		http.Error(w, "Error reconciling fixed expenses", http.StatusInternalServerError)
+		return
+	}
+
+	response := FixedExpenseReconciliationResponse{
+		Year:            year,
+		Month:           month,
+		AmountTolerance: tolerance.AmountPercent,
+		DateTolerance:   tolerance.Days,
+		Items:           make([]FixedExpenseReconciliationItemResponse, len(reconciliation)),
+	}
+
+	for i, item := range reconciliation {
+		matches := make([]ExpenseResponse, len(item.Matches))
+		for j, match := range item.Matches {
+			matches[j] = convertExpenseToResponse(&match)
+		}
+
+		response.Items[i] = FixedExpenseReconciliationItemResponse{
+			FixedExpense: convertFixedExpenseToResponse(&item.FixedExpense),
+			DueDate:      item.DueDate.Format("2006-01-02"),
+			Status:       string(item.Status),
+			Matches:      matches,
+		}
+
+		switch item.Status {
+		case services.ReconciliationMatched:
+			response.MatchedCount++
+		case services.ReconciliationMissed:
+			response.MissedCount++
+		case services.ReconciliationDuplicate:
+			response.DuplicateCount++
+		case services.ReconciliationPending:
+			response.PendingCount++
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -493,7 +670,7 @@ func parseIntParam(param string) (int, error) {
 // @Failure 404 {string} string "Fixed expense not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/fixed-expenses/{id} [delete]
-func DeleteFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) DeleteFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -511,7 +688,7 @@ func DeleteFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := services.DeleteFixedExpense(userID, id)
+	_, err := h.services.DeleteFixedExpense(userID, id)
 	if err != nil {
 		logger.Error("Error deleting fixed expense: %v", err)
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "deleted") {
@@ -527,14 +704,14 @@ func DeleteFixedExpenseHandler(w http.ResponseWriter, r *http.Request) {
 
 // ProcessFixedExpensesHandler godoc
 // @Summary Process due fixed expenses (scheduled job)
-// @Description Processes all fixed expenses that are due and creates expense records
+// @Description Processes all fixed expenses that are due and creates expense records. Each period is posted once, so reruns are safe; see /api/v1/fixed-expenses/runs
 // @Tags fixed_expense
 // @Accept json
 // @Produce json
 // @Security bearerAuth
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} ProcessFixedExpensesResponse
 // @Router /api/v1/fixed-expenses/process [post]
-func ProcessFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ProcessFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -543,16 +720,16 @@ func ProcessFixedExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	// This endpoint should be called by a cron job
 	// Consider adding API key authentication for this endpoint
 	
-	if err := services.ProcessDueFixedExpenses(); err != nil {
+	if err := h.services.ProcessDueFixedExpenses(); err != nil {
 		logger.Error("Error processing fixed expenses: %v", err)
 		http.Error(w, "Error processing fixed expenses", http.StatusInternalServerError)
 		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Fixed expenses processed successfully",
-		"timestamp": time.Now(),
+	json.NewEncoder(w).Encode(ProcessFixedExpensesResponse{
+		Message:   "Fixed expenses processed successfully",
+		Timestamp: time.Now().Format(time.RFC3339),
 	})
 }
 
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type FixedExpenseDriftsResponse struct {
+	Drifts []dto.FixedExpenseDrift `json:"drifts"`
+	Count  int                     `json:"count" example:"1"`
+}
+
+// GetFixedExpenseDriftsHandler godoc
+// @Summary Suggest new amounts for drifting fixed expenses
+// @Description Lists the monthly fixed expenses whose last three payments all differed from the configured amount in the same direction (by 2% or more), with the median paid amount as the suggested new amount. Users are also notified once per suggestion with a fixed_expense.drift_detected event.
+// @Tags fixed_expense
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} FixedExpenseDriftsResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/drift [get]
+func (h *Handlers) GetFixedExpenseDriftsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	drifts, err := h.services.DetectFixedExpenseDrifts(userID)
+	if err != nil {
+		logger.Error("Error detecting fixed expense drift: %v", err)
+		http.Error(w, "Error detecting fixed expense drift", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FixedExpenseDriftsResponse{Drifts: drifts, Count: len(drifts)})
+}
+
+// AcceptFixedExpenseDriftHandler godoc
+// @Summary Accept the suggested amount of a drifting fixed expense
+// @Description Updates the fixed expense to the amount suggested by GET /fixed-expenses/drift, so forecasts use what is actually being paid
+// @Tags fixed_expense
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Fixed Expense ID"
+// @Success 200 {object} FixedExpenseResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "No drift found for the fixed expense"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/{id}/drift/accept [post]
+func (h *Handlers) AcceptFixedExpenseDriftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/fixed-expenses/{id}/drift/accept
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/fixed-expenses/"), "/drift/accept")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	fixedExpense, err := h.services.AcceptFixedExpenseDrift(userID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "No drift found for the fixed expense", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error updating fixed expense", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertFixedExpenseToResponse(fixedExpense))
+}
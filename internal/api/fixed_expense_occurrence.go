@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type FixedExpenseOccurrenceResponse struct {
+	ID             *string      `json:"id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Absent for cycles not posted, confirmed or skipped yet
+	FixedExpenseID string       `json:"fixed_expense_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Period         string       `json:"period" example:"2024-01-15"`                                         // Due date of the cycle
+	Status         string       `json:"status" example:"pending"`                                            // pending, paid or skipped
+	ExpenseID      *string      `json:"expense_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Expense posted for the cycle
+	Amount         models.Money `json:"amount" example:"1200.00"`
+	ResolvedAt     *string      `json:"resolved_at,omitempty" example:"2024-01-16T09:00:00Z"`
+}
+
+type FixedExpensesCalendarResponse struct {
+	FixedExpenses []FixedExpenseResponse           `json:"fixed_expenses"`
+	Count         int                              `json:"count" example:"5"`
+	Occurrences   []FixedExpenseOccurrenceResponse `json:"occurrences"` // The cycle of each fixed expense in the month
+}
+
+func convertFixedExpenseOccurrenceToResponse(occurrence *models.FixedExpenseOccurrence) FixedExpenseOccurrenceResponse {
+	response := FixedExpenseOccurrenceResponse{
+		FixedExpenseID: occurrence.FixedExpenseID.String(),
+		Period:         occurrence.Period.Format("2006-01-02"),
+		Status:         occurrence.Status,
+		Amount:         occurrence.Amount,
+	}
+	if occurrence.ID != [16]byte{} {
+		id := occurrence.ID.String()
+		response.ID = &id
+	}
+	if occurrence.ExpenseID != nil {
+		expenseID := occurrence.ExpenseID.String()
+		response.ExpenseID = &expenseID
+	}
+	if occurrence.ResolvedAt != nil {
+		resolvedAt := occurrence.ResolvedAt.Format(time.RFC3339)
+		response.ResolvedAt = &resolvedAt
+	}
+	return response
+}
+
+// ConfirmFixedExpenseOccurrenceHandler godoc
+// @Summary Confirm a fixed expense cycle as paid
+// @Description Marks the cycle of a fixed expense due on period as paid. The next due cycle can be confirmed before its due date, which posts its expense right away and moves the due date on. Confirming a paid cycle does nothing.
+// @Tags fixed_expense
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Fixed expense ID"
+// @Param period path string true "Due date of the cycle (YYYY-MM-DD)"
+// @Success 200 {object} FixedExpenseOccurrenceResponse
+// @Failure 400 {string} string "Invalid period"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Fixed expense not found"
+// @Failure 409 {string} string "The cycle was skipped"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/{id}/occurrences/{period}/confirm [post]
+func (h *Handlers) ConfirmFixedExpenseOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	resolveFixedExpenseOccurrence(w, r, "/confirm", h.services.ConfirmFixedExpenseOccurrence)
+}
+
+// SkipFixedExpenseOccurrenceHandler godoc
+// @Summary Skip a fixed expense cycle
+// @Description Skips the cycle of a fixed expense due on period. An expense already posted for it is deleted and its amount given back to the account; a cycle not posted yet won't be, and skipping the next due one moves the due date on. Skipping a skipped cycle does nothing.
+// @Tags fixed_expense
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Fixed expense ID"
+// @Param period path string true "Due date of the cycle (YYYY-MM-DD)"
+// @Success 200 {object} FixedExpenseOccurrenceResponse
+// @Failure 400 {string} string "Invalid period"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Fixed expense not found"
+// @Failure 409 {string} string "The cycle was confirmed as paid"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/{id}/occurrences/{period}/skip [post]
+func (h *Handlers) SkipFixedExpenseOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	resolveFixedExpenseOccurrence(w, r, "/skip", h.services.SkipFixedExpenseOccurrence)
+}
+
+func resolveFixedExpenseOccurrence(w http.ResponseWriter, r *http.Request, action string,
+	resolve func(userID string, id string, period time.Time) (*models.FixedExpenseOccurrence, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/fixed-expenses/{id}/occurrences/{period}/{action}
+	rest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/fixed-expenses/"), action)
+	id, periodStr, found := strings.Cut(rest, "/occurrences/")
+	if !found || id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	period, err := parseDate(periodStr)
+	if err != nil {
+		http.Error(w, "Invalid period format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	occurrence, err := resolve(userID, id, period)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "Fixed expense not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrFixedExpenseOccurrenceResolved):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Error("Error updating fixed expense occurrence: %v", err)
+			http.Error(w, "Error updating occurrence", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertFixedExpenseOccurrenceToResponse(occurrence))
+}
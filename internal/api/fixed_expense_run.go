@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// Request and response structures
+type FixedExpenseRunResponse struct {
+	ID             string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	FixedExpenseID string       `json:"fixed_expense_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Period         string       `json:"period" example:"2024-01-15"` // Due date of the occurrence
+	Status         string       `json:"status" example:"posted"`     // posted, skipped or failed
+	ExpenseID      *string      `json:"expense_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount         models.Money `json:"amount" example:"1200.00"`
+	Reason         *string      `json:"reason,omitempty" example:"fixed expense has no category"`
+	Attempts       int          `json:"attempts" example:"1"`
+	ProcessedAt    string       `json:"processed_at" example:"2024-01-15T06:00:00Z"` // Last attempt
+}
+
+type FixedExpenseRunsListResponse struct {
+	Runs  []FixedExpenseRunResponse `json:"runs"`
+	Count int                       `json:"count" example:"12"`
+	*PageResponse
+}
+
+func convertFixedExpenseRunToResponse(run *models.FixedExpenseRun) FixedExpenseRunResponse {
+	response := FixedExpenseRunResponse{
+		ID:             run.ID.String(),
+		FixedExpenseID: run.FixedExpenseID.String(),
+		Period:         run.Period.Format("2006-01-02"),
+		Status:         run.Status,
+		Amount:         run.Amount,
+		Reason:         run.Reason,
+		Attempts:       run.Attempts,
+		ProcessedAt:    run.ProcessedAt.Format(time.RFC3339),
+	}
+	if run.ExpenseID != nil {
+		expenseID := run.ExpenseID.String()
+		response.ExpenseID = &expenseID
+	}
+	return response
+}
+
+// GetFixedExpenseRunsHandler godoc
+// @Summary Fixed expense processing history
+// @Description Lists what processing did with each occurrence (period) of the user's fixed expenses, latest period first. Every period is posted at most once; skipped and failed periods are retried on later runs and count their attempts. A period missing from the history wasn't processed yet.
+// @Tags fixed_expense
+// @Produce json
+// @Security bearerAuth
+// @Param fixed_expense_id query string false "Only the runs of this fixed expense"
+// @Param status query string false "Only this status: posted, skipped or failed"
+// @Param from query string false "Periods from this date (YYYY-MM-DD)"
+// @Param to query string false "Periods up to this date (YYYY-MM-DD)"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} FixedExpenseRunsListResponse
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/fixed-expenses/runs [get]
+func (h *Handlers) GetFixedExpenseRunsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.FixedExpenseRunFilter{
+		FixedExpenseID: query.Get("fixed_expense_id"),
+		Status:         query.Get("status"),
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			date, err := parseDate(value)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" format, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*target = &date
+		}
+	}
+
+	runs, info, err := h.services.GetFixedExpenseRuns(userID, filter, page)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving fixed expense runs", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	responses := make([]FixedExpenseRunResponse, len(runs))
+	for i := range runs {
+		responses[i] = convertFixedExpenseRunToResponse(&runs[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FixedExpenseRunsListResponse{Runs: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
@@ -12,39 +14,45 @@ import (
 
 // Request and response structures
 type CreateGoalRequest struct {
-	Name        string  `json:"name" example:"Emergency Fund"`
-	TotalAmount float64 `json:"total_amount" example:"10000.00"`
-	SavedAmount float64 `json:"saved_amount,omitempty" example:"2500.00"`
+	Name        string       `json:"name" example:"Emergency Fund"`
+	TotalAmount models.Money `json:"total_amount" example:"10000.00"`
+	SavedAmount models.Money `json:"saved_amount,omitempty" example:"2500.00"`
+	APY         float64      `json:"apy,omitempty" example:"4.5"` // Annual percentage yield of the account holding the savings
 }
 
 type UpdateGoalRequest struct {
-	Name        *string  `json:"name,omitempty" example:"Updated Goal Name"`
-	TotalAmount *float64 `json:"total_amount,omitempty" example:"12000.00"`
-	SavedAmount *float64 `json:"saved_amount,omitempty" example:"3500.00"`
+	Name        *string       `json:"name,omitempty" example:"Updated Goal Name"`
+	TotalAmount *models.Money `json:"total_amount,omitempty" example:"12000.00"`
+	SavedAmount *models.Money `json:"saved_amount,omitempty" example:"3500.00"`
+	APY         *float64      `json:"apy,omitempty" example:"4.5"` // 0 removes it
 }
 
 type GoalResponse struct {
-	ID              string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Name            string  `json:"name" example:"Emergency Fund"`
-	TotalAmount     float64 `json:"total_amount" example:"10000.00"`
-	SavedAmount     float64 `json:"saved_amount" example:"2500.00"`
-	ProgressPercent float64 `json:"progress_percent" example:"25.0"`
-	Status          string  `json:"status" example:"active"`
-	StatusChangedAt *string `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
-	CreatedAt       string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
-	UpdatedAt       string  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+	ID              string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name            string       `json:"name" example:"Emergency Fund"`
+	TotalAmount     models.Money `json:"total_amount" example:"10000.00"`
+	SavedAmount     models.Money `json:"saved_amount" example:"2500.00"`
+	ProgressPercent float64      `json:"progress_percent" example:"25.0"`
+	Priority        int          `json:"priority" example:"1"`
+	APY             *float64     `json:"apy,omitempty" example:"4.5"`
+	Status          string       `json:"status" example:"active"`
+	StatusChangedAt *string      `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	AllowedStatuses []string     `json:"allowed_statuses" example:"deleted"` // Statuses it can be changed to
+	CreatedAt       string       `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt       string       `json:"updated_at" example:"2024-01-15T10:30:00Z"`
 }
 
 type GoalsListResponse struct {
 	Goals []GoalResponse `json:"goals"`
 	Count int            `json:"count" example:"3"`
+	*PageResponse
 }
 
 // Helper function to convert model to response
 func convertGoalToResponse(goal *models.Goal) GoalResponse {
 	progressPercent := 0.0
 	if goal.TotalAmount > 0 {
-		progressPercent = (goal.SavedAmount / goal.TotalAmount) * 100
+		progressPercent = goal.SavedAmount.Ratio(goal.TotalAmount) * 100
 	}
 
 	response := GoalResponse{
@@ -53,13 +61,16 @@ func convertGoalToResponse(goal *models.Goal) GoalResponse {
 		TotalAmount:     goal.TotalAmount,
 		SavedAmount:     goal.SavedAmount,
 		ProgressPercent: progressPercent,
+		Priority:        goal.Priority,
+		APY:             goal.APY,
 		Status:          string(goal.Status),
-		CreatedAt:       goal.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:       goal.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		AllowedStatuses: allowedStatuses(models.GoalStatusMachine, goal.Status),
+		CreatedAt:       goal.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       goal.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if goal.StatusChangedAt != nil {
-		statusChangedAtStr := goal.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+		statusChangedAtStr := goal.StatusChangedAt.Format(time.RFC3339)
 		response.StatusChangedAt = &statusChangedAtStr
 	}
 
@@ -79,7 +90,7 @@ func convertGoalToResponse(goal *models.Goal) GoalResponse {
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals [post]
-func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	var req CreateGoalRequest
@@ -105,6 +116,10 @@ func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Saved amount cannot exceed total amount", http.StatusBadRequest)
 		return
 	}
+	if req.APY < 0 || req.APY > services.MaxGoalAPY {
+		http.Error(w, "APY must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
 
 	// Create goal model
 	goal := models.Goal{
@@ -112,9 +127,12 @@ func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
 		TotalAmount: req.TotalAmount,
 		SavedAmount: req.SavedAmount,
 	}
+	if req.APY > 0 {
+		goal.APY = &req.APY
+	}
 
 	// Create goal
-	createdGoal, err := services.CreateGoal(userID, goal)
+	createdGoal, err := h.services.CreateGoal(userID, goal)
 	if err != nil {
 		logger.Error("Error creating goal: %v", err)
 		http.Error(w, "Error creating goal", http.StatusInternalServerError)
@@ -132,15 +150,31 @@ func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
 // @Description Retrieves all goals for the authenticated user (active and deleted)
 // @Tags goals
 // @Produce json
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} GoalsListResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/goals [get]
-func GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	goals, err := services.GetGoals(userID, true) // Include deleted
+	if listNotModified(w, r, h.services, userID, "goals") {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	goals, info, err := h.services.ListGoals(userID, nil, page) // Include deleted
 	if err != nil {
 		logger.Error("Error getting goals: %v", err)
 		http.Error(w, "Error retrieving goals", http.StatusInternalServerError)
@@ -153,8 +187,9 @@ func GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := GoalsListResponse{
-		Goals: goalResponses,
-		Count: len(goalResponses),
+		Goals:        goalResponses,
+		Count:        len(goalResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -166,15 +201,25 @@ func GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
 // @Description Retrieves only active goals for the authenticated user
 // @Tags goals
 // @Produce json
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} GoalsListResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/active [get]
-func GetActiveGoalsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetActiveGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	goals, err := services.GetGoals(userID, false) // Don't include deleted
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	goals, info, err := h.services.ListGoals(userID, []models.Status{models.StatusActive}, page)
 	if err != nil {
 		logger.Error("Error getting active goals: %v", err)
 		http.Error(w, "Error retrieving active goals", http.StatusInternalServerError)
@@ -187,8 +232,9 @@ func GetActiveGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := GoalsListResponse{
-		Goals: goalResponses,
-		Count: len(goalResponses),
+		Goals:        goalResponses,
+		Count:        len(goalResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -200,27 +246,29 @@ func GetActiveGoalsHandler(w http.ResponseWriter, r *http.Request) {
 // @Description Retrieves only deleted goals for the authenticated user
 // @Tags goals
 // @Produce json
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} GoalsListResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/deleted [get]
-func GetDeletedGoalsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetDeletedGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	// Get all goals and filter deleted ones
-	allGoals, err := services.GetGoals(userID, true)
+	page, err := parsePageRequest(r)
 	if err != nil {
-		logger.Error("Error getting goals: %v", err)
-		http.Error(w, "Error retrieving deleted goals", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var deletedGoals []models.Goal
-	for _, goal := range allGoals {
-		if goal.Status == models.StatusDeleted {
-			deletedGoals = append(deletedGoals, goal)
-		}
+	deletedGoals, info, err := h.services.ListGoals(userID, []models.Status{models.StatusDeleted}, page)
+	if err != nil {
+		logger.Error("Error getting goals: %v", err)
+		http.Error(w, "Error retrieving deleted goals", http.StatusInternalServerError)
+		return
 	}
 
 	var goalResponses []GoalResponse
@@ -229,8 +277,9 @@ func GetDeletedGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := GoalsListResponse{
-		Goals: goalResponses,
-		Count: len(goalResponses),
+		Goals:        goalResponses,
+		Count:        len(goalResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -250,7 +299,7 @@ func GetDeletedGoalsHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/{id} [get]
-func GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	// Extract goal ID from URL
@@ -260,7 +309,7 @@ func GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goal, err := services.GetGoalByID(userID, goalID)
+	goal, err := h.services.GetGoalByID(userID, goalID)
 	if err != nil {
 		logger.Error("Error getting goal by ID: %v", err)
 		http.Error(w, "Goal not found", http.StatusNotFound)
@@ -287,7 +336,7 @@ func GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/{id} [patch]
-func UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	// Extract goal ID from URL
@@ -326,6 +375,13 @@ func UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		updates.SavedAmount = *req.SavedAmount
 	}
+	if req.APY != nil {
+		if *req.APY < 0 || *req.APY > services.MaxGoalAPY {
+			http.Error(w, "APY must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+		updates.APY = req.APY
+	}
 
 	// Additional validation: if both amounts are provided, check relationship
 	if req.TotalAmount != nil && req.SavedAmount != nil {
@@ -335,7 +391,7 @@ func UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	updatedGoal, err := services.UpdateGoal(userID, goalID, updates)
+	updatedGoal, err := h.services.UpdateGoal(userID, goalID, updates)
 	if err != nil {
 		logger.Error("Error updating goal: %v", err)
 		if strings.Contains(err.Error(), "not found") {
@@ -363,7 +419,7 @@ func UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/{id} [delete]
-func DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	// Extract goal ID from URL
@@ -373,7 +429,7 @@ func DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := services.DeleteGoal(userID, goalID)
+	err := h.services.DeleteGoal(userID, goalID)
 	if err != nil {
 		logger.Error("Error deleting goal: %v", err)
 		if strings.Contains(err.Error(), "not found") {
@@ -399,7 +455,7 @@ func DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/{id}/restore [post]
-func RestoreGoalHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RestoreGoalHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	// Extract goal ID from URL path
@@ -410,7 +466,7 @@ func RestoreGoalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	restoredGoal, err := services.RestoreGoal(userID, goalID)
+	restoredGoal, err := h.services.RestoreGoal(userID, goalID)
 	if err != nil {
 		logger.Error("Error restoring goal: %v", err)
 		if strings.Contains(err.Error(), "not found") {
@@ -443,10 +499,11 @@ type ChangeGoalStatusRequest struct {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security bearerAuth
 // @Router /api/v1/goals/{id}/status [patch]
-func ChangeGoalStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ChangeGoalStatusHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	// Extract goal ID from URL path
@@ -475,10 +532,12 @@ func ChangeGoalStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedGoal, err := services.ChangeGoalStatus(userID, goalID, newStatus)
+	updatedGoal, err := h.services.ChangeGoalStatus(userID, goalID, newStatus)
 	if err != nil {
 		logger.Error("Error changing goal status: %v", err)
-		if strings.Contains(err.Error(), "not found") {
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Goal not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error changing goal status", http.StatusInternalServerError)
@@ -490,3 +549,85 @@ func ChangeGoalStatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// CreateGoalMilestoneRequest represents a custom milestone on a goal.
+// Exactly one of percent or amount must be set.
+type CreateGoalMilestoneRequest struct {
+	Percent *float64      `json:"percent,omitempty" example:"10"`
+	Amount  *models.Money `json:"amount,omitempty" example:"1000.00"`
+	Label   *string       `json:"label,omitempty" example:"First thousand"`
+}
+
+// GoalMilestonesHandler lists or adds the milestones of a goal
+// @Summary List or add goal milestones
+// @Description GET returns the milestones of a goal (25/50/75/100% by default plus custom ones) with the date each was first crossed, for progress charts. POST adds a custom milestone as a percent or an amount; crossing a milestone emits a goal.milestone_reached event.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Param milestone body CreateGoalMilestoneRequest false "Custom milestone (POST only)"
+// @Success 200 {object} dto.GoalMilestones
+// @Success 201 {object} dto.GoalMilestones
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/{id}/milestones [get]
+// @Router /api/v1/goals/{id}/milestones [post]
+func (h *Handlers) GoalMilestonesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	// Extract goal ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/goals/")
+	goalID := strings.TrimSuffix(path, "/milestones")
+	if goalID == "" || goalID == path {
+		http.Error(w, "Goal ID is required", http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		var req CreateGoalMilestoneRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		milestone := models.GoalMilestone{
+			Percent: req.Percent,
+			Amount:  req.Amount,
+			Label:   req.Label,
+		}
+		if err := h.services.AddGoalMilestone(userID, goalID, &milestone); err != nil {
+			switch {
+			case strings.Contains(err.Error(), "not found"):
+				http.Error(w, "Goal not found", http.StatusNotFound)
+			case errors.Is(err, services.ErrGoalMilestoneExists):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case strings.Contains(err.Error(), "error creating"):
+				http.Error(w, "Error creating goal milestone", http.StatusInternalServerError)
+			default:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	milestones, err := h.services.GetGoalMilestones(userID, goalID)
+	if err != nil {
+		logger.Error("Error getting goal milestones: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Goal not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error getting goal milestones", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(milestones)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type GoalContributionResponse struct {
+	ID         string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount     models.Money `json:"amount" example:"37.50"`
+	Source     string       `json:"source" example:"interest"` // manual, sweep, round_up or interest
+	IsInterest bool         `json:"is_interest" example:"true"`
+	Date       string       `json:"date" example:"2024-01-31"`
+	CreatedAt  string       `json:"created_at" example:"2024-02-01T00:00:00Z"`
+}
+
+type GoalContributionsListResponse struct {
+	Contributions []GoalContributionResponse `json:"contributions"`
+	Count         int                        `json:"count" example:"4"`
+	Interest      models.Money               `json:"interest" example:"112.40"` // Total earned as interest
+}
+
+func convertGoalContributionToResponse(contribution *models.GoalContribution) GoalContributionResponse {
+	return GoalContributionResponse{
+		ID:         contribution.ID.String(),
+		Amount:     contribution.Amount,
+		Source:     contribution.Source,
+		IsInterest: contribution.IsInterest,
+		Date:       contribution.Date.Format("2006-01-02"),
+		CreatedAt:  contribution.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// GetGoalProjectionHandler estimates when a goal completes
+// @Summary Project goal completion
+// @Description Estimates when a goal completes saving the same amount every month, compounding the interest of its APY monthly, and when it would without interest. Without monthly_contribution the average of the last 3 months of contributions is used. Goals not reached in 50 years have no completion date
+// @Tags goals
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Param monthly_contribution query number false "Amount saved every month"
+// @Success 200 {object} dto.GoalProjection
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/{id}/projection [get]
+func (h *Handlers) GetGoalProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	// Extract goal ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/goals/")
+	goalID := strings.TrimSuffix(path, "/projection")
+	if goalID == "" || goalID == path {
+		http.Error(w, "Goal ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var monthlyContribution *models.Money
+	if value := r.URL.Query().Get("monthly_contribution"); value != "" {
+		parsed, err := models.ParseMoney(value)
+		if err != nil {
+			http.Error(w, "Invalid monthly_contribution", http.StatusBadRequest)
+			return
+		}
+		monthlyContribution = &parsed
+	}
+
+	projection, err := h.services.ProjectGoal(userID, goalID, monthlyContribution)
+	if err != nil {
+		logger.Error("Error projecting goal: %v", err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "Goal not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error projecting goal", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
+
+// GetGoalContributionsHandler lists the money added to a goal
+// @Summary List goal contributions
+// @Description Lists what was added to a goal, newest first: waterfall fundings and the monthly interest of its APY, flagged with is_interest
+// @Tags goals
+// @Produce json
+// @Param id path string true "Goal ID"
+// @Success 200 {object} GoalContributionsListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/{id}/contributions [get]
+func (h *Handlers) GetGoalContributionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	// Extract goal ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/goals/")
+	goalID := strings.TrimSuffix(path, "/contributions")
+	if goalID == "" || goalID == path {
+		http.Error(w, "Goal ID is required", http.StatusBadRequest)
+		return
+	}
+
+	contributions, err := h.services.GetGoalContributions(userID, goalID)
+	if err != nil {
+		logger.Error("Error getting goal contributions: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Goal not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error retrieving goal contributions", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	currency := h.services.GetUserCurrency(userID)
+	response := GoalContributionsListResponse{
+		Contributions: make([]GoalContributionResponse, len(contributions)),
+		Count:         len(contributions),
+	}
+	for i := range contributions {
+		response.Contributions[i] = convertGoalContributionToResponse(&contributions[i])
+		if contributions[i].IsInterest {
+			response.Interest = currency.RoundMoney(response.Interest + contributions[i].Amount)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type GoalPrioritiesRequest struct {
+	GoalIDs []string `json:"goal_ids" example:"123e4567-e89b-12d3-a456-426614174000"` // Highest priority first
+}
+
+type FundGoalsRequest struct {
+	Amount models.Money `json:"amount" example:"500.00"`
+	Source string       `json:"source,omitempty" example:"manual"` // manual, sweep or round_up
+}
+
+type GoalWaterfallSettingsRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+type GoalWaterfallSettingsResponse struct {
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// UpdateGoalPrioritiesHandler changes the funding order of the goals
+// @Summary Reorder goal priorities
+// @Description Sets the order in which the waterfall funds active goals. Listed goals come first, in the given order; goals left out keep their relative order after them.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param request body GoalPrioritiesRequest true "Goal IDs, highest priority first"
+// @Success 200 {object} GoalsListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/priorities [put]
+func (h *Handlers) UpdateGoalPrioritiesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var req GoalPrioritiesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	goals, err := h.services.ReorderGoalPriorities(userID, req.GoalIDs)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "error "):
+			logger.Error("Error reordering goals: %v", err)
+			http.Error(w, "Error updating goal priorities", http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	goalResponses := make([]GoalResponse, 0, len(goals))
+	for _, goal := range goals {
+		goalResponses = append(goalResponses, convertGoalToResponse(&goal))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GoalsListResponse{Goals: goalResponses, Count: len(goalResponses)})
+}
+
+// GoalWaterfallHandler previews or applies the funding waterfall
+// @Summary Preview or fund goals by priority
+// @Description GET previews how an amount would be split across active goals in priority order, each goal filled up to its total before moving to the next. POST applies the split, adds it to the saved amounts and emits a goal.funded event per goal. Sweeps and round-ups (source sweep or round_up) need the waterfall enabled in the settings.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param amount query number false "Amount to split (GET only)"
+// @Param request body FundGoalsRequest false "Amount and source (POST only)"
+// @Success 200 {object} dto.GoalWaterfall
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/waterfall [get]
+// @Router /api/v1/goals/waterfall [post]
+func (h *Handlers) GoalWaterfallHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var amount models.Money
+	source := ""
+	switch r.Method {
+	case http.MethodGet:
+		parsed, err := models.ParseMoney(r.URL.Query().Get("amount"))
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		amount = parsed
+
+	case http.MethodPost:
+		var req FundGoalsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		amount = req.Amount
+		source = req.Source
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var waterfall *dto.GoalWaterfall
+	var err error
+	if r.Method == http.MethodGet {
+		waterfall, err = h.services.PreviewGoalWaterfall(userID, amount)
+	} else {
+		waterfall, err = h.services.FundGoalsWaterfall(userID, amount, source)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrGoalWaterfallDisabled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "error "):
+			logger.Error("Error in goal waterfall: %v", err)
+			http.Error(w, "Error processing goal waterfall", http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(waterfall)
+}
+
+// GoalWaterfallSettingsHandler reads or changes the waterfall option
+// @Summary Get or update the goal funding waterfall option
+// @Description GET returns whether sweeps and round-ups fund goals in priority order; PUT turns it on or off. Manual fundings through POST /goals/waterfall always work.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param request body GoalWaterfallSettingsRequest false "Option (PUT only)"
+// @Success 200 {object} GoalWaterfallSettingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security bearerAuth
+// @Router /api/v1/goals/waterfall/settings [get]
+// @Router /api/v1/goals/waterfall/settings [put]
+func (h *Handlers) GoalWaterfallSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var enabled bool
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		enabled, err = h.services.GetGoalWaterfallEnabled(userID)
+
+	case http.MethodPut:
+		var req GoalWaterfallSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		err = h.services.SetGoalWaterfallEnabled(userID, req.Enabled)
+		enabled = req.Enabled
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, "Error processing goal waterfall settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GoalWaterfallSettingsResponse{Enabled: enabled})
+}
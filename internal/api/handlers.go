@@ -0,0 +1,13 @@
+package api
+
+import "github.com/Osminalx/fluxio/internal/services"
+
+// Handlers are the endpoints working on the services they are built with
+type Handlers struct {
+	services *services.Services
+}
+
+// NewHandlers returns the endpoints backed by the services
+func NewHandlers(svc *services.Services) *Handlers {
+	return &Handlers{services: svc}
+}
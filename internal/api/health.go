@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds the database check of a readiness probe
+const readinessTimeout = 2 * time.Second
+
+// Request and response structures
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LivenessHandler godoc
+// @Summary Liveness probe
+// @Description Answers as long as the process serves HTTP; it doesn't check dependencies, so a database outage doesn't get the instance restarted
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /health/live [get]
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "alive"})
+}
+
+// ReadinessHandler godoc
+// @Summary Readiness probe
+// @Description Checks that Postgres answers and the instance isn't shutting down; returns 503 otherwise so no traffic is routed to it
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /health/ready [get]
+func (h *Handlers) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.services.CheckReadiness(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(HealthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ready"})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type HolidayCountryRequest struct {
+	Country string `json:"country" example:"MX"` // ISO 3166-1 alpha-2 code of a supported calendar, or empty for weekends only
+}
+
+// HolidaySettingsHandler godoc
+// @Summary Get or set the holiday calendar
+// @Description GET returns the bank holiday calendar the user's scheduled items follow and the supported ones. PUT sets it; fixed expenses with skip_holidays due on a weekend or one of its holidays are posted the next business day
+// @Tags holidays
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body HolidayCountryRequest false "Country (PUT only)"
+// @Success 200 {object} dto.HolidaySettings
+// @Failure 400 {string} string "Unsupported country"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/holidays/settings [get]
+// @Router /api/v1/holidays/settings [put]
+func (h *Handlers) HolidaySettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err := h.services.GetHolidaySettings(userID)
+		if err != nil {
+			http.Error(w, "Error getting holiday settings", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+
+	case http.MethodPut:
+		var req HolidayCountryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err := h.services.SetHolidayCountry(userID, req.Country)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating holiday settings", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetUpcomingHolidaysHandler godoc
+// @Summary List upcoming holidays
+// @Description Lists the bank holidays of the user's calendar in the next days, each with the fixed expenses due on it and the day they will be posted
+// @Tags holidays
+// @Produce json
+// @Security bearerAuth
+// @Param days query int false "Days ahead to look (1-365, default 60)"
+// @Success 200 {object} dto.UpcomingHolidays
+// @Failure 400 {string} string "Invalid days"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/holidays/upcoming [get]
+func (h *Handlers) GetUpcomingHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := 60
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > services.MaxUpcomingHolidayDays {
+			http.Error(w, "Invalid days: must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	holidays, err := h.services.GetUpcomingHolidays(userID, days)
+	if err != nil {
+		logger.Error("Error getting upcoming holidays: %v", err)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error getting upcoming holidays", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type ImportTransactionRequest struct {
+	Date        string       `json:"date" example:"2024-01-15"`
+	Amount      models.Money `json:"amount" example:"45.90"`
+	Description *string      `json:"description,omitempty" example:"SUPERMARKET 0423"`
+	ExternalID  *string      `json:"external_id,omitempty" example:"TX-998877"` // Bank reference, used to skip rows already imported
+}
+
+type CreateImportRequest struct {
+	BankAccountID string                     `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	CategoryID    string                     `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174001"` // For expenses created from the import
+	Source        *string                    `json:"source,omitempty" example:"statement-2024-01.csv"`
+	Transactions  []ImportTransactionRequest `json:"transactions"`
+}
+
+type ResolveImportMatchRequest struct {
+	Strategy string            `json:"strategy" example:"merge-fields"` // keep-mine, keep-imported or merge-fields
+	Fields   map[string]string `json:"fields,omitempty"`                // merge-fields only: field -> mine | imported
+}
+
+type ExpenseProvenanceResponse struct {
+	ExpenseID string                `json:"expense_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Fields    []dto.FieldProvenance `json:"fields"`
+}
+
+// CreateImportHandler godoc
+// @Summary Import bank transactions
+// @Description Imports transactions into a bank account. Rows that look like an existing manual expense (same amount, dates up to 3 days apart) are held as matches to resolve; the rest become expenses. Rows whose external_id was already imported into the account are skipped as duplicates. Descriptions are run through merchant enrichment: recognized merchants give the expense its name and, when the user has a category with the merchant's usual category name, that category instead of category_id.
+// @Tags import
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateImportRequest true "Transactions to import"
+// @Success 201 {object} dto.ImportBatch
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Bank account or category not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/import [post]
+func (h *Handlers) CreateImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transactions := make([]services.ImportTransactionInput, 0, len(req.Transactions))
+	for _, item := range req.Transactions {
+		date, err := parseDate(item.Date)
+		if err != nil {
+			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		transactions = append(transactions, services.ImportTransactionInput{
+			Date:        date,
+			Amount:      item.Amount,
+			Description: item.Description,
+			ExternalID:  item.ExternalID,
+		})
+	}
+
+	batch, err := h.services.CreateImportBatch(r.Context(), userID, req.BankAccountID, req.CategoryID, req.Source, transactions)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "error creating"):
+			http.Error(w, "Error creating import", http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(batch)
+}
+
+// GetImportHandler godoc
+// @Summary Get an import batch
+// @Description Returns an import batch with each imported transaction and the matches waiting to be resolved
+// @Tags import
+// @Produce json
+// @Security bearerAuth
+// @Param batch path string true "Import batch ID"
+// @Success 200 {object} dto.ImportBatch
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Import batch not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/import/{batch} [get]
+func (h *Handlers) GetImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	batchID := extractIDFromPath(r.URL.Path, "/api/v1/import/")
+	if batchID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.services.GetImportBatch(userID, batchID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, "Error retrieving import", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// ResolveImportMatchHandler godoc
+// @Summary Resolve an import match
+// @Description Settles a likely duplicate between an imported transaction and a manual expense. keep-mine leaves the expense untouched, keep-imported overwrites amount, date and description with the imported values, and merge-fields picks the source per field. The provenance of each field is returned.
+// @Tags import
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param batch path string true "Import batch ID"
+// @Param id path string true "Match ID"
+// @Param request body ResolveImportMatchRequest true "Resolution strategy"
+// @Success 200 {object} dto.ImportMatchResolution
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Import match not found"
+// @Failure 409 {string} string "Match already resolved or expense is split"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/import/{batch}/matches/{id}/resolve [post]
+func (h *Handlers) ResolveImportMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/import/{batch}/matches/{id}/resolve
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/import/"), "/")
+	if len(parts) != 4 || parts[0] == "" || parts[1] != "matches" || parts[2] == "" || parts[3] != "resolve" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveImportMatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resolution, err := h.services.ResolveImportMatch(userID, parts[0], parts[2], req.Strategy, req.Fields)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImportMatchResolved), errors.Is(err, services.ErrSplitExpenseLedgerChange):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "must be"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error resolving import match", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resolution)
+}
+
+// GetExpenseProvenanceHandler godoc
+// @Summary Get the provenance of an expense's fields
+// @Description Tells, for amount, date and description, whether the current value was entered by hand or came from an imported transaction
+// @Tags expense
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Expense ID"
+// @Success 200 {object} ExpenseProvenanceResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Expense not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/{id}/provenance [get]
+func (h *Handlers) GetExpenseProvenanceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/expenses/")
+	if id == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	provenance, err := h.services.GetExpenseProvenance(userID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Expense not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error retrieving expense provenance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExpenseProvenanceResponse{ExpenseID: id, Fields: provenance})
+}
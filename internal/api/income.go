@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
@@ -13,32 +14,48 @@ import (
 
 // Request and response structures
 type CreateIncomeRequest struct {
-	Amount        float64 `json:"amount" example:"2500.50"`
-	BankAccountID string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        models.Money `json:"amount" example:"2500.50"`
+	BankAccountID *string `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Optional; the income is added to its balance
 	Date          string  `json:"date" example:"2024-01-15"`
+	Source        string  `json:"source,omitempty" example:"salary"` // salary, freelance, business, dividends, interest, rental, gift or other (default); refunds get refund
+	Description   *string `json:"description,omitempty" example:"January payroll"`
+	// Optional: marks this income as a refund of an existing expense
+	RefundOfExpenseID *string `json:"refund_of_expense_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	// Free-form; tags the user doesn't have yet are created
+	Tags []string `json:"tags,omitempty" example:"freelance,work"`
 }
 
 type UpdateIncomeRequest struct {
-	Amount        *float64 `json:"amount,omitempty" example:"2800.75"`
-	BankAccountID *string  `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        *models.Money `json:"amount,omitempty" example:"2800.75"`
+	BankAccountID *string  `json:"bank_account_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // "" unlinks the account, taking the income out of its balance
 	Date          *string  `json:"date,omitempty" example:"2024-01-16"`
+	Source        *string  `json:"source,omitempty" example:"freelance"`
+	Description   *string  `json:"description,omitempty" example:"Website redesign invoice"` // "" clears it
+	Tags          []string `json:"tags,omitempty" example:"freelance"` // Replaces the current tags; [] removes them
 }
 
 type IncomeResponse struct {
     ID                string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
-    Amount            float64 `json:"amount" example:"2500.50"`
-    BankAccountID     string  `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+    Amount            models.Money `json:"amount" example:"2500.50"`
+    BankAccountID     *string `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"` // Null when the income isn't tied to an account
     BankAccountName   string  `json:"bank_account_name" example:"Main Account"`
     Date              string  `json:"date" example:"2024-01-15"`
+    Source            string  `json:"source" example:"salary"`
+    SourceName        string  `json:"source_name" example:"Salary"`
+    Description       *string `json:"description,omitempty" example:"January payroll"`
+    RefundOfExpenseID *string `json:"refund_of_expense_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
     Status            string  `json:"status" example:"active"`
     StatusChangedAt   *string `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+    AllowedStatuses   []string `json:"allowed_statuses" example:"pending,suspended,archived,locked,deleted"` // Statuses it can be changed to
     CreatedAt         string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
     UpdatedAt         string  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+    Tags              []TagResponse `json:"tags,omitempty"`
 }
 
 type IncomesListResponse struct {
 	Incomes []IncomeResponse `json:"incomes"`
 	Count   int              `json:"count" example:"5"`
+	*PageResponse
 }
 
 // Helper function to convert model to response
@@ -46,29 +63,42 @@ func convertIncomeToResponse(income *models.Income) IncomeResponse {
     response := IncomeResponse{
         ID:              income.ID.String(),
         Amount:          income.Amount,
-        BankAccountID:   income.BankAccountID.String(),
         BankAccountName: "",
         Date:            income.Date.Format("2006-01-02"),
+        Source:          income.Source,
+        SourceName:      models.GetIncomeSourceName(income.Source),
+        Description:     income.Description,
         Status:          string(income.Status),
-        CreatedAt:       income.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-        UpdatedAt:       income.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+        CreatedAt:       income.CreatedAt.Format(time.RFC3339),
+        UpdatedAt:       income.UpdatedAt.Format(time.RFC3339),
+        AllowedStatuses: allowedStatuses(models.IncomeStatusMachine, income.Status),
     }
 
-    if income.BankAccount.AccountName != "" {
+    if income.BankAccountID != nil {
+        bankAccountID := income.BankAccountID.String()
+        response.BankAccountID = &bankAccountID
+    }
+    if income.BankAccount != nil {
         response.BankAccountName = income.BankAccount.AccountName
     }
     
     if income.StatusChangedAt != nil {
-        statusChangedAt := income.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+        statusChangedAt := income.StatusChangedAt.Format(time.RFC3339)
         response.StatusChangedAt = &statusChangedAt
     }
+
+    if income.RefundOfExpenseID != nil {
+        refundOf := income.RefundOfExpenseID.String()
+        response.RefundOfExpenseID = &refundOf
+    }
+    response.Tags = convertTagsToResponse(income.Tags)
     
     return response
 }
 
 // CreateIncomeHandler godoc
 // @Summary Create a new income
-// @Description Creates a new income for the authenticated user
+// @Description Creates a new income for the authenticated user. The bank account is optional: when given, the income is added to its balance. source says where the money came from (salary, freelance, business, dividends, interest, rental, gift or other, the default) and drives the income breakdown of the cash-flow and score reports; refunds of an expense always get the refund source
 // @Tags income
 // @Accept json
 // @Produce json
@@ -77,9 +107,10 @@ func convertIncomeToResponse(income *models.Income) IncomeResponse {
 // @Success 201 {object} IncomeResponse
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Income in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes [post]
-func CreateIncomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -110,22 +141,22 @@ func CreateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.BankAccountID == "" {
-		http.Error(w, "Bank account ID is required", http.StatusBadRequest)
-		return
-	}
-
-	// Parse bank account ID
-	bankAccountID, err := uuid.Parse(req.BankAccountID)
-	if err != nil {
-		http.Error(w, "Invalid bank account ID format", http.StatusBadRequest)
-		return
-	}
-
 	// Create the model
 	income := &models.Income{
-		Amount:        req.Amount,
-		BankAccountID: bankAccountID,
+		Amount:      req.Amount,
+		Source:      req.Source,
+		Description: req.Description,
+		Tags:        tagsFromNames(req.Tags),
+	}
+
+	// Parse the bank account ID if provided
+	if req.BankAccountID != nil && *req.BankAccountID != "" {
+		bankAccountID, err := uuid.Parse(*req.BankAccountID)
+		if err != nil {
+			http.Error(w, "Invalid bank account ID format", http.StatusBadRequest)
+			return
+		}
+		income.BankAccountID = &bankAccountID
 	}
 
 	// Parse the date
@@ -136,15 +167,32 @@ func CreateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 		income.Date = date
 	}
 
+	// Parse the refunded expense ID if provided
+	if req.RefundOfExpenseID != nil {
+		expenseID, err := uuid.Parse(*req.RefundOfExpenseID)
+		if err != nil {
+			http.Error(w, "Invalid refunded expense ID format", http.StatusBadRequest)
+			return
+		}
+		income.RefundOfExpenseID = &expenseID
+	}
+
     // Create in the database
-    if err := services.CreateIncome(userID, income); err != nil {
+    if err := h.services.CreateIncome(userID, income); err != nil {
 		logger.Error("Error creating income: %v", err)
-		http.Error(w, "Error creating income", http.StatusInternalServerError)
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "refund") || strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error creating income", http.StatusInternalServerError)
+		}
 		return
 	}
 
     // Reload with relations so we can return bank account name
-    createdIncome, err := services.GetIncomeByID(userID, income.ID.String())
+    createdIncome, err := h.services.GetIncomeByID(userID, income.ID.String())
     if err != nil {
         logger.Error("Error retrieving created income: %v", err)
         http.Error(w, "Error retrieving income", http.StatusInternalServerError)
@@ -173,7 +221,7 @@ func CreateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Income not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/{id} [get]
-func GetIncomeByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetIncomeByIDHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -193,7 +241,7 @@ func GetIncomeByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the income
-	income, err := services.GetIncomeByID(userID, id)
+	income, err := h.services.GetIncomeByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting income: %v", err)
 		http.Error(w, "Income not found", http.StatusNotFound)
@@ -214,11 +262,19 @@ func GetIncomeByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security bearerAuth
 // @Param include_deleted query boolean false "Include deleted incomes"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} IncomesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/incomes [get]
-func GetAllIncomesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetAllIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -230,14 +286,33 @@ func GetAllIncomesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listNotModified(w, r, h.services, userID, "incomes", "bank_accounts", "tags") {
+		return
+	}
+
 	// Check parameter to include deleted
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get incomes
-	incomes, err := services.GetAllIncomes(userID, includeDeleted)
+	incomes, info, err := h.services.GetAllIncomes(userID, includeDeleted, tags, page)
 	if err != nil {
 		logger.Error("Error getting incomes: %v", err)
-		http.Error(w, "Error retrieving incomes", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving incomes", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -248,8 +323,9 @@ func GetAllIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := IncomesListResponse{
-		Incomes: incomeResponses,
-		Count:   len(incomeResponses),
+		Incomes:      incomeResponses,
+		Count:        len(incomeResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -263,11 +339,17 @@ func GetAllIncomesHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} IncomesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/active [get]
-func GetActiveIncomesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetActiveIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -279,10 +361,25 @@ func GetActiveIncomesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	incomes, err := services.GetActiveIncomes(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	incomes, info, err := h.services.GetActiveIncomes(userID, tags, page)
 	if err != nil {
 		logger.Error("Error getting active incomes: %v", err)
-		http.Error(w, "Error retrieving active incomes", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving active incomes", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -292,8 +389,9 @@ func GetActiveIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := IncomesListResponse{
-		Incomes: incomeResponses,
-		Count:   len(incomeResponses),
+		Incomes:      incomeResponses,
+		Count:        len(incomeResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -307,11 +405,17 @@ func GetActiveIncomesHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param tags query string false "Comma separated tags, e.g. vacation,work"
+// @Param tags_match query string false "any (default): records with any of the tags; all: with every tag"
 // @Success 200 {object} IncomesListResponse
+// @Failure 400 {string} string "Invalid pagination or tag parameters"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/deleted [get]
-func GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -323,10 +427,25 @@ func GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	incomes, err := services.GetDeletedIncomes(userID)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	incomes, info, err := h.services.GetDeletedIncomes(userID, tags, page)
 	if err != nil {
 		logger.Error("Error getting deleted incomes: %v", err)
-		http.Error(w, "Error retrieving deleted incomes", http.StatusInternalServerError)
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error retrieving deleted incomes", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -336,8 +455,9 @@ func GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := IncomesListResponse{
-		Incomes: incomeResponses,
-		Count:   len(incomeResponses),
+		Incomes:      incomeResponses,
+		Count:        len(incomeResponses),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -346,7 +466,7 @@ func GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateIncomeHandler godoc
 // @Summary Update an income
-// @Description Updates partially an income for the authenticated user
+// @Description Updates partially an income for the authenticated user. An empty bank_account_id unlinks the account and an empty description clears it. Refunds keep the refund source
 // @Tags income
 // @Accept json
 // @Produce json
@@ -357,9 +477,10 @@ func GetDeletedIncomesHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Income not found"
+// @Failure 409 {string} string "Income in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/{id} [patch]
-func UpdateIncomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -404,25 +525,50 @@ func UpdateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// An empty bank account or description clears it
+	var clear []string
 	if req.BankAccountID != nil {
 		if *req.BankAccountID == "" {
-			http.Error(w, "Bank account ID cannot be empty", http.StatusBadRequest)
-			return
+			clear = append(clear, services.IncomeClearBankAccount)
+		} else {
+			bankAccountID, err := uuid.Parse(*req.BankAccountID)
+			if err != nil {
+				http.Error(w, "Invalid bank account ID format", http.StatusBadRequest)
+				return
+			}
+			income.BankAccountID = &bankAccountID
 		}
-		// Parse bank account ID
-		bankAccountID, err := uuid.Parse(*req.BankAccountID)
-		if err != nil {
-			http.Error(w, "Invalid bank account ID format", http.StatusBadRequest)
+	}
+
+	if req.Source != nil {
+		if *req.Source == "" {
+			http.Error(w, "Source cannot be empty", http.StatusBadRequest)
 			return
 		}
-		income.BankAccountID = bankAccountID
+		income.Source = *req.Source
+	}
+	if req.Description != nil {
+		if *req.Description == "" {
+			clear = append(clear, services.IncomeClearDescription)
+		} else {
+			income.Description = req.Description
+		}
+	}
+
+	if req.Tags != nil {
+		income.Tags = tagsFromNames(req.Tags)
 	}
 
 	// Update in the database
-	updatedIncome, err := services.PatchIncome(userID, id, income)
+	updatedIncome, err := h.services.PatchIncome(userID, id, income, clear...)
 	if err != nil {
 		logger.Error("Error updating income: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "refund") || strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Income not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error updating income", http.StatusInternalServerError)
@@ -448,9 +594,10 @@ func UpdateIncomeHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid ID"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Income not found"
+// @Failure 409 {string} string "Status transition not allowed or income in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/{id} [delete]
-func DeleteIncomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) DeleteIncomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -468,9 +615,14 @@ func DeleteIncomeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := services.SoftDeleteIncome(userID, id); err != nil {
+	if err := h.services.SoftDeleteIncome(userID, id); err != nil {
 		logger.Error("Error deleting income: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already deleted") {
 			http.Error(w, "Income not found or already deleted", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error deleting income", http.StatusInternalServerError)
@@ -493,9 +645,10 @@ func DeleteIncomeHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid ID"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Income not found or not deleted"
+// @Failure 409 {string} string "Income in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/{id}/restore [post]
-func RestoreIncomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RestoreIncomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -513,10 +666,15 @@ func RestoreIncomeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	restoredIncome, err := services.RestoreIncome(userID, id)
+	restoredIncome, err := h.services.RestoreIncome(userID, id)
 	if err != nil {
 		logger.Error("Error restoring income: %v", err)
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not deleted") || strings.Contains(err.Error(), "access denied") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "refund") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not deleted") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Income not found, not deleted, or access denied", http.StatusNotFound)
 		} else {
 			http.Error(w, "Error restoring income", http.StatusInternalServerError)
@@ -543,9 +701,10 @@ func RestoreIncomeHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {string} string "Income not found"
+// @Failure 409 {string} string "Status transition not allowed or income in a closed month"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/incomes/{id}/status [patch]
-func ChangeIncomeStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ChangeIncomeStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -578,10 +737,15 @@ func ChangeIncomeStatusHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert string to Status
 	status := models.Status(req.Status)
 
-	updatedIncome, err := services.ChangeIncomeStatus(userID, id, status, req.Reason)
+	updatedIncome, err := h.services.ChangeIncomeStatus(userID, id, status, req.Reason)
 	if err != nil {
 		logger.Error("Error changing income status: %v", err)
-		if strings.Contains(err.Error(), "invalid status") {
+		if writeMonthClosed(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "invalid status") {
 			http.Error(w, "Invalid status", http.StatusBadRequest)
 		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Income not found", http.StatusNotFound)
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type BenchmarkOptInRequest struct {
+	OptIn bool `json:"opt_in" example:"true"`
+}
+
+type BenchmarkOptInResponse struct {
+	OptIn bool `json:"opt_in" example:"true"`
+}
+
+// defaultInsightMonths is the period insights and forecasts look back on by default
+const defaultInsightMonths = 3
+
+// insightMonths reads the months query parameter
+func insightMonths(r *http.Request) (int, error) {
+	monthsStr := r.URL.Query().Get("months")
+	if monthsStr == "" {
+		return defaultInsightMonths, nil
+	}
+	return strconv.Atoi(monthsStr)
+}
+
+// GetSpendingInsightsHandler godoc
+// @Summary Get spending insights
+// @Description Plain-language insights drawn from the user's expenses of the last months: how much expense sizes vary (level from the standard deviation against the mean), the weekday with most expenses, the typical (median) expense, the average spend on days with spending, the largest expense and the number of categories used. No insights are given without expenses.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param months query int false "Months to look back on (1-24)" default(3)
+// @Success 200 {object} dto.SpendingInsights
+// @Failure 400 {string} string "Invalid months"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/insights [get]
+func (h *Handlers) GetSpendingInsightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	months, err := insightMonths(r)
+	if err != nil {
+		http.Error(w, "Invalid months", http.StatusBadRequest)
+		return
+	}
+
+	insights, err := h.services.GetSpendingInsights(userID, months)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error calculating insights", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(insights)
+}
+
+// GetSpendingForecastHandler godoc
+// @Summary Forecast next month's spending
+// @Description Projects next month's spend per expense type (needs, wants, savings) from the last complete months, counting recent months more: the latest weighs as many times as there are months, the oldest once. The trend compares the projection with the plain average (10% or more is up or down). Amounts are net of refunds.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param months query int false "Complete months to project from (1-24)" default(3)
+// @Param group_id query string false "Only spend paid from the accounts in this account group"
+// @Success 200 {object} dto.SpendingForecast
+// @Failure 400 {string} string "Invalid months"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Account group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/insights/forecast [get]
+func (h *Handlers) GetSpendingForecastHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	months, err := insightMonths(r)
+	if err != nil {
+		http.Error(w, "Invalid months", http.StatusBadRequest)
+		return
+	}
+
+	accounts, ok := h.accountGroupFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	forecast, err := h.services.GetSpendingForecast(userID, months, accounts)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error calculating spending forecast", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
+
+// GetSpendingBenchmarksHandler godoc
+// @Summary Get category spending benchmarks
+// @Description Shows where the user's monthly spend per category falls among all users who opted in (e.g. "your transport spend is in the 70th percentile"). Only available to users who opted in; categories with too few participants are not benchmarked.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param month query string false "Month (YYYY-MM), defaults to the previous month"
+// @Success 200 {object} dto.SpendingBenchmarks
+// @Failure 400 {string} string "Invalid month format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Benchmarking is not enabled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/insights/benchmarks [get]
+func (h *Handlers) GetSpendingBenchmarksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The current month is still incomplete, so compare the previous one by default
+	month := h.services.UserNow(userID).AddDate(0, -1, 0)
+	if monthStr := r.URL.Query().Get("month"); monthStr != "" {
+		parsed, err := parseMonth(monthStr)
+		if err != nil {
+			http.Error(w, "Invalid month format. Use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	benchmarks, err := h.services.GetSpendingBenchmarks(userID, month)
+	if err != nil {
+		if errors.Is(err, services.ErrBenchmarkNotOptedIn) {
+			http.Error(w, "Benchmarking is not enabled. Opt in at /api/v1/insights/benchmarks/opt-in first", http.StatusForbidden)
+			return
+		}
+		logger.Error("Error getting spending benchmarks: %v", err)
+		http.Error(w, "Error calculating benchmarks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(benchmarks)
+}
+
+// BenchmarkOptInHandler godoc
+// @Summary Get or update benchmark participation
+// @Description GET returns whether the user shares anonymized spending in benchmarks; PUT opts in or out
+// @Tags insights
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body BenchmarkOptInRequest false "Participation (PUT only)"
+// @Success 200 {object} BenchmarkOptInResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/insights/benchmarks/opt-in [get]
+// @Router /api/v1/insights/benchmarks/opt-in [put]
+func (h *Handlers) BenchmarkOptInHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var optIn bool
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		optIn, err = h.services.GetBenchmarkOptIn(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving benchmark preference", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPut:
+		var req BenchmarkOptInRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.services.SetBenchmarkOptIn(userID, req.OptIn); err != nil {
+			http.Error(w, "Error updating benchmark preference", http.StatusInternalServerError)
+			return
+		}
+		optIn = req.OptIn
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BenchmarkOptInResponse{OptIn: optIn})
+}
+
+type BudgetReviewCadenceRequest struct {
+	Cadence string `json:"cadence" example:"monthly" enums:"weekly,monthly,"`
+}
+
+// BudgetReviewHandler godoc
+// @Summary Get or set budget review reminders
+// @Description GET tells how up to date the user's budget reviews are: when a budget_review reminder was last completed and how many are waiting. PUT sets the cadence (weekly, monthly, or empty to stop); a budget_review reminder linking to the budget-vs-actual report of the last week or month is then created at the start of each period.
+// @Tags insights
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body BudgetReviewCadenceRequest false "Cadence (PUT only)"
+// @Success 200 {object} dto.BudgetReviewStatus
+// @Failure 400 {string} string "Invalid cadence"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/insights/budget-review [get]
+// @Router /api/v1/insights/budget-review [put]
+func (h *Handlers) BudgetReviewHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Nothing to change, only the status is returned
+
+	case http.MethodPut:
+		var req BudgetReviewCadenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.services.SetBudgetReviewCadence(userID, req.Cadence); err != nil {
+			if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating budget review cadence", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := h.services.GetBudgetReviewStatus(userID)
+	if err != nil {
+		http.Error(w, "Error getting budget review status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// liveStreamKeepAliveInterval is how often an idle live stream sends a comment so proxies keep
+// the connection open
+const liveStreamKeepAliveInterval = 15 * time.Second
+
+// writeLiveEvent sends the event as a server-sent event named after its type. Derived events
+// carry no ID, so a reconnecting client resumes after the last stored event
+func writeLiveEvent(w http.ResponseWriter, controller *http.ResponseController, event dto.LiveEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return controller.Flush()
+}
+
+// StreamLiveEventsHandler godoc
+// @Summary Follow live updates
+// @Description Server-sent events stream of the user's changes, so every open client stays up to date without polling. Each event is named after its type (expense.created, income.created, budget.created, budget.updated, budget.exceeded, transfer.completed, reminder.due, goal.funded) with the event ID as the SSE ID; balance.changed follows the events that move money with the balances of every active account. A client reconnecting with Last-Event-ID (or last_event_id) first gets the events it missed, up to 100. Comments are sent periodically to keep the connection open
+// @Tags live
+// @Produce text/event-stream
+// @Security bearerAuth
+// @Param types query string false "Comma-separated event types to receive (default: all)"
+// @Param last_event_id query string false "Resume after this event, for clients that can't send Last-Event-ID"
+// @Success 200 {object} dto.LiveEvent
+// @Failure 400 {string} string "Invalid event type or last event ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /api/v1/stream [get]
+func (h *Handlers) StreamLiveEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var wanted map[string]bool
+	if value := r.URL.Query().Get("types"); value != "" {
+		known := make(map[string]bool)
+		for _, eventType := range services.LiveEventTypes() {
+			known[eventType] = true
+		}
+		wanted = make(map[string]bool)
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !known[eventType] {
+				http.Error(w, "Invalid event type: "+eventType, http.StatusBadRequest)
+				return
+			}
+			wanted[eventType] = true
+		}
+	}
+
+	// Subscribe before replaying so nothing slips in between; replayed events aren't sent twice
+	events, cancel := services.SubscribeLiveEvents(userID)
+	defer cancel()
+
+	var replay []dto.LiveEvent
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		var err error
+		if replay, err = h.services.ListLiveEventsSince(userID, lastEventID); err != nil {
+			http.Error(w, "Invalid last event ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		logger.Warn("Live stream of user %s can't be flushed: %v", userID, err)
+		return
+	}
+
+	replayed := make(map[string]bool, len(replay))
+	for _, event := range replay {
+		replayed[event.ID] = true
+		if wanted != nil && !wanted[event.Type] {
+			continue
+		}
+		if err := writeLiveEvent(w, controller, event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(liveStreamKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-services.StreamsClosing():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case event := <-events:
+			if (event.ID != "" && replayed[event.ID]) || (wanted != nil && !wanted[event.Type]) {
+				continue
+			}
+			if err := writeLiveEvent(w, controller, event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type MaintenanceModeRequest struct {
+	Enabled           bool   `json:"enabled" example:"true"`
+	Reason            string `json:"reason,omitempty" example:"Database migration"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty" example:"600"`
+}
+
+// MaintenanceModeHandler godoc
+// @Summary Get or toggle maintenance mode (admin)
+// @Description GET returns the maintenance switch; PUT turns read-only mode on or off on every instance. While it is on, writes return 503 with a Retry-After header and reads keep working.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body MaintenanceModeRequest false "Maintenance switch (PUT only)"
+// @Success 200 {object} services.MaintenanceStatus
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/maintenance [get]
+// @Router /api/v1/admin/maintenance [put]
+func (h *Handlers) MaintenanceModeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var status services.MaintenanceStatus
+
+	switch r.Method {
+	case http.MethodGet:
+		status = h.services.GetMaintenanceStatus()
+
+	case http.MethodPut:
+		var req MaintenanceModeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		status, err = h.services.SetMaintenanceMode(userID, req.Enabled, req.Reason, req.RetryAfterSeconds)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "error ") {
+				http.Error(w, "Error saving maintenance mode", http.StatusInternalServerError)
+			} else {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
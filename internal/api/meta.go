@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// GetEnumsHandler godoc
+// @Summary List enumerations
+// @Description Lists the statuses, expense types, recurrence types, reminder types and cap modes the server accepts, with labels in the requested locale (en, es) and the status transitions each entity allows
+// @Tags meta
+// @Produce json
+// @Security bearerAuth
+// @Param lang query string false "Locale of the labels; defaults to the Accept-Language header, then en"
+// @Success 200 {object} dto.Enums
+// @Failure 401 {string} string "Unauthorized"
+// @Router /api/v1/meta/enums [get]
+func GetEnumsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	locale := r.URL.Query().Get("lang")
+	if locale == "" {
+		locale = r.Header.Get("Accept-Language")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Vary", "Accept-Language")
+	json.NewEncoder(w).Encode(services.GetEnums(locale))
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// Request and response structures
+type NotificationResponse struct {
+	ID              string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventType       string  `json:"event_type" example:"reminder.due"`
+	EntityType      string  `json:"entity_type" example:"reminder"`
+	EntityID        string  `json:"entity_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	DueDate         string  `json:"due_date" example:"2024-01-15"`
+	Channel         string  `json:"channel" example:"push"`
+	Status          string  `json:"status" example:"delivered"`                                                 // held, sent, delivered, acknowledged, suppressed or failed
+	Reason          *string `json:"reason,omitempty" example:"channel muted"`                                   // Why it was suppressed or failed
+	EscalatedFromID *string `json:"escalated_from_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Delivery this one replaced
+	SentAt          *string `json:"sent_at,omitempty" example:"2024-01-15T09:00:00Z"`
+	DispatchedAt    *string `json:"dispatched_at,omitempty" example:"2024-01-15T09:00:01Z"` // When the push service or mail server took it
+	DeliveredAt     *string `json:"delivered_at,omitempty" example:"2024-01-15T09:00:05Z"`
+	AcknowledgedAt  *string `json:"acknowledged_at,omitempty" example:"2024-01-15T09:30:00Z"`
+	EscalatedAt     *string `json:"escalated_at,omitempty" example:"2024-01-15T13:00:00Z"`
+	CreatedAt       string  `json:"created_at" example:"2024-01-15T09:00:00Z"`
+}
+
+type NotificationsListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	Count         int                    `json:"count" example:"5"`
+	*PageResponse
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
+func convertNotificationToResponse(delivery *models.NotificationDelivery) NotificationResponse {
+	response := NotificationResponse{
+		ID:             delivery.ID.String(),
+		EventType:      delivery.EventType,
+		EntityType:     delivery.EntityType,
+		EntityID:       delivery.EntityID.String(),
+		DueDate:        delivery.DueDate.Format("2006-01-02"),
+		Channel:        delivery.Channel,
+		Status:         delivery.Status,
+		Reason:         delivery.Reason,
+		SentAt:         formatOptionalTime(delivery.SentAt),
+		DispatchedAt:   formatOptionalTime(delivery.DispatchedAt),
+		DeliveredAt:    formatOptionalTime(delivery.DeliveredAt),
+		AcknowledgedAt: formatOptionalTime(delivery.AcknowledgedAt),
+		EscalatedAt:    formatOptionalTime(delivery.EscalatedAt),
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+	}
+	if delivery.EscalatedFromID != nil {
+		escalatedFrom := delivery.EscalatedFromID.String()
+		response.EscalatedFromID = &escalatedFrom
+	}
+	return response
+}
+
+// GetNotificationsHandler godoc
+// @Summary List notifications
+// @Description Lists the notifications sent to the authenticated user on every channel, newest first, with how far each got. Suppressed and failed ones record why they weren't sent.
+// @Tags notifications
+// @Produce json
+// @Security bearerAuth
+// @Param status query string false "Only this status: held, sent, delivered, acknowledged, suppressed or failed"
+// @Param channel query string false "Only this channel: push, email or in_app"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} NotificationsListResponse
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications [get]
+func (h *Handlers) GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.NotificationStatusHeld, models.NotificationStatusSent, models.NotificationStatusDelivered,
+		models.NotificationStatusAcknowledged, models.NotificationStatusSuppressed, models.NotificationStatusFailed:
+	default:
+		http.Error(w, "Invalid status: use held, sent, delivered, acknowledged, suppressed or failed", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, info, err := h.services.GetNotificationDeliveries(userID, status, r.URL.Query().Get("channel"), page)
+	if err != nil {
+		http.Error(w, "Error retrieving notifications", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]NotificationResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = convertNotificationToResponse(&deliveries[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NotificationsListResponse{Notifications: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
+
+// writeNotificationUpdate answers a delivered or acknowledge call
+func writeNotificationUpdate(w http.ResponseWriter, r *http.Request, update func(userID, deliveryID string) (*models.NotificationDelivery, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/notifications/{id}/delivered or /acknowledge
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 {
+		http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := update(userID, pathParts[3])
+	if err != nil {
+		switch {
+		case err.Error() == "notification not found":
+			http.Error(w, "Notification not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error updating notification", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertNotificationToResponse(delivery))
+}
+
+// MarkNotificationDeliveredHandler godoc
+// @Summary Report a notification delivered
+// @Description Called by the client when a notification reaches the device. Escalation rules with unless=delivered stop here; the default ones wait for the acknowledgement.
+// @Tags notifications
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} NotificationResponse
+// @Failure 400 {string} string "Invalid notification ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Notification not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/{id}/delivered [post]
+func (h *Handlers) MarkNotificationDeliveredHandler(w http.ResponseWriter, r *http.Request) {
+	writeNotificationUpdate(w, r, h.services.MarkNotificationDelivered)
+}
+
+// AcknowledgeNotificationHandler godoc
+// @Summary Acknowledge a notification
+// @Description Called by the client when the user opens or dismisses a notification. It stops the escalation to another channel, e.g. the email sent for a due reminder whose push went unanswered.
+// @Tags notifications
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} NotificationResponse
+// @Failure 400 {string} string "Invalid notification ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Notification not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/{id}/acknowledge [post]
+func (h *Handlers) AcknowledgeNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	writeNotificationUpdate(w, r, h.services.AcknowledgeNotification)
+}
+
+// GetReminderDeliveriesHandler godoc
+// @Summary Delivery history of a reminder
+// @Description Lists the notifications sent for a reminder, oldest first: the push when it fell due, and the email it was escalated to when the push went unacknowledged.
+// @Tags reminders
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Reminder ID"
+// @Success 200 {object} NotificationsListResponse
+// @Failure 400 {string} string "Invalid reminder ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Reminder not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/reminders/{id}/deliveries [get]
+func (h *Handlers) GetReminderDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/reminders/{id}/deliveries
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 {
+		http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.services.GetReminderDeliveries(userID, pathParts[3])
+	if err != nil {
+		switch {
+		case err.Error() == "reminder not found":
+			http.Error(w, "Reminder not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error retrieving reminder deliveries", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	responses := make([]NotificationResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = convertNotificationToResponse(&deliveries[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NotificationsListResponse{Notifications: responses, Count: len(responses)})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// NotificationSettingsHandler godoc
+// @Summary Get or replace notification settings
+// @Description GET returns the quiet hours, per-channel muting, per-entity mutes and batching rules of the authenticated user; PUT replaces them. Muting a category silences its budget alerts. A batching rule coalesces bursts of one event type (e.g. expense.* for imports) within window_minutes into a single digest per channel. Escalation rules resend a notification on another channel when it isn't delivered or acknowledged within after_hours; by default a due reminder push is followed by an email after REMINDER_ESCALATION_HOURS (4).
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body services.NotificationSettings false "New settings (PUT only)"
+// @Success 200 {object} services.NotificationSettings
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/settings [get]
+// @Router /api/v1/notifications/settings [put]
+func (h *Handlers) NotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var settings *services.NotificationSettings
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err = h.services.GetNotificationSettings(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving notification settings", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPut:
+		var req services.NotificationSettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		settings, err = h.services.UpdateNotificationSettings(userID, &req)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "require") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating notification settings", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/auth/oidc"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type OAuthStartResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=..."`
+}
+
+// appleUser is the user field Apple posts to the callback on the first sign-in only
+type appleUser struct {
+	Name struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	} `json:"name"`
+}
+
+// oauthStateCookie keeps the hash of the sign-in state in the browser that started it
+const oauthStateCookie = "oauth_state"
+
+// setOAuthStateCookie binds the sign-in to the browser until the state expires. Providers that
+// post the callback from their own site need SameSite=None, as Lax cookies are left out of
+// cross-site posts
+func setOAuthStateCookie(w http.ResponseWriter, start *services.OAuthStart) {
+	sameSite := http.SameSiteLaxMode
+	if start.FormPost {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    start.Binding,
+		Path:     "/api/v1/auth/oauth/",
+		Expires:  start.ExpiresAt,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// clearOAuthStateCookie drops the binding once the callback used it
+func clearOAuthStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/api/v1/auth/oauth/", MaxAge: -1, Secure: true, HttpOnly: true})
+}
+
+// oauthProviderFromPath reads the provider of /api/v1/auth/oauth/{provider}/...
+func oauthProviderFromPath(path string) string {
+	return extractIDFromPath(path, "/api/v1/auth/oauth/")
+}
+
+// OAuthStartHandler godoc
+// @Summary Start signing in with Google or Apple
+// @Description Redirects to the provider's sign-in page (authorization code flow with PKCE). With redirect_uri, which must be listed in OAUTH_REDIRECT_URIS, the callback forwards the tokens to it in the URL fragment; without it the callback answers with them as JSON. Clients that can't follow the redirect send Accept: application/json to get the URL instead. The sign-in is bound to the browser with a short-lived oauth_state cookie, so the start must be opened in the browser that reaches the callback.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "google or apple"
+// @Param redirect_uri query string false "Client URI that receives the tokens"
+// @Success 200 {object} OAuthStartResponse
+// @Success 302 {string} string "Redirect to the provider"
+// @Failure 400 {string} string "Redirect URI not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Provider not configured"
+// @Router /api/v1/auth/oauth/{provider}/start [get]
+func (h *Handlers) OAuthStartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start, err := h.services.StartOAuthLogin(oauthProviderFromPath(r.URL.Path), r.URL.Query().Get("redirect_uri"))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	setOAuthStateCookie(w, start)
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OAuthStartResponse{AuthorizationURL: start.AuthorizationURL})
+		return
+	}
+	http.Redirect(w, r, start.AuthorizationURL, http.StatusFound)
+}
+
+// OAuthCallbackHandler godoc
+// @Summary Finish signing in with Google or Apple
+// @Description Called by the provider after the user signs in (GET for Google, form POST for Apple). The identity is signed in to the account it was linked to before; on its first sign-in it is linked to the account with the same email when the provider verified the address, or a new account is created. Without a redirect_uri at the start the tokens are returned as JSON, otherwise the browser is sent to it with token, refresh_token and expires_in in the URL fragment.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param provider path string true "google or apple"
+// @Param state query string true "State from the start"
+// @Param code query string true "Authorization code"
+// @Success 200 {object} AuthResponse
+// @Success 302 {string} string "Redirect to the client with the tokens"
+// @Failure 400 {string} string "Invalid or expired sign-in state, or one started in another browser"
+// @Failure 401 {string} string "The provider's identity didn't verify"
+// @Failure 403 {string} string "Cuenta bloqueada"
+// @Failure 409 {string} string "An account with this email exists and the provider didn't verify the address"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Provider not configured"
+// @Router /api/v1/auth/oauth/{provider}/callback [get]
+func (h *Handlers) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("error") != "" {
+		http.Error(w, "Sign-in was cancelled or denied at the provider", http.StatusBadRequest)
+		return
+	}
+
+	var name string
+	if raw := r.Form.Get("user"); raw != "" {
+		var user appleUser
+		if err := json.Unmarshal([]byte(raw), &user); err == nil {
+			name = strings.TrimSpace(user.Name.FirstName + " " + user.Name.LastName)
+		}
+	}
+
+	var binding string
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		binding = cookie.Value
+	}
+	clearOAuthStateCookie(w)
+
+	result, err := h.services.CompleteOAuthLogin(r.Context(), oauthProviderFromPath(r.URL.Path), r.Form.Get("state"), binding, r.Form.Get("code"), name)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	user := result.User
+
+	// Locked accounts can't start sessions until an admin unlocks them
+	if !user.IsAccessible() {
+		http.Error(w, "User account is not accessible", http.StatusForbidden)
+		return
+	}
+
+	// The provider already authenticated the user, so there's no step-up; the login still
+	// counts as history for later password logins
+	login := loginContextFromRequest(r)
+	risk, err := h.services.AssessLoginRisk(user.ID, login)
+	if err != nil {
+		http.Error(w, "Error checking login", http.StatusInternalServerError)
+		return
+	}
+	if err := h.services.RecordSuccessfulLogin(user.ID, models.SecurityEventOAuthLogin, login, risk); err != nil {
+		logger.Error("Error recording login for user %s: %v", user.ID, err)
+	}
+
+	response, err := h.newAuthResponse(r, user)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	if result.RedirectURI != nil {
+		fragment := url.Values{
+			"token":         {response.Token},
+			"refresh_token": {response.RefreshToken},
+			"expires_in":    {strconv.FormatInt(response.ExpiresIn, 10)},
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, *result.RedirectURI+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeOAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrNotConfigured):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, oidc.ErrInvalidToken):
+		http.Error(w, "Sign-in could not be verified with the provider", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrOAuthEmailInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// PageResponse is embedded in paginated list responses. It's only present on endpoints that
+// support limit/offset/cursor
+type PageResponse struct {
+	Total      int64   `json:"total" example:"120"`                     // Rows across all pages
+	NextCursor *string `json:"next_cursor,omitempty" example:"bzE6NTA"` // Pass as cursor to get the next page; absent on the last one
+}
+
+func newPageResponse(info services.PageInfo) *PageResponse {
+	return &PageResponse{Total: info.Total, NextCursor: info.NextCursor}
+}
+
+// parsePageRequest reads the limit, offset and cursor query parameters. Without a limit the
+// first DefaultPageLimit rows are returned
+func parsePageRequest(r *http.Request) (services.PageRequest, error) {
+	var page services.PageRequest
+	query := r.URL.Query()
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return page, errors.New("invalid limit: must be between 1 and " + strconv.Itoa(services.MaxPageLimit))
+		}
+		page.Limit = limit
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			return page, errors.New("invalid offset: must be zero or positive")
+		}
+		page.Offset = offset
+	}
+	page.Cursor = query.Get("cursor")
+
+	if err := page.Validate(); err != nil {
+		return page, err
+	}
+	return page, nil
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetPushConfigHandler godoc
+// @Summary Push notification setup
+// @Description Lists the platforms this server can push to. Browsers subscribe with vapid_public_key as the applicationServerKey of PushManager.subscribe; apps register their FCM token.
+// @Tags notifications
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} services.PushConfig
+// @Failure 401 {string} string "Unauthorized"
+// @Router /api/v1/notifications/push-config [get]
+func GetPushConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := r.Context().Value("userID").(string); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.GetPushConfig())
+}
+
+// PushSubscriptionsHandler handles GET (list) and POST (register) on /api/v1/notifications/push-subscriptions
+func (h *Handlers) PushSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetPushSubscriptionsHandler(w, r)
+	case http.MethodPost:
+		h.RegisterPushSubscriptionHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetPushSubscriptionsHandler godoc
+// @Summary List push devices
+// @Description Returns the devices and browsers that receive the user's push notifications
+// @Tags notifications
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {array} models.PushSubscription
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/push-subscriptions [get]
+func (h *Handlers) GetPushSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subscriptions, err := h.services.GetPushSubscriptions(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving push subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// RegisterPushSubscriptionHandler godoc
+// @Summary Register a push device
+// @Description Registers an FCM token (platform fcm) or a browser PushSubscription (platform webpush, with endpoint and keys) to receive due and overdue reminders. Web Push endpoints must be on a known push service (FCM, Mozilla, Apple or WNS). Registering a known token of the user updates it; call it whenever the app gets a new token. A token registered to another account is refused until that account removes it. Devices that unsubscribed are removed on the next push.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body services.PushSubscriptionInput true "Device"
+// @Success 201 {object} models.PushSubscription
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Push subscription already registered to another account"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/push-subscriptions [post]
+func (h *Handlers) RegisterPushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.PushSubscriptionInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.services.RegisterPushSubscription(userID, &req)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err.Error() == "push subscription already registered to another account":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Error registering push subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// DeletePushSubscriptionHandler godoc
+// @Summary Remove a push device
+// @Description Stops push notifications to a device, e.g. when the user signs out of it
+// @Tags notifications
+// @Security bearerAuth
+// @Param id path string true "Push subscription ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid push subscription ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Push subscription not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/notifications/push-subscriptions/{id} [delete]
+func (h *Handlers) DeletePushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subscriptionID := strings.TrimPrefix(r.URL.Path, "/api/v1/notifications/push-subscriptions/")
+	if err := h.services.DeletePushSubscription(userID, subscriptionID); err != nil {
+		switch {
+		case err.Error() == "push subscription not found":
+			http.Error(w, "Push subscription not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error deleting push subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
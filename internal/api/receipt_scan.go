@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/ocr"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type ScanReceiptResponse struct {
+	Expense CreateExpenseRequest `json:"expense"` // Prefilled; review it and POST it to /api/v1/expenses
+	Scan    dto.ReceiptScan      `json:"scan"`    // What was read, to highlight the fields that still need input
+}
+
+// ScanReceiptHandler godoc
+// @Summary Scan a receipt
+// @Description Reads the merchant, date and total of a receipt photo (JPEG, PNG or WebP, up to ATTACHMENT_MAX_MB) with the OCR provider in OCR_PROVIDER and returns a prefilled expense to confirm. The category is the merchant's usual one when the user has it, else the one used most for past expenses there; the account is the one of the latest expense. Fields that couldn't be read are left empty, except the date, which defaults to today. Nothing is saved.
+// @Tags expense
+// @Accept multipart/form-data
+// @Produce json
+// @Security bearerAuth
+// @Param file formData file true "Receipt image"
+// @Success 200 {object} ScanReceiptResponse
+// @Failure 400 {string} string "Invalid file"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 413 {string} string "File too large"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Receipt scanning is not configured"
+// @Router /api/v1/expenses/scan [post]
+func (h *Handlers) ScanReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxAttachmentBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "A multipart file field named file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	scan, err := h.services.ScanReceipt(r.Context(), userID, file, header.Size)
+	if err != nil {
+		switch {
+		case errors.Is(err, ocr.ErrNotConfigured):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case strings.Contains(err.Error(), "larger than"):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Error("Error scanning receipt: %v", err)
+			http.Error(w, "Error scanning receipt", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	expense := CreateExpenseRequest{Description: scan.Merchant}
+	if scan.CategoryID != nil {
+		expense.CategoryID = *scan.CategoryID
+	}
+	if scan.Total != nil {
+		expense.Amount = *scan.Total
+	}
+	if scan.BankAccountID != nil {
+		expense.BankAccountID = *scan.BankAccountID
+	}
+	if scan.Date != nil {
+		expense.Date = *scan.Date
+	} else {
+		expense.Date = h.services.UserNow(userID).Format("2006-01-02")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ScanReceiptResponse{Expense: expense, Scan: *scan})
+}
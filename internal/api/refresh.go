@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/Osminalx/fluxio/internal/services"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
@@ -15,17 +17,17 @@ type RefreshTokenRequest struct {
 
 // RefreshTokenHandler godoc
 // @Summary Refresh access token
-// @Description Generates a new access token using a valid refresh token
+// @Description Rotates a valid refresh token for a new token pair of the same session. Each refresh token works once: presenting one that was already rotated revokes its whole session
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body RefreshTokenRequest true "Refresh token"
 // @Success 200 {object} services.TokenPair
 // @Failure 400 {string} string "Invalid request body"
-// @Failure 401 {string} string "Invalid or expired refresh token"
+// @Failure 401 {string} string "Invalid or expired refresh token, or reuse detected"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/auth/refresh [post]
-func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -43,31 +45,24 @@ func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create refresh token service instance
-	refreshTokenService := services.NewRefreshTokenService()
-	
-	// Validate refresh token and get user
-	user, err := refreshTokenService.ValidateRefreshToken(req.RefreshToken)
+	// Rotate the refresh token, sliding the session up to its absolute maximum
+	tokenPair, err := h.services.GenerateSlidingTokenPair(req.RefreshToken, loginContextFromRequest(r))
 	if err != nil {
-		logger.Warn("Failed refresh token attempt: %v", err)
-		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			http.Error(w, "Refresh token already used, the session was revoked", http.StatusUnauthorized)
+		case strings.Contains(err.Error(), "maximum lifetime"):
+			http.Error(w, "Session expired, please log in again", http.StatusUnauthorized)
+		case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "not accessible"):
+			logger.Warn("Failed refresh token attempt: %v", err)
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		default:
+			logger.Error("Error generating new token pair: %v", err)
+			http.Error(w, "Error generating tokens", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// Generate new token pair using auth service
-	tokenPair, err := services.GenerateTokenPair(user)
-	if err != nil {
-		logger.Error("Error generating new token pair: %v", err)
-		http.Error(w, "Error generating tokens", http.StatusInternalServerError)
-		return
-	}
-
-	// Revoke the old refresh token
-	if err := refreshTokenService.RevokeRefreshToken(req.RefreshToken); err != nil {
-		logger.Warn("Failed to revoke old refresh token: %v", err)
-		// Continue anyway, as the new token was generated successfully
-	}
-
 	logger.Info("Token refreshed successfully")
 
 	w.Header().Set("Content-Type", "application/json")
@@ -85,7 +80,7 @@ func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/auth/logout [post]
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -104,7 +99,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create refresh token service instance
-	refreshTokenService := services.NewRefreshTokenService()
+	refreshTokenService := h.services.NewRefreshTokenService()
 	
 	// Revoke the refresh token
 	if err := refreshTokenService.RevokeRefreshToken(req.RefreshToken); err != nil {
@@ -132,7 +127,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/auth/logout-all [post]
-func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -154,7 +149,7 @@ func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create refresh token service instance
-	refreshTokenService := services.NewRefreshTokenService()
+	refreshTokenService := h.services.NewRefreshTokenService()
 	
 	// Revoke all refresh tokens for this user
 	if err := refreshTokenService.RevokeAllUserRefreshTokens(userID); err != nil {
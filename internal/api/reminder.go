@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/Osminalx/fluxio/internal/models"
-	"github.com/Osminalx/fluxio/internal/services"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 )
@@ -43,7 +42,7 @@ type UpdateReminderRequest struct {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders [post]
-func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -81,7 +80,7 @@ func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	reminder, err := reminderService.CreateReminder(userID, req.Title, req.Description, req.DueDate, req.ReminderType)
 	if err != nil {
 		logger.Error("Error creating reminder: %v", err)
@@ -111,8 +110,10 @@ func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {array} models.Reminder
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/reminders [get]
-func GetAllRemindersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetAllRemindersHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -120,6 +121,10 @@ func GetAllRemindersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listNotModified(w, r, h.services, userIDStr, "reminders") {
+		return
+	}
+
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		logger.Error("Invalid userID format: %v", err)
@@ -140,7 +145,7 @@ func GetAllRemindersHandler(w http.ResponseWriter, r *http.Request) {
 		completed = &c
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	
 	var reminders []*models.Reminder
 	
@@ -184,7 +189,7 @@ func GetAllRemindersHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Reminder not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/{id} [get]
-func GetReminderByIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetReminderByIDHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -214,7 +219,7 @@ func GetReminderByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	reminder, err := reminderService.GetReminderByID(reminderID, userID)
 	if err != nil {
 		if err.Error() == "reminder not found" {
@@ -245,7 +250,7 @@ func GetReminderByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Reminder not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/{id} [patch]
-func UpdateReminderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateReminderHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -282,7 +287,7 @@ func UpdateReminderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -333,7 +338,7 @@ func UpdateReminderHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Reminder not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/{id} [delete]
-func DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -363,7 +368,7 @@ func DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	err = reminderService.DeleteReminder(userID, reminderID)
 	if err != nil {
 		if err.Error() == "reminder not found" {
@@ -397,7 +402,7 @@ func DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Reminder not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/{id}/complete [post]
-func CompleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CompleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -427,7 +432,7 @@ func CompleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	
 	// Mark as completed using UpdateReminder
 	updates := map[string]interface{}{
@@ -463,7 +468,7 @@ func CompleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/overdue [get]
-func GetOverdueRemindersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetOverdueRemindersHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -482,7 +487,7 @@ func GetOverdueRemindersHandler(w http.ResponseWriter, r *http.Request) {
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	reminders, err := reminderService.GetOverdueReminders(userID)
 	
 	// Apply manual pagination if needed
@@ -518,11 +523,11 @@ func GetOverdueRemindersHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Security bearerAuth
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} dto.ReminderStats
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/reminders/stats [get]
-func GetReminderStatsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetReminderStatsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context
 	userIDStr, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -537,7 +542,7 @@ func GetReminderStatsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := services.NewReminderService()
+	reminderService := h.services.Reminders
 	stats, err := reminderService.GetReminderStats(userID)
 	if err != nil {
 		logger.Error("Error retrieving reminder stats: %v", err)
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ResolveEntityHandler godoc
+// @Summary Resolve a UUID to its entity
+// @Description Finds which of the user's entities (expense, income, transfer, bank account, category, budget, fixed expense, goal, reminder, trip, account group or import batch) a UUID belongs to and returns a minimal descriptor with its canonical URL. Deleted entities are resolved too. Meant for deep links from notifications and activity feeds.
+// @Tags resolve
+// @Produce json
+// @Security bearerAuth
+// @Param uuid path string true "Any entity ID"
+// @Success 200 {object} dto.ResolvedEntity
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Entity not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/resolve/{uuid} [get]
+func (h *Handlers) ResolveEntityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/resolve/")
+	if id == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entity, err := h.services.ResolveEntity(userID, id)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "Entity not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+		default:
+			http.Error(w, "Error resolving ID", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entity)
+}
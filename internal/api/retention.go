@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type RetentionPoliciesRequest struct {
+	Policies services.RetentionPolicies `json:"policies"` // Days to keep deleted records per entity type, null = forever
+}
+
+type RetentionPoliciesResponse struct {
+	Policies services.RetentionPolicies `json:"policies"`
+}
+
+type EmptyTrashResponse struct {
+	EntityType string `json:"entity_type" example:"expenses"`
+	Purged     int64  `json:"purged" example:"12"` // Records permanently removed
+}
+
+type UpcomingPurgesResponse struct {
+	Days   int                      `json:"days" example:"30"`
+	Purges []services.UpcomingPurge `json:"purges"`
+	Count  int                      `json:"count" example:"4"`
+}
+
+// RetentionPoliciesHandler godoc
+// @Summary Get or update soft-delete retention policies
+// @Description GET returns how long deleted records of each entity type are kept before being purged; PUT updates some of them (null keeps them forever). Expenses, incomes and reminders the user never set a policy for follow the server default (DELETED_RETENTION_DAYS, 90 days unless changed).
+// @Tags retention
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body RetentionPoliciesRequest false "Policies to update (PUT only)"
+// @Success 200 {object} RetentionPoliciesResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/retention/policies [get]
+// @Router /api/v1/retention/policies [put]
+func (h *Handlers) RetentionPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var policies services.RetentionPolicies
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		policies, err = h.services.GetRetentionPolicies(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving retention policies", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPut:
+		var req RetentionPoliciesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		policies, err = h.services.UpdateRetentionPolicies(userID, req.Policies)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid entity type") || strings.Contains(err.Error(), "retention days") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating retention policies", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetentionPoliciesResponse{Policies: policies})
+}
+
+// GetUpcomingPurgesHandler godoc
+// @Summary Report upcoming purges
+// @Description Lists deleted records that will be permanently removed within the next days according to the retention policies
+// @Tags retention
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param days query int false "Look-ahead window in days (1-365, default 30)"
+// @Success 200 {object} UpcomingPurgesResponse
+// @Failure 400 {string} string "Invalid days parameter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/retention/upcoming-purges [get]
+func (h *Handlers) GetUpcomingPurgesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := parseIntParam(daysStr)
+		if err != nil || parsed < 1 || parsed > 365 {
+			http.Error(w, "Invalid days parameter (1-365)", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	purges, err := h.services.GetUpcomingPurges(userID, days)
+	if err != nil {
+		http.Error(w, "Error retrieving upcoming purges", http.StatusInternalServerError)
+		return
+	}
+
+	response := UpcomingPurgesResponse{
+		Days:   days,
+		Purges: purges,
+		Count:  len(purges),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// trashEntityTypes maps the resource of a /deleted path to its retention entity type
+var trashEntityTypes = map[string]string{
+	"expenses":      "expenses",
+	"incomes":       "incomes",
+	"reminders":     "reminders",
+	"goals":         "goals",
+	"bank-accounts": "bank_accounts",
+}
+
+// EmptyTrashHandler godoc
+// @Summary Empty the trash of a resource
+// @Description Permanently removes every deleted record of the resource right away instead of waiting for its retention. Records other rows still point at are kept: bank accounts with expenses, goals with contributions or milestones and refunded expenses. Cannot be undone.
+// @Tags retention
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} EmptyTrashResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expenses/deleted [delete]
+// @Router /api/v1/incomes/deleted [delete]
+// @Router /api/v1/reminders/deleted [delete]
+// @Router /api/v1/goals/deleted [delete]
+// @Router /api/v1/bank-accounts/deleted [delete]
+func (h *Handlers) EmptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resource := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/deleted")
+	entityType, ok := trashEntityTypes[resource]
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	purged, err := h.services.EmptyTrash(userID, entityType)
+	if err != nil {
+		http.Error(w, "Error emptying trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EmptyTrashResponse{EntityType: entityType, Purged: purged})
+}
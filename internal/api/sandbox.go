@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateSandboxRequest struct {
+	Name string `json:"name" example:"CI sandbox"`
+}
+
+type SandboxesListResponse struct {
+	Sandboxes []dto.Sandbox `json:"sandboxes"`
+	Count     int           `json:"count" example:"1"`
+}
+
+type AdvanceSandboxTimeRequest struct {
+	Days  int `json:"days" example:"30"`
+	Hours int `json:"hours" example:"0"`
+}
+
+// SandboxesHandler godoc
+// @Summary List or create sandboxes
+// @Description GET lists the sandbox tenants of the user. POST creates one and returns an API key to act as it; its data is isolated from the owner and it has its own virtual clock.
+// @Tags sandbox
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateSandboxRequest false "Sandbox name (POST)"
+// @Success 200 {object} SandboxesListResponse
+// @Success 201 {object} dto.Sandbox
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Sandboxes can't create sandboxes"
+// @Failure 409 {string} string "Sandbox limit exceeded"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sandbox [get]
+// @Router /api/v1/sandbox [post]
+func (h *Handlers) SandboxesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sandboxes, err := h.services.GetSandboxes(userID)
+		if err != nil {
+			http.Error(w, "Error getting sandboxes", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SandboxesListResponse{Sandboxes: sandboxes, Count: len(sandboxes)})
+
+	case http.MethodPost:
+		if !requireSessionAuth(w, r) {
+			return
+		}
+		var req CreateSandboxRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logger.Error("Error decoding request body: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		sandbox, err := h.services.CreateSandbox(userID, strings.TrimSpace(req.Name))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrSandboxNested):
+				http.Error(w, "Sandboxes can't create sandboxes", http.StatusForbidden)
+			case errors.Is(err, services.ErrSandboxLimitExceeded):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Error creating sandbox", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(sandbox)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetSandboxClockHandler godoc
+// @Summary Get the sandbox clock
+// @Description Returns the real and virtual time of the sandbox tenant making the request (authenticate with the sandbox API key)
+// @Tags sandbox
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} dto.SandboxClock
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Only sandbox tenants have a virtual clock"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sandbox/clock [get]
+func (h *Handlers) GetSandboxClockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clock, err := h.services.GetSandboxClock(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotSandbox) {
+			http.Error(w, "Only sandbox tenants have a virtual clock", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Error getting sandbox clock", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clock)
+}
+
+// AdvanceSandboxTimeHandler godoc
+// @Summary Advance the sandbox clock
+// @Description Moves the virtual clock of the sandbox tenant forward and runs the scheduler-driven work that became due (fixed expense posting), then reports due reminders at the new time. Authenticate with the sandbox API key.
+// @Tags sandbox
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body AdvanceSandboxTimeRequest true "How far to advance"
+// @Success 200 {object} dto.SandboxAdvance
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Only sandbox tenants can advance time"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sandbox/advance-time [post]
+func (h *Handlers) AdvanceSandboxTimeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req AdvanceSandboxTimeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Days < 0 || req.Hours < 0 {
+		http.Error(w, "days and hours can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	by := time.Duration(req.Days)*24*time.Hour + time.Duration(req.Hours)*time.Hour
+	result, err := h.services.AdvanceSandboxClock(userID, by)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotSandbox):
+			http.Error(w, "Only sandbox tenants can advance time", http.StatusForbidden)
+		case strings.Contains(err.Error(), "advance must"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error advancing sandbox clock", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// Response structures
+type SecurityEventsResponse struct {
+	Events []models.SecurityEvent `json:"events"`
+	Count  int                    `json:"count" example:"10"`
+}
+
+// GetSecurityEventsHandler godoc
+// @Summary List recent security events
+// @Description Lists the recent authentication events of the user (logins, failed attempts, step-up verifications) with IP, country, device and risk reasons
+// @Tags security
+// @Produce json
+// @Security bearerAuth
+// @Param limit query int false "Maximum number of events (default 20, max 100)"
+// @Success 200 {object} SecurityEventsResponse
+// @Failure 400 {string} string "Invalid limit"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/security/events [get]
+func (h *Handlers) GetSecurityEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := parseIntParam(limitStr)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			http.Error(w, "Invalid limit. Must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := h.services.GetSecurityEvents(userID, limit)
+	if err != nil {
+		http.Error(w, "Error retrieving security events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SecurityEventsResponse{Events: events, Count: len(events)})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type SessionsListResponse struct {
+	Sessions []dto.Session `json:"sessions"`
+	Count    int           `json:"count" example:"2"`
+}
+
+// currentSessionID returns the session of the access token making the request, if any
+func currentSessionID(r *http.Request) string {
+	if claims, ok := r.Context().Value("userClaims").(*services.Claims); ok {
+		return claims.SessionID
+	}
+	return ""
+}
+
+// GetSessionsHandler godoc
+// @Summary List active sessions
+// @Description Lists the devices signed in to the account, most recently used first. Each session is a login followed through its refresh token rotations; current marks the one making the request
+// @Tags auth
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} SessionsListResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/auth/sessions [get]
+func (h *Handlers) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.services.GetUserSessions(userID, currentSessionID(r))
+	if err != nil {
+		http.Error(w, "Error getting sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SessionsListResponse{Sessions: sessions, Count: len(sessions)})
+}
+
+// RevokeSessionHandler godoc
+// @Summary Revoke a session
+// @Description Signs a device out: the refresh token of the session stops working and its access tokens are rejected
+// @Tags auth
+// @Security bearerAuth
+// @Param id path string true "Session ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid session ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *Handlers) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/api/v1/auth/sessions/")
+	if sessionID == "" || strings.Contains(sessionID, "/") {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.RevokeUserSession(userID, sessionID); err != nil {
+		logger.Warn("Error revoking session %s: %v", sessionID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error revoking session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// UpdateSettingsRequest holds the settings to change; omitted fields are left as they are
+type UpdateSettingsRequest struct {
+	Currency            *string                        `json:"currency,omitempty" example:"MXN"`
+	Timezone            *string                        `json:"timezone,omitempty" example:"America/Mexico_City"` // IANA name, or empty for UTC
+	FirstDayOfWeek      *string                        `json:"first_day_of_week,omitempty" example:"sunday"`
+	FirstDayOfMonth     *int                           `json:"first_day_of_month,omitempty" example:"15"` // 1-28
+	BudgetingMode       *string                        `json:"budgeting_mode,omitempty" example:"70_20_10"`
+	DateFormat          *string                        `json:"date_format,omitempty" example:"DD/MM/YYYY"`
+	BudgetReviewCadence *string                        `json:"budget_review_cadence,omitempty" example:"monthly"`
+	Notifications       *services.NotificationSettings `json:"notifications,omitempty"` // Replaces the notification settings
+}
+
+// SettingsHandler godoc
+// @Summary Get or update the user's settings
+// @Description GET returns every setting of the authenticated user in one object: currency, timezone, first day of the week and of the month, budgeting mode, date format, budget review cadence and notifications. PATCH changes only the fields given; all of them are validated before any is saved. Weekly analytics bins and weekly reviews start on first_day_of_week; monthly analytics bins and monthly reviews run from first_day_of_month. budgeting_mode is how suggested budgets split the declared income (50_30_20, 70_20_10, 60_20_20) or recent_spending to follow the last three months. date_format is for clients; the API always uses ISO 8601
+// @Tags preferences
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body UpdateSettingsRequest false "Settings to change (PATCH only)"
+// @Success 200 {object} services.UserSettings
+// @Failure 400 {string} string "Invalid request body or setting"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/settings [get]
+// @Router /api/v1/settings [patch]
+func (h *Handlers) SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var settings *services.UserSettings
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err = h.services.GetUserSettings(userID)
+		if err != nil {
+			http.Error(w, "Error getting settings", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPatch:
+		var req UpdateSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		settings, err = h.services.UpdateUserSettings(userID, services.UserSettingsUpdate{
+			Currency:            req.Currency,
+			Timezone:            req.Timezone,
+			FirstDayOfWeek:      req.FirstDayOfWeek,
+			FirstDayOfMonth:     req.FirstDayOfMonth,
+			BudgetingMode:       req.BudgetingMode,
+			DateFormat:          req.DateFormat,
+			BudgetReviewCadence: req.BudgetReviewCadence,
+			Notifications:       req.Notifications,
+		})
+		if err != nil {
+			logger.Error("Error updating settings: %v", err)
+			if strings.HasPrefix(err.Error(), "invalid ") || strings.Contains(err.Error(), "require") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating settings", http.StatusInternalServerError)
+			}
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetSpendingTrendsHandler godoc
+// @Summary Monthly spending trends
+// @Description Returns the spend of each of the last months, the current one (to date) last, with a zero for months without spending so every line has one point per month. Each line carries a 3-month trailing moving average and the change versus the month before, in amount and percent (null after a month with no spending). Spend is net of refunds; group_by adds one line per category, expense type or account, biggest first, next to the total.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Param months query int false "Number of months (default 12, at most 60)"
+// @Param group_by query string false "category, type or account"
+// @Success 200 {object} dto.SpendingTrends
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/reports/trends [get]
+func (h *Handlers) GetSpendingTrendsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	months := 12
+	if value := query.Get("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid months parameter", http.StatusBadRequest)
+			return
+		}
+		months = parsed
+	}
+
+	trends, err := h.services.GetSpendingTrends(r.Context(), userID, months, query.Get("group_by"))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// The client went away; nothing left to answer
+			logger.Info("Spending trends cancelled for user %s", userID)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error calculating spending trends", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trends)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GetSpendingVelocityHandler godoc
+// @Summary Rolling spending velocity
+// @Description Returns the spend and daily rate of the last 7, 14 and 30 days, overall, per bucket (needs, wants, savings) and per category, each compared with the same number of days just before. The trend is up or down when the change is 10% or more, flat otherwise. Amounts are net of refunds.
+// @Tags insights
+// @Produce json
+// @Security bearerAuth
+// @Success 200 {object} dto.SpendingVelocity
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/analytics/velocity [get]
+func (h *Handlers) GetSpendingVelocityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	velocity, err := h.services.GetSpendingVelocity(userID)
+	if err != nil {
+		http.Error(w, "Error calculating spending velocity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(velocity)
+}
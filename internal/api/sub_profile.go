@@ -0,0 +1,390 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateSubProfileRequest struct {
+	Name              string        `json:"name" example:"Sofia"`
+	Email             string        `json:"email" example:"sofia@example.com"`
+	Password          string        `json:"password" example:"a-strong-password"`
+	ApprovalThreshold *models.Money `json:"approval_threshold,omitempty" example:"20.00"`
+}
+
+type UpdateSubProfileRequest struct {
+	Name              string        `json:"name,omitempty" example:"Sofia"`
+	ApprovalThreshold *models.Money `json:"approval_threshold" example:"20.00"` // Null means expenses never need approval
+}
+
+type SubProfileAllowanceRequest struct {
+	Amount models.Money `json:"amount" example:"15.00"`
+}
+
+type SubProfilesListResponse struct {
+	SubProfiles []dto.SubProfile `json:"sub_profiles"`
+	Count       int              `json:"count" example:"2"`
+}
+
+type SubProfileOverviewResponse struct {
+	Profile   dto.SubProfile            `json:"profile"`
+	Expenses  []ExpenseResponse         `json:"expenses"` // Latest first
+	Goals     []GoalResponse            `json:"goals"`
+	Approvals []ExpenseApprovalResponse `json:"pending_approvals"`
+}
+
+type ExpenseApprovalResponse struct {
+	ID            string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	SubProfileID  string       `json:"sub_profile_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	CategoryID    string       `json:"category_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	BankAccountID string       `json:"bank_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount        models.Money `json:"amount" example:"35.00"`
+	Date          string       `json:"date" example:"2024-01-15"`
+	Description   *string      `json:"description,omitempty" example:"Video game"`
+	Status        string       `json:"status" example:"pending"`
+	Reason        *string      `json:"reason,omitempty" example:"Too expensive this month"`
+	ExpenseID     *string      `json:"expense_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	DecidedAt     *string      `json:"decided_at,omitempty" example:"2024-01-16T10:00:00Z"`
+	CreatedAt     string       `json:"created_at" example:"2024-01-15T10:00:00Z"`
+}
+
+type ExpenseApprovalsListResponse struct {
+	Approvals []ExpenseApprovalResponse `json:"approvals"`
+	Count     int                       `json:"count" example:"1"`
+}
+
+type DecideExpenseApprovalRequest struct {
+	Reason *string `json:"reason,omitempty" example:"Too expensive this month"`
+}
+
+func convertExpenseApprovalToResponse(approval *models.ExpenseApproval) ExpenseApprovalResponse {
+	response := ExpenseApprovalResponse{
+		ID:            approval.ID.String(),
+		SubProfileID:  approval.UserID.String(),
+		CategoryID:    approval.CategoryID.String(),
+		BankAccountID: approval.BankAccountID.String(),
+		Amount:        approval.Amount,
+		Date:          approval.Date.Format("2006-01-02"),
+		Description:   approval.Description,
+		Status:        approval.Status,
+		Reason:        approval.Reason,
+		CreatedAt:     approval.CreatedAt.Format(time.RFC3339),
+	}
+	if approval.ExpenseID != nil {
+		expenseID := approval.ExpenseID.String()
+		response.ExpenseID = &expenseID
+	}
+	if approval.DecidedAt != nil {
+		decidedAt := approval.DecidedAt.Format(time.RFC3339)
+		response.DecidedAt = &decidedAt
+	}
+	return response
+}
+
+// writeSubProfileError maps sub-profile service errors to responses
+func writeSubProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrSubProfileEmailTaken), errors.Is(err, services.ErrExpenseApprovalDecided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrSubProfileNested):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrSubProfileLimitExceeded):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not active"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing sub-profile", http.StatusInternalServerError)
+	}
+}
+
+// SubProfilesHandler godoc
+// @Summary List or create sub-profiles
+// @Description GET lists the restricted sub-profiles (e.g. kids with an allowance) of the user. POST creates one with its own login, a Wallet account and the default categories. Sub-profiles can only track their own expenses and goals; expenses above approval_threshold wait for the approval of the parent.
+// @Tags sub-profiles
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateSubProfileRequest false "New sub-profile (POST)"
+// @Success 200 {object} SubProfilesListResponse
+// @Success 201 {object} dto.SubProfile
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Sub-profiles and sandbox tenants can't have sub-profiles"
+// @Failure 409 {string} string "A user with this email already exists"
+// @Failure 422 {string} string "Sub-profile limit exceeded"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sub-profiles [get]
+// @Router /api/v1/sub-profiles [post]
+func (h *Handlers) SubProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profiles, err := h.services.GetSubProfiles(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving sub-profiles", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SubProfilesListResponse{SubProfiles: profiles, Count: len(profiles)})
+
+	case http.MethodPost:
+		var req CreateSubProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profile, err := h.services.CreateSubProfile(userID, services.SubProfileInput{
+			Name:              req.Name,
+			Email:             req.Email,
+			Password:          req.Password,
+			ApprovalThreshold: req.ApprovalThreshold,
+		})
+		if err != nil {
+			writeSubProfileError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(profile)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SubProfileHandler godoc
+// @Summary Get, update or remove a sub-profile
+// @Description GET returns the sub-profile with its wallet, latest expenses, goals and pending approvals. PUT sets its name and approval threshold (null never asks for approval). DELETE deactivates it: it can't sign in anymore, pending approvals are rejected and its data is kept.
+// @Tags sub-profiles
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Sub-profile user ID"
+// @Param request body UpdateSubProfileRequest false "Settings (PUT)"
+// @Success 200 {object} SubProfileOverviewResponse
+// @Success 204 "Removed"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Sub-profile not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sub-profiles/{id} [get]
+// @Router /api/v1/sub-profiles/{id} [put]
+// @Router /api/v1/sub-profiles/{id} [delete]
+func (h *Handlers) SubProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/sub-profiles/")
+	if id == "" {
+		http.Error(w, "Sub-profile ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		overview, err := h.services.GetSubProfileOverview(userID, id)
+		if err != nil {
+			writeSubProfileError(w, err)
+			return
+		}
+		response := SubProfileOverviewResponse{
+			Profile:   overview.Profile,
+			Expenses:  make([]ExpenseResponse, len(overview.Expenses)),
+			Goals:     make([]GoalResponse, len(overview.Goals)),
+			Approvals: make([]ExpenseApprovalResponse, len(overview.Approvals)),
+		}
+		for i := range overview.Expenses {
+			response.Expenses[i] = convertExpenseToResponse(&overview.Expenses[i])
+		}
+		for i := range overview.Goals {
+			response.Goals[i] = convertGoalToResponse(&overview.Goals[i])
+		}
+		for i := range overview.Approvals {
+			response.Approvals[i] = convertExpenseApprovalToResponse(&overview.Approvals[i])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case http.MethodPut:
+		var req UpdateSubProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profile, err := h.services.UpdateSubProfile(userID, id, services.SubProfileInput{
+			Name:              req.Name,
+			ApprovalThreshold: req.ApprovalThreshold,
+		})
+		if err != nil {
+			writeSubProfileError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+
+	case http.MethodDelete:
+		if err := h.services.RemoveSubProfile(userID, id); err != nil {
+			writeSubProfileError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SubProfileAllowanceHandler godoc
+// @Summary Pay an allowance to a sub-profile
+// @Description Adds the amount to the Wallet account of the sub-profile, recorded as an income of the sub-profile dated today
+// @Tags sub-profiles
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Sub-profile user ID"
+// @Param request body SubProfileAllowanceRequest true "Allowance"
+// @Success 200 {object} dto.SubProfile
+// @Failure 400 {string} string "Invalid amount"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Sub-profile not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sub-profiles/{id}/allowance [post]
+func (h *Handlers) SubProfileAllowanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/sub-profiles/")
+	var req SubProfileAllowanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.services.AddSubProfileAllowance(userID, id, req.Amount)
+	if err != nil {
+		writeSubProfileError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// GetExpenseApprovalsHandler godoc
+// @Summary List expense approval requests
+// @Description Parents see the requests of all their sub-profiles, a sub-profile sees its own
+// @Tags sub-profiles
+// @Produce json
+// @Security bearerAuth
+// @Param status query string false "pending, approved or rejected"
+// @Success 200 {object} ExpenseApprovalsListResponse
+// @Failure 400 {string} string "Invalid status"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expense-approvals [get]
+func (h *Handlers) GetExpenseApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	approvals, err := h.services.GetExpenseApprovals(userID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeSubProfileError(w, err)
+		return
+	}
+
+	response := ExpenseApprovalsListResponse{Approvals: make([]ExpenseApprovalResponse, len(approvals)), Count: len(approvals)}
+	for i := range approvals {
+		response.Approvals[i] = convertExpenseApprovalToResponse(&approvals[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DecideExpenseApprovalHandler godoc
+// @Summary Approve or reject an expense of a sub-profile
+// @Description Approving creates the expense on the sub-profile's account; rejecting keeps the request with the optional reason. The sub-profile is notified with an expense_approval.decided event.
+// @Tags sub-profiles
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Expense approval ID"
+// @Param request body DecideExpenseApprovalRequest false "Reason (reject)"
+// @Success 200 {object} ExpenseApprovalResponse
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Expense approval not found"
+// @Failure 409 {string} string "Expense approval was already decided"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/expense-approvals/{id}/approve [post]
+// @Router /api/v1/expense-approvals/{id}/reject [post]
+func (h *Handlers) DecideExpenseApprovalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/expense-approvals/")
+	approve := strings.HasSuffix(r.URL.Path, "/approve")
+
+	var req DecideExpenseApprovalRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	approval, err := h.services.DecideExpenseApproval(userID, id, approve, req.Reason)
+	if err != nil {
+		var capErr *services.CategoryCapExceededError
+		if errors.As(err, &capErr) {
+			http.Error(w, capErr.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeSubProfileError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertExpenseApprovalToResponse(approval))
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// syncMaxChanges caps the changes a client can push in one request
+const syncMaxChanges = 500
+
+// SyncResponse is a page of the changes since the cursor. Entities come oldest change first;
+// tombstones name the ones deleted. Pull again with cursor while has_more
+type SyncResponse struct {
+	BankAccounts  []BankAccountFullResponse `json:"bank_accounts"`
+	Categories    []UserCategoryResponse    `json:"categories"`
+	Budgets       []BudgetResponse          `json:"budgets"`
+	Goals         []GoalResponse            `json:"goals"`
+	FixedExpenses []FixedExpenseResponse    `json:"fixed_expenses"`
+	Expenses      []ExpenseResponse         `json:"expenses"`
+	Incomes       []IncomeResponse          `json:"incomes"`
+	Transfers     []TransferResponse        `json:"transfers"`
+	Reminders     []models.Reminder         `json:"reminders"`
+	Tombstones    []dto.SyncTombstone       `json:"tombstones"`
+	Cursor        string                    `json:"cursor" example:"eyJleHBlbnNlIjp7In..."` // Send as since on the next pull
+	HasMore       bool                      `json:"has_more" example:"false"`
+}
+
+// SyncPushRequest is a batch of changes made offline, applied in order
+type SyncPushRequest struct {
+	Strategies map[string]string   `json:"strategies,omitempty"` // Per entity: server_wins (default) or client_wins
+	Changes    []SyncChangeRequest `json:"changes"`
+}
+
+// SyncChangeRequest is one change made offline. data is the whole entity, in the shape of its
+// create request; ids are generated by the client
+type SyncChangeRequest struct {
+	Entity        string          `json:"entity" example:"expense" enums:"expense,income,category"`
+	Op            string          `json:"op" example:"upsert" enums:"upsert,delete"`
+	ID            string          `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	BaseUpdatedAt *string         `json:"base_updated_at,omitempty" example:"2024-01-15T10:30:00.123456Z"` // updated_at of the copy changed, as pulled or pushed; omit for new entities
+	Data          json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+// SyncPushResponse has the outcome of every change, in the order they were sent
+type SyncPushResponse struct {
+	Results []dto.SyncChangeResult `json:"results"`
+}
+
+// SyncHandler godoc
+// @Summary Sync an offline-first client
+// @Description GET returns what changed since the cursor (since) in every entity, deleted ones as tombstones. Without a cursor it returns everything alive. Follow has_more with the returned cursor, at most limit rows per entity each time (default 200, max 500). Changes from the last seconds come in the next pull. Deleted records are removed for good after their retention; a cursor that didn't pull their tombstones first gets 410 and the client must sync again without a cursor. POST applies a batch of changes made offline to expenses, incomes and categories, each on its own and in order. A change conflicts when the server copy was updated after its base_updated_at (or exists for a change without one); pulled expenses, incomes and categories carry updated_at at full precision to send back as base_updated_at; with server_wins (the default) it's skipped and reported, with client_wins it overwrites. Deleting is idempotent; upserting a deleted entity restores it
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param since query string false "Cursor returned by the previous pull"
+// @Param limit query int false "Rows per entity (default 200, max 500)"
+// @Param request body SyncPushRequest false "Changes to apply (POST)"
+// @Success 200 {object} SyncResponse
+// @Success 207 {object} SyncPushResponse
+// @Failure 400 {string} string "Invalid cursor or request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 410 {string} string "Cursor from before deleted records were removed for good; sync again without a cursor"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/sync [get]
+// @Router /api/v1/sync [post]
+func (h *Handlers) SyncHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.pullSync(w, r)
+	case http.MethodPost:
+		h.pushSync(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handlers) pullSync(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 200
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := parseIntParam(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			http.Error(w, "Invalid limit, must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.services.GetSyncChanges(userID, r.URL.Query().Get("since"), limit)
+	if err != nil {
+		if err.Error() == "invalid sync cursor" {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrSyncCursorExpired) {
+			http.Error(w, "Cursor expired: deleted records were removed since, sync again without a cursor", http.StatusGone)
+			return
+		}
+		http.Error(w, "Error listing changes", http.StatusInternalServerError)
+		return
+	}
+
+	response := SyncResponse{
+		BankAccounts:  make([]BankAccountFullResponse, 0, len(page.BankAccounts)),
+		Categories:    make([]UserCategoryResponse, 0, len(page.Categories)),
+		Budgets:       make([]BudgetResponse, 0, len(page.Budgets)),
+		Goals:         make([]GoalResponse, 0, len(page.Goals)),
+		FixedExpenses: make([]FixedExpenseResponse, 0, len(page.FixedExpenses)),
+		Expenses:      make([]ExpenseResponse, 0, len(page.Expenses)),
+		Incomes:       make([]IncomeResponse, 0, len(page.Incomes)),
+		Transfers:     make([]TransferResponse, 0, len(page.Transfers)),
+		Reminders:     page.Reminders,
+		Tombstones:    page.Tombstones,
+		Cursor:        page.Cursor,
+		HasMore:       page.HasMore,
+	}
+	for i := range page.BankAccounts {
+		response.BankAccounts = append(response.BankAccounts, convertBankAccountToResponse(&page.BankAccounts[i]))
+	}
+	// Entities that can be pushed carry updated_at at full precision, so sending it back as
+	// base_updated_at tells apart changes made within the same second
+	for i := range page.Categories {
+		category := convertUserCategoryToResponse(&page.Categories[i])
+		category.UpdatedAt = page.Categories[i].UpdatedAt.Format(time.RFC3339Nano)
+		response.Categories = append(response.Categories, category)
+	}
+	for i := range page.Budgets {
+		response.Budgets = append(response.Budgets, convertBudgetToResponse(&page.Budgets[i]))
+	}
+	for i := range page.Goals {
+		response.Goals = append(response.Goals, convertGoalToResponse(&page.Goals[i]))
+	}
+	for i := range page.FixedExpenses {
+		response.FixedExpenses = append(response.FixedExpenses, convertFixedExpenseToResponse(&page.FixedExpenses[i]))
+	}
+	for i := range page.Expenses {
+		expense := convertExpenseToResponse(&page.Expenses[i])
+		expense.UpdatedAt = page.Expenses[i].UpdatedAt.Format(time.RFC3339Nano)
+		response.Expenses = append(response.Expenses, expense)
+	}
+	for i := range page.Incomes {
+		income := convertIncomeToResponse(&page.Incomes[i])
+		income.UpdatedAt = page.Incomes[i].UpdatedAt.Format(time.RFC3339Nano)
+		response.Incomes = append(response.Incomes, income)
+	}
+	for i := range page.Transfers {
+		response.Transfers = append(response.Transfers, convertTransferToResponse(&page.Transfers[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) pushSync(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) == 0 || len(req.Changes) > syncMaxChanges {
+		http.Error(w, "Between 1 and 500 changes are required", http.StatusBadRequest)
+		return
+	}
+
+	pushable := make(map[string]bool)
+	for _, entity := range services.SyncPushEntities() {
+		pushable[entity] = true
+	}
+	for entity, strategy := range req.Strategies {
+		if !pushable[entity] || (strategy != services.SyncServerWins && strategy != services.SyncClientWins) {
+			http.Error(w, "Invalid strategy for "+entity+", must be server_wins or client_wins", http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes := make([]services.SyncChange, 0, len(req.Changes))
+	for i, changeReq := range req.Changes {
+		change, err := parseSyncChange(changeReq, pushable)
+		if err != nil {
+			http.Error(w, "Invalid change "+strconv.Itoa(i)+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		changes = append(changes, change)
+	}
+
+	results := h.services.ApplySyncChanges(r.Context(), userID, changes, req.Strategies)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(SyncPushResponse{Results: results})
+}
+
+// parseSyncChange checks a pushed change and builds the entity of an upsert from its data
+func parseSyncChange(req SyncChangeRequest, pushable map[string]bool) (services.SyncChange, error) {
+	change := services.SyncChange{Entity: req.Entity, Op: req.Op}
+	if !pushable[req.Entity] {
+		return change, errors.New("entity must be expense, income or category")
+	}
+	if req.Op != services.SyncOpUpsert && req.Op != services.SyncOpDelete {
+		return change, errors.New("op must be upsert or delete")
+	}
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return change, errors.New("invalid id")
+	}
+	change.ID = id
+	if req.BaseUpdatedAt != nil {
+		base, err := time.Parse(time.RFC3339, *req.BaseUpdatedAt)
+		if err != nil {
+			return change, errors.New("invalid base_updated_at, use RFC 3339")
+		}
+		change.BaseUpdatedAt = &base
+	}
+	if req.Op == services.SyncOpDelete {
+		return change, nil
+	}
+
+	switch req.Entity {
+	case "expense":
+		var data CreateExpenseRequest
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return change, errors.New("invalid expense data")
+		}
+		change.Expense, err = syncExpenseFromRequest(data)
+		change.OverrideCap = data.OverrideCap
+		change.ConfirmToken = data.ConfirmToken
+	case "income":
+		var data CreateIncomeRequest
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return change, errors.New("invalid income data")
+		}
+		change.Income, err = syncIncomeFromRequest(data)
+	case "category":
+		var data CreateUserCategoryRequest
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return change, errors.New("invalid category data")
+		}
+		if data.Name == "" || !models.IsValidExpenseType(data.ExpenseType) {
+			return change, errors.New("category name and a valid expense type are required")
+		}
+		change.Category = &models.Category{
+			Name:        data.Name,
+			ExpenseType: models.ExpenseType(data.ExpenseType),
+			Icon:        data.Icon,
+			Color:       data.Color,
+		}
+	}
+	return change, err
+}
+
+func syncExpenseFromRequest(req CreateExpenseRequest) (*models.Expense, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+	categoryID, err := uuid.Parse(req.CategoryID)
+	if err != nil {
+		return nil, errors.New("invalid category ID")
+	}
+	date, err := parseDate(req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date, use YYYY-MM-DD")
+	}
+	expense := &models.Expense{
+		CategoryID:  categoryID,
+		Amount:      req.Amount,
+		Date:        date,
+		Description: req.Description,
+		Tags:        tagsFromNames(req.Tags),
+	}
+	if len(req.Allocations) > 0 {
+		for _, allocation := range req.Allocations {
+			bankAccountID, err := uuid.Parse(allocation.BankAccountID)
+			if err != nil {
+				return nil, errors.New("invalid bank account ID in allocations")
+			}
+			expense.Allocations = append(expense.Allocations, models.ExpenseAllocation{
+				BankAccountID: bankAccountID,
+				Amount:        allocation.Amount,
+			})
+		}
+	} else if expense.BankAccountID, err = uuid.Parse(req.BankAccountID); err != nil {
+		return nil, errors.New("invalid bank account ID")
+	}
+	return expense, nil
+}
+
+func syncIncomeFromRequest(req CreateIncomeRequest) (*models.Income, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+	date, err := parseDate(req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date, use YYYY-MM-DD")
+	}
+	income := &models.Income{
+		Amount:      req.Amount,
+		Date:        date,
+		Source:      req.Source,
+		Description: req.Description,
+		Tags:        tagsFromNames(req.Tags),
+	}
+	if req.BankAccountID != nil && *req.BankAccountID != "" {
+		bankAccountID, err := uuid.Parse(*req.BankAccountID)
+		if err != nil {
+			return nil, errors.New("invalid bank account ID")
+		}
+		income.BankAccountID = &bankAccountID
+	}
+	if req.RefundOfExpenseID != nil {
+		expenseID, err := uuid.Parse(*req.RefundOfExpenseID)
+		if err != nil {
+			return nil, errors.New("invalid refunded expense ID")
+		}
+		income.RefundOfExpenseID = &expenseID
+	}
+	return income, nil
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateTagRequest struct {
+	Name  string  `json:"name" example:"trip to cancún"`
+	Color *string `json:"color,omitempty" example:"#FF8800"`
+}
+
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty" example:"cancún 2024"`
+	Color *string `json:"color,omitempty" example:"#FF8800"` // Empty removes the color
+}
+
+// TagResponse is a tag as shown on an expense or income
+type TagResponse struct {
+	ID    string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name  string  `json:"name" example:"vacation"`
+	Color *string `json:"color,omitempty" example:"#FF8800"`
+}
+
+type TagsListResponse struct {
+	Tags  []dto.Tag `json:"tags"`
+	Count int       `json:"count" example:"8"`
+	*PageResponse
+}
+
+// tagsFromNames turns the tag names of a request into tags for the services to resolve. A nil
+// list stays nil (tags not given) and an empty one stays empty (remove every tag)
+func tagsFromNames(names []string) []models.Tag {
+	if names == nil {
+		return nil
+	}
+	tags := make([]models.Tag, len(names))
+	for i, name := range names {
+		tags[i] = models.Tag{Name: name}
+	}
+	return tags
+}
+
+func convertTagsToResponse(tags []models.Tag) []TagResponse {
+	if len(tags) == 0 {
+		return nil
+	}
+	responses := make([]TagResponse, len(tags))
+	for i, tag := range tags {
+		responses[i] = TagResponse{ID: tag.ID.String(), Name: tag.Name, Color: tag.Color}
+	}
+	return responses
+}
+
+// parseTagFilter reads the comma separated tags query parameter and tags_match (any or all)
+func parseTagFilter(r *http.Request) (services.TagFilter, error) {
+	var filter services.TagFilter
+	query := r.URL.Query()
+	for _, name := range strings.Split(query.Get("tags"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			filter.Names = append(filter.Names, name)
+		}
+	}
+	switch query.Get("tags_match") {
+	case "", "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		return filter, errors.New("invalid tags_match: use any or all")
+	}
+	return filter, nil
+}
+
+// writeTagError maps tag service errors to responses
+func writeTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing tag", http.StatusInternalServerError)
+	}
+}
+
+// TagsHandler godoc
+// @Summary List or create tags
+// @Description GET lists the tags of the user by name with how many expenses and incomes carry each one. POST creates a tag ahead of using it; expenses and incomes also create the tags they name. Names are trimmed and lowercased, so "Vacation" and "vacation" are the same tag.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param q query string false "Only tags starting with this text (GET)"
+// @Param limit query int false "Page size, up to 500; 50 when omitted (GET)"
+// @Param offset query int false "Rows to skip (GET)"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset (GET)"
+// @Param request body CreateTagRequest false "Tag (POST)"
+// @Success 200 {object} TagsListResponse
+// @Success 201 {object} dto.Tag
+// @Failure 400 {string} string "Invalid request body or pagination parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "A tag with this name already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/tags [get]
+// @Router /api/v1/tags [post]
+func (h *Handlers) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		page, err := parsePageRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tags, info, err := h.services.GetTags(userID, r.URL.Query().Get("q"), page)
+		if err != nil {
+			http.Error(w, "Error retrieving tags", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TagsListResponse{Tags: tags, Count: len(tags), PageResponse: newPageResponse(info)})
+
+	case http.MethodPost:
+		var req CreateTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		tag, err := h.services.CreateTag(userID, req.Name, req.Color)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tag)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// TagHandler godoc
+// @Summary Get, update or delete a tag
+// @Description GET returns the tag with its usage, PATCH renames it or changes its color (the tag stays on its records) and DELETE removes it from every expense and income, keeping the records
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Tag ID"
+// @Param request body UpdateTagRequest false "Changes (PATCH)"
+// @Success 200 {object} dto.Tag
+// @Success 204 "Deleted"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Tag not found"
+// @Failure 409 {string} string "A tag with this name already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/tags/{id} [get]
+// @Router /api/v1/tags/{id} [patch]
+// @Router /api/v1/tags/{id} [delete]
+func (h *Handlers) TagHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tagID := extractIDFromPath(r.URL.Path, "/api/v1/tags/")
+	if tagID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var tag *dto.Tag
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		tag, err = h.services.GetTag(userID, tagID)
+
+	case http.MethodPatch:
+		var req UpdateTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		tag, err = h.services.UpdateTag(userID, tagID, req.Name, req.Color)
+
+	case http.MethodDelete:
+		if err := h.services.DeleteTag(userID, tagID); err != nil {
+			writeTagError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeTagError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
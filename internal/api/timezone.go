@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+type TimezoneRequest struct {
+	Timezone string `json:"timezone" example:"America/Mexico_City"` // IANA name, or empty for UTC
+}
+
+// TimezoneSettingsHandler godoc
+// @Summary Get or set the timezone
+// @Description GET returns the timezone the user's days and months follow, with its current offset and date. PUT sets it: "today", the current month and due dates are computed in it, so monthly totals and budgets match the user's calendar near midnight. Dates already stored aren't moved
+// @Tags preferences
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body TimezoneRequest false "Timezone (PUT only)"
+// @Success 200 {object} dto.TimezoneSettings
+// @Failure 400 {string} string "Invalid timezone"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/preferences/timezone [get]
+// @Router /api/v1/preferences/timezone [put]
+func (h *Handlers) TimezoneSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err := h.services.GetTimezoneSettings(userID)
+		if err != nil {
+			http.Error(w, "Error getting timezone", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+
+	case http.MethodPut:
+		var req TimezoneRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err := h.services.SetUserTimezone(userID, req.Timezone)
+		if err != nil {
+			logger.Error("Error updating timezone: %v", err)
+			if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Error updating timezone", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
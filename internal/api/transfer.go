@@ -0,0 +1,358 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// Request and response structures
+type CreateTransferRequest struct {
+	FromAccountID    string       `json:"from_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ToAccountID      string       `json:"to_account_id" example:"123e4567-e89b-12d3-a456-426614174001"`
+	Amount           models.Money `json:"amount" example:"250.00"`
+	Date             string       `json:"date" example:"2024-01-15"`
+	Description      *string      `json:"description,omitempty" example:"Move to savings"`
+	GoalID           *string      `json:"goal_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174002"` // Count it as a contribution to this goal
+	ConfirmDuplicate bool         `json:"confirm_duplicate,omitempty" example:"false"`                      // Create it even if it looks like a double submission
+	ConfirmToken     string       `json:"confirm_token,omitempty"`                                          // From the 428 response of an amount above the confirmation threshold
+}
+
+type TransferResponse struct {
+	ID            string       `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	FromAccountID string       `json:"from_account_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ToAccountID   string       `json:"to_account_id" example:"123e4567-e89b-12d3-a456-426614174001"`
+	Amount        models.Money `json:"amount" example:"250.00"`
+	Date          string       `json:"date" example:"2024-01-15"`
+	Description   *string      `json:"description,omitempty" example:"Move to savings"`
+	GoalID        *string      `json:"goal_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174002"`
+	Status        string       `json:"status" example:"active"`
+	CreatedAt     string       `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+type SetTransferGoalRequest struct {
+	GoalID *string `json:"goal_id" example:"123e4567-e89b-12d3-a456-426614174002"` // null unlinks the transfer
+}
+
+type TransfersListResponse struct {
+	Transfers []TransferResponse `json:"transfers"`
+	Count     int                `json:"count" example:"5"`
+	*PageResponse
+}
+
+// DuplicateTransferResponse is returned with 409 when a transfer looks like a double submission
+type DuplicateTransferResponse struct {
+	Error           string             `json:"error" example:"possible_duplicate"`
+	Message         string             `json:"message" example:"A similar transfer was created moments ago. Resend with confirm_duplicate=true to create it anyway"`
+	Duplicates      []TransferResponse `json:"duplicates"`
+	ConfirmRequired bool               `json:"confirm_required" example:"true"`
+}
+
+// Helper function to convert model to response
+func convertTransferToResponse(transfer *models.Transfer) TransferResponse {
+	response := TransferResponse{
+		ID:            transfer.ID.String(),
+		FromAccountID: transfer.FromAccountID.String(),
+		ToAccountID:   transfer.ToAccountID.String(),
+		Amount:        transfer.Amount,
+		Date:          transfer.Date.Format("2006-01-02"),
+		Description:   transfer.Description,
+		Status:        string(transfer.Status),
+		CreatedAt:     transfer.CreatedAt.Format(time.RFC3339),
+	}
+	if transfer.GoalID != nil {
+		goalID := transfer.GoalID.String()
+		response.GoalID = &goalID
+	}
+	return response
+}
+
+// CreateTransferHandler godoc
+// @Summary Create a transfer
+// @Description Moves money between two bank accounts of the authenticated user. A transfer with the same accounts, amount and date as one created in the last minutes is rejected until resent with confirm_duplicate. With goal_id the transfer also counts as a contribution to that goal
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateTransferRequest true "Transfer data"
+// @Success 201 {object} TransferResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {object} DuplicateTransferResponse "Possible duplicate, confirmation required"
+// @Failure 428 {object} ConfirmationRequiredResponse "Amount above the confirmation threshold, resend with confirm_token; a token confirms a single entry"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers [post]
+func (h *Handlers) CreateTransferHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+		return
+	}
+
+	fromAccountID, err := uuid.Parse(req.FromAccountID)
+	if err != nil {
+		http.Error(w, "Invalid from_account_id format", http.StatusBadRequest)
+		return
+	}
+
+	toAccountID, err := uuid.Parse(req.ToAccountID)
+	if err != nil {
+		http.Error(w, "Invalid to_account_id format", http.StatusBadRequest)
+		return
+	}
+
+	date, err := parseDate(req.Date)
+	if err != nil {
+		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	transfer := &models.Transfer{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        req.Amount,
+		Date:          date,
+		Description:   req.Description,
+	}
+	if req.GoalID != nil {
+		goalID, err := uuid.Parse(*req.GoalID)
+		if err != nil {
+			http.Error(w, "Invalid goal_id format", http.StatusBadRequest)
+			return
+		}
+		transfer.GoalID = &goalID
+	}
+
+	// Amounts above the user's threshold are created once confirmed
+	if err := h.services.RequireTransferConfirmation(userID, transfer, req.ConfirmToken); err != nil {
+		if !writeConfirmationRequired(w, err) {
+			http.Error(w, "Error creating transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.services.CreateTransfer(userID, transfer, req.ConfirmDuplicate); err != nil {
+		if writeConfirmTokenUsed(w, err, func() error {
+			return h.services.RequireTransferConfirmation(userID, transfer, req.ConfirmToken)
+		}) {
+			return
+		}
+		var duplicateErr *services.DuplicateTransferError
+		if errors.As(err, &duplicateErr) {
+			duplicates := make([]TransferResponse, len(duplicateErr.Duplicates))
+			for i, duplicate := range duplicateErr.Duplicates {
+				duplicates[i] = convertTransferToResponse(&duplicate)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(DuplicateTransferResponse{
+				Error:           "possible_duplicate",
+				Message:         "A similar transfer was created moments ago. Resend with confirm_duplicate=true to create it anyway",
+				Duplicates:      duplicates,
+				ConfirmRequired: true,
+			})
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "must be") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error creating transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(convertTransferToResponse(transfer))
+}
+
+// GetAllTransfersHandler godoc
+// @Summary Get all transfers
+// @Description Gets the transfers of the authenticated user, newest first
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} TransfersListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
+// @Router /api/v1/transfers [get]
+func (h *Handlers) GetAllTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if listNotModified(w, r, h.services, userID, "transfers", "bank_accounts") {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfers, info, err := h.services.GetAllTransfers(userID, page)
+	if err != nil {
+		http.Error(w, "Error retrieving transfers", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]TransferResponse, len(transfers))
+	for i, transfer := range transfers {
+		responses[i] = convertTransferToResponse(&transfer)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TransfersListResponse{Transfers: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
+
+// GetTransferByIDHandler godoc
+// @Summary Get a transfer by ID
+// @Description Gets a specific transfer of the authenticated user
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Transfer not found"
+// @Router /api/v1/transfers/{id} [get]
+func (h *Handlers) GetTransferByIDHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/transfers/")
+	if id == "" {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.services.GetTransferByID(userID, id)
+	if err != nil {
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertTransferToResponse(transfer))
+}
+
+// SetTransferGoalHandler godoc
+// @Summary Link a transfer to a goal
+// @Description Counts the transfer as a contribution to the goal: its saved amount grows and the contribution shows in the goal's history. Linking it to another goal moves the contribution; goal_id null unlinks it and takes the contribution back
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Transfer ID"
+// @Param request body SetTransferGoalRequest true "Goal to link, or null"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {string} string "Invalid request body or goal not active"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers/{id}/goal [put]
+func (h *Handlers) SetTransferGoalHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/transfers/")
+	if id == "" {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetTransferGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.services.SetTransferGoal(userID, id, req.GoalID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "transfer not found"):
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+		case strings.Contains(err.Error(), "not found"), strings.HasPrefix(err.Error(), "invalid "):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error updating transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertTransferToResponse(transfer))
+}
+
+// DeleteTransferHandler godoc
+// @Summary Delete a transfer (soft delete)
+// @Description Marks a transfer as deleted and moves the money back between the accounts. A goal contribution made by the transfer is taken back
+// @Tags transfers
+// @Security bearerAuth
+// @Param id path string true "Transfer ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers/{id} [delete]
+func (h *Handlers) DeleteTransferHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := extractIDFromPath(r.URL.Path, "/api/v1/transfers/")
+	if id == "" {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.DeleteTransfer(userID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error deleting transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
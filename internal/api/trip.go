@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateTripRequest struct {
+	Name              string        `json:"name" example:"Lisbon"`
+	StartDate         string        `json:"start_date" example:"2024-07-01"`
+	EndDate           string        `json:"end_date" example:"2024-07-10"`
+	Budget            *models.Money `json:"budget,omitempty" example:"1500.00"`
+	Currency          string        `json:"currency,omitempty" example:"EUR"` // Defaults to the user's currency
+	ExcludeFromBudget bool          `json:"exclude_from_budget" example:"true"`
+}
+
+type UpdateTripRequest struct {
+	Name              *string       `json:"name,omitempty" example:"Lisbon and Porto"`
+	StartDate         *string       `json:"start_date,omitempty" example:"2024-07-01"`
+	EndDate           *string       `json:"end_date,omitempty" example:"2024-07-12"`
+	Budget            *models.Money `json:"budget,omitempty" example:"1800.00"` // 0 removes the budget
+	Currency          *string       `json:"currency,omitempty" example:"EUR"`
+	ExcludeFromBudget *bool         `json:"exclude_from_budget,omitempty" example:"false"`
+}
+
+type TripExpensesRequest struct {
+	ExpenseIDs []string `json:"expense_ids" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+type TripExpensesResponse struct {
+	Assigned int64 `json:"assigned" example:"2"`
+}
+
+type TripsListResponse struct {
+	Trips []models.Trip `json:"trips"`
+	Count int           `json:"count" example:"2"`
+}
+
+// writeTripError maps trip service errors to responses
+func writeTripError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "error "):
+		http.Error(w, "Error processing trip", http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// parseOptionalDate parses a YYYY-MM-DD date that may be missing
+func parseOptionalDate(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	date, err := parseDate(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// TripsHandler godoc
+// @Summary List or create trips
+// @Description GET lists the trips of the user, most recent first. POST creates a trip; expenses dated between its start and end date are associated automatically.
+// @Tags trips
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateTripRequest false "Trip (POST)"
+// @Success 200 {object} TripsListResponse
+// @Success 201 {object} models.Trip
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/trips [get]
+// @Router /api/v1/trips [post]
+func (h *Handlers) TripsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		trips, err := h.services.GetTrips(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving trips", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TripsListResponse{Trips: trips, Count: len(trips)})
+
+	case http.MethodPost:
+		var req CreateTripRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		startDate, err := parseDate(req.StartDate)
+		if err != nil {
+			http.Error(w, "Invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		endDate, err := parseDate(req.EndDate)
+		if err != nil {
+			http.Error(w, "Invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		trip, err := h.services.CreateTrip(userID, models.Trip{
+			Name:              req.Name,
+			StartDate:         startDate,
+			EndDate:           endDate,
+			Budget:            req.Budget,
+			Currency:          req.Currency,
+			ExcludeFromBudget: req.ExcludeFromBudget,
+		})
+		if err != nil {
+			writeTripError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(trip)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// TripHandler godoc
+// @Summary Get, update or delete a trip
+// @Description GET returns a trip, PATCH changes it and DELETE removes it. Deleting a trip keeps its expenses.
+// @Tags trips
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Trip ID"
+// @Param request body UpdateTripRequest false "Fields to change (PATCH)"
+// @Success 200 {object} models.Trip
+// @Success 204 "Deleted"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Trip not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/trips/{id} [get]
+// @Router /api/v1/trips/{id} [patch]
+// @Router /api/v1/trips/{id} [delete]
+func (h *Handlers) TripHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tripID := extractIDFromPath(r.URL.Path, "/api/v1/trips/")
+	if tripID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var trip *models.Trip
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		trip, err = h.services.GetTrip(userID, tripID)
+
+	case http.MethodPatch:
+		var req UpdateTripRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		startDate, err := parseOptionalDate(req.StartDate)
+		if err != nil {
+			http.Error(w, "Invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		endDate, err := parseOptionalDate(req.EndDate)
+		if err != nil {
+			http.Error(w, "Invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		trip, err = h.services.UpdateTrip(userID, tripID, services.TripUpdate{
+			Name:              req.Name,
+			StartDate:         startDate,
+			EndDate:           endDate,
+			Budget:            req.Budget,
+			Currency:          req.Currency,
+			ExcludeFromBudget: req.ExcludeFromBudget,
+		})
+		if err != nil {
+			writeTripError(w, err)
+			return
+		}
+
+	case http.MethodDelete:
+		if err := h.services.DeleteTrip(userID, tripID); err != nil {
+			writeTripError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeTripError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trip)
+}
+
+// GetTripSummaryHandler godoc
+// @Summary Get the spend summary of a trip
+// @Description Returns what the trip cost against its budget, with the spend of every day and per category. Expenses assigned to the trip from outside its dates are listed as extra days.
+// @Tags trips
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Trip ID"
+// @Success 200 {object} dto.TripSummary
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Trip not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/trips/{id}/summary [get]
+func (h *Handlers) GetTripSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tripID := extractIDFromPath(r.URL.Path, "/api/v1/trips/")
+	if tripID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.services.GetTripSummary(userID, tripID)
+	if err != nil {
+		writeTripError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// TripExpensesHandler godoc
+// @Summary Assign or unassign trip expenses
+// @Description POST /trips/{id}/expenses assigns expenses to the trip whatever their date (moving them from any other trip); DELETE /trips/{id}/expenses/{expense_id} removes a manual assignment
+// @Tags trips
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Trip ID"
+// @Param expense_id path string false "Expense ID (DELETE)"
+// @Param request body TripExpensesRequest false "Expenses to assign (POST)"
+// @Success 200 {object} TripExpensesResponse
+// @Success 204 "Unassigned"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Trip or expense not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/trips/{id}/expenses [post]
+// @Router /api/v1/trips/{id}/expenses/{expense_id} [delete]
+func (h *Handlers) TripExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/trips/{id}/expenses[/{expense_id}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/trips/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] != "expenses" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2:
+		var req TripExpensesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		assigned, err := h.services.AssignExpensesToTrip(userID, parts[0], req.ExpenseIDs)
+		if err != nil {
+			writeTripError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TripExpensesResponse{Assigned: assigned})
+
+	case r.Method == http.MethodDelete && len(parts) == 3 && parts[2] != "":
+		if err := h.services.UnassignExpenseFromTrip(userID, parts[0], parts[2]); err != nil {
+			writeTripError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
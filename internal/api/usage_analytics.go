@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type AnalyticsPreferenceRequest struct {
+	OptOut bool `json:"opt_out" example:"true"`
+}
+
+type AnalyticsPreferenceResponse struct {
+	OptOut bool `json:"opt_out" example:"false"`
+}
+
+type EndpointUsageReportResponse struct {
+	StartDate string                         `json:"start_date" example:"2024-01-01"`
+	EndDate   string                         `json:"end_date" example:"2024-01-31"`
+	Endpoints []services.EndpointUsageReport `json:"endpoints"`
+	Count     int                            `json:"count" example:"12"`
+}
+
+type FeatureAdoptionReportResponse struct {
+	StartDate string                           `json:"start_date" example:"2024-01-01"`
+	EndDate   string                           `json:"end_date" example:"2024-01-31"`
+	Features  []services.FeatureAdoptionReport `json:"features"`
+	Count     int                              `json:"count" example:"6"`
+}
+
+type DeprecatedFieldReportResponse struct {
+	StartDate string                           `json:"start_date" example:"2024-01-01"`
+	EndDate   string                           `json:"end_date" example:"2024-01-31"`
+	Fields    []services.DeprecatedFieldReport `json:"fields"`
+	Count     int                              `json:"count" example:"1"`
+}
+
+// parseReportRange reads start_date/end_date, defaulting to the last 30 days
+func parseReportRange(r *http.Request) (time.Time, time.Time, error) {
+	endDate := time.Now().UTC().Truncate(24 * time.Hour)
+	startDate := endDate.AddDate(0, 0, -30)
+
+	if startStr := r.URL.Query().Get("start_date"); startStr != "" {
+		parsed, err := parseDate(startStr)
+		if err != nil {
+			return startDate, endDate, err
+		}
+		startDate = parsed
+	}
+
+	if endStr := r.URL.Query().Get("end_date"); endStr != "" {
+		parsed, err := parseDate(endStr)
+		if err != nil {
+			return startDate, endDate, err
+		}
+		endDate = parsed
+	}
+
+	return startDate, endDate, nil
+}
+
+// AnalyticsPreferenceHandler godoc
+// @Summary Get or update the usage analytics preference
+// @Description GET returns whether the authenticated user is excluded from usage analytics; PUT changes it
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body AnalyticsPreferenceRequest false "New preference (PUT only)"
+// @Success 200 {object} AnalyticsPreferenceResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/analytics/preference [get]
+// @Router /api/v1/analytics/preference [put]
+func (h *Handlers) AnalyticsPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		user, err := h.services.GetUserByID(userID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AnalyticsPreferenceResponse{OptOut: user.AnalyticsOptOut})
+
+	case http.MethodPut:
+		var req AnalyticsPreferenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.services.SetAnalyticsOptOut(userID, req.OptOut); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "User not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Error updating analytics preference", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AnalyticsPreferenceResponse{OptOut: req.OptOut})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetEndpointUsageReportHandler godoc
+// @Summary Endpoint usage report (admin)
+// @Description Returns aggregate request counts per endpoint and signup cohort
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param end_date query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} EndpointUsageReportResponse
+// @Failure 400 {string} string "Invalid date parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/analytics/endpoints [get]
+func (h *Handlers) GetEndpointUsageReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate, endDate, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.services.GetEndpointUsageReport(startDate, endDate)
+	if err != nil {
+		http.Error(w, "Error retrieving usage report", http.StatusInternalServerError)
+		return
+	}
+
+	response := EndpointUsageReportResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Endpoints: report,
+		Count:     len(report),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFeatureAdoptionReportHandler godoc
+// @Summary Feature adoption report (admin)
+// @Description Returns how many users of each signup cohort used each feature
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param end_date query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} FeatureAdoptionReportResponse
+// @Failure 400 {string} string "Invalid date parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/analytics/features [get]
+func (h *Handlers) GetFeatureAdoptionReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate, endDate, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.services.GetFeatureAdoptionReport(startDate, endDate)
+	if err != nil {
+		http.Error(w, "Error retrieving adoption report", http.StatusInternalServerError)
+		return
+	}
+
+	response := FeatureAdoptionReportResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Features:  report,
+		Count:     len(report),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeprecatedFieldReportHandler godoc
+// @Summary Deprecated response field usage (admin)
+// @Description Lists every response field tagged as deprecated with the clients (X-Client-ID header, "unknown" when missing) that still got it in the period. A field without clients can be removed.
+// @Tags admin
+// @Produce json
+// @Security bearerAuth
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param end_date query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} DeprecatedFieldReportResponse
+// @Failure 400 {string} string "Invalid date parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/admin/analytics/deprecations [get]
+func (h *Handlers) GetDeprecatedFieldReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate, endDate, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.services.GetDeprecatedFieldReport(startDate, endDate)
+	if err != nil {
+		http.Error(w, "Error retrieving deprecation report", http.StatusInternalServerError)
+		return
+	}
+
+	response := DeprecatedFieldReportResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Fields:    report,
+		Count:     len(report),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// AnonymizeUserRequest confirms an anonymization. An empty body returns the preview and the
+// confirmation token instead.
+type AnonymizeUserRequest struct {
+	ConfirmationToken string `json:"confirmation_token,omitempty" example:"1705312800.3f2a..."`
+	Confirm           string `json:"confirm,omitempty" example:"ANONYMIZE"`
+	KeepAggregates    *bool  `json:"keep_aggregates,omitempty" example:"true"` // Keep amounts, dates and categories (default true)
+}
+
+// AnonymizeUserHandler godoc
+// @Summary Anonymize the current user
+// @Description Irreversible alternative to deleting the account. Without a confirmation token it returns a preview and a short-lived token; sending the token back with confirm=ANONYMIZE replaces the email with a tombstone, clears names and descriptions, removes sessions, API keys and security history, and keeps the financial records anonymized unless keep_aggregates is false.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body AnonymizeUserRequest false "Confirmation (omit for preview)"
+// @Success 200 {object} dto.AnonymizationPreview "Preview with confirmation token"
+// @Success 200 {object} dto.AnonymizationResult "Anonymization done"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys can't manage the account"
+// @Failure 422 {string} string "Invalid or expired confirmation"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/users/me/anonymize [post]
+func (h *Handlers) AnonymizeUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, isAPIKey := r.Context().Value("apiKeyID").(string); isAPIKey {
+		http.Error(w, "API keys can't manage the account", http.StatusForbidden)
+		return
+	}
+
+	var req AnonymizeUserRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// First step: show what will happen and hand out the confirmation token
+	if req.ConfirmationToken == "" {
+		preview, err := h.services.PreviewAnonymization(userID)
+		if err != nil {
+			http.Error(w, "Error preparing anonymization", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+
+	keepAggregates := true
+	if req.KeepAggregates != nil {
+		keepAggregates = *req.KeepAggregates
+	}
+
+	result, err := h.services.AnonymizeUser(userID, req.ConfirmationToken, req.Confirm, keepAggregates)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAnonymizationConfirmation) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else {
+			http.Error(w, "Error anonymizing user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
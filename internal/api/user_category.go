@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/internal/services"
@@ -12,34 +13,66 @@ import (
 
 // Request and response structures
 type CreateUserCategoryRequest struct {
-	Name        string `json:"name" example:"Groceries"`
-	ExpenseType string `json:"expense_type" example:"needs" enums:"needs,wants,savings"`
+	Name        string  `json:"name" example:"Groceries"`
+	ExpenseType string  `json:"expense_type" example:"needs" enums:"needs,wants,savings"`
+	Icon        *string `json:"icon,omitempty" example:"cart"`
+	Color       *string `json:"color,omitempty" example:"#22C55E"`
 }
 
 type UpdateUserCategoryRequest struct {
 	Name        *string `json:"name,omitempty" example:"Groceries Updated"`
 	ExpenseType *string `json:"expense_type,omitempty" example:"needs" enums:"needs,wants,savings"`
+	Icon        *string `json:"icon,omitempty" example:"cart"`     // "" goes back to the expense type icon
+	Color       *string `json:"color,omitempty" example:"#22C55E"` // "" goes back to the expense type color
+}
+
+type CategoryAppearanceRequest struct {
+	ID    string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Icon  *string `json:"icon,omitempty" example:"cart"`
+	Color *string `json:"color,omitempty" example:"#22C55E"`
+}
+
+type BulkCategoryAppearanceRequest struct {
+	Categories []CategoryAppearanceRequest `json:"categories"`
+}
+
+type SetUserCategoryCapRequest struct {
+	MonthlyCap *models.Money `json:"monthly_cap" example:"500.00"` // null removes the cap
+	CapMode    string   `json:"cap_mode" example:"hard" enums:"alert,hard"`
 }
 
 type UserCategoryResponse struct {
-	ID              string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Name            string  `json:"name" example:"Groceries"`
-	ExpenseType     string  `json:"expense_type" example:"needs" enums:"needs,wants,savings"`
-	ExpenseTypeName string  `json:"expense_type_name" example:"Needs"`
-	Status          string  `json:"status" example:"active"`
-	StatusChangedAt *string `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
-	CreatedAt       string  `json:"created_at" example:"2024-01-15T10:30:00Z"`
-	UpdatedAt       string  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+	ID              string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name            string   `json:"name" example:"Groceries"`
+	ExpenseType     string   `json:"expense_type" example:"needs" enums:"needs,wants,savings"`
+	ExpenseTypeName string   `json:"expense_type_name" example:"Needs"`
+	MonthlyCap      *models.Money `json:"monthly_cap,omitempty" example:"500.00"`
+	CapMode         string   `json:"cap_mode" example:"alert" enums:"alert,hard"`
+	Icon            string   `json:"icon" example:"cart"`        // Category icon, or its expense type's
+	Color           string   `json:"color" example:"#22C55E"`    // Category color, or its expense type's
+	CustomIcon      bool     `json:"custom_icon" example:"true"` // False when icon and color come from the expense type
+	Status          string   `json:"status" example:"active"`
+	StatusChangedAt *string  `json:"status_changed_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	CreatedAt       string   `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt       string   `json:"updated_at" example:"2024-01-15T10:30:00Z"`
 }
 
 type UserCategoriesListResponse struct {
 	Categories []UserCategoryResponse `json:"categories"`
 	Count      int                    `json:"count" example:"15"`
+	*PageResponse
+}
+
+type ExpenseTypeAppearanceResponse struct {
+	ExpenseType string `json:"expense_type" example:"needs" enums:"needs,wants,savings"`
+	Icon        string `json:"icon" example:"home"`
+	Color       string `json:"color" example:"#2563EB"`
 }
 
 type UserCategoriesGroupedResponse struct {
-	GroupedCategories map[string][]UserCategoryResponse `json:"grouped_categories"`
-	TotalCount        int                               `json:"total_count" example:"15"`
+	GroupedCategories map[string][]UserCategoryResponse        `json:"grouped_categories"`
+	ExpenseTypes      map[string]ExpenseTypeAppearanceResponse `json:"expense_types"` // Keyed like grouped_categories
+	TotalCount        int                                      `json:"total_count" example:"15"`
 }
 
 type UserCategoryStatsResponse struct {
@@ -48,6 +81,11 @@ type UserCategoryStatsResponse struct {
 	DeletedCategories  int64            `json:"deleted_categories" example:"2"`
 }
 
+type CategoryIconsResponse struct {
+	Icons        []string                        `json:"icons" example:"cart"`
+	ExpenseTypes []ExpenseTypeAppearanceResponse `json:"expense_types"`
+}
+
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
@@ -59,13 +97,26 @@ func convertUserCategoryToResponse(category *models.Category) UserCategoryRespon
 		Name:            category.Name,
 		ExpenseType:     string(category.ExpenseType),
 		ExpenseTypeName: models.GetExpenseTypeName(category.ExpenseType),
+		MonthlyCap:      category.MonthlyCap,
+		CapMode:         string(category.CapMode),
+		Icon:            models.GetExpenseTypeIcon(category.ExpenseType),
+		Color:           models.GetExpenseTypeColor(category.ExpenseType),
 		Status:          string(category.Status),
-		CreatedAt:       category.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:       category.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		CreatedAt:       category.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       category.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if category.Icon != nil {
+		response.Icon = *category.Icon
+		response.CustomIcon = true
+	}
+	if category.Color != nil {
+		response.Color = *category.Color
+		response.CustomIcon = true
 	}
 
 	if category.StatusChangedAt != nil {
-		statusChangedAt := category.StatusChangedAt.Format("2006-01-02T15:04:05Z")
+		statusChangedAt := category.StatusChangedAt.Format(time.RFC3339)
 		response.StatusChangedAt = &statusChangedAt
 	}
 
@@ -84,7 +135,7 @@ func convertUserCategoryToResponse(category *models.Category) UserCategoryRespon
 // @Failure 409 {string} string "Category already exists"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories [post]
-func CreateUserCategory(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateUserCategory(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
 	var req CreateUserCategoryRequest
@@ -112,15 +163,17 @@ func CreateUserCategory(w http.ResponseWriter, r *http.Request) {
 	category := &models.Category{
 		Name:        req.Name,
 		ExpenseType: models.ExpenseType(req.ExpenseType),
+		Icon:        req.Icon,
+		Color:       req.Color,
 	}
 
-	if err := services.CreateUserCategory(userID, category); err != nil {
+	if err := h.services.CreateUserCategory(userID, category); err != nil {
 		logger.Error("Error creating user category: %v", err)
 		if err.Error() == "you already have a category with this name in this expense type" {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
-		if err.Error() == "invalid expense type. Must be one of: needs, wants, savings" {
+		if strings.HasPrefix(err.Error(), "invalid ") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -129,7 +182,7 @@ func CreateUserCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the created category with relations
-	createdCategory, err := services.GetUserCategoryByID(userID, category.ID.String())
+	createdCategory, err := h.services.GetUserCategoryByID(userID, category.ID.String())
 	if err != nil {
 		logger.Error("Error retrieving created category: %v", err)
 		http.Error(w, "Category created but error retrieving details", http.StatusInternalServerError)
@@ -154,7 +207,7 @@ func CreateUserCategory(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Category not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/{id} [get]
-func GetUserCategoryByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategoryByID(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract ID from URL path
@@ -171,7 +224,7 @@ func GetUserCategoryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	category, err := services.GetUserCategoryByID(userID, id)
+	category, err := h.services.GetUserCategoryByID(userID, id)
 	if err != nil {
 		logger.Error("Error getting user category by ID: %v", err)
 		http.Error(w, "Category not found", http.StatusNotFound)
@@ -191,14 +244,30 @@ func GetUserCategoryByID(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BearerAuth
 // @Param include_deleted query bool false "Include deleted categories" default:false
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page; takes precedence over offset"
 // @Success 200 {object} UserCategoriesListResponse
+// @Failure 400 {string} string "Invalid pagination parameters"
 // @Failure 500 {string} string "Internal server error"
+// @Header 200 {string} ETag "Changes with any write to the listed records, deletions included; send it back as If-None-Match to get 304 Not Modified"
+// @Header 200 {string} Last-Modified "Latest change to the listed records"
 // @Router /api/v1/user-categories [get]
-func GetUserCategories(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategories(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
-	categories, err := services.GetUserCategories(userID, includeDeleted)
+	if listNotModified(w, r, h.services, userID, "categories") {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories, info, err := h.services.GetUserCategories(userID, includeDeleted, page)
 	if err != nil {
 		logger.Error("Error getting user categories: %v", err)
 		http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
@@ -211,8 +280,9 @@ func GetUserCategories(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := UserCategoriesListResponse{
-		Categories: responseCategories,
-		Count:      len(responseCategories),
+		Categories:   responseCategories,
+		Count:        len(responseCategories),
+		PageResponse: newPageResponse(info),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -232,7 +302,7 @@ func GetUserCategories(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Expense type is required or invalid"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/expense-type/{expense_type} [get]
-func GetUserCategoriesByExpenseType(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategoriesByExpenseType(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract expense_type from URL path
@@ -255,7 +325,7 @@ func GetUserCategoriesByExpenseType(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	categories, err := services.GetUserCategoriesByExpenseType(userID, expenseType, includeDeleted)
+	categories, err := h.services.GetUserCategoriesByExpenseType(userID, expenseType, includeDeleted)
 	if err != nil {
 		logger.Error("Error getting user categories by expense type: %v", err)
 		http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
@@ -288,7 +358,7 @@ func GetUserCategoriesByExpenseType(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Expense type name is required"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/expense-type-name/{expense_type_name} [get]
-func GetUserCategoriesByExpenseTypeName(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategoriesByExpenseTypeName(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract expense_type_name from URL path
@@ -303,7 +373,7 @@ func GetUserCategoriesByExpenseTypeName(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	categories, err := services.GetUserCategoriesByExpenseTypeName(userID, expenseTypeName)
+	categories, err := h.services.GetUserCategoriesByExpenseTypeName(userID, expenseTypeName)
 	if err != nil {
 		logger.Error("Error getting user categories by expense type name: %v", err)
 		http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
@@ -334,10 +404,10 @@ func GetUserCategoriesByExpenseTypeName(w http.ResponseWriter, r *http.Request)
 // @Success 200 {object} UserCategoriesGroupedResponse
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/grouped [get]
-func GetUserCategoriesGroupedByType(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategoriesGroupedByType(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	groupedCategories, err := services.GetUserCategoriesGroupedByType(userID)
+	groupedCategories, err := h.services.GetUserCategoriesGroupedByType(userID)
 	if err != nil {
 		logger.Error("Error getting user categories grouped by type: %v", err)
 		http.Error(w, "Error retrieving grouped categories", http.StatusInternalServerError)
@@ -345,6 +415,14 @@ func GetUserCategoriesGroupedByType(w http.ResponseWriter, r *http.Request) {
 	}
 
 	responseGrouped := make(map[string][]UserCategoryResponse)
+	expenseTypes := make(map[string]ExpenseTypeAppearanceResponse)
+	for _, expenseType := range models.ValidExpenseTypes() {
+		expenseTypes[models.GetExpenseTypeName(expenseType)] = ExpenseTypeAppearanceResponse{
+			ExpenseType: string(expenseType),
+			Icon:        models.GetExpenseTypeIcon(expenseType),
+			Color:       models.GetExpenseTypeColor(expenseType),
+		}
+	}
 	totalCount := 0
 
 	for typeName, categories := range groupedCategories {
@@ -358,6 +436,7 @@ func GetUserCategoriesGroupedByType(w http.ResponseWriter, r *http.Request) {
 
 	response := UserCategoriesGroupedResponse{
 		GroupedCategories: responseGrouped,
+		ExpenseTypes:      expenseTypes,
 		TotalCount:        totalCount,
 	}
 
@@ -380,7 +459,7 @@ func GetUserCategoriesGroupedByType(w http.ResponseWriter, r *http.Request) {
 // @Failure 409 {string} string "Category name already exists"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/{id} [put]
-func UpdateUserCategory(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateUserCategory(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract ID from URL path
@@ -404,7 +483,7 @@ func UpdateUserCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get existing category
-	existingCategory, err := services.GetUserCategoryByID(userID, id)
+	existingCategory, err := h.services.GetUserCategoryByID(userID, id)
 	if err != nil {
 		logger.Error("Category not found for update: %v", err)
 		http.Error(w, "Category not found", http.StatusNotFound)
@@ -430,14 +509,17 @@ func UpdateUserCategory(w http.ResponseWriter, r *http.Request) {
 		updatedCategory.ExpenseType = models.ExpenseType(*req.ExpenseType)
 	}
 
-	updatedCategoryResult, err := services.UpdateUserCategory(userID, id, updatedCategory)
+	updatedCategory.Icon = req.Icon
+	updatedCategory.Color = req.Color
+
+	updatedCategoryResult, err := h.services.UpdateUserCategory(userID, id, updatedCategory)
 	if err != nil {
 		logger.Error("Error updating user category: %v", err)
 		if err.Error() == "you already have a category with this name in this expense type" {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
-		if err.Error() == "invalid expense type. Must be one of: needs, wants, savings" {
+		if strings.HasPrefix(err.Error(), "invalid ") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -464,7 +546,7 @@ func UpdateUserCategory(w http.ResponseWriter, r *http.Request) {
 // @Failure 409 {string} string "Category has active expenses"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/{id} [delete]
-func SoftDeleteUserCategory(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) SoftDeleteUserCategory(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract ID from URL path
@@ -481,7 +563,7 @@ func SoftDeleteUserCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := services.SoftDeleteUserCategory(userID, id)
+	err := h.services.SoftDeleteUserCategory(userID, id)
 	if err != nil {
 		logger.Error("Error soft deleting user category: %v", err)
 		if err.Error() == "cannot delete category: you have active expenses in this category" {
@@ -512,7 +594,7 @@ func SoftDeleteUserCategory(w http.ResponseWriter, r *http.Request) {
 // @Failure 409 {string} string "Category name conflict"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/{id}/restore [post]
-func RestoreUserCategory(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) RestoreUserCategory(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 	
 	// Extract ID from URL path - remove "/api/v1/user-categories/" and "/restore"
@@ -525,7 +607,7 @@ func RestoreUserCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	restoredCategory, err := services.RestoreUserCategory(userID, id)
+	restoredCategory, err := h.services.RestoreUserCategory(userID, id)
 	if err != nil {
 		logger.Error("Error restoring user category: %v", err)
 		if err.Error() == "cannot restore: you already have a category with this name in this expense type" {
@@ -547,6 +629,64 @@ func RestoreUserCategory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// @Summary Set user category cap
+// @Description Configure the monthly spending cap of a category. In alert mode exceeding the cap only warns; in hard mode expenses over the cap are rejected unless created with override_cap
+// @Tags User Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param request body SetUserCategoryCapRequest true "Cap settings"
+// @Success 200 {object} UserCategoryResponse
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Category not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/user-categories/{id}/cap [put]
+func (h *Handlers) SetUserCategoryCap(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	// Extract ID from URL path - remove "/api/v1/user-categories/" and "/cap"
+	path := r.URL.Path
+	id := path[len("/api/v1/user-categories/"):]
+	id = strings.TrimSuffix(id, "/cap")
+
+	if id == "" {
+		http.Error(w, "Category ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req SetUserCategoryCapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CapMode == "" {
+		req.CapMode = string(models.CapModeAlert)
+	}
+
+	category, err := h.services.SetCategoryCap(userID, id, req.MonthlyCap, models.CapMode(req.CapMode))
+	if err != nil {
+		logger.Error("Error setting user category cap: %v", err)
+		if err.Error() == "category not found or access denied" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "monthly cap must be positive" ||
+		   err.Error() == "invalid cap mode. Must be one of: alert, hard" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error setting category cap", http.StatusInternalServerError)
+		return
+	}
+
+	response := convertUserCategoryToResponse(category)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 // @Summary Create default user categories
 // @Description Create default categories for the authenticated user
 // @Tags User Categories
@@ -556,10 +696,10 @@ func RestoreUserCategory(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} SuccessResponse
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/defaults [post]
-func CreateDefaultUserCategories(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateDefaultUserCategories(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	err := services.CreateDefaultUserCategories(userID)
+	err := h.services.CreateDefaultUserCategories(userID)
 	if err != nil {
 		logger.Error("Error creating default user categories: %v", err)
 		http.Error(w, "Error creating default categories", http.StatusInternalServerError)
@@ -583,10 +723,10 @@ func CreateDefaultUserCategories(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} UserCategoryStatsResponse
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/user-categories/stats [get]
-func GetUserCategoryStats(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserCategoryStats(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
 
-	stats, err := services.GetUserCategoryStats(userID)
+	stats, err := h.services.GetUserCategoryStats(userID)
 	if err != nil {
 		logger.Error("Error getting user category stats: %v", err)
 		http.Error(w, "Error retrieving category statistics", http.StatusInternalServerError)
@@ -594,12 +734,86 @@ func GetUserCategoryStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := UserCategoryStatsResponse{
-		TotalCategories:   stats["total_categories"].(int64),
-		CategoriesByType:  stats["categories_by_type"].(map[string]int64),
-		DeletedCategories: stats["deleted_categories"].(int64),
+		TotalCategories:   stats.TotalCategories,
+		CategoriesByType:  stats.CategoriesByType,
+		DeletedCategories: stats.DeletedCategories,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+// @Summary Bulk update category icons and colors
+// @Description Sets the icon and color of several categories in one request, all or nothing. Icons must come from the allowed set (see GET /api/v1/user-categories/icons) and colors must be #RRGGBB. An omitted field is left as is; an empty string clears it so the category shows its expense type's icon or color.
+// @Tags User Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkCategoryAppearanceRequest true "Categories to update"
+// @Success 200 {object} UserCategoriesListResponse
+// @Failure 400 {string} string "Invalid icon or color"
+// @Failure 404 {string} string "Category not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/user-categories/appearance [put]
+func (h *Handlers) BulkUpdateUserCategoryAppearance(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var req BulkCategoryAppearanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updates := make([]services.CategoryAppearanceUpdate, 0, len(req.Categories))
+	for _, item := range req.Categories {
+		updates = append(updates, services.CategoryAppearanceUpdate{
+			CategoryID: item.ID,
+			Icon:       item.Icon,
+			Color:      item.Color,
+		})
+	}
+
+	categories, err := h.services.UpdateCategoryAppearances(userID, updates)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid ") || strings.Contains(err.Error(), "required"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error updating category appearance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	responseCategories := make([]UserCategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		responseCategories = append(responseCategories, convertUserCategoryToResponse(&category))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UserCategoriesListResponse{Categories: responseCategories, Count: len(responseCategories)})
+}
+
+// @Summary Get the allowed category icons
+// @Description Lists the icon names a category can use and the icon and color of each expense type
+// @Tags User Categories
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} CategoryIconsResponse
+// @Router /api/v1/user-categories/icons [get]
+func GetCategoryIcons(w http.ResponseWriter, r *http.Request) {
+	expenseTypes := make([]ExpenseTypeAppearanceResponse, 0, len(models.ValidExpenseTypes()))
+	for _, expenseType := range models.ValidExpenseTypes() {
+		expenseTypes = append(expenseTypes, ExpenseTypeAppearanceResponse{
+			ExpenseType: string(expenseType),
+			Icon:        models.GetExpenseTypeIcon(expenseType),
+			Color:       models.GetExpenseTypeColor(expenseType),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CategoryIconsResponse{Icons: models.CategoryIcons, ExpenseTypes: expenseTypes})
+}
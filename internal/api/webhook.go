@@ -0,0 +1,349 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" example:"https://hooks.example.com/fluxio"`
+	Description *string  `json:"description,omitempty" example:"Home automation"`
+	Secret      *string  `json:"secret,omitempty" example:"my-long-shared-secret"` // At least 16 characters; generated when omitted
+	EventTypes  []string `json:"event_types" example:"expense.created,budget.exceeded"`
+	Active      *bool    `json:"active,omitempty" example:"true"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty" example:"https://hooks.example.com/fluxio"`
+	Description *string  `json:"description,omitempty" example:"Home automation"`
+	Secret      *string  `json:"secret,omitempty" example:"my-new-long-shared-secret"` // Rotates the signing secret
+	EventTypes  []string `json:"event_types,omitempty" example:"expense.*,reminder.due"`
+	Active      *bool    `json:"active,omitempty" example:"false"` // true also re-enables a webhook disabled by a 410
+}
+
+type WebhookResponse struct {
+	ID          string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	URL         string   `json:"url" example:"https://hooks.example.com/fluxio"`
+	Description *string  `json:"description,omitempty" example:"Home automation"`
+	EventTypes  []string `json:"event_types" example:"expense.created,budget.exceeded"`
+	Active      bool     `json:"active" example:"true"`
+	DisabledAt  *string  `json:"disabled_at,omitempty" example:"2024-01-15T10:00:00Z"`              // Set when the receiver answered 410 Gone
+	Secret      string   `json:"secret,omitempty" example:"whsec_9f86d081884c7d659a2feaa0c55ad015"` // Only when created
+	CreatedAt   string   `json:"created_at" example:"2024-01-15T10:00:00Z"`
+}
+
+type WebhooksListResponse struct {
+	Webhooks   []WebhookResponse `json:"webhooks"`
+	Count      int               `json:"count" example:"1"`
+	EventTypes []string          `json:"event_types" example:"expense.created,budget.exceeded,reminder.due,transfer.completed"` // Types that can be subscribed to
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventID        string  `json:"event_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventType      string  `json:"event_type" example:"expense.created"`
+	Status         string  `json:"status" example:"succeeded"` // pending, succeeded or failed
+	Attempts       int     `json:"attempts" example:"1"`
+	NextAttemptAt  *string `json:"next_attempt_at,omitempty" example:"2024-01-15T10:01:00Z"` // Only while pending
+	ResponseStatus *int    `json:"response_status,omitempty" example:"200"`
+	ResponseBody   *string `json:"response_body,omitempty" example:"ok"` // First KB of the last answer
+	LastError      *string `json:"last_error,omitempty" example:"receiver answered 503"`
+	DurationMs     *int64  `json:"duration_ms,omitempty" example:"184"`
+	DeliveredAt    *string `json:"delivered_at,omitempty" example:"2024-01-15T10:00:01Z"`
+	CreatedAt      string  `json:"created_at" example:"2024-01-15T10:00:00Z"`
+}
+
+type WebhookDeliveriesListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Count      int                       `json:"count" example:"20"`
+	*PageResponse
+}
+
+func convertWebhookToResponse(webhook *models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:          webhook.ID.String(),
+		URL:         webhook.URL,
+		Description: webhook.Description,
+		EventTypes:  services.WebhookSubscriptions(webhook),
+		Active:      webhook.Active,
+		DisabledAt:  formatOptionalTime(webhook.DisabledAt),
+		CreatedAt:   webhook.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func convertWebhookDeliveryToResponse(delivery *models.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             delivery.ID.String(),
+		EventID:        delivery.EventID.String(),
+		EventType:      delivery.EventType,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		LastError:      delivery.LastError,
+		DurationMs:     delivery.DurationMs,
+		DeliveredAt:    formatOptionalTime(delivery.DeliveredAt),
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		response.NextAttemptAt = formatOptionalTime(&delivery.NextAttemptAt)
+	}
+	return response
+}
+
+// writeWebhookError maps webhook service errors to responses
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error processing webhook", http.StatusInternalServerError)
+	}
+}
+
+// WebhooksHandler godoc
+// @Summary List or register webhooks
+// @Description GET lists the webhooks of the user and the event types they can subscribe to. POST registers a URL that receives those events as signed JSON POSTs: X-Fluxio-Signature is t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed with the secret>. The secret is only returned here. Failed deliveries are retried with exponential backoff up to WEBHOOK_MAX_ATTEMPTS (8); a 410 Gone answer disables the webhook.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param request body CreateWebhookRequest false "Webhook (POST)"
+// @Success 200 {object} WebhooksListResponse
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks [get]
+// @Router /api/v1/webhooks [post]
+func (h *Handlers) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		webhooks, err := h.services.GetWebhooks(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving webhooks", http.StatusInternalServerError)
+			return
+		}
+		response := WebhooksListResponse{
+			Webhooks:   make([]WebhookResponse, len(webhooks)),
+			Count:      len(webhooks),
+			EventTypes: services.WebhookEventTypes(),
+		}
+		for i := range webhooks {
+			response.Webhooks[i] = convertWebhookToResponse(&webhooks[i])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case http.MethodPost:
+		var req CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		webhook, secret, err := h.services.CreateWebhook(userID, services.WebhookInput{
+			URL:         &req.URL,
+			Description: req.Description,
+			Secret:      req.Secret,
+			EventTypes:  req.EventTypes,
+			Active:      req.Active,
+		})
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		response := convertWebhookToResponse(webhook)
+		response.Secret = secret
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// WebhookHandler godoc
+// @Summary Get, update or delete a webhook
+// @Description GET returns a webhook, PATCH changes it (a new secret signs every later attempt, retries included) and DELETE removes it with its delivery log.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Webhook ID"
+// @Param request body UpdateWebhookRequest false "Fields to change (PATCH)"
+// @Success 200 {object} WebhookResponse
+// @Success 204 "Deleted"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks/{id} [get]
+// @Router /api/v1/webhooks/{id} [patch]
+// @Router /api/v1/webhooks/{id} [delete]
+func (h *Handlers) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := extractIDFromPath(r.URL.Path, "/api/v1/webhooks/")
+	if webhookID == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var webhook *models.Webhook
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		webhook, err = h.services.GetWebhook(userID, webhookID)
+
+	case http.MethodPatch:
+		var req UpdateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		webhook, err = h.services.UpdateWebhook(userID, webhookID, services.WebhookInput{
+			URL:         req.URL,
+			Description: req.Description,
+			Secret:      req.Secret,
+			EventTypes:  req.EventTypes,
+			Active:      req.Active,
+		})
+
+	case http.MethodDelete:
+		if err := h.services.DeleteWebhook(userID, webhookID); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertWebhookToResponse(webhook))
+}
+
+// GetWebhookDeliveriesHandler godoc
+// @Summary Delivery log of a webhook
+// @Description Lists the events sent to a webhook, newest first, with the attempts made and the status, body excerpt and duration of the last answer.
+// @Tags webhooks
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Only this status: pending, succeeded or failed"
+// @Param limit query int false "Page size, up to 500; 50 when omitted"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} WebhookDeliveriesListResponse
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (h *Handlers) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch models.WebhookDeliveryStatus(status) {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		http.Error(w, "Invalid status: use pending, succeeded or failed", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, info, err := h.services.GetWebhookDeliveries(userID, extractIDFromPath(r.URL.Path, "/api/v1/webhooks/"), status, page)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	responses := make([]WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = convertWebhookDeliveryToResponse(&deliveries[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookDeliveriesListResponse{Deliveries: responses, Count: len(responses), PageResponse: newPageResponse(info)})
+}
+
+// RetryWebhookDeliveryHandler godoc
+// @Summary Retry a failed webhook delivery
+// @Description Puts a delivery that ran out of attempts back in the queue with a fresh set of attempts. The body is the one originally sent, signed with the current secret.
+// @Tags webhooks
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 200 {object} WebhookDeliveryResponse
+// @Failure 400 {string} string "Only failed deliveries can be retried"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Delivery not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks/{id}/deliveries/{delivery_id}/retry [post]
+func (h *Handlers) RetryWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// /api/v1/webhooks/{id}/deliveries/{delivery_id}/retry
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 7 {
+		http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.services.RetryWebhookDelivery(userID, pathParts[3], pathParts[5])
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertWebhookDeliveryToResponse(delivery))
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Request and response structures
+type DeliveryResponse struct {
+	ID            string          `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventType     string          `json:"event_type" example:"expense.created"`
+	AggregateType string          `json:"aggregate_type" example:"expense"`
+	AggregateID   string          `json:"aggregate_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Payload       json.RawMessage `json:"payload,omitempty" swaggertype:"object"` // Only when inspecting one delivery
+	Status        string          `json:"status" example:"failed"`
+	Attempts      int             `json:"attempts" example:"10"`
+	LastError     *string         `json:"last_error,omitempty" example:"connection refused"`
+	CreatedAt     string          `json:"created_at" example:"2024-01-15T10:00:00Z"`
+}
+
+type DeliveriesListResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+	Count      int                `json:"count" example:"3"`
+}
+
+type RedeliverRequest struct {
+	IDs []string `json:"ids,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"` // Empty redelivers every failed delivery
+}
+
+type RedeliverResponse struct {
+	Requeued int64 `json:"requeued" example:"3"`
+}
+
+func convertDeliveryToResponse(event *models.OutboxEvent, withPayload bool) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:            event.ID.String(),
+		EventType:     event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID.String(),
+		Status:        string(event.Status),
+		Attempts:      event.Attempts,
+		LastError:     event.LastError,
+		CreatedAt:     event.CreatedAt.Format(time.RFC3339),
+	}
+	if withPayload {
+		response.Payload = json.RawMessage(event.Payload)
+	}
+	return response
+}
+
+// GetFailedDeliveriesHandler godoc
+// @Summary List failed event deliveries
+// @Description Lists the events the dispatcher gave up on after the maximum attempts (the dead-letter queue), latest first. Failed deliveries are kept for OUTBOX_DEAD_LETTER_RETENTION_DAYS (30 by default) after the last attempt.
+// @Tags webhooks
+// @Produce json
+// @Security bearerAuth
+// @Param event_type query string false "Only this event type, e.g. expense.created"
+// @Param limit query int false "Maximum deliveries (default and max 500)"
+// @Success 200 {object} DeliveriesListResponse
+// @Failure 400 {string} string "Invalid limit"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks/deliveries [get]
+func (h *Handlers) GetFailedDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := h.services.GetFailedOutboxEvents(userID, r.URL.Query().Get("event_type"), limit)
+	if err != nil {
+		http.Error(w, "Error retrieving failed deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	response := DeliveriesListResponse{Deliveries: make([]DeliveryResponse, len(events)), Count: len(events)}
+	for i := range events {
+		response.Deliveries[i] = convertDeliveryToResponse(&events[i], false)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeliveryHandler godoc
+// @Summary Inspect an event delivery
+// @Description Returns one event with its payload, attempts and last delivery error
+// @Tags webhooks
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Delivery (event) ID"
+// @Success 200 {object} DeliveryResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Delivery not found"
+// @Router /api/v1/webhooks/deliveries/{id} [get]
+func (h *Handlers) GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := h.services.GetOutboxEvent(userID, extractIDFromPath(r.URL.Path, "/api/v1/webhooks/deliveries/"))
+	if err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertDeliveryToResponse(event, true))
+}
+
+// RedeliverHandler godoc
+// @Summary Replay failed event deliveries
+// @Description POST /webhooks/{id}/redeliver requeues one failed delivery; POST /webhooks/redeliver requeues the given ids, or every failed delivery (up to 500) when none are given. Requeued events get a fresh set of attempts; only failed deliveries are requeued.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security bearerAuth
+// @Param id path string true "Delivery (event) ID"
+// @Param request body RedeliverRequest false "Deliveries to replay (bulk)"
+// @Success 200 {object} RedeliverResponse
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Failed delivery not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/webhooks/{id}/redeliver [post]
+// @Router /api/v1/webhooks/redeliver [post]
+func (h *Handlers) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	single := r.URL.Path != "/api/v1/webhooks/redeliver"
+	var req RedeliverRequest
+	if single {
+		req.IDs = []string{extractIDFromPath(r.URL.Path, "/api/v1/webhooks/")}
+	} else if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Error decoding request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	requeued, err := h.services.RedeliverOutboxEvents(userID, req.IDs)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error redelivering events", http.StatusInternalServerError)
+		}
+		return
+	}
+	if single && requeued == 0 {
+		http.Error(w, "Failed delivery not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RedeliverResponse{Requeued: requeued})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// GetYearlyComparisonHandler godoc
+// @Summary Multi-year comparison report
+// @Description Puts income, spending by type and category, savings and the net worth change of each calendar year side by side, for annual reviews. Spending is net of refunds; the net worth change is the money that came into the accounts minus what left them. With format=csv the report is downloaded as a CSV file with one row per metric and one column per year.
+// @Tags insights
+// @Produce json
+// @Produce text/csv
+// @Security bearerAuth
+// @Param years query string true "Comma separated years, e.g. 2023,2024"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} dto.YearlyComparison
+// @Failure 400 {string} string "Invalid years"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/reports/yearly-comparison [get]
+func (h *Handlers) GetYearlyComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var years []int
+	for _, value := range strings.Split(r.URL.Query().Get("years"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid years, use e.g. years=2023,2024", http.StatusBadRequest)
+			return
+		}
+		years = append(years, year)
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, use json or csv", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := h.services.GetYearlyComparison(r.Context(), userID, years)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// The client went away; nothing left to answer
+			logger.Info("Yearly comparison cancelled for user %s", userID)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Error calculating yearly comparison", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if format == "csv" {
+		writeYearlyComparisonCSV(w, comparison)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// writeYearlyComparisonCSV writes the report with one row per metric and one column per year
+func writeYearlyComparisonCSV(w http.ResponseWriter, comparison *dto.YearlyComparison) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="yearly-comparison.csv"`)
+
+	writer := csv.NewWriter(w)
+	header := []string{"section", "name"}
+	for _, year := range comparison.Years {
+		header = append(header, strconv.Itoa(year))
+	}
+	writer.Write(header)
+
+	row := func(section, name string, value func(i int) models.Money) {
+		record := []string{section, name}
+		for i := range comparison.Years {
+			record = append(record, value(i).String())
+		}
+		writer.Write(record)
+	}
+	summaries := comparison.Summaries
+	row("summary", "Income", func(i int) models.Money { return summaries[i].Income })
+	row("summary", "Spending", func(i int) models.Money { return summaries[i].Spending })
+	row("summary", "Savings", func(i int) models.Money { return summaries[i].Savings })
+	row("summary", "Net worth change", func(i int) models.Money { return summaries[i].NetWorthChange })
+	if len(summaries) > 0 {
+		for t, typeAmount := range summaries[0].ByExpenseType {
+			row("expense_type", typeAmount.Name, func(i int) models.Money { return summaries[i].ByExpenseType[t].Amount })
+		}
+	}
+	for _, category := range comparison.Categories {
+		row("category", category.Name, func(i int) models.Money { return category.Amounts[i] })
+	}
+	writer.Flush()
+}
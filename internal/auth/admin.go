@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// AdminMiddleware only lets through tokens with the admin role whose user is still an
+// accessible admin, so demoting or locking an admin takes effect right away. API keys never
+// carry admin claims. It must run after AuthMiddleware.
+func AdminMiddleware(svc *services.Services, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("userClaims").(*services.Claims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.Role != models.RoleAdmin || !svc.IsAdminUser(claims.UserID) {
+			logger.Warn("🚫 Acceso de administración denegado para %s", claims.UserID)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// apiKeyScopeWrites are the routes each limited API key scope may change; every scope can read
+var apiKeyScopeWrites = map[string][]subProfileRoute{
+	models.APIKeyScopeWriteExpenses: {
+		{path: "/api/v1/expenses", subpaths: true},
+	},
+}
+
+// apiKeyScopeAllows reports whether a key of the scope may make the request
+func apiKeyScopeAllows(scope string, r *http.Request) bool {
+	if scope == models.APIKeyScopeFull {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	for _, route := range apiKeyScopeWrites[scope] {
+		if route.allows(r) {
+			return true
+		}
+	}
+	return false
+}
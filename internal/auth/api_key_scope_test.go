@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/auth"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+
+	keys := make(map[string]string)
+	for _, scope := range []string{models.APIKeyScopeReadOnly, models.APIKeyScopeWriteExpenses, models.APIKeyScopeFull} {
+		_, raw, err := h.Services.CreateAPIKey(user.ID.String(), scope+" key", scope)
+		if err != nil {
+			t.Fatalf("creating %s key: %v", scope, err)
+		}
+		keys[scope] = raw
+	}
+
+	handler := auth.AuthMiddleware(h.Services, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value("userID") != user.ID.String() {
+			t.Errorf("request authenticated as %v, want %s", r.Context().Value("userID"), user.ID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name       string
+		scope      string
+		bearer     bool // Send the key as a bearer token instead of X-API-Key
+		rawKey     string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"read only key reads", models.APIKeyScopeReadOnly, false, "", http.MethodGet, "/api/v1/expenses", http.StatusNoContent},
+		{"read only key can't write", models.APIKeyScopeReadOnly, false, "", http.MethodPost, "/api/v1/expenses", http.StatusForbidden},
+		{"expenses key writes expenses", models.APIKeyScopeWriteExpenses, false, "", http.MethodPatch, "/api/v1/expenses/1", http.StatusNoContent},
+		{"expenses key can't write incomes", models.APIKeyScopeWriteExpenses, false, "", http.MethodPost, "/api/v1/incomes", http.StatusForbidden},
+		{"expenses key can't write lookalike paths", models.APIKeyScopeWriteExpenses, false, "", http.MethodPost, "/api/v1/expenses-export", http.StatusForbidden},
+		{"full key writes anything", models.APIKeyScopeFull, false, "", http.MethodDelete, "/api/v1/incomes/1", http.StatusNoContent},
+		{"key as bearer token", models.APIKeyScopeReadOnly, true, "", http.MethodPost, "/api/v1/expenses", http.StatusForbidden},
+		{"unknown key", "", false, "fx_unknown", http.MethodGet, "/api/v1/expenses", http.StatusUnauthorized},
+		{"header without the key prefix", "", false, "not-a-key", http.MethodGet, "/api/v1/expenses", http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := tc.rawKey
+			if tc.scope != "" {
+				raw = keys[tc.scope]
+			}
+			request := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.bearer {
+				request.Header.Set("Authorization", "Bearer "+raw)
+			} else {
+				request.Header.Set("X-API-Key", raw)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", recorder.Code, tc.wantStatus, recorder.Body.String())
+			}
+		})
+	}
+}
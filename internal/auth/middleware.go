@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/Osminalx/fluxio/internal/services"
@@ -12,25 +13,56 @@ import (
 type contextKey string
 const UserContextKey contextKey = "user"
 
-func AuthMiddleware(next http.Handler) http.Handler {
+func AuthMiddleware(svc *services.Services, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract token from Authorization header
+		// Extract token from Authorization header; scripts may send an API key in X-API-Key instead
 		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		apiKeyHeader := r.Header.Get("X-API-Key")
+		if authHeader == "" && apiKeyHeader == "" {
 			logger.Warn("🚫 Intento de acceso sin token de autorización desde %s", r.RemoteAddr)
 			http.Error(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
-		// Check if it's a Bearer token
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			logger.Warn("🚫 Formato de token inválido desde %s", r.RemoteAddr)
-			http.Error(w, "Invalid token format", http.StatusUnauthorized)
-			return
+		var tokenString string
+		if authHeader != "" {
+			// Check if it's a Bearer token
+			tokenParts := strings.Split(authHeader, " ")
+			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+				logger.Warn("🚫 Formato de token inválido desde %s", r.RemoteAddr)
+				http.Error(w, "Invalid token format", http.StatusUnauthorized)
+				return
+			}
+			tokenString = tokenParts[1]
+		} else {
+			if !strings.HasPrefix(apiKeyHeader, services.APIKeyPrefix) {
+				logger.Warn("🚫 API key inválida desde %s", r.RemoteAddr)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			tokenString = apiKeyHeader
 		}
 
-		tokenString := tokenParts[1]
+		// API keys authenticate as their owner, without admin claims
+		if strings.HasPrefix(tokenString, services.APIKeyPrefix) {
+			key, err := svc.AuthenticateAPIKey(tokenString)
+			if err != nil {
+				logger.Warn("🚫 API key inválida desde %s", r.RemoteAddr)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			if !apiKeyScopeAllows(key.Scope, r) {
+				logger.Warn("🚫 API key %s fuera de su alcance (%s) en %s %s", key.Prefix, key.Scope, r.Method, r.URL.Path)
+				http.Error(w, "API key scope "+key.Scope+" doesn't allow this request", http.StatusForbidden)
+				return
+			}
+
+			logger.Auth("ACCESS", key.UserID.String(), true, "API key "+key.Prefix+" Route: "+r.URL.Path)
+			ctx := context.WithValue(r.Context(), "userID", key.UserID.String())
+			ctx = context.WithValue(ctx, "apiKeyID", key.ID.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
 
 		// Validate token
 		token, err := services.ValidateToken(tokenString)
@@ -48,9 +80,25 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens of a revoked session are rejected before they expire
+		if claims.SessionID != "" && !svc.IsSessionActive(claims.SessionID) {
+			logger.Warn("🚫 Token de una sesión revocada desde %s", r.RemoteAddr)
+			http.Error(w, "Session revoked", http.StatusUnauthorized)
+			return
+		}
+
 		// Log successful authentication
 		logger.Auth("ACCESS", claims.UserID, true, "Route: "+r.URL.Path)
 
+		// Hint a renewed access token when the current one is about to expire
+		renewedToken, err := services.RenewAccessTokenIfExpiring(claims)
+		if err != nil {
+			logger.Warn("Error renewing access token for %s: %v", claims.UserID, err)
+		} else if renewedToken != "" {
+			w.Header().Set("X-Renewed-Access-Token", renewedToken)
+			w.Header().Set("X-Access-Token-Expires-In", strconv.Itoa(int(services.GetSessionConfig().AccessTokenTTL.Seconds())))
+		}
+
 		// Store user claims in request context
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, "userClaims", claims)
@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrNotConfigured is returned for a provider without credentials
+	ErrNotConfigured = errors.New("sign-in with this provider is not configured")
+	// ErrInvalidToken is returned when the ID token doesn't verify
+	ErrInvalidToken = errors.New("invalid ID token")
+)
+
+// Identity is who the provider says signed in
+type Identity struct {
+	Subject       string // Stable ID of the account at the provider
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an OpenID Connect identity provider using the authorization code flow with PKCE
+type Provider struct {
+	Name     string
+	Issuer   string
+	ClientID string
+	AuthURL  string
+	TokenURL string
+	Scopes   []string
+	FormPost bool // The provider posts the callback as a form (Apple, when asking for email)
+
+	callbackURL  string
+	clientSecret func() (string, error)
+	keys         *keySet
+	client       *http.Client
+}
+
+// FromEnv builds the providers whose credentials are set, keyed by name ("google", "apple").
+// OAUTH_CALLBACK_BASE_URL is the public URL of the API the providers redirect back to
+func FromEnv() (map[string]*Provider, error) {
+	providers := make(map[string]*Provider)
+	baseURL := strings.TrimSuffix(os.Getenv("OAUTH_CALLBACK_BASE_URL"), "/")
+	if baseURL == "" {
+		return providers, nil
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		secret := os.Getenv("GOOGLE_CLIENT_SECRET")
+		if secret == "" {
+			return nil, errors.New("Google sign-in needs GOOGLE_CLIENT_SECRET")
+		}
+		providers["google"] = NewGoogleProvider(clientID, secret, baseURL, client)
+	}
+	if clientID := os.Getenv("APPLE_CLIENT_ID"); clientID != "" {
+		key, err := os.ReadFile(os.Getenv("APPLE_PRIVATE_KEY_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("Apple sign-in needs APPLE_PRIVATE_KEY_FILE: %w", err)
+		}
+		provider, err := NewAppleProvider(clientID, os.Getenv("APPLE_TEAM_ID"), os.Getenv("APPLE_KEY_ID"), key, baseURL, client)
+		if err != nil {
+			return nil, err
+		}
+		providers["apple"] = provider
+	}
+	return providers, nil
+}
+
+// NewGoogleProvider signs users in with their Google account
+func NewGoogleProvider(clientID, clientSecret, baseURL string, client *http.Client) *Provider {
+	return &Provider{
+		Name:         "google",
+		Issuer:       "https://accounts.google.com",
+		ClientID:     clientID,
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		Scopes:       []string{"openid", "email", "profile"},
+		callbackURL:  baseURL + "/api/v1/auth/oauth/google/callback",
+		clientSecret: func() (string, error) { return clientSecret, nil },
+		keys:         newKeySet("https://www.googleapis.com/oauth2/v3/certs", client),
+		client:       client,
+	}
+}
+
+// NewAppleProvider signs users in with their Apple ID. Apple takes a short-lived JWT signed
+// with the team's key as client secret
+func NewAppleProvider(clientID, teamID, keyID string, privateKeyPEM []byte, baseURL string, client *http.Client) (*Provider, error) {
+	if teamID == "" || keyID == "" {
+		return nil, errors.New("Apple sign-in needs APPLE_TEAM_ID and APPLE_KEY_ID")
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Apple private key: %w", err)
+	}
+	const issuer = "https://appleid.apple.com"
+	return &Provider{
+		Name:        "apple",
+		Issuer:      issuer,
+		ClientID:    clientID,
+		AuthURL:     "https://appleid.apple.com/auth/authorize",
+		TokenURL:    "https://appleid.apple.com/auth/token",
+		Scopes:      []string{"name", "email"},
+		FormPost:    true,
+		callbackURL: baseURL + "/api/v1/auth/oauth/apple/callback",
+		clientSecret: func() (string, error) {
+			now := time.Now()
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+				"iss": teamID,
+				"iat": now.Unix(),
+				"exp": now.Add(5 * time.Minute).Unix(),
+				"aud": issuer,
+				"sub": clientID,
+			})
+			token.Header["kid"] = keyID
+			return token.SignedString(key)
+		},
+		keys:   newKeySet("https://appleid.apple.com/auth/keys", client),
+		client: client,
+	}, nil
+}
+
+// NewPKCE returns a code verifier and its S256 challenge
+func NewPKCE() (verifier string, challenge string) {
+	verifier = RandomString(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString returns n random bytes, base64url encoded
+func RandomString(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// AuthCodeURL is where the user is sent to sign in
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.callbackURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	if p.FormPost {
+		query.Set("response_mode", "form_post")
+	}
+	return p.AuthURL + "?" + query.Encode()
+}
+
+// Exchange trades the authorization code for the user's verified identity
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	secret, err := p.clientSecret()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.callbackURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {secret},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s token exchange failed with status %d: %s", p.Name, resp.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, ErrInvalidToken
+	}
+	return p.Verify(ctx, tokens.IDToken, nonce)
+}
+
+// idTokenClaims are the claims read from an ID token. Apple sends email_verified as a string
+type idTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) Verify(ctx context.Context, idToken, nonce string) (*Identity, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	identity := &Identity{Subject: claims.Subject, Email: strings.TrimSpace(claims.Email), Name: claims.Name}
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	return identity, nil
+}
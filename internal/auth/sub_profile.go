@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// subProfileRoute is a path a sub-profile may use: the exact path and, with subpaths, anything under it
+type subProfileRoute struct {
+	path     string
+	subpaths bool
+	methods  []string // Nil allows every method
+}
+
+// subProfileRoutes are the only routes a sub-profile can reach. Sub-profiles track their own
+// spending and goals; everything else stays with the parent
+var subProfileRoutes = []subProfileRoute{
+	{path: "/api/v1/expenses", methods: []string{http.MethodGet, http.MethodPost}},
+	{path: "/api/v1/expenses/", subpaths: true, methods: []string{http.MethodGet}},
+	{path: "/api/v1/expenses/scan", methods: []string{http.MethodPost}},
+	{path: "/api/v1/goals", subpaths: true},
+	{path: "/api/v1/bank-accounts", subpaths: true, methods: []string{http.MethodGet}},
+	{path: "/api/v1/user-categories", subpaths: true, methods: []string{http.MethodGet}},
+	{path: "/api/v1/incomes", subpaths: true, methods: []string{http.MethodGet}},
+	{path: "/api/v1/expense-approvals", methods: []string{http.MethodGet}},
+	{path: "/api/v1/auth/me", methods: []string{http.MethodGet}},
+	{path: "/api/v1/currencies", methods: []string{http.MethodGet}},
+	{path: "/api/v1/meta/enums", methods: []string{http.MethodGet}},
+	{path: "/api/v1/resolve/", subpaths: true, methods: []string{http.MethodGet}},
+}
+
+func (route subProfileRoute) allows(r *http.Request) bool {
+	if r.URL.Path != route.path && !(route.subpaths && strings.HasPrefix(r.URL.Path, strings.TrimSuffix(route.path, "/")+"/")) {
+		return false
+	}
+	if route.methods == nil {
+		return true
+	}
+	for _, method := range route.methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+// SubProfileMiddleware restricts sub-profiles to subProfileRoutes and turns away removed ones.
+// It must run after AuthMiddleware.
+func SubProfileMiddleware(svc *services.Services, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("userID").(string)
+		isSubProfile, active := svc.GetSubProfileAccess(userID)
+		if !isSubProfile {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !active {
+			logger.Warn("🚫 Acceso de sub-perfil eliminado %s", userID)
+			http.Error(w, "Sub-profile removed", http.StatusUnauthorized)
+			return
+		}
+		for _, route := range subProfileRoutes {
+			if route.allows(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		logger.Warn("🚫 Sub-perfil %s sin acceso a %s %s", userID, r.Method, r.URL.Path)
+		http.Error(w, "Not available for sub-profiles", http.StatusForbidden)
+	})
+}
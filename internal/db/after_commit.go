@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// hookedPool is the connection pool of the databases opened by Open. Its transactions run the
+// functions registered with AfterCommit once they commit
+type hookedPool struct {
+	*sql.DB
+}
+
+// BeginTx starts a transaction that can take AfterCommit functions
+func (p *hookedPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &hookedTx{Tx: tx, db: p.DB}, nil
+}
+
+// GetDBConn returns the pool to gorm.DB.DB()
+func (p *hookedPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+type hookedTx struct {
+	*sql.Tx
+	db *sql.DB
+
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// Commit commits the transaction and then runs its AfterCommit functions
+func (t *hookedTx) Commit() error {
+	err := t.Tx.Commit()
+	t.mu.Lock()
+	hooks := t.afterCommit
+	t.afterCommit = nil
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
+// Rollback rolls the transaction back, dropping its AfterCommit functions
+func (t *hookedTx) Rollback() error {
+	t.mu.Lock()
+	t.afterCommit = nil
+	t.mu.Unlock()
+	return t.Tx.Rollback()
+}
+
+// GetDBConn returns the pool to gorm.DB.DB() called inside the transaction
+func (t *hookedTx) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
+
+// hookPool makes the transactions of database take AfterCommit functions
+func hookPool(database *gorm.DB) error {
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	pool := &hookedPool{sqlDB}
+	database.ConnPool = pool
+	database.Statement.ConnPool = pool
+	return nil
+}
+
+// AfterCommit runs fn once the transaction tx runs in commits, and never if it rolls back. GORM
+// wraps single writes in a transaction of their own, so callbacks of those writes wait for it
+// too. Outside a transaction, or on a connection not opened by Open, fn runs right away
+func AfterCommit(tx *gorm.DB, fn func()) {
+	if hooked, ok := tx.Statement.ConnPool.(*hookedTx); ok {
+		hooked.mu.Lock()
+		hooked.afterCommit = append(hooked.afterCommit, fn)
+		hooked.mu.Unlock()
+		return
+	}
+	fn()
+}
@@ -1,39 +1,87 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/Osminalx/fluxio/internal/telemetry"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func Connect() {
+// Open connects to DATABASE_URL without touching the schema
+func Open() (*gorm.DB, error) {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		dsn = "host=localhost user=postgres password=postgres dbname=fluxio port=5432 sslmode=disable"
 	}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		log.Fatal("Error connecting to database:", err)
+		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	// Enable pgcrypto extension for gen_random_uuid()
-	err = DB.Exec("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\"").Error
-	if err != nil {
-		log.Fatal("Error creating pgcrypto extension:", err)
+	// Let writes defer work, like cache invalidation, until their transaction commits
+	if err := hookPool(database); err != nil {
+		return nil, fmt.Errorf("error configuring connection pool: %w", err)
+	}
+
+	// Time every query and trace the ones made with a request context
+	if err := database.Use(telemetry.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("error registering telemetry plugin: %w", err)
 	}
+	return database, nil
+}
 
-	// Run all migrations (auto-migrate + custom migrations)
-	err = RunAllMigrations(DB)
+// MigrateOnStart tells whether the server applies pending migrations when it starts.
+// Otherwise they are left to cmd/migrate and the server refuses to start until they are applied
+func MigrateOnStart() bool {
+	return os.Getenv("DB_MIGRATE_ON_START") != "false"
+}
+
+// Connect opens the database and applies the pending migrations, or refuses to go on while
+// some are pending when the server doesn't migrate on start
+func Connect() *gorm.DB {
+	database, err := Open()
 	if err != nil {
-		log.Fatal("Error migrating database:", err)
+		log.Fatal(err)
+	}
+
+	if MigrateOnStart() {
+		if _, err := MigrateUp(database, 0); err != nil {
+			log.Fatal("Error migrating database:", err)
+		}
+	} else {
+		pending, err := PendingMigrations(database)
+		if err != nil {
+			log.Fatal("Error checking database migrations:", err)
+		}
+		if len(pending) > 0 {
+			log.Fatalf("%d database migrations pending, starting with %d_%s: run `migrate up` or set DB_MIGRATE_ON_START=true",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
 	}
 
 	fmt.Println("✅ Conectado a Postgres con GORM")
-}
\ No newline at end of file
+	return database
+}
+
+// Ping checks that Postgres answers on the connection pool of the database
+func Ping(ctx context.Context, database *gorm.DB) error {
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pool once nothing uses the database anymore
+func Close(database *gorm.DB) error {
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,379 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+	"github.com/pressly/goose/v3/lock"
+	"gorm.io/gorm"
+)
+
+// MigrationsDir is where the SQL migrations live in the source tree, for cmd/migrate create
+const MigrationsDir = "internal/db/migrations"
+
+// migrationLockID keys the advisory lock that keeps two processes from migrating at once
+const migrationLockID = 0x666c7578696f
+
+// downMarker starts the statements that roll a migration back
+const downMarker = "-- +goose Down"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches {version}_{name}.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+
+	file string
+}
+
+// MigrationStatus is a known or recorded migration and where the database stands with it
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Missing is set for versions recorded in the database that this binary doesn't know
+	Missing bool
+}
+
+// Migrations returns every migration embedded in the binary, ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, other, match[2])
+		}
+		seen[version] = match[2]
+		migrations = append(migrations, Migration{Version: version, Name: match[2], file: entry.Name()})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrationFor returns the embedded migration with the version
+func migrationFor(version int64) (Migration, bool) {
+	migrations, err := Migrations()
+	if err != nil {
+		return Migration{}, false
+	}
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// reversible tells whether the migration has statements to roll it back
+func reversible(migration Migration) bool {
+	content, err := migrationFiles.ReadFile(path.Join("migrations", migration.file))
+	if err != nil {
+		return false
+	}
+	_, down, found := strings.Cut(string(content), downMarker)
+	return found && strings.TrimSpace(down) != ""
+}
+
+// migrationProvider runs the embedded migrations on db with goose, taking the migration lock
+// around every run
+func migrationProvider(ctx context.Context, db *gorm.DB) (*goose.Provider, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(migrationLockID))
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, sqlDB, fsys,
+		goose.WithSessionLocker(locker),
+		goose.WithDisableGlobalRegistry(true),
+		goose.WithLogger(goose.NopLogger()),
+	)
+}
+
+// versionStore reads and writes the goose version table directly, for the commands goose
+// doesn't have
+func versionStore() (database.Store, error) {
+	return database.NewStore(database.DialectPostgres, goose.DefaultTablename)
+}
+
+// withVersionTable runs fc in a transaction holding the migration lock, with the goose version
+// table in place
+func withVersionTable(ctx context.Context, db *gorm.DB, fc func(tx *gorm.DB, store database.Store) error) error {
+	store, err := versionStore()
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("error taking the migration lock: %w", err)
+		}
+		if !tx.Migrator().HasTable(store.Tablename()) {
+			conn := tx.Statement.ConnPool
+			if err := store.CreateVersionTable(ctx, conn); err != nil {
+				return fmt.Errorf("error creating %s: %w", store.Tablename(), err)
+			}
+			// goose expects version 0 at the bottom of the table
+			if err := store.Insert(ctx, conn, database.InsertRequest{Version: 0}); err != nil {
+				return err
+			}
+		}
+		return fc(tx, store)
+	})
+}
+
+// appliedMigrations turns goose results into the migrations they ran
+func appliedMigrations(results []*goose.MigrationResult) []Migration {
+	migrations := make([]Migration, 0, len(results))
+	for _, result := range results {
+		migration, ok := migrationFor(result.Source.Version)
+		if !ok {
+			migration = Migration{Version: result.Source.Version}
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations
+}
+
+// MigrateUp applies up to steps pending migrations in version order, all of them when steps is
+// 0, and returns the ones applied
+func MigrateUp(db *gorm.DB, steps int) ([]Migration, error) {
+	ctx := context.Background()
+	provider, err := migrationProvider(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*goose.MigrationResult
+	if steps == 0 {
+		results, err = provider.Up(ctx)
+	} else {
+		for len(results) < steps {
+			result, stepErr := provider.UpByOne(ctx)
+			if errors.Is(stepErr, goose.ErrNoNextVersion) {
+				break
+			}
+			if stepErr != nil {
+				err = stepErr
+				break
+			}
+			results = append(results, result)
+		}
+	}
+	done := appliedMigrations(results)
+	for _, migration := range done {
+		logger.Info("✅ Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return done, fmt.Errorf("error applying migrations: %w", err)
+	}
+	return done, nil
+}
+
+// MigrateDown rolls back the last steps applied migrations, newest first, and returns them
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	ctx := context.Background()
+	provider, err := migrationProvider(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for len(done) < steps {
+		version, err := provider.GetDBVersion(ctx)
+		if err != nil {
+			return done, err
+		}
+		if version == 0 {
+			break
+		}
+		migration, ok := migrationFor(version)
+		if !ok {
+			return done, fmt.Errorf("migration version %d is not in this binary", version)
+		}
+		if !reversible(migration) {
+			return done, fmt.Errorf("migration %d_%s can't be rolled back", migration.Version, migration.Name)
+		}
+		logger.Info("↩️  Rolling back migration %d_%s...", migration.Version, migration.Name)
+		if _, err := provider.Down(ctx); err != nil {
+			return done, fmt.Errorf("error rolling back migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// GetMigrationStatus lists the known migrations with whether they are applied, plus any
+// recorded version this binary doesn't know
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	ctx := context.Background()
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	err = withVersionTable(ctx, db, func(tx *gorm.DB, store database.Store) error {
+		conn := tx.Statement.ConnPool
+		recorded, err := store.ListMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		applied := make(map[int64]bool, len(recorded))
+		for _, row := range recorded {
+			if row.Version > 0 && row.IsApplied {
+				applied[row.Version] = true
+			}
+		}
+
+		appliedAt := func(version int64) (*time.Time, error) {
+			row, err := store.GetMigration(ctx, conn, version)
+			if err != nil {
+				return nil, err
+			}
+			return &row.Timestamp, nil
+		}
+		for _, migration := range migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if applied[migration.Version] {
+				if status.AppliedAt, err = appliedAt(migration.Version); err != nil {
+					return err
+				}
+				status.Applied = true
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for version := range applied {
+			status := MigrationStatus{Version: version, Applied: true, Missing: true}
+			if status.AppliedAt, err = appliedAt(version); err != nil {
+				return err
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// PendingMigrations returns the migrations not applied yet
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, Migration{Version: status.Version, Name: status.Name})
+		}
+	}
+	return pending, nil
+}
+
+// ForceMigration records a version as applied without running it, after applying it by hand,
+// for instance once a migration run outside a transaction failed halfway and was finished
+func ForceMigration(db *gorm.DB, version int64) error {
+	if _, ok := migrationFor(version); !ok {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	ctx := context.Background()
+	return withVersionTable(ctx, db, func(tx *gorm.DB, store database.Store) error {
+		conn := tx.Statement.ConnPool
+		if _, err := store.GetMigration(ctx, conn, version); err == nil {
+			return fmt.Errorf("migration version %d is already recorded", version)
+		} else if !errors.Is(err, database.ErrVersionNotFound) {
+			return err
+		}
+		return store.Insert(ctx, conn, database.InsertRequest{Version: version})
+	})
+}
+
+// ForgetMigration removes the record of a version without rolling it back, so the next up
+// runs it again
+func ForgetMigration(db *gorm.DB, version int64) error {
+	ctx := context.Background()
+	return withVersionTable(ctx, db, func(tx *gorm.DB, store database.Store) error {
+		conn := tx.Statement.ConnPool
+		if _, err := store.GetMigration(ctx, conn, version); errors.Is(err, database.ErrVersionNotFound) {
+			return fmt.Errorf("migration version %d is not recorded", version)
+		} else if err != nil {
+			return err
+		}
+		return store.Delete(ctx, conn, version)
+	})
+}
+
+// CreateMigration writes an empty migration file in dir, numbered after the highest known
+// version, and returns its path
+func CreateMigration(dir string, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, errors.New("invalid migration name")
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	// Files added since this binary was built count too
+	version := int64(0)
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if match := migrationFileName.FindStringSubmatch(entry.Name()); match != nil {
+				if v, _ := strconv.ParseInt(match[1], 10, 64); v > version {
+					version = v
+				}
+			}
+		}
+	}
+	version++
+
+	file := path.Join(dir, fmt.Sprintf("%04d_%s.sql", version, name))
+	content := "-- +goose Up\n\n" + downMarker + "\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		return nil, err
+	}
+	return []string{file}, nil
+}
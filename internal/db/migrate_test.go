@@ -0,0 +1,115 @@
+package db_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"gorm.io/gorm/schema"
+)
+
+// TestMigrateFromOriginalSchema upgrades a database created by auto-migration before the schema
+// was versioned: it has the baseline tables, some data, and no goose version table
+func TestMigrateFromOriginalSchema(t *testing.T) {
+	database := testutil.EmptySchema(t)
+	if _, err := db.MigrateUp(database, 1); err != nil {
+		t.Fatalf("creating the original schema: %v", err)
+	}
+	if err := database.Exec("DROP TABLE goose_db_version").Error; err != nil {
+		t.Fatalf("dropping the version table: %v", err)
+	}
+
+	var userID, accountID, categoryID string
+	steps := []struct {
+		sql  string
+		dest *string
+	}{
+		{"INSERT INTO users (email, password, name) VALUES ('old@example.com', 'hash', 'Old User') RETURNING id", &userID},
+		{"INSERT INTO bank_accounts (user_id, account_name, balance) VALUES (@user, 'Checking', 100) RETURNING id", &accountID},
+		{"INSERT INTO categories (user_id, name, expense_type) VALUES (@user, 'Rent', 'needs') RETURNING id", &categoryID},
+		{"INSERT INTO expenses (user_id, category_id, bank_account_id, amount, date) VALUES (@user, @category, @account, 40, CURRENT_DATE) RETURNING id", nil},
+		{"INSERT INTO incomes (user_id, bank_account_id, amount, date) VALUES (@user, @account, 500, CURRENT_DATE) RETURNING id", nil},
+		{"INSERT INTO fixed_expenses (user_id, name, amount, due_date, category_id, bank_account_id) VALUES (@user, 'Rent', 30, CURRENT_DATE, @category, @account) RETURNING id", nil},
+		{"INSERT INTO goals (user_id, name, total_amount) VALUES (@user, 'Trip', 1000) RETURNING id", nil},
+	}
+	for _, step := range steps {
+		var id string
+		err := database.Raw(step.sql, map[string]interface{}{"user": userID, "account": accountID, "category": categoryID}).Scan(&id).Error
+		if err != nil {
+			t.Fatalf("seeding the original schema: %v", err)
+		}
+		if step.dest != nil {
+			*step.dest = id
+		}
+	}
+
+	if _, err := db.MigrateUp(database, 0); err != nil {
+		t.Fatalf("migrating the original schema: %v", err)
+	}
+	pending, err := db.PendingMigrations(database)
+	if err != nil {
+		t.Fatalf("listing pending migrations: %v", err)
+	}
+	if len(pending) > 0 {
+		t.Fatalf("%d migrations still pending after migrating up", len(pending))
+	}
+
+	// Every column the models map must exist
+	cache := &sync.Map{}
+	for _, model := range models.GetAllModels() {
+		parsed, err := schema.Parse(model, cache, database.NamingStrategy)
+		if err != nil {
+			t.Fatalf("parsing %T: %v", model, err)
+		}
+		tables := []*schema.Schema{parsed}
+		for _, relation := range parsed.Relationships.Relations {
+			if relation.JoinTable != nil {
+				tables = append(tables, relation.JoinTable)
+			}
+		}
+		for _, table := range tables {
+			if !database.Migrator().HasTable(table.Table) {
+				t.Errorf("table %s is missing", table.Table)
+				continue
+			}
+			for _, field := range table.Fields {
+				if field.DBName != "" && !database.Migrator().HasColumn(table.Table, field.DBName) {
+					t.Errorf("column %s.%s is missing", table.Table, field.DBName)
+				}
+			}
+		}
+	}
+
+	// The existing rows load with the current models and get the defaults of the new columns
+	var user models.User
+	if err := database.First(&user, "id = ?", userID).Error; err != nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if user.Currency != "USD" || user.Role != models.RoleUser {
+		t.Errorf("user has currency %q and role %q, want USD and %s", user.Currency, user.Role, models.RoleUser)
+	}
+	var account models.BankAccount
+	if err := database.First(&account, "id = ?", accountID).Error; err != nil {
+		t.Fatalf("loading the bank account: %v", err)
+	}
+	if account.Version != 1 || account.ManualBalance || account.Balance != models.NewMoney(100) {
+		t.Errorf("bank account has version %d, manual balance %t and balance %s, want 1, false and 100.00",
+			account.Version, account.ManualBalance, account.Balance)
+	}
+	var incomes []models.Income
+	if err := database.Find(&incomes, "user_id = ?", userID).Error; err != nil {
+		t.Fatalf("loading incomes: %v", err)
+	}
+	if len(incomes) != 1 || incomes[0].Source != "other" {
+		t.Errorf("incomes = %+v, want one with source other", incomes)
+	}
+	var expenses []models.Expense
+	if err := database.Find(&expenses, "user_id = ?", userID).Error; err != nil {
+		t.Fatalf("loading expenses: %v", err)
+	}
+	if len(expenses) != 1 {
+		t.Errorf("found %d expenses, want 1", len(expenses))
+	}
+}
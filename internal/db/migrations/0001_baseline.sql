@@ -0,0 +1,157 @@
+-- Baseline schema: what GORM auto-migrated on every start before the schema was versioned, which
+-- every database created then still has. Every statement tolerates a database that already has
+-- it, so such a database adopts it as it is and gets the later migrations on top.
+
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- +goose StatementBegin
+DO $$
+BEGIN
+    CREATE TYPE expense_type_enum AS ENUM ('needs', 'wants', 'savings');
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "name" text NOT NULL,
+    "monthly_income" decimal(15,2),
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "last_login" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "bank_accounts" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "account_name" text NOT NULL,
+    "balance" decimal(15,2) NOT NULL DEFAULT 0,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bank_accounts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "categories" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "expense_type" expense_type_enum NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_categories_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "fixed_expenses" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "due_date" date NOT NULL,
+    "category_id" uuid,
+    "bank_account_id" uuid,
+    "is_recurring" boolean DEFAULT true,
+    "recurrence_type" varchar(20) DEFAULT 'monthly',
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "last_processed_at" timestamptz,
+    "next_due_date" date,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_fixed_expenses_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_fixed_expenses_category" FOREIGN KEY ("category_id") REFERENCES "categories"("id"),
+    CONSTRAINT "fk_fixed_expenses_bank_account" FOREIGN KEY ("bank_account_id") REFERENCES "bank_accounts"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "goals" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "total_amount" decimal(15,2) NOT NULL,
+    "saved_amount" decimal(15,2) NOT NULL DEFAULT 0,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_goals_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "expenses" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "category_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "date" date NOT NULL,
+    "bank_account_id" uuid,
+    "description" text,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_expenses_bank_account" FOREIGN KEY ("bank_account_id") REFERENCES "bank_accounts"("id"),
+    CONSTRAINT "fk_categories_expenses" FOREIGN KEY ("category_id") REFERENCES "categories"("id"),
+    CONSTRAINT "fk_expenses_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "incomes" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "bank_account_id" uuid,
+    "date" date NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_incomes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_incomes_bank_account" FOREIGN KEY ("bank_account_id") REFERENCES "bank_accounts"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "reminders" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "title" text NOT NULL,
+    "description" text,
+    "due_date" date NOT NULL,
+    "is_completed" boolean DEFAULT false,
+    "reminder_type" text,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reminders_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "chk_reminders_reminder_type" CHECK (reminder_type IN ('bill', 'goal', 'budget_review'))
+);
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "token" varchar(512) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "is_revoked" boolean DEFAULT false,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refresh_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "uni_refresh_tokens_token" UNIQUE ("token")
+);
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_token" ON "refresh_tokens" ("token");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
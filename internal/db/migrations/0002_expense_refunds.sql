@@ -0,0 +1,9 @@
+-- Refunds are incomes linked to the expense they refund
+
+-- +goose Up
+ALTER TABLE "incomes" ADD COLUMN IF NOT EXISTS "refund_of_expense_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_incomes_refund_of_expense_id" ON "incomes" ("refund_of_expense_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_incomes_refund_of_expense_id";
+ALTER TABLE "incomes" DROP COLUMN IF EXISTS "refund_of_expense_id";
@@ -0,0 +1,34 @@
+-- Aggregate usage analytics and the per-user opt-out
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "usage_endpoint_stats" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "day" date NOT NULL,
+    "method" varchar(10) NOT NULL,
+    "endpoint" varchar(255) NOT NULL,
+    "cohort" varchar(7) NOT NULL,
+    "request_count" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_usage_endpoint_day" ON "usage_endpoint_stats" ("day","method","endpoint","cohort");
+
+CREATE TABLE IF NOT EXISTS "usage_feature_stats" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "day" date NOT NULL,
+    "feature" varchar(100) NOT NULL,
+    "cohort" varchar(7) NOT NULL,
+    "active_users" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_usage_feature_day" ON "usage_feature_stats" ("day","feature","cohort");
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "analytics_opt_out" boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE "users" DROP COLUMN IF EXISTS "analytics_opt_out";
+DROP TABLE IF EXISTS "usage_feature_stats";
+DROP TABLE IF EXISTS "usage_endpoint_stats";
@@ -0,0 +1,7 @@
+-- Sliding sessions keep an absolute expiry per refresh token
+
+-- +goose Up
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "absolute_expires_at" timestamptz;
+
+-- +goose Down
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "absolute_expires_at";
@@ -0,0 +1,23 @@
+-- Monthly category caps and their audited overrides
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "action" varchar(100) NOT NULL,
+    "entity_type" varchar(50) NOT NULL,
+    "entity_id" uuid,
+    "details" jsonb NOT NULL DEFAULT '{}',
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_user_id" ON "audit_logs" ("user_id");
+
+ALTER TABLE "categories" ADD COLUMN IF NOT EXISTS "monthly_cap" decimal(15,2);
+ALTER TABLE "categories" ADD COLUMN IF NOT EXISTS "cap_mode" varchar(10) NOT NULL DEFAULT 'alert';
+
+-- +goose Down
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "cap_mode";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "monthly_cap";
+DROP TABLE IF EXISTS "audit_logs";
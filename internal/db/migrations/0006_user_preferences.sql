@@ -0,0 +1,14 @@
+-- User preferences, starting with the retention of soft-deleted records
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "user_preferences" (
+    "user_id" uuid,
+    "retention_policies" jsonb NOT NULL DEFAULT '{}',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_user_preferences_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "user_preferences";
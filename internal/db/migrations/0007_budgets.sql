@@ -0,0 +1,21 @@
+-- Monthly budgets
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "budgets" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "month_year" date NOT NULL,
+    "needs_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "wants_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "savings_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_budgets_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_budget_user_month" ON "budgets" ("user_id","month_year");
+
+-- +goose Down
+DROP TABLE IF EXISTS "budgets";
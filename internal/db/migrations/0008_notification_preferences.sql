@@ -0,0 +1,7 @@
+-- Notification quiet hours, channel muting and entity mutes
+
+-- +goose Up
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "notification_settings" jsonb NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "notification_settings";
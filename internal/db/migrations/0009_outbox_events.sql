@@ -0,0 +1,23 @@
+-- Transactional outbox for domain events
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "aggregate_type" varchar(50) NOT NULL,
+    "aggregate_id" uuid NOT NULL,
+    "payload" jsonb NOT NULL DEFAULT '{}',
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "last_error" text,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox_events" ("status","next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_user_id" ON "outbox_events" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "outbox_events";
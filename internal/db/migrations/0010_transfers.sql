@@ -0,0 +1,24 @@
+-- Transfers between accounts
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "transfers" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "from_account_id" uuid NOT NULL,
+    "to_account_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "date" date NOT NULL,
+    "description" text,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "status_changed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_transfers_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_transfers_from_account" FOREIGN KEY ("from_account_id") REFERENCES "bank_accounts"("id"),
+    CONSTRAINT "fk_transfers_to_account" FOREIGN KEY ("to_account_id") REFERENCES "bank_accounts"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transfers_user_id" ON "transfers" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "transfers";
@@ -0,0 +1,19 @@
+-- Goal milestones and their crossings
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "goal_milestones" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "goal_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "percent" decimal(5,2),
+    "amount" decimal(15,2),
+    "label" varchar(100),
+    "reached_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_goal_milestones_goal" FOREIGN KEY ("goal_id") REFERENCES "goals"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_goal_milestones_goal_id" ON "goal_milestones" ("goal_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "goal_milestones";
@@ -0,0 +1,7 @@
+-- Opt-in to category spending benchmarks
+
+-- +goose Up
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "benchmark_opt_in" boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "benchmark_opt_in";
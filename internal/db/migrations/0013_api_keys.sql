@@ -0,0 +1,36 @@
+-- API keys and their usage
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "api_keys" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "prefix" varchar(16) NOT NULL,
+    "key_hash" varchar(64) NOT NULL,
+    "last_used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_keys_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_key_hash" ON "api_keys" ("key_hash");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_user_id" ON "api_keys" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "api_key_usage_stats" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "api_key_id" uuid NOT NULL,
+    "day" date NOT NULL,
+    "method" varchar(10) NOT NULL,
+    "endpoint" varchar(255) NOT NULL,
+    "request_count" bigint NOT NULL DEFAULT 0,
+    "last_used_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_usage_day" ON "api_key_usage_stats" ("api_key_id","day","method","endpoint");
+
+-- +goose Down
+DROP TABLE IF EXISTS "api_key_usage_stats";
+DROP TABLE IF EXISTS "api_keys";
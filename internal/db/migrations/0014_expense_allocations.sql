@@ -0,0 +1,18 @@
+-- Expenses split across bank accounts
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "expense_allocations" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "expense_id" uuid NOT NULL,
+    "bank_account_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_expenses_allocations" FOREIGN KEY ("expense_id") REFERENCES "expenses"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_expense_allocations_bank_account" FOREIGN KEY ("bank_account_id") REFERENCES "bank_accounts"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_expense_allocations_bank_account_id" ON "expense_allocations" ("bank_account_id");
+CREATE INDEX IF NOT EXISTS "idx_expense_allocations_expense_id" ON "expense_allocations" ("expense_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "expense_allocations";
@@ -0,0 +1,37 @@
+-- Login locations, step-up challenges and security events
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "security_events" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "event_type" varchar(30) NOT NULL,
+    "ip_address" varchar(45),
+    "country" varchar(2),
+    "user_agent" varchar(512),
+    "device_id" varchar(64),
+    "new_device" boolean NOT NULL DEFAULT false,
+    "risk_score" bigint NOT NULL DEFAULT 0,
+    "risk_reasons" varchar(255),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_security_events_user" ON "security_events" ("user_id","created_at");
+
+CREATE TABLE IF NOT EXISTS "login_challenges" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "ip_address" varchar(45),
+    "country" varchar(2),
+    "user_agent" varchar(512),
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "expires_at" timestamptz NOT NULL,
+    "verified_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_challenges_user_id" ON "login_challenges" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "login_challenges";
+DROP TABLE IF EXISTS "security_events";
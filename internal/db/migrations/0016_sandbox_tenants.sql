@@ -0,0 +1,13 @@
+-- Sandbox tenants with their virtual clock
+
+-- +goose Up
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "is_sandbox" boolean NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "sandbox_owner_id" uuid;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "clock_offset_seconds" bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS "idx_users_sandbox_owner_id" ON "users" ("sandbox_owner_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_users_sandbox_owner_id";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "clock_offset_seconds";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "sandbox_owner_id";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_sandbox";
@@ -0,0 +1,7 @@
+-- Dashboard widget configuration
+
+-- +goose Up
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "dashboard_config" jsonb NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "dashboard_config";
@@ -0,0 +1,29 @@
+-- Currency metadata and the currency of each user
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "currencies" (
+    "code" varchar(3),
+    "name" text NOT NULL,
+    "minor_units" bigint NOT NULL DEFAULT 2,
+    "rounding_rule" varchar(20) NOT NULL DEFAULT 'half_up',
+    PRIMARY KEY ("code")
+);
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "currency" varchar(3) NOT NULL DEFAULT 'USD';
+
+INSERT INTO "currencies" ("code", "name", "minor_units", "rounding_rule") VALUES
+    ('USD', 'US Dollar', 2, 'half_up'),
+    ('EUR', 'Euro', 2, 'half_even'),
+    ('GBP', 'Pound Sterling', 2, 'half_up'),
+    ('MXN', 'Mexican Peso', 2, 'half_up'),
+    ('CAD', 'Canadian Dollar', 2, 'half_up'),
+    ('JPY', 'Yen', 0, 'half_up'),
+    ('KRW', 'Won', 0, 'half_up'),
+    ('CLP', 'Chilean Peso', 0, 'half_up'),
+    ('BHD', 'Bahraini Dinar', 3, 'half_up'),
+    ('KWD', 'Kuwaiti Dinar', 3, 'half_up')
+ON CONFLICT ("code") DO NOTHING;
+
+-- +goose Down
+ALTER TABLE "users" DROP COLUMN IF EXISTS "currency";
+DROP TABLE IF EXISTS "currencies";
@@ -0,0 +1,45 @@
+-- Monthly budget compliance and budget revisions
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "budget_revisions" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "budget_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "month_year" date NOT NULL,
+    "needs_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "wants_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "savings_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "effective_from" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_budget_revisions_budget" FOREIGN KEY ("budget_id") REFERENCES "budgets"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_budget_revision_user_month" ON "budget_revisions" ("user_id","month_year");
+CREATE INDEX IF NOT EXISTS "idx_budget_revisions_budget_id" ON "budget_revisions" ("budget_id");
+
+CREATE TABLE IF NOT EXISTS "budget_compliances" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "month_year" date NOT NULL,
+    "needs_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "wants_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "savings_budget" decimal(15,2) NOT NULL DEFAULT 0,
+    "needs_spent" decimal(15,2) NOT NULL DEFAULT 0,
+    "wants_spent" decimal(15,2) NOT NULL DEFAULT 0,
+    "savings_spent" decimal(15,2) NOT NULL DEFAULT 0,
+    "needs_within" boolean,
+    "wants_within" boolean,
+    "savings_within" boolean,
+    "within_budget" boolean,
+    "usage_percent" decimal(7,2) NOT NULL,
+    "computed_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_budget_compliances_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_budget_compliance_user_month" ON "budget_compliances" ("user_id","month_year");
+
+-- +goose Down
+DROP TABLE IF EXISTS "budget_compliances";
+DROP TABLE IF EXISTS "budget_revisions";
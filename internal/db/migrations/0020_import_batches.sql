@@ -0,0 +1,64 @@
+-- Import batches, imported transactions and field provenance
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "import_batches" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "bank_account_id" uuid NOT NULL,
+    "category_id" uuid NOT NULL,
+    "source" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_batches_user_id" ON "import_batches" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "imported_transactions" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "batch_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "date" date NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "description" text,
+    "external_id" text,
+    "expense_id" uuid,
+    "status" varchar(20) NOT NULL,
+    "error" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_imported_transactions_batch" FOREIGN KEY ("batch_id") REFERENCES "import_batches"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_imported_transactions_expense_id" ON "imported_transactions" ("expense_id");
+CREATE INDEX IF NOT EXISTS "idx_imported_transactions_batch_id" ON "imported_transactions" ("batch_id");
+
+CREATE TABLE IF NOT EXISTS "import_matches" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "batch_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "imported_transaction_id" uuid NOT NULL,
+    "expense_id" uuid NOT NULL,
+    "score" decimal(4,2) NOT NULL,
+    "strategy" varchar(20),
+    "resolved_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_import_matches_batch" FOREIGN KEY ("batch_id") REFERENCES "import_batches"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_import_matches_imported_transaction_id" ON "import_matches" ("imported_transaction_id");
+CREATE INDEX IF NOT EXISTS "idx_import_matches_batch_id" ON "import_matches" ("batch_id");
+
+CREATE TABLE IF NOT EXISTS "expense_field_provenances" (
+    "expense_id" uuid,
+    "field" varchar(30),
+    "source" varchar(20) NOT NULL,
+    "imported_transaction_id" uuid,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("expense_id","field"),
+    CONSTRAINT "fk_expense_field_provenances_expense" FOREIGN KEY ("expense_id") REFERENCES "expenses"("id") ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "expense_field_provenances";
+DROP TABLE IF EXISTS "import_matches";
+DROP TABLE IF EXISTS "imported_transactions";
+DROP TABLE IF EXISTS "import_batches";
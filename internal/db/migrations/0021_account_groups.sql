@@ -0,0 +1,26 @@
+-- Account groups and their members
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "account_groups" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_account_group_user_name" ON "account_groups" ("user_id","name");
+
+CREATE TABLE IF NOT EXISTS "account_group_members" (
+    "group_id" uuid,
+    "bank_account_id" uuid,
+    "created_at" timestamptz,
+    PRIMARY KEY ("group_id","bank_account_id"),
+    CONSTRAINT "fk_account_group_members_bank_account" FOREIGN KEY ("bank_account_id") REFERENCES "bank_accounts"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_account_groups_members" FOREIGN KEY ("group_id") REFERENCES "account_groups"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_account_group_members_bank_account_id" ON "account_group_members" ("bank_account_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "account_group_members";
+DROP TABLE IF EXISTS "account_groups";
@@ -0,0 +1,9 @@
+-- Goal priorities
+
+-- +goose Up
+ALTER TABLE "goals" ADD COLUMN IF NOT EXISTS "priority" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "goal_waterfall" boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "goal_waterfall";
+ALTER TABLE "goals" DROP COLUMN IF EXISTS "priority";
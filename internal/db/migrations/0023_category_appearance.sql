@@ -0,0 +1,9 @@
+-- Icons and colors of categories and expense types
+
+-- +goose Up
+ALTER TABLE "categories" ADD COLUMN IF NOT EXISTS "icon" varchar(32);
+ALTER TABLE "categories" ADD COLUMN IF NOT EXISTS "color" varchar(7);
+
+-- +goose Down
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "color";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "icon";
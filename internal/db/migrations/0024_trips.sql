@@ -0,0 +1,26 @@
+-- Trips and the expenses associated with them
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "trips" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "start_date" date NOT NULL,
+    "end_date" date NOT NULL,
+    "budget" decimal(15,2),
+    "currency" varchar(3) NOT NULL,
+    "exclude_from_budget" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_trips_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_trips_user_id" ON "trips" ("user_id");
+
+ALTER TABLE "expenses" ADD COLUMN IF NOT EXISTS "trip_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_expenses_trip_id" ON "expenses" ("trip_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_expenses_trip_id";
+ALTER TABLE "expenses" DROP COLUMN IF EXISTS "trip_id";
+DROP TABLE IF EXISTS "trips";
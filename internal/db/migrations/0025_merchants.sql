@@ -0,0 +1,11 @@
+-- Merchant enrichment of imported descriptors
+
+-- +goose Up
+ALTER TABLE "imported_transactions" ADD COLUMN IF NOT EXISTS "merchant_name" text;
+ALTER TABLE "imported_transactions" ADD COLUMN IF NOT EXISTS "merchant_logo_url" text;
+ALTER TABLE "imported_transactions" ADD COLUMN IF NOT EXISTS "merchant_provider" varchar(20);
+
+-- +goose Down
+ALTER TABLE "imported_transactions" DROP COLUMN IF EXISTS "merchant_provider";
+ALTER TABLE "imported_transactions" DROP COLUMN IF EXISTS "merchant_logo_url";
+ALTER TABLE "imported_transactions" DROP COLUMN IF EXISTS "merchant_name";
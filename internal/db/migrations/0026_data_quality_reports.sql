@@ -0,0 +1,19 @@
+-- Weekly data quality reports
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "data_quality_reports" (
+    "user_id" uuid,
+    "uncategorized_expenses" bigint NOT NULL DEFAULT 0,
+    "expenses_missing_account" bigint NOT NULL DEFAULT 0,
+    "stale_pending" bigint NOT NULL DEFAULT 0,
+    "negative_balances" bigint NOT NULL DEFAULT 0,
+    "issues" jsonb NOT NULL DEFAULT '[]',
+    "generated_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_data_quality_reports_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "data_quality_reports";
@@ -0,0 +1,7 @@
+-- Fixed expense amount drift suggestions
+
+-- +goose Up
+ALTER TABLE "fixed_expenses" ADD COLUMN IF NOT EXISTS "drift_suggestion" decimal(15,2);
+
+-- +goose Down
+ALTER TABLE "fixed_expenses" DROP COLUMN IF EXISTS "drift_suggestion";
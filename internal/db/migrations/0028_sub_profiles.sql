@@ -0,0 +1,40 @@
+-- Allowance sub-profiles and expense approvals
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "sub_profiles" (
+    "user_id" uuid,
+    "parent_id" uuid NOT NULL,
+    "wallet_account_id" uuid NOT NULL,
+    "approval_threshold" decimal(15,2),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_sub_profiles_parent" FOREIGN KEY ("parent_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_sub_profiles_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_sub_profiles_parent_id" ON "sub_profiles" ("parent_id");
+
+CREATE TABLE IF NOT EXISTS "expense_approvals" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "parent_id" uuid NOT NULL,
+    "category_id" uuid NOT NULL,
+    "bank_account_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "date" date NOT NULL,
+    "description" text,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "reason" text,
+    "expense_id" uuid,
+    "decided_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_expense_approvals_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_expense_approvals_parent_id" ON "expense_approvals" ("parent_id");
+CREATE INDEX IF NOT EXISTS "idx_expense_approvals_user_id" ON "expense_approvals" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "expense_approvals";
+DROP TABLE IF EXISTS "sub_profiles";
@@ -0,0 +1,13 @@
+-- Hash-chained audit log
+
+-- +goose Up
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "sequence" bigint;
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "prev_hash" varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "hash" varchar(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_logs_sequence" ON "audit_logs" ("sequence");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_audit_logs_sequence";
+ALTER TABLE "audit_logs" DROP COLUMN IF EXISTS "hash";
+ALTER TABLE "audit_logs" DROP COLUMN IF EXISTS "prev_hash";
+ALTER TABLE "audit_logs" DROP COLUMN IF EXISTS "sequence";
@@ -0,0 +1,19 @@
+-- Telemetry of deprecated response fields
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "deprecated_field_stats" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "day" date NOT NULL,
+    "client_id" varchar(100) NOT NULL,
+    "method" varchar(10) NOT NULL,
+    "endpoint" varchar(255) NOT NULL,
+    "field" varchar(255) NOT NULL,
+    "request_count" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_deprecated_field_day" ON "deprecated_field_stats" ("day","client_id","method","endpoint","field");
+
+-- +goose Down
+DROP TABLE IF EXISTS "deprecated_field_stats";
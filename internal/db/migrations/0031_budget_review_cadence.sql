@@ -0,0 +1,13 @@
+-- Budget review reminder cadence
+
+-- +goose Up
+ALTER TABLE "reminders" ADD COLUMN IF NOT EXISTS "link" text;
+ALTER TABLE "reminders" ADD COLUMN IF NOT EXISTS "period_start" date;
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "budget_review_cadence" varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "last_budget_review_at" timestamptz;
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "last_budget_review_at";
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "budget_review_cadence";
+ALTER TABLE "reminders" DROP COLUMN IF EXISTS "period_start";
+ALTER TABLE "reminders" DROP COLUMN IF EXISTS "link";
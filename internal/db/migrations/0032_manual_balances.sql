@@ -0,0 +1,7 @@
+-- Accounts whose balance is kept by hand
+
+-- +goose Up
+ALTER TABLE "bank_accounts" ADD COLUMN IF NOT EXISTS "manual_balance" boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE "bank_accounts" DROP COLUMN IF EXISTS "manual_balance";
@@ -0,0 +1,20 @@
+-- Expense receipt attachments
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "expense_attachments" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "expense_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "file_name" varchar(255) NOT NULL,
+    "content_type" varchar(100) NOT NULL,
+    "size" bigint NOT NULL,
+    "storage_key" varchar(255) NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_expenses_attachments" FOREIGN KEY ("expense_id") REFERENCES "expenses"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_expense_attachments_user_id" ON "expense_attachments" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_expense_attachments_expense_id" ON "expense_attachments" ("expense_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "expense_attachments";
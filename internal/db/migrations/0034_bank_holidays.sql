@@ -0,0 +1,9 @@
+-- Bank holidays skipped when posting fixed expenses
+
+-- +goose Up
+ALTER TABLE "fixed_expenses" ADD COLUMN IF NOT EXISTS "skip_holidays" boolean NOT NULL DEFAULT false;
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "holiday_country" varchar(2) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "holiday_country";
+ALTER TABLE "fixed_expenses" DROP COLUMN IF EXISTS "skip_holidays";
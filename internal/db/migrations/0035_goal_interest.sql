@@ -0,0 +1,24 @@
+-- Savings interest on goals
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "goal_contributions" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "goal_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "source" varchar(20) NOT NULL,
+    "is_interest" boolean NOT NULL DEFAULT false,
+    "date" date NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_goal_contributions_goal" FOREIGN KEY ("goal_id") REFERENCES "goals"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_goal_contributions_goal_id" ON "goal_contributions" ("goal_id");
+
+ALTER TABLE "goals" ADD COLUMN IF NOT EXISTS "apy" decimal(6,3);
+ALTER TABLE "goals" ADD COLUMN IF NOT EXISTS "interest_through" date;
+
+-- +goose Down
+ALTER TABLE "goals" DROP COLUMN IF EXISTS "interest_through";
+ALTER TABLE "goals" DROP COLUMN IF EXISTS "apy";
+DROP TABLE IF EXISTS "goal_contributions";
@@ -0,0 +1,17 @@
+-- Refresh token families and sessions
+
+-- +goose Up
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "family_id" uuid;
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "ip_address" varchar(45);
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "user_agent" varchar(512);
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "revoked_reason" varchar(20);
+ALTER TABLE "refresh_tokens" ADD COLUMN IF NOT EXISTS "replaced_by_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_refresh_tokens_family_id";
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "replaced_by_id";
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "revoked_reason";
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "user_agent";
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "ip_address";
+ALTER TABLE "refresh_tokens" DROP COLUMN IF EXISTS "family_id";
@@ -0,0 +1,9 @@
+-- Confirmation of expenses and transfers above a threshold
+
+-- +goose Up
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "expense_confirm_above" decimal(15,2);
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "transfer_confirm_above" decimal(15,2);
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "transfer_confirm_above";
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "expense_confirm_above";
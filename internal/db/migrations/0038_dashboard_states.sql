@@ -0,0 +1,16 @@
+-- Dashboard read model
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "dashboard_states" (
+    "user_id" uuid,
+    "month" date NOT NULL,
+    "version" bigint NOT NULL DEFAULT 0,
+    "state" jsonb NOT NULL DEFAULT '{}',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_dashboard_states_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "dashboard_states";
@@ -0,0 +1,28 @@
+-- Reminder notification deliveries
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "notification_deliveries" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "entity_type" varchar(50) NOT NULL,
+    "entity_id" uuid NOT NULL,
+    "due_date" date NOT NULL,
+    "channel" varchar(20) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'sent',
+    "reason" text,
+    "sent_at" timestamptz,
+    "delivered_at" timestamptz,
+    "acknowledged_at" timestamptz,
+    "escalated_at" timestamptz,
+    "escalated_from_id" uuid,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_notification_deliveries_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_notification_delivery_once" ON "notification_deliveries" ("entity_id","due_date","channel");
+CREATE INDEX IF NOT EXISTS "idx_notification_deliveries_user_id" ON "notification_deliveries" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "notification_deliveries";
@@ -0,0 +1,46 @@
+-- Signed webhooks and their deliveries
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "url" varchar(2048) NOT NULL,
+    "description" varchar(255),
+    "secret" varchar(255) NOT NULL,
+    "event_types" jsonb NOT NULL DEFAULT '[]',
+    "active" boolean NOT NULL DEFAULT true,
+    "disabled_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhooks_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_user_id" ON "webhooks" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "webhook_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "event_id" uuid NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "payload" jsonb NOT NULL DEFAULT '{}',
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "response_status" bigint,
+    "response_body" text,
+    "last_error" text,
+    "duration_ms" bigint,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_delivery_pending" ON "webhook_deliveries" ("status","next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_user_id" ON "webhook_deliveries" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_webhook_delivery_event" ON "webhook_deliveries" ("webhook_id","event_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhooks";
@@ -0,0 +1,23 @@
+-- Fixed expense processing runs
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "fixed_expense_runs" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "fixed_expense_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "period" date NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "expense_id" uuid,
+    "amount" decimal(15,2) NOT NULL,
+    "reason" text,
+    "attempts" bigint NOT NULL DEFAULT 1,
+    "processed_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_fixed_expense_runs_user_id" ON "fixed_expense_runs" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_fixed_expense_run_period" ON "fixed_expense_runs" ("fixed_expense_id","period");
+
+-- +goose Down
+DROP TABLE IF EXISTS "fixed_expense_runs";
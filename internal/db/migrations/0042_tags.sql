@@ -0,0 +1,34 @@
+-- Tags on expenses and incomes
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "tags" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "color" varchar(7),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_tag_user_name" ON "tags" ("user_id","name");
+
+CREATE TABLE IF NOT EXISTS "expense_tags" (
+    "expense_id" uuid DEFAULT gen_random_uuid(),
+    "tag_id" uuid DEFAULT gen_random_uuid(),
+    PRIMARY KEY ("expense_id","tag_id"),
+    CONSTRAINT "fk_expense_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_expense_tags_expense" FOREIGN KEY ("expense_id") REFERENCES "expenses"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "income_tags" (
+    "income_id" uuid DEFAULT gen_random_uuid(),
+    "tag_id" uuid DEFAULT gen_random_uuid(),
+    PRIMARY KEY ("income_id","tag_id"),
+    CONSTRAINT "fk_income_tags_income" FOREIGN KEY ("income_id") REFERENCES "incomes"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_income_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id") ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "income_tags";
+DROP TABLE IF EXISTS "expense_tags";
+DROP TABLE IF EXISTS "tags";
@@ -0,0 +1,7 @@
+-- User roles
+
+-- +goose Up
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "role" varchar(20) NOT NULL DEFAULT 'user';
+
+-- +goose Down
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Expense search. The full-text expression must match the document the search service builds
+CREATE INDEX IF NOT EXISTS idx_expenses_user_date ON expenses (user_id, date DESC);
+CREATE INDEX IF NOT EXISTS idx_expenses_description_fts ON expenses USING GIN (to_tsvector('simple', COALESCE(description, '')));
+
+-- Trigram index for ILIKE '%text%'. pg_trgm may not be available on every server, and substring
+-- search works without the index
+-- +goose StatementBegin
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+    CREATE INDEX IF NOT EXISTS idx_expenses_description_trgm ON expenses USING GIN (description gin_trgm_ops);
+EXCEPTION
+    WHEN OTHERS THEN RAISE WARNING 'substring search will not be indexed: %', SQLERRM;
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_expenses_description_trgm;
+DROP INDEX IF EXISTS idx_expenses_description_fts;
+DROP INDEX IF EXISTS idx_expenses_user_date;
@@ -0,0 +1,34 @@
+-- Per-category budget lines
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "category_budgets" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "budget_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "category_id" uuid NOT NULL,
+    "amount" decimal(15,2) NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_category_budgets_category" FOREIGN KEY ("category_id") REFERENCES "categories"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_budgets_category_budgets" FOREIGN KEY ("budget_id") REFERENCES "budgets"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_category_budgets_user_id" ON "category_budgets" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_category_budget_line" ON "category_budgets" ("budget_id","category_id");
+
+CREATE TABLE IF NOT EXISTS "budget_category_compliances" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "compliance_id" uuid NOT NULL,
+    "category_id" uuid NOT NULL,
+    "category_name" text NOT NULL,
+    "budget" decimal(15,2) NOT NULL,
+    "spent" decimal(15,2) NOT NULL,
+    "within" boolean,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_budget_compliances_categories" FOREIGN KEY ("compliance_id") REFERENCES "budget_compliances"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_budget_category_compliances_compliance_id" ON "budget_category_compliances" ("compliance_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "budget_category_compliances";
+DROP TABLE IF EXISTS "category_budgets";
@@ -0,0 +1,7 @@
+-- Request ID of each outbox event
+
+-- +goose Up
+ALTER TABLE "outbox_events" ADD COLUMN IF NOT EXISTS "request_id" varchar(64);
+
+-- +goose Down
+ALTER TABLE "outbox_events" DROP COLUMN IF EXISTS "request_id";
@@ -0,0 +1,9 @@
+-- Version columns for optimistic locking
+
+-- +goose Up
+ALTER TABLE "bank_accounts" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "budgets" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE "budgets" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "bank_accounts" DROP COLUMN IF EXISTS "version";
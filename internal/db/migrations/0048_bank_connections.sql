@@ -0,0 +1,52 @@
+-- Open-banking bank connections
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "bank_connections" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "provider" varchar(20) NOT NULL,
+    "item_id" varchar(100) NOT NULL,
+    "access_token" text NOT NULL,
+    "institution_name" varchar(100),
+    "default_category_id" uuid NOT NULL,
+    "cursor" text,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "last_error" text,
+    "last_synced_at" timestamptz,
+    "sync_requested_at" timestamptz,
+    "sync_started_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_bank_connection_item" ON "bank_connections" ("provider","item_id");
+CREATE INDEX IF NOT EXISTS "idx_bank_connections_user_id" ON "bank_connections" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "bank_connection_accounts" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "connection_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "external_account_id" varchar(100) NOT NULL,
+    "name" text NOT NULL,
+    "mask" varchar(10),
+    "type" varchar(30),
+    "bank_account_id" uuid,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bank_connections_accounts" FOREIGN KEY ("connection_id") REFERENCES "bank_connections"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_bank_connection_account" ON "bank_connection_accounts" ("connection_id","external_account_id");
+
+ALTER TABLE "import_batches" ADD COLUMN IF NOT EXISTS "bank_connection_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_import_batches_bank_connection_id" ON "import_batches" ("bank_connection_id");
+ALTER TABLE "imported_transactions" ADD COLUMN IF NOT EXISTS "income_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_imported_transactions_income_id" ON "imported_transactions" ("income_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_imported_transactions_income_id";
+ALTER TABLE "imported_transactions" DROP COLUMN IF EXISTS "income_id";
+DROP INDEX IF EXISTS "idx_import_batches_bank_connection_id";
+ALTER TABLE "import_batches" DROP COLUMN IF EXISTS "bank_connection_id";
+DROP TABLE IF EXISTS "bank_connection_accounts";
+DROP TABLE IF EXISTS "bank_connections";
@@ -0,0 +1,23 @@
+-- User data export jobs
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "data_exports" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "format" varchar(10) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "storage_key" text,
+    "size" bigint,
+    "error" text,
+    "started_at" timestamptz,
+    "completed_at" timestamptz,
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_data_exports_status" ON "data_exports" ("status");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_user_id" ON "data_exports" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "data_exports";
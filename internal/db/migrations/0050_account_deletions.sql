@@ -0,0 +1,9 @@
+-- Account deletion requests
+
+-- +goose Up
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deletion_scheduled_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_users_deletion_scheduled_at" ON "users" ("deletion_scheduled_at");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_users_deletion_scheduled_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deletion_scheduled_at";
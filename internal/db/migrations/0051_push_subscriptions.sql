@@ -0,0 +1,25 @@
+-- Push subscriptions and when reminder notifications were dispatched
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "push_subscriptions" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "platform" varchar(20) NOT NULL,
+    "token" text NOT NULL,
+    "p256dh" text,
+    "auth" text,
+    "label" varchar(100),
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_push_subscriptions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_push_subscriptions_token" ON "push_subscriptions" ("token");
+CREATE INDEX IF NOT EXISTS "idx_push_subscriptions_user_id" ON "push_subscriptions" ("user_id");
+
+ALTER TABLE "notification_deliveries" ADD COLUMN IF NOT EXISTS "dispatched_at" timestamptz;
+
+-- +goose Down
+ALTER TABLE "notification_deliveries" DROP COLUMN IF EXISTS "dispatched_at";
+DROP TABLE IF EXISTS "push_subscriptions";
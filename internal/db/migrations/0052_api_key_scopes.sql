@@ -0,0 +1,7 @@
+-- API key scopes
+
+-- +goose Up
+ALTER TABLE "api_keys" ADD COLUMN IF NOT EXISTS "scope" varchar(20) NOT NULL DEFAULT 'full';
+
+-- +goose Down
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "scope";
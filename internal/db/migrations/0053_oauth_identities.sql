@@ -0,0 +1,32 @@
+-- Google and Apple sign-in identities
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "user_identities" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "user_id" uuid NOT NULL,
+    "provider" varchar(20) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "email" varchar(255),
+    "last_login_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identities_subject" ON "user_identities" ("provider","subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_user_id" ON "user_identities" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "o_auth_login_states" (
+    "id" varchar(64),
+    "provider" varchar(20) NOT NULL,
+    "nonce" varchar(64) NOT NULL,
+    "code_verifier" varchar(128) NOT NULL,
+    "redirect_uri" varchar(512),
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_o_auth_login_states_expires_at" ON "o_auth_login_states" ("expires_at");
+
+-- +goose Down
+DROP TABLE IF EXISTS "o_auth_login_states";
+DROP TABLE IF EXISTS "user_identities";
@@ -0,0 +1,7 @@
+-- User timezone
+
+-- +goose Up
+ALTER TABLE "user_preferences" ADD COLUMN IF NOT EXISTS "timezone" varchar(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE "user_preferences" DROP COLUMN IF EXISTS "timezone";
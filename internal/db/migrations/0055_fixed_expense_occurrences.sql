@@ -0,0 +1,21 @@
+-- Fixed expense occurrences
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "fixed_expense_occurrences" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "fixed_expense_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "period" date NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "expense_id" uuid,
+    "amount" decimal(15,2) NOT NULL,
+    "resolved_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_fixed_expense_occurrences_user_id" ON "fixed_expense_occurrences" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_fixed_expense_occurrence_period" ON "fixed_expense_occurrences" ("fixed_expense_id","period");
+
+-- +goose Down
+DROP TABLE IF EXISTS "fixed_expense_occurrences";
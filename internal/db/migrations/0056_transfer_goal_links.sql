@@ -0,0 +1,13 @@
+-- Transfers linked to goals as contributions
+
+-- +goose Up
+ALTER TABLE "goal_contributions" ADD COLUMN IF NOT EXISTS "transfer_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_goal_contributions_transfer_id" ON "goal_contributions" ("transfer_id");
+ALTER TABLE "transfers" ADD COLUMN IF NOT EXISTS "goal_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_transfers_goal_id" ON "transfers" ("goal_id");
+
+-- +goose Down
+DROP INDEX IF EXISTS "idx_transfers_goal_id";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "goal_id";
+DROP INDEX IF EXISTS "idx_goal_contributions_transfer_id";
+ALTER TABLE "goal_contributions" DROP COLUMN IF EXISTS "transfer_id";
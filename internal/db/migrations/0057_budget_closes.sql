@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS budget_closes (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES users(id),
+    month_year date NOT NULL,
+    budget_id uuid,
+    needs_budget decimal(15,2) NOT NULL DEFAULT 0.00,
+    wants_budget decimal(15,2) NOT NULL DEFAULT 0.00,
+    savings_budget decimal(15,2) NOT NULL DEFAULT 0.00,
+    needs_spent decimal(15,2) NOT NULL DEFAULT 0.00,
+    wants_spent decimal(15,2) NOT NULL DEFAULT 0.00,
+    savings_spent decimal(15,2) NOT NULL DEFAULT 0.00,
+    total_income decimal(15,2) NOT NULL DEFAULT 0.00,
+    total_spent decimal(15,2) NOT NULL DEFAULT 0.00,
+    net decimal(15,2) NOT NULL DEFAULT 0.00,
+    expense_count bigint NOT NULL DEFAULT 0,
+    within_budget boolean,
+    usage_percent decimal(7,2) NOT NULL,
+    closed_at timestamptz NOT NULL,
+    closed_by varchar(20) NOT NULL,
+    reopened_at timestamptz,
+    reopen_reason text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_close_user_month ON budget_closes (user_id, month_year);
+
+CREATE TABLE IF NOT EXISTS budget_close_categories (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    close_id uuid NOT NULL REFERENCES budget_closes(id) ON DELETE CASCADE,
+    category_id uuid NOT NULL,
+    category_name text NOT NULL,
+    budget decimal(15,2) NOT NULL,
+    spent decimal(15,2) NOT NULL,
+    within boolean
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_close_categories_close_id ON budget_close_categories (close_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS budget_close_categories;
+DROP TABLE IF EXISTS budget_closes;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS first_day_of_week varchar(9) NOT NULL DEFAULT 'monday';
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS first_day_of_month bigint NOT NULL DEFAULT 1;
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS budgeting_mode varchar(20) NOT NULL DEFAULT '50_30_20';
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS date_format varchar(10) NOT NULL DEFAULT 'YYYY-MM-DD';
+
+-- +goose Down
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS date_format;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS budgeting_mode;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS first_day_of_month;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS first_day_of_week;
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS source varchar(20) NOT NULL DEFAULT 'other';
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS description text;
+ALTER TABLE incomes ALTER COLUMN bank_account_id DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_incomes_source ON incomes (source);
+UPDATE incomes SET source = 'refund' WHERE refund_of_expense_id IS NOT NULL AND source = 'other';
+
+-- +goose Down
+-- Incomes without an account move to the oldest account of their user, whose balance takes
+-- them like it would have when they were created; users without any account lose them
+UPDATE bank_accounts b SET balance = b.balance + moved.total
+	FROM (
+		SELECT f.id, SUM(i.amount) AS total
+		FROM incomes i
+		JOIN (SELECT DISTINCT ON (user_id) user_id, id FROM bank_accounts ORDER BY user_id, created_at, id) f ON f.user_id = i.user_id
+		WHERE i.bank_account_id IS NULL AND i.status IN ('active', 'pending')
+		GROUP BY f.id
+	) moved
+	WHERE b.id = moved.id AND NOT b.manual_balance;
+UPDATE incomes i SET bank_account_id = f.id
+	FROM (SELECT DISTINCT ON (user_id) user_id, id FROM bank_accounts ORDER BY user_id, created_at, id) f
+	WHERE i.user_id = f.user_id AND i.bank_account_id IS NULL;
+DELETE FROM incomes WHERE bank_account_id IS NULL;
+ALTER TABLE incomes ALTER COLUMN bank_account_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_incomes_source;
+ALTER TABLE incomes DROP COLUMN IF EXISTS description;
+ALTER TABLE incomes DROP COLUMN IF EXISTS source;
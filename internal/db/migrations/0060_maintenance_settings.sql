@@ -0,0 +1,17 @@
+-- Maintenance switch shared by every instance
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS maintenance_settings (
+    id bigint PRIMARY KEY,
+    enabled boolean NOT NULL DEFAULT false,
+    reason text NOT NULL DEFAULT '',
+    retry_after_seconds bigint NOT NULL DEFAULT 0,
+    since timestamptz,
+    updated_by uuid,
+    updated_at timestamptz
+);
+
+INSERT INTO maintenance_settings (id, updated_at) VALUES (1, now()) ON CONFLICT (id) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS maintenance_settings;
@@ -0,0 +1,13 @@
+-- Status records had before being archived with their bank account
+
+-- +goose Up
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS prior_status varchar(20);
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS prior_status varchar(20);
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS prior_status varchar(20);
+ALTER TABLE fixed_expenses ADD COLUMN IF NOT EXISTS prior_status varchar(20);
+
+-- +goose Down
+ALTER TABLE fixed_expenses DROP COLUMN IF EXISTS prior_status;
+ALTER TABLE transfers DROP COLUMN IF EXISTS prior_status;
+ALTER TABLE incomes DROP COLUMN IF EXISTS prior_status;
+ALTER TABLE expenses DROP COLUMN IF EXISTS prior_status;
@@ -0,0 +1,150 @@
+-- Amounts keep three decimals, for currencies such as BHD and KWD. Rolling back rounds them to
+-- two again
+
+-- +goose Up
+ALTER TABLE users
+    ALTER COLUMN monthly_income TYPE decimal(18,3);
+ALTER TABLE bank_accounts
+    ALTER COLUMN balance TYPE decimal(18,3);
+ALTER TABLE fixed_expenses
+    ALTER COLUMN amount TYPE decimal(18,3),
+    ALTER COLUMN drift_suggestion TYPE decimal(18,3);
+ALTER TABLE goals
+    ALTER COLUMN total_amount TYPE decimal(18,3),
+    ALTER COLUMN saved_amount TYPE decimal(18,3);
+ALTER TABLE expenses
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE incomes
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE categories
+    ALTER COLUMN monthly_cap TYPE decimal(18,3);
+ALTER TABLE budgets
+    ALTER COLUMN needs_budget TYPE decimal(18,3),
+    ALTER COLUMN wants_budget TYPE decimal(18,3),
+    ALTER COLUMN savings_budget TYPE decimal(18,3);
+ALTER TABLE transfers
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE goal_milestones
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE expense_allocations
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE budget_revisions
+    ALTER COLUMN needs_budget TYPE decimal(18,3),
+    ALTER COLUMN wants_budget TYPE decimal(18,3),
+    ALTER COLUMN savings_budget TYPE decimal(18,3);
+ALTER TABLE budget_compliances
+    ALTER COLUMN needs_budget TYPE decimal(18,3),
+    ALTER COLUMN wants_budget TYPE decimal(18,3),
+    ALTER COLUMN savings_budget TYPE decimal(18,3),
+    ALTER COLUMN needs_spent TYPE decimal(18,3),
+    ALTER COLUMN wants_spent TYPE decimal(18,3),
+    ALTER COLUMN savings_spent TYPE decimal(18,3);
+ALTER TABLE imported_transactions
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE trips
+    ALTER COLUMN budget TYPE decimal(18,3);
+ALTER TABLE sub_profiles
+    ALTER COLUMN approval_threshold TYPE decimal(18,3);
+ALTER TABLE expense_approvals
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE goal_contributions
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE user_preferences
+    ALTER COLUMN expense_confirm_above TYPE decimal(18,3),
+    ALTER COLUMN transfer_confirm_above TYPE decimal(18,3);
+ALTER TABLE fixed_expense_runs
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE category_budgets
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE budget_category_compliances
+    ALTER COLUMN budget TYPE decimal(18,3),
+    ALTER COLUMN spent TYPE decimal(18,3);
+ALTER TABLE fixed_expense_occurrences
+    ALTER COLUMN amount TYPE decimal(18,3);
+ALTER TABLE budget_closes
+    ALTER COLUMN needs_budget TYPE decimal(18,3),
+    ALTER COLUMN wants_budget TYPE decimal(18,3),
+    ALTER COLUMN savings_budget TYPE decimal(18,3),
+    ALTER COLUMN needs_spent TYPE decimal(18,3),
+    ALTER COLUMN wants_spent TYPE decimal(18,3),
+    ALTER COLUMN savings_spent TYPE decimal(18,3),
+    ALTER COLUMN total_income TYPE decimal(18,3),
+    ALTER COLUMN total_spent TYPE decimal(18,3),
+    ALTER COLUMN net TYPE decimal(18,3);
+ALTER TABLE budget_close_categories
+    ALTER COLUMN budget TYPE decimal(18,3),
+    ALTER COLUMN spent TYPE decimal(18,3);
+
+-- +goose Down
+ALTER TABLE users
+    ALTER COLUMN monthly_income TYPE decimal(15,2);
+ALTER TABLE bank_accounts
+    ALTER COLUMN balance TYPE decimal(15,2);
+ALTER TABLE fixed_expenses
+    ALTER COLUMN amount TYPE decimal(15,2),
+    ALTER COLUMN drift_suggestion TYPE decimal(15,2);
+ALTER TABLE goals
+    ALTER COLUMN total_amount TYPE decimal(15,2),
+    ALTER COLUMN saved_amount TYPE decimal(15,2);
+ALTER TABLE expenses
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE incomes
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE categories
+    ALTER COLUMN monthly_cap TYPE decimal(15,2);
+ALTER TABLE budgets
+    ALTER COLUMN needs_budget TYPE decimal(15,2),
+    ALTER COLUMN wants_budget TYPE decimal(15,2),
+    ALTER COLUMN savings_budget TYPE decimal(15,2);
+ALTER TABLE transfers
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE goal_milestones
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE expense_allocations
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE budget_revisions
+    ALTER COLUMN needs_budget TYPE decimal(15,2),
+    ALTER COLUMN wants_budget TYPE decimal(15,2),
+    ALTER COLUMN savings_budget TYPE decimal(15,2);
+ALTER TABLE budget_compliances
+    ALTER COLUMN needs_budget TYPE decimal(15,2),
+    ALTER COLUMN wants_budget TYPE decimal(15,2),
+    ALTER COLUMN savings_budget TYPE decimal(15,2),
+    ALTER COLUMN needs_spent TYPE decimal(15,2),
+    ALTER COLUMN wants_spent TYPE decimal(15,2),
+    ALTER COLUMN savings_spent TYPE decimal(15,2);
+ALTER TABLE imported_transactions
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE trips
+    ALTER COLUMN budget TYPE decimal(15,2);
+ALTER TABLE sub_profiles
+    ALTER COLUMN approval_threshold TYPE decimal(15,2);
+ALTER TABLE expense_approvals
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE goal_contributions
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE user_preferences
+    ALTER COLUMN expense_confirm_above TYPE decimal(15,2),
+    ALTER COLUMN transfer_confirm_above TYPE decimal(15,2);
+ALTER TABLE fixed_expense_runs
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE category_budgets
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE budget_category_compliances
+    ALTER COLUMN budget TYPE decimal(15,2),
+    ALTER COLUMN spent TYPE decimal(15,2);
+ALTER TABLE fixed_expense_occurrences
+    ALTER COLUMN amount TYPE decimal(15,2);
+ALTER TABLE budget_closes
+    ALTER COLUMN needs_budget TYPE decimal(15,2),
+    ALTER COLUMN wants_budget TYPE decimal(15,2),
+    ALTER COLUMN savings_budget TYPE decimal(15,2),
+    ALTER COLUMN needs_spent TYPE decimal(15,2),
+    ALTER COLUMN wants_spent TYPE decimal(15,2),
+    ALTER COLUMN savings_spent TYPE decimal(15,2),
+    ALTER COLUMN total_income TYPE decimal(15,2),
+    ALTER COLUMN total_spent TYPE decimal(15,2),
+    ALTER COLUMN net TYPE decimal(15,2);
+ALTER TABLE budget_close_categories
+    ALTER COLUMN budget TYPE decimal(15,2),
+    ALTER COLUMN spent TYPE decimal(15,2);
@@ -0,0 +1,36 @@
+-- The currency of each amount, so amounts keep theirs when the user changes currency. Existing
+-- rows take the current currency of their user
+
+-- +goose Up
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE fixed_expenses ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS currency varchar(3);
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS currency varchar(3);
+
+UPDATE bank_accounts t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE expenses t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE incomes t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE transfers t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE fixed_expenses t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE goals t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+UPDATE budgets t SET currency = u.currency FROM users u WHERE u.id = t.user_id AND t.currency IS NULL;
+
+ALTER TABLE bank_accounts ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE expenses ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE incomes ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE transfers ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE fixed_expenses ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE goals ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE budgets ALTER COLUMN currency SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE budgets DROP COLUMN IF EXISTS currency;
+ALTER TABLE goals DROP COLUMN IF EXISTS currency;
+ALTER TABLE fixed_expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE transfers DROP COLUMN IF EXISTS currency;
+ALTER TABLE incomes DROP COLUMN IF EXISTS currency;
+ALTER TABLE expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS currency;
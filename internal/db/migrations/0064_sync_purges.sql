@@ -0,0 +1,15 @@
+-- How far deleted rows were removed for good, per user and sync entity, to turn away sync
+-- cursors that missed their deletions
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "sync_purges" (
+    "user_id" uuid NOT NULL,
+    "entity" varchar(30) NOT NULL,
+    "purged_through" timestamptz NOT NULL,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id","entity"),
+    CONSTRAINT "fk_sync_purges_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS "sync_purges";
@@ -0,0 +1,8 @@
+-- Login codes are no longer sent through the outbox. Take the codes out of the events that
+-- still carry them
+
+-- +goose Up
+UPDATE outbox_events SET payload = payload - 'code' WHERE event_type = 'security.step_up_code';
+
+-- +goose Down
+-- The codes aren't restored
@@ -0,0 +1,13 @@
+-- Large amount confirm tokens already spent, so each one confirms a single entry
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS "used_confirm_tokens" (
+    "id" uuid PRIMARY KEY,
+    "user_id" uuid NOT NULL,
+    "created_at" timestamptz NOT NULL,
+    CONSTRAINT "fk_used_confirm_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_used_confirm_tokens_created_at" ON "used_confirm_tokens" ("created_at");
+
+-- +goose Down
+DROP TABLE IF EXISTS "used_confirm_tokens";
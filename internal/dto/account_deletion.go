@@ -0,0 +1,7 @@
+package dto
+
+// AccountDeletion tells when a scheduled account deletion takes place
+type AccountDeletion struct {
+	ScheduledFor string `json:"scheduled_for"` // RFC 3339; until then POST /api/v1/auth/me/restore cancels it
+	GraceDays    int    `json:"grace_days"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// AccountGroup is a group of bank accounts with its aggregated balance
+type AccountGroup struct {
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	BankAccountIDs []string     `json:"bank_account_ids"`
+	AccountCount   int          `json:"account_count"`
+	TotalBalance   models.Money `json:"total_balance"` // Sum of the active member accounts
+	CreatedAt      time.Time    `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// SystemStats is the instance-wide overview shown to admins
+type SystemStats struct {
+	Users                    UserStats `json:"users"`
+	Expenses                 int64     `json:"expenses"` // Visible records of every user
+	Incomes                  int64     `json:"incomes"`
+	BankAccounts             int64     `json:"bank_accounts"`
+	OutboxPending            int64     `json:"outbox_pending"`
+	OutboxFailed             int64     `json:"outbox_failed"` // Dead letters waiting for a retry or the purge
+	WebhookDeliveriesPending int64     `json:"webhook_deliveries_pending"`
+	GeneratedAt              time.Time `json:"generated_at"`
+}
+
+// UserStats counts the users of the instance; sandbox tenants are left out
+type UserStats struct {
+	Total        int64            `json:"total"`
+	ByStatus     map[string]int64 `json:"by_status"`
+	ByRole       map[string]int64 `json:"by_role"`
+	NewLast30    int64            `json:"new_last_30_days"`
+	ActiveLast30 int64            `json:"active_last_30_days"` // Logged in during the last 30 days
+	Sandboxes    int64            `json:"sandboxes"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// AnalyticsSeriesLine is one line of a chart: a value per bin, zero where nothing happened
+type AnalyticsSeriesLine struct {
+	Key    string         `json:"key"` // Group ID (category or account ID, normalized payee), "total" when ungrouped
+	Name   string         `json:"name"`
+	Values []models.Money `json:"values"` // One per bin, in the order of AnalyticsSeries.Bins
+	Total  models.Money   `json:"total"`
+}
+
+// AnalyticsSeries is a metric binned evenly over a date range, ready to chart
+type AnalyticsSeries struct {
+	Metric   string                `json:"metric"`   // spend, income or net
+	Interval string                `json:"interval"` // day, week or month
+	GroupBy  string                `json:"group_by,omitempty"`
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	Currency string                `json:"currency"`
+	Bins     []string              `json:"bins"` // Start date of each bin; weeks start on Monday
+	Series   []AnalyticsSeriesLine `json:"series"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+// AnonymizationPreview describes what anonymizing the account will do, with the token needed
+// to confirm it
+type AnonymizationPreview struct {
+	ConfirmationToken string           `json:"confirmation_token"`
+	ExpiresAt         string           `json:"expires_at"`
+	ConfirmText       string           `json:"confirm_text"` // Must be sent back verbatim in "confirm"
+	Records           map[string]int64 `json:"records"`      // Financial records that are kept anonymized, or deleted
+}
+
+// AnonymizationResult is the outcome of an anonymization
+type AnonymizationResult struct {
+	UserID         string `json:"user_id"`
+	KeptAggregates bool   `json:"kept_aggregates"`
+	AnonymizedAt   string `json:"anonymized_at"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// APIKeyUsage is the usage of one API key over a period, used to spot leaked keys
+type APIKeyUsage struct {
+	KeyID         string                `json:"key_id"`
+	Name          string                `json:"name"`
+	Prefix        string                `json:"prefix"`
+	StartDate     string                `json:"start_date"`
+	EndDate       string                `json:"end_date"`
+	TotalRequests int64                 `json:"total_requests"`
+	LastUsedAt    *time.Time            `json:"last_used_at,omitempty"`
+	Endpoints     []APIKeyEndpointUsage `json:"endpoints"`
+	Daily         []APIKeyDailyUsage    `json:"daily"`
+}
+
+// APIKeyEndpointUsage is the request count of an API key on one endpoint
+type APIKeyEndpointUsage struct {
+	Method       string    `json:"method"`
+	Endpoint     string    `json:"endpoint"`
+	RequestCount int64     `json:"request_count"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+// APIKeyDailyUsage is the request count of an API key on one day
+type APIKeyDailyUsage struct {
+	Day          string `json:"day"` // YYYY-MM-DD
+	RequestCount int64  `json:"request_count"`
+}
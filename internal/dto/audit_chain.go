@@ -0,0 +1,18 @@
+package dto
+
+// AuditChainProblem is an audit entry that doesn't fit in the hash chain
+type AuditChainProblem struct {
+	Sequence int64  `json:"sequence"`
+	EntryID  string `json:"entry_id"`
+	Issue    string `json:"issue"`
+}
+
+// AuditChainVerification is the result of checking the audit log hash chain
+type AuditChainVerification struct {
+	Valid        bool                `json:"valid"`
+	Checked      int64               `json:"checked"`   // Chained entries checked
+	Unchained    int64               `json:"unchained"` // Entries written before chaining, which can't be verified
+	LastSequence int64               `json:"last_sequence"`
+	LastHash     string              `json:"last_hash"` // Keep it elsewhere to detect removal of the latest entries
+	Problems     []AuditChainProblem `json:"problems"`  // At most 100
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// AvailableBalance splits the balance of an account into what is booked and what can
+// actually be spent once pending and upcoming commitments are taken out
+type AvailableBalance struct {
+	BankAccountID       string              `json:"bank_account_id"`
+	AccountName         string              `json:"account_name"`
+	Days                int                 `json:"days"` // Window used for upcoming commitments
+	BookedBalance       models.Money        `json:"booked_balance"`
+	PendingHolds        models.Money        `json:"pending_holds"`        // Already due, not yet posted
+	UpcomingCommitments models.Money        `json:"upcoming_commitments"` // Due within the window
+	AvailableBalance    models.Money        `json:"available_balance"`    // Booked - holds - upcoming
+	Commitments         []BalanceCommitment `json:"commitments"`
+}
+
+// BalanceCommitment is one scheduled movement that reduces the available balance
+type BalanceCommitment struct {
+	Kind     string       `json:"kind"` // fixed_expense
+	SourceID string       `json:"source_id"`
+	Name     string       `json:"name"`
+	Amount   models.Money `json:"amount"`
+	DueDate  string       `json:"due_date"` // YYYY-MM-DD
+	Pending  bool         `json:"pending"`  // Due date already passed, waiting to be processed
+}
@@ -0,0 +1,14 @@
+package dto
+
+// BankAccountDeleteImpact is what deleting a bank account would affect: the records that still
+// use it and would be archived with it
+type BankAccountDeleteImpact struct {
+	BankAccountID   string `json:"bank_account_id"`
+	AccountName     string `json:"account_name"`
+	Expenses        int64  `json:"expenses"` // Paid from the account, split ones included
+	Incomes         int64  `json:"incomes"`
+	Transfers       int64  `json:"transfers"` // From or to the account
+	FixedExpenses   int64  `json:"fixed_expenses"`
+	Total           int64  `json:"total"`
+	RequiresCascade bool   `json:"requires_cascade"` // Deleting needs cascade=true, which archives these records
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// BankLinkToken starts the provider's bank login flow on the client
+type BankLinkToken struct {
+	Provider   string    `json:"provider" example:"plaid"`
+	LinkToken  string    `json:"link_token" example:"link-sandbox-af1a0311-da53-4636-b754-dd15cc058176"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// BankSyncResult tells what a sync of a bank connection pulled in
+type BankSyncResult struct {
+	ConnectionID string    `json:"connection_id"`
+	Expenses     int       `json:"expenses"` // Imported transactions that became expenses or matches
+	Incomes      int       `json:"incomes"`
+	Removed      int       `json:"removed"` // Records deleted because the bank dropped their transaction
+	Skipped      int       `json:"skipped"` // Pending, too old or on accounts not linked to a Fluxio account
+	BatchIDs     []string  `json:"batch_ids"`
+	SyncedAt     time.Time `json:"synced_at"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// SpendingBenchmarks compares the user's monthly spending per category with other opted-in users
+type SpendingBenchmarks struct {
+	Month           string              `json:"month"`            // YYYY-MM
+	MinParticipants int                 `json:"min_participants"` // Users needed before a category is benchmarked
+	Categories      []CategoryBenchmark `json:"categories"`
+}
+
+// CategoryBenchmark places the user's spending of one category in the distribution of all
+// participants. Distribution fields are only set when the privacy threshold is met.
+type CategoryBenchmark struct {
+	Category    string        `json:"category"`
+	YourSpend   models.Money  `json:"your_spend"`
+	Available   bool          `json:"available"`
+	Percentile  *int          `json:"percentile,omitempty"` // Share of participants spending less than the user
+	P25         *models.Money `json:"p25,omitempty"`
+	Median      *models.Money `json:"median,omitempty"`
+	P75         *models.Money `json:"p75,omitempty"`
+	Description string        `json:"description"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// BudgetComplianceBackfill summarizes a backfill run
+type BudgetComplianceBackfill struct {
+	Computed int      `json:"computed"`
+	Skipped  int      `json:"skipped"` // Already stored and not recomputed
+	Months   []string `json:"months"`  // YYYY-MM computed in this run
+}
+
+// BudgetComplianceHistory is the stored compliance over a range of months
+type BudgetComplianceHistory struct {
+	Months             []models.BudgetCompliance `json:"months"`
+	MonthsWithinBudget int                       `json:"months_within_budget"`
+	AdherencePercent   float64                   `json:"adherence_percent"` // Share of months within budget
+}
+
+// BudgetLineCompliance compares one budget line with what was spent against it
+type BudgetLineCompliance struct {
+	Budget       models.Money `json:"budget"`
+	Spent        models.Money `json:"spent"`
+	Remaining    models.Money `json:"remaining"`     // Negative when over budget
+	UsagePercent float64      `json:"usage_percent"` // Spent / budget
+	OverBudget   bool         `json:"over_budget"`
+}
+
+// CategoryLineCompliance is a category budget line of the month against its spending
+type CategoryLineCompliance struct {
+	CategoryID   string `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	BudgetLineCompliance
+}
+
+// MonthlyBudgetCompliance is the budget of a month against the actual spending. For the
+// current month it is spending to date
+type MonthlyBudgetCompliance struct {
+	Month      string                   `json:"month"`  // YYYY-MM
+	From       string                   `json:"from"`   // YYYY-MM-DD, first day of the user's month period
+	To         string                   `json:"to"`     // YYYY-MM-DD, last day of the period
+	Closed     bool                     `json:"closed"` // The period has ended
+	Currency   string                   `json:"currency"`
+	Needs      BudgetLineCompliance     `json:"needs"` // Time-weighted over the month's budget revisions
+	Wants      BudgetLineCompliance     `json:"wants"`
+	Savings    BudgetLineCompliance     `json:"savings"`
+	Total      BudgetLineCompliance     `json:"total"`
+	Categories []CategoryLineCompliance `json:"categories"`
+	OverBudget bool                     `json:"over_budget"` // Any line, category lines included, over its budget
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// BudgetPlan is a preview (or the applied result) of budgets for several consecutive months
+type BudgetPlan struct {
+	Source     string            `json:"source"`      // template or suggested
+	OnConflict string            `json:"on_conflict"` // fail, skip or overwrite
+	Months     []BudgetPlanMonth `json:"months"`
+	Conflicts  int               `json:"conflicts"` // Months that already have a budget
+	Applied    bool              `json:"applied"`
+}
+
+// BudgetPlanMonth is the planned budget of one month
+type BudgetPlanMonth struct {
+	Month            string       `json:"month"` // YYYY-MM
+	NeedsBudget      models.Money `json:"needs_budget"`
+	WantsBudget      models.Money `json:"wants_budget"`
+	SavingsBudget    models.Money `json:"savings_budget"`
+	ExistingBudgetID *string      `json:"existing_budget_id,omitempty"`
+	Action           string       `json:"action"` // create, skip, overwrite or conflict
+}
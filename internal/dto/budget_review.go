@@ -0,0 +1,11 @@
+package dto
+
+// BudgetReviewStatus tells how up to date the user's budget reviews are
+type BudgetReviewStatus struct {
+	Cadence         string  `json:"cadence"`                    // weekly, monthly or empty when reviews aren't scheduled
+	LastReviewedAt  *string `json:"last_reviewed_at,omitempty"` // When a budget_review reminder was last completed
+	DaysSinceReview *int    `json:"days_since_review,omitempty"`
+	NextReviewDue   *string `json:"next_review_due,omitempty"` // YYYY-MM-DD the next reminder is created for
+	PendingReviews  int64   `json:"pending_reviews"`           // Due budget_review reminders not completed yet
+	Overdue         bool    `json:"overdue"`
+}
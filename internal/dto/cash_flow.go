@@ -0,0 +1,72 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// CashFlowTypeAmount is what left the accounts for one expense type (50/30/20) in the month
+type CashFlowTypeAmount struct {
+	ExpenseType string       `json:"expense_type"`
+	Name        string       `json:"name"`
+	Amount      models.Money `json:"amount"`
+}
+
+// IncomeSourceAmount is the income of one source (salary, freelance, dividends...) in a period
+type IncomeSourceAmount struct {
+	Source  string       `json:"source"`
+	Name    string       `json:"name"`
+	Amount  models.Money `json:"amount"`
+	Percent float64      `json:"percent"` // Share of the income broken down
+}
+
+// CashFlowInflows is the money that came in
+type CashFlowInflows struct {
+	Income   models.Money         `json:"income"`
+	BySource []IncomeSourceAmount `json:"by_source"` // Composition of income, largest first
+	Refunds  models.Money         `json:"refunds"`   // Incomes refunding an expense, counted when received
+	Total    models.Money         `json:"total"`
+}
+
+// CashFlowOutflows is the money that left the accounts, by expense type
+type CashFlowOutflows struct {
+	ByExpenseType []CashFlowTypeAmount `json:"by_expense_type"`
+	Total         models.Money         `json:"total"`
+}
+
+// CashFlowFixedExpenses relates the month to the fixed expenses scheduled in it. Posted fixed
+// expenses are already part of the outflows
+type CashFlowFixedExpenses struct {
+	Scheduled models.Money `json:"scheduled"` // Due in the month
+	Pending   models.Money `json:"pending"`   // Due in the month and not posted yet
+}
+
+// CashFlowAccount is the statement of one bank account. Balances are nil for accounts kept by
+// hand, whose balance doesn't follow the records
+type CashFlowAccount struct {
+	BankAccountID  string        `json:"bank_account_id"`
+	AccountName    string        `json:"account_name"`
+	ManualBalance  bool          `json:"manual_balance"`
+	OpeningBalance *models.Money `json:"opening_balance"`
+	Inflows        models.Money  `json:"inflows"`
+	Outflows       models.Money  `json:"outflows"`
+	TransfersIn    models.Money  `json:"transfers_in"`
+	TransfersOut   models.Money  `json:"transfers_out"`
+	NetChange      models.Money  `json:"net_change"`
+	ClosingBalance *models.Money `json:"closing_balance"`
+}
+
+// CashFlowReport combines incomes, expenses, fixed expenses and transfers of a month into a
+// single statement
+type CashFlowReport struct {
+	Year           int                   `json:"year"`
+	Month          int                   `json:"month"`
+	From           string                `json:"from"`
+	To             string                `json:"to"`
+	Currency       string                `json:"currency"`
+	OpeningBalance models.Money          `json:"opening_balance"` // Accounts with a tracked balance
+	Inflows        CashFlowInflows       `json:"inflows"`
+	Outflows       CashFlowOutflows      `json:"outflows"`
+	FixedExpenses  CashFlowFixedExpenses `json:"fixed_expenses"`
+	Transfers      models.Money          `json:"transfers"` // Moved between accounts; no effect on the total
+	NetChange      models.Money          `json:"net_change"`
+	ClosingBalance models.Money          `json:"closing_balance"`
+	Accounts       []CashFlowAccount     `json:"accounts"`
+}
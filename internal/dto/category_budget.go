@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// CategoryBudgetLine is the budget and spend of one category budget line in a month
+type CategoryBudgetLine struct {
+	CategoryID   string       `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	ExpenseType  string       `json:"expense_type"`
+	Budget       models.Money `json:"budget"`
+	Spent        models.Money `json:"spent"` // Net of refunds
+	Remaining    models.Money `json:"remaining"`
+	UsagePercent float64      `json:"usage_percent"`
+	Within       bool         `json:"within"`
+}
+
+// CategoryBudgetReport compares the category budget lines of a monthly budget with the spending
+type CategoryBudgetReport struct {
+	BudgetID string               `json:"budget_id"`
+	Month    string               `json:"month"` // YYYY-MM
+	Currency string               `json:"currency"`
+	Lines    []CategoryBudgetLine `json:"lines"`
+	Budgeted models.Money         `json:"budgeted"` // Sum of the category lines
+	Spent    models.Money         `json:"spent"`    // Spent in the budgeted categories
+}
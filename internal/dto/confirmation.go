@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// ConfirmationThresholds are the amounts above which creating an entry needs a confirmation
+// step. Nil turns the confirmation off
+type ConfirmationThresholds struct {
+	Expense  *models.Money `json:"expense"`
+	Transfer *models.Money `json:"transfer"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// DashboardBudgetLine is the budget and spend of one expense type (50/30/20) this month
+type DashboardBudgetLine struct {
+	ExpenseType string       `json:"expense_type"`
+	Name        string       `json:"name"`
+	Budget      models.Money `json:"budget"`
+	Spent       models.Money `json:"spent"` // Net of refunds
+	Remaining   models.Money `json:"remaining"`
+}
+
+// DashboardUpcomingBills sums the fixed expenses due in the next days
+type DashboardUpcomingBills struct {
+	Days        int          `json:"days"`
+	Count       int          `json:"count"`
+	Total       models.Money `json:"total"`
+	NextDueDate *string      `json:"next_due_date,omitempty"`
+}
+
+// DashboardGoals sums the active savings goals
+type DashboardGoals struct {
+	Active int          `json:"active"`
+	Saved  models.Money `json:"saved"`
+	Target models.Money `json:"target"`
+}
+
+// DashboardState holds the figures of the dashboard widgets for the current month
+type DashboardState struct {
+	Version       int64                  `json:"version"`
+	Month         string                 `json:"month"` // YYYY-MM
+	Currency      string                 `json:"currency"`
+	Income        models.Money           `json:"income"`
+	Spent         models.Money           `json:"spent"`  // Net of refunds
+	Budget        models.Money           `json:"budget"` // 0 without a budget for the month
+	Remaining     models.Money           `json:"remaining"`
+	ByExpenseType []DashboardBudgetLine  `json:"by_expense_type"`
+	ByCategory    []CategoryBudgetLine   `json:"by_category,omitempty"` // Category budget lines of the month
+	UpcomingBills DashboardUpcomingBills `json:"upcoming_bills"`
+	Goals         DashboardGoals         `json:"goals"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// DataQualityIssue is one record that needs the user's attention
+type DataQualityIssue struct {
+	Type        string        `json:"type"`        // uncategorized_expense, expense_missing_account, stale_pending or negative_balance
+	EntityType  string        `json:"entity_type"` // expense, income, transfer, import_match or bank_account
+	EntityID    string        `json:"entity_id"`
+	Description string        `json:"description"`
+	Amount      *models.Money `json:"amount,omitempty"`
+	Date        *string       `json:"date,omitempty"`
+}
+
+// DataQualityReport lists what looks wrong in the user's data. Counts cover every issue
+// found; the list is capped per type
+type DataQualityReport struct {
+	GeneratedAt            time.Time          `json:"generated_at"`
+	UncategorizedExpenses  int                `json:"uncategorized_expenses"`
+	ExpensesMissingAccount int                `json:"expenses_missing_account"`
+	StalePending           int                `json:"stale_pending"`
+	NegativeBalances       int                `json:"negative_balances"`
+	TotalIssues            int                `json:"total_issues"`
+	Issues                 []DataQualityIssue `json:"issues"`
+}
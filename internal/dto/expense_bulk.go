@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// ExpenseBulkChange is one expense matched by a bulk edit, with the values it has and the ones
+// it gets. New values are only set for the fields the edit changes
+type ExpenseBulkChange struct {
+	ExpenseID        string       `json:"expense_id"`
+	Date             string       `json:"date"`
+	Amount           models.Money `json:"amount"`
+	Description      *string      `json:"description,omitempty"`
+	CategoryID       string       `json:"category_id"`
+	NewCategoryID    *string      `json:"new_category_id,omitempty"`
+	BankAccountID    string       `json:"bank_account_id"`
+	NewBankAccountID *string      `json:"new_bank_account_id,omitempty"`
+}
+
+// ExpenseBulkEdit is the result of a bulk edit, or what it would do when run as a dry run
+type ExpenseBulkEdit struct {
+	DryRun    bool                `json:"dry_run"`
+	Matched   int                 `json:"matched"`
+	Updated   int                 `json:"updated"` // Expenses that change, or would change on a dry run
+	Unchanged int                 `json:"unchanged"`
+	Changes   []ExpenseBulkChange `json:"changes"`
+}
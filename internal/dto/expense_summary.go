@@ -0,0 +1,30 @@
+// Package dto holds the typed results shared between services and API handlers
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// ExpenseSummary is the expense summary of a period
+type ExpenseSummary struct {
+	TotalAmount   models.Money        `json:"total_amount"`
+	TotalCount    int64               `json:"total_count"`
+	AverageAmount models.Money        `json:"average_amount"`
+	ByExpenseType []ExpenseTypeTotal  `json:"by_expense_type"`
+	GroupBy       string              `json:"group_by"`
+	TopGroups     []ExpenseGroupTotal `json:"top_groups"`
+}
+
+// ExpenseTypeTotal is the net spent for one expense type (50/30/20)
+type ExpenseTypeTotal struct {
+	ExpenseTypeName string       `json:"expense_type_name"`
+	TotalAmount     models.Money `json:"total_amount"`
+	Count           int64        `json:"count"`
+}
+
+// ExpenseGroupTotal is the net spent for one group (category, account, payee...)
+type ExpenseGroupTotal struct {
+	Key             string       `json:"key"`
+	Name            string       `json:"name"`
+	ExpenseTypeName string       `json:"expense_type_name,omitempty"` // Only set when grouping by category
+	TotalAmount     models.Money `json:"total_amount"`
+	Count           int64        `json:"count"`
+}
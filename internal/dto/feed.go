@@ -0,0 +1,36 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// Feed item types
+const (
+	FeedTypeExpense  = "expense"
+	FeedTypeIncome   = "income"
+	FeedTypeTransfer = "transfer"
+)
+
+// FeedRef names a related account or category
+type FeedRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FeedItem is one expense, income or transfer in the transaction feed
+type FeedItem struct {
+	Type        string       `json:"type" example:"expense"` // expense, income or transfer
+	ID          string       `json:"id"`
+	Amount      models.Money `json:"amount" example:"42.5"`
+	Date        string       `json:"date" example:"2025-03-14"`
+	Description *string      `json:"description,omitempty"`
+	Account     *FeedRef     `json:"account,omitempty"`    // The account charged or credited; the source account of a transfer
+	Category    *FeedRef     `json:"category,omitempty"`   // Only for expenses
+	ToAccount   *FeedRef     `json:"to_account,omitempty"` // Only for transfers
+	RefundOf    *string      `json:"refund_of,omitempty"`  // Expense an income refunds
+}
+
+// FeedPage is a page of the transaction feed, newest first
+type FeedPage struct {
+	Items      []FeedItem `json:"items"`
+	Count      int        `json:"count"`
+	NextCursor *string    `json:"next_cursor,omitempty" example:"azE6MjAyNS0wMy0xNHw"` // Pass as cursor to get the next page; absent on the last one
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// FinancialScoreComponent is one measure behind the financial health score
+type FinancialScoreComponent struct {
+	Key    string   `json:"key"` // savings_rate, rule_50_30_20, fixed_expense_load or budget_volatility
+	Name   string   `json:"name"`
+	Score  *float64 `json:"score"`  // 0-100, null when the month has no data for it
+	Weight float64  `json:"weight"` // Share of the overall score in percent, 0 when the component has no score
+	Value  *float64 `json:"value"`  // The measure that was scored, in percent
+	Target string   `json:"target"` // What earns the full score
+}
+
+// FinancialScore is the financial health of a month as one number with its breakdown
+type FinancialScore struct {
+	Month         string                    `json:"month"` // YYYY-MM
+	Currency      string                    `json:"currency"`
+	Score         *float64                  `json:"score"`          // 0-100, weighted over the components with data; null when none has
+	Rating        string                    `json:"rating"`         // excellent, good, fair or poor; empty without a score
+	Income        models.Money              `json:"income"`         // Refunds left out when they already reduce spending
+	IncomeSources []IncomeSourceAmount      `json:"income_sources"` // Composition of income, largest first
+	Needs         models.Money              `json:"needs"`          // Spent, net of refunds
+	Wants         models.Money              `json:"wants"`
+	Savings       models.Money              `json:"savings"`        // Spent on savings categories
+	Saved         models.Money              `json:"saved"`          // Income not spent on needs or wants
+	FixedExpenses models.Money              `json:"fixed_expenses"` // Scheduled for the month
+	Components    []FinancialScoreComponent `json:"components"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// FixedExpenseDriftPeriod is what was actually paid for a fixed expense in one month
+type FixedExpenseDriftPeriod struct {
+	Month     string       `json:"month"` // YYYY-MM
+	DueDate   string       `json:"due_date"`
+	ExpenseID string       `json:"expense_id"`
+	Amount    models.Money `json:"amount"`
+}
+
+// FixedExpenseDrift is a fixed expense whose recent payments keep differing from its configured
+// amount, with the amount it should probably be updated to
+type FixedExpenseDrift struct {
+	FixedExpenseID  string                    `json:"fixed_expense_id"`
+	Name            string                    `json:"name"`
+	Amount          models.Money              `json:"amount"`
+	SuggestedAmount models.Money              `json:"suggested_amount"`
+	DriftPercent    float64                   `json:"drift_percent"` // Positive when payments are above the configured amount
+	Periods         []FixedExpenseDriftPeriod `json:"periods"`       // Most recent first
+}
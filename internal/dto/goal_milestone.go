@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// GoalMilestone is a goal milestone with its resolved target, used for progress charts
+type GoalMilestone struct {
+	ID           string        `json:"id"`
+	Percent      *float64      `json:"percent,omitempty"`
+	Amount       *models.Money `json:"amount,omitempty"`
+	Label        *string       `json:"label,omitempty"`
+	TargetAmount models.Money  `json:"target_amount"`
+	Reached      bool          `json:"reached"`
+	ReachedAt    *time.Time    `json:"reached_at,omitempty"` // First time the saved amount crossed the target
+}
+
+// GoalMilestones is the milestone history of a goal
+type GoalMilestones struct {
+	GoalID      string          `json:"goal_id"`
+	TotalAmount models.Money    `json:"total_amount"`
+	SavedAmount models.Money    `json:"saved_amount"`
+	Milestones  []GoalMilestone `json:"milestones"`
+}
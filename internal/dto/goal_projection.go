@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// GoalProjectionMonth is one month of a goal projection
+type GoalProjectionMonth struct {
+	Month        string       `json:"month"` // YYYY-MM
+	Contribution models.Money `json:"contribution"`
+	Interest     models.Money `json:"interest"`
+	Balance      models.Money `json:"balance"` // Saved amount at the end of the month
+}
+
+// GoalProjection estimates when a goal completes saving the same amount every month, with the
+// interest of its APY compounded monthly. Months and dates are nil when the goal isn't reached
+// within the projection horizon
+type GoalProjection struct {
+	GoalID                        string                `json:"goal_id"`
+	TotalAmount                   models.Money          `json:"total_amount"`
+	SavedAmount                   models.Money          `json:"saved_amount"`
+	APY                           float64               `json:"apy"`
+	MonthlyContribution           models.Money          `json:"monthly_contribution"`
+	ContributionBasis             string                `json:"contribution_basis"` // request, or history for the average of the last 3 months
+	MonthsToComplete              *int                  `json:"months_to_complete"`
+	CompletionDate                *string               `json:"completion_date"`
+	MonthsWithoutInterest         *int                  `json:"months_without_interest"`
+	CompletionDateWithoutInterest *string               `json:"completion_date_without_interest"`
+	InterestEarned                models.Money          `json:"interest_earned"` // Until completion, or over the whole horizon
+	Schedule                      []GoalProjectionMonth `json:"schedule"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// GoalWaterfallAllocation is the share of a waterfall amount that goes to one goal
+type GoalWaterfallAllocation struct {
+	GoalID     string       `json:"goal_id"`
+	Name       string       `json:"name"`
+	Priority   int          `json:"priority"`
+	Remaining  models.Money `json:"remaining"` // Left to save before the allocation
+	Amount     models.Money `json:"amount"`
+	SavedAfter models.Money `json:"saved_after"`
+	Completes  bool         `json:"completes"` // The allocation finishes the goal
+}
+
+// GoalWaterfall splits an amount across the active goals in priority order. Whatever is left
+// once every goal is complete stays unallocated
+type GoalWaterfall struct {
+	Source      string                    `json:"source"`
+	Amount      models.Money              `json:"amount"`
+	Allocated   models.Money              `json:"allocated"`
+	Unallocated models.Money              `json:"unallocated"`
+	Allocations []GoalWaterfallAllocation `json:"allocations"`
+	Applied     bool                      `json:"applied"`
+}
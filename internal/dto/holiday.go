@@ -0,0 +1,42 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// HolidayCalendar is a country whose bank holidays are known
+type HolidayCalendar struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2
+	Name    string `json:"name"`
+}
+
+// HolidaySettings is the holiday calendar scheduled items of the user follow
+type HolidaySettings struct {
+	Country   string            `json:"country"` // Empty when only weekends are skipped
+	Calendars []HolidayCalendar `json:"calendars"`
+}
+
+// HolidayAffectedItem is a scheduled item due on a holiday
+type HolidayAffectedItem struct {
+	Type         string       `json:"type"` // fixed_expense
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Amount       models.Money `json:"amount"`
+	DueDate      string       `json:"due_date"`
+	ProcessedOn  string       `json:"processed_on"` // The next business day when SkipHolidays is set, the due date otherwise
+	SkipHolidays bool         `json:"skip_holidays"`
+}
+
+// UpcomingHoliday is a holiday and the scheduled items it affects
+type UpcomingHoliday struct {
+	Date          string                `json:"date"`
+	Name          string                `json:"name"`
+	AffectedItems []HolidayAffectedItem `json:"affected_items"`
+}
+
+// UpcomingHolidays lists the holidays of the user's calendar in a window
+type UpcomingHolidays struct {
+	Country       string            `json:"country"`
+	From          string            `json:"from"`
+	To            string            `json:"to"`
+	Holidays      []UpcomingHoliday `json:"holidays"`
+	AffectedCount int               `json:"affected_count"` // Scheduled items due on one of the holidays
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// ImportBatch is an import with what happened to each of its transactions
+type ImportBatch struct {
+	ID             string                       `json:"id"`
+	BankAccountID  string                       `json:"bank_account_id"`
+	Source         *string                      `json:"source,omitempty"`
+	Created        int                          `json:"created"` // Transactions that became new expenses
+	Failed         int                          `json:"failed"`
+	Duplicates     int                          `json:"duplicates"`      // Already imported or kept as the manual expense
+	PendingMatches int                          `json:"pending_matches"` // Likely duplicates waiting to be resolved
+	Transactions   []models.ImportedTransaction `json:"transactions"`
+	Matches        []models.ImportMatch         `json:"matches"`
+	CreatedAt      time.Time                    `json:"created_at"`
+}
+
+// FieldProvenance tells where the current value of an expense field came from
+type FieldProvenance struct {
+	Field                 string     `json:"field"`
+	Source                string     `json:"source"` // manual or import
+	ImportedTransactionID *string    `json:"imported_transaction_id,omitempty"`
+	UpdatedAt             *time.Time `json:"updated_at,omitempty"`
+}
+
+// ImportMatchResolution is the expense left after resolving a match
+type ImportMatchResolution struct {
+	MatchID    string            `json:"match_id"`
+	Strategy   string            `json:"strategy"`
+	Expense    *models.Expense   `json:"expense"`
+	Provenance []FieldProvenance `json:"provenance"`
+}
@@ -0,0 +1,46 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// SpendingInsight is one observation about the user's spending, worded for display
+type SpendingInsight struct {
+	Kind    string  `json:"kind" example:"volatility"`
+	Level   string  `json:"level,omitempty" example:"high"` // low, moderate or high, volatility only
+	Value   float64 `json:"value" example:"1.42"`           // What the message is built from, in the unit of the kind
+	Message string  `json:"message" example:"Your expense sizes vary a lot: a few large purchases make up much of your spending."`
+}
+
+// SpendingInsights are the insights drawn from the user's expenses over a period
+type SpendingInsights struct {
+	PeriodStart  string            `json:"period_start"`
+	PeriodEnd    string            `json:"period_end"`
+	Months       int               `json:"months"`
+	Currency     string            `json:"currency"`
+	ExpenseCount int               `json:"expense_count"`
+	Insights     []SpendingInsight `json:"insights"`
+}
+
+// MonthlySpend is what was spent in one month
+type MonthlySpend struct {
+	Month  string       `json:"month" example:"2024-01"`
+	Amount models.Money `json:"amount"`
+}
+
+// ExpenseTypeForecast projects next month's spend of one expense type from the months before
+type ExpenseTypeForecast struct {
+	ExpenseType string         `json:"expense_type" example:"needs"`
+	Name        string         `json:"name" example:"Needs"`
+	Projected   models.Money   `json:"projected"`
+	Average     models.Money   `json:"average"`
+	Trend       string         `json:"trend"` // up, down or flat: the projection against the plain average
+	History     []MonthlySpend `json:"history"`
+}
+
+// SpendingForecast is the projected spend of the coming month
+type SpendingForecast struct {
+	Month         string                `json:"month" example:"2024-02"`
+	Currency      string                `json:"currency"`
+	BasedOnMonths int                   `json:"based_on_months"`
+	Projected     models.Money          `json:"projected"`
+	Types         []ExpenseTypeForecast `json:"types"`
+}
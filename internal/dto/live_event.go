@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// LiveEvent is a change pushed to the user's open streams, as the data of a server-sent event
+type LiveEvent struct {
+	ID            string          `json:"id,omitempty"` // Outbox event ID, empty for derived events
+	Type          string          `json:"type"`         // e.g. expense.created, balance.changed
+	AggregateType string          `json:"aggregate_type,omitempty"`
+	AggregateID   string          `json:"aggregate_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// LiveAccountBalance is the balance of one account in a balance.changed event
+type LiveAccountBalance struct {
+	BankAccountID string       `json:"bank_account_id"`
+	AccountName   string       `json:"account_name"`
+	Balance       models.Money `json:"balance"`
+}
+
+// LiveBalances is the data of a balance.changed event: the balances of every active account
+type LiveBalances struct {
+	Accounts []LiveAccountBalance `json:"accounts"`
+}
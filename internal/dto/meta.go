@@ -0,0 +1,21 @@
+package dto
+
+// EnumValue is a value the server accepts, with its label in the requested locale
+type EnumValue struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Enums lists the enumerations clients need instead of hardcoding them
+type Enums struct {
+	Locale          string      `json:"locale"`
+	Statuses        []EnumValue `json:"statuses"`
+	ExpenseTypes    []EnumValue `json:"expense_types"`
+	RecurrenceTypes []EnumValue `json:"recurrence_types"`
+	ReminderTypes   []EnumValue `json:"reminder_types"`
+	CapModes        []EnumValue `json:"cap_modes"`
+	IncomeSources   []EnumValue `json:"income_sources"`
+
+	// Entity -> status -> statuses a record in it can be changed to
+	StatusTransitions map[string]map[string][]string `json:"status_transitions"`
+}
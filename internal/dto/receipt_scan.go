@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// ReceiptScan is what was read from a receipt image and the category and account suggested
+// for it; fields are nil when they couldn't be found
+type ReceiptScan struct {
+	Merchant       *string       `json:"merchant,omitempty"`
+	Date           *string       `json:"date,omitempty"` // YYYY-MM-DD
+	Total          *models.Money `json:"total,omitempty"`
+	CategoryID     *string       `json:"category_id,omitempty"`
+	CategorySource string        `json:"category_source,omitempty"` // merchant (its usual category) or history (the user's past expenses there)
+	BankAccountID  *string       `json:"bank_account_id,omitempty"` // Account of the user's latest expense
+	Provider       string        `json:"provider"`
+	Text           string        `json:"text"` // Recognized text, for showing what was read
+}
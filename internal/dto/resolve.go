@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// ResolvedEntity is the minimal description of whatever a UUID points to, enough to render a
+// deep link before loading the entity itself from URL
+type ResolvedEntity struct {
+	Type   string        `json:"type"` // expense, income, bank_account, category, budget...
+	ID     string        `json:"id"`
+	Label  string        `json:"label"`
+	Status *string       `json:"status,omitempty"`
+	Amount *models.Money `json:"amount,omitempty"`
+	Date   *string       `json:"date,omitempty"`
+	URL    string        `json:"url"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// Sandbox is a test tenant owned by a real user
+type Sandbox struct {
+	SandboxUserID string    `json:"sandbox_user_id"`
+	Name          string    `json:"name"`
+	APIKey        string    `json:"api_key,omitempty"` // Only returned at creation
+	OffsetSeconds int64     `json:"offset_seconds"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SandboxClock is the virtual clock of a sandbox tenant
+type SandboxClock struct {
+	SandboxUserID string    `json:"sandbox_user_id"`
+	RealNow       time.Time `json:"real_now"`
+	VirtualNow    time.Time `json:"virtual_now"`
+	OffsetSeconds int64     `json:"offset_seconds"`
+}
+
+// SandboxAdvance reports what ran after the sandbox clock moved forward
+type SandboxAdvance struct {
+	SandboxClock
+	FixedExpensesPosted int `json:"fixed_expenses_posted"`
+	OverdueReminders    int `json:"overdue_reminders"`
+	UpcomingReminders   int `json:"upcoming_reminders"` // Due within 7 virtual days
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// Session is a login of the user on one device, followed through every refresh token rotation
+type Session struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ip_address"` // Of the last refresh
+	UserAgent  string    `json:"user_agent"`
+	DeviceID   string    `json:"device_id"`    // Hash of the user agent, as in security events
+	CreatedAt  time.Time `json:"created_at"`   // Login
+	LastUsedAt time.Time `json:"last_used_at"` // Last refresh
+	ExpiresAt  time.Time `json:"expires_at"`   // Unless refreshed again before
+	Current    bool      `json:"current"`      // The session of the access token making the request
+}
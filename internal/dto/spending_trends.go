@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// SpendingTrendLine is the monthly spend of one group, one value per month even when zero
+type SpendingTrendLine struct {
+	Key           string         `json:"key"` // Category or account ID, expense type; "total" for the total line
+	Name          string         `json:"name"`
+	Values        []models.Money `json:"values"`         // One per month, in the order of SpendingTrends.Months
+	MovingAverage []models.Money `json:"moving_average"` // Trailing average over up to MovingAverageWindow months
+	Change        []models.Money `json:"change"`         // Versus the month before, 0 for the first month
+	ChangePercent []*float64     `json:"change_percent"` // Versus the month before, null when that month is zero
+	Total         models.Money   `json:"total"`
+	Average       models.Money   `json:"average"` // Per month
+}
+
+// SpendingTrends is the spend of the last months aligned for charting, oldest first
+type SpendingTrends struct {
+	Months              []string            `json:"months"` // YYYY-MM, the current month last
+	GroupBy             string              `json:"group_by,omitempty"`
+	MovingAverageWindow int                 `json:"moving_average_window"`
+	Currency            string              `json:"currency"`
+	Total               SpendingTrendLine   `json:"total"`
+	Series              []SpendingTrendLine `json:"series"` // One per group, biggest first; empty when ungrouped
+}
+
+// ExpenseAnalyticsRecord is one expense prepared for analysis
+type ExpenseAnalyticsRecord struct {
+	Amount          models.Money `json:"amount"`
+	Date            time.Time    `json:"date"`
+	DayOfWeek       int          `json:"day_of_week"` // 0 = Sunday
+	Month           int          `json:"month"`
+	CategoryName    string       `json:"category_name"`
+	ExpenseTypeName string       `json:"expense_type_name"`
+	Description     *string      `json:"description"`
+}
+
+// ExpenseAnalyticsFeatures aggregates the expenses of the period
+type ExpenseAnalyticsFeatures struct {
+	AvgDailySpending   float64      `json:"avg_daily_spending"`  // Over the days with expenses
+	SpendingVolatility float64      `json:"spending_volatility"` // Variance of the expense amounts
+	MostActiveDay      int          `json:"most_active_day"`     // Weekday with most expenses, 0 = Sunday
+	CategoryDiversity  int          `json:"category_diversity"`  // Distinct categories used
+	LargestExpense     models.Money `json:"largest_expense"`
+	TypicalExpenseSize float64      `json:"typical_expense_size"` // Median amount
+}
+
+// ExpenseAnalytics is the expense data of a period prepared for ML analysis
+type ExpenseAnalytics struct {
+	RawData      []ExpenseAnalyticsRecord `json:"raw_data"`
+	TotalRecords int                      `json:"total_records"`
+	PeriodStart  time.Time                `json:"period_start"`
+	PeriodEnd    time.Time                `json:"period_end"`
+	Features     ExpenseAnalyticsFeatures `json:"features"`
+}
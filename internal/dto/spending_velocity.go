@@ -0,0 +1,30 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// VelocityWindow compares the spend rate of the last days with the same number of days before
+type VelocityWindow struct {
+	Days              int          `json:"days"`
+	Spent             models.Money `json:"spent"`
+	DailyRate         models.Money `json:"daily_rate"`
+	PreviousSpent     models.Money `json:"previous_spent"`
+	PreviousDailyRate models.Money `json:"previous_daily_rate"`
+	ChangePercent     *float64     `json:"change_percent,omitempty"` // Nil when nothing was spent in the previous window
+	Trend             string       `json:"trend"`                    // up, down or flat
+}
+
+// VelocityGroup is the spending velocity of one bucket or category
+type VelocityGroup struct {
+	ID      string           `json:"id"` // Expense type for buckets, category ID for categories
+	Name    string           `json:"name"`
+	Windows []VelocityWindow `json:"windows"`
+}
+
+// SpendingVelocity holds the rolling spend rates of the user up to a day
+type SpendingVelocity struct {
+	AsOf       string           `json:"as_of"`
+	Currency   string           `json:"currency"`
+	Total      []VelocityWindow `json:"total"`
+	Buckets    []VelocityGroup  `json:"buckets"`
+	Categories []VelocityGroup  `json:"categories"`
+}
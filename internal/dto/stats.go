@@ -0,0 +1,39 @@
+package dto
+
+// UserCategoryStats summarizes the categories of a user
+type UserCategoryStats struct {
+	TotalCategories   int64            `json:"total_categories"`
+	CategoriesByType  map[string]int64 `json:"categories_by_type"` // Expense type name -> active categories
+	DeletedCategories int64            `json:"deleted_categories"`
+}
+
+// ReminderStats summarizes the reminders of a user
+type ReminderStats struct {
+	TotalReminders     int64            `json:"total_reminders"`
+	CompletedReminders int64            `json:"completed_reminders"`
+	PendingReminders   int64            `json:"pending_reminders"`
+	OverdueReminders   int64            `json:"overdue_reminders"`
+	UpcomingReminders  int64            `json:"upcoming_reminders"` // Due in the next 7 days
+	ByType             map[string]int64 `json:"by_type"`
+}
+
+// SystemOverview describes how the expense system is set up
+type SystemOverview struct {
+	ExpenseTypesCount int               `json:"expense_types_count"`
+	ExpenseTypes      []ExpenseTypeInfo `json:"expense_types"`
+	SystemInfo        SystemInfo        `json:"system_info"`
+}
+
+// ExpenseTypeInfo is an expense type value with its display name
+type ExpenseTypeInfo struct {
+	Value string `json:"value"`
+	Name  string `json:"name"`
+}
+
+// SystemInfo is static information about the expense system architecture
+type SystemInfo struct {
+	Architecture string   `json:"architecture"`
+	ExpenseTypes []string `json:"expense_types"`
+	Note         string   `json:"note"`
+	ValidTypes   []string `json:"valid_types"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// SubProfile is a restricted profile under a parent account, as the parent sees it
+type SubProfile struct {
+	UserID            string        `json:"user_id"`
+	Name              string        `json:"name"`
+	Email             string        `json:"email"`
+	Active            bool          `json:"active"`
+	WalletAccountID   string        `json:"wallet_account_id"`
+	WalletBalance     models.Money  `json:"wallet_balance"`
+	ApprovalThreshold *models.Money `json:"approval_threshold"` // Null when expenses never need approval
+	PendingApprovals  int64         `json:"pending_approvals"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// SyncTombstone tells a syncing client that an entity it may hold was deleted
+type SyncTombstone struct {
+	Entity    string    `json:"entity"` // expense, income, category, bank_account, budget, goal, transfer, fixed_expense, reminder
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncChangeResult is the outcome of one change pushed by a client
+type SyncChangeResult struct {
+	Entity          string     `json:"entity"`
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`                      // applied, conflict, rejected or pending_approval
+	Error           string     `json:"error,omitempty"`             // Why the change was rejected
+	ServerUpdatedAt *time.Time `json:"server_updated_at,omitempty"` // updated_at of the server copy after the change, or the one it conflicts with
+	ConfirmToken    string     `json:"confirm_token,omitempty"`     // For an amount above the confirmation threshold; push again with it
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// Tag is a tag of the user with how many visible expenses and incomes carry it
+type Tag struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Color        *string   `json:"color,omitempty"`
+	ExpenseCount int64     `json:"expense_count"`
+	IncomeCount  int64     `json:"income_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// TimezoneSettings is the timezone the user's days and months follow
+type TimezoneSettings struct {
+	Timezone  string `json:"timezone" example:"America/Mexico_City"` // IANA name
+	UTCOffset string `json:"utc_offset" example:"-06:00"`            // Current offset, which changes with daylight saving time
+	LocalTime string `json:"local_time" example:"2024-01-15T22:30:00-06:00"`
+	Today     string `json:"today" example:"2024-01-15"` // The date new entries default to
+}
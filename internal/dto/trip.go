@@ -0,0 +1,38 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// TripDaySpend is the spend of one day of a trip
+type TripDaySpend struct {
+	Date         string       `json:"date"`
+	Amount       models.Money `json:"amount"`
+	ExpenseCount int64        `json:"expense_count"`
+	InWindow     bool         `json:"in_window"` // False for expenses assigned to the trip from outside its dates
+}
+
+// TripCategorySpend is the spend of a trip in one category
+type TripCategorySpend struct {
+	CategoryID   string       `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Amount       models.Money `json:"amount"`
+}
+
+// TripSummary compares what a trip cost against its budget
+type TripSummary struct {
+	TripID            string              `json:"trip_id"`
+	Name              string              `json:"name"`
+	StartDate         string              `json:"start_date"`
+	EndDate           string              `json:"end_date"`
+	Days              int                 `json:"days"`
+	Currency          string              `json:"currency"`
+	Budget            *models.Money       `json:"budget,omitempty"`
+	Spent             models.Money        `json:"spent"`
+	Remaining         *models.Money       `json:"remaining,omitempty"`
+	UsagePercent      *float64            `json:"usage_percent,omitempty"`
+	DailyBudget       *models.Money       `json:"daily_budget,omitempty"`
+	DailyAverage      models.Money        `json:"daily_average"`
+	ExpenseCount      int64               `json:"expense_count"`
+	ExcludeFromBudget bool                `json:"exclude_from_budget"`
+	ByDay             []TripDaySpend      `json:"by_day"`
+	ByCategory        []TripCategorySpend `json:"by_category"`
+}
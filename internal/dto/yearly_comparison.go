@@ -0,0 +1,37 @@
+package dto
+
+import "github.com/Osminalx/fluxio/internal/models"
+
+// YearlyTypeAmount is the net spent in one expense type (50/30/20) during a year
+type YearlyTypeAmount struct {
+	ExpenseType string       `json:"expense_type"`
+	Name        string       `json:"name"`
+	Amount      models.Money `json:"amount"`
+}
+
+// YearSummary holds the totals of one calendar year
+type YearSummary struct {
+	Year           int                `json:"year"`
+	Income         models.Money       `json:"income"`   // Refunds excluded when they are netted from expenses
+	Spending       models.Money       `json:"spending"` // Net of refunds
+	ByExpenseType  []YearlyTypeAmount `json:"by_expense_type"`
+	Savings        models.Money       `json:"savings"`                // Income minus spending
+	SavingsRate    *float64           `json:"savings_rate,omitempty"` // Percent of income; nil without income
+	NetWorthChange models.Money       `json:"net_worth_change"`       // Money in minus money out of the accounts
+}
+
+// YearlyCategoryComparison is the net spent in a category in each year of the report
+type YearlyCategoryComparison struct {
+	CategoryID  string         `json:"category_id"`
+	Name        string         `json:"name"`
+	ExpenseType string         `json:"expense_type"`
+	Amounts     []models.Money `json:"amounts"` // Same order as the years of the report
+}
+
+// YearlyComparison puts the totals of several years side by side
+type YearlyComparison struct {
+	Years      []int                      `json:"years"`
+	Currency   string                     `json:"currency"`
+	Summaries  []YearSummary              `json:"summaries"`
+	Categories []YearlyCategoryComparison `json:"categories"`
+}
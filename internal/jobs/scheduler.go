@@ -0,0 +1,248 @@
+// Package jobs runs the periodic background work of the server, such as purging expired
+// records. Jobs are registered by name before Start and skip their runs while the API is in
+// maintenance mode; admins can list them and trigger a run by name
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/telemetry"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+var (
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "job_run_duration_seconds",
+		Help: "Duration of background job runs.",
+	}, []string{"job"})
+	jobFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_failures_total",
+		Help: "Background job runs that returned an error.",
+	}, []string{"job"})
+)
+
+var (
+	ErrUnknownJob = errors.New("job not found")
+	ErrJobRunning = errors.New("job is already running")
+	ErrStopped    = errors.New("job scheduler is stopped")
+)
+
+// Status describes a registered job and its last run
+type Status struct {
+	Name            string     `json:"name"`
+	IntervalSeconds int64      `json:"interval_seconds"`
+	Running         bool       `json:"running"`
+	LastStartedAt   *time.Time `json:"last_started_at,omitempty"`
+	LastDurationMs  *int64     `json:"last_duration_ms,omitempty"`
+	LastError       *string    `json:"last_error,omitempty"`
+}
+
+// job is a registered piece of periodic work
+type job struct {
+	name      string
+	interval  time.Duration
+	atStartup bool
+	run       func() error
+	running   sync.Mutex // Keeps runs of the same job from overlapping
+
+	statusMu sync.Mutex
+	status   Status
+}
+
+var (
+	jobsMu  sync.Mutex
+	jobs    = map[string]*job{}
+	started bool
+	stopped bool
+	stop    = make(chan struct{}) // Closed by Stop to end the job loops
+	runs    sync.WaitGroup        // Runs in progress, waited for by Stop
+
+	inMaintenance func() bool // Set by Start to the maintenance mode of the services
+)
+
+// Register adds a job run every interval once the scheduler starts. Names must be unique
+func Register(name string, interval time.Duration, run func() error) {
+	register(&job{name: name, interval: interval, run: run})
+}
+
+// RegisterAtStartup adds a job that also runs once as soon as the scheduler starts
+func RegisterAtStartup(name string, interval time.Duration, run func() error) {
+	register(&job{name: name, interval: interval, atStartup: true, run: run})
+}
+
+func register(j *job) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if _, exists := jobs[j.name]; exists {
+		panic("jobs: duplicate job " + j.name)
+	}
+	j.status = Status{Name: j.name, IntervalSeconds: int64(j.interval.Seconds())}
+	jobs[j.name] = j
+	if started {
+		go j.loop()
+	}
+}
+
+// Start runs every registered job on its interval, skipping runs while the services are in
+// maintenance mode; jobs registered later start right away
+func Start(svc *services.Services) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if started {
+		return
+	}
+	started = true
+	inMaintenance = svc.IsMaintenanceMode
+	for _, j := range jobs {
+		go j.loop()
+	}
+	logger.Info("Job scheduler started with %d jobs", len(jobs))
+}
+
+// List returns the registered jobs by name with their last run
+func List() []Status {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	statuses := make([]Status, 0, len(jobs))
+	for _, j := range jobs {
+		j.statusMu.Lock()
+		statuses = append(statuses, j.status)
+		j.statusMu.Unlock()
+	}
+	sort.Slice(statuses, func(a, b int) bool { return statuses[a].Name < statuses[b].Name })
+	return statuses
+}
+
+// Run starts a run of the job in the background, even in maintenance mode, unless one is
+// already going
+func Run(name string) error {
+	jobsMu.Lock()
+	j, ok := jobs[name]
+	jobsMu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !j.running.TryLock() {
+		return ErrJobRunning
+	}
+	if !beginRun() {
+		j.running.Unlock()
+		return ErrStopped
+	}
+	go func() {
+		defer runs.Done()
+		defer j.running.Unlock()
+		j.execute()
+	}()
+	return nil
+}
+
+// Stop ends the job loops and waits for the runs in progress, until ctx is done
+func Stop(ctx context.Context) error {
+	jobsMu.Lock()
+	if !stopped {
+		stopped = true
+		close(stop)
+	}
+	jobsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// beginRun counts a run unless the scheduler is stopped; the caller must call runs.Done
+func beginRun() bool {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if stopped {
+		return false
+	}
+	runs.Add(1)
+	return true
+}
+
+func (j *job) loop() {
+	if j.atStartup && !inMaintenance() {
+		j.tryExecute()
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		// Jobs write to the database, so they wait until maintenance is over
+		if inMaintenance() {
+			continue
+		}
+		j.tryExecute()
+	}
+}
+
+// tryExecute runs the job unless a previous run is still going
+func (j *job) tryExecute() {
+	if !j.running.TryLock() {
+		logger.Warn("Job %s is still running, skipping this run", j.name)
+		return
+	}
+	defer j.running.Unlock()
+	if !beginRun() {
+		return
+	}
+	defer runs.Done()
+	j.execute()
+}
+
+// execute runs the job and records the outcome; the caller holds the running lock
+func (j *job) execute() {
+	startedAt := time.Now()
+	j.statusMu.Lock()
+	j.status.Running = true
+	j.status.LastStartedAt = &startedAt
+	j.statusMu.Unlock()
+
+	_, span := telemetry.Tracer.Start(context.Background(), "job "+j.name)
+	err := j.run()
+	telemetry.RecordError(span, err)
+	span.End()
+
+	duration := time.Since(startedAt)
+	jobDuration.WithLabelValues(j.name).Observe(duration.Seconds())
+	durationMs := duration.Milliseconds()
+	j.statusMu.Lock()
+	j.status.Running = false
+	j.status.LastDurationMs = &durationMs
+	j.status.LastError = nil
+	if err != nil {
+		message := err.Error()
+		j.status.LastError = &message
+	}
+	j.statusMu.Unlock()
+
+	if err != nil {
+		jobFailures.WithLabelValues(j.name).Inc()
+		logger.Error("Job %s failed after %s: %v", j.name, duration, err)
+		return
+	}
+	logger.Debug("Job %s finished in %s", j.name, duration)
+}
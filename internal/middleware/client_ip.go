@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// trustedProxies parses TRUSTED_PROXIES, a comma separated list of the IPs or CIDRs of the
+// proxies in front of the server. Entries that don't parse are ignored
+func trustedProxies() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func isTrustedProxy(ip net.IP, proxies []*net.IPNet) bool {
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the IP of the peer that opened the connection
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// FromTrustedProxy reports whether the request was handed over by one of TRUSTED_PROXIES, so
+// the headers that proxy sets (X-Forwarded-For, CF-IPCountry...) can be believed
+func FromTrustedProxy(r *http.Request) bool {
+	ip := net.ParseIP(remoteHost(r))
+	return ip != nil && isTrustedProxy(ip, trustedProxies())
+}
+
+// ClientIP returns the address the request came from. X-Forwarded-For is only read when the
+// connection comes from a trusted proxy, and then from the right: each proxy appends the peer
+// it saw, so the rightmost hop that isn't a trusted proxy is the client. Anything left of it
+// was sent by the client and could be made up
+func ClientIP(r *http.Request) string {
+	remote := remoteHost(r)
+	proxies := trustedProxies()
+	if ip := net.ParseIP(remote); ip == nil || !isTrustedProxy(ip, proxies) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			// Not written by a proxy we trust; keep the last hop that was
+			break
+		}
+		client = ip.String()
+		if !isTrustedProxy(ip, proxies) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/middleware"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5")
+
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct client ignores the header", "203.0.113.7:4000", []string{"1.2.3.4"}, "203.0.113.7"},
+		{"trusted proxy without header", "10.0.0.2:4000", nil, "10.0.0.2"},
+		{"trusted proxy", "10.0.0.2:4000", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"spoofed leftmost hop", "10.0.0.2:4000", []string{"1.2.3.4, 198.51.100.9"}, "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.2:4000", []string{"1.2.3.4, 198.51.100.9, 192.168.1.5, 10.1.1.1"}, "198.51.100.9"},
+		{"several headers", "10.0.0.2:4000", []string{"1.2.3.4", "198.51.100.9"}, "198.51.100.9"},
+		{"garbage hop", "10.0.0.2:4000", []string{"198.51.100.9, not-an-ip"}, "10.0.0.2"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = c.remote
+			for _, value := range c.forwarded {
+				request.Header.Add("X-Forwarded-For", value)
+			}
+			if got := middleware.ClientIP(request); got != c.want {
+				t.Errorf("ClientIP = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// heavyRoute matches endpoints running large aggregate queries: paths starting with prefix
+// and, if set, ending in suffix, or exactly path when set
+type heavyRoute struct {
+	prefix string
+	suffix string
+	path   string
+}
+
+// heavyRoutes are the reports, forecasts and imports a single user could use to swamp the
+// database, e.g. a dashboard refreshing every widget at once
+var heavyRoutes = []heavyRoute{
+	{prefix: "/api/v1/reports/"},
+	{path: "/api/v1/insights"},
+	{prefix: "/api/v1/insights/forecast"},
+	{prefix: "/api/v1/insights/benchmarks"},
+	{prefix: "/api/v1/analytics/velocity"},
+	{prefix: "/api/v1/analytics/series"},
+	{prefix: "/api/v1/assistant/context"},
+	{prefix: "/api/v1/expenses/summary"},
+	{prefix: "/api/v1/expenses/scan"},
+	{prefix: "/api/v1/budgets/plan"},
+	{prefix: "/api/v1/budgets/compliance"},
+	{prefix: "/api/v1/fixed-expenses/drift"},
+	{prefix: "/api/v1/goals/waterfall"},
+	{prefix: "/api/v1/data-quality"},
+	{prefix: "/api/v1/import"},
+	{prefix: "/api/v1/export"},
+	{prefix: "/api/v1/bank-accounts/", suffix: "/available-balance"},
+	{prefix: "/api/v1/trips/", suffix: "/summary"},
+}
+
+func isHeavyRequest(path string) bool {
+	for _, route := range heavyRoutes {
+		if route.path != "" {
+			if path == route.path {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(path, route.prefix) && strings.HasSuffix(path, route.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConcurrencyLimitMiddleware limits how many expensive requests each user runs at once. Extra
+// requests wait briefly for a slot and are rejected with 429 if none frees up.
+// It must run after AuthMiddleware.
+func ConcurrencyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(string)
+		if !ok || !isHeavyRequest(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		release, acquired := services.AcquireHeavyRequestSlot(r.Context(), userID)
+		if !acquired {
+			if r.Context().Err() != nil {
+				return
+			}
+			limit, _ := services.HeavyRequestLimits()
+			logger.Warn("Too many concurrent heavy requests for user %s: %s %s", userID, r.Method, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":          "Too many reports running at once, retry when one finishes",
+				"max_concurrent": limit,
+			})
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/middleware"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/google/uuid"
+)
+
+func TestConcurrencyLimitHeavyRoutes(t *testing.T) {
+	t.Setenv("HEAVY_REQUEST_CONCURRENCY", "1")
+	t.Setenv("HEAVY_REQUEST_QUEUE_SECONDS", "1")
+
+	handler := middleware.ConcurrencyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Each request is sent while another heavy request of the user holds the only slot
+	cases := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"reports", "/api/v1/reports/cash-flow", http.StatusTooManyRequests},
+		{"spending insights", "/api/v1/insights", http.StatusTooManyRequests},
+		{"spending forecast", "/api/v1/insights/forecast", http.StatusTooManyRequests},
+		{"benchmarks", "/api/v1/insights/benchmarks", http.StatusTooManyRequests},
+		{"suffix route", "/api/v1/trips/1/summary", http.StatusTooManyRequests},
+		{"budget review isn't heavy", "/api/v1/insights/budget-review", http.StatusNoContent},
+		{"plain list", "/api/v1/expenses", http.StatusNoContent},
+		{"prefix without its suffix", "/api/v1/trips/1", http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Heavy requests wait out the queue before the 429
+			t.Parallel()
+			userID := uuid.NewString()
+			release, ok := services.AcquireHeavyRequestSlot(context.Background(), userID)
+			if !ok {
+				t.Fatal("no slot for the first request")
+			}
+			defer release()
+
+			request := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			request = request.WithContext(context.WithValue(request.Context(), "userID", userID))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tc.wantStatus)
+			}
+		})
+	}
+}
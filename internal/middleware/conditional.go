@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxETagBodyBytes is the largest response buffered to compute its ETag; bigger ones are sent
+// as they're written, without an ETag
+const maxETagBodyBytes = 4 << 20
+
+// etagResponseWriter holds the response back until the handler returns so its ETag can be set
+// before the body goes out. It falls back to writing through when the handler flushes (event
+// streams) or the body grows past maxETagBodyBytes
+type etagResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (w *etagResponseWriter) WriteHeader(status int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *etagResponseWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(data) > maxETagBodyBytes {
+		if err := w.commit(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *etagResponseWriter) FlushError() error {
+	if err := w.commit(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagResponseWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *etagResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// commit sends what was held back and switches to writing through
+func (w *etagResponseWriter) commit() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
+
+// finish writes the held back response with the given ETag, or a bodyless 304 when notModified
+func (w *etagResponseWriter) finish(etag string, notModified bool) {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if notModified {
+		for _, header := range []string{"Content-Type", "Content-Length"} {
+			w.Header().Del(header)
+		}
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// responseETag is the ETag of a buffered response: the one its handler set, else a hash of the body
+func responseETag(header http.Header, body []byte) string {
+	if etag := header.Get("ETag"); etag != "" {
+		return etag
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// VersionedETag is the ETag of a record with an optimistic-lock version: the version, so an
+// If-Match names the version an update is based on, then a hash of the body, so fields outside
+// the record (computed or embedded ones) still change it
+func VersionedETag(version int64, body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"v%d-%s"`, version, hex.EncodeToString(sum[:16]))
+}
+
+// ListETag is the ETag of a list response from the version of the listed rows and the query
+// that filtered and paged them. It's weak: the body is only known to be equivalent
+func ListETag(query string, version string) string {
+	sum := sha256.Sum256([]byte(query + "\n" + version))
+	return `W/"l-` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NoneMatch reports whether the request's If-None-Match lists etag
+func NoneMatch(r *http.Request, etag string) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	return ifNoneMatch != "" && etagListMatches(ifNoneMatch, etag, true)
+}
+
+// ETagVersion returns the version carried by an ETag made by VersionedETag
+func ETagVersion(etag string) (int64, bool) {
+	etag = strings.TrimSpace(etag)
+	if !strings.HasPrefix(etag, `"v`) || !strings.HasSuffix(etag, `"`) {
+		return 0, false
+	}
+	number, _, found := strings.Cut(etag[2:len(etag)-1], "-")
+	if !found {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match value lists etag. "*" matches
+// any current representation. weak compares ignoring the W/ prefix, as If-None-Match does
+func etagListMatches(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedResponse keeps a whole response in memory
+type recordedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *recordedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recordedResponse) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+// currentETag runs a GET of the request's URL for the same user and returns the ETag the
+// client would have received; empty when the resource can't be read
+func currentETag(next http.Handler, r *http.Request) string {
+	get := r.Clone(r.Context())
+	get.Method = http.MethodGet
+	get.Body = http.NoBody
+	get.ContentLength = 0
+	for _, header := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "Content-Type"} {
+		get.Header.Del(header)
+	}
+
+	recorded := &recordedResponse{header: http.Header{}}
+	next.ServeHTTP(recorded, get)
+	if recorded.status != http.StatusOK {
+		return ""
+	}
+	return responseETag(recorded.header, recorded.body.Bytes())
+}
+
+// ConditionalRequestMiddleware adds ETags to successful GET responses and answers 304 Not
+// Modified when If-None-Match lists the current one. PATCH, PUT and DELETE requests carrying
+// If-Match only go through while the resource's ETag (as a GET of the same URL returns it)
+// still matches, otherwise they get 412 Precondition Failed; successful PATCH and PUT responses
+// then carry the new ETag. It must run after AuthMiddleware.
+func ConditionalRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			buffered := &etagResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(buffered, r)
+			if buffered.passthrough || (buffered.status != 0 && buffered.status != http.StatusOK) {
+				buffered.finish("", false)
+				return
+			}
+
+			if w.Header().Get("Cache-Control") == "" {
+				w.Header().Set("Cache-Control", "private, no-cache")
+			}
+			etag := responseETag(w.Header(), buffered.body.Bytes())
+			buffered.finish(etag, NoneMatch(r, etag))
+
+		case http.MethodPatch, http.MethodPut, http.MethodDelete:
+			ifMatch := r.Header.Get("If-Match")
+			if ifMatch == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			etag := currentETag(next, r)
+			if !etagListMatches(ifMatch, etag, false) {
+				if etag != "" {
+					w.Header().Set("ETag", etag)
+				}
+				http.Error(w, "Precondition failed: the resource changed since it was read", http.StatusPreconditionFailed)
+				return
+			}
+			if r.Method == http.MethodDelete {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffered := &etagResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(buffered, r)
+			if buffered.passthrough || buffered.status < http.StatusOK || buffered.status >= http.StatusMultipleChoices {
+				buffered.finish("", false)
+				return
+			}
+			buffered.finish(currentETag(next, r), false)
+
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/middleware"
+)
+
+func TestETagVersion(t *testing.T) {
+	etag := middleware.VersionedETag(42, []byte(`{"id":"1"}`))
+	if version, ok := middleware.ETagVersion(etag); !ok || version != 42 {
+		t.Fatalf("ETagVersion(%s) = %d, %t, want 42", etag, version, ok)
+	}
+	for _, etag := range []string{`"abc"`, `W/"v3-abc"`, `"v0-abc"`, `"vx-abc"`, `"v3"`, `*`} {
+		if version, ok := middleware.ETagVersion(etag); ok {
+			t.Errorf("ETagVersion(%s) = %d, want no version", etag, version)
+		}
+	}
+}
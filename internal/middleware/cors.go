@@ -10,8 +10,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*") // You can restrict this to specific domains
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Client-ID, X-API-Key, If-Match, If-None-Match, If-Modified-Since")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type, X-Renewed-Access-Token, X-Access-Token-Expires-In, X-Request-Id, X-Trace-Id, ETag, Last-Modified")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 
@@ -48,8 +48,8 @@ func RestrictedCORSMiddleware(allowedOrigins []string) func(http.Handler) http.H
 			}
 			
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-			w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Client-ID, X-API-Key, If-Match, If-None-Match, If-Modified-Since")
+			w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type, X-Renewed-Access-Token, X-Access-Token-Expires-In, X-Request-Id, X-Trace-Id, ETag, Last-Modified")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "86400")
 
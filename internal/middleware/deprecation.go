@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// ClientIDHeader identifies the client app (e.g. ios/2.3.1) in deprecation telemetry
+const ClientIDHeader = "X-Client-ID"
+
+// maxCapturedResponseBytes limits how much of a response is kept to look for deprecated fields
+const maxCapturedResponseBytes = 1 << 20
+
+// capturingResponseWriter keeps a copy of the response body while writing it through
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *capturingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *capturingResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= maxCapturedResponseBytes {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// DeprecationTelemetryMiddleware counts, per client, the successful responses that still carry
+// fields tagged as deprecated. Only endpoints with deprecated fields have their responses captured.
+func DeprecationTelemetryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint, _ := normalizeEndpoint(r.URL.Path)
+		if len(services.DeprecatedFieldsFor(r.Method, endpoint)) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		capture := &capturingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(capture, r)
+
+		if capture.status < http.StatusBadRequest {
+			services.RecordDeprecatedFieldUse(r.Header.Get(ClientIDHeader), r.Method, endpoint, capture.body.Bytes())
+		}
+	})
+}
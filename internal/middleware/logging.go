@@ -23,6 +23,7 @@ func LoggingMiddleware(next http.Handler) http.Handler{
 		
 		// Log the request
 		logger.HTTPRequest(
+			r.Context(),
 			r.Method,
 			r.URL.Path,
 			r.RemoteAddr,
@@ -48,3 +49,8 @@ func (rw *responseWriter) WriteHeader(code int) {
 func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush event streams
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// maintenanceExemptPaths keep working in read-only mode: signing in, refreshing and signing
+// out keep sessions usable for reads, and admins need to be able to turn maintenance off
+// again. Everything else under /api/v1/auth/ (registration, API keys, sessions) is a write
+var maintenanceExemptPaths = []string{
+	"/api/v1/auth/login",
+	"/api/v1/auth/login/verify",
+	"/api/v1/auth/refresh",
+	"/api/v1/auth/logout",
+	"/api/v1/admin/maintenance",
+}
+
+// MaintenanceMiddleware rejects writes with 503 and a Retry-After header while maintenance
+// mode is on. Reads (GET, HEAD, OPTIONS) keep working.
+func MaintenanceMiddleware(svc *services.Services, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !svc.IsMaintenanceMode() || isReadOnlyMethod(r.Method) || isMaintenanceExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := svc.GetMaintenanceStatus()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfterSeconds))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":               "The API is in read-only maintenance mode",
+			"reason":              status.Reason,
+			"retry_after_seconds": status.RetryAfterSeconds,
+		})
+	})
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isMaintenanceExempt(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, exempt := range maintenanceExemptPaths {
+		if path == exempt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Osminalx/fluxio/internal/telemetry"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route and status code.",
+	}, []string{"method", "route", "status"})
+	httpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_errors_total",
+		Help: "HTTP requests that failed with a 5xx status.",
+	}, []string{"method", "route"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_request_duration_seconds",
+		Help: "Duration of HTTP requests.",
+	}, []string{"method", "route"})
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests being served.",
+	})
+)
+
+// TelemetryMiddleware traces every request as a server span, continuing the trace of a
+// traceparent header, and records per-route request metrics. The trace ID is returned in the
+// X-Trace-Id header so a failed request can be found in the traces
+func TelemetryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		route, _ := normalizeEndpoint(r.URL.Path)
+		ctx, span := telemetry.Tracer.Start(telemetry.Extract(r.Context(), r.Header), r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer))
+		w.Header().Set("X-Trace-Id", span.SpanContext().TraceID().String())
+
+		responseWriter := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(responseWriter, r.WithContext(ctx))
+
+		// Unknown paths are grouped so scanners can't create a series per path
+		if responseWriter.statusCode == http.StatusNotFound {
+			route = "unmatched"
+		}
+		status := responseWriter.statusCode
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+
+		span.SetAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if requestID := logger.RequestID(r.Context()); requestID != "" {
+			span.SetAttributes(attribute.String("http.request_id", requestID))
+		}
+		if status >= http.StatusInternalServerError {
+			httpErrors.WithLabelValues(r.Method, route).Inc()
+			telemetry.RecordError(span, errors.New(http.StatusText(status)))
+		}
+		span.End()
+	})
+}
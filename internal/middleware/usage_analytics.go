@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/google/uuid"
+)
+
+// UsageAnalyticsMiddleware records aggregate endpoint usage for authenticated requests.
+// It must run after AuthMiddleware so the user ID is available in the context.
+func UsageAnalyticsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		userID, ok := r.Context().Value("userID").(string)
+		if !ok {
+			return
+		}
+
+		endpoint, feature := normalizeEndpoint(r.URL.Path)
+		services.RecordUsage(userID, r.Method, endpoint, feature)
+
+		// Requests made with an API key are also counted per key so users can spot leaked keys
+		if apiKeyID, ok := r.Context().Value("apiKeyID").(string); ok {
+			services.RecordAPIKeyUsage(apiKeyID, r.Method, endpoint)
+		}
+	})
+}
+
+// normalizeEndpoint replaces IDs in the path with placeholders so usage is aggregated
+// per route, and returns the feature (first segment after /api/v1/)
+func normalizeEndpoint(path string) (string, string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if _, err := uuid.Parse(segment); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+
+	feature := ""
+	if len(segments) > 2 && segments[0] == "api" && segments[1] == "v1" {
+		feature = segments[2]
+	}
+
+	return "/" + strings.Join(segments, "/"), feature
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountGroup is a user-defined set of bank accounts, e.g. "Liquid" or "Long-term",
+// used to look at metrics for a subset of accounts
+type AccountGroup struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_account_group_user_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_account_group_user_name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relaciones
+	Members []AccountGroupMember `json:"members,omitempty" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+}
+
+// AccountGroupMember puts a bank account in a group; an account can be in several groups
+type AccountGroupMember struct {
+	GroupID       uuid.UUID `json:"group_id" gorm:"type:uuid;primary_key"`
+	BankAccountID uuid.UUID `json:"bank_account_id" gorm:"type:uuid;primary_key;index"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Relaciones
+	BankAccount BankAccount `json:"-" gorm:"foreignKey:BankAccountID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userCurrencyCode returns the currency of the user, which records created without one take
+func userCurrencyCode(tx *gorm.DB, userID uuid.UUID) (string, error) {
+	var code string
+	err := tx.Session(&gorm.Session{NewDB: true}).Model(&User{}).
+		Where("id = ?", userID).Select("currency").Scan(&code).Error
+	if err != nil {
+		return "", err
+	}
+	if code == "" {
+		code = DefaultCurrencyCode
+	}
+	return code, nil
+}
+
+// fillCurrency sets currency to the user's when it is empty
+func fillCurrency(tx *gorm.DB, userID uuid.UUID, currency *string) error {
+	if *currency != "" {
+		return nil
+	}
+	code, err := userCurrencyCode(tx, userID)
+	if err != nil {
+		return err
+	}
+	*currency = code
+	return nil
+}
+
+// BeforeCreate keeps the currency the balance is in
+func (b *BankAccount) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, b.UserID, &b.Currency)
+}
+
+// BeforeCreate keeps the currency the amount is in
+func (e *Expense) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, e.UserID, &e.Currency)
+}
+
+// BeforeCreate keeps the currency the amount is in
+func (i *Income) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, i.UserID, &i.Currency)
+}
+
+// BeforeCreate keeps the currency the amount is in
+func (t *Transfer) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, t.UserID, &t.Currency)
+}
+
+// BeforeCreate keeps the currency the amount is in
+func (f *FixedExpense) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, f.UserID, &f.Currency)
+}
+
+// BeforeCreate keeps the currency the amounts are in
+func (g *Goal) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, g.UserID, &g.Currency)
+}
+
+// BeforeCreate keeps the currency the amounts are in
+func (b *Budget) BeforeCreate(tx *gorm.DB) error {
+	return fillCurrency(tx, b.UserID, &b.Currency)
+}
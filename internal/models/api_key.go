@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes of an API key; every scope can read
+const (
+	APIKeyScopeReadOnly      = "read_only"
+	APIKeyScopeWriteExpenses = "write_expenses" // Can also create, edit and delete expenses
+	APIKeyScopeFull          = "full"           // Everything the owner can do, except managing the account and keys
+)
+
+// APIKey is a long-lived credential a user creates for scripts and integrations.
+// Only a SHA-256 hash of the key is stored; the prefix lets users recognize it.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scope      string     `json:"scope" gorm:"type:varchar(20);not null;default:'full'"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
+
+// IsActive reports whether the key can still authenticate requests
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil
+}
+
+// APIKeyUsageStat stores the request count of one API key per endpoint and day
+type APIKeyUsageStat struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	APIKeyID     uuid.UUID `json:"api_key_id" gorm:"type:uuid;not null;uniqueIndex:idx_api_key_usage_day"`
+	Day          time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_api_key_usage_day"`
+	Method       string    `json:"method" gorm:"type:varchar(10);not null;uniqueIndex:idx_api_key_usage_day"`
+	Endpoint     string    `json:"endpoint" gorm:"type:varchar(255);not null;uniqueIndex:idx_api_key_usage_day"`
+	RequestCount int64     `json:"request_count" gorm:"not null;default:0"`
+	LastUsedAt   time.Time `json:"last_used_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
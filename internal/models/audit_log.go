@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditLog records security or business relevant actions taken by a user. Entries form a
+// hash chain: each one stores the hash of the previous entry, so editing or removing an
+// entry breaks every hash after it
+type AuditLog struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Action     string     `json:"action" gorm:"type:varchar(100);not null;index"` // e.g. expense.cap_override
+	EntityType string     `json:"entity_type" gorm:"type:varchar(50);not null"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty" gorm:"type:uuid"`
+	Details    string     `json:"details" gorm:"type:jsonb;not null;default:'{}'"`
+	Sequence   *int64     `json:"sequence,omitempty" gorm:"uniqueIndex"`                 // Position in the chain; nil for entries written before chaining
+	PrevHash   string     `json:"prev_hash" gorm:"type:varchar(64);not null;default:''"` // Empty for the first chained entry
+	Hash       string     `json:"hash" gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
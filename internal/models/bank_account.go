@@ -10,7 +10,10 @@ type BankAccount struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
 	AccountName     string     `json:"account_name" gorm:"not null"`
-	Balance         float64    `json:"balance" gorm:"type:decimal(15,2);not null;default:0.00"`
+	Balance         Money      `json:"balance" gorm:"type:decimal(18,3);not null;default:0.00"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"`     // Currency of the user when it was created, kept if the user changes it
+	ManualBalance   bool       `json:"manual_balance" gorm:"not null;default:false"` // The user keeps the balance up to date; expenses, incomes and transfers don't move it
+	Version         int64      `json:"version" gorm:"not null;default:1"`            // Bumped on every edit of the account and every balance movement
 	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
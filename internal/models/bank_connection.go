@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status of a bank connection
+const (
+	BankConnectionActive        = "active"
+	BankConnectionLoginRequired = "login_required" // The user has to log in to the bank again
+	BankConnectionDisconnected  = "disconnected"
+)
+
+// BankConnection is a bank login linked through an open-banking provider. Its transactions
+// are pulled periodically into the Fluxio accounts its bank accounts are linked to
+type BankConnection struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Provider          string     `json:"provider" gorm:"type:varchar(20);not null;uniqueIndex:idx_bank_connection_item"`
+	ItemID            string     `json:"-" gorm:"type:varchar(100);not null;uniqueIndex:idx_bank_connection_item"`
+	AccessToken       string     `json:"-" gorm:"not null"` // Sealed with OPEN_BANKING_TOKEN_KEY
+	InstitutionName   *string    `json:"institution_name,omitempty" gorm:"type:varchar(100)"`
+	DefaultCategoryID uuid.UUID  `json:"default_category_id" gorm:"type:uuid;not null"` // For expenses created from its transactions
+	Cursor            *string    `json:"-"`                                             // Provider sync position
+	Status            string     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	LastError         *string    `json:"last_error,omitempty"`
+	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"`
+	SyncRequestedAt   *time.Time `json:"sync_requested_at,omitempty"` // Set by provider webhooks; the sync job picks it up
+	SyncStartedAt     *time.Time `json:"-"`                           // Claimed by the sync running now, so two don't overlap
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Relaciones
+	Accounts []BankConnectionAccount `json:"accounts" gorm:"foreignKey:ConnectionID;constraint:OnDelete:CASCADE"`
+}
+
+// BankConnectionAccount is a bank account reachable through a connection. Transactions are only
+// imported once it is linked to a Fluxio bank account
+type BankConnectionAccount struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ConnectionID      uuid.UUID  `json:"connection_id" gorm:"type:uuid;not null;uniqueIndex:idx_bank_connection_account"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	ExternalAccountID string     `json:"-" gorm:"type:varchar(100);not null;uniqueIndex:idx_bank_connection_account"`
+	Name              string     `json:"name" gorm:"not null"`
+	Mask              *string    `json:"mask,omitempty" gorm:"type:varchar(10)"`
+	Type              *string    `json:"type,omitempty" gorm:"type:varchar(30)"`
+	BankAccountID     *uuid.UUID `json:"bank_account_id,omitempty" gorm:"type:uuid"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
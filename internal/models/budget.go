@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Budget is the monthly 50/30/20 budget of a user
+type Budget struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index:idx_budget_user_month"`
+	MonthYear       time.Time  `json:"month_year" gorm:"type:date;not null;index:idx_budget_user_month"` // First day of the month
+	NeedsBudget     Money      `json:"needs_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	WantsBudget     Money      `json:"wants_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsBudget   Money      `json:"savings_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"` // Currency of the user when it was created, kept if the user changes it
+	Version         int64      `json:"version" gorm:"not null;default:1"`        // Bumped on every edit; updates must name the version they are based on
+	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User            User             `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	CategoryBudgets []CategoryBudget `json:"category_budgets,omitempty" gorm:"foreignKey:BudgetID;constraint:OnDelete:CASCADE"`
+}
+
+// CategoryBudget is an optional budget line for one category inside a monthly budget, on top
+// of the needs/wants/savings lines
+type CategoryBudget struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BudgetID   uuid.UUID `json:"budget_id" gorm:"type:uuid;not null;uniqueIndex:idx_category_budget_line"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	CategoryID uuid.UUID `json:"category_id" gorm:"type:uuid;not null;uniqueIndex:idx_category_budget_line"`
+	Amount     Money     `json:"amount" gorm:"type:decimal(18,3);not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relaciones
+	Category Category `json:"category,omitempty" gorm:"foreignKey:CategoryID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// Total returns the sum of the three budget lines
+func (b Budget) Total() Money {
+	return b.NeedsBudget + b.WantsBudget + b.SavingsBudget
+}
+
+// MonthStart normalizes a date to the first day of its month (UTC)
+func MonthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
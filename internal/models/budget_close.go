@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Who closed a month
+const (
+	BudgetCloseManual    = "manual"
+	BudgetCloseScheduled = "scheduled"
+)
+
+// BudgetClose freezes a month: the final actuals against its budget as they stood when it was
+// closed. Expenses dated in a closed month can't be changed until it's reopened
+type BudgetClose struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_budget_close_user_month"`
+	MonthYear     time.Time  `json:"month_year" gorm:"type:date;not null;uniqueIndex:idx_budget_close_user_month"`
+	BudgetID      *uuid.UUID `json:"budget_id,omitempty" gorm:"type:uuid"`                         // nil when the month had no budget
+	NeedsBudget   Money      `json:"needs_budget" gorm:"type:decimal(18,3);not null;default:0.00"` // Time-weighted over the month
+	WantsBudget   Money      `json:"wants_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsBudget Money      `json:"savings_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	NeedsSpent    Money      `json:"needs_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	WantsSpent    Money      `json:"wants_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsSpent  Money      `json:"savings_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	TotalIncome   Money      `json:"total_income" gorm:"type:decimal(18,3);not null;default:0.00"`
+	TotalSpent    Money      `json:"total_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	Net           Money      `json:"net" gorm:"type:decimal(18,3);not null;default:0.00"` // Income minus spending
+	ExpenseCount  int64      `json:"expense_count" gorm:"not null;default:0"`
+	WithinBudget  bool       `json:"within_budget"`
+	UsagePercent  float64    `json:"usage_percent" gorm:"type:decimal(7,2);not null"` // Total spent / total budget
+	ClosedAt      time.Time  `json:"closed_at" gorm:"not null"`
+	ClosedBy      string     `json:"closed_by" gorm:"type:varchar(20);not null"` // manual or scheduled
+	ReopenedAt    *time.Time `json:"reopened_at,omitempty"`                      // Set while the month is open again
+	ReopenReason  *string    `json:"reopen_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User       User                  `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	Categories []BudgetCloseCategory `json:"categories,omitempty" gorm:"foreignKey:CloseID;constraint:OnDelete:CASCADE"`
+}
+
+// IsClosed tells whether the month is still closed, i.e. not reopened since
+func (c BudgetClose) IsClosed() bool {
+	return c.ReopenedAt == nil
+}
+
+// BudgetCloseCategory is the spending of a closed month against one category budget line
+type BudgetCloseCategory struct {
+	ID           uuid.UUID `json:"-" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CloseID      uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	CategoryID   uuid.UUID `json:"category_id" gorm:"type:uuid;not null"`
+	CategoryName string    `json:"category_name" gorm:"not null"` // As of the close
+	Budget       Money     `json:"budget" gorm:"type:decimal(18,3);not null"`
+	Spent        Money     `json:"spent" gorm:"type:decimal(18,3);not null"`
+	Within       bool      `json:"within"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BudgetCompliance is the stored result of comparing a closed month's spending against its budget
+type BudgetCompliance struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_budget_compliance_user_month"`
+	MonthYear     time.Time `json:"month_year" gorm:"type:date;not null;uniqueIndex:idx_budget_compliance_user_month"`
+	NeedsBudget   Money     `json:"needs_budget" gorm:"type:decimal(18,3);not null;default:0.00"` // Time-weighted over the month
+	WantsBudget   Money     `json:"wants_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsBudget Money     `json:"savings_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	NeedsSpent    Money     `json:"needs_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	WantsSpent    Money     `json:"wants_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsSpent  Money     `json:"savings_spent" gorm:"type:decimal(18,3);not null;default:0.00"`
+	NeedsWithin   bool      `json:"needs_within"`
+	WantsWithin   bool      `json:"wants_within"`
+	SavingsWithin bool      `json:"savings_within"`
+	WithinBudget  bool      `json:"within_budget"`                                   // Every line, category lines included, within its budget
+	UsagePercent  float64   `json:"usage_percent" gorm:"type:decimal(7,2);not null"` // Total spent / total budget
+	ComputedAt    time.Time `json:"computed_at" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Relaciones
+	User       User                       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	Categories []BudgetCategoryCompliance `json:"categories,omitempty" gorm:"foreignKey:ComplianceID;constraint:OnDelete:CASCADE"`
+}
+
+// BudgetCategoryCompliance is the spending of a closed month against one category budget line
+type BudgetCategoryCompliance struct {
+	ID           uuid.UUID `json:"-" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ComplianceID uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	CategoryID   uuid.UUID `json:"category_id" gorm:"type:uuid;not null"`
+	CategoryName string    `json:"category_name" gorm:"not null"` // As of the computation
+	Budget       Money     `json:"budget" gorm:"type:decimal(18,3);not null"`
+	Spent        Money     `json:"spent" gorm:"type:decimal(18,3);not null"`
+	Within       bool      `json:"within"`
+}
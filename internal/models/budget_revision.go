@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BudgetRevision records the amounts a budget had from EffectiveFrom on, so past months can
+// be evaluated with the values that applied at each point of the month
+type BudgetRevision struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BudgetID      uuid.UUID `json:"budget_id" gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index:idx_budget_revision_user_month"`
+	MonthYear     time.Time `json:"month_year" gorm:"type:date;not null;index:idx_budget_revision_user_month"`
+	NeedsBudget   Money     `json:"needs_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	WantsBudget   Money     `json:"wants_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	SavingsBudget Money     `json:"savings_budget" gorm:"type:decimal(18,3);not null;default:0.00"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Relaciones
+	Budget Budget `json:"-" gorm:"foreignKey:BudgetID;references:ID;constraint:OnDelete:CASCADE"`
+}
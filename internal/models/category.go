@@ -10,7 +10,11 @@ type Category struct {
 	ID              uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID   `json:"user_id" gorm:"type:uuid;not null"`
 	Name            string      `json:"name" gorm:"not null"`
-	ExpenseType     ExpenseType `json:"expense_type" gorm:"type:expense_type_enum;not null"` // PostgreSQL enum: needs, wants, savings
+	ExpenseType     ExpenseType `json:"expense_type" gorm:"type:expense_type_enum;not null"`       // PostgreSQL enum: needs, wants, savings
+	MonthlyCap      *Money      `json:"monthly_cap,omitempty" gorm:"type:decimal(18,3)"`           // Optional monthly spending cap
+	CapMode         CapMode     `json:"cap_mode" gorm:"type:varchar(10);not null;default:'alert'"` // alert or hard
+	Icon            *string     `json:"icon,omitempty" gorm:"type:varchar(32)"`                    // One of CategoryIcons; nil uses the expense type icon
+	Color           *string     `json:"color,omitempty" gorm:"type:varchar(7)"`                    // #RRGGBB; nil uses the expense type color
 	Status          Status      `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time  `json:"status_changed_at,omitempty"`
 	CreatedAt       time.Time   `json:"created_at"`
@@ -20,3 +24,16 @@ type Category struct {
 	User     User      `json:"user" gorm:"foreignKey:UserID;references:ID"`
 	Expenses []Expense `json:"expenses" gorm:"foreignKey:CategoryID"`
 }
+
+// CapMode defines what happens when an expense would exceed a category's monthly cap
+type CapMode string
+
+const (
+	CapModeAlert CapMode = "alert" // Exceeding the cap only logs a warning
+	CapModeHard  CapMode = "hard"  // Exceeding the cap is rejected unless explicitly overridden
+)
+
+// IsValidCapMode checks if a given string is a valid cap mode
+func IsValidCapMode(mode string) bool {
+	return CapMode(mode) == CapModeAlert || CapMode(mode) == CapModeHard
+}
@@ -0,0 +1,57 @@
+package models
+
+import "regexp"
+
+// CategoryIcons is the icon set the native clients ship. Categories can only use these
+// names so every platform can render them without its own mapping
+var CategoryIcons = []string{
+	"home", "cart", "utensils", "coffee", "car", "bus", "fuel", "heart-pulse", "bolt", "wifi",
+	"phone", "film", "gamepad", "music", "book", "graduation-cap", "shopping-bag", "shirt", "gift",
+	"plane", "dumbbell", "paw", "baby", "wrench", "receipt", "shield", "piggy-bank", "chart-line",
+	"wallet", "briefcase", "tag",
+}
+
+var categoryColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// IsValidCategoryIcon checks if an icon belongs to the allowed icon set
+func IsValidCategoryIcon(icon string) bool {
+	for _, allowed := range CategoryIcons {
+		if icon == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidCategoryColor checks if a color is a #RRGGBB hex value
+func IsValidCategoryColor(color string) bool {
+	return categoryColorPattern.MatchString(color)
+}
+
+// GetExpenseTypeIcon returns the icon shown for an expense type and for its categories without one
+func GetExpenseTypeIcon(expenseType ExpenseType) string {
+	switch expenseType {
+	case ExpenseTypeNeeds:
+		return "home"
+	case ExpenseTypeWants:
+		return "shopping-bag"
+	case ExpenseTypeSavings:
+		return "piggy-bank"
+	default:
+		return "tag"
+	}
+}
+
+// GetExpenseTypeColor returns the color of an expense type, also the fallback of its categories
+func GetExpenseTypeColor(expenseType ExpenseType) string {
+	switch expenseType {
+	case ExpenseTypeNeeds:
+		return "#2563EB"
+	case ExpenseTypeWants:
+		return "#F59E0B"
+	case ExpenseTypeSavings:
+		return "#10B981"
+	default:
+		return "#6B7280"
+	}
+}
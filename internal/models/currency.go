@@ -0,0 +1,142 @@
+package models
+
+import (
+	"math"
+	"strconv"
+)
+
+// Rounding rules applied when an amount has more precision than the currency allows
+const (
+	RoundingHalfUp   = "half_up"   // 2.5 -> 3, -2.5 -> -3
+	RoundingHalfEven = "half_even" // 2.5 -> 2, 3.5 -> 4 (banker's rounding)
+)
+
+// DefaultCurrencyCode is used for users that never picked a currency
+const DefaultCurrencyCode = "USD"
+
+// Currency holds the ISO 4217 metadata the money helpers need
+type Currency struct {
+	Code         string `json:"code" gorm:"type:varchar(3);primary_key"`
+	Name         string `json:"name" gorm:"not null"`
+	MinorUnits   int    `json:"minor_units" gorm:"not null;default:2"` // Decimals shown and kept, e.g. 0 for JPY, 3 for BHD
+	RoundingRule string `json:"rounding_rule" gorm:"type:varchar(20);not null;default:'half_up'"`
+}
+
+// DefaultCurrencies are seeded on migration
+var DefaultCurrencies = []Currency{
+	{Code: "USD", Name: "US Dollar", MinorUnits: 2, RoundingRule: RoundingHalfUp},
+	{Code: "EUR", Name: "Euro", MinorUnits: 2, RoundingRule: RoundingHalfEven},
+	{Code: "GBP", Name: "Pound Sterling", MinorUnits: 2, RoundingRule: RoundingHalfUp},
+	{Code: "MXN", Name: "Mexican Peso", MinorUnits: 2, RoundingRule: RoundingHalfUp},
+	{Code: "CAD", Name: "Canadian Dollar", MinorUnits: 2, RoundingRule: RoundingHalfUp},
+	{Code: "JPY", Name: "Yen", MinorUnits: 0, RoundingRule: RoundingHalfUp},
+	{Code: "KRW", Name: "Won", MinorUnits: 0, RoundingRule: RoundingHalfUp},
+	{Code: "CLP", Name: "Chilean Peso", MinorUnits: 0, RoundingRule: RoundingHalfUp},
+	{Code: "BHD", Name: "Bahraini Dinar", MinorUnits: 3, RoundingRule: RoundingHalfUp},
+	{Code: "KWD", Name: "Kuwaiti Dinar", MinorUnits: 3, RoundingRule: RoundingHalfUp},
+}
+
+// scale is the number of minor units in one major unit
+func (c *Currency) scale() float64 {
+	return math.Pow10(c.MinorUnits)
+}
+
+// roundUnits rounds an amount expressed in minor units to an integer using the currency rule
+func (c *Currency) roundUnits(units float64) float64 {
+	if c.RoundingRule == RoundingHalfEven {
+		return math.RoundToEven(units)
+	}
+	return math.Round(units)
+}
+
+// toUnits converts an amount to whole minor units. Units are snapped to 6 decimals first so
+// float noise (1.005 * 100 = 100.49999...) doesn't hide an exact half from the rounding rule
+func (c *Currency) toUnits(amount float64) float64 {
+	units := math.Round(amount*c.scale()*1e6) / 1e6
+	return c.roundUnits(units)
+}
+
+// Round rounds an amount to the precision of the currency
+func (c *Currency) Round(amount float64) float64 {
+	return c.toUnits(amount) / c.scale()
+}
+
+// Epsilon is half a minor unit: amounts closer than this are equal in the currency
+func (c *Currency) Epsilon() float64 {
+	return 0.5 / c.scale()
+}
+
+// Split divides total proportionally to weights. Parts are rounded down to whole minor units
+// and the leftover units go to the parts with the largest remainders (ties to the earliest),
+// so the parts always add up to the rounded total
+func (c *Currency) Split(total float64, weights []float64) []float64 {
+	parts := make([]float64, len(weights))
+	var weightSum float64
+	for _, weight := range weights {
+		if weight > 0 {
+			weightSum += weight
+		}
+	}
+	if len(weights) == 0 || weightSum == 0 {
+		return parts
+	}
+
+	totalUnits := c.toUnits(total)
+	units := make([]float64, len(weights))
+	remainders := make([]float64, len(weights))
+	var assigned float64
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		exact := totalUnits * weight / weightSum
+		units[i] = math.Floor(exact)
+		remainders[i] = exact - units[i]
+		assigned += units[i]
+	}
+
+	for leftover := int(totalUnits - assigned); leftover > 0; leftover-- {
+		best := -1
+		for i := range remainders {
+			if weights[i] > 0 && (best == -1 || remainders[i] > remainders[best]) {
+				best = i
+			}
+		}
+		units[best]++
+		remainders[best] = -1
+	}
+
+	for i := range parts {
+		parts[i] = units[i] / c.scale()
+	}
+	return parts
+}
+
+// Format renders an amount with exactly the currency's decimals
+func (c *Currency) Format(amount float64) string {
+	return strconv.FormatFloat(c.Round(amount), 'f', c.MinorUnits, 64)
+}
+
+// RoundMoney rounds an amount to the precision of the currency, e.g. to cents or whole yen
+func (c *Currency) RoundMoney(amount Money) Money {
+	if c.MinorUnits >= 3 {
+		return amount
+	}
+	step := float64(MoneyScale) / c.scale()
+	return Money(c.roundUnits(float64(amount)/step) * step)
+}
+
+// SplitMoney divides total like Split, in the minor units of the currency. Money holds
+// thousandths, so a currency with more decimals is split in thousandths
+func (c *Currency) SplitMoney(total Money, weights []float64) []Money {
+	precision := *c
+	if precision.MinorUnits > 3 {
+		precision.MinorUnits = 3
+	}
+	shares := precision.Split(total.Float64(), weights)
+	parts := make([]Money, len(shares))
+	for i, share := range shares {
+		parts[i] = NewMoney(share)
+	}
+	return parts
+}
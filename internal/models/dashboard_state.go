@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DashboardState is the read model behind the dashboard: the figures of its widgets, kept up
+// to date by an outbox event subscriber so reading the dashboard doesn't aggregate anything
+type DashboardState struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	Month     time.Time `json:"month" gorm:"type:date;not null"`           // Month the figures are for
+	Version   int64     `json:"version" gorm:"not null;default:0"`         // Increases on every refresh, for clients following changes
+	State     string    `json:"-" gorm:"type:jsonb;not null;default:'{}'"` // dto.DashboardState
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats of a data export
+const (
+	DataExportCSV  = "csv"  // ZIP with one CSV file per kind of record
+	DataExportJSON = "json" // A single JSON document
+)
+
+// Statuses of a data export job
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+	DataExportCancelled = "cancelled" // Cancelled by the user before it completed
+)
+
+// DataExport is a copy of a user's records generated in the background, for accounts too large
+// to export within a request. The file is kept in storage until ExpiresAt
+type DataExport struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	Format      string     `json:"format" gorm:"type:varchar(10);not null"`
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	StorageKey  string     `json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // When the file is deleted
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataQualityReport is the latest data quality check of a user. It is regenerated weekly,
+// or on demand, replacing the previous one
+type DataQualityReport struct {
+	UserID                 uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	UncategorizedExpenses  int       `json:"uncategorized_expenses" gorm:"not null;default:0"`
+	ExpensesMissingAccount int       `json:"expenses_missing_account" gorm:"not null;default:0"`
+	StalePending           int       `json:"stale_pending" gorm:"not null;default:0"`
+	NegativeBalances       int       `json:"negative_balances" gorm:"not null;default:0"`
+	Issues                 string    `json:"-" gorm:"type:jsonb;not null;default:'[]'"` // []dto.DataQualityIssue
+	GeneratedAt            time.Time `json:"generated_at" gorm:"not null"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
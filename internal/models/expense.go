@@ -10,17 +10,28 @@ type Expense struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
 	CategoryID      uuid.UUID  `json:"category_id" gorm:"type:uuid;not null"`
-	Amount          float64    `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Amount          Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"` // Currency of the user when it was created, kept if the user changes it
 	Date            time.Time  `json:"date" gorm:"type:date;not null"`
 	BankAccountID   uuid.UUID  `json:"bank_account_id" gorm:"type:uuid"` // Note: nullable for migration, validation in service layer ensures NOT NULL
 	Description     *string    `json:"description"`
+	TripID          *uuid.UUID `json:"trip_id,omitempty" gorm:"type:uuid;index"` // Set when assigned to a trip by hand
 	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	PriorStatus     *Status    `json:"-" gorm:"type:varchar(20)"` // Status before it was archived with its bank account, restored with it
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 
 	// Relaciones
-	User        User        `json:"user" gorm:"foreignKey:UserID;references:ID"`
-	Category    Category    `json:"category" gorm:"foreignKey:CategoryID;references:ID"`
-	BankAccount BankAccount `json:"bank_account" gorm:"foreignKey:BankAccountID;references:ID"`
+	User        User                `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	Category    Category            `json:"category" gorm:"foreignKey:CategoryID;references:ID"`
+	BankAccount BankAccount         `json:"bank_account" gorm:"foreignKey:BankAccountID;references:ID"`
+	Allocations []ExpenseAllocation `json:"allocations,omitempty" gorm:"foreignKey:ExpenseID;constraint:OnDelete:CASCADE"` // Only for expenses split across accounts
+	Attachments []ExpenseAttachment `json:"attachments,omitempty" gorm:"foreignKey:ExpenseID;constraint:OnDelete:CASCADE"`
+	Tags        []Tag               `json:"tags,omitempty" gorm:"many2many:expense_tags;constraint:OnDelete:CASCADE"`
+
+	// AllocatedAmount is the portion paid from the account a listing was filtered by
+	AllocatedAmount *Money `json:"allocated_amount,omitempty" gorm:"-"`
+	// ConfirmTokenID is the large amount confirm token creating the expense spends
+	ConfirmTokenID *uuid.UUID `json:"-" gorm:"-"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseAllocation is the portion of a split expense paid from one bank account. An
+// expense paid from a single account has no allocations; its BankAccountID is the ledger.
+type ExpenseAllocation struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ExpenseID     uuid.UUID `json:"expense_id" gorm:"type:uuid;not null;index"`
+	BankAccountID uuid.UUID `json:"bank_account_id" gorm:"type:uuid;not null;index"`
+	Amount        Money     `json:"amount" gorm:"type:decimal(18,3);not null"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Relaciones
+	BankAccount BankAccount `json:"bank_account" gorm:"foreignKey:BankAccountID;references:ID"`
+}
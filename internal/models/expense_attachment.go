@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseAttachment is a receipt (image or PDF) uploaded for an expense. The file itself is in
+// the attachment storage under StorageKey
+type ExpenseAttachment struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ExpenseID   uuid.UUID `json:"expense_id" gorm:"type:uuid;not null;index"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	FileName    string    `json:"file_name" gorm:"type:varchar(255);not null"`
+	ContentType string    `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	StorageKey  string    `json:"-" gorm:"type:varchar(255);not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
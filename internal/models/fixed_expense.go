@@ -6,11 +6,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// Recurrence types of a fixed expense
+const (
+	RecurrenceMonthly = "monthly"
+	RecurrenceYearly  = "yearly"
+)
+
+// ValidRecurrenceTypes returns all valid recurrence types
+func ValidRecurrenceTypes() []string {
+	return []string{RecurrenceMonthly, RecurrenceYearly}
+}
+
 type FixedExpense struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
 	Name            string     `json:"name" gorm:"not null"`
-	Amount          float64    `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Amount          Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"` // Currency of the user when it was created, kept if the user changes it
 	DueDate         time.Time  `json:"due_date" gorm:"type:date;not null"` // Day of month (1-31)
 	CategoryID      *uuid.UUID `json:"category_id" gorm:"type:uuid"`       // Optional category to classify as needs/wants/savings
 	BankAccountID   uuid.UUID  `json:"bank_account_id" gorm:"type:uuid"`   // Note: nullable for migration, validation in service layer ensures NOT NULL
@@ -18,10 +30,13 @@ type FixedExpense struct {
 	RecurrenceType  string     `json:"recurrence_type" gorm:"type:varchar(20);default:'monthly'"` // monthly, yearly
 	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	PriorStatus     *Status    `json:"-" gorm:"type:varchar(20)"` // Status before it was archived with its bank account, restored with it
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 	LastProcessedAt *time.Time `json:"last_processed_at,omitempty"` // Last time it was auto-deducted
 	NextDueDate     time.Time  `json:"next_due_date" gorm:"type:date"` // Next scheduled deduction (nullable for migration)
+	DriftSuggestion *Money     `json:"-" gorm:"type:decimal(18,3)"`    // Amount suggested in the last drift notification
+	SkipHolidays    bool       `json:"skip_holidays" gorm:"not null;default:false"` // Due on a weekend or bank holiday: posted the next business day
 
 	// Relaciones
 	User        User        `json:"user" gorm:"foreignKey:UserID;references:ID"`
@@ -64,3 +79,49 @@ func (f FixedExpense) ShouldApplyForMonth(year int, month time.Month) bool {
 	
 	return true
 }
+
+// Outcomes of a fixed expense processing run
+const (
+	FixedExpenseRunPosted  = "posted"  // The expense was created and the due date moved on
+	FixedExpenseRunSkipped = "skipped" // Not posted: no category (retried on the next run) or skipped by the user
+	FixedExpenseRunFailed  = "failed"  // Posting failed; retried on the next run
+)
+
+// FixedExpenseRun records what processing did with one occurrence (period) of a fixed expense.
+// The unique (fixed_expense_id, period) index is what keeps an occurrence from being posted twice
+type FixedExpenseRun struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	FixedExpenseID uuid.UUID  `json:"fixed_expense_id" gorm:"type:uuid;not null;uniqueIndex:idx_fixed_expense_run_period,priority:1"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Period         time.Time  `json:"period" gorm:"type:date;not null;uniqueIndex:idx_fixed_expense_run_period,priority:2"` // Due date of the occurrence
+	Status         string     `json:"status" gorm:"type:varchar(20);not null"`
+	ExpenseID      *uuid.UUID `json:"expense_id,omitempty" gorm:"type:uuid"` // Expense created when posted
+	Amount         Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Reason         *string    `json:"reason,omitempty"` // Why it was skipped or failed
+	Attempts       int        `json:"attempts" gorm:"not null;default:1"`
+	ProcessedAt    time.Time  `json:"processed_at" gorm:"not null"` // Last attempt
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Statuses of a fixed expense occurrence as the user sees it
+const (
+	FixedExpenseOccurrencePending = "pending" // Not confirmed yet; posted or still to be posted
+	FixedExpenseOccurrencePaid    = "paid"    // The user confirmed the payment
+	FixedExpenseOccurrenceSkipped = "skipped" // The user skipped this cycle; nothing is posted for it
+)
+
+// FixedExpenseOccurrence is one cycle (period) of a fixed expense, linked to the expense posted
+// for it, which the user confirms as paid or skips
+type FixedExpenseOccurrence struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	FixedExpenseID uuid.UUID  `json:"fixed_expense_id" gorm:"type:uuid;not null;uniqueIndex:idx_fixed_expense_occurrence_period,priority:1"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Period         time.Time  `json:"period" gorm:"type:date;not null;uniqueIndex:idx_fixed_expense_occurrence_period,priority:2"` // Due date of the cycle
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	ExpenseID      *uuid.UUID `json:"expense_id,omitempty" gorm:"type:uuid"` // Expense posted for the cycle
+	Amount         Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"` // When it was confirmed or skipped
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
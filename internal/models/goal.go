@@ -10,8 +10,12 @@ type Goal struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
 	Name            string     `json:"name" gorm:"not null"`
-	TotalAmount     float64    `json:"total_amount" gorm:"type:decimal(15,2);not null"`
-	SavedAmount     float64    `json:"saved_amount" gorm:"type:decimal(15,2);not null;default:0.00"`
+	TotalAmount     Money      `json:"total_amount" gorm:"type:decimal(18,3);not null"`
+	SavedAmount     Money      `json:"saved_amount" gorm:"type:decimal(18,3);not null;default:0.00"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"`    // Currency of the user when it was created, kept if the user changes it
+	Priority        int        `json:"priority" gorm:"not null;default:0"`          // Funding order in the waterfall, lower goes first
+	APY             *float64   `json:"apy,omitempty" gorm:"type:decimal(6,3)"`      // Annual percentage yield of the account holding the savings
+	InterestThrough *time.Time `json:"interest_through,omitempty" gorm:"type:date"` // Last month end whose interest was posted
 	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GoalContribution is money added to a goal, either funded by the user or earned as interest
+// by the account holding the savings
+type GoalContribution struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GoalID     uuid.UUID  `json:"goal_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	Amount     Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Source     string     `json:"source" gorm:"type:varchar(20);not null"` // manual, sweep, round_up, interest or transfer
+	IsInterest bool       `json:"is_interest" gorm:"not null;default:false"`
+	TransferID *uuid.UUID `json:"transfer_id,omitempty" gorm:"type:uuid;index"` // Transfer that funded it
+	Date       time.Time  `json:"date" gorm:"type:date;not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relaciones
+	Goal Goal `json:"-" gorm:"foreignKey:GoalID;references:ID"`
+}
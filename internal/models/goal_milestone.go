@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GoalMilestone is a checkpoint on the way to a goal, defined either as a percent of the
+// goal's total amount or as a fixed amount. ReachedAt records when it was first crossed.
+type GoalMilestone struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GoalID    uuid.UUID  `json:"goal_id" gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	Percent   *float64   `json:"percent,omitempty" gorm:"type:decimal(5,2)"`
+	Amount    *Money     `json:"amount,omitempty" gorm:"type:decimal(18,3)"`
+	Label     *string    `json:"label,omitempty" gorm:"type:varchar(100)"`
+	ReachedAt *time.Time `json:"reached_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relaciones
+	Goal Goal `json:"-" gorm:"foreignKey:GoalID;references:ID"`
+}
+
+// TargetAmount returns the saved amount at which the milestone is reached for a goal total
+func (m GoalMilestone) TargetAmount(totalAmount Money) Money {
+	if m.Amount != nil {
+		return *m.Amount
+	}
+	if m.Percent != nil {
+		return totalAmount.MulRatio(*m.Percent / 100)
+	}
+	return totalAmount
+}
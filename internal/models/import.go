@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status of an imported transaction
+const (
+	ImportedCreated   = "created"   // Became a new expense, or income for money coming in
+	ImportedMatched   = "matched"   // Looks like an existing expense, waiting for the user
+	ImportedMerged    = "merged"    // Resolved into the existing expense
+	ImportedDuplicate = "duplicate" // Resolved keeping the existing expense untouched
+	ImportedFailed    = "failed"    // Could not be turned into an expense
+)
+
+// Field provenance sources
+const (
+	ProvenanceManual = "manual"
+	ProvenanceImport = "import"
+)
+
+// ImportBatch is one upload of bank transactions into an account
+type ImportBatch struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID           uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	BankAccountID    uuid.UUID  `json:"bank_account_id" gorm:"type:uuid;not null"`
+	CategoryID       uuid.UUID  `json:"category_id" gorm:"type:uuid;not null"`               // Used for expenses created from the batch
+	Source           *string    `json:"source,omitempty"`                                    // e.g. the file name or bank
+	BankConnectionID *uuid.UUID `json:"bank_connection_id,omitempty" gorm:"type:uuid;index"` // Set when pulled from a bank connection
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// ImportedTransaction is a single row of an import batch
+type ImportedTransaction struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BatchID          uuid.UUID  `json:"batch_id" gorm:"type:uuid;not null;index"`
+	UserID           uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	Date             time.Time  `json:"date" gorm:"type:date;not null"`
+	Amount           Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Description      *string    `json:"description,omitempty"`
+	ExternalID       *string    `json:"external_id,omitempty"`
+	MerchantName     *string    `json:"merchant_name,omitempty"` // Recognized from the description by merchant enrichment
+	MerchantLogoURL  *string    `json:"merchant_logo_url,omitempty"`
+	MerchantProvider *string    `json:"merchant_provider,omitempty" gorm:"type:varchar(20)"`
+	ExpenseID        *uuid.UUID `json:"expense_id,omitempty" gorm:"type:uuid;index"` // Expense it created or was resolved into
+	IncomeID         *uuid.UUID `json:"income_id,omitempty" gorm:"type:uuid;index"`  // Income it created, for money coming in
+	Status           string     `json:"status" gorm:"type:varchar(20);not null"`
+	Error            *string    `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+
+	// Relaciones
+	Batch ImportBatch `json:"-" gorm:"foreignKey:BatchID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// ImportMatch pairs an imported transaction with an existing manual expense it likely duplicates
+type ImportMatch struct {
+	ID                    uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BatchID               uuid.UUID  `json:"batch_id" gorm:"type:uuid;not null;index"`
+	UserID                uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	ImportedTransactionID uuid.UUID  `json:"imported_transaction_id" gorm:"type:uuid;not null;uniqueIndex"`
+	ExpenseID             uuid.UUID  `json:"expense_id" gorm:"type:uuid;not null"`
+	Score                 float64    `json:"score" gorm:"type:decimal(4,2);not null"` // 0-1, higher is more likely
+	Strategy              *string    `json:"strategy,omitempty" gorm:"type:varchar(20)"`
+	ResolvedAt            *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+
+	// Relaciones
+	Batch ImportBatch `json:"-" gorm:"foreignKey:BatchID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// ExpenseFieldProvenance records where the current value of an expense field came from.
+// Fields without a row were entered by hand
+type ExpenseFieldProvenance struct {
+	ExpenseID             uuid.UUID  `json:"expense_id" gorm:"type:uuid;primary_key"`
+	Field                 string     `json:"field" gorm:"type:varchar(30);primary_key"`
+	Source                string     `json:"source" gorm:"type:varchar(20);not null"`
+	ImportedTransactionID *uuid.UUID `json:"imported_transaction_id,omitempty" gorm:"type:uuid"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+
+	// Relaciones
+	Expense Expense `json:"-" gorm:"foreignKey:ExpenseID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -6,18 +6,81 @@ import (
 	"github.com/google/uuid"
 )
 
+// Income sources, what the money came from. Refunds of expenses always have the refund source
+const (
+	IncomeSourceSalary    = "salary"
+	IncomeSourceFreelance = "freelance"
+	IncomeSourceBusiness  = "business"
+	IncomeSourceDividends = "dividends"
+	IncomeSourceInterest  = "interest"
+	IncomeSourceRental    = "rental"
+	IncomeSourceGift      = "gift"
+	IncomeSourceRefund    = "refund"
+	IncomeSourceOther     = "other"
+)
+
+// ValidIncomeSources returns all valid income sources
+func ValidIncomeSources() []string {
+	return []string{
+		IncomeSourceSalary, IncomeSourceFreelance, IncomeSourceBusiness, IncomeSourceDividends,
+		IncomeSourceInterest, IncomeSourceRental, IncomeSourceGift, IncomeSourceRefund, IncomeSourceOther,
+	}
+}
+
+// IsValidIncomeSource checks if a given string is a valid income source
+func IsValidIncomeSource(source string) bool {
+	for _, valid := range ValidIncomeSources() {
+		if source == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIncomeSourceName returns the display name for an income source
+func GetIncomeSourceName(source string) string {
+	switch source {
+	case IncomeSourceSalary:
+		return "Salary"
+	case IncomeSourceFreelance:
+		return "Freelance"
+	case IncomeSourceBusiness:
+		return "Business"
+	case IncomeSourceDividends:
+		return "Dividends"
+	case IncomeSourceInterest:
+		return "Interest"
+	case IncomeSourceRental:
+		return "Rental"
+	case IncomeSourceGift:
+		return "Gifts"
+	case IncomeSourceRefund:
+		return "Refunds"
+	case IncomeSourceOther:
+		return "Other"
+	default:
+		return source
+	}
+}
+
 type Income struct {
-	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
-	Amount          float64    `json:"amount" gorm:"type:decimal(15,2);not null"`
-	BankAccountID   uuid.UUID  `json:"bank_account_id" gorm:"type:uuid"` // Note: nullable for migration, validation in service layer ensures NOT NULL
-	Date            time.Time  `json:"date" gorm:"type:date;not null"`
-	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
-	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	Amount            Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Currency          string     `json:"currency" gorm:"type:varchar(3);not null"`   // Currency of the user when it was created, kept if the user changes it
+	BankAccountID     *uuid.UUID `json:"bank_account_id,omitempty" gorm:"type:uuid"` // Optional; incomes without an account don't move any balance
+	Date              time.Time  `json:"date" gorm:"type:date;not null"`
+	Source            string     `json:"source" gorm:"type:varchar(20);not null;default:'other';index"` // salary, freelance, dividends... see ValidIncomeSources
+	Description       *string    `json:"description,omitempty" gorm:"type:text"`
+	RefundOfExpenseID *uuid.UUID `json:"refund_of_expense_id,omitempty" gorm:"type:uuid;index"` // Set when this income refunds an expense
+	Status            Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	StatusChangedAt   *time.Time `json:"status_changed_at,omitempty"`
+	PriorStatus       *Status    `json:"-" gorm:"type:varchar(20)"` // Status before it was archived with its bank account, restored with it
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 
 	// Relaciones
-	User        User        `json:"user" gorm:"foreignKey:UserID;references:ID"`
-	BankAccount BankAccount `json:"bank_account" gorm:"foreignKey:BankAccountID;references:ID"`
+	User        User         `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	BankAccount *BankAccount `json:"bank_account,omitempty" gorm:"foreignKey:BankAccountID;references:ID"`
+	Tags        []Tag        `json:"tags,omitempty" gorm:"many2many:income_tags;constraint:OnDelete:CASCADE"`
 }
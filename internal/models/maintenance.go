@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaintenanceSetting is the read-only maintenance switch of the API, shared by every instance.
+// The table holds a single row, with ID 1
+type MaintenanceSetting struct {
+	ID                int        `json:"-" gorm:"primary_key"`
+	Enabled           bool       `json:"enabled" gorm:"not null;default:false"`
+	Reason            string     `json:"reason" gorm:"type:text;not null;default:''"`
+	RetryAfterSeconds int        `json:"retry_after_seconds" gorm:"not null;default:0"` // 0 for MAINTENANCE_RETRY_AFTER_SECONDS
+	Since             *time.Time `json:"since,omitempty"`                               // When it was last turned on
+	UpdatedBy         *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"`         // Admin who last changed it; null when set from MAINTENANCE_MODE
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
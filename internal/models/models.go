@@ -4,14 +4,63 @@ package models
 func GetAllModels() []interface{} {
 	return []interface{}{
 		&User{},
+		&Currency{},
 		&BankAccount{},
+		&AccountGroup{},
+		&AccountGroupMember{},
+		&SubProfile{},
 		// ExpenseType is now an enum (needs/wants/savings) - no longer a DB table
 		&Category{},
+		&Tag{},
 		&FixedExpense{},
+		&FixedExpenseRun{},
+		&FixedExpenseOccurrence{},
 		&Goal{},
+		&GoalMilestone{},
+		&GoalContribution{},
+		&Budget{},
+		&CategoryBudget{},
+		&BudgetRevision{},
+		&BudgetCompliance{},
+		&BudgetCategoryCompliance{},
+		&BudgetClose{},
+		&BudgetCloseCategory{},
 		&Expense{},
+		&ExpenseAllocation{},
+		&ExpenseAttachment{},
+		&ExpenseApproval{},
+		&Trip{},
+		&BankConnection{},
+		&BankConnectionAccount{},
+		&ImportBatch{},
+		&ImportedTransaction{},
+		&ImportMatch{},
+		&ExpenseFieldProvenance{},
 		&Income{},
+		&Transfer{},
 		&Reminder{},
 		&RefreshToken{},
+		&APIKey{},
+		&APIKeyUsageStat{},
+		&SecurityEvent{},
+		&LoginChallenge{},
+		&UserIdentity{},
+		&OAuthLoginState{},
+		&UsageEndpointStat{},
+		&UsageFeatureStat{},
+		&DeprecatedFieldStat{},
+		&AuditLog{},
+		&UserPreferences{},
+		&DataQualityReport{},
+		&DataExport{},
+		&DashboardState{},
+		&OutboxEvent{},
+		&SyncPurge{},
+		&UsedConfirmToken{},
+		&NotificationDelivery{},
+		&PushSubscription{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&MaintenanceSetting{},
 	}
 }
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale is the number of thousandths in one unit, enough for the three decimals of BHD or
+// KWD; amounts are stored as decimal(18,3)
+const MoneyScale = 1000
+
+// Money is an exact amount in thousandths of a unit. It is read from and written to the
+// database and JSON as a decimal ("12.34", "1.235"), so sums and comparisons don't drift like
+// float64 amounts do. The currency is kept next to it, on the record that owns the amount
+type Money int64
+
+// NewMoney converts a float amount to the nearest thousandth
+func NewMoney(amount float64) Money {
+	return Money(math.Round(amount * MoneyScale))
+}
+
+// ParseMoney reads a decimal such as "12.34", "1.235", "-0.5" or "7" exactly; more than three
+// decimals are rounded half up
+func ParseMoney(text string) (Money, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, errors.New("invalid amount: empty")
+	}
+	negative := false
+	switch text[0] {
+	case '-':
+		negative = true
+		text = text[1:]
+	case '+':
+		text = text[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid amount: %q", text)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/MoneyScale-1 {
+		return 0, fmt.Errorf("invalid amount: %q is out of range", text)
+	}
+
+	amount := units * MoneyScale
+	for i, scale := 0, int64(MoneyScale/10); i < len(fraction) && scale > 0; i, scale = i+1, scale/10 {
+		amount += int64(fraction[i]-'0') * scale
+	}
+	if len(fraction) > 3 && fraction[3] >= '5' {
+		amount++
+	}
+
+	if negative {
+		amount = -amount
+	}
+	return Money(amount), nil
+}
+
+func isDigits(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] < '0' || text[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 returns the amount in units, for ratios and display only
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// Abs returns the amount without its sign
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// MulRatio scales the amount, e.g. to prorate it, rounding to the nearest thousandth. Round
+// the result with the currency before showing or storing it
+func (m Money) MulRatio(ratio float64) Money {
+	return Money(math.Round(float64(m) * ratio))
+}
+
+// Ratio returns m / total, or 0 when total is zero
+func (m Money) Ratio(total Money) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(m) / float64(total)
+}
+
+// String renders the amount with two decimals, or three when it has thousandths, e.g. "-12.30"
+// or "1.235"
+func (m Money) String() string {
+	sign := ""
+	amount := int64(m)
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	whole, fraction := amount/MoneyScale, amount%MoneyScale
+	if fraction%10 == 0 {
+		return fmt.Sprintf("%s%d.%02d", sign, whole, fraction/10)
+	}
+	return fmt.Sprintf("%s%d.%03d", sign, whole, fraction)
+}
+
+// MarshalJSON writes the amount as a JSON number with two decimals, as float amounts were, or
+// three when it has thousandths
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a decimal string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	} else if strings.ContainsAny(text, "eE") {
+		// Exponents only come from number literals; go through float like before
+		amount, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount: %s", text)
+		}
+		*m = NewMoney(amount)
+		return nil
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads a decimal column exactly
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	case int64:
+		*m = Money(v * MoneyScale)
+		return nil
+	case float64:
+		*m = NewMoney(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+func (m *Money) scanText(text string) error {
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value writes the amount as a decimal string so Postgres stores it exactly
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,93 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+func kuwaitiDinar() *models.Currency {
+	for i := range models.DefaultCurrencies {
+		if models.DefaultCurrencies[i].Code == "KWD" {
+			return &models.DefaultCurrencies[i]
+		}
+	}
+	panic("KWD is not a default currency")
+}
+
+// TestKWDAmountRoundTrip keeps the third decimal of a Kuwaiti dinar amount through JSON, the
+// database value and a split
+func TestKWDAmountRoundTrip(t *testing.T) {
+	var amount models.Money
+	if err := json.Unmarshal([]byte(`"12.345"`), &amount); err != nil {
+		t.Fatalf("decoding the amount: %v", err)
+	}
+	encoded, err := json.Marshal(amount)
+	if err != nil {
+		t.Fatalf("encoding the amount: %v", err)
+	}
+	if string(encoded) != "12.345" {
+		t.Fatalf("JSON = %s, want 12.345", encoded)
+	}
+
+	value, err := amount.Value()
+	if err != nil {
+		t.Fatalf("writing the amount: %v", err)
+	}
+	var scanned models.Money
+	if err := scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("reading the amount back: %v", err)
+	}
+	if scanned != amount {
+		t.Fatalf("read back %s, want %s", scanned, amount)
+	}
+
+	parts := kuwaitiDinar().SplitMoney(amount, []float64{1, 1, 1})
+	var sum models.Money
+	for _, part := range parts {
+		sum += part
+	}
+	if parts[0].String() != "4.115" || parts[1].String() != "4.115" || parts[2].String() != "4.115" || sum != amount {
+		t.Fatalf("split = %v, want three parts of 4.115 adding up to 12.345", parts)
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"12.34", "12.34"},
+		{"7", "7.00"},
+		{"-0.5", "-0.50"},
+		{"1.235", "1.235"},
+		{"1.2344", "1.234"},
+		{"1.2345", "1.235"},
+		{"-0.005", "-0.005"},
+	}
+	for _, c := range cases {
+		amount, err := models.ParseMoney(c.text)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", c.text, err)
+		}
+		if got := amount.String(); got != c.want {
+			t.Errorf("ParseMoney(%q).String() = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestRoundMoneyFollowsTheCurrency(t *testing.T) {
+	amount, _ := models.ParseMoney("10.005")
+	usd := &models.Currency{Code: "USD", MinorUnits: 2, RoundingRule: models.RoundingHalfUp}
+	jpy := &models.Currency{Code: "JPY", MinorUnits: 0, RoundingRule: models.RoundingHalfUp}
+	if got := usd.RoundMoney(amount).String(); got != "10.01" {
+		t.Errorf("USD rounding = %s, want 10.01", got)
+	}
+	if got := jpy.RoundMoney(amount).String(); got != "10.00" {
+		t.Errorf("JPY rounding = %s, want 10.00", got)
+	}
+	if got := kuwaitiDinar().RoundMoney(amount); got != amount {
+		t.Errorf("KWD rounding = %s, want %s", got, amount)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery states of a notification
+const (
+	NotificationStatusHeld         = "held"         // Waiting for the window of a batching rule to close
+	NotificationStatusSent         = "sent"         // Handed to the channel through the outbox
+	NotificationStatusDelivered    = "delivered"    // The device reported it received it
+	NotificationStatusAcknowledged = "acknowledged" // The user opened or dismissed it
+	NotificationStatusSuppressed   = "suppressed"   // Held back by the user's notification settings
+	NotificationStatusFailed       = "failed"       // The channel couldn't take it, e.g. no device registered
+)
+
+// NotificationDelivery tracks one notification on one channel, from sending to acknowledgement.
+// A push that isn't delivered or acknowledged in time is escalated to another channel, e.g. email
+type NotificationDelivery struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	EventType       string     `json:"event_type" gorm:"type:varchar(100);not null"` // e.g. reminder.due
+	EntityType      string     `json:"entity_type" gorm:"type:varchar(50);not null"`
+	EntityID        uuid.UUID  `json:"entity_id" gorm:"type:uuid;not null;uniqueIndex:idx_notification_delivery_once,priority:1"`
+	DueDate         time.Time  `json:"due_date" gorm:"type:date;not null;uniqueIndex:idx_notification_delivery_once,priority:2"` // Occurrence notified; a snoozed reminder is notified again
+	Channel         string     `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_delivery_once,priority:3"`
+	Status          string     `json:"status" gorm:"type:varchar(20);not null;default:'sent'"`
+	Reason          *string    `json:"reason,omitempty"` // Why it was suppressed or failed
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	DispatchedAt    *time.Time `json:"dispatched_at,omitempty"` // When the push service or mail server accepted it
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty"`
+	EscalatedAt     *time.Time `json:"escalated_at,omitempty"`                       // When it was resent on another channel
+	EscalatedFromID *uuid.UUID `json:"escalated_from_id,omitempty" gorm:"type:uuid"` // The delivery this one escalates
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
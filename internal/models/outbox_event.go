@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxStatus is the delivery state of an outbox event
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusDelivered OutboxStatus = "delivered"
+	OutboxStatusFailed    OutboxStatus = "failed" // Gave up after the maximum attempts
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change that caused it,
+// and delivered afterwards by the outbox dispatcher
+type OutboxEvent struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	EventType     string       `json:"event_type" gorm:"type:varchar(100);not null"` // e.g. expense.created
+	AggregateType string       `json:"aggregate_type" gorm:"type:varchar(50);not null"`
+	AggregateID   uuid.UUID    `json:"aggregate_id" gorm:"type:uuid;not null"`
+	Payload       string       `json:"payload" gorm:"type:jsonb;not null;default:'{}'"`
+	RequestID     string       `json:"request_id,omitempty" gorm:"type:varchar(64)"` // Request that caused the event, for tracing logs
+	Status        OutboxStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_pending,priority:1"`
+	Attempts      int          `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time    `json:"next_attempt_at" gorm:"not null;index:idx_outbox_pending,priority:2"`
+	LastError     *string      `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time   `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushSubscription is a device or browser that receives the user's push notifications
+type PushSubscription struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Platform   string     `json:"platform" gorm:"type:varchar(20);not null"` // fcm or webpush
+	Token      string     `json:"-" gorm:"not null;uniqueIndex"`             // FCM registration token or Web Push endpoint
+	P256dh     *string    `json:"-"`                                         // Web Push encryption key of the browser
+	Auth       *string    `json:"-"`                                         // Web Push auth secret of the browser
+	Label      *string    `json:"label,omitempty" gorm:"type:varchar(100)"`  // e.g. "Pixel 8" or "Firefox on laptop"
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`                    // Last push accepted by the push service
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
@@ -7,14 +7,31 @@ import (
 	"gorm.io/gorm"
 )
 
+// Why a refresh token stopped being valid
+const (
+	RefreshTokenRevokedLogout  = "logout"
+	RefreshTokenRevokedRotated = "rotated"
+	RefreshTokenRevokedReuse   = "reuse_detected"
+	RefreshTokenRevokedSession = "session_revoked"
+	RefreshTokenRevokedLocked  = "account_locked"
+)
+
+// RefreshToken is one link of a session: every refresh rotates it for a new token of the same
+// family, so a family is a login on one device
 type RefreshToken struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
-	Token     string    `json:"token" gorm:"type:varchar(512);not null;unique;index"`
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	IsRevoked bool      `json:"is_revoked" gorm:"default:false"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	FamilyID          uuid.UUID  `json:"family_id" gorm:"type:uuid;index"` // Shared by every rotation of a login
+	Token             string     `json:"token" gorm:"type:varchar(512);not null;unique;index"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	AbsoluteExpiresAt *time.Time `json:"absolute_expires_at,omitempty"` // Caps how far sliding renewals can push ExpiresAt
+	IPAddress         string     `json:"ip_address" gorm:"type:varchar(45)"`
+	UserAgent         string     `json:"user_agent" gorm:"type:varchar(512)"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	IsRevoked         bool       `json:"is_revoked" gorm:"default:false"`
+	RevokedReason     string     `json:"revoked_reason,omitempty" gorm:"type:varchar(20)"`
+	ReplacedByID      *uuid.UUID `json:"replaced_by_id,omitempty" gorm:"type:uuid"` // Set on rotation; presenting the token again means it was stolen
 
 	// Relationships
 	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -24,6 +41,9 @@ func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
 	if rt.ID == uuid.Nil {
 		rt.ID = uuid.New()
 	}
+	if rt.FamilyID == uuid.Nil {
+		rt.FamilyID = rt.ID
+	}
 	return
 }
 
@@ -6,6 +6,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// Reminder types, matching the check constraint of the reminder_type column
+const (
+	ReminderTypeBill         = "bill"
+	ReminderTypeGoal         = "goal"
+	ReminderTypeBudgetReview = "budget_review"
+)
+
+// ValidReminderTypes returns all valid reminder types
+func ValidReminderTypes() []string {
+	return []string{ReminderTypeBill, ReminderTypeGoal, ReminderTypeBudgetReview}
+}
+
 type Reminder struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
@@ -14,6 +26,8 @@ type Reminder struct {
 	DueDate         time.Time  `json:"due_date" gorm:"type:date;not null"`
 	IsCompleted     bool       `json:"is_completed" gorm:"default:false"`
 	ReminderType    string     `json:"reminder_type" gorm:"check:reminder_type IN ('bill', 'goal', 'budget_review')"`
+	Link            *string    `json:"link,omitempty"`                          // API path of what to look at, e.g. the report to review
+	PeriodStart     *time.Time `json:"period_start,omitempty" gorm:"type:date"` // First day of the period an automatic reminder is about
 	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
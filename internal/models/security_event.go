@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType is the kind of authentication event recorded for a user
+type SecurityEventType string
+
+const (
+	SecurityEventLogin          SecurityEventType = "login"
+	SecurityEventLoginFailed    SecurityEventType = "login_failed"
+	SecurityEventStepUpRequired SecurityEventType = "step_up_required"
+	SecurityEventStepUpVerified SecurityEventType = "step_up_verified"
+	SecurityEventStepUpFailed   SecurityEventType = "step_up_failed"
+	SecurityEventTokenReuse     SecurityEventType = "refresh_token_reuse"
+	SecurityEventOAuthLogin     SecurityEventType = "oauth_login"
+)
+
+// SecurityEvent stores the metadata of an authentication event (IP, country, device)
+// so unusual logins can be detected against the user's history
+type SecurityEvent struct {
+	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID         `json:"user_id" gorm:"type:uuid;not null;index:idx_security_events_user,priority:1"`
+	EventType   SecurityEventType `json:"event_type" gorm:"type:varchar(30);not null"`
+	IPAddress   string            `json:"ip_address" gorm:"type:varchar(45)"`
+	Country     *string           `json:"country,omitempty" gorm:"type:varchar(2)"` // From the proxy geo header, when available
+	UserAgent   string            `json:"user_agent" gorm:"type:varchar(512)"`
+	DeviceID    string            `json:"device_id" gorm:"type:varchar(64)"` // Hash of the user agent
+	NewDevice   bool              `json:"new_device" gorm:"not null;default:false"`
+	RiskScore   int               `json:"risk_score" gorm:"not null;default:0"`
+	RiskReasons string            `json:"risk_reasons" gorm:"type:varchar(255)"` // Comma separated
+	CreatedAt   time.Time         `json:"created_at" gorm:"index:idx_security_events_user,priority:2"`
+}
+
+// LoginChallenge is a pending step-up verification for a risky login. Only a hash of the
+// code is stored.
+type LoginChallenge struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash   string     `json:"-" gorm:"type:varchar(64);not null"`
+	IPAddress  string     `json:"ip_address" gorm:"type:varchar(45)"`
+	Country    *string    `json:"country,omitempty" gorm:"type:varchar(2)"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(512)"`
+	Attempts   int        `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -69,6 +69,11 @@ func ValidateStatus(status Status) bool {
 	}
 }
 
+// ValidStatuses returns all valid statuses
+func ValidStatuses() []Status {
+	return []Status{StatusActive, StatusPending, StatusSuspended, StatusArchived, StatusLocked, StatusDeleted}
+}
+
 // GetActiveStatuses returns statuses that should be considered for normal operations
 func GetActiveStatuses() []Status {
 	return []Status{StatusActive, StatusPending}
@@ -0,0 +1,122 @@
+package models
+
+// StatusSideEffect is work a status change sets off besides updating the status
+type StatusSideEffect string
+
+const (
+	// SideEffectReverseBalance undoes the record's effect on its bank account balances
+	SideEffectReverseBalance StatusSideEffect = "reverse_balance"
+
+	// SideEffectReapplyBalance applies the record's effect on its bank account balances again
+	SideEffectReapplyBalance StatusSideEffect = "reapply_balance"
+)
+
+// StatusTransition is an allowed status change and what it sets off
+type StatusTransition struct {
+	From        Status
+	To          Status
+	SideEffects []StatusSideEffect
+}
+
+// HasSideEffect reports whether the transition sets off the side effect
+func (t StatusTransition) HasSideEffect(effect StatusSideEffect) bool {
+	for _, e := range t.SideEffects {
+		if e == effect {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusMachine holds the status changes allowed for an entity. Changes not listed are rejected
+type StatusMachine struct {
+	Entity      string
+	Transitions []StatusTransition
+}
+
+// Transition returns the transition from one status to another, if allowed
+func (m *StatusMachine) Transition(from, to Status) (StatusTransition, bool) {
+	for _, t := range m.Transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return StatusTransition{}, false
+}
+
+// CanTransition reports whether a record can change from one status to another
+func (m *StatusMachine) CanTransition(from, to Status) bool {
+	_, ok := m.Transition(from, to)
+	return ok
+}
+
+// AllowedNext returns the statuses a record can change to from its current one
+func (m *StatusMachine) AllowedNext(from Status) []Status {
+	next := []Status{}
+	for _, t := range m.Transitions {
+		if t.From == from {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// ledgerTransitions apply to records that move bank account balances (expenses, incomes).
+// Locked records are under dispute: they must be unlocked before anything else happens to them.
+// Deleting gives the amount back to the accounts and restoring takes it again
+func ledgerTransitions() []StatusTransition {
+	reverse := []StatusSideEffect{SideEffectReverseBalance}
+	reapply := []StatusSideEffect{SideEffectReapplyBalance}
+	return []StatusTransition{
+		{From: StatusActive, To: StatusPending},
+		{From: StatusActive, To: StatusSuspended},
+		{From: StatusActive, To: StatusArchived},
+		{From: StatusActive, To: StatusLocked},
+		{From: StatusActive, To: StatusDeleted, SideEffects: reverse},
+		{From: StatusPending, To: StatusActive},
+		{From: StatusPending, To: StatusLocked},
+		{From: StatusPending, To: StatusDeleted, SideEffects: reverse},
+		{From: StatusSuspended, To: StatusActive},
+		{From: StatusSuspended, To: StatusArchived},
+		{From: StatusSuspended, To: StatusDeleted, SideEffects: reverse},
+		{From: StatusArchived, To: StatusActive},
+		{From: StatusArchived, To: StatusDeleted, SideEffects: reverse},
+		{From: StatusLocked, To: StatusActive},
+		{From: StatusDeleted, To: StatusActive, SideEffects: reapply},
+	}
+}
+
+// ExpenseStatusMachine governs ChangeExpenseStatus and soft deletes of expenses
+var ExpenseStatusMachine = &StatusMachine{Entity: "expense", Transitions: ledgerTransitions()}
+
+// IncomeStatusMachine governs ChangeIncomeStatus and soft deletes of incomes
+var IncomeStatusMachine = &StatusMachine{Entity: "income", Transitions: ledgerTransitions()}
+
+// BankAccountStatusMachine governs ChangeAccountStatus and soft deletes of bank accounts.
+// Locked accounts can only be restored
+var BankAccountStatusMachine = &StatusMachine{Entity: "bank_account", Transitions: []StatusTransition{
+	{From: StatusActive, To: StatusSuspended},
+	{From: StatusActive, To: StatusArchived},
+	{From: StatusActive, To: StatusLocked},
+	{From: StatusActive, To: StatusDeleted},
+	{From: StatusPending, To: StatusActive},
+	{From: StatusPending, To: StatusDeleted},
+	{From: StatusSuspended, To: StatusActive},
+	{From: StatusSuspended, To: StatusArchived},
+	{From: StatusSuspended, To: StatusDeleted},
+	{From: StatusArchived, To: StatusActive},
+	{From: StatusArchived, To: StatusDeleted},
+	{From: StatusLocked, To: StatusActive},
+	{From: StatusDeleted, To: StatusActive},
+}}
+
+// GoalStatusMachine governs ChangeGoalStatus. Goals are only ever active or deleted
+var GoalStatusMachine = &StatusMachine{Entity: "goal", Transitions: []StatusTransition{
+	{From: StatusActive, To: StatusDeleted},
+	{From: StatusDeleted, To: StatusActive},
+}}
+
+// StatusMachines returns the status machine of every entity with status changes
+func StatusMachines() []*StatusMachine {
+	return []*StatusMachine{ExpenseStatusMachine, IncomeStatusMachine, BankAccountStatusMachine, GoalStatusMachine}
+}
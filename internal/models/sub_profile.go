@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubProfile marks a user as a restricted profile under a parent account, e.g. a kid with an
+// allowance. The sub-profile logs in on its own and keeps its own data; the parent can see it
+// and has to approve its expenses above the threshold
+type SubProfile struct {
+	UserID            uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	ParentID          uuid.UUID `json:"parent_id" gorm:"type:uuid;not null;index"`
+	WalletAccountID   uuid.UUID `json:"wallet_account_id" gorm:"type:uuid;not null"`  // Bank account of the sub-profile holding the allowance
+	ApprovalThreshold *Money    `json:"approval_threshold" gorm:"type:decimal(18,3)"` // Expenses above it wait for the parent; nil never asks
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// Relaciones
+	User   User `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Parent User `json:"-" gorm:"foreignKey:ParentID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// Status of an expense approval
+const (
+	ExpenseApprovalPending  = "pending"
+	ExpenseApprovalApproved = "approved"
+	ExpenseApprovalRejected = "rejected"
+)
+
+// ExpenseApproval holds an expense of a sub-profile until the parent decides on it. The
+// expense only exists, and only moves the balance, once approved
+type ExpenseApproval struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"` // The sub-profile
+	ParentID      uuid.UUID  `json:"parent_id" gorm:"type:uuid;not null;index"`
+	CategoryID    uuid.UUID  `json:"category_id" gorm:"type:uuid;not null"`
+	BankAccountID uuid.UUID  `json:"bank_account_id" gorm:"type:uuid;not null"`
+	Amount        Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Date          time.Time  `json:"date" gorm:"type:date;not null"`
+	Description   *string    `json:"description,omitempty"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	Reason        *string    `json:"reason,omitempty"`                      // Given by the parent when rejecting
+	ExpenseID     *uuid.UUID `json:"expense_id,omitempty" gorm:"type:uuid"` // Created on approval
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncPurge records how far deleted rows of one entity of a user were removed for good, by the
+// retention purge, the trash or a hard delete. Sync cursors from before it may have missed
+// deletions no tombstone can report anymore, so the client must sync again from scratch
+type SyncPurge struct {
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Entity        string    `json:"entity" gorm:"type:varchar(30);primaryKey"` // Sync entity, e.g. expense
+	PurgedThrough time.Time `json:"purged_through" gorm:"not null"`            // Latest change removed
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxTagNameLength is the longest tag name accepted
+const MaxTagNameLength = 50
+
+// Tag is a free-form label the user puts on expenses and incomes, across categories
+// (e.g. "trip to cancún"). Names are stored trimmed and lowercased, unique per user
+type Tag struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tag_user_name,priority:1"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tag_user_name,priority:2"`
+	Color     *string   `json:"color,omitempty" gorm:"type:varchar(7)"` // #RRGGBB
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transfer moves money between two bank accounts of the same user
+type Transfer struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	FromAccountID   uuid.UUID  `json:"from_account_id" gorm:"type:uuid;not null"`
+	ToAccountID     uuid.UUID  `json:"to_account_id" gorm:"type:uuid;not null"`
+	Amount          Money      `json:"amount" gorm:"type:decimal(18,3);not null"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null"` // Currency of the user when it was created, kept if the user changes it
+	Date            time.Time  `json:"date" gorm:"type:date;not null"`
+	Description     *string    `json:"description"`
+	GoalID          *uuid.UUID `json:"goal_id,omitempty" gorm:"type:uuid;index"` // Goal the transfer funds; its contribution goes away with the link
+	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	PriorStatus     *Status    `json:"-" gorm:"type:varchar(20)"` // Status before it was archived with its bank account, restored with it
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User        User        `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	FromAccount BankAccount `json:"from_account" gorm:"foreignKey:FromAccountID;references:ID"`
+	ToAccount   BankAccount `json:"to_account" gorm:"foreignKey:ToAccountID;references:ID"`
+
+	// ConfirmTokenID is the large amount confirm token creating the transfer spends
+	ConfirmTokenID *uuid.UUID `json:"-" gorm:"-"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Trip is a period of travel with its own budget. Expenses dated inside the trip window
+// belong to it unless they were assigned to another trip; expenses outside the window can
+// be assigned by hand through Expense.TripID
+type Trip struct {
+	ID                uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name              string    `json:"name" gorm:"not null"`
+	StartDate         time.Time `json:"start_date" gorm:"type:date;not null"`
+	EndDate           time.Time `json:"end_date" gorm:"type:date;not null"` // Inclusive
+	Budget            *Money    `json:"budget,omitempty" gorm:"type:decimal(18,3)"`
+	Currency          string    `json:"currency" gorm:"type:varchar(3);not null"`
+	ExcludeFromBudget bool      `json:"exclude_from_budget" gorm:"not null;default:false"` // Leave trip spending out of monthly budget compliance
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// Relaciones
+	User     User      `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	Expenses []Expense `json:"-" gorm:"foreignKey:TripID;constraint:OnDelete:SET NULL"`
+}
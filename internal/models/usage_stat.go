@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageEndpointStat stores aggregate request counts per endpoint, day and user cohort.
+// No user identifiers are stored, only the month the users signed up in.
+type UsageEndpointStat struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Day          time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_usage_endpoint_day"`
+	Method       string    `json:"method" gorm:"type:varchar(10);not null;uniqueIndex:idx_usage_endpoint_day"`
+	Endpoint     string    `json:"endpoint" gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_endpoint_day"`
+	Cohort       string    `json:"cohort" gorm:"type:varchar(7);not null;uniqueIndex:idx_usage_endpoint_day"` // Signup month (YYYY-MM)
+	RequestCount int64     `json:"request_count" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UsageFeatureStat stores how many distinct users of a cohort used a feature on a given day
+type UsageFeatureStat struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Day         time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_usage_feature_day"`
+	Feature     string    `json:"feature" gorm:"type:varchar(100);not null;uniqueIndex:idx_usage_feature_day"`
+	Cohort      string    `json:"cohort" gorm:"type:varchar(7);not null;uniqueIndex:idx_usage_feature_day"`
+	ActiveUsers int64     `json:"active_users" gorm:"not null;default:0"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DeprecatedFieldStat counts the responses that still carried a deprecated field, per client,
+// endpoint and day, so the field can be removed once nobody gets it anymore
+type DeprecatedFieldStat struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Day          time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_deprecated_field_day"`
+	ClientID     string    `json:"client_id" gorm:"type:varchar(100);not null;uniqueIndex:idx_deprecated_field_day"` // X-Client-ID header, "unknown" when missing
+	Method       string    `json:"method" gorm:"type:varchar(10);not null;uniqueIndex:idx_deprecated_field_day"`
+	Endpoint     string    `json:"endpoint" gorm:"type:varchar(255);not null;uniqueIndex:idx_deprecated_field_day"`
+	Field        string    `json:"field" gorm:"type:varchar(255);not null;uniqueIndex:idx_deprecated_field_day"` // JSON path, e.g. top_categories
+	RequestCount int64     `json:"request_count" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
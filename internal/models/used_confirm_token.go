@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsedConfirmToken marks a large amount confirm token as spent on the entry it confirmed, so it
+// can't create another. It's written with the entry, and kept until the token has expired
+type UsedConfirmToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"` // The token's jti
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+}
@@ -6,16 +6,36 @@ import (
 	"github.com/google/uuid"
 )
 
+// Roles of a user; admins can operate the whole instance through /api/v1/admin
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ValidRoles returns all valid user roles
+func ValidRoles() []string {
+	return []string{RoleUser, RoleAdmin}
+}
+
 type User struct {
-	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email         string     `json:"email" gorm:"uniqueIndex;not null"`
-	Password      string     `json:"-" gorm:"not null"` // "-" means don't include in JSON
-	Name          string     `json:"name" gorm:"not null"`
-	MonthlyIncome *float64   `json:"monthly_income" gorm:"type:decimal(15,2)"`
-	Status        Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
-	LastLogin     *time.Time `json:"last_login,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`
+	Password        string     `json:"-" gorm:"not null"` // "-" means don't include in JSON
+	Name            string     `json:"name" gorm:"not null"`
+	MonthlyIncome   *Money     `json:"monthly_income" gorm:"type:decimal(18,3)"`
+	Status          Status     `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	Role            string     `json:"role" gorm:"type:varchar(20);not null;default:'user'"`
+	LastLogin       *time.Time `json:"last_login,omitempty"`
+	AnalyticsOptOut bool       `json:"analytics_opt_out" gorm:"not null;default:false"`        // Excludes the user from usage analytics
+	Currency        string     `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"` // Drives rounding and display precision
+	// Sandbox tenants are test users owned by a real user; their clock runs ClockOffsetSeconds ahead
+	IsSandbox          bool       `json:"is_sandbox" gorm:"not null;default:false"`
+	SandboxOwnerID     *uuid.UUID `json:"sandbox_owner_id,omitempty" gorm:"type:uuid;index"`
+	ClockOffsetSeconds int64      `json:"clock_offset_seconds" gorm:"not null;default:0"`
+	// Set when the user asked to delete the account; every record is erased once it passes
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" gorm:"index"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 // IsActive returns true if the user account is active
@@ -23,6 +43,11 @@ func (u *User) IsActive() bool {
 	return u.Status.IsActive()
 }
 
+// IsAdmin returns true if the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 // IsAccessible returns true if the user can access the system
 func (u *User) IsAccessible() bool {
 	return u.Status.IsAccessible()
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to an account at an external identity provider (Google, Apple)
+// they can sign in with
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Provider    string     `json:"provider" gorm:"type:varchar(20);not null;uniqueIndex:idx_user_identities_subject,priority:1"`
+	Subject     string     `json:"-" gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_subject,priority:2"` // Stable account ID at the provider
+	Email       string     `json:"email" gorm:"type:varchar(255)"`                                                         // As reported by the provider when linked
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
+
+// OAuthLoginState is a sign-in with an external provider in progress, between the redirect
+// to the provider and its callback. It is consumed by the callback.
+type OAuthLoginState struct {
+	ID           string    `json:"id" gorm:"type:varchar(64);primary_key"` // The state parameter
+	Provider     string    `json:"provider" gorm:"type:varchar(20);not null"`
+	Nonce        string    `json:"-" gorm:"type:varchar(64);not null"`
+	CodeVerifier string    `json:"-" gorm:"type:varchar(128);not null"`
+	RedirectURI  *string   `json:"redirect_uri,omitempty" gorm:"type:varchar(512)"` // Where the client wants the tokens
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserPreferences stores per-user settings that don't belong to a specific entity
+type UserPreferences struct {
+	UserID               uuid.UUID  `json:"user_id" gorm:"type:uuid;primary_key"`
+	RetentionPolicies    string     `json:"retention_policies" gorm:"type:jsonb;not null;default:'{}'"`         // Entity type -> days to keep deleted records (null = forever)
+	NotificationSettings string     `json:"notification_settings" gorm:"type:jsonb;not null;default:'{}'"`      // Quiet hours, channel and entity muting
+	BenchmarkOptIn       bool       `json:"benchmark_opt_in" gorm:"not null;default:false"`                     // Share anonymized spending in category benchmarks
+	DashboardConfig      string     `json:"dashboard_config" gorm:"type:jsonb;not null;default:'{}'"`           // Order and visibility of dashboard widgets
+	GoalWaterfall        bool       `json:"goal_waterfall" gorm:"not null;default:false"`                       // Sweeps and round-ups fund goals in priority order
+	BudgetReviewCadence  string     `json:"budget_review_cadence" gorm:"type:varchar(10);not null;default:''"`  // weekly or monthly budget_review reminders; empty for none
+	LastBudgetReviewAt   *time.Time `json:"last_budget_review_at,omitempty"`                                    // When a budget_review reminder was last completed
+	HolidayCountry       string     `json:"holiday_country" gorm:"type:varchar(2);not null;default:''"`         // Bank holiday calendar of scheduled items; empty for weekends only
+	ExpenseConfirmAbove  *Money     `json:"expense_confirm_above,omitempty" gorm:"type:decimal(18,3)"`          // Expenses above it need a confirmation step; null for none
+	TransferConfirmAbove *Money     `json:"transfer_confirm_above,omitempty" gorm:"type:decimal(18,3)"`         // Transfers above it need a confirmation step; null for none
+	Timezone             string     `json:"timezone" gorm:"type:varchar(64);not null;default:''"`               // IANA name the user's days and months follow; empty for UTC
+	FirstDayOfWeek       string     `json:"first_day_of_week" gorm:"type:varchar(9);not null;default:'monday'"` // Weekday weekly periods start on, e.g. monday or sunday
+	FirstDayOfMonth      int        `json:"first_day_of_month" gorm:"not null;default:1"`                       // 1-28; day monthly review periods and month bins start on
+	BudgetingMode        string     `json:"budgeting_mode" gorm:"type:varchar(20);not null;default:'50_30_20'"` // How suggested budgets split the income
+	DateFormat           string     `json:"date_format" gorm:"type:varchar(10);not null;default:'YYYY-MM-DD'"`  // How clients show dates; the API always uses ISO 8601
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
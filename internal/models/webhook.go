@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is a URL of the user that receives their domain events, signed with a secret
+// shared with the receiver. The secret is kept as is because every delivery is signed with it
+type Webhook struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	URL         string     `json:"url" gorm:"type:varchar(2048);not null"`
+	Description *string    `json:"description,omitempty" gorm:"type:varchar(255)"`
+	Secret      string     `json:"-" gorm:"type:varchar(255);not null"`
+	EventTypes  string     `json:"-" gorm:"type:jsonb;not null;default:'[]'"` // []string, exact types or prefixes ending in *
+	Active      bool       `json:"active" gorm:"not null;default:true"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"` // Set when the receiver answered 410 Gone
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relaciones
+	User User `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
+
+// WebhookDeliveryStatus is the state of one event sent to one webhook
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed" // Gave up after the maximum attempts
+)
+
+// WebhookDelivery is the delivery log of one event to one webhook, with the last response.
+// The outbox event it carries is referenced, not copied
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WebhookID      uuid.UUID             `json:"webhook_id" gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,priority:1"`
+	UserID         uuid.UUID             `json:"user_id" gorm:"type:uuid;not null;index"`
+	EventID        uuid.UUID             `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,priority:2"`
+	EventType      string                `json:"event_type" gorm:"type:varchar(100);not null"`
+	Payload        string                `json:"-" gorm:"type:jsonb;not null;default:'{}'"` // Body sent, the same on every attempt
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_delivery_pending,priority:1"`
+	Attempts       int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at" gorm:"not null;index:idx_webhook_delivery_pending,priority:2"`
+	ResponseStatus *int                  `json:"response_status,omitempty"`
+	ResponseBody   *string               `json:"response_body,omitempty"` // Truncated
+	LastError      *string               `json:"last_error,omitempty"`
+	DurationMs     *int64                `json:"duration_ms,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+
+	// Relaciones
+	Webhook Webhook `json:"-" gorm:"foreignKey:WebhookID;references:ID;constraint:OnDelete:CASCADE"`
+}
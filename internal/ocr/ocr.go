@@ -0,0 +1,150 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when no OCR provider is set up
+var ErrNotConfigured = errors.New("receipt scanning is not configured")
+
+// Provider reads the text of an image
+type Provider interface {
+	Name() string
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+// FromEnv builds the provider selected by OCR_PROVIDER: "tesseract" runs the local binary,
+// "google-vision" calls the Cloud Vision API. Without it ErrNotConfigured is returned
+func FromEnv() (Provider, error) {
+	switch os.Getenv("OCR_PROVIDER") {
+	case "":
+		return nil, ErrNotConfigured
+	case "tesseract":
+		return NewTesseractProvider(os.Getenv("TESSERACT_PATH"), os.Getenv("TESSERACT_LANGUAGES"))
+	case "google-vision":
+		return NewVisionProvider(os.Getenv("GOOGLE_VISION_API_KEY"))
+	default:
+		return nil, errors.New("invalid OCR_PROVIDER: must be tesseract or google-vision")
+	}
+}
+
+// === TESSERACT ===
+
+// TesseractProvider runs the tesseract command line on the server
+type TesseractProvider struct {
+	path      string
+	languages string
+}
+
+// NewTesseractProvider finds the tesseract binary; path defaults to the one on PATH and
+// languages (e.g. "eng+spa") to eng
+func NewTesseractProvider(path, languages string) (*TesseractProvider, error) {
+	if path == "" {
+		path = "tesseract"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w", err)
+	}
+	if languages == "" {
+		languages = "eng"
+	}
+	return &TesseractProvider{path: resolved, languages: languages}, nil
+}
+
+func (p *TesseractProvider) Name() string { return "tesseract" }
+
+// Recognize pipes the image through tesseract; page segmentation mode 4 reads receipts as a
+// single column of lines
+func (p *TesseractProvider) Recognize(ctx context.Context, image []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, p.path, "stdin", "stdout", "-l", p.languages, "--psm", "4")
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// === GOOGLE CLOUD VISION ===
+
+// visionEndpoint is the images:annotate method of the Cloud Vision API
+const visionEndpoint = "https://vision.googleapis.com/v1/images:annotate"
+
+// VisionProvider reads images with Google Cloud Vision document text detection
+type VisionProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewVisionProvider checks the API key and returns the provider
+func NewVisionProvider(apiKey string) (*VisionProvider, error) {
+	if apiKey == "" {
+		return nil, errors.New("Google Vision needs GOOGLE_VISION_API_KEY")
+	}
+	return &VisionProvider{apiKey: apiKey, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *VisionProvider) Name() string { return "google-vision" }
+
+// Recognize sends the image inline and returns the full text annotation
+func (p *VisionProvider) Recognize(ctx context.Context, image []byte) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"requests": []map[string]interface{}{{
+			"image":    map[string]string{"content": base64.StdEncoding.EncodeToString(image)},
+			"features": []map[string]string{{"type": "DOCUMENT_TEXT_DETECTION"}},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, visionEndpoint+"?key="+url.QueryEscape(p.apiKey), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Google Vision returned %d: %s", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Responses []struct {
+			FullTextAnnotation struct {
+				Text string `json:"text"`
+			} `json:"fullTextAnnotation"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"responses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Responses) == 0 {
+		return "", nil
+	}
+	if result.Responses[0].Error != nil {
+		return "", errors.New("Google Vision: " + result.Responses[0].Error.Message)
+	}
+	return result.Responses[0].FullTextAnnotation.Text, nil
+}
@@ -0,0 +1,229 @@
+package ocr
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// merchantSearchLines is how far from the top of a receipt the merchant name is looked for
+const merchantSearchLines = 6
+
+// Receipt is what could be read from a receipt; fields are nil when not found
+type Receipt struct {
+	Merchant *string
+	Date     *time.Time
+	Total    *models.Money
+	Lines    []string // Non-empty lines of the text, top to bottom
+}
+
+var (
+	// Amounts with two decimals, with either separator and optional thousands groups
+	amountPattern       = regexp.MustCompile(`(\d{1,3}(?:[,.' ]\d{3})+|\d+)\s?[.,]\s?(\d{2})\b`)
+	isoDatePattern      = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	numericDatePattern  = regexp.MustCompile(`\b(\d{1,2})[-/.](\d{1,2})[-/.](\d{4}|\d{2})\b`)
+	dayMonthDatePattern = regexp.MustCompile(`(?i)\b(\d{1,2})(?:\s+de)?[\s\-/]+([a-záéíóú]{3,10})\.?(?:\s+de)?[\s\-/,]+(\d{4})\b`)
+	monthDayDatePattern = regexp.MustCompile(`(?i)\b([a-z]{3,9})\.?\s+(\d{1,2}),?\s+(\d{4})\b`)
+)
+
+// totalKeywords mark the line of the amount paid; subtotals and change are left out
+var totalKeywords = []string{"TOTAL", "IMPORTE", "A PAGAR", "AMOUNT DUE", "BALANCE DUE", "MONTANT", "SUMME"}
+
+var notTotalKeywords = []string{"SUBTOTAL", "SUB TOTAL", "SUB-TOTAL", "TOTAL ITEMS", "ARTICULOS", "ARTÍCULOS", "CAMBIO", "CHANGE"}
+
+// notMerchantKeywords mark header lines that aren't the store name
+var notMerchantKeywords = []string{"TICKET", "FACTURA", "RECEIPT", "RECIBO", "RFC", "NIF", "CIF", "TEL", "WWW", "HTTP", "@", "CAJA", "CAJERO", "FOLIO", "SUCURSAL"}
+
+// monthNames maps English and Spanish month names and their abbreviations
+var monthNames = map[string]time.Month{
+	"jan": time.January, "january": time.January, "ene": time.January, "enero": time.January,
+	"feb": time.February, "february": time.February, "febrero": time.February,
+	"mar": time.March, "march": time.March, "marzo": time.March,
+	"apr": time.April, "april": time.April, "abr": time.April, "abril": time.April,
+	"may": time.May, "mayo": time.May,
+	"jun": time.June, "june": time.June, "junio": time.June,
+	"jul": time.July, "july": time.July, "julio": time.July,
+	"aug": time.August, "august": time.August, "ago": time.August, "agosto": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September, "septiembre": time.September, "set": time.September,
+	"oct": time.October, "october": time.October, "octubre": time.October,
+	"nov": time.November, "november": time.November, "noviembre": time.November,
+	"dec": time.December, "december": time.December, "dic": time.December, "diciembre": time.December,
+}
+
+// ParseReceipt finds the merchant, date and total in the text of a receipt. Numeric dates are
+// read day first (15/01/2024) unless monthFirst is set; dates where only one order is valid are
+// read that way regardless
+func ParseReceipt(text string, monthFirst bool) Receipt {
+	var receipt Receipt
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			receipt.Lines = append(receipt.Lines, line)
+		}
+	}
+
+	receipt.Merchant = findMerchant(receipt.Lines)
+	for _, line := range receipt.Lines {
+		if date := parseDate(line, monthFirst); date != nil {
+			receipt.Date = date
+			break
+		}
+	}
+	receipt.Total = findTotal(receipt.Lines)
+	return receipt
+}
+
+// findMerchant takes the first header line that is mostly letters
+func findMerchant(lines []string) *string {
+	for i, line := range lines {
+		if i >= merchantSearchLines {
+			break
+		}
+		upper := strings.ToUpper(line)
+		if containsAny(upper, notMerchantKeywords) {
+			continue
+		}
+		letters, others := 0, 0
+		for _, r := range line {
+			switch {
+			case unicode.IsLetter(r):
+				letters++
+			case !unicode.IsSpace(r) && r != '&' && r != '\'' && r != '.' && r != '-':
+				others++
+			}
+		}
+		if letters >= 3 && letters > others*2 {
+			merchant := strings.Join(strings.Fields(line), " ")
+			if len(merchant) > 100 {
+				merchant = merchant[:100]
+			}
+			return &merchant
+		}
+	}
+	return nil
+}
+
+// findTotal takes the largest amount on a total line (or the line after it, for receipts that
+// print the amount below the label); without one, the largest amount on the receipt
+func findTotal(lines []string) *models.Money {
+	var best *models.Money
+	keep := func(amount *models.Money) {
+		if amount != nil && *amount > 0 && (best == nil || *amount > *best) {
+			best = amount
+		}
+	}
+
+	for i, line := range lines {
+		upper := strings.ToUpper(line)
+		if !containsAny(upper, totalKeywords) || containsAny(upper, notTotalKeywords) {
+			continue
+		}
+		amount := lastAmount(line)
+		if amount == nil && i+1 < len(lines) {
+			amount = lastAmount(lines[i+1])
+		}
+		keep(amount)
+	}
+	if best != nil {
+		return best
+	}
+
+	for _, line := range lines {
+		if containsAny(strings.ToUpper(line), notTotalKeywords) {
+			continue
+		}
+		for _, match := range amountPattern.FindAllStringSubmatch(line, -1) {
+			keep(parseAmount(match[1], match[2]))
+		}
+	}
+	return best
+}
+
+func lastAmount(line string) *models.Money {
+	matches := amountPattern.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	match := matches[len(matches)-1]
+	return parseAmount(match[1], match[2])
+}
+
+// parseAmount drops the thousands separators of the whole part
+func parseAmount(whole, cents string) *models.Money {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, whole)
+	amount, err := models.ParseMoney(digits + "." + cents)
+	if err != nil {
+		return nil
+	}
+	return &amount
+}
+
+func parseDate(line string, monthFirst bool) *time.Time {
+	if match := isoDatePattern.FindStringSubmatch(line); match != nil {
+		if date := makeDate(atoi(match[1]), atoi(match[2]), atoi(match[3])); date != nil {
+			return date
+		}
+	}
+	if match := numericDatePattern.FindStringSubmatch(line); match != nil {
+		first, second, year := atoi(match[1]), atoi(match[2]), atoi(match[3])
+		if year < 100 {
+			year += 2000
+		}
+		day, month := first, second
+		if monthFirst && first <= 12 || second > 12 {
+			day, month = second, first
+		}
+		if date := makeDate(year, month, day); date != nil {
+			return date
+		}
+	}
+	if match := dayMonthDatePattern.FindStringSubmatch(line); match != nil {
+		if month, ok := monthNames[strings.ToLower(match[2])]; ok {
+			if date := makeDate(atoi(match[3]), int(month), atoi(match[1])); date != nil {
+				return date
+			}
+		}
+	}
+	if match := monthDayDatePattern.FindStringSubmatch(line); match != nil {
+		if month, ok := monthNames[strings.ToLower(match[1])]; ok {
+			if date := makeDate(atoi(match[3]), int(month), atoi(match[2])); date != nil {
+				return date
+			}
+		}
+	}
+	return nil
+}
+
+// makeDate rejects impossible dates instead of letting time.Date normalize them
+func makeDate(year, month, day int) *time.Time {
+	if year < 2000 || month < 1 || month > 12 || day < 1 {
+		return nil
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return nil
+	}
+	return &date
+}
+
+func atoi(text string) int {
+	n, _ := strconv.Atoi(text)
+	return n
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package openbanking
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+var (
+	// ErrNotConfigured is returned when no open-banking provider is set up
+	ErrNotConfigured = errors.New("open banking is not configured")
+	// ErrLoginRequired is returned when the user must log in to their bank again through Link
+	ErrLoginRequired = errors.New("bank login required")
+)
+
+// LinkToken starts the provider's bank login flow (e.g. Plaid Link) on the client
+type LinkToken struct {
+	Token      string
+	Expiration time.Time
+}
+
+// Item is a bank login of a user at the provider
+type Item struct {
+	ID          string
+	AccessToken string // Secret; keep it sealed
+}
+
+// Account is a bank account reachable through an item
+type Account struct {
+	ID   string
+	Name string
+	Mask *string // Last digits of the account number
+	Type *string // depository, credit, loan...
+}
+
+// Transaction is a bank transaction. Positive amounts are money leaving the account, negative
+// ones money coming in
+type Transaction struct {
+	ID           string
+	AccountID    string
+	Amount       models.Money
+	Date         time.Time
+	Description  string
+	MerchantName *string
+	Pending      bool
+}
+
+// SyncPage is one page of changes since a cursor
+type SyncPage struct {
+	Added      []Transaction
+	Modified   []Transaction
+	Removed    []string // IDs of transactions the bank dropped
+	NextCursor string
+	HasMore    bool
+}
+
+// Webhook kinds a provider notification is reduced to
+const (
+	WebhookSyncAvailable = "sync_available" // New or changed transactions can be pulled
+	WebhookLoginRequired = "login_required" // The user must log in again
+	WebhookOther         = "other"
+)
+
+// WebhookEvent is a provider notification about an item
+type WebhookEvent struct {
+	Kind   string
+	ItemID string
+}
+
+// Provider connects bank logins and pulls their transactions
+type Provider interface {
+	Name() string
+	CreateLinkToken(ctx context.Context, clientUserID string) (*LinkToken, error)
+	ExchangePublicToken(ctx context.Context, publicToken string) (*Item, error)
+	Accounts(ctx context.Context, accessToken string) ([]Account, error)
+	// SyncTransactions returns the changes after cursor; an empty cursor starts from the beginning
+	SyncTransactions(ctx context.Context, accessToken string, cursor string) (*SyncPage, error)
+	RemoveItem(ctx context.Context, accessToken string) error
+	// ParseWebhook reads a notification body. Notifications are only hints to sync: nothing in
+	// them is trusted beyond the item ID
+	ParseWebhook(body []byte) (*WebhookEvent, error)
+}
+
+// FromEnv builds the provider selected by OPEN_BANKING_PROVIDER; only "plaid" is supported.
+// Without it ErrNotConfigured is returned
+func FromEnv() (Provider, error) {
+	switch os.Getenv("OPEN_BANKING_PROVIDER") {
+	case "":
+		return nil, ErrNotConfigured
+	case "plaid":
+		return NewPlaidProvider(PlaidConfig{
+			ClientID:    os.Getenv("PLAID_CLIENT_ID"),
+			Secret:      os.Getenv("PLAID_SECRET"),
+			Environment: os.Getenv("PLAID_ENV"),
+			WebhookURL:  os.Getenv("OPEN_BANKING_WEBHOOK_URL"),
+			ClientName:  os.Getenv("OPEN_BANKING_CLIENT_NAME"),
+			Countries:   strings.Split(os.Getenv("PLAID_COUNTRY_CODES"), ","),
+		})
+	default:
+		return nil, errors.New("invalid OPEN_BANKING_PROVIDER: must be plaid")
+	}
+}
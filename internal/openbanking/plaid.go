@@ -0,0 +1,270 @@
+package openbanking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// plaidHosts are the API hosts of each Plaid environment
+var plaidHosts = map[string]string{
+	"sandbox":     "https://sandbox.plaid.com",
+	"development": "https://development.plaid.com",
+	"production":  "https://production.plaid.com",
+}
+
+// plaidSyncPageSize is how many transactions a /transactions/sync call returns at most
+const plaidSyncPageSize = 500
+
+// PlaidConfig holds the Plaid API credentials
+type PlaidConfig struct {
+	ClientID    string
+	Secret      string
+	Environment string   // sandbox (the default), development or production
+	WebhookURL  string   // Public URL of /api/v1/open-banking/webhook; empty disables webhooks
+	ClientName  string   // Shown to the user in Link; defaults to Fluxio
+	Countries   []string // Country codes of the institutions offered in Link; defaults to US
+}
+
+// PlaidProvider talks to the Plaid API
+type PlaidProvider struct {
+	config PlaidConfig
+	host   string
+	client *http.Client
+}
+
+// NewPlaidProvider checks the configuration and returns the provider
+func NewPlaidProvider(config PlaidConfig) (*PlaidProvider, error) {
+	if config.ClientID == "" || config.Secret == "" {
+		return nil, errors.New("Plaid needs PLAID_CLIENT_ID and PLAID_SECRET")
+	}
+	if config.Environment == "" {
+		config.Environment = "sandbox"
+	}
+	host, ok := plaidHosts[config.Environment]
+	if !ok {
+		return nil, errors.New("invalid PLAID_ENV: must be sandbox, development or production")
+	}
+	if config.ClientName == "" {
+		config.ClientName = "Fluxio"
+	}
+	countries := make([]string, 0, len(config.Countries))
+	for _, country := range config.Countries {
+		if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) == 0 {
+		countries = []string{"US"}
+	}
+	config.Countries = countries
+
+	return &PlaidProvider{
+		config: config,
+		host:   host,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (p *PlaidProvider) Name() string { return "plaid" }
+
+// plaidError is the body of a failed Plaid call
+type plaidError struct {
+	ErrorType    string `json:"error_type"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// call posts body, with the credentials added, to a Plaid endpoint and decodes the answer into out
+func (p *PlaidProvider) call(ctx context.Context, endpoint string, body map[string]interface{}, out interface{}) error {
+	body["client_id"] = p.config.ClientID
+	body["secret"] = p.config.Secret
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure plaidError
+		json.NewDecoder(resp.Body).Decode(&failure)
+		if failure.ErrorCode == "ITEM_LOGIN_REQUIRED" {
+			return ErrLoginRequired
+		}
+		return fmt.Errorf("plaid %s returned %d: %s %s", endpoint, resp.StatusCode, failure.ErrorCode, failure.ErrorMessage)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (p *PlaidProvider) CreateLinkToken(ctx context.Context, clientUserID string) (*LinkToken, error) {
+	body := map[string]interface{}{
+		"client_name":   p.config.ClientName,
+		"language":      "en",
+		"country_codes": p.config.Countries,
+		"user":          map[string]string{"client_user_id": clientUserID},
+		"products":      []string{"transactions"},
+	}
+	if p.config.WebhookURL != "" {
+		body["webhook"] = p.config.WebhookURL
+	}
+
+	var out struct {
+		LinkToken  string    `json:"link_token"`
+		Expiration time.Time `json:"expiration"`
+	}
+	if err := p.call(ctx, "/link/token/create", body, &out); err != nil {
+		return nil, err
+	}
+	return &LinkToken{Token: out.LinkToken, Expiration: out.Expiration}, nil
+}
+
+func (p *PlaidProvider) ExchangePublicToken(ctx context.Context, publicToken string) (*Item, error) {
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ItemID      string `json:"item_id"`
+	}
+	if err := p.call(ctx, "/item/public_token/exchange", map[string]interface{}{"public_token": publicToken}, &out); err != nil {
+		return nil, err
+	}
+	return &Item{ID: out.ItemID, AccessToken: out.AccessToken}, nil
+}
+
+func (p *PlaidProvider) Accounts(ctx context.Context, accessToken string) ([]Account, error) {
+	var out struct {
+		Accounts []struct {
+			AccountID string  `json:"account_id"`
+			Name      string  `json:"name"`
+			Mask      *string `json:"mask"`
+			Type      *string `json:"type"`
+		} `json:"accounts"`
+	}
+	if err := p.call(ctx, "/accounts/get", map[string]interface{}{"access_token": accessToken}, &out); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]Account, len(out.Accounts))
+	for i, account := range out.Accounts {
+		accounts[i] = Account{ID: account.AccountID, Name: account.Name, Mask: account.Mask, Type: account.Type}
+	}
+	return accounts, nil
+}
+
+// plaidTransaction is a transaction as /transactions/sync returns it
+type plaidTransaction struct {
+	TransactionID string       `json:"transaction_id"`
+	AccountID     string       `json:"account_id"`
+	Amount        models.Money `json:"amount"`
+	Date          string       `json:"date"`
+	Name          string       `json:"name"`
+	MerchantName  *string      `json:"merchant_name"`
+	Pending       bool         `json:"pending"`
+}
+
+func (t plaidTransaction) transaction() (Transaction, error) {
+	date, err := time.Parse("2006-01-02", t.Date)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("plaid transaction %s has an invalid date %q", t.TransactionID, t.Date)
+	}
+	return Transaction{
+		ID:           t.TransactionID,
+		AccountID:    t.AccountID,
+		Amount:       t.Amount,
+		Date:         date,
+		Description:  t.Name,
+		MerchantName: t.MerchantName,
+		Pending:      t.Pending,
+	}, nil
+}
+
+func convertPlaidTransactions(items []plaidTransaction) ([]Transaction, error) {
+	transactions := make([]Transaction, len(items))
+	for i, item := range items {
+		transaction, err := item.transaction()
+		if err != nil {
+			return nil, err
+		}
+		transactions[i] = transaction
+	}
+	return transactions, nil
+}
+
+func (p *PlaidProvider) SyncTransactions(ctx context.Context, accessToken string, cursor string) (*SyncPage, error) {
+	body := map[string]interface{}{"access_token": accessToken, "count": plaidSyncPageSize}
+	if cursor != "" {
+		body["cursor"] = cursor
+	}
+
+	var out struct {
+		Added    []plaidTransaction `json:"added"`
+		Modified []plaidTransaction `json:"modified"`
+		Removed  []struct {
+			TransactionID string `json:"transaction_id"`
+		} `json:"removed"`
+		NextCursor string `json:"next_cursor"`
+		HasMore    bool   `json:"has_more"`
+	}
+	if err := p.call(ctx, "/transactions/sync", body, &out); err != nil {
+		return nil, err
+	}
+
+	page := &SyncPage{NextCursor: out.NextCursor, HasMore: out.HasMore, Removed: make([]string, len(out.Removed))}
+	var err error
+	if page.Added, err = convertPlaidTransactions(out.Added); err != nil {
+		return nil, err
+	}
+	if page.Modified, err = convertPlaidTransactions(out.Modified); err != nil {
+		return nil, err
+	}
+	for i, removed := range out.Removed {
+		page.Removed[i] = removed.TransactionID
+	}
+	return page, nil
+}
+
+func (p *PlaidProvider) RemoveItem(ctx context.Context, accessToken string) error {
+	var out struct{}
+	return p.call(ctx, "/item/remove", map[string]interface{}{"access_token": accessToken}, &out)
+}
+
+func (p *PlaidProvider) ParseWebhook(body []byte) (*WebhookEvent, error) {
+	var notification struct {
+		WebhookType string      `json:"webhook_type"`
+		WebhookCode string      `json:"webhook_code"`
+		ItemID      string      `json:"item_id"`
+		Error       *plaidError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil || notification.ItemID == "" {
+		return nil, errors.New("invalid webhook: item_id is required")
+	}
+
+	event := &WebhookEvent{Kind: WebhookOther, ItemID: notification.ItemID}
+	switch {
+	case notification.WebhookType == "TRANSACTIONS" &&
+		(notification.WebhookCode == "SYNC_UPDATES_AVAILABLE" || notification.WebhookCode == "DEFAULT_UPDATE" ||
+			notification.WebhookCode == "INITIAL_UPDATE" || notification.WebhookCode == "HISTORICAL_UPDATE" ||
+			notification.WebhookCode == "TRANSACTIONS_REMOVED"):
+		event.Kind = WebhookSyncAvailable
+	case notification.WebhookType == "ITEM" &&
+		(notification.WebhookCode == "PENDING_EXPIRATION" ||
+			(notification.WebhookCode == "ERROR" && notification.Error != nil && notification.Error.ErrorCode == "ITEM_LOGIN_REQUIRED")):
+		event.Kind = WebhookLoginRequired
+	}
+	return event, nil
+}
@@ -0,0 +1,64 @@
+package openbanking
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// TokenCipher seals provider access tokens before they are stored, with AES-256-GCM
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher takes a base64-encoded 32-byte key
+func NewTokenCipher(key string) (*TokenCipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("invalid OPEN_BANKING_TOKEN_KEY: must be 32 bytes, base64-encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// TokenCipherFromEnv builds the cipher from OPEN_BANKING_TOKEN_KEY
+func TokenCipherFromEnv() (*TokenCipher, error) {
+	key := os.Getenv("OPEN_BANKING_TOKEN_KEY")
+	if key == "" {
+		return nil, errors.New("open banking needs OPEN_BANKING_TOKEN_KEY to store access tokens")
+	}
+	return NewTokenCipher(key)
+}
+
+// Seal encrypts a token; the nonce is kept in front of the ciphertext
+func (c *TokenCipher) Seal(token string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+// Open decrypts a token sealed by Seal
+func (c *TokenCipher) Open(sealed string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", errors.New("invalid sealed token")
+	}
+	nonce, ciphertext := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("invalid sealed token")
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,113 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Platforms a push subscription can belong to
+const (
+	PlatformFCM     = "fcm"     // Android and iOS apps through Firebase Cloud Messaging
+	PlatformWebPush = "webpush" // Browsers through the Web Push protocol
+)
+
+var (
+	// ErrNotConfigured is returned when the platform of a subscription has no credentials set up
+	ErrNotConfigured = errors.New("push is not configured")
+	// ErrSubscriptionGone is returned when the device unsubscribed or the token expired; the
+	// subscription should be removed
+	ErrSubscriptionGone = errors.New("push subscription is gone")
+)
+
+// Subscription is where a device receives pushes
+type Subscription struct {
+	Platform string
+	Token    string // FCM registration token, or the Web Push endpoint URL
+	P256dh   string // Web Push only: the browser's public key, base64url
+	Auth     string // Web Push only: the browser's auth secret, base64url
+}
+
+// Message is the notification shown on the device
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string // Handed to the app, e.g. the delivery ID to acknowledge
+	TTL   time.Duration     // How long the push service keeps it for an offline device
+}
+
+// Sender delivers pushes on one platform
+type Sender interface {
+	Send(ctx context.Context, sub Subscription, msg Message) error
+}
+
+// Dispatcher routes each push to the sender of its platform
+type Dispatcher struct {
+	fcm     *FCMSender
+	webPush *WebPushSender
+}
+
+// FromEnv builds the senders whose credentials are set: FCM_SERVICE_ACCOUNT_FILE for FCM, and
+// VAPID_PUBLIC_KEY, VAPID_PRIVATE_KEY and VAPID_SUBJECT for Web Push, sending with client.
+// Without either ErrNotConfigured is returned
+func FromEnv(client *http.Client) (*Dispatcher, error) {
+	dispatcher := &Dispatcher{}
+
+	if file := os.Getenv("FCM_SERVICE_ACCOUNT_FILE"); file != "" {
+		credentials, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if dispatcher.fcm, err = NewFCMSender(credentials, client); err != nil {
+			return nil, err
+		}
+	}
+	if os.Getenv("VAPID_PRIVATE_KEY") != "" {
+		var err error
+		dispatcher.webPush, err = NewWebPushSender(VAPIDConfig{
+			PublicKey:  os.Getenv("VAPID_PUBLIC_KEY"),
+			PrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+			Subject:    os.Getenv("VAPID_SUBJECT"),
+		}, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dispatcher.fcm == nil && dispatcher.webPush == nil {
+		return nil, ErrNotConfigured
+	}
+	return dispatcher, nil
+}
+
+// Supports reports whether pushes can be sent to the platform
+func (d *Dispatcher) Supports(platform string) bool {
+	switch platform {
+	case PlatformFCM:
+		return d.fcm != nil
+	case PlatformWebPush:
+		return d.webPush != nil
+	}
+	return false
+}
+
+// VAPIDPublicKey is the application server key browsers subscribe with; empty without Web Push
+func (d *Dispatcher) VAPIDPublicKey() string {
+	if d.webPush == nil {
+		return ""
+	}
+	return d.webPush.config.PublicKey
+}
+
+// Send delivers the message to the subscription
+func (d *Dispatcher) Send(ctx context.Context, sub Subscription, msg Message) error {
+	switch {
+	case sub.Platform == PlatformFCM && d.fcm != nil:
+		return d.fcm.Send(ctx, sub, msg)
+	case sub.Platform == PlatformWebPush && d.webPush != nil:
+		return d.webPush.Send(ctx, sub, msg)
+	}
+	return ErrNotConfigured
+}
@@ -0,0 +1,243 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// webPushRecordSize is the aes128gcm record size; a notification always fits in one record
+const webPushRecordSize = 4096
+
+// webPushMaxPayload is what push services accept at least once encrypted
+const webPushMaxPayload = 3993
+
+// webPushHosts are the push services browsers subscribe with: Chrome (FCM), Firefox (Mozilla
+// autopush), Safari (Apple) and Edge (WNS). Endpoints on any other host are refused
+var webPushHosts = []string{
+	"fcm.googleapis.com",
+	"push.services.mozilla.com",
+	"push.apple.com",
+	"notify.windows.com",
+}
+
+// ValidateWebPushEndpoint requires an https endpoint on the default port of a known push service
+func ValidateWebPushEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || (parsed.Port() != "" && parsed.Port() != "443") {
+		return errors.New("webpush endpoint must be an https URL")
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, known := range webPushHosts {
+		if host == known || strings.HasSuffix(host, "."+known) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webpush endpoint host %s is not a known push service", host)
+}
+
+// VAPIDConfig identifies this server to the push services (RFC 8292)
+type VAPIDConfig struct {
+	PublicKey  string // Uncompressed P-256 point, base64url; given to browsers to subscribe
+	PrivateKey string // Raw 32-byte P-256 scalar, base64url
+	Subject    string // mailto: or https: contact of the operator
+}
+
+// WebPushSender sends encrypted pushes to browser push services (RFC 8030 and RFC 8291)
+type WebPushSender struct {
+	config VAPIDConfig
+	key    *ecdsa.PrivateKey
+	client *http.Client
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers hand out both
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// NewWebPushSender checks the VAPID key pair and returns the sender
+func NewWebPushSender(config VAPIDConfig, client *http.Client) (*WebPushSender, error) {
+	if config.Subject == "" {
+		return nil, errors.New("Web Push needs VAPID_SUBJECT")
+	}
+	raw, err := decodeBase64URL(config.PrivateKey)
+	if err != nil {
+		return nil, errors.New("invalid VAPID_PRIVATE_KEY: must be base64url")
+	}
+	private, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	public := private.PublicKey().Bytes()
+	encoded := base64.RawURLEncoding.EncodeToString(public)
+	// The public key follows from the private one; when given it must match
+	if config.PublicKey != "" && strings.TrimRight(config.PublicKey, "=") != encoded {
+		return nil, errors.New("invalid VAPID_PUBLIC_KEY: it doesn't match VAPID_PRIVATE_KEY")
+	}
+	config.PublicKey = encoded
+
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:65]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return &WebPushSender{config: config, key: key, client: client}, nil
+}
+
+// vapidAuthorization signs the VAPID token for the push service hosting the endpoint
+func (s *WebPushSender) vapidAuthorization(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return "", errors.New("invalid push endpoint")
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": parsed.Scheme + "://" + parsed.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.config.Subject,
+	}).SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + s.config.PublicKey, nil
+}
+
+// encryptWebPush encrypts the payload for the browser with aes128gcm (RFC 8291)
+func encryptWebPush(payload []byte, p256dh, authSecret string) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, errors.New("invalid push subscription key")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, errors.New("invalid push subscription key")
+	}
+	auth, err := decodeBase64URL(authSecret)
+	if err != nil || len(auth) == 0 {
+		return nil, errors.New("invalid push subscription auth secret")
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublicBytes...), asPublic...)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, auth, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key ID length and the key ID, which is our public key
+	body := make([]byte, 0, 16+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, webPushRecordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	// A single record ends with the 0x02 delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// Send delivers the message to a browser push endpoint
+func (s *WebPushSender) Send(ctx context.Context, sub Subscription, msg Message) error {
+	// Subscriptions stored before endpoints were checked are dropped rather than posted to
+	if err := ValidateWebPushEndpoint(sub.Token); err != nil {
+		return fmt.Errorf("%w: %v", ErrSubscriptionGone, err)
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"title": msg.Title,
+		"body":  msg.Body,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+	if len(payload) > webPushMaxPayload {
+		return errors.New("push payload too large")
+	}
+	body, err := encryptWebPush(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.vapidAuthorization(sub.Token)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(int(msg.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("Web Push failed with status %d: %s", resp.StatusCode, respBody)
+}
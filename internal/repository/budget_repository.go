@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetRepository loads and stores the monthly budgets of users
+type BudgetRepository interface {
+	// FindByID returns the budget of the user with its category lines, or gorm.ErrRecordNotFound
+	FindByID(userID, id string, statuses []models.Status) (*models.Budget, error)
+	// FindByMonth returns the budget of the month with its category lines, or gorm.ErrRecordNotFound
+	FindByMonth(userID string, month time.Time, statuses []models.Status) (*models.Budget, error)
+	// ExistsForMonth reports whether the user has a visible budget for the month besides excludeID
+	ExistsForMonth(userID string, month time.Time, excludeID *uuid.UUID) (bool, error)
+	// UpdateStatus moves the budget to the status
+	UpdateStatus(budget *models.Budget, status models.Status, changedAt time.Time) error
+}
+
+// PreloadCategoryBudgets loads the category lines of budgets with their category
+func PreloadCategoryBudgets(query *gorm.DB) *gorm.DB {
+	return query.Preload("CategoryBudgets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).Preload("CategoryBudgets.Category")
+}
+
+// gormBudgetRepository is the BudgetRepository of a GORM database
+type gormBudgetRepository struct {
+	db *gorm.DB
+}
+
+// NewBudgetRepository returns the budget repository of the database
+func NewBudgetRepository(database *gorm.DB) BudgetRepository {
+	return &gormBudgetRepository{db: database}
+}
+
+func (r *gormBudgetRepository) FindByID(userID, id string, statuses []models.Status) (*models.Budget, error) {
+	return r.find(withStatuses(r.db.Where("user_id = ? AND id = ?", userID, id), statuses))
+}
+
+func (r *gormBudgetRepository) FindByMonth(userID string, month time.Time, statuses []models.Status) (*models.Budget, error) {
+	return r.find(withStatuses(r.db.Where("user_id = ? AND month_year = ?", userID, models.MonthStart(month)), statuses))
+}
+
+func (r *gormBudgetRepository) find(query *gorm.DB) (*models.Budget, error) {
+	var budget models.Budget
+	if err := query.Scopes(PreloadCategoryBudgets).First(&budget).Error; err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *gormBudgetRepository) ExistsForMonth(userID string, month time.Time, excludeID *uuid.UUID) (bool, error) {
+	query := r.db.Model(&models.Budget{}).
+		Where("user_id = ? AND month_year = ? AND status IN ?", userID, models.MonthStart(month), models.GetVisibleStatuses())
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *gormBudgetRepository) UpdateStatus(budget *models.Budget, status models.Status, changedAt time.Time) error {
+	return r.db.Model(budget).Omit("CategoryBudgets").Updates(map[string]interface{}{
+		"status":            status,
+		"status_changed_at": &changedAt,
+		"version":           gorm.Expr("version + 1"),
+	}).Error
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"gorm.io/gorm"
+)
+
+// ExpenseRepository loads and stores the expenses of users
+type ExpenseRepository interface {
+	// FindByID returns the expense of the user with its relationships, or gorm.ErrRecordNotFound
+	FindByID(userID, id string, statuses []models.Status) (*models.Expense, error)
+	// ListByDateRange returns the expenses dated between start and end, newest first
+	ListByDateRange(userID string, start, end time.Time, statuses []models.Status) ([]models.Expense, error)
+	// ListByCategory returns the expenses of a category, newest first
+	ListByCategory(userID, categoryID string, statuses []models.Status) ([]models.Expense, error)
+	// ListByBankAccount returns the expenses paid (even partly) from the account, newest first
+	ListByBankAccount(userID, bankAccountID string, statuses []models.Status) ([]models.Expense, error)
+	// UpdateStatus moves the expense to the status; balances are the caller's business
+	UpdateStatus(expense *models.Expense, status models.Status, changedAt time.Time) error
+	// Delete removes the expense for good and returns whether it existed
+	Delete(userID, id string) (bool, error)
+}
+
+// PreloadExpenseRelations loads what expense responses need
+func PreloadExpenseRelations(query *gorm.DB) *gorm.DB {
+	return query.Preload("Category").Preload("BankAccount").Preload("Allocations").Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
+
+// gormExpenseRepository is the ExpenseRepository of a GORM database
+type gormExpenseRepository struct {
+	db *gorm.DB
+}
+
+// NewExpenseRepository returns the expense repository of the database
+func NewExpenseRepository(database *gorm.DB) ExpenseRepository {
+	return &gormExpenseRepository{db: database}
+}
+
+func (r *gormExpenseRepository) FindByID(userID, id string, statuses []models.Status) (*models.Expense, error) {
+	var expense models.Expense
+	query := withStatuses(r.db.Where("user_id = ? AND id = ?", userID, id), statuses)
+	if err := query.Scopes(PreloadExpenseRelations).First(&expense).Error; err != nil {
+		return nil, err
+	}
+	return &expense, nil
+}
+
+func (r *gormExpenseRepository) ListByDateRange(userID string, start, end time.Time, statuses []models.Status) ([]models.Expense, error) {
+	return r.list(withStatuses(r.db.Where("user_id = ? AND date BETWEEN ? AND ?", userID, start, end), statuses))
+}
+
+func (r *gormExpenseRepository) ListByCategory(userID, categoryID string, statuses []models.Status) ([]models.Expense, error) {
+	return r.list(withStatuses(r.db.Where("user_id = ? AND category_id = ?", userID, categoryID), statuses))
+}
+
+func (r *gormExpenseRepository) ListByBankAccount(userID, bankAccountID string, statuses []models.Status) ([]models.Expense, error) {
+	query := r.db.Where("user_id = ?", userID).
+		Where("bank_account_id = ? OR id IN (?)", bankAccountID,
+			r.db.Model(&models.ExpenseAllocation{}).Select("expense_id").Where("bank_account_id = ?", bankAccountID))
+	return r.list(withStatuses(query, statuses))
+}
+
+func (r *gormExpenseRepository) list(query *gorm.DB) ([]models.Expense, error) {
+	var expenses []models.Expense
+	if err := query.Scopes(PreloadExpenseRelations).Order("date DESC, created_at DESC").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (r *gormExpenseRepository) UpdateStatus(expense *models.Expense, status models.Status, changedAt time.Time) error {
+	return r.db.Model(expense).Updates(map[string]interface{}{
+		"status":            status,
+		"status_changed_at": &changedAt,
+	}).Error
+}
+
+func (r *gormExpenseRepository) Delete(userID, id string) (bool, error) {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Expense{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,17 @@
+// Package repository loads and stores the aggregates of the domain (expenses, budgets, …)
+// behind interfaces, so services can run against GORM on Postgres or against a test double.
+// Queries take the statuses to match; nil matches every status
+package repository
+
+import (
+	"github.com/Osminalx/fluxio/internal/models"
+	"gorm.io/gorm"
+)
+
+// withStatuses limits the query to the statuses, unless they are nil
+func withStatuses(query *gorm.DB, statuses []models.Status) *gorm.DB {
+	if statuses == nil {
+		return query
+	}
+	return query.Where("status IN ?", statuses)
+}
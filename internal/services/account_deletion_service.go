@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidPassword is returned when the password confirming an account change is wrong
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrNoAccountDeletion is returned when cancelling a deletion that wasn't scheduled
+var ErrNoAccountDeletion = errors.New("no account deletion is scheduled")
+
+// accountDeletionGraceDays is how long a deleted account can still be restored
+// (ACCOUNT_DELETION_GRACE_DAYS, default 30)
+func accountDeletionGraceDays() int {
+	return envInt("ACCOUNT_DELETION_GRACE_DAYS", 30)
+}
+
+// ScheduleAccountDeletion confirms the password and schedules the erasure of the account after
+// the grace period. Every session and API key is revoked right away; logging in again and
+// restoring the account cancels the deletion. Asking again keeps the first date
+func (s *Services) ScheduleAccountDeletion(userID string, password string) (*dto.AccountDeletion, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !CheckPassword(password, user.Password) {
+		return nil, ErrInvalidPassword
+	}
+
+	graceDays := accountDeletionGraceDays()
+	scheduledFor := time.Now().AddDate(0, 0, graceDays)
+	if user.DeletionScheduledAt != nil {
+		scheduledFor = *user.DeletionScheduledAt
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("deletion_scheduled_at", scheduledFor).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		logger.Error("Error scheduling deletion of user %s: %v", userID, err)
+		return nil, errors.New("error scheduling account deletion")
+	}
+	if err := s.NewRefreshTokenService().RevokeAllUserRefreshTokens(user.ID); err != nil {
+		logger.Error("Error revoking sessions of user %s: %v", userID, err)
+		return nil, errors.New("error scheduling account deletion")
+	}
+
+	s.RecordAudit(user.ID, "user.deletion_scheduled", "user", &user.ID, map[string]interface{}{"scheduled_for": scheduledFor.Format(time.RFC3339)})
+	logger.Info("Deletion of user %s scheduled for %s", userID, scheduledFor.Format(time.RFC3339))
+
+	return &dto.AccountDeletion{ScheduledFor: scheduledFor.Format(time.RFC3339), GraceDays: graceDays}, nil
+}
+
+// CancelAccountDeletion keeps an account whose deletion is still in its grace period. Revoked
+// API keys stay revoked
+func (s *Services) CancelAccountDeletion(userID string) error {
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		logger.Error("Error cancelling deletion of user %s: %v", userID, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoAccountDeletion
+	}
+
+	uid := uuid.MustParse(userID)
+	s.RecordAudit(uid, "user.deletion_cancelled", "user", &uid, nil)
+	logger.Info("Deletion of user %s cancelled", userID)
+	return nil
+}
+
+// dependentUsers returns the users that only exist through the user: its sandbox tenants and
+// its sub-profiles
+func dependentUsers(tx *gorm.DB, uid uuid.UUID) (tenants []uuid.UUID, subProfiles []uuid.UUID, err error) {
+	if err := tx.Model(&models.User{}).Where("sandbox_owner_id = ?", uid).Pluck("id", &tenants).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Model(&models.SubProfile{}).Where("parent_id = ?", uid).Pluck("user_id", &subProfiles).Error; err != nil {
+		return nil, nil, err
+	}
+	return tenants, subProfiles, nil
+}
+
+// eraseUser deletes every row of the user, and of its sandbox tenants and sub-profiles, in the
+// caller's transaction. The audit chain keeps its entries, which only hold the now meaningless
+// user ID
+func eraseUser(tx *gorm.DB, uid uuid.UUID, now time.Time) error {
+	tenants, subProfiles, err := dependentUsers(tx, uid)
+	if err != nil {
+		return err
+	}
+	for _, dependent := range append(tenants, subProfiles...) {
+		if err := eraseUser(tx, dependent, now); err != nil {
+			return err
+		}
+	}
+
+	if err := deletePersonalRecords(tx, uid, now); err != nil {
+		return err
+	}
+	if err := deleteFinancialRecords(tx, uid); err != nil {
+		return err
+	}
+	return tx.Where("id = ?", uid).Delete(&models.User{}).Error
+}
+
+// EraseDueAccounts erases the accounts whose deletion grace period is over, each in its own
+// transaction. Receipt files go after the rows; a failure there only leaves orphan files
+func (s *Services) EraseDueAccounts() error {
+	var users []models.User
+	if err := s.db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		logger.Error("Error listing accounts to erase: %v", err)
+		return err
+	}
+
+	for _, user := range users {
+		tenants, subProfiles, err := dependentUsers(s.db, user.ID)
+		if err != nil {
+			logger.Error("Error listing the users depending on user %s: %v", user.ID, err)
+			return err
+		}
+		erased := append([]uuid.UUID{user.ID}, append(tenants, subProfiles...)...)
+		var attachmentKeys []string
+		if err := s.db.Model(&models.ExpenseAttachment{}).Where("user_id IN ?", erased).
+			Pluck("storage_key", &attachmentKeys).Error; err != nil {
+			logger.Error("Error listing attachments of user %s: %v", user.ID, err)
+			return err
+		}
+
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return eraseUser(tx, user.ID, time.Now())
+		}); err != nil {
+			logger.Error("Error erasing user %s: %v", user.ID, err)
+			return err
+		}
+		for _, id := range erased {
+			forgetUserSessionStates(id)
+		}
+		// Tokens the sub-profiles still hold are turned away instead of passing as full accounts
+		for _, id := range subProfiles {
+			setSubProfileAccess(id.String(), subProfileAccess{isSubProfile: true, active: false})
+		}
+		s.RecordAudit(user.ID, "user.erased", "user", &user.ID, nil)
+		logger.Info("User %s erased after the deletion grace period", user.ID)
+
+		if len(attachmentKeys) == 0 {
+			continue
+		}
+		store, err := getAttachmentStorage()
+		if err != nil {
+			continue
+		}
+		for _, key := range attachmentKeys {
+			if err := store.Delete(context.Background(), key); err != nil {
+				logger.Warn("Error deleting attachment %s of erased user %s: %v", key, user.ID, err)
+			}
+		}
+	}
+	return nil
+}
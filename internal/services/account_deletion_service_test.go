@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/auth"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestErasingParentErasesSubProfiles(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	parent := h.CreateUser(t)
+	child, err := h.Services.CreateSubProfile(parent.ID.String(), services.SubProfileInput{
+		Name:     "Kid",
+		Email:    uuid.NewString() + "@example.com",
+		Password: "a-long-test-password",
+	})
+	if err != nil {
+		t.Fatalf("creating sub-profile: %v", err)
+	}
+
+	if err := h.DB.Model(&models.User{}).Where("id = ?", parent.ID).
+		Update("deletion_scheduled_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("scheduling deletion: %v", err)
+	}
+	if err := h.Services.EraseDueAccounts(); err != nil {
+		t.Fatalf("erasing due accounts: %v", err)
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.User{}).Where("id IN ?", []string{parent.ID.String(), child.UserID}).Count(&count).Error; err != nil {
+		t.Fatalf("counting users: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d of the parent and sub-profile users are left after erasing the parent", count)
+	}
+
+	// A token the sub-profile still holds must not reach routes closed to sub-profiles
+	reached := false
+	handler := auth.SubProfileMiddleware(h.Services, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/budgets", nil)
+	request = request.WithContext(context.WithValue(request.Context(), "userID", child.UserID))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if reached || recorder.Code < http.StatusBadRequest {
+		t.Fatalf("sub-profile of an erased parent got %d on a restricted route", recorder.Code)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrAccountGroupExists = errors.New("an account group with this name already exists")
+
+// getAccountGroup returns a group of the user with its members
+func (s *Services) getAccountGroup(userID string, groupID string) (*models.AccountGroup, error) {
+	var group models.AccountGroup
+	if err := s.db.Preload("Members").Where("id = ? AND user_id = ?", groupID, userID).First(&group).Error; err != nil {
+		return nil, errors.New("account group not found")
+	}
+	return &group, nil
+}
+
+// accountGroupNameTaken reports whether the user already has another group with the name
+func (s *Services) accountGroupNameTaken(userID string, name string, excludeID *uuid.UUID) (bool, error) {
+	query := s.db.Model(&models.AccountGroup{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// toAccountGroupDTO adds the member list and balance of the active members
+func (s *Services) toAccountGroupDTO(group *models.AccountGroup) (*dto.AccountGroup, error) {
+	result := &dto.AccountGroup{
+		ID:             group.ID.String(),
+		Name:           group.Name,
+		BankAccountIDs: make([]string, 0, len(group.Members)),
+		AccountCount:   len(group.Members),
+		CreatedAt:      group.CreatedAt,
+	}
+	ids := make([]uuid.UUID, 0, len(group.Members))
+	for _, member := range group.Members {
+		result.BankAccountIDs = append(result.BankAccountIDs, member.BankAccountID.String())
+		ids = append(ids, member.BankAccountID)
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	if err := s.db.Model(&models.BankAccount{}).
+		Where("id IN ? AND user_id = ? AND status IN ?", ids, group.UserID, models.GetActiveStatuses()).
+		Select("COALESCE(SUM(balance), 0)").Scan(&result.TotalBalance).Error; err != nil {
+		logger.Error("Error getting account group balance: %v", err)
+		return nil, errors.New("error getting account group")
+	}
+	return result, nil
+}
+
+// CreateAccountGroup creates an empty group of accounts
+func (s *Services) CreateAccountGroup(userID string, name string) (*dto.AccountGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("account group name is required")
+	}
+	taken, err := s.accountGroupNameTaken(userID, name, nil)
+	if err != nil {
+		logger.Error("Error checking account group name: %v", err)
+		return nil, errors.New("error creating account group")
+	}
+	if taken {
+		return nil, ErrAccountGroupExists
+	}
+
+	group := models.AccountGroup{UserID: uuid.MustParse(userID), Name: name}
+	if err := s.db.Create(&group).Error; err != nil {
+		logger.Error("Error creating account group: %v", err)
+		return nil, errors.New("error creating account group")
+	}
+
+	logger.Info("Account group %s created for user %s", group.ID, userID)
+	return s.toAccountGroupDTO(&group)
+}
+
+// GetAccountGroups lists the account groups of the user
+func (s *Services) GetAccountGroups(userID string) ([]dto.AccountGroup, error) {
+	var groups []models.AccountGroup
+	if err := s.db.Preload("Members").Where("user_id = ?", userID).Order("name ASC").Find(&groups).Error; err != nil {
+		logger.Error("Error getting account groups: %v", err)
+		return nil, errors.New("error getting account groups")
+	}
+
+	result := make([]dto.AccountGroup, 0, len(groups))
+	for i := range groups {
+		group, err := s.toAccountGroupDTO(&groups[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *group)
+	}
+	return result, nil
+}
+
+// GetAccountGroup returns a group of the user
+func (s *Services) GetAccountGroup(userID string, groupID string) (*dto.AccountGroup, error) {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toAccountGroupDTO(group)
+}
+
+// RenameAccountGroup changes the name of a group
+func (s *Services) RenameAccountGroup(userID string, groupID string, name string) (*dto.AccountGroup, error) {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("account group name is required")
+	}
+	taken, err := s.accountGroupNameTaken(userID, name, &group.ID)
+	if err != nil {
+		logger.Error("Error checking account group name: %v", err)
+		return nil, errors.New("error updating account group")
+	}
+	if taken {
+		return nil, ErrAccountGroupExists
+	}
+
+	if err := s.db.Model(group).Update("name", name).Error; err != nil {
+		logger.Error("Error renaming account group: %v", err)
+		return nil, errors.New("error updating account group")
+	}
+	return s.toAccountGroupDTO(group)
+}
+
+// DeleteAccountGroup removes a group; the accounts themselves are not touched
+func (s *Services) DeleteAccountGroup(userID string, groupID string) error {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(group).Error; err != nil { // Memberships go with it (ON DELETE CASCADE)
+		logger.Error("Error deleting account group: %v", err)
+		return errors.New("error deleting account group")
+	}
+	logger.Info("Account group %s deleted", groupID)
+	return nil
+}
+
+// AddAccountsToGroup adds bank accounts of the user to a group, ignoring ones already in it
+func (s *Services) AddAccountsToGroup(userID string, groupID string, bankAccountIDs []string) (*dto.AccountGroup, error) {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bankAccountIDs) == 0 {
+		return nil, errors.New("at least one bank account ID is required")
+	}
+
+	ids := make([]uuid.UUID, 0, len(bankAccountIDs))
+	for _, raw := range bankAccountIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("invalid bank account ID: " + raw)
+		}
+		ids = append(ids, id)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.BankAccount{}).Where("id IN ? AND user_id = ? AND status IN ?", ids, userID, models.GetVisibleStatuses()).
+		Count(&count).Error; err != nil {
+		logger.Error("Error checking bank accounts for group: %v", err)
+		return nil, errors.New("error updating account group")
+	}
+	if int(count) != len(uniqueUUIDs(ids)) {
+		return nil, errors.New("bank account not found or access denied")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.AccountGroupMember{GroupID: group.ID, BankAccountID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error adding accounts to group: %v", err)
+		return nil, errors.New("error updating account group")
+	}
+
+	return s.GetAccountGroup(userID, groupID)
+}
+
+// RemoveAccountFromGroup takes a bank account out of a group
+func (s *Services) RemoveAccountFromGroup(userID string, groupID string, bankAccountID string) (*dto.AccountGroup, error) {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.Where("group_id = ? AND bank_account_id = ?", group.ID, bankAccountID).Delete(&models.AccountGroupMember{})
+	if result.Error != nil {
+		logger.Error("Error removing account from group: %v", result.Error)
+		return nil, errors.New("error updating account group")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("bank account not found in group")
+	}
+
+	return s.GetAccountGroup(userID, groupID)
+}
+
+// GetAccountGroupAccountIDs returns the accounts of a group, for endpoints that accept a group filter
+func (s *Services) GetAccountGroupAccountIDs(userID string, groupID string) (map[uuid.UUID]bool, error) {
+	group, err := s.getAccountGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uuid.UUID]bool, len(group.Members))
+	for _, member := range group.Members {
+		ids[member.BankAccountID] = true
+	}
+	return ids, nil
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// filterExpensesByAccounts limits an expense query (aliased as "e") to what was paid from the
+// accounts; split expenses match once per allocation paid from them. nil accounts leaves the
+// query as is. Sum accountsExpenseAmountSQL over it
+func filterExpensesByAccounts(query *gorm.DB, accounts []uuid.UUID) *gorm.DB {
+	if accounts == nil {
+		return query
+	}
+	return query.Joins("LEFT JOIN expense_allocations ea ON ea.expense_id = e.id").
+		Where("COALESCE(ea.bank_account_id, e.bank_account_id) IN ?", accounts)
+}
+
+// accountsExpenseAmountSQL is netExpenseAmountSQL counting only the share of split expenses
+// paid from the accounts of filterExpensesByAccounts
+func accountsExpenseAmountSQL(accounts []uuid.UUID) string {
+	if accounts == nil {
+		return netExpenseAmountSQL()
+	}
+	return netExpenseAmountSQL() + " * " + allocationShareSQL
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminUserFilter narrows the user list of the admin API; zero fields don't filter
+type AdminUserFilter struct {
+	Query  string // Part of the email or name
+	Status string
+	Role   string
+}
+
+// IsAdminUser reports whether the user exists, can access the system and acts as an admin.
+// The admin middleware checks it on every request, so a demoted or locked admin loses
+// access before their token expires
+func (s *Services) IsAdminUser(userID string) bool {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAccessible() && EffectiveRole(user) == models.RoleAdmin
+}
+
+// ListUsers lists the users of the instance, newest first. Sandbox tenants are left out
+func (s *Services) ListUsers(filter AdminUserFilter, page PageRequest) ([]models.User, PageInfo, error) {
+	query := s.db.Model(&models.User{}).Where("is_sandbox = ?", false)
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ?", pattern, pattern)
+	}
+	if filter.Status != "" {
+		if !models.ValidateStatus(models.Status(filter.Status)) {
+			return nil, PageInfo{}, errors.New("invalid status")
+		}
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Role != "" {
+		if !isValidRole(filter.Role) {
+			return nil, PageInfo{}, errors.New("invalid role: use user or admin")
+		}
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	var users []models.User
+	info, err := paginate(query, "created_at DESC, id", page, &users)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			return nil, PageInfo{}, err
+		}
+		logger.Error("Error listing users: %v", err)
+		return nil, PageInfo{}, errors.New("error listing users")
+	}
+	return users, info, nil
+}
+
+// LockUser locks a user account and revokes all its sessions, so the user is logged out
+// everywhere and can't log in until unlocked. Like every admin action, it is audited in the
+// trail of the affected user with the admin in the details
+func (s *Services) LockUser(adminID, userID, reason string) (*models.User, error) {
+	user, err := s.getManagedUser(adminID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == models.StatusLocked {
+		return user, nil
+	}
+	if !user.IsAccessible() {
+		return nil, errors.New("invalid status: only active or pending users can be locked")
+	}
+
+	now := time.Now()
+	previous := user.Status
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"status":     models.StatusLocked,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND is_revoked = ?", user.ID, false).
+			Updates(map[string]interface{}{
+				"is_revoked":     true,
+				"revoked_reason": models.RefreshTokenRevokedLocked,
+				"updated_at":     now,
+			}).Error
+	})
+	if err != nil {
+		logger.Error("Error locking user %s: %v", user.ID, err)
+		return nil, errors.New("error locking user")
+	}
+	forgetUserSessionStates(user.ID)
+
+	details := map[string]interface{}{"previous_status": previous, "admin_id": adminID}
+	if reason = strings.TrimSpace(reason); reason != "" {
+		details["reason"] = reason
+	}
+	s.RecordAudit(user.ID, "admin.user_locked", "user", &user.ID, details)
+	logger.Info("User %s locked by admin %s", user.ID, adminID)
+	return user, nil
+}
+
+// UnlockUser gives a locked user access again; their sessions stay revoked
+func (s *Services) UnlockUser(adminID, userID string) (*models.User, error) {
+	user, err := s.getManagedUser(adminID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != models.StatusLocked {
+		return nil, errors.New("invalid status: user is not locked")
+	}
+
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"status":     models.StatusActive,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		logger.Error("Error unlocking user %s: %v", user.ID, err)
+		return nil, errors.New("error unlocking user")
+	}
+
+	s.RecordAudit(user.ID, "admin.user_unlocked", "user", &user.ID, map[string]interface{}{"admin_id": adminID})
+	logger.Info("User %s unlocked by admin %s", user.ID, adminID)
+	return user, nil
+}
+
+// SetUserRole promotes a user to admin or demotes them. Admins listed in ADMIN_EMAILS stay
+// admins whatever their stored role
+func (s *Services) SetUserRole(adminID, userID, role string) (*models.User, error) {
+	if !isValidRole(role) {
+		return nil, errors.New("invalid role: use user or admin")
+	}
+	user, err := s.getManagedUser(adminID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	previous := user.Role
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		logger.Error("Error changing role of user %s: %v", user.ID, err)
+		return nil, errors.New("error changing user role")
+	}
+
+	s.RecordAudit(user.ID, "admin.user_role_changed", "user", &user.ID, map[string]interface{}{
+		"admin_id":      adminID,
+		"previous_role": previous,
+		"role":          role,
+	})
+	logger.Info("Role of user %s changed from %s to %s by admin %s", user.ID, previous, role, adminID)
+	return user, nil
+}
+
+// GetSystemStats counts users, records and pending background work across the instance
+func (s *Services) GetSystemStats() (*dto.SystemStats, error) {
+	stats := &dto.SystemStats{
+		Users: dto.UserStats{
+			ByStatus: make(map[string]int64),
+			ByRole:   make(map[string]int64),
+		},
+		GeneratedAt: time.Now(),
+	}
+	since := stats.GeneratedAt.AddDate(0, 0, -30)
+	users := func() *gorm.DB {
+		return s.db.Model(&models.User{}).Where("is_sandbox = ?", false)
+	}
+
+	var byStatus []struct {
+		Status string
+		Count  int64
+	}
+	if err := users().Select("status, COUNT(*) AS count").Group("status").Scan(&byStatus).Error; err != nil {
+		logger.Error("Error counting users by status: %v", err)
+		return nil, errors.New("error getting system stats")
+	}
+	for _, row := range byStatus {
+		stats.Users.ByStatus[row.Status] = row.Count
+		stats.Users.Total += row.Count
+	}
+
+	var byRole []struct {
+		Role  string
+		Count int64
+	}
+	if err := users().Select("role, COUNT(*) AS count").Group("role").Scan(&byRole).Error; err != nil {
+		logger.Error("Error counting users by role: %v", err)
+		return nil, errors.New("error getting system stats")
+	}
+	for _, row := range byRole {
+		stats.Users.ByRole[row.Role] = row.Count
+	}
+
+	counts := []struct {
+		query  *gorm.DB
+		target *int64
+	}{
+		{users().Where("created_at >= ?", since), &stats.Users.NewLast30},
+		{users().Where("last_login >= ?", since), &stats.Users.ActiveLast30},
+		{s.db.Model(&models.User{}).Where("is_sandbox = ?", true), &stats.Users.Sandboxes},
+		{s.db.Model(&models.Expense{}).Where("status IN ?", models.GetVisibleStatuses()), &stats.Expenses},
+		{s.db.Model(&models.Income{}).Where("status IN ?", models.GetVisibleStatuses()), &stats.Incomes},
+		{s.db.Model(&models.BankAccount{}).Where("status IN ?", models.GetVisibleStatuses()), &stats.BankAccounts},
+		{s.db.Model(&models.OutboxEvent{}).Where("status = ?", models.OutboxStatusPending), &stats.OutboxPending},
+		{s.db.Model(&models.OutboxEvent{}).Where("status = ?", models.OutboxStatusFailed), &stats.OutboxFailed},
+		{s.db.Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookDeliveryPending), &stats.WebhookDeliveriesPending},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.target).Error; err != nil {
+			logger.Error("Error getting system stats: %v", err)
+			return nil, errors.New("error getting system stats")
+		}
+	}
+	return stats, nil
+}
+
+// getManagedUser loads a user an admin acts on; admins can't act on their own account so
+// they can't lock themselves out
+func (s *Services) getManagedUser(adminID, userID string) (*models.User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	if userID == adminID {
+		return nil, errors.New("invalid user: admins cannot change their own account")
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ? AND is_sandbox = ?", id, false).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		logger.Error("Error getting user %s: %v", id, err)
+		return nil, errors.New("error getting user")
+	}
+	return &user, nil
+}
+
+func isValidRole(role string) bool {
+	for _, valid := range models.ValidRoles() {
+		if role == valid {
+			return true
+		}
+	}
+	return false
+}
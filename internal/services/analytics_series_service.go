@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Metrics an analytics series can chart
+const (
+	SeriesMetricSpend  = "spend"
+	SeriesMetricIncome = "income"
+	SeriesMetricNet    = "net" // Income minus spend
+)
+
+// Bin widths of an analytics series
+const (
+	SeriesIntervalDay   = "day"
+	SeriesIntervalWeek  = "week"
+	SeriesIntervalMonth = "month"
+)
+
+// MaxSeriesBins bounds how many points a series has, e.g. about 2.7 years of days
+const MaxSeriesBins = 1000
+
+// seriesRow is what was spent or earned on one day, for one group when grouped
+type seriesRow struct {
+	Date   time.Time
+	Key    string
+	Name   string
+	Amount models.Money
+}
+
+// seriesBinStart returns the first day of the bin containing date. Weeks and months start on
+// the days of the user's calendar
+func seriesBinStart(date time.Time, interval string, calendar userCalendar) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case SeriesIntervalWeek:
+		return calendar.weekStart(day)
+	case SeriesIntervalMonth:
+		return calendar.monthStart(day)
+	}
+	return day
+}
+
+// nextSeriesBin returns the start of the bin after the one starting at start
+func nextSeriesBin(start time.Time, interval string) time.Time {
+	switch interval {
+	case SeriesIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case SeriesIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// defaultSeriesFrom is where a series starts when no from is given: 30 days, 12 weeks or 12 months
+func defaultSeriesFrom(to time.Time, interval string, calendar userCalendar) time.Time {
+	switch interval {
+	case SeriesIntervalWeek:
+		return seriesBinStart(to, interval, calendar).AddDate(0, 0, -7*11)
+	case SeriesIntervalMonth:
+		return seriesBinStart(to, interval, calendar).AddDate(0, -11, 0)
+	}
+	return to.AddDate(0, 0, -29)
+}
+
+// spendSeriesRows returns the spend of each day in the range net of refunds, per group when
+// groupBy is set
+func (s *Services) spendSeriesRows(ctx context.Context, userID string, from, to time.Time, groupBy string) ([]seriesRow, error) {
+	var rows []seriesRow
+	if groupBy == "" {
+		result := s.summaryPeriodQuery(userID, from, to).WithContext(ctx).
+			Select("e.date as date, COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) as amount").
+			Group("e.date").
+			Scan(&rows)
+		return rows, result.Error
+	}
+
+	return s.groupedSpendRows(ctx, userID, from, to, summaryGroupings[SummaryGroupBy(groupBy)])
+}
+
+// groupedSpendRows returns the spend of each day in the range net of refunds, per group of the
+// grouping
+func (s *Services) groupedSpendRows(ctx context.Context, userID string, from, to time.Time, grouping summaryGrouping) ([]seriesRow, error) {
+	var rows []seriesRow
+	amount := netExpenseAmountSQL()
+	if grouping.share != "" {
+		amount += " * " + grouping.share
+	}
+	query := s.summaryPeriodQuery(userID, from, to).WithContext(ctx).
+		Select(strings.Join([]string{
+			"e.date as date",
+			grouping.key + " as key",
+			grouping.name + " as name",
+			"COALESCE(SUM(" + amount + "), 0) as amount",
+		}, ", "))
+	for _, join := range grouping.joins {
+		query = query.Joins(join)
+	}
+	result := query.Group("e.date, " + grouping.groupBy).Scan(&rows)
+	return rows, result.Error
+}
+
+// incomeSeriesRows returns the income of each day in the range. Refunds are left out when they
+// already reduce spending
+func (s *Services) incomeSeriesRows(ctx context.Context, userID string, from, to time.Time) ([]seriesRow, error) {
+	var rows []seriesRow
+	query := s.db.WithContext(ctx).Model(&models.Income{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, from, to, models.GetActiveStatuses())
+	if refundsNettedInOriginalMonth() {
+		query = query.Where("refund_of_expense_id IS NULL")
+	}
+	result := query.Select("date, COALESCE(SUM(amount), 0) as amount").Group("date").Scan(&rows)
+	return rows, result.Error
+}
+
+// GetAnalyticsSeries bins a metric evenly from from to to, both optional, filling bins without
+// activity with zero. Spend can be grouped by any summary dimension, one line per group
+func (s *Services) GetAnalyticsSeries(ctx context.Context, userID, metric, interval, groupBy string, from, to *time.Time) (*dto.AnalyticsSeries, error) {
+	switch metric {
+	case SeriesMetricSpend, SeriesMetricIncome, SeriesMetricNet:
+	default:
+		return nil, errors.New("invalid metric: use spend, income or net")
+	}
+	switch interval {
+	case SeriesIntervalDay, SeriesIntervalWeek, SeriesIntervalMonth:
+	default:
+		return nil, errors.New("invalid interval: use day, week or month")
+	}
+	if groupBy != "" {
+		if !IsValidSummaryGroupBy(groupBy) {
+			return nil, errors.New("invalid group_by: use category, account, payee or tag")
+		}
+		if metric != SeriesMetricSpend {
+			return nil, errors.New("invalid group_by: only the spend metric can be grouped")
+		}
+	}
+
+	calendar := s.getUserCalendar(userID)
+	var end time.Time
+	if to != nil {
+		end = seriesBinStart(*to, SeriesIntervalDay, calendar)
+	} else {
+		end = seriesBinStart(s.UserNow(userID), SeriesIntervalDay, calendar)
+	}
+	start := defaultSeriesFrom(end, interval, calendar)
+	if from != nil {
+		start = seriesBinStart(*from, SeriesIntervalDay, calendar)
+	}
+	if start.After(end) {
+		return nil, errors.New("invalid range: from is after to")
+	}
+
+	// Bins cover whole days, weeks or months; the first and last may extend past the range
+	series := &dto.AnalyticsSeries{
+		Metric:   metric,
+		Interval: interval,
+		GroupBy:  groupBy,
+		From:     start.Format("2006-01-02"),
+		To:       end.Format("2006-01-02"),
+		Bins:     []string{},
+		Series:   []dto.AnalyticsSeriesLine{},
+	}
+	binIndex := make(map[time.Time]int)
+	for bin := seriesBinStart(start, interval, calendar); !bin.After(end); bin = nextSeriesBin(bin, interval) {
+		if len(series.Bins) == MaxSeriesBins {
+			return nil, fmt.Errorf("invalid range: more than %d %ss, use a longer interval or a shorter range", MaxSeriesBins, interval)
+		}
+		binIndex[bin] = len(series.Bins)
+		series.Bins = append(series.Bins, bin.Format("2006-01-02"))
+	}
+
+	var spend, income []seriesRow
+	var err error
+	if metric != SeriesMetricIncome {
+		if spend, err = s.spendSeriesRows(ctx, userID, start, end, groupBy); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Error getting spend series: %v", err)
+			return nil, errors.New("error calculating analytics series")
+		}
+	}
+	if metric != SeriesMetricSpend {
+		if income, err = s.incomeSeriesRows(ctx, userID, start, end); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Error getting income series: %v", err)
+			return nil, errors.New("error calculating analytics series")
+		}
+	}
+
+	lines := make(map[string]*dto.AnalyticsSeriesLine)
+	add := func(row seriesRow, sign models.Money) {
+		key, name := row.Key, row.Name
+		if groupBy == "" {
+			key, name = "total", "Total"
+		}
+		line, ok := lines[key]
+		if !ok {
+			line = &dto.AnalyticsSeriesLine{Key: key, Name: name, Values: make([]models.Money, len(series.Bins))}
+			lines[key] = line
+		}
+		if i, ok := binIndex[seriesBinStart(row.Date.UTC(), interval, calendar)]; ok {
+			line.Values[i] += sign * row.Amount
+			line.Total += sign * row.Amount
+		}
+	}
+	for _, row := range spend {
+		if metric == SeriesMetricNet {
+			add(row, -1)
+		} else {
+			add(row, 1)
+		}
+	}
+	for _, row := range income {
+		add(row, 1)
+	}
+	// An ungrouped series always has its line, even when nothing happened
+	if groupBy == "" && len(lines) == 0 {
+		lines["total"] = &dto.AnalyticsSeriesLine{Key: "total", Name: "Total", Values: make([]models.Money, len(series.Bins))}
+	}
+
+	currency := s.GetUserCurrency(userID)
+	series.Currency = currency.Code
+	for _, line := range lines {
+		for i := range line.Values {
+			line.Values[i] = currency.RoundMoney(line.Values[i])
+		}
+		line.Total = currency.RoundMoney(line.Total)
+		series.Series = append(series.Series, *line)
+	}
+	// Biggest groups first, so a chart can keep the first few
+	sort.Slice(series.Series, func(i, j int) bool {
+		if series.Series[i].Total != series.Series[j].Total {
+			return series.Series[i].Total > series.Series[j].Total
+		}
+		return series.Series[i].Name < series.Series[j].Name
+	})
+
+	return series, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnonymizeConfirmText must be typed back by the user to confirm the anonymization
+const AnonymizeConfirmText = "ANONYMIZE"
+
+const anonymizationTokenTTL = 15 * time.Minute
+
+// ErrInvalidAnonymizationConfirmation is returned when the confirmation token or text is wrong
+var ErrInvalidAnonymizationConfirmation = errors.New("invalid or expired anonymization confirmation")
+
+// anonymizedTables are the financial tables whose rows are kept (anonymized) or deleted
+var anonymizedTables = []string{
+	"expenses", "incomes", "transfers", "fixed_expenses", "budgets", "goals", "reminders", "bank_accounts", "categories",
+}
+
+// anonymizationSignature signs the user ID and expiry of a confirmation token
+func anonymizationSignature(userID string, expires int64) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("anonymize:" + userID + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PreviewAnonymization returns the records affected by anonymizing the user and a
+// short-lived token to confirm it
+func (s *Services) PreviewAnonymization(userID string) (*dto.AnonymizationPreview, error) {
+	records := make(map[string]int64, len(anonymizedTables))
+	for _, table := range anonymizedTables {
+		var count int64
+		if err := s.db.Table(table).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			logger.Error("Error counting %s for anonymization: %v", table, err)
+			return nil, errors.New("error preparing anonymization")
+		}
+		records[table] = count
+	}
+
+	expires := time.Now().Add(anonymizationTokenTTL)
+	return &dto.AnonymizationPreview{
+		ConfirmationToken: fmt.Sprintf("%d.%s", expires.Unix(), anonymizationSignature(userID, expires.Unix())),
+		ExpiresAt:         expires.Format(time.RFC3339),
+		ConfirmText:       AnonymizeConfirmText,
+		Records:           records,
+	}, nil
+}
+
+// verifyAnonymizationToken checks the signature and expiry of a confirmation token
+func verifyAnonymizationToken(userID, token string) bool {
+	expiresStr, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(anonymizationSignature(userID, expires)))
+}
+
+// AnonymizeUser irreversibly strips the personal data of a user as an alternative to deleting
+// the account: the email becomes a tombstone, names and free-text descriptions are cleared and
+// sessions, API keys and security history are removed. With keepAggregates the financial
+// records stay (amounts, dates and categories only); otherwise they are deleted too.
+func (s *Services) AnonymizeUser(userID string, token string, confirmText string, keepAggregates bool) (*dto.AnonymizationResult, error) {
+	if confirmText != AnonymizeConfirmText || !verifyAnonymizationToken(userID, token) {
+		return nil, ErrInvalidAnonymizationConfirmation
+	}
+
+	// Nobody knows this password, so the account can't be signed into again
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	unusablePassword, err := HashPassword(hex.EncodeToString(random))
+	if err != nil {
+		return nil, err
+	}
+
+	uid := uuid.MustParse(userID)
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", uid).Updates(map[string]interface{}{
+			"email":             "anonymized+" + userID + "@invalid",
+			"name":              "Anonymized user",
+			"password":          unusablePassword,
+			"monthly_income":    nil,
+			"last_login":        nil,
+			"status":            models.StatusDeleted,
+			"analytics_opt_out": true,
+			"updated_at":        now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := deletePersonalRecords(tx, uid, now); err != nil {
+			return err
+		}
+
+		if keepAggregates {
+			return anonymizeFinancialRecords(tx, uid)
+		}
+		return deleteFinancialRecords(tx, uid)
+	})
+	if err != nil {
+		logger.Error("Error anonymizing user %s: %v", userID, err)
+		return nil, errors.New("error anonymizing user")
+	}
+
+	var subProfiles []uuid.UUID
+	if err := s.db.Model(&models.SubProfile{}).Where("parent_id = ?", uid).Pluck("user_id", &subProfiles).Error; err != nil {
+		logger.Warn("Error listing the sub-profiles of anonymized user %s: %v", userID, err)
+	}
+	for _, id := range subProfiles {
+		forgetUserSessionStates(id)
+		setSubProfileAccess(id.String(), subProfileAccess{isSubProfile: true, active: false})
+	}
+
+	s.RecordAudit(uid, "user.anonymized", "user", &uid, map[string]interface{}{"kept_aggregates": keepAggregates})
+	logger.Info("User %s anonymized (kept aggregates: %t)", userID, keepAggregates)
+
+	return &dto.AnonymizationResult{
+		UserID:         userID,
+		KeptAggregates: keepAggregates,
+		AnonymizedAt:   now.Format(time.RFC3339),
+	}, nil
+}
+
+// deletePersonalRecords removes what identifies the user besides the financial records:
+// sessions, credentials, raw imported bank rows and everything that stores IPs, devices or emails
+func deletePersonalRecords(tx *gorm.DB, uid uuid.UUID, now time.Time) error {
+	if err := tx.Exec("DELETE FROM api_key_usage_stats WHERE api_key_id IN (SELECT id FROM api_keys WHERE user_id = ?)", uid).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.RefreshToken{}, &models.APIKey{}, &models.SecurityEvent{}, &models.LoginChallenge{}, &models.UserIdentity{},
+		&models.OutboxEvent{}, &models.NotificationDelivery{}, &models.PushSubscription{}, &models.WebhookDelivery{}, &models.Webhook{}, &models.UserPreferences{},
+		&models.ImportMatch{}, &models.ImportedTransaction{}, &models.ImportBatch{}, &models.BankConnectionAccount{}, &models.BankConnection{},
+		&models.DataQualityReport{}, &models.DashboardState{}, &models.Tag{}, &models.ExpenseApproval{}, &models.SubProfile{},
+	} {
+		if err := tx.Where("user_id = ?", uid).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	// Exports hold copies of the records: drop the queued ones and let the purge job delete the files now
+	if err := tx.Model(&models.DataExport{}).Where("user_id = ? AND status IN ?", uid,
+		[]string{models.DataExportPending, models.DataExportRunning}).Updates(map[string]interface{}{"status": models.DataExportFailed, "error": "the account was closed"}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.DataExport{}).Where("user_id = ? AND status = ?", uid, models.DataExportCompleted).Update("expires_at", now).Error; err != nil {
+		return err
+	}
+	// The sub-profiles are users of their own. They are turned off like removed sub-profiles and
+	// keep their sub-profile rows, so they are still restricted and turned away
+	var subProfiles []uuid.UUID
+	if err := tx.Model(&models.SubProfile{}).Where("parent_id = ?", uid).Pluck("user_id", &subProfiles).Error; err != nil {
+		return err
+	}
+	if len(subProfiles) > 0 {
+		if err := tx.Model(&models.User{}).Where("id IN ?", subProfiles).
+			Updates(map[string]interface{}{"status": models.StatusDeleted, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).Where("user_id IN ?", subProfiles).Update("is_revoked", true).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("parent_id = ?", uid).Delete(&models.ExpenseApproval{}).Error
+}
+
+// anonymizeFinancialRecords clears the free text of the user's records, keeping amounts,
+// dates and categories
+func anonymizeFinancialRecords(tx *gorm.DB, userID uuid.UUID) error {
+	updates := []struct {
+		model   interface{}
+		columns map[string]interface{}
+	}{
+		{&models.Expense{}, map[string]interface{}{"description": nil}},
+		{&models.Income{}, map[string]interface{}{"description": nil}},
+		{&models.Transfer{}, map[string]interface{}{"description": nil}},
+		{&models.Reminder{}, map[string]interface{}{"title": "Reminder", "description": nil}},
+		{&models.FixedExpense{}, map[string]interface{}{"name": "Fixed expense"}},
+		{&models.Goal{}, map[string]interface{}{"name": "Goal"}},
+		{&models.BankAccount{}, map[string]interface{}{"account_name": "Account"}},
+		{&models.GoalMilestone{}, map[string]interface{}{"label": nil}},
+		{&models.Trip{}, map[string]interface{}{"name": "Trip"}},
+	}
+
+	for _, update := range updates {
+		if err := tx.Model(update.model).Where("user_id = ?", userID).Updates(update.columns).Error; err != nil {
+			return err
+		}
+	}
+	// Group names are unique per user, so they can't all get the same placeholder
+	return tx.Exec("UPDATE account_groups SET name = 'Group ' || LEFT(id::text, 8) WHERE user_id = ?", userID).Error
+}
+
+// deleteFinancialRecords removes every financial record of the user, children first, closed
+// months included
+func deleteFinancialRecords(tx *gorm.DB, userID uuid.UUID) error {
+	tx = allowClosedMonths(tx)
+	for _, model := range []interface{}{
+		&models.Income{}, &models.Expense{}, &models.Trip{}, &models.Transfer{}, &models.GoalContribution{}, &models.GoalMilestone{}, &models.Goal{},
+		&models.BudgetRevision{}, &models.BudgetCompliance{}, &models.BudgetClose{}, &models.CategoryBudget{}, &models.Budget{},
+		&models.FixedExpenseOccurrence{}, &models.FixedExpenseRun{}, &models.FixedExpense{}, &models.Reminder{},
+		&models.AccountGroup{}, &models.BankAccount{}, &models.Category{},
+	} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APIKeyPrefix marks bearer tokens that are API keys instead of JWT access tokens
+const APIKeyPrefix = "fx_"
+
+// hashAPIKey returns the stored representation of a raw key
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey creates a key for the user and returns it with the raw key, which is only
+// available at creation time. Without a scope the key gets full access
+func (s *Services) CreateAPIKey(userID string, name string, scope string) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("api key name is required")
+	}
+	switch scope {
+	case "":
+		scope = models.APIKeyScopeFull
+	case models.APIKeyScopeReadOnly, models.APIKeyScopeWriteExpenses, models.APIKeyScopeFull:
+	default:
+		return nil, "", errors.New("invalid scope: use read_only, write_expenses or full")
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		logger.Error("Error generating api key: %v", err)
+		return nil, "", errors.New("error creating api key")
+	}
+	raw := APIKeyPrefix + hex.EncodeToString(bytes)
+
+	key := models.APIKey{
+		UserID:  uuid.MustParse(userID),
+		Name:    name,
+		Prefix:  raw[:len(APIKeyPrefix)+8],
+		KeyHash: hashAPIKey(raw),
+		Scope:   scope,
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		logger.Error("Error creating api key: %v", err)
+		return nil, "", errors.New("error creating api key")
+	}
+
+	s.RecordAudit(key.UserID, "api_key.created", "api_key", &key.ID, map[string]interface{}{"name": key.Name, "scope": key.Scope})
+	return &key, raw, nil
+}
+
+// GetAPIKeys returns every key of the user, including revoked ones
+func (s *Services) GetAPIKeys(userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		logger.Error("Error getting api keys: %v", err)
+		return nil, errors.New("error getting api keys")
+	}
+	return keys, nil
+}
+
+// getAPIKeyByID returns a key of the user
+func (s *Services) getAPIKeyByID(userID string, keyID string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error; err != nil {
+		return nil, errors.New("api key not found")
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey permanently disables a key
+func (s *Services) RevokeAPIKey(userID string, keyID string) error {
+	key, err := s.getAPIKeyByID(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !key.IsActive() {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(key).Updates(map[string]interface{}{"revoked_at": &now, "updated_at": now}).Error; err != nil {
+		logger.Error("Error revoking api key: %v", err)
+		return errors.New("error revoking api key")
+	}
+
+	s.RecordAudit(key.UserID, "api_key.revoked", "api_key", &key.ID, map[string]interface{}{"name": key.Name})
+	return nil
+}
+
+// AuthenticateAPIKey returns the active key matching a raw key
+func (s *Services) AuthenticateAPIKey(raw string) (*models.APIKey, error) {
+	var key models.APIKey
+	// Keys of a locked or deleted account stop working with it
+	err := s.db.Joins("JOIN users ON users.id = api_keys.user_id AND users.status IN ?", models.GetActiveStatuses()).
+		Where("api_keys.key_hash = ? AND api_keys.revoked_at IS NULL", hashAPIKey(raw)).
+		First(&key).Error
+	if err != nil {
+		return nil, errors.New("invalid api key")
+	}
+	return &key, nil
+}
+
+// apiKeyUsageKey identifies one key hitting one endpoint on one day
+type apiKeyUsageKey struct {
+	day      string
+	keyID    string
+	method   string
+	endpoint string
+}
+
+type apiKeyUsageCount struct {
+	count    int64
+	lastUsed time.Time
+}
+
+// apiKeyUsage buffers API key requests in memory, like the usage analytics recorder
+var apiKeyUsage = struct {
+	mu     sync.Mutex
+	counts map[apiKeyUsageKey]*apiKeyUsageCount
+}{counts: make(map[apiKeyUsageKey]*apiKeyUsageCount)}
+
+// RecordAPIKeyUsage buffers a request made with an API key to an endpoint (already normalized)
+func RecordAPIKeyUsage(keyID, method, endpoint string) {
+	if keyID == "" || endpoint == "" {
+		return
+	}
+
+	now := time.Now()
+	key := apiKeyUsageKey{
+		day:      now.UTC().Format("2006-01-02"),
+		keyID:    keyID,
+		method:   method,
+		endpoint: endpoint,
+	}
+
+	apiKeyUsage.mu.Lock()
+	entry, ok := apiKeyUsage.counts[key]
+	if !ok {
+		entry = &apiKeyUsageCount{}
+		apiKeyUsage.counts[key] = entry
+	}
+	entry.count++
+	entry.lastUsed = now
+	apiKeyUsage.mu.Unlock()
+}
+
+// FlushAPIKeyUsage upserts the buffered API key usage and updates each key's last use
+func (s *Services) FlushAPIKeyUsage() error {
+	apiKeyUsage.mu.Lock()
+	counts := apiKeyUsage.counts
+	apiKeyUsage.counts = make(map[apiKeyUsageKey]*apiKeyUsageCount)
+	apiKeyUsage.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	lastUsed := make(map[string]time.Time)
+	for key, entry := range counts {
+		day, _ := time.Parse("2006-01-02", key.day)
+		stat := models.APIKeyUsageStat{
+			APIKeyID:     uuid.MustParse(key.keyID),
+			Day:          day,
+			Method:       key.method,
+			Endpoint:     key.endpoint,
+			RequestCount: entry.count,
+			LastUsedAt:   entry.lastUsed,
+		}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "api_key_id"}, {Name: "day"}, {Name: "method"}, {Name: "endpoint"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"request_count": gorm.Expr("api_key_usage_stats.request_count + EXCLUDED.request_count"),
+				"last_used_at":  gorm.Expr("GREATEST(api_key_usage_stats.last_used_at, EXCLUDED.last_used_at)"),
+				"updated_at":    time.Now(),
+			}),
+		}).Create(&stat).Error; err != nil {
+			return err
+		}
+
+		if entry.lastUsed.After(lastUsed[key.keyID]) {
+			lastUsed[key.keyID] = entry.lastUsed
+		}
+	}
+
+	for keyID, at := range lastUsed {
+		if err := s.db.Model(&models.APIKey{}).
+			Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", keyID, at).
+			Update("last_used_at", at).Error; err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("Flushed api key usage: %d rows", len(counts))
+	return nil
+}
+
+// GetAPIKeyUsage returns the request counts of a key per endpoint and day for a period
+func (s *Services) GetAPIKeyUsage(userID string, keyID string, startDate, endDate time.Time) (*dto.APIKeyUsage, error) {
+	key, err := s.getAPIKeyByID(userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &dto.APIKeyUsage{
+		KeyID:      key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		StartDate:  startDate.Format("2006-01-02"),
+		EndDate:    endDate.Format("2006-01-02"),
+		LastUsedAt: key.LastUsedAt,
+		Endpoints:  []dto.APIKeyEndpointUsage{},
+		Daily:      []dto.APIKeyDailyUsage{},
+	}
+
+	base := func() *gorm.DB {
+		return s.db.Model(&models.APIKeyUsageStat{}).
+			Where("api_key_id = ? AND day BETWEEN ? AND ?", key.ID, startDate, endDate)
+	}
+
+	if err := base().
+		Select("method, endpoint, SUM(request_count) AS request_count, MAX(last_used_at) AS last_used_at").
+		Group("method, endpoint").
+		Order("request_count DESC").
+		Scan(&usage.Endpoints).Error; err != nil {
+		logger.Error("Error getting api key endpoint usage: %v", err)
+		return nil, errors.New("error getting api key usage")
+	}
+
+	if err := base().
+		Select("TO_CHAR(day, 'YYYY-MM-DD') AS day, SUM(request_count) AS request_count").
+		Group("day").
+		Order("day ASC").
+		Scan(&usage.Daily).Error; err != nil {
+		logger.Error("Error getting api key daily usage: %v", err)
+		return nil, errors.New("error getting api key usage")
+	}
+
+	for _, endpoint := range usage.Endpoints {
+		usage.TotalRequests += endpoint.RequestCount
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+
+	cases := []struct {
+		name      string
+		keyName   string
+		scope     string
+		wantScope string
+		wantErr   string
+	}{
+		{"defaults to full access", "Backup script", "", models.APIKeyScopeFull, ""},
+		{"read only", "Dashboard", models.APIKeyScopeReadOnly, models.APIKeyScopeReadOnly, ""},
+		{"write expenses", "Receipt importer", models.APIKeyScopeWriteExpenses, models.APIKeyScopeWriteExpenses, ""},
+		{"unknown scope", "Admin", "admin", "", "invalid scope: use read_only, write_expenses or full"},
+		{"missing name", "  ", models.APIKeyScopeFull, "", "api key name is required"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, raw, err := h.Services.CreateAPIKey(user.ID.String(), tc.keyName, tc.scope)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("creating api key: %v", err)
+			}
+			if key.Scope != tc.wantScope || !strings.HasPrefix(raw, services.APIKeyPrefix) || !strings.HasPrefix(raw, key.Prefix) {
+				t.Errorf("key %s with scope %s, want scope %s and the raw key's prefix", key.Prefix, key.Scope, tc.wantScope)
+			}
+
+			authenticated, err := h.Services.AuthenticateAPIKey(raw)
+			if err != nil {
+				t.Fatalf("authenticating with the new key: %v", err)
+			}
+			if authenticated.ID != key.ID || authenticated.Scope != tc.wantScope {
+				t.Errorf("authenticated as key %s with scope %s, want %s with %s", authenticated.ID, authenticated.Scope, key.ID, tc.wantScope)
+			}
+		})
+	}
+
+	_, raw, err := h.Services.CreateAPIKey(user.ID.String(), "Revoked", models.APIKeyScopeReadOnly)
+	if err != nil {
+		t.Fatalf("creating api key: %v", err)
+	}
+	key, err := h.Services.AuthenticateAPIKey(raw)
+	if err != nil {
+		t.Fatalf("authenticating: %v", err)
+	}
+	if err := h.Services.RevokeAPIKey(user.ID.String(), key.ID.String()); err != nil {
+		t.Fatalf("revoking api key: %v", err)
+	}
+	if _, err := h.Services.AuthenticateAPIKey(raw); err == nil {
+		t.Error("revoked key still authenticates")
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Osminalx/fluxio/docs"
+)
+
+// APISpec is an OpenAPI document ready to serve: the specs never change while the process
+// runs, so the ETag and the compressed body are computed once
+type APISpec struct {
+	Version string
+	Body    []byte
+	Gzipped []byte
+	ETag    string
+}
+
+var (
+	apiSpecsMu sync.Mutex
+	apiSpecs   = make(map[string]*APISpec)
+)
+
+// GetAPISpec returns the OpenAPI document of a version (see docs.SpecVersions)
+func GetAPISpec(version string) (*APISpec, error) {
+	apiSpecsMu.Lock()
+	defer apiSpecsMu.Unlock()
+	if spec, ok := apiSpecs[version]; ok {
+		return spec, nil
+	}
+
+	body, ok := docs.Spec(version)
+	if !ok {
+		return nil, errors.New("spec version not found: " + version)
+	}
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write(body)
+	writer.Close()
+
+	sum := sha256.Sum256(body)
+	spec := &APISpec{
+		Version: version,
+		Body:    body,
+		Gzipped: gzipped.Bytes(),
+		ETag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+	apiSpecs[version] = spec
+	return spec, nil
+}
+
+// specDocument is the part of a Swagger 2.0 document the diff looks at
+type specDocument struct {
+	Paths       map[string]map[string]json.RawMessage `json:"paths"`
+	Definitions map[string]struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	} `json:"definitions"`
+}
+
+type specOperation struct {
+	Parameters []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+// SpecOperationChange lists what changed in an operation present in both versions
+type SpecOperationChange struct {
+	Operation         string   `json:"operation"`                    // e.g. GET /api/v1/expenses
+	AddedParameters   []string `json:"added_parameters,omitempty"`   // <in> <name>, e.g. query limit
+	RemovedParameters []string `json:"removed_parameters,omitempty"` // Breaking for clients sending them
+	AddedResponses    []string `json:"added_responses,omitempty"`    // Status codes
+	RemovedResponses  []string `json:"removed_responses,omitempty"`
+}
+
+// SpecDefinitionChange lists the properties added to or removed from a model
+type SpecDefinitionChange struct {
+	Definition        string   `json:"definition"`
+	AddedProperties   []string `json:"added_properties,omitempty"`
+	RemovedProperties []string `json:"removed_properties,omitempty"` // Breaking for clients reading them
+}
+
+// SpecDiff is what changed between two spec versions
+type SpecDiff struct {
+	From               string                 `json:"from"`
+	To                 string                 `json:"to"`
+	AddedOperations    []string               `json:"added_operations"`
+	RemovedOperations  []string               `json:"removed_operations"`
+	ChangedOperations  []SpecOperationChange  `json:"changed_operations"`
+	AddedDefinitions   []string               `json:"added_definitions"`
+	RemovedDefinitions []string               `json:"removed_definitions"`
+	ChangedDefinitions []SpecDefinitionChange `json:"changed_definitions"`
+	Breaking           bool                   `json:"breaking"` // Something clients may rely on was removed
+}
+
+// DiffAPISpecs compares the operations and models of two spec versions
+func DiffAPISpecs(from, to string) (*SpecDiff, error) {
+	fromDoc, err := loadSpecDocument(from)
+	if err != nil {
+		return nil, err
+	}
+	toDoc, err := loadSpecDocument(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SpecDiff{
+		From:               from,
+		To:                 to,
+		AddedOperations:    []string{},
+		RemovedOperations:  []string{},
+		ChangedOperations:  []SpecOperationChange{},
+		AddedDefinitions:   []string{},
+		RemovedDefinitions: []string{},
+		ChangedDefinitions: []SpecDefinitionChange{},
+	}
+
+	fromOps, toOps := specOperations(fromDoc), specOperations(toDoc)
+	for _, name := range sortedKeys(toOps) {
+		before, ok := fromOps[name]
+		if !ok {
+			diff.AddedOperations = append(diff.AddedOperations, name)
+			continue
+		}
+		after := toOps[name]
+		change := SpecOperationChange{Operation: name}
+		change.AddedParameters, change.RemovedParameters = diffKeys(specParameters(before), specParameters(after))
+		change.AddedResponses, change.RemovedResponses = diffKeys(before.Responses, after.Responses)
+		if len(change.AddedParameters)+len(change.RemovedParameters)+len(change.AddedResponses)+len(change.RemovedResponses) > 0 {
+			diff.ChangedOperations = append(diff.ChangedOperations, change)
+		}
+	}
+	for _, name := range sortedKeys(fromOps) {
+		if _, ok := toOps[name]; !ok {
+			diff.RemovedOperations = append(diff.RemovedOperations, name)
+		}
+	}
+
+	for _, name := range sortedKeys(toDoc.Definitions) {
+		before, ok := fromDoc.Definitions[name]
+		if !ok {
+			diff.AddedDefinitions = append(diff.AddedDefinitions, name)
+			continue
+		}
+		change := SpecDefinitionChange{Definition: name}
+		change.AddedProperties, change.RemovedProperties = diffKeys(before.Properties, toDoc.Definitions[name].Properties)
+		if len(change.AddedProperties)+len(change.RemovedProperties) > 0 {
+			diff.ChangedDefinitions = append(diff.ChangedDefinitions, change)
+		}
+	}
+	for _, name := range sortedKeys(fromDoc.Definitions) {
+		if _, ok := toDoc.Definitions[name]; !ok {
+			diff.RemovedDefinitions = append(diff.RemovedDefinitions, name)
+		}
+	}
+
+	diff.Breaking = len(diff.RemovedOperations) > 0 || len(diff.RemovedDefinitions) > 0
+	for _, change := range diff.ChangedOperations {
+		diff.Breaking = diff.Breaking || len(change.RemovedParameters) > 0
+	}
+	for _, change := range diff.ChangedDefinitions {
+		diff.Breaking = diff.Breaking || len(change.RemovedProperties) > 0
+	}
+	return diff, nil
+}
+
+func loadSpecDocument(version string) (*specDocument, error) {
+	body, ok := docs.Spec(version)
+	if !ok {
+		return nil, errors.New("spec version not found: " + version)
+	}
+	var doc specDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, errors.New("error parsing spec " + version)
+	}
+	return &doc, nil
+}
+
+// specOperations indexes the operations of a document by "METHOD path"
+func specOperations(doc *specDocument) map[string]specOperation {
+	operations := make(map[string]specOperation)
+	for path, methods := range doc.Paths {
+		for method, raw := range methods {
+			if method == "parameters" {
+				continue
+			}
+			var operation specOperation
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				continue
+			}
+			operations[strings.ToUpper(method)+" "+path] = operation
+		}
+	}
+	return operations
+}
+
+func specParameters(operation specOperation) map[string]bool {
+	parameters := make(map[string]bool, len(operation.Parameters))
+	for _, parameter := range operation.Parameters {
+		parameters[parameter.In+" "+parameter.Name] = true
+	}
+	return parameters
+}
+
+// diffKeys returns the keys only in after and the keys only in before, sorted
+func diffKeys[V any, W any](before map[string]V, after map[string]W) (added, removed []string) {
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
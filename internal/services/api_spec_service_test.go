@@ -0,0 +1,48 @@
+package services_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Osminalx/fluxio/docs"
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+// TestAPISpecDiffAgainstRelease diffs the generated spec against the frozen 1.0 release: the
+// operations added since then must show up, and unknown versions are refused
+func TestAPISpecDiffAgainstRelease(t *testing.T) {
+	cases := []struct {
+		name      string
+		from, to  string
+		wantAdded string
+		wantErr   bool
+	}{
+		{name: "release to current", from: "1.0", to: docs.CurrentSpecVersion, wantAdded: "GET /api/v1/transfers"},
+		{name: "same version", from: "1.0", to: "1.0"},
+		{name: "unknown version", from: "0.9", to: docs.CurrentSpecVersion, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff, err := services.DiffAPISpecs(tc.from, tc.to)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("diff of an unknown version succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("diffing: %v", err)
+			}
+			if tc.wantAdded == "" {
+				if len(diff.AddedOperations)+len(diff.RemovedOperations)+len(diff.ChangedOperations) != 0 {
+					t.Errorf("a version differs from itself: %+v", diff)
+				}
+				return
+			}
+			if !slices.Contains(diff.AddedOperations, tc.wantAdded) {
+				t.Errorf("added operations %v don't include %s", diff.AddedOperations, tc.wantAdded)
+			}
+		})
+	}
+}
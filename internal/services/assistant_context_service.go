@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Assistant context sections
+const (
+	AssistantSectionBalances = "balances"
+	AssistantSectionBudgets  = "budgets"
+	AssistantSectionBills    = "bills"
+	AssistantSectionGoals    = "goals"
+)
+
+// Assistant context redactable fields
+const (
+	AssistantRedactNames   = "names"   // Account, category, bill and goal names become generic labels
+	AssistantRedactAmounts = "amounts" // Absolute amounts are dropped, ratios are kept
+)
+
+// AssistantContextOptions controls what the assistant snapshot contains
+type AssistantContextOptions struct {
+	Sections  map[string]bool // Empty means every section
+	Redact    map[string]bool
+	BillsDays int // How far ahead to look for upcoming bills
+}
+
+// AssistantContext is a compact snapshot of the user's finances meant as grounding
+// context for assistant integrations
+type AssistantContext struct {
+	GeneratedAt string             `json:"generated_at"`
+	Redacted    []string           `json:"redacted"`
+	Balances    *AssistantBalances `json:"balances,omitempty"`
+	Budgets     []AssistantBudget  `json:"budgets,omitempty"`
+	Bills       []AssistantBill    `json:"upcoming_bills,omitempty"`
+	Goals       []AssistantGoal    `json:"goals,omitempty"`
+}
+
+type AssistantBalances struct {
+	Total    *models.Money             `json:"total,omitempty"`
+	Accounts []AssistantAccountBalance `json:"accounts"`
+}
+
+type AssistantAccountBalance struct {
+	Name    string        `json:"name"`
+	Balance *models.Money `json:"balance,omitempty"`
+}
+
+type AssistantBudget struct {
+	Category    string        `json:"category"`
+	ExpenseType string        `json:"expense_type"`
+	Mode        string        `json:"mode"`
+	Cap         *models.Money `json:"cap,omitempty"`
+	Spent       *models.Money `json:"spent,omitempty"`
+	Remaining   *models.Money `json:"remaining,omitempty"`
+	UsedPercent float64       `json:"used_percent"`
+}
+
+type AssistantBill struct {
+	Name    string        `json:"name"`
+	DueDate string        `json:"due_date"`
+	Amount  *models.Money `json:"amount,omitempty"`
+}
+
+type AssistantGoal struct {
+	Name            string        `json:"name"`
+	TargetAmount    *models.Money `json:"target_amount,omitempty"`
+	SavedAmount     *models.Money `json:"saved_amount,omitempty"`
+	ProgressPercent float64       `json:"progress_percent"`
+}
+
+// wants reports whether a section was requested
+func (o AssistantContextOptions) wants(section string) bool {
+	return len(o.Sections) == 0 || o.Sections[section]
+}
+
+// amount returns the value, or nil when amounts are redacted
+func (o AssistantContextOptions) amount(value models.Money) *models.Money {
+	if o.Redact[AssistantRedactAmounts] {
+		return nil
+	}
+	return &value
+}
+
+// name returns the real name, or a generic label when names are redacted
+func (o AssistantContextOptions) name(value, label string, index int) string {
+	if o.Redact[AssistantRedactNames] {
+		return fmt.Sprintf("%s %d", label, index+1)
+	}
+	return value
+}
+
+// GetAssistantContext builds the assistant snapshot for the user
+func (s *Services) GetAssistantContext(userID string, opts AssistantContextOptions) (*AssistantContext, error) {
+	now := s.UserNow(userID)
+	snapshot := &AssistantContext{
+		GeneratedAt: now.Format(time.RFC3339),
+		Redacted:    []string{},
+	}
+	for field, enabled := range opts.Redact {
+		if enabled {
+			snapshot.Redacted = append(snapshot.Redacted, field)
+		}
+	}
+
+	if opts.wants(AssistantSectionBalances) {
+		var accounts []models.BankAccount
+		if err := s.activeBankAccountsQuery(userID).Order("created_at DESC").Find(&accounts).Error; err != nil {
+			return nil, err
+		}
+
+		balances := &AssistantBalances{Accounts: make([]AssistantAccountBalance, 0, len(accounts))}
+		var total models.Money
+		for i, account := range accounts {
+			total += account.Balance
+			balances.Accounts = append(balances.Accounts, AssistantAccountBalance{
+				Name:    opts.name(account.AccountName, "Account", i),
+				Balance: opts.amount(account.Balance),
+			})
+		}
+		balances.Total = opts.amount(total)
+		snapshot.Balances = balances
+	}
+
+	if opts.wants(AssistantSectionBudgets) {
+		var categories []models.Category
+		result := s.db.Where("user_id = ? AND status IN ? AND monthly_cap IS NOT NULL", userID, models.GetActiveStatuses()).
+			Order("name ASC").Find(&categories)
+		if result.Error != nil {
+			logger.Error("Error getting capped categories: %v", result.Error)
+			return nil, result.Error
+		}
+
+		snapshot.Budgets = make([]AssistantBudget, 0, len(categories))
+		for i, category := range categories {
+			spent, err := s.getCategoryMonthSpent(userID, category.ID, now)
+			if err != nil {
+				return nil, err
+			}
+
+			remaining := *category.MonthlyCap - spent
+			if remaining < 0 {
+				remaining = 0
+			}
+			snapshot.Budgets = append(snapshot.Budgets, AssistantBudget{
+				Category:    opts.name(category.Name, "Category", i),
+				ExpenseType: string(category.ExpenseType),
+				Mode:        string(category.CapMode),
+				Cap:         opts.amount(*category.MonthlyCap),
+				Spent:       opts.amount(spent),
+				Remaining:   opts.amount(remaining),
+				UsedPercent: percentOf(spent, *category.MonthlyCap),
+			})
+		}
+	}
+
+	if opts.wants(AssistantSectionBills) {
+		var fixedExpenses []models.FixedExpense
+		today := dateOf(now)
+		result := s.db.Where("user_id = ? AND status = ? AND next_due_date BETWEEN ? AND ?",
+			userID, models.StatusActive, today, today.AddDate(0, 0, opts.BillsDays)).
+			Order("next_due_date ASC").Find(&fixedExpenses)
+		if result.Error != nil {
+			logger.Error("Error getting upcoming bills: %v", result.Error)
+			return nil, result.Error
+		}
+
+		snapshot.Bills = make([]AssistantBill, 0, len(fixedExpenses))
+		for i, fixedExpense := range fixedExpenses {
+			snapshot.Bills = append(snapshot.Bills, AssistantBill{
+				Name:    opts.name(fixedExpense.Name, "Bill", i),
+				DueDate: fixedExpense.NextDueDate.Format("2006-01-02"),
+				Amount:  opts.amount(fixedExpense.Amount),
+			})
+		}
+	}
+
+	if opts.wants(AssistantSectionGoals) {
+		goals, err := s.GetGoals(userID, false)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.Goals = make([]AssistantGoal, 0, len(goals))
+		for i, goal := range goals {
+			snapshot.Goals = append(snapshot.Goals, AssistantGoal{
+				Name:            opts.name(goal.Name, "Goal", i),
+				TargetAmount:    opts.amount(goal.TotalAmount),
+				SavedAmount:     opts.amount(goal.SavedAmount),
+				ProgressPercent: percentOf(goal.SavedAmount, goal.TotalAmount),
+			})
+		}
+	}
+
+	logger.Info("Assistant context generated for user %s", userID)
+	return snapshot, nil
+}
+
+// percentOf returns part as a percentage of whole, rounded to one decimal
+func percentOf(part, whole models.Money) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(int(part.Ratio(whole)*1000+0.5)) / 10
+}
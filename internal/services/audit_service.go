@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditChainLockKey is the Postgres advisory lock serializing appends to the audit chain
+const auditChainLockKey = 7461637
+
+// maxAuditChainProblems limits how many problems a verification reports
+const maxAuditChainProblems = 100
+
+// auditVerifyBatchSize is how many entries are checked per query when verifying
+const auditVerifyBatchSize = 1000
+
+// canonicalAuditDetails normalizes the details JSON so the hash doesn't depend on how
+// Postgres stores jsonb (key order, whitespace)
+func canonicalAuditDetails(details string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(details), &value); err != nil {
+		return details
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return details
+	}
+	return string(encoded)
+}
+
+// auditEntryHash hashes an entry together with the hash of the entry before it
+func auditEntryHash(entry *models.AuditLog) string {
+	entityID := ""
+	if entry.EntityID != nil {
+		entityID = entry.EntityID.String()
+	}
+	var sequence int64
+	if entry.Sequence != nil {
+		sequence = *entry.Sequence
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		entry.PrevHash,
+		fmt.Sprint(sequence),
+		entry.ID.String(),
+		entry.UserID.String(),
+		entry.Action,
+		entry.EntityType,
+		entityID,
+		canonicalAuditDetails(entry.Details),
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordAudit stores an audit log entry at the end of the hash chain. Failures are logged
+// but never interrupt the operation being audited.
+func (s *Services) RecordAudit(userID uuid.UUID, action, entityType string, entityID *uuid.UUID, details map[string]interface{}) {
+	payload := "{}"
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			logger.Error("Error encoding audit details for %s: %v", action, err)
+		} else {
+			payload = string(encoded)
+		}
+	}
+
+	entry := models.AuditLog{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Details:    payload,
+		// Postgres keeps microseconds; hash what will be read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditLog
+		result := tx.Where("sequence IS NOT NULL").Order("sequence DESC").Limit(1).Find(&last)
+		if result.Error != nil {
+			return result.Error
+		}
+		sequence := int64(1)
+		if result.RowsAffected > 0 {
+			sequence = *last.Sequence + 1
+			entry.PrevHash = last.Hash
+		}
+		entry.Sequence = &sequence
+		entry.Hash = auditEntryHash(&entry)
+
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		logger.Error("Error recording audit log %s for user %s: %v", action, userID, err)
+	}
+}
+
+// VerifyAuditChain walks the whole audit chain and reports every entry whose hash doesn't
+// match its content or the entry before it, and every gap in the sequence. Removing the
+// latest entries can't be detected from the chain alone, so the last sequence and hash are
+// returned to be compared with a copy kept elsewhere
+func (s *Services) VerifyAuditChain() (*dto.AuditChainVerification, error) {
+	verification := &dto.AuditChainVerification{Valid: true, Problems: []dto.AuditChainProblem{}}
+	report := func(entry *models.AuditLog, issue string) {
+		verification.Valid = false
+		if len(verification.Problems) < maxAuditChainProblems {
+			verification.Problems = append(verification.Problems, dto.AuditChainProblem{
+				Sequence: *entry.Sequence,
+				EntryID:  entry.ID.String(),
+				Issue:    issue,
+			})
+		}
+	}
+
+	if err := s.db.Model(&models.AuditLog{}).Where("sequence IS NULL").Count(&verification.Unchained).Error; err != nil {
+		logger.Error("Error counting unchained audit entries: %v", err)
+		return nil, errors.New("error verifying audit log")
+	}
+
+	var previous *models.AuditLog
+	var after int64
+	for {
+		var entries []models.AuditLog
+		if err := s.db.Where("sequence > ?", after).Order("sequence ASC").Limit(auditVerifyBatchSize).Find(&entries).Error; err != nil {
+			logger.Error("Error reading audit chain: %v", err)
+			return nil, errors.New("error verifying audit log")
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			switch {
+			case previous == nil && *entry.Sequence != 1:
+				report(entry, fmt.Sprintf("chain starts at sequence %d, earlier entries are missing", *entry.Sequence))
+			case previous != nil && *entry.Sequence != *previous.Sequence+1:
+				report(entry, fmt.Sprintf("entries %d to %d are missing", *previous.Sequence+1, *entry.Sequence-1))
+			}
+
+			expectedPrev := ""
+			if previous != nil {
+				expectedPrev = previous.Hash
+			}
+			if entry.PrevHash != expectedPrev {
+				report(entry, "previous hash doesn't match the entry before")
+			}
+			if entry.Hash != auditEntryHash(entry) {
+				report(entry, "hash doesn't match the entry content")
+			}
+
+			previous = entry
+			verification.Checked++
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			break
+		}
+		after = *previous.Sequence
+	}
+
+	if previous != nil {
+		verification.LastSequence = *previous.Sequence
+		verification.LastHash = previous.Hash
+	}
+	if !verification.Valid {
+		logger.Warn("🚨 Audit chain verification failed: %d problems in %d entries", len(verification.Problems), verification.Checked)
+	}
+	return verification, nil
+}
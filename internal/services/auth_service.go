@@ -2,9 +2,10 @@ package services
 
 import (
 	"errors"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -13,8 +14,10 @@ import (
 var jwtSecret = []byte("your-secret-key-change-in-production")
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"` // Refresh token family the token was issued for
 	jwt.RegisteredClaims
 }
 
@@ -26,11 +29,32 @@ type TokenPair struct {
 }
 
 func GenerateToken(user *models.User) (string, error) {
+	return signAccessToken(user.ID.String(), user.Email, EffectiveRole(user), "")
+}
+
+// EffectiveRole returns the role a user acts with: the stored role, or admin when the email
+// is listed in ADMIN_EMAILS (comma separated) so an instance can be bootstrapped
+func EffectiveRole(user *models.User) string {
+	if user.IsAdmin() {
+		return models.RoleAdmin
+	}
+	for _, admin := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, user.Email) {
+			return models.RoleAdmin
+		}
+	}
+	return models.RoleUser
+}
+
+// signAccessToken signs a short-lived access token for the given identity and session
+func signAccessToken(userID, email, role, sessionID string) (string, error) {
 	claims := Claims{
-		UserID: user.ID.String(),
-		Email:  user.Email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)), // Short-lived access token
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(GetSessionConfig().AccessTokenTTL)), // Short-lived access token
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -39,17 +63,52 @@ func GenerateToken(user *models.User) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// GenerateTokenPair creates both access and refresh tokens
-func GenerateTokenPair(user *models.User) (*TokenPair, error) {
-	// Generate access token (short-lived)
-	accessToken, err := GenerateToken(user)
+// RenewAccessTokenIfExpiring returns a fresh access token when the current one is inside
+// the renewal window, or an empty string when no renewal is needed
+func RenewAccessTokenIfExpiring(claims *Claims) (string, error) {
+	config := GetSessionConfig()
+	if !config.SlidingEnabled || claims.ExpiresAt == nil {
+		return "", nil
+	}
+
+	if time.Until(claims.ExpiresAt.Time) > config.AccessRenewWindow {
+		return "", nil
+	}
+
+	return signAccessToken(claims.UserID, claims.Email, claims.Role, claims.SessionID)
+}
+
+// GenerateTokenPair starts a session on the login's device, creating both access and refresh tokens
+func (s *Services) GenerateTokenPair(user *models.User, login LoginContext) (*TokenPair, error) {
+	// Use the new RefreshTokenService to create refresh token
+	refreshTokenService := s.NewRefreshTokenService()
+	refreshTokenModel, err := refreshTokenService.CreateSlidingRefreshToken(user.ID, nil, login)
 	if err != nil {
 		return nil, err
 	}
 
-	// Use the new RefreshTokenService to create refresh token
-	refreshTokenService := NewRefreshTokenService()
-	refreshTokenModel, err := refreshTokenService.CreateRefreshToken(user.ID, 7) // 7 days
+	// Generate access token (short-lived), bound to the session so revoking it rejects the token
+	accessToken, err := signAccessToken(user.ID.String(), user.Email, EffectiveRole(user), refreshTokenModel.FamilyID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshTokenModel.Token,
+		ExpiresIn:    int64(GetSessionConfig().AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// GenerateSlidingTokenPair rotates a refresh token in use for a new token pair, keeping the
+// session and the absolute expiry of the original login
+func (s *Services) GenerateSlidingTokenPair(previousToken string, login LoginContext) (*TokenPair, error) {
+	refreshTokenModel, user, err := s.NewRefreshTokenService().RotateRefreshToken(previousToken, login)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := signAccessToken(user.ID.String(), user.Email, EffectiveRole(user), refreshTokenModel.FamilyID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +116,7 @@ func GenerateTokenPair(user *models.User) (*TokenPair, error) {
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshTokenModel.Token,
-		ExpiresIn:    15 * 60, // 15 minutes in seconds
+		ExpiresIn:    int64(GetSessionConfig().AccessTokenTTL.Seconds()),
 	}, nil
 }
 
@@ -89,18 +148,23 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-func GetUserByEmail(email string) (*models.User, error) {
+// CreateUser stores a new user
+func (s *Services) CreateUser(user *models.User) error {
+	return s.db.Create(user).Error
+}
+
+func (s *Services) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	result := db.DB.Where("email = ?", email).First(&user)
+	result := s.db.Where("email = ?", email).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
-func GetUserByID(userID string) (*models.User, error) {
+func (s *Services) GetUserByID(userID string) (*models.User, error) {
 	var user models.User
-	result := db.DB.Where("id = ?", userID).First(&user)
+	result := s.db.Where("id = ?", userID).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// MaxAvailableBalanceDays caps the window of upcoming commitments
+const MaxAvailableBalanceDays = 365
+
+// GetAvailableBalance returns the booked balance of an account, the fixed expenses already due
+// but not yet processed (pending holds), the ones due within the next days, and what remains
+// available after both
+func (s *Services) GetAvailableBalance(userID string, bankAccountID string, days int) (*dto.AvailableBalance, error) {
+	if days < 0 || days > MaxAvailableBalanceDays {
+		return nil, errors.New("days must be between 0 and 365")
+	}
+
+	account, err := s.GetBankAccountByID(userID, bankAccountID)
+	if err != nil {
+		return nil, errors.New("bank account not found")
+	}
+
+	var fixedExpenses []models.FixedExpense
+	result := s.db.Where("user_id = ? AND bank_account_id = ? AND status = ? AND is_recurring = ?",
+		userID, account.ID, models.StatusActive, true).Find(&fixedExpenses)
+	if result.Error != nil {
+		logger.Error("Error getting fixed expenses for available balance: %v", result.Error)
+		return nil, errors.New("error calculating available balance")
+	}
+
+	today := s.UserToday(userID)
+	windowEnd := today.AddDate(0, 0, days)
+
+	balance := &dto.AvailableBalance{
+		BankAccountID: account.ID.String(),
+		AccountName:   account.AccountName,
+		Days:          days,
+		BookedBalance: account.Balance,
+		Commitments:   []dto.BalanceCommitment{},
+	}
+
+	for _, fixedExpense := range fixedExpenses {
+		if fixedExpense.NextDueDate.IsZero() {
+			continue
+		}
+
+		// Walk the recurrence so long windows include every occurrence
+		due := fixedExpense.NextDueDate
+		for !due.After(windowEnd) {
+			pending := due.Before(today)
+			balance.Commitments = append(balance.Commitments, dto.BalanceCommitment{
+				Kind:     "fixed_expense",
+				SourceID: fixedExpense.ID.String(),
+				Name:     fixedExpense.Name,
+				Amount:   fixedExpense.Amount,
+				DueDate:  due.Format("2006-01-02"),
+				Pending:  pending,
+			})
+			if pending {
+				balance.PendingHolds += fixedExpense.Amount
+			} else {
+				balance.UpcomingCommitments += fixedExpense.Amount
+			}
+
+			// Advance the same way the fixed expense processor does
+			due = calculateNextDueDate(&models.FixedExpense{NextDueDate: due, RecurrenceType: fixedExpense.RecurrenceType})
+		}
+	}
+
+	sort.SliceStable(balance.Commitments, func(i, j int) bool {
+		return balance.Commitments[i].DueDate < balance.Commitments[j].DueDate
+	})
+
+	currency := s.GetUserCurrency(userID)
+	balance.PendingHolds = currency.RoundMoney(balance.PendingHolds)
+	balance.UpcomingCommitments = currency.RoundMoney(balance.UpcomingCommitments)
+	balance.AvailableBalance = currency.RoundMoney(balance.BookedBalance - balance.PendingHolds - balance.UpcomingCommitments)
+
+	return balance, nil
+}
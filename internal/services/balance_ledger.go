@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// adjustAccountBalance adds delta to the balance of an account, unless the user keeps its
+// balance by hand (ManualBalance). The account's version is bumped with it, so an edit based on
+// the old balance loses its optimistic lock. It must run in the transaction writing the record
+// that moves the money, so the balance never drifts from the records
+func adjustAccountBalance(tx *gorm.DB, bankAccountID uuid.UUID, delta models.Money) error {
+	if bankAccountID == uuid.Nil || delta == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.BankAccount{}).Where("id = ? AND manual_balance = ?", bankAccountID, false).
+		Updates(map[string]interface{}{
+			"balance": gorm.Expr("balance + ?", delta),
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		logger.Error("Error updating bank account balance: %v", err)
+		return errors.New("error updating bank account balance")
+	}
+	return nil
+}
+
+// applyIncomeLedger adds the income amount to (sign 1) or takes it back from (sign -1) its
+// account, if it has one
+func applyIncomeLedger(tx *gorm.DB, income *models.Income, sign models.Money) error {
+	if income.BankAccountID == nil {
+		return nil
+	}
+	return adjustAccountBalance(tx, *income.BankAccountID, sign*income.Amount)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// BankAccountInUseError is returned when deleting a bank account that records still use
+// without cascade
+type BankAccountInUseError struct {
+	Impact *dto.BankAccountDeleteImpact
+}
+
+func (e *BankAccountInUseError) Error() string {
+	return fmt.Sprintf("bank account in use by %d records", e.Impact.Total)
+}
+
+// accountDependent selects the records of one kind that use a bank account, @id being the account
+type accountDependent struct {
+	model interface{}
+	where string
+}
+
+// bankAccountDependents are the records archived with a deleted account and brought back when
+// it is restored. Archiving keeps their effect on balances, so history still adds up
+var bankAccountDependents = []accountDependent{
+	{&models.Expense{}, "(bank_account_id = @id OR id IN (SELECT expense_id FROM expense_allocations WHERE bank_account_id = @id))"},
+	{&models.Income{}, "bank_account_id = @id"},
+	{&models.Transfer{}, "(from_account_id = @id OR to_account_id = @id)"},
+	{&models.FixedExpense{}, "bank_account_id = @id"},
+}
+
+func (d accountDependent) query(tx *gorm.DB, account *models.BankAccount) *gorm.DB {
+	return tx.Model(d.model).Where(d.where, map[string]interface{}{"id": account.ID}).
+		Where("user_id = ?", account.UserID)
+}
+
+// bankAccountDeleteImpact counts the visible records that use the account
+func bankAccountDeleteImpact(tx *gorm.DB, account *models.BankAccount) (*dto.BankAccountDeleteImpact, error) {
+	counts := make([]int64, len(bankAccountDependents))
+	for i, dependent := range bankAccountDependents {
+		if err := dependent.query(tx, account).Where("status IN ?", models.GetVisibleStatuses()).
+			Count(&counts[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	impact := &dto.BankAccountDeleteImpact{
+		BankAccountID: account.ID.String(),
+		AccountName:   account.AccountName,
+		Expenses:      counts[0],
+		Incomes:       counts[1],
+		Transfers:     counts[2],
+		FixedExpenses: counts[3],
+	}
+	impact.Total = impact.Expenses + impact.Incomes + impact.Transfers + impact.FixedExpenses
+	impact.RequiresCascade = impact.Total > 0
+	return impact, nil
+}
+
+// GetBankAccountDeleteImpact shows what deleting the account would archive, without deleting it
+func (s *Services) GetBankAccountDeleteImpact(userID string, id string) (*dto.BankAccountDeleteImpact, error) {
+	var account models.BankAccount
+	if err := s.db.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).
+		First(&account).Error; err != nil {
+		return nil, errors.New("bank account not found or already deleted")
+	}
+	impact, err := bankAccountDeleteImpact(s.db, &account)
+	if err != nil {
+		logger.Error("Error getting bank account delete impact: %v", err)
+		return nil, errors.New("error getting bank account delete impact")
+	}
+	return impact, nil
+}
+
+// archiveBankAccountDependents archives the visible records using a deleted account, stamping
+// them with the deletion time and keeping the status each one had so restoring brings it back
+func archiveBankAccountDependents(tx *gorm.DB, account *models.BankAccount, deletedAt time.Time) error {
+	for _, dependent := range bankAccountDependents {
+		// Pending and suspended records are archived too: the account they would post to is gone
+		if err := dependent.query(tx, account).Where("status IN ?", models.GetVisibleStatuses()).
+			Updates(map[string]interface{}{
+				"prior_status":      gorm.Expr("status"),
+				"status":            models.StatusArchived,
+				"status_changed_at": deletedAt,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBankAccountDependents brings back the records archived when the account was deleted,
+// each with the status it had then. Records archived before that status was kept come back active
+func restoreBankAccountDependents(tx *gorm.DB, account *models.BankAccount, restoredAt time.Time) error {
+	if account.Status != models.StatusDeleted || account.StatusChangedAt == nil {
+		return nil
+	}
+	for _, dependent := range bankAccountDependents {
+		if err := dependent.query(tx, account).
+			Where("status = ? AND status_changed_at = ?", models.StatusArchived, *account.StatusChangedAt).
+			Updates(map[string]interface{}{
+				"status":            gorm.Expr("COALESCE(prior_status, ?)", models.StatusActive),
+				"prior_status":      nil,
+				"status_changed_at": restoredAt,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,18 +5,18 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 )
 
-func CreateBankAccount(userID string, bankAccount *models.BankAccount) error {
+func (s *Services) CreateBankAccount(userID string, bankAccount *models.BankAccount) error {
 	// Force the UserID and Status to prevent manipulation
 	bankAccount.UserID = uuid.MustParse(userID)
 	bankAccount.Status = models.StatusActive
 
-	result := db.DB.Create(bankAccount)
+	result := s.db.Create(bankAccount)
 	if result.Error != nil{
 		logger.Error("Error creating bank account: %v", result.Error)
 		return result.Error
@@ -25,9 +25,9 @@ func CreateBankAccount(userID string, bankAccount *models.BankAccount) error {
 	return nil
 }
 
-func GetBankAccountByID(userID string, id string) (*models.BankAccount, error) {
+func (s *Services) GetBankAccountByID(userID string, id string) (*models.BankAccount, error) {
 	var bankAccount models.BankAccount
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&bankAccount)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&bankAccount)
 	if result.Error != nil{
 		logger.Error("Error getting bank account by id: %v", result.Error)
 		return nil, result.Error
@@ -36,52 +36,69 @@ func GetBankAccountByID(userID string, id string) (*models.BankAccount, error) {
 	return &bankAccount, nil
 }
 
-func GetAllBankAccounts(userID string, includeDeleted bool) ([]models.BankAccount, error){
+// GetAllBankAccounts gets a page of the accounts of the user, newest first. accounts limits it
+// to those accounts; nil lists them all
+func (s *Services) GetAllBankAccounts(userID string, includeDeleted bool, accounts []uuid.UUID, page PageRequest) ([]models.BankAccount, PageInfo, error){
 	var bankAccounts []models.BankAccount
-	query := db.DB.Where("user_id = ?", userID)
+	query := s.db.Model(&models.BankAccount{}).Where("user_id = ?", userID)
 	
 	if !includeDeleted {
 		query = query.Where("status IN ?", models.GetVisibleStatuses())
 	}
+	if accounts != nil {
+		query = query.Where("id IN ?", accounts)
+	}
 	
-	result := query.Order("created_at DESC").Find(&bankAccounts)
-	if result.Error != nil{
-		logger.Error("Error getting all bank accounts: %v", result.Error)
-		return nil, result.Error
+	info, err := paginate(query, "created_at DESC, id", page, &bankAccounts)
+	if err != nil{
+		logger.Error("Error getting all bank accounts: %v", err)
+		return nil, PageInfo{}, err
 	}
-	logger.Info("All bank accounts retrieved successfully: %+v", bankAccounts)
-	return bankAccounts, nil
+	return bankAccounts, info, nil
 }
 
-func GetActiveBankAccounts(userID string) ([]models.BankAccount, error){
+// activeBankAccountsQuery selects the active accounts of the user
+func (s *Services) activeBankAccountsQuery(userID string) *gorm.DB {
+	return s.db.Model(&models.BankAccount{}).Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses())
+}
+
+// GetActiveBankAccounts gets a page of the active accounts of the user, newest first. accounts
+// limits it to those accounts; nil lists them all
+func (s *Services) GetActiveBankAccounts(userID string, accounts []uuid.UUID, page PageRequest) ([]models.BankAccount, PageInfo, error){
 	var bankAccounts []models.BankAccount
-	result := db.DB.Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses()).
-		Order("created_at DESC").Find(&bankAccounts)
-	if result.Error != nil{
-		logger.Error("Error getting active bank accounts: %v", result.Error)
-		return nil, result.Error
+	query := s.activeBankAccountsQuery(userID)
+	if accounts != nil {
+		query = query.Where("id IN ?", accounts)
+	}
+
+	info, err := paginate(query, "created_at DESC, id", page, &bankAccounts)
+	if err != nil{
+		logger.Error("Error getting active bank accounts: %v", err)
+		return nil, PageInfo{}, err
 	}
-	logger.Info("Active bank accounts retrieved successfully: %+v", bankAccounts)
-	return bankAccounts, nil
+	return bankAccounts, info, nil
 }
 
-func GetDeletedBankAccounts(userID string) ([]models.BankAccount, error){
+// GetDeletedBankAccounts gets a page of the deleted accounts of the user, last deleted first
+func (s *Services) GetDeletedBankAccounts(userID string, page PageRequest) ([]models.BankAccount, PageInfo, error){
 	var bankAccounts []models.BankAccount
-	result := db.DB.Where("user_id = ? AND status = ?", userID, models.StatusDeleted).
-		Order("status_changed_at DESC").Find(&bankAccounts)
-	if result.Error != nil{
-		logger.Error("Error getting deleted bank accounts: %v", result.Error)
-		return nil, result.Error
+	query := s.db.Model(&models.BankAccount{}).Where("user_id = ? AND status = ?", userID, models.StatusDeleted)
+
+	info, err := paginate(query, "status_changed_at DESC, id", page, &bankAccounts)
+	if err != nil{
+		logger.Error("Error getting deleted bank accounts: %v", err)
+		return nil, PageInfo{}, err
 	}
-	logger.Info("Deleted bank accounts retrieved successfully: %+v", bankAccounts)
-	return bankAccounts, nil
+	return bankAccounts, info, nil
 }
 
-func PatchBankAccount(userID string, id string, bankAccount *models.BankAccount) (*models.BankAccount, error) {
+// PatchBankAccount updates an account of the user. bankAccount.Version must be the version the
+// change is based on; ErrVersionConflict is returned when the account was changed since
+func (s *Services) PatchBankAccount(userID string, id string, bankAccount *models.BankAccount) (*models.BankAccount, error) {
 	var existingAccount models.BankAccount
 	
 	// Check if the account exists, belongs to the user and is not deleted
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingAccount)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingAccount)
 	if result.Error != nil{
 		logger.Error("Bank account not found or doesn't belong to the user: %v", result.Error)
 		return nil, errors.New("bank account not found or access denied")
@@ -96,15 +113,36 @@ func PatchBankAccount(userID string, id string, bankAccount *models.BankAccount)
 	bankAccount.Status = existingAccount.Status
 	bankAccount.StatusChangedAt = existingAccount.StatusChangedAt
 	
-	// Update only if the account belongs to the user
-	result = db.DB.Model(&existingAccount).Where("user_id = ? AND id = ?", userID, id).Updates(bankAccount)
-	if result.Error != nil{
-		logger.Error("Error patching bank account: %v", result.Error)
-		return nil, result.Error
+	// Update only if the account belongs to the user and is still at the version the change is based on
+	wasManualBalance := existingAccount.ManualBalance
+	basedOn := bankAccount.Version
+	bankAccount.Version = basedOn + 1
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&existingAccount).Where("user_id = ? AND id = ? AND version = ?", userID, id, basedOn).Updates(bankAccount)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		// Updates skips false, so turning ManualBalance off needs its own write
+		if bankAccount.ManualBalance != wasManualBalance {
+			return tx.Model(&existingAccount).Update("manual_balance", bankAccount.ManualBalance).Error
+		}
+		return nil
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		logger.Warn("Bank account %s changed since version %d was read", id, basedOn)
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Error patching bank account: %v", err)
+		return nil, err
 	}
 	
 	// Get the updated account
-	result = db.DB.Where("user_id = ? AND id = ?", userID, id).First(&existingAccount)
+	result = s.db.Where("user_id = ? AND id = ?", userID, id).First(&existingAccount)
 	if result.Error != nil{
 		logger.Error("Error retrieving updated bank account: %v", result.Error)
 		return nil, result.Error
@@ -114,54 +152,78 @@ func PatchBankAccount(userID string, id string, bankAccount *models.BankAccount)
 	return &existingAccount, nil
 }
 
-func SoftDeleteBankAccount(userID string, id string) error {
+// SoftDeleteBankAccount deletes an account. While records still use it the deletion is refused
+// with *BankAccountInUseError, unless cascade is set: then they are archived along with it
+func (s *Services) SoftDeleteBankAccount(userID string, id string, cascade bool) error {
 	// Check if the account exists and belongs to the user
 	var existingAccount models.BankAccount
-	result := db.DB.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingAccount)
+	result := s.db.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingAccount)
 	if result.Error != nil {
 		logger.Error("Bank account not found or already deleted: %v", result.Error)
 		return errors.New("bank account not found or already deleted")
 	}
+	if _, err := checkStatusTransition(models.BankAccountStatusMachine, existingAccount.Status, models.StatusDeleted); err != nil {
+		return err
+	}
+	
+	impact, err := bankAccountDeleteImpact(s.db, &existingAccount)
+	if err != nil {
+		logger.Error("Error getting bank account delete impact: %v", err)
+		return err
+	}
+	if impact.RequiresCascade && !cascade {
+		return &BankAccountInUseError{Impact: impact}
+	}
 	
-	// Mark as deleted
+	// Mark as deleted, archiving what still uses the account
 	now := time.Now()
-	result = db.DB.Model(&existingAccount).Updates(map[string]interface{}{
-		"status": models.StatusDeleted,
-		"status_changed_at": &now,
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingAccount).Updates(map[string]interface{}{
+			"status": models.StatusDeleted,
+			"status_changed_at": &now,
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return archiveBankAccountDependents(tx, &existingAccount, now)
 	})
-	
-	if result.Error != nil{
-		logger.Error("Error soft deleting bank account: %v", result.Error)
-		return result.Error
+	if err != nil {
+		logger.Error("Error soft deleting bank account: %v", err)
+		return err
 	}
 	
-	logger.Info("Bank account soft deleted successfully: %s", id)
+	logger.Info("Bank account soft deleted successfully: %s (%d records archived)", id, impact.Total)
 	return nil
 }
 
-func RestoreBankAccount(userID string, id string) (*models.BankAccount, error) {
+func (s *Services) RestoreBankAccount(userID string, id string) (*models.BankAccount, error) {
 	// Check if the account exists, belongs to the user and is in a restorable state (deleted, archived, or locked)
 	var existingAccount models.BankAccount
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, []models.Status{models.StatusDeleted, models.StatusArchived, models.StatusLocked}).First(&existingAccount)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, []models.Status{models.StatusDeleted, models.StatusArchived, models.StatusLocked}).First(&existingAccount)
 	if result.Error != nil {
 		logger.Error("Bank account not found, not restorable, or access denied: %v", result.Error)
 		return nil, errors.New("bank account not found, not restorable, or access denied")
 	}
 	
-	// Restore as active
+	// Restore as active, along with the records archived when it was deleted
 	now := time.Now()
-	result = db.DB.Model(&existingAccount).Updates(map[string]interface{}{
-		"status": models.StatusActive,
-		"status_changed_at": &now,
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := restoreBankAccountDependents(tx, &existingAccount, now); err != nil {
+			return err
+		}
+		return tx.Model(&existingAccount).Updates(map[string]interface{}{
+			"status": models.StatusActive,
+			"status_changed_at": &now,
+			"version": gorm.Expr("version + 1"),
+		}).Error
 	})
-	
-	if result.Error != nil{
-		logger.Error("Error restoring bank account: %v", result.Error)
-		return nil, result.Error
+	if err != nil {
+		logger.Error("Error restoring bank account: %v", err)
+		return nil, err
 	}
 	
 	// Get the updated bank account
-	updatedAccount, err := GetBankAccountByID(userID, id)
+	updatedAccount, err := s.GetBankAccountByID(userID, id)
 	if err != nil {
 		logger.Error("Error retrieving updated bank account: %v", err)
 		return nil, errors.New("error retrieving updated bank account")
@@ -171,7 +233,7 @@ func RestoreBankAccount(userID string, id string) (*models.BankAccount, error) {
 	return updatedAccount, nil
 }
 
-func ChangeAccountStatus(userID string, id string, newStatus models.Status, reason *string) error {
+func (s *Services) ChangeAccountStatus(userID string, id string, newStatus models.Status, reason *string) error {
 	// Validate that the status is valid
 	if !models.ValidateStatus(newStatus) {
 		return errors.New("invalid status")
@@ -179,7 +241,7 @@ func ChangeAccountStatus(userID string, id string, newStatus models.Status, reas
 	
 	// Check if the account exists and belongs to the user
 	var existingAccount models.BankAccount
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).First(&existingAccount)
+	result := s.db.Where("user_id = ? AND id = ?", userID, id).First(&existingAccount)
 	if result.Error != nil {
 		logger.Error("Bank account not found: %v", result.Error)
 		return errors.New("bank account not found or access denied")
@@ -190,14 +252,23 @@ func ChangeAccountStatus(userID string, id string, newStatus models.Status, reas
 		return nil
 	}
 	
+	if _, err := checkStatusTransition(models.BankAccountStatusMachine, existingAccount.Status, newStatus); err != nil {
+		return err
+	}
+	// Deleting goes through the same checks on the records using the account
+	if newStatus == models.StatusDeleted {
+		return s.SoftDeleteBankAccount(userID, id, false)
+	}
+	
 	// Update status
 	now := time.Now()
 	updates := map[string]interface{}{
 		"status": newStatus,
 		"status_changed_at": &now,
+		"version": gorm.Expr("version + 1"),
 	}
 	
-	result = db.DB.Model(&existingAccount).Updates(updates)
+	result = s.db.Model(&existingAccount).Updates(updates)
 	if result.Error != nil{
 		logger.Error("Error changing bank account status: %v", result.Error)
 		return result.Error
@@ -207,18 +278,27 @@ func ChangeAccountStatus(userID string, id string, newStatus models.Status, reas
 	return nil
 }
 
-func HardDeleteBankAccount(userID string, id string) error {
+func (s *Services) HardDeleteBankAccount(userID string, id string) error {
 	// Only for special cases - permanently delete
-	// Check if the account exists and belongs to the user
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&models.BankAccount{})
-	if result.Error != nil{
-		logger.Error("Error hard deleting bank account: %v", result.Error)
-		return result.Error
-	}
-	
-	if result.RowsAffected == 0{
+	// Check if the account exists and belongs to the user; sync clients holding it must resync
+	errNotFound := errors.New("bank account not found or access denied")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND id = ?", userID, id).Delete(&models.BankAccount{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNotFound
+		}
+		return recordSyncPurge(tx, uuid.MustParse(userID), "bank_accounts", time.Now())
+	})
+	if errors.Is(err, errNotFound) {
 		logger.Error("Bank account not found or doesn't belong to user")
-		return errors.New("bank account not found or access denied")
+		return err
+	}
+	if err != nil {
+		logger.Error("Error hard deleting bank account: %v", err)
+		return err
 	}
 
 	logger.Info("Bank account permanently deleted: %s", id)
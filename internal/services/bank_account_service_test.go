@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// TestRestoringBankAccountKeepsDependentStatuses deletes an account with cascade and restores
+// it: each archived record must come back with the status it had, not as active
+func TestRestoringBankAccountKeepsDependentStatuses(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(100))
+
+	fixedExpenses := map[models.Status]*models.FixedExpense{}
+	for _, status := range []models.Status{models.StatusActive, models.StatusSuspended} {
+		fixedExpense := &models.FixedExpense{
+			UserID:        user.ID,
+			Name:          "Gym " + string(status),
+			Amount:        models.NewMoney(30),
+			DueDate:       time.Now(),
+			NextDueDate:   time.Now().AddDate(0, 1, 0),
+			BankAccountID: account.ID,
+			Status:        status,
+		}
+		if err := h.DB.Create(fixedExpense).Error; err != nil {
+			t.Fatalf("creating %s fixed expense: %v", status, err)
+		}
+		fixedExpenses[status] = fixedExpense
+	}
+
+	if err := h.Services.SoftDeleteBankAccount(user.ID.String(), account.ID.String(), true); err != nil {
+		t.Fatalf("deleting the account: %v", err)
+	}
+	if _, err := h.Services.RestoreBankAccount(user.ID.String(), account.ID.String()); err != nil {
+		t.Fatalf("restoring the account: %v", err)
+	}
+
+	for status, fixedExpense := range fixedExpenses {
+		var restored models.FixedExpense
+		if err := h.DB.First(&restored, "id = ?", fixedExpense.ID).Error; err != nil {
+			t.Fatalf("loading the %s fixed expense: %v", status, err)
+		}
+		if restored.Status != status || restored.PriorStatus != nil {
+			t.Errorf("%s fixed expense came back %s with prior status %v", status, restored.Status, restored.PriorStatus)
+		}
+	}
+}
+
+// TestBankAccountsArePaginatedWithinTheGroup pages through the accounts of a group: the group
+// filter is part of the query, so totals and cursors count only its accounts
+func TestBankAccountsArePaginatedWithinTheGroup(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	var inGroup []uuid.UUID
+	for i := 0; i < 3; i++ {
+		inGroup = append(inGroup, h.CreateBankAccount(t, user, models.NewMoney(10)).ID)
+	}
+	h.CreateBankAccount(t, user, models.NewMoney(10))
+
+	cases := []struct {
+		name       string
+		accounts   []uuid.UUID
+		page       services.PageRequest
+		wantRows   int
+		wantTotal  int64
+		wantCursor bool
+	}{
+		{name: "every account, default page", page: services.PageRequest{}, wantRows: 4, wantTotal: 4},
+		{name: "group, first page", accounts: inGroup, page: services.PageRequest{Limit: 2}, wantRows: 2, wantTotal: 3, wantCursor: true},
+		{name: "group, last page", accounts: inGroup, page: services.PageRequest{Limit: 2, Offset: 2}, wantRows: 1, wantTotal: 3},
+		{name: "empty group", accounts: []uuid.UUID{}, page: services.PageRequest{}, wantRows: 0, wantTotal: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			accounts, info, err := h.Services.GetActiveBankAccounts(user.ID.String(), tc.accounts, tc.page)
+			if err != nil {
+				t.Fatalf("listing accounts: %v", err)
+			}
+			if len(accounts) != tc.wantRows || info.Total != tc.wantTotal || (info.NextCursor != nil) != tc.wantCursor {
+				t.Errorf("got %d rows of %d, cursor %v; want %d of %d, cursor %v",
+					len(accounts), info.Total, info.NextCursor != nil, tc.wantRows, tc.wantTotal, tc.wantCursor)
+			}
+		})
+	}
+
+	if _, _, err := h.Services.GetActiveBankAccounts(user.ID.String(), nil, services.PageRequest{Limit: services.MaxPageLimit + 1}); err == nil {
+		t.Error("a page over the maximum was accepted")
+	}
+}
+
+// TestBalanceMovementBumpsAccountVersion checks that an edit based on the account as it was
+// before an expense moved its balance loses the optimistic lock
+func TestBalanceMovementBumpsAccountVersion(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(100))
+	category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+	userID := user.ID.String()
+
+	var read models.BankAccount
+	if err := h.DB.First(&read, "id = ?", account.ID).Error; err != nil {
+		t.Fatalf("reading account: %v", err)
+	}
+	if err := h.Expenses.Create(context.Background(), userID, &models.Expense{
+		CategoryID:    category.ID,
+		BankAccountID: account.ID,
+		Amount:        models.NewMoney(30),
+		Date:          time.Now().UTC().Truncate(24 * time.Hour),
+	}, false); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+
+	var moved models.BankAccount
+	if err := h.DB.First(&moved, "id = ?", account.ID).Error; err != nil {
+		t.Fatalf("reading account: %v", err)
+	}
+	if moved.Version != read.Version+1 || moved.Balance != models.NewMoney(70) {
+		t.Errorf("account at version %d with balance %s, want %d and 70.00", moved.Version, moved.Balance, read.Version+1)
+	}
+
+	_, err := h.Services.PatchBankAccount(userID, account.ID.String(), &models.BankAccount{AccountName: "Renamed", Version: read.Version})
+	if !errors.Is(err, services.ErrVersionConflict) {
+		t.Fatalf("patching the account read before the expense = %v, want a version conflict", err)
+	}
+}
@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/openbanking"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportedTag is given to the expenses and incomes pulled from bank connections
+const ImportedTag = "imported"
+
+// bankSyncClaimTimeout is how long a sync can hold a connection before another may take over
+const bankSyncClaimTimeout = 30 * time.Minute
+
+var (
+	openBankingOnce     sync.Once
+	openBankingProvider openbanking.Provider
+	openBankingCipher   *openbanking.TokenCipher
+	openBankingErr      error
+)
+
+// getOpenBanking returns the provider configured by OPEN_BANKING_PROVIDER and the cipher of
+// its access tokens
+func getOpenBanking() (openbanking.Provider, *openbanking.TokenCipher, error) {
+	openBankingOnce.Do(func() {
+		openBankingProvider, openBankingErr = openbanking.FromEnv()
+		if openBankingErr == nil {
+			openBankingCipher, openBankingErr = openbanking.TokenCipherFromEnv()
+		}
+		if openBankingErr != nil && !errors.Is(openBankingErr, openbanking.ErrNotConfigured) {
+			logger.Error("Error configuring open banking: %v", openBankingErr)
+		}
+	})
+	return openBankingProvider, openBankingCipher, openBankingErr
+}
+
+// bankSyncInterval is how often the job pulls each connection (OPEN_BANKING_SYNC_HOURS, default 6)
+func bankSyncInterval() time.Duration {
+	return time.Duration(envInt("OPEN_BANKING_SYNC_HOURS", 6)) * time.Hour
+}
+
+// bankSyncHistoryDays is how far before the connection was made transactions are imported
+// (OPEN_BANKING_HISTORY_DAYS, default 30), so linking a bank doesn't pull years of history
+func bankSyncHistoryDays() int {
+	return envInt("OPEN_BANKING_HISTORY_DAYS", 30)
+}
+
+// CreateBankLinkToken starts linking a bank: the client opens the provider's login flow with it
+func CreateBankLinkToken(ctx context.Context, userID string) (*dto.BankLinkToken, error) {
+	provider, _, err := getOpenBanking()
+	if err != nil {
+		return nil, err
+	}
+	token, err := provider.CreateLinkToken(ctx, userID)
+	if err != nil {
+		logger.Error("Error creating %s link token: %v", provider.Name(), err)
+		return nil, errors.New("error creating link token")
+	}
+	return &dto.BankLinkToken{Provider: provider.Name(), LinkToken: token.Token, Expiration: token.Expiration}, nil
+}
+
+// ConnectBank finishes linking a bank with the public token the login flow returned. Its bank
+// accounts are listed on the connection; transactions are imported once they are linked to
+// Fluxio accounts. Linking the same bank login again refreshes the existing connection
+func (s *Services) ConnectBank(ctx context.Context, userID string, publicToken string, categoryID string, institutionName *string) (*models.BankConnection, error) {
+	if strings.TrimSpace(publicToken) == "" {
+		return nil, errors.New("invalid public_token: required")
+	}
+	var category models.Category
+	if err := s.db.Where("id = ? AND user_id = ? AND status IN ?", categoryID, userID, models.GetActiveStatuses()).First(&category).Error; err != nil {
+		return nil, errors.New("category not found or not active")
+	}
+
+	provider, cipher, err := getOpenBanking()
+	if err != nil {
+		return nil, err
+	}
+	item, err := provider.ExchangePublicToken(ctx, publicToken)
+	if err != nil {
+		logger.Error("Error exchanging %s public token: %v", provider.Name(), err)
+		return nil, errors.New("error connecting bank")
+	}
+	accounts, err := provider.Accounts(ctx, item.AccessToken)
+	if err != nil {
+		logger.Error("Error getting %s accounts: %v", provider.Name(), err)
+		return nil, errors.New("error connecting bank")
+	}
+	sealed, err := cipher.Seal(item.AccessToken)
+	if err != nil {
+		logger.Error("Error sealing access token: %v", err)
+		return nil, errors.New("error connecting bank")
+	}
+
+	uid := uuid.MustParse(userID)
+	connection := models.BankConnection{
+		UserID:            uid,
+		Provider:          provider.Name(),
+		ItemID:            item.ID,
+		AccessToken:       sealed,
+		InstitutionName:   institutionName,
+		DefaultCategoryID: category.ID,
+		Status:            models.BankConnectionActive,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.BankConnection
+		err := tx.Where("provider = ? AND item_id = ?", connection.Provider, connection.ItemID).First(&existing).Error
+		switch {
+		case err == nil && existing.UserID != uid:
+			return errors.New("bank login already linked by another user")
+		case err == nil:
+			connection.ID = existing.ID
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"access_token":        sealed,
+				"institution_name":    institutionName,
+				"default_category_id": category.ID,
+				"status":              models.BankConnectionActive,
+				"last_error":          nil,
+			}).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&connection).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		for _, account := range accounts {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "connection_id"}, {Name: "external_account_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "mask", "type", "updated_at"}),
+			}).Create(&models.BankConnectionAccount{
+				ConnectionID:      connection.ID,
+				UserID:            uid,
+				ExternalAccountID: account.ID,
+				Name:              account.Name,
+				Mask:              account.Mask,
+				Type:              account.Type,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error storing bank connection: %v", err)
+		return nil, errors.New("error connecting bank")
+	}
+
+	logger.Info("Bank connection %s linked through %s for user %s with %d accounts", connection.ID, provider.Name(), userID, len(accounts))
+	return s.GetBankConnection(userID, connection.ID.String())
+}
+
+// GetBankConnections returns the connections of the user that weren't disconnected
+func (s *Services) GetBankConnections(userID string) ([]models.BankConnection, error) {
+	var connections []models.BankConnection
+	err := s.db.Preload("Accounts", func(query *gorm.DB) *gorm.DB { return query.Order("name") }).
+		Where("user_id = ? AND status <> ?", userID, models.BankConnectionDisconnected).
+		Order("created_at DESC").Find(&connections).Error
+	if err != nil {
+		logger.Error("Error getting bank connections: %v", err)
+		return nil, err
+	}
+	return connections, nil
+}
+
+// GetBankConnection returns a connection of the user with its accounts
+func (s *Services) GetBankConnection(userID string, id string) (*models.BankConnection, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New("bank connection not found")
+	}
+	var connection models.BankConnection
+	err := s.db.Preload("Accounts", func(query *gorm.DB) *gorm.DB { return query.Order("name") }).
+		Where("id = ? AND user_id = ? AND status <> ?", id, userID, models.BankConnectionDisconnected).
+		First(&connection).Error
+	if err != nil {
+		return nil, errors.New("bank connection not found")
+	}
+	return &connection, nil
+}
+
+// LinkBankConnectionAccount sets the Fluxio bank account the transactions of a connected
+// account are imported into; nil stops importing them
+func (s *Services) LinkBankConnectionAccount(userID string, connectionID string, accountID string, bankAccountID *string) (*models.BankConnectionAccount, error) {
+	connection, err := s.GetBankConnection(userID, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	var account *models.BankConnectionAccount
+	for i := range connection.Accounts {
+		if connection.Accounts[i].ID.String() == accountID {
+			account = &connection.Accounts[i]
+		}
+	}
+	if account == nil {
+		return nil, errors.New("bank connection account not found")
+	}
+
+	var linked *uuid.UUID
+	if bankAccountID != nil {
+		bankAccount, err := s.GetBankAccountByID(userID, *bankAccountID)
+		if err != nil {
+			return nil, errors.New("bank account not found")
+		}
+		var taken int64
+		if err := s.db.Model(&models.BankConnectionAccount{}).
+			Where("bank_account_id = ? AND id <> ?", bankAccount.ID, account.ID).Count(&taken).Error; err != nil {
+			return nil, err
+		}
+		if taken > 0 {
+			return nil, errors.New("invalid bank_account_id: already linked to another connected account")
+		}
+		linked = &bankAccount.ID
+	}
+
+	if err := s.db.Model(account).Update("bank_account_id", linked).Error; err != nil {
+		logger.Error("Error linking bank connection account: %v", err)
+		return nil, err
+	}
+	account.BankAccountID = linked
+	return account, nil
+}
+
+// SyncBankConnection pulls the new transactions of a connection right away
+func (s *Services) SyncBankConnection(ctx context.Context, userID string, id string) (*dto.BankSyncResult, error) {
+	connection, err := s.GetBankConnection(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncBankConnection(ctx, connection)
+}
+
+// syncBankConnection imports the transactions added since the connection's cursor: money out
+// becomes expenses (or matches with manual ones), money in becomes incomes, all tagged
+// ImportedTag. Pending transactions wait until they post. Records created from transactions the
+// bank later drops are deleted; changes to transactions already imported are left to the user
+func (s *Services) syncBankConnection(ctx context.Context, connection *models.BankConnection) (*dto.BankSyncResult, error) {
+	provider, cipher, err := getOpenBanking()
+	if err != nil {
+		return nil, err
+	}
+	if provider.Name() != connection.Provider {
+		return nil, errors.New("bank connection belongs to provider " + connection.Provider + ", which is not configured")
+	}
+
+	now := time.Now()
+	claim := s.db.Model(&models.BankConnection{}).
+		Where("id = ? AND (sync_started_at IS NULL OR sync_started_at < ?)", connection.ID, now.Add(-bankSyncClaimTimeout)).
+		Update("sync_started_at", now)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, errors.New("invalid request: a sync of this bank connection is already running")
+	}
+	defer s.db.Model(&models.BankConnection{}).Where("id = ?", connection.ID).Update("sync_started_at", nil)
+
+	result, cursor, err := s.pullBankConnection(ctx, provider, cipher, connection)
+	if err != nil {
+		message := err.Error()
+		updates := map[string]interface{}{"last_error": message, "sync_requested_at": nil}
+		if errors.Is(err, openbanking.ErrLoginRequired) {
+			updates["status"] = models.BankConnectionLoginRequired
+		}
+		s.db.Model(connection).Updates(updates)
+		logger.Warn("Sync of bank connection %s failed: %v", connection.ID, err)
+		return nil, err
+	}
+
+	result.SyncedAt = time.Now().UTC()
+	if err := s.db.Model(connection).Updates(map[string]interface{}{
+		"cursor":            cursor,
+		"status":            models.BankConnectionActive,
+		"last_error":        nil,
+		"last_synced_at":    result.SyncedAt,
+		"sync_requested_at": nil,
+	}).Error; err != nil {
+		logger.Error("Error saving sync of bank connection %s: %v", connection.ID, err)
+		return nil, err
+	}
+
+	logger.Info("Bank connection %s synced: %d expenses, %d incomes, %d removed, %d skipped",
+		connection.ID, result.Expenses, result.Incomes, result.Removed, result.Skipped)
+	return result, nil
+}
+
+// pullBankConnection reads the changes after the connection's cursor and imports them. It
+// returns the cursor to continue from; the cursor is left as it was while no account is linked
+func (s *Services) pullBankConnection(ctx context.Context, provider openbanking.Provider, cipher *openbanking.TokenCipher, connection *models.BankConnection) (*dto.BankSyncResult, *string, error) {
+	userID := connection.UserID.String()
+	result := &dto.BankSyncResult{ConnectionID: connection.ID.String(), BatchIDs: []string{}}
+
+	linked := make(map[string]*models.BankAccount)
+	for _, account := range connection.Accounts {
+		if account.BankAccountID == nil {
+			continue
+		}
+		bankAccount, err := s.GetBankAccountByID(userID, account.BankAccountID.String())
+		if err != nil {
+			logger.Warn("Bank account %s linked to bank connection %s is gone", account.BankAccountID, connection.ID)
+			continue
+		}
+		linked[account.ExternalAccountID] = bankAccount
+	}
+	if len(linked) == 0 {
+		return result, connection.Cursor, nil
+	}
+
+	accessToken, err := cipher.Open(connection.AccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursor := ""
+	if connection.Cursor != nil {
+		cursor = *connection.Cursor
+	}
+	var added []openbanking.Transaction
+	var removed []string
+	for {
+		page, err := provider.SyncTransactions(ctx, accessToken, cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		added = append(added, page.Added...)
+		removed = append(removed, page.Removed...)
+		cursor = page.NextCursor
+		if !page.HasMore {
+			break
+		}
+	}
+
+	source := "open-banking:" + connection.Provider
+	if connection.InstitutionName != nil {
+		source += " " + *connection.InstitutionName
+	}
+	since := connection.CreatedAt.AddDate(0, 0, -bankSyncHistoryDays())
+	runs := make(map[uuid.UUID]*importRun)
+	for _, transaction := range added {
+		account, ok := linked[transaction.AccountID]
+		if !ok || transaction.Pending || transaction.Date.Before(since) || transaction.Amount == 0 {
+			result.Skipped++
+			continue
+		}
+
+		run := runs[account.ID]
+		if run == nil {
+			batch := models.ImportBatch{
+				UserID:           connection.UserID,
+				BankAccountID:    account.ID,
+				CategoryID:       connection.DefaultCategoryID,
+				Source:           &source,
+				BankConnectionID: &connection.ID,
+			}
+			if err := s.db.Create(&batch).Error; err != nil {
+				return nil, nil, err
+			}
+			run = s.newImportRun(userID, &batch, account)
+			runs[account.ID] = run
+			result.BatchIDs = append(result.BatchIDs, batch.ID.String())
+		}
+
+		externalID := transaction.ID
+		description := transaction.Description
+		input := ImportTransactionInput{
+			Date:        transaction.Date,
+			Amount:      transaction.Amount.Abs(),
+			Description: &description,
+			ExternalID:  &externalID,
+			Tags:        []string{ImportedTag},
+		}
+		if transaction.Amount > 0 {
+			err = run.importExpense(ctx, input)
+			result.Expenses++
+		} else {
+			err = run.importIncome(input)
+			result.Incomes++
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, externalID := range removed {
+		var imports []models.ImportedTransaction
+		if err := s.db.Joins("JOIN import_batches b ON b.id = imported_transactions.batch_id").
+			Where("b.bank_connection_id = ? AND imported_transactions.external_id = ? AND imported_transactions.status = ?",
+				connection.ID, externalID, models.ImportedCreated).
+			Find(&imports).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, imported := range imports {
+			var err error
+			switch {
+			case imported.ExpenseID != nil:
+				err = s.Expenses.SoftDelete(userID, imported.ExpenseID.String())
+			case imported.IncomeID != nil:
+				err = s.SoftDeleteIncome(userID, imported.IncomeID.String())
+			}
+			if err != nil {
+				logger.Warn("Can't delete the record of dropped bank transaction %s: %v", externalID, err)
+				continue
+			}
+			result.Removed++
+		}
+	}
+	return result, &cursor, nil
+}
+
+// DisconnectBankConnection revokes the connection at the provider and stops syncing it. Records
+// already imported are kept
+func (s *Services) DisconnectBankConnection(ctx context.Context, userID string, id string) error {
+	connection, err := s.GetBankConnection(userID, id)
+	if err != nil {
+		return err
+	}
+	provider, cipher, err := getOpenBanking()
+	if err == nil && provider.Name() == connection.Provider {
+		accessToken, err := cipher.Open(connection.AccessToken)
+		if err == nil {
+			err = provider.RemoveItem(ctx, accessToken)
+		}
+		if err != nil {
+			logger.Warn("Can't revoke bank connection %s at %s: %v", connection.ID, connection.Provider, err)
+		}
+	}
+
+	if err := s.db.Model(connection).Updates(map[string]interface{}{
+		"status":            models.BankConnectionDisconnected,
+		"access_token":      "",
+		"cursor":            nil,
+		"sync_requested_at": nil,
+	}).Error; err != nil {
+		logger.Error("Error disconnecting bank connection: %v", err)
+		return err
+	}
+	logger.Info("Bank connection %s disconnected", connection.ID)
+	return nil
+}
+
+// HandleOpenBankingWebhook takes a provider notification and flags the connection for the sync
+// job. Notifications aren't authenticated, so they are only a hint: the sync asks the provider
+// what actually changed
+func (s *Services) HandleOpenBankingWebhook(body []byte) error {
+	provider, _, err := getOpenBanking()
+	if err != nil {
+		return err
+	}
+	event, err := provider.ParseWebhook(body)
+	if err != nil {
+		return err
+	}
+	if event.Kind == openbanking.WebhookOther {
+		return nil
+	}
+
+	result := s.db.Model(&models.BankConnection{}).
+		Where("provider = ? AND item_id = ? AND status <> ?", provider.Name(), event.ItemID, models.BankConnectionDisconnected).
+		Update("sync_requested_at", time.Now())
+	if result.Error != nil {
+		logger.Error("Error flagging bank connection for sync: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Info("Sync of %s item %s requested by webhook (%s)", provider.Name(), event.ItemID, event.Kind)
+	}
+	return nil
+}
+
+// SyncDueBankConnections runs the sync of every connection flagged by a webhook or not synced
+// within bankSyncInterval
+func (s *Services) SyncDueBankConnections() error {
+	if _, _, err := getOpenBanking(); err != nil {
+		if errors.Is(err, openbanking.ErrNotConfigured) {
+			return nil
+		}
+		return err
+	}
+
+	var connections []models.BankConnection
+	if err := s.db.Preload("Accounts").
+		Where("status = ? AND (sync_requested_at IS NOT NULL OR last_synced_at IS NULL OR last_synced_at < ?)",
+			models.BankConnectionActive, time.Now().Add(-bankSyncInterval())).
+		Find(&connections).Error; err != nil {
+		logger.Error("Error listing bank connections to sync: %v", err)
+		return err
+	}
+
+	for i := range connections {
+		if _, err := s.syncBankConnection(context.Background(), &connections[i]); err != nil {
+			logger.Error("Error syncing bank connection %s: %v", connections[i].ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// minBenchmarkFloor is the lowest k-anonymity threshold allowed, whatever the configuration says
+const minBenchmarkFloor = 10
+
+// ErrBenchmarkNotOptedIn is returned when a user asks for benchmarks without sharing their own data
+var ErrBenchmarkNotOptedIn = errors.New("benchmarking is not enabled for this user")
+
+// benchmarkMinParticipants is the number of distinct users a category needs before it is
+// benchmarked (BENCHMARK_MIN_PARTICIPANTS, never below minBenchmarkFloor)
+func benchmarkMinParticipants() int {
+	k := envInt("BENCHMARK_MIN_PARTICIPANTS", 20)
+	if k < minBenchmarkFloor {
+		return minBenchmarkFloor
+	}
+	return k
+}
+
+// benchmarkCategoryKeySQL matches categories across users by their normalized name
+const benchmarkCategoryKeySQL = "LOWER(TRIM(c.name))"
+
+// GetBenchmarkOptIn reports whether the user shares anonymized spending in benchmarks
+func (s *Services) GetBenchmarkOptIn(userID string) (bool, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return false, errors.New("error getting benchmark preference")
+	}
+	return preferences.BenchmarkOptIn, nil
+}
+
+// SetBenchmarkOptIn enables or disables the user's participation in benchmarks
+func (s *Services) SetBenchmarkOptIn(userID string, optIn bool) error {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return errors.New("error updating benchmark preference")
+	}
+
+	preferences.BenchmarkOptIn = optIn
+	if err := s.saveUserPreferences(preferences, "benchmark_opt_in"); err != nil {
+		logger.Error("Error saving benchmark preference: %v", err)
+		return errors.New("error updating benchmark preference")
+	}
+
+	return nil
+}
+
+// categorySpendRow is the monthly spend of one user in one normalized category
+type categorySpendRow struct {
+	UserID      string
+	CategoryKey string
+	Total       models.Money
+}
+
+// GetSpendingBenchmarks places the user's spending of a month in the distribution of every
+// opted-in user, per category. Only users who opted in can see benchmarks, individual
+// amounts are never returned, and categories with fewer than the minimum number of
+// participants only report the user's own spend.
+func (s *Services) GetSpendingBenchmarks(userID string, month time.Time) (*dto.SpendingBenchmarks, error) {
+	optedIn, err := s.GetBenchmarkOptIn(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !optedIn {
+		return nil, ErrBenchmarkNotOptedIn
+	}
+
+	start := models.MonthStart(month)
+	end := start.AddDate(0, 1, 0)
+	minParticipants := benchmarkMinParticipants()
+
+	spendQuery := func() *gorm.DB {
+		return s.db.Table("expenses e").Scopes(joinExpenseRefunds).
+			Joins("JOIN categories c ON c.id = e.category_id").
+			Where("e.status IN ? AND e.date >= ? AND e.date < ?", models.GetActiveStatuses(), start, end)
+	}
+
+	var own []categorySpendRow
+	if err := spendQuery().
+		Select("e.user_id::text AS user_id, "+benchmarkCategoryKeySQL+" AS category_key, SUM("+netExpenseAmountSQL()+") AS total").
+		Where("e.user_id = ?", userID).
+		Group("e.user_id, category_key").
+		Scan(&own).Error; err != nil {
+		logger.Error("Error getting user spending for benchmarks: %v", err)
+		return nil, errors.New("error calculating benchmarks")
+	}
+
+	result := &dto.SpendingBenchmarks{
+		Month:           start.Format("2006-01"),
+		MinParticipants: minParticipants,
+		Categories:      []dto.CategoryBenchmark{},
+	}
+	if len(own) == 0 {
+		return result, nil
+	}
+
+	currency := s.GetUserCurrency(userID)
+	keys := make([]string, 0, len(own))
+	for _, row := range own {
+		keys = append(keys, row.CategoryKey)
+	}
+
+	var rows []categorySpendRow
+	if err := spendQuery().
+		Select("e.user_id::text AS user_id, "+benchmarkCategoryKeySQL+" AS category_key, SUM("+netExpenseAmountSQL()+") AS total").
+		Joins("JOIN user_preferences up ON up.user_id = e.user_id AND up.benchmark_opt_in").
+		Joins("JOIN users bu ON bu.id = e.user_id AND bu.currency = ?", currency.Code). // Spend is only comparable within a currency
+		Where(benchmarkCategoryKeySQL+" IN ?", keys).
+		Group("e.user_id, category_key").
+		Scan(&rows).Error; err != nil {
+		logger.Error("Error getting spending distribution for benchmarks: %v", err)
+		return nil, errors.New("error calculating benchmarks")
+	}
+
+	distributions := make(map[string][]float64)
+	for _, row := range rows {
+		distributions[row.CategoryKey] = append(distributions[row.CategoryKey], row.Total.Float64())
+	}
+
+	for _, row := range own {
+		benchmark := dto.CategoryBenchmark{
+			Category:  row.CategoryKey,
+			YourSpend: currency.RoundMoney(row.Total),
+		}
+
+		values := distributions[row.CategoryKey]
+		if len(values) < minParticipants {
+			benchmark.Description = fmt.Sprintf("Not enough participants to benchmark your %s spend yet", row.CategoryKey)
+			result.Categories = append(result.Categories, benchmark)
+			continue
+		}
+
+		sort.Float64s(values)
+		percentile := spendPercentile(values, row.Total.Float64())
+		p25 := currency.RoundMoney(models.NewMoney(benchmarkQuantile(values, 0.25)))
+		median := currency.RoundMoney(models.NewMoney(benchmarkQuantile(values, 0.5)))
+		p75 := currency.RoundMoney(models.NewMoney(benchmarkQuantile(values, 0.75)))
+
+		benchmark.Available = true
+		benchmark.Percentile = &percentile
+		benchmark.P25 = &p25
+		benchmark.Median = &median
+		benchmark.P75 = &p75
+		benchmark.Description = fmt.Sprintf("Your %s spend is in the %s percentile", row.CategoryKey, ordinal(percentile))
+		result.Categories = append(result.Categories, benchmark)
+	}
+
+	sort.Slice(result.Categories, func(i, j int) bool {
+		return result.Categories[i].YourSpend > result.Categories[j].YourSpend
+	})
+
+	return result, nil
+}
+
+// spendPercentile returns the share of sorted values below the given amount, counting ties as half
+func spendPercentile(sorted []float64, amount float64) int {
+	below := sort.SearchFloat64s(sorted, amount)
+	equal := 0
+	for i := below; i < len(sorted) && sorted[i] == amount; i++ {
+		equal++
+	}
+	return int(math.Round((float64(below) + float64(equal)/2) / float64(len(sorted)) * 100))
+}
+
+// benchmarkQuantile interpolates a quantile of sorted values, rounded to whole units so that
+// small distributions don't reveal exact individual amounts
+func benchmarkQuantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	value := sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+	return math.Round(value)
+}
+
+// ordinal formats a number as 1st, 2nd, 3rd, 4th...
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// EventBudgetExceeded is emitted by the expense that takes the spending of an expense type past
+// its line of the monthly budget. Later expenses of the month don't emit it again
+const EventBudgetExceeded = "budget.exceeded"
+
+// budgetExceededPayload returns the budget.exceeded payload when the expense crosses the budget
+// line of its expense type, or nil when it doesn't (or the month has no budget for that type)
+func (s *Services) budgetExceededPayload(userID string, category models.Category, expense *models.Expense) (map[string]interface{}, error) {
+	start := models.MonthStart(expense.Date)
+	end := start.AddDate(0, 1, -1)
+
+	var budget models.Budget
+	result := s.db.Where("user_id = ? AND month_year = ? AND status IN ?", userID, start, models.GetActiveStatuses()).
+		Limit(1).Find(&budget)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	var line models.Money
+	switch category.ExpenseType {
+	case models.ExpenseTypeNeeds:
+		line = budget.NeedsBudget
+	case models.ExpenseTypeWants:
+		line = budget.WantsBudget
+	case models.ExpenseTypeSavings:
+		line = budget.SavingsBudget
+	}
+	if line <= 0 {
+		return nil, nil
+	}
+
+	var spent models.Money
+	if err := s.summaryPeriodQuery(userID, start, end).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Where("c.expense_type = ?", category.ExpenseType).
+		Select("COALESCE(SUM(" + netExpenseAmountSQL() + "), 0)").
+		Scan(&spent).Error; err != nil {
+		return nil, err
+	}
+	if spent > line || spent+expense.Amount <= line {
+		return nil, nil
+	}
+
+	currency := s.GetUserCurrency(userID)
+	return map[string]interface{}{
+		"budget_id":    budget.ID,
+		"month":        start.Format("2006-01"),
+		"expense_type": category.ExpenseType,
+		"budget":       currency.RoundMoney(line),
+		"spent":        currency.RoundMoney(spent + expense.Amount),
+		"over_by":      currency.RoundMoney(spent + expense.Amount - line),
+		"category_id":  category.ID,
+		"exceeded_at":  time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
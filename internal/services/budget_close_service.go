@@ -0,0 +1,324 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MonthClosedError is returned when a write would change the actuals of a closed month
+type MonthClosedError struct {
+	Month time.Time
+}
+
+func (e *MonthClosedError) Error() string {
+	return fmt.Sprintf("month %s is closed; reopen it to change its expenses and incomes", e.Month.Format("2006-01"))
+}
+
+var errMonthAlreadyClosed = errors.New("month is already closed")
+
+// ErrMonthNotClosed is returned when reopening or reporting a month that was never closed
+var ErrMonthNotClosed = errors.New("month not closed")
+
+// monthCloseGraceDays is how many days into a month the scheduled job waits before closing the
+// previous one, leaving time for late expenses
+func monthCloseGraceDays() int {
+	return envInt("MONTH_CLOSE_GRACE_DAYS", 5)
+}
+
+// monthCloseLock is the advisory lock a close of the user's month holds exclusively and writes
+// to the month hold shared. Months are the user's month periods, by their label, so a close never snapshots while a write is in flight
+func monthCloseLock(userID string, month time.Time) string {
+	return "month_close:" + userID + ":" + month.Format("2006-01")
+}
+
+// ensureMonthsOpen fails with a *MonthClosedError when any of the dates falls in a closed month
+// period of the user. Zero dates are ignored. Within a transaction, the months stay locked
+// against closing until it ends
+func ensureMonthsOpen(tx *gorm.DB, userID string, dates ...time.Time) error {
+	var calendar *userCalendar
+	seen := make(map[time.Time]bool, len(dates))
+	months := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		if date.IsZero() {
+			continue
+		}
+		if calendar == nil {
+			loaded := loadUserCalendar(tx, userID)
+			calendar = &loaded
+		}
+		if month := calendar.currentMonth(date); !seen[month] {
+			seen[month] = true
+			months = append(months, month)
+		}
+	}
+	if len(months) == 0 {
+		return nil
+	}
+
+	for _, month := range months {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(hashtext(?))", monthCloseLock(userID, month)).Error; err != nil {
+			logger.Error("Error locking month %s: %v", month.Format("2006-01"), err)
+			return errors.New("error checking closed months")
+		}
+	}
+
+	var closed models.BudgetClose
+	result := tx.Select("month_year").Where("user_id = ? AND month_year IN ? AND reopened_at IS NULL", userID, months).
+		Order("month_year ASC").Limit(1).Find(&closed)
+	if result.Error != nil {
+		logger.Error("Error checking closed months: %v", result.Error)
+		return errors.New("error checking closed months")
+	}
+	if result.RowsAffected > 0 {
+		return &MonthClosedError{Month: closed.MonthYear}
+	}
+	return nil
+}
+
+// CloseMonth freezes a past month period of the user, given by its label: it stores its budget
+// compliance and a snapshot of the final actuals, and locks its expenses. Closing a reopened
+// month takes a new snapshot
+func (s *Services) CloseMonth(userID string, month time.Time, closedBy string) (*models.BudgetClose, error) {
+	start := models.MonthStart(month)
+	if !start.Before(s.getUserCalendar(userID).currentMonth(s.UserToday(userID))) {
+		return nil, errors.New("invalid month: only past months can be closed")
+	}
+
+	// The writes to the month wait for the close to commit, and the close for those in flight.
+	// The budget row is locked too, so its amounts and lines can't change under the snapshot
+	var snapshot *models.BudgetClose
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", monthCloseLock(userID, start)).Error; err != nil {
+			return err
+		}
+
+		var existing models.BudgetClose
+		result := tx.Where("user_id = ? AND month_year = ?", userID, start).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 && existing.IsClosed() {
+			return errMonthAlreadyClosed
+		}
+
+		var err error
+		if snapshot, err = s.buildMonthClose(tx, userID, start); err != nil {
+			return err
+		}
+		snapshot.ClosedBy = closedBy
+		return storeMonthClose(tx, snapshot)
+	})
+	if errors.Is(err, errMonthAlreadyClosed) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Error closing %s: %v", start.Format("2006-01"), err)
+		return nil, errors.New("error closing month")
+	}
+
+	s.RecordAudit(snapshot.UserID, "budget.month_closed", "budget_close", &snapshot.ID, map[string]interface{}{
+		"month":     start.Format("2006-01"),
+		"closed_by": closedBy,
+	})
+	logger.Info("Month %s closed for user %s (%s)", start.Format("2006-01"), userID, closedBy)
+	return s.GetMonthCloseReport(userID, start)
+}
+
+// buildMonthClose computes the snapshot of the user's month period labelled start, the span the
+// close locks, from its budget compliance and its income and expense totals. When the month has
+// a budget its compliance is stored too. The budget is locked for the rest of tx
+func (s *Services) buildMonthClose(tx *gorm.DB, userID string, start time.Time) (*models.BudgetClose, error) {
+	userUUID := uuid.MustParse(userID)
+	budget := models.Budget{UserID: userUUID, MonthYear: start}
+	var budgets []models.Budget
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND month_year = ? AND status IN ?", userID, start, models.GetVisibleStatuses()).
+		Limit(1).Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	hasBudget := len(budgets) > 0
+	if hasBudget {
+		budget = budgets[0]
+	}
+
+	currency := s.GetUserCurrency(userID)
+	calendar := loadUserCalendar(tx, userID)
+	compliance, err := s.computeBudgetCompliance(userID, budget, currency, calendar)
+	if err != nil {
+		return nil, err
+	}
+	if hasBudget {
+		if err := storeBudgetCompliance(tx, compliance); err != nil {
+			return nil, err
+		}
+	}
+
+	periodStart, last := calendar.monthPeriod(start.Year(), start.Month())
+	end := last.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	var totalIncome models.Money
+	var expenseCount int64
+	if err := tx.Model(&models.Income{}).Select("COALESCE(SUM(amount), 0) AS total_income").
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, periodStart, end, models.GetActiveStatuses()).
+		Scan(&totalIncome).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Expense{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, periodStart, end, models.GetActiveStatuses()).
+		Count(&expenseCount).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.BudgetClose{
+		UserID:        userUUID,
+		MonthYear:     start,
+		NeedsBudget:   compliance.NeedsBudget,
+		WantsBudget:   compliance.WantsBudget,
+		SavingsBudget: compliance.SavingsBudget,
+		NeedsSpent:    compliance.NeedsSpent,
+		WantsSpent:    compliance.WantsSpent,
+		SavingsSpent:  compliance.SavingsSpent,
+		TotalIncome:   currency.RoundMoney(totalIncome),
+		TotalSpent:    compliance.NeedsSpent + compliance.WantsSpent + compliance.SavingsSpent,
+		ExpenseCount:  expenseCount,
+		WithinBudget:  hasBudget && compliance.WithinBudget,
+		UsagePercent:  compliance.UsagePercent,
+		ClosedAt:      time.Now().UTC(),
+	}
+	snapshot.Net = snapshot.TotalIncome - snapshot.TotalSpent
+	if hasBudget {
+		snapshot.BudgetID = &budget.ID
+	}
+	for _, line := range compliance.Categories {
+		snapshot.Categories = append(snapshot.Categories, models.BudgetCloseCategory{
+			CategoryID:   line.CategoryID,
+			CategoryName: line.CategoryName,
+			Budget:       line.Budget,
+			Spent:        line.Spent,
+			Within:       line.Within,
+		})
+	}
+	return snapshot, nil
+}
+
+// storeMonthClose upserts the close of a month, clearing any reopen, and replaces its category lines
+func storeMonthClose(tx *gorm.DB, snapshot *models.BudgetClose) error {
+	categories := snapshot.Categories
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "month_year"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"budget_id", "needs_budget", "wants_budget", "savings_budget", "needs_spent", "wants_spent", "savings_spent",
+				"total_income", "total_spent", "net", "expense_count", "within_budget", "usage_percent",
+				"closed_at", "closed_by", "reopened_at", "reopen_reason", "updated_at",
+			}),
+		}).Omit("Categories").Create(snapshot).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("close_id = ?", snapshot.ID).Delete(&models.BudgetCloseCategory{}).Error; err != nil {
+			return err
+		}
+		if len(categories) == 0 {
+			return nil
+		}
+		for i := range categories {
+			categories[i].CloseID = snapshot.ID
+		}
+		return tx.Create(&categories).Error
+	})
+}
+
+// ReopenMonth unlocks the expenses of a closed month. The snapshot is kept until the month is
+// closed again
+func (s *Services) ReopenMonth(userID string, month time.Time, reason *string) (*models.BudgetClose, error) {
+	start := models.MonthStart(month)
+	now := time.Now().UTC()
+	result := s.db.Model(&models.BudgetClose{}).
+		Where("user_id = ? AND month_year = ? AND reopened_at IS NULL", userID, start).
+		Updates(map[string]interface{}{"reopened_at": &now, "reopen_reason": reason})
+	if result.Error != nil {
+		logger.Error("Error reopening month: %v", result.Error)
+		return nil, errors.New("error reopening month")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMonthNotClosed
+	}
+
+	report, err := s.GetMonthCloseReport(userID, start)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordAudit(report.UserID, "budget.month_reopened", "budget_close", &report.ID, map[string]interface{}{
+		"month":  start.Format("2006-01"),
+		"reason": reason,
+	})
+	logger.Info("Month %s reopened for user %s", start.Format("2006-01"), userID)
+	return report, nil
+}
+
+// GetMonthCloseReport returns the snapshot taken when the month was last closed
+func (s *Services) GetMonthCloseReport(userID string, month time.Time) (*models.BudgetClose, error) {
+	var report models.BudgetClose
+	result := s.db.Where("user_id = ? AND month_year = ?", userID, models.MonthStart(month)).
+		Preload("Categories", func(db *gorm.DB) *gorm.DB {
+			return db.Order("category_name")
+		}).Limit(1).Find(&report)
+	if result.Error != nil {
+		logger.Error("Error getting month close report: %v", result.Error)
+		return nil, errors.New("error getting close report")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMonthNotClosed
+	}
+	return &report, nil
+}
+
+// CloseDueMonths closes the previous month period of every user with a budget for it, once the
+// grace days of the current period have passed. Months closed before, even if reopened since, are left
+// to the user
+func (s *Services) CloseDueMonths() error {
+	previous := models.MonthStart(time.Now().UTC()).AddDate(0, -1, 0)
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.Budget{}).Distinct("user_id").
+		Where("month_year BETWEEN ? AND ? AND status IN ?", previous.AddDate(0, -1, 0), previous, models.GetVisibleStatuses()).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		logger.Error("Error listing users for month close: %v", err)
+		return err
+	}
+
+	grace := monthCloseGraceDays()
+	for _, userUUID := range userIDs {
+		userID := userUUID.String()
+		today := s.UserToday(userID)
+		calendar := s.getUserCalendar(userID)
+		if today.Before(calendar.monthStart(today).AddDate(0, 0, grace)) {
+			continue
+		}
+		month := calendar.currentMonth(today).AddDate(0, -1, 0)
+
+		var budgets, closes int64
+		if err := s.db.Model(&models.Budget{}).Where("user_id = ? AND month_year = ? AND status IN ?",
+			userID, month, models.GetVisibleStatuses()).Count(&budgets).Error; err != nil {
+			logger.Error("Error checking budget of user %s for month close: %v", userID, err)
+			continue
+		}
+		if err := s.db.Model(&models.BudgetClose{}).Where("user_id = ? AND month_year = ?", userID, month).
+			Count(&closes).Error; err != nil {
+			logger.Error("Error checking month close of user %s: %v", userID, err)
+			continue
+		}
+		if budgets == 0 || closes > 0 {
+			continue
+		}
+
+		if _, err := s.CloseMonth(userID, month, models.BudgetCloseScheduled); err != nil {
+			logger.Error("Error closing %s for user %s: %v", month.Format("2006-01"), userID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestCloseMonthFollowsUserMonth(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(5000))
+	wants := h.CreateCategory(t, user, "Dining out", models.ExpenseTypeWants)
+	userID := user.ID.String()
+
+	firstDay := 15
+	if _, err := h.Services.UpdateUserSettings(userID, services.UserSettingsUpdate{FirstDayOfMonth: &firstDay}); err != nil {
+		t.Fatalf("updating settings: %v", err)
+	}
+
+	// Runs from the 15th of the month to the 14th of the next, both already past
+	month := models.MonthStart(time.Now().UTC()).AddDate(0, -3, 0)
+	if err := h.Budgets.Create(userID, &models.Budget{MonthYear: month, WantsBudget: models.NewMoney(300)}); err != nil {
+		t.Fatalf("creating budget: %v", err)
+	}
+	createExpense := func(date time.Time, amount models.Money) error {
+		return h.Expenses.Create(context.Background(), userID,
+			&models.Expense{CategoryID: wants.ID, BankAccountID: account.ID, Amount: amount, Date: date}, true)
+	}
+	for _, expense := range []struct {
+		date   time.Time
+		amount models.Money
+	}{
+		{month.AddDate(0, 0, 9), models.NewMoney(25)},  // Before the period
+		{month.AddDate(0, 0, 19), models.NewMoney(40)}, // In it
+		{month.AddDate(0, 1, 4), models.NewMoney(60)},  // In it, in the next calendar month
+	} {
+		if err := createExpense(expense.date, expense.amount); err != nil {
+			t.Fatalf("creating expense: %v", err)
+		}
+	}
+
+	report, err := h.Services.CloseMonth(userID, month, models.BudgetCloseManual)
+	if err != nil {
+		t.Fatalf("closing month: %v", err)
+	}
+	if report.ExpenseCount != 2 || report.TotalSpent != models.NewMoney(100) || report.WantsSpent != models.NewMoney(100) {
+		t.Errorf("closed with %d expenses, %s spent and %s on wants, want 2 and 100.00 on wants",
+			report.ExpenseCount, report.TotalSpent, report.WantsSpent)
+	}
+
+	cases := []struct {
+		name       string
+		date       time.Time
+		wantClosed bool
+	}{
+		{"locks the period in the next calendar month", month.AddDate(0, 1, 9), true},
+		{"locks the period in its own calendar month", month.AddDate(0, 0, 24), true},
+		{"leaves the period before open", month.AddDate(0, 0, 4), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := createExpense(tc.date, models.NewMoney(5))
+			var closedErr *services.MonthClosedError
+			if errors.As(err, &closedErr) != tc.wantClosed {
+				t.Fatalf("creating expense = %v, want the month closed: %t", err, tc.wantClosed)
+			}
+			if tc.wantClosed && !closedErr.Month.Equal(month) {
+				t.Errorf("closed month %s, want %s", closedErr.Month.Format("2006-01"), month.Format("2006-01"))
+			}
+			if !tc.wantClosed && err != nil {
+				t.Fatalf("creating expense: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// effectiveBudget weights each revision of a month's budget by how long it was in force over
+// the calendar month. See effectiveBudgetOver
+func effectiveBudget(budget models.Budget, revisions []models.BudgetRevision) models.Budget {
+	start := models.MonthStart(budget.MonthYear)
+	return effectiveBudgetOver(budget, revisions, start, start.AddDate(0, 1, 0))
+}
+
+// effectiveBudgetOver weights each revision of a month's budget by how long it was in force
+// between start and end. A budget set up after the period started applies to the whole period
+// up to its first revision, and edits made after it ended only count if nothing earlier exists
+func effectiveBudgetOver(budget models.Budget, revisions []models.BudgetRevision, start, end time.Time) models.Budget {
+
+	inMonth := make([]models.BudgetRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		if revision.EffectiveFrom.Before(end) {
+			inMonth = append(inMonth, revision)
+		}
+	}
+	if len(inMonth) == 0 {
+		if len(revisions) > 0 {
+			return revisionAmounts(budget, revisions[0])
+		}
+		// Budgets created before revisions were recorded only have their current values
+		return budget
+	}
+
+	result := models.Budget{ID: budget.ID, UserID: budget.UserID, MonthYear: models.MonthStart(budget.MonthYear)}
+	total := end.Sub(start).Seconds()
+	var needs, wants, savings float64 // In Money units, rounded once at the end
+	for i, revision := range inMonth {
+		from := revision.EffectiveFrom
+		if i == 0 || from.Before(start) {
+			from = start
+		}
+		to := end
+		if i+1 < len(inMonth) {
+			to = inMonth[i+1].EffectiveFrom
+		}
+		if !to.After(from) {
+			continue // Superseded before the month started
+		}
+		weight := to.Sub(from).Seconds() / total
+		needs += float64(revision.NeedsBudget) * weight
+		wants += float64(revision.WantsBudget) * weight
+		savings += float64(revision.SavingsBudget) * weight
+	}
+	result.NeedsBudget = models.Money(math.Round(needs))
+	result.WantsBudget = models.Money(math.Round(wants))
+	result.SavingsBudget = models.Money(math.Round(savings))
+	return result
+}
+
+func revisionAmounts(budget models.Budget, revision models.BudgetRevision) models.Budget {
+	budget.NeedsBudget = revision.NeedsBudget
+	budget.WantsBudget = revision.WantsBudget
+	budget.SavingsBudget = revision.SavingsBudget
+	return budget
+}
+
+// computeBudgetCompliance evaluates one closed month of the user, over the month period of
+// the calendar
+func (s *Services) computeBudgetCompliance(userID string, budget models.Budget, currency *models.Currency, calendar userCalendar) (*models.BudgetCompliance, error) {
+	var revisions []models.BudgetRevision
+	if err := s.db.Where("budget_id = ?", budget.ID).Order("effective_from ASC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	start, last := calendar.monthPeriod(budget.MonthYear.Year(), budget.MonthYear.Month())
+	end := last.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	effective := effectiveBudgetOver(budget, revisions, start, last.AddDate(0, 0, 1))
+	spent, err := s.GetExpensesByExpenseType(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// Trips flagged as outside the budget don't count against the month
+	tripSpent, err := s.excludedTripSpendByType(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for expenseType, amount := range tripSpent {
+		spent[expenseType] -= amount
+	}
+
+	compliance := &models.BudgetCompliance{
+		UserID:        budget.UserID,
+		MonthYear:     models.MonthStart(budget.MonthYear),
+		NeedsBudget:   currency.RoundMoney(effective.NeedsBudget),
+		WantsBudget:   currency.RoundMoney(effective.WantsBudget),
+		SavingsBudget: currency.RoundMoney(effective.SavingsBudget),
+		NeedsSpent:    currency.RoundMoney(spent["Needs"]),
+		WantsSpent:    currency.RoundMoney(spent["Wants"]),
+		SavingsSpent:  currency.RoundMoney(spent["Savings"]),
+		ComputedAt:    time.Now().UTC(),
+	}
+	compliance.NeedsWithin = compliance.NeedsSpent <= compliance.NeedsBudget
+	compliance.WantsWithin = compliance.WantsSpent <= compliance.WantsBudget
+	compliance.SavingsWithin = compliance.SavingsSpent <= compliance.SavingsBudget
+	compliance.WithinBudget = compliance.NeedsWithin && compliance.WantsWithin && compliance.SavingsWithin
+
+	// Category lines aren't revised over time: the current amounts apply to the whole month
+	var lines []models.CategoryBudget
+	if err := s.db.Where("budget_id = ?", budget.ID).Preload("Category").Order("created_at, id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	categorySpent, err := s.categoryMonthSpend(userID, start, end, categoryBudgetIDs(lines), false, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range categoryBudgetLines(lines, categorySpent, currency) {
+		compliance.Categories = append(compliance.Categories, models.BudgetCategoryCompliance{
+			CategoryID:   lines[i].CategoryID,
+			CategoryName: line.CategoryName,
+			Budget:       line.Budget,
+			Spent:        line.Spent,
+			Within:       line.Within,
+		})
+		compliance.WithinBudget = compliance.WithinBudget && line.Within
+	}
+
+	totalBudget := compliance.NeedsBudget + compliance.WantsBudget + compliance.SavingsBudget
+	if totalBudget > 0 {
+		totalSpent := compliance.NeedsSpent + compliance.WantsSpent + compliance.SavingsSpent
+		compliance.UsagePercent = math.Round(totalSpent.Ratio(totalBudget)*10000) / 100
+	}
+	return compliance, nil
+}
+
+// BackfillBudgetCompliance computes and stores the compliance of every closed month that has
+// a budget, over the user's month periods. With recompute false, months already stored are
+// left alone
+func (s *Services) BackfillBudgetCompliance(userID string, recompute bool) (*dto.BudgetComplianceBackfill, error) {
+	calendar := s.getUserCalendar(userID)
+	currentMonth := calendar.currentMonth(s.UserToday(userID))
+
+	var budgets []models.Budget
+	if err := s.db.Where("user_id = ? AND month_year < ? AND status IN ?", userID, currentMonth, models.GetVisibleStatuses()).
+		Order("month_year ASC").Find(&budgets).Error; err != nil {
+		logger.Error("Error getting budgets for compliance backfill: %v", err)
+		return nil, errors.New("error backfilling budget compliance")
+	}
+
+	stored := make(map[string]bool)
+	if !recompute {
+		var months []time.Time
+		if err := s.db.Model(&models.BudgetCompliance{}).Where("user_id = ?", userID).
+			Pluck("month_year", &months).Error; err != nil {
+			logger.Error("Error getting stored budget compliance: %v", err)
+			return nil, errors.New("error backfilling budget compliance")
+		}
+		for _, month := range months {
+			stored[month.Format("2006-01")] = true
+		}
+	}
+
+	result := &dto.BudgetComplianceBackfill{Months: []string{}}
+	currency := s.GetUserCurrency(userID)
+	for _, budget := range budgets {
+		month := budget.MonthYear.Format("2006-01")
+		if stored[month] {
+			result.Skipped++
+			continue
+		}
+
+		compliance, err := s.computeBudgetCompliance(userID, budget, currency, calendar)
+		if err != nil {
+			logger.Error("Error computing budget compliance for %s: %v", month, err)
+			return nil, errors.New("error backfilling budget compliance")
+		}
+		if err := storeBudgetCompliance(s.db, compliance); err != nil {
+			logger.Error("Error storing budget compliance for %s: %v", month, err)
+			return nil, errors.New("error backfilling budget compliance")
+		}
+		result.Computed++
+		result.Months = append(result.Months, month)
+	}
+
+	logger.Info("Budget compliance backfill for user %s: %d computed, %d skipped", userID, result.Computed, result.Skipped)
+	return result, nil
+}
+
+// storeBudgetCompliance upserts the compliance of a month and replaces its category lines
+func storeBudgetCompliance(tx *gorm.DB, compliance *models.BudgetCompliance) error {
+	categories := compliance.Categories
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "month_year"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"needs_budget", "wants_budget", "savings_budget", "needs_spent", "wants_spent", "savings_spent",
+				"needs_within", "wants_within", "savings_within", "within_budget", "usage_percent", "computed_at", "updated_at",
+			}),
+		}).Omit("Categories").Create(compliance).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("compliance_id = ?", compliance.ID).Delete(&models.BudgetCategoryCompliance{}).Error; err != nil {
+			return err
+		}
+		if len(categories) == 0 {
+			return nil
+		}
+		for i := range categories {
+			categories[i].ComplianceID = compliance.ID
+		}
+		return tx.Create(&categories).Error
+	})
+}
+
+// GetBudgetCompliance returns the stored compliance of the user between two months, oldest first
+func (s *Services) GetBudgetCompliance(userID string, from, to time.Time) (*dto.BudgetComplianceHistory, error) {
+	var rows []models.BudgetCompliance
+	if err := s.db.Where("user_id = ? AND month_year BETWEEN ? AND ?", userID, models.MonthStart(from), models.MonthStart(to)).
+		Preload("Categories", func(db *gorm.DB) *gorm.DB {
+			return db.Order("category_name")
+		}).Order("month_year ASC").Find(&rows).Error; err != nil {
+		logger.Error("Error getting budget compliance: %v", err)
+		return nil, errors.New("error getting budget compliance")
+	}
+
+	history := &dto.BudgetComplianceHistory{Months: rows}
+	for _, row := range rows {
+		if row.WithinBudget {
+			history.MonthsWithinBudget++
+		}
+	}
+	if len(rows) > 0 {
+		history.AdherencePercent = math.Round(float64(history.MonthsWithinBudget)/float64(len(rows))*10000) / 100
+	}
+	return history, nil
+}
+
+func budgetLineCompliance(budget, spent models.Money) dto.BudgetLineCompliance {
+	return dto.BudgetLineCompliance{
+		Budget:       budget,
+		Spent:        spent,
+		Remaining:    budget - spent,
+		UsagePercent: math.Round(spent.Ratio(budget)*10000) / 100,
+		OverBudget:   spent > budget,
+	}
+}
+
+// ValidateMonthlyBudgetCompliance compares the budget of a month with the actual spending over
+// the user's month period, computed live so the current month shows its spending to date. Writes to the user's data
+// invalidate the cached result
+func (s *Services) ValidateMonthlyBudgetCompliance(userID string, year int, month time.Month) (*dto.MonthlyBudgetCompliance, error) {
+	key := fmt.Sprintf("budget-compliance:%04d-%02d", year, month)
+	return cachedSummary(context.Background(), userID, key, func() (*dto.MonthlyBudgetCompliance, error) {
+		return s.buildMonthlyBudgetCompliance(userID, year, month)
+	})
+}
+
+// buildMonthlyBudgetCompliance computes the budget compliance of a month
+func (s *Services) buildMonthlyBudgetCompliance(userID string, year int, month time.Month) (*dto.MonthlyBudgetCompliance, error) {
+	budget, err := s.Budgets.GetByMonth(userID, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := s.GetUserCurrency(userID)
+	calendar := s.getUserCalendar(userID)
+	compliance, err := s.computeBudgetCompliance(userID, *budget, currency, calendar)
+	if err != nil {
+		logger.Error("Error computing budget compliance: %v", err)
+		return nil, errors.New("error computing budget compliance")
+	}
+
+	from, to := calendar.monthPeriod(year, month)
+	result := &dto.MonthlyBudgetCompliance{
+		Month:      compliance.MonthYear.Format("2006-01"),
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		Closed:     compliance.MonthYear.Before(calendar.currentMonth(s.UserToday(userID))),
+		Currency:   currency.Code,
+		Needs:      budgetLineCompliance(compliance.NeedsBudget, compliance.NeedsSpent),
+		Wants:      budgetLineCompliance(compliance.WantsBudget, compliance.WantsSpent),
+		Savings:    budgetLineCompliance(compliance.SavingsBudget, compliance.SavingsSpent),
+		Categories: make([]dto.CategoryLineCompliance, 0, len(compliance.Categories)),
+		OverBudget: !compliance.WithinBudget,
+	}
+	result.Total = budgetLineCompliance(
+		compliance.NeedsBudget+compliance.WantsBudget+compliance.SavingsBudget,
+		compliance.NeedsSpent+compliance.WantsSpent+compliance.SavingsSpent,
+	)
+	for _, line := range compliance.Categories {
+		result.Categories = append(result.Categories, dto.CategoryLineCompliance{
+			CategoryID:           line.CategoryID.String(),
+			CategoryName:         line.CategoryName,
+			BudgetLineCompliance: budgetLineCompliance(line.Budget, line.Spent),
+		})
+	}
+	return result, nil
+}
+
+// BackfillAllBudgetCompliance fills the months missing for every user with budgets. Run as a
+// job at startup and then periodically, closed months get stored shortly after they end
+func (s *Services) BackfillAllBudgetCompliance() error {
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.Budget{}).Distinct("user_id").
+		Where("status IN ?", models.GetVisibleStatuses()).Pluck("user_id", &userIDs).Error; err != nil {
+		logger.Error("Error listing users for budget compliance: %v", err)
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if _, err := s.BackfillBudgetCompliance(userID.String(), false); err != nil {
+			logger.Error("Error backfilling budget compliance for user %s: %v", userID, err)
+		}
+	}
+	return nil
+}
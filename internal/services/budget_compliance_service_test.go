@@ -0,0 +1,131 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestMonthlyBudgetCompliance(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(5000))
+	needs := h.CreateCategory(t, user, "Rent", models.ExpenseTypeNeeds)
+	wants := h.CreateCategory(t, user, "Dining out", models.ExpenseTypeWants)
+	userID := user.ID.String()
+
+	// A past month, so the budget's only revision comes after it and applies in full
+	month := models.MonthStart(time.Now().UTC()).AddDate(0, -1, 0)
+	budget := &models.Budget{
+		MonthYear:     month,
+		NeedsBudget:   models.NewMoney(1000),
+		WantsBudget:   models.NewMoney(300),
+		SavingsBudget: models.NewMoney(200),
+	}
+	if err := h.Budgets.Create(userID, budget); err != nil {
+		t.Fatalf("creating budget: %v", err)
+	}
+
+	for _, expense := range []*models.Expense{
+		{CategoryID: needs.ID, Amount: models.NewMoney(800), Date: month.AddDate(0, 0, 2)},
+		{CategoryID: wants.ID, Amount: models.NewMoney(250), Date: month.AddDate(0, 0, 9)},
+		{CategoryID: wants.ID, Amount: models.NewMoney(100), Date: month.AddDate(0, 0, 20)},
+		// Outside the month
+		{CategoryID: needs.ID, Amount: models.NewMoney(500), Date: month.AddDate(0, 1, 0)},
+	} {
+		expense.BankAccountID = account.ID
+		if err := h.Expenses.Create(context.Background(), userID, expense, true); err != nil {
+			t.Fatalf("creating expense: %v", err)
+		}
+	}
+
+	compliance, err := h.Services.ValidateMonthlyBudgetCompliance(userID, month.Year(), month.Month())
+	if err != nil {
+		t.Fatalf("computing compliance: %v", err)
+	}
+
+	if compliance.Month != month.Format("2006-01") || !compliance.Closed {
+		t.Fatalf("compliance of %s (closed %t), want %s closed", compliance.Month, compliance.Closed, month.Format("2006-01"))
+	}
+	if compliance.Needs.Spent != models.NewMoney(800) || compliance.Needs.OverBudget {
+		t.Errorf("needs = %+v, want 800.00 spent within budget", compliance.Needs)
+	}
+	if compliance.Wants.Spent != models.NewMoney(350) || !compliance.Wants.OverBudget {
+		t.Errorf("wants = %+v, want 350.00 spent over budget", compliance.Wants)
+	}
+	if compliance.Wants.Remaining != models.NewMoney(-50) {
+		t.Errorf("wants remaining = %s, want -50.00", compliance.Wants.Remaining)
+	}
+	if compliance.Savings.Spent != 0 || compliance.Savings.OverBudget {
+		t.Errorf("savings = %+v, want nothing spent", compliance.Savings)
+	}
+	if compliance.Total.Budget != models.NewMoney(1500) || compliance.Total.Spent != models.NewMoney(1150) {
+		t.Errorf("total = %+v, want 1150.00 of 1500.00", compliance.Total)
+	}
+	if !compliance.OverBudget {
+		t.Error("month with a line over budget isn't over budget")
+	}
+}
+
+func TestMonthlyBudgetComplianceWithoutBudget(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+
+	month := models.MonthStart(time.Now().UTC()).AddDate(0, -1, 0)
+	if _, err := h.Services.ValidateMonthlyBudgetCompliance(user.ID.String(), month.Year(), month.Month()); err == nil {
+		t.Fatal("compliance of a month without budget didn't fail")
+	}
+}
+
+func TestMonthlyBudgetComplianceFollowsUserMonth(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(5000))
+	wants := h.CreateCategory(t, user, "Dining out", models.ExpenseTypeWants)
+	userID := user.ID.String()
+
+	firstDay := 15
+	if _, err := h.Services.UpdateUserSettings(userID, services.UserSettingsUpdate{FirstDayOfMonth: &firstDay}); err != nil {
+		t.Fatalf("updating settings: %v", err)
+	}
+
+	// Runs from the 15th of the month to the 14th of the next, both already past
+	month := models.MonthStart(time.Now().UTC()).AddDate(0, -2, 0)
+	budget := &models.Budget{MonthYear: month, WantsBudget: models.NewMoney(300)}
+	if err := h.Budgets.Create(userID, budget); err != nil {
+		t.Fatalf("creating budget: %v", err)
+	}
+
+	for _, date := range []time.Time{
+		month.AddDate(0, 0, 9),  // Before the period
+		month.AddDate(0, 0, 19), // In it
+		month.AddDate(0, 1, 4),  // In it, in the next calendar month
+		month.AddDate(0, 1, 19), // After it
+	} {
+		expense := &models.Expense{CategoryID: wants.ID, BankAccountID: account.ID, Amount: models.NewMoney(100), Date: date}
+		if err := h.Expenses.Create(context.Background(), userID, expense, true); err != nil {
+			t.Fatalf("creating expense: %v", err)
+		}
+	}
+
+	compliance, err := h.Services.ValidateMonthlyBudgetCompliance(userID, month.Year(), month.Month())
+	if err != nil {
+		t.Fatalf("computing compliance: %v", err)
+	}
+	if want := month.AddDate(0, 0, 14).Format("2006-01-02"); compliance.From != want {
+		t.Errorf("period starts %s, want %s", compliance.From, want)
+	}
+	if want := month.AddDate(0, 1, 13).Format("2006-01-02"); compliance.To != want {
+		t.Errorf("period ends %s, want %s", compliance.To, want)
+	}
+	if compliance.Wants.Spent != models.NewMoney(200) {
+		t.Errorf("wants spent = %s, want 200.00 from the two expenses in the period", compliance.Wants.Spent)
+	}
+	if !compliance.Closed {
+		t.Error("compliance of a past period isn't closed")
+	}
+}
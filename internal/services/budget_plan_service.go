@@ -0,0 +1,252 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Budget plan sources
+const (
+	BudgetPlanSourceTemplate  = "template"  // Same amounts every month
+	BudgetPlanSourceSuggested = "suggested" // 50/30/20 of the monthly income, or recent spending
+)
+
+// Budget plan conflict policies for months that already have a budget
+const (
+	BudgetPlanConflictFail      = "fail"
+	BudgetPlanConflictSkip      = "skip"
+	BudgetPlanConflictOverwrite = "overwrite"
+)
+
+// MaxBudgetPlanMonths is the longest plan that can be created at once
+const MaxBudgetPlanMonths = 12
+
+// ErrBudgetPlanConflict is returned when applying a plan with conflicts and the fail policy
+var ErrBudgetPlanConflict = errors.New("budget plan conflicts with existing budgets")
+
+// BudgetPlanOptions describes the plan to build
+type BudgetPlanOptions struct {
+	StartMonth time.Time
+	Months     int
+	Source     string
+	Template   *models.Budget // Amounts used with the template source
+	OnConflict string
+}
+
+// suggestBudget proposes monthly amounts: the declared income split by the user's budgeting
+// mode (50/30/20 by default), or the average spending per expense type over the last three
+// complete months when there's no income or the mode is recent_spending
+func (s *Services) suggestBudget(userID string) (*models.Budget, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := s.GetUserCurrency(userID)
+	split, splitsIncome := budgetingModeShares[preferences.BudgetingMode]
+	if preferences.BudgetingMode == "" {
+		split, splitsIncome = budgetingModeShares[BudgetingMode503020], true
+	}
+	if splitsIncome && user.MonthlyIncome != nil && *user.MonthlyIncome > 0 {
+		// Split instead of rounding each share so the three always add up to the income
+		shares := currency.SplitMoney(*user.MonthlyIncome, split)
+		return &models.Budget{
+			NeedsBudget:   shares[0],
+			WantsBudget:   shares[1],
+			SavingsBudget: shares[2],
+		}, nil
+	}
+
+	currentMonth := models.MonthStart(s.UserNow(userID))
+	byType, err := s.GetExpensesByExpenseType(userID, currentMonth.AddDate(0, -3, 0), currentMonth.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &models.Budget{
+		NeedsBudget:   currency.RoundMoney(byType["Needs"] / 3),
+		WantsBudget:   currency.RoundMoney(byType["Wants"] / 3),
+		SavingsBudget: currency.RoundMoney(byType["Savings"] / 3),
+	}
+	if suggestion.Total() <= 0 {
+		return nil, errors.New("not enough data to suggest a budget, set a monthly income or use a template")
+	}
+
+	return suggestion, nil
+}
+
+// buildBudgetPlan computes the plan against the budgets that exist when tx runs
+func (s *Services) buildBudgetPlan(tx *gorm.DB, userID string, opts BudgetPlanOptions) (*dto.BudgetPlan, map[string]uuid.UUID, error) {
+	if opts.Months < 1 || opts.Months > MaxBudgetPlanMonths {
+		return nil, nil, errors.New("months must be between 1 and 12")
+	}
+
+	if opts.OnConflict == "" {
+		opts.OnConflict = BudgetPlanConflictFail
+	}
+	if opts.OnConflict != BudgetPlanConflictFail && opts.OnConflict != BudgetPlanConflictSkip && opts.OnConflict != BudgetPlanConflictOverwrite {
+		return nil, nil, errors.New("invalid on_conflict. Must be one of: fail, skip, overwrite")
+	}
+
+	var amounts *models.Budget
+	switch opts.Source {
+	case BudgetPlanSourceTemplate:
+		if opts.Template == nil {
+			return nil, nil, errors.New("template amounts are required for the template source")
+		}
+		amounts = opts.Template
+	case BudgetPlanSourceSuggested:
+		suggestion, err := s.suggestBudget(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		amounts = suggestion
+	default:
+		return nil, nil, errors.New("invalid source. Must be one of: template, suggested")
+	}
+
+	if err := validateBudgetAmounts(amounts); err != nil {
+		return nil, nil, err
+	}
+
+	start := models.MonthStart(opts.StartMonth)
+	end := start.AddDate(0, opts.Months-1, 0)
+
+	var existing []models.Budget
+	result := tx.Where("user_id = ? AND month_year BETWEEN ? AND ? AND status IN ?",
+		userID, start, end, models.GetVisibleStatuses()).Find(&existing)
+	if result.Error != nil {
+		logger.Error("Error getting existing budgets: %v", result.Error)
+		return nil, nil, result.Error
+	}
+
+	existingByMonth := make(map[string]uuid.UUID, len(existing))
+	for _, budget := range existing {
+		existingByMonth[budget.MonthYear.Format("2006-01")] = budget.ID
+	}
+
+	plan := &dto.BudgetPlan{
+		Source:     opts.Source,
+		OnConflict: opts.OnConflict,
+		Months:     make([]dto.BudgetPlanMonth, 0, opts.Months),
+	}
+
+	for i := 0; i < opts.Months; i++ {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		item := dto.BudgetPlanMonth{
+			Month:         month,
+			NeedsBudget:   amounts.NeedsBudget,
+			WantsBudget:   amounts.WantsBudget,
+			SavingsBudget: amounts.SavingsBudget,
+			Action:        "create",
+		}
+
+		if existingID, ok := existingByMonth[month]; ok {
+			id := existingID.String()
+			item.ExistingBudgetID = &id
+			plan.Conflicts++
+
+			switch opts.OnConflict {
+			case BudgetPlanConflictSkip:
+				item.Action = "skip"
+			case BudgetPlanConflictOverwrite:
+				item.Action = "overwrite"
+			default:
+				item.Action = "conflict"
+			}
+		}
+
+		plan.Months = append(plan.Months, item)
+	}
+
+	return plan, existingByMonth, nil
+}
+
+// PreviewBudgetPlan builds a budget plan without saving anything
+func (s *Services) PreviewBudgetPlan(userID string, opts BudgetPlanOptions) (*dto.BudgetPlan, error) {
+	plan, _, err := s.buildBudgetPlan(s.db, userID, opts)
+	return plan, err
+}
+
+// ApplyBudgetPlan creates (or overwrites) the planned budgets in a single transaction.
+// With the fail policy and any conflict, nothing is saved and ErrBudgetPlanConflict is
+// returned together with the plan.
+func (s *Services) ApplyBudgetPlan(userID string, opts BudgetPlanOptions) (*dto.BudgetPlan, error) {
+	var plan *dto.BudgetPlan
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		built, existingByMonth, err := s.buildBudgetPlan(tx, userID, opts)
+		if err != nil {
+			return err
+		}
+		plan = built
+
+		if plan.Conflicts > 0 && plan.OnConflict == BudgetPlanConflictFail {
+			return ErrBudgetPlanConflict
+		}
+
+		for _, item := range plan.Months {
+			month, _ := time.Parse("2006-01", item.Month)
+
+			switch item.Action {
+			case "create":
+				budget := models.Budget{
+					UserID:        uuid.MustParse(userID),
+					MonthYear:     month,
+					NeedsBudget:   item.NeedsBudget,
+					WantsBudget:   item.WantsBudget,
+					SavingsBudget: item.SavingsBudget,
+					Status:        models.StatusActive,
+				}
+				if err := tx.Create(&budget).Error; err != nil {
+					return err
+				}
+				if err := recordBudgetRevision(tx, budget.ID, budget.UserID, &budget); err != nil {
+					return err
+				}
+				if err := EnqueueEvent(tx, budget.UserID, EventBudgetCreated, "budget", budget.ID, budgetEventPayload(&budget)); err != nil {
+					return err
+				}
+			case "overwrite":
+				budgetID := existingByMonth[item.Month]
+				if err := tx.Model(&models.Budget{}).Where("id = ?", budgetID).
+					Updates(map[string]interface{}{
+						"needs_budget":   item.NeedsBudget,
+						"wants_budget":   item.WantsBudget,
+						"savings_budget": item.SavingsBudget,
+					}).Error; err != nil {
+					return err
+				}
+				updated := models.Budget{MonthYear: month, NeedsBudget: item.NeedsBudget, WantsBudget: item.WantsBudget, SavingsBudget: item.SavingsBudget}
+				if err := recordBudgetRevision(tx, budgetID, uuid.MustParse(userID), &updated); err != nil {
+					return err
+				}
+				if err := EnqueueEvent(tx, uuid.MustParse(userID), EventBudgetUpdated, "budget", budgetID, budgetEventPayload(&updated)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrBudgetPlanConflict) {
+			logger.Error("Error applying budget plan: %v", err)
+		}
+		return plan, err
+	}
+
+	plan.Applied = true
+	logger.Info("Budget plan of %d months applied for user %s", len(plan.Months), userID)
+	return plan, nil
+}
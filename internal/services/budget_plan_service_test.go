@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestApplyBudgetPlan(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	start := models.MonthStart(time.Now().UTC()).AddDate(0, 1, 0)
+	template := &models.Budget{
+		NeedsBudget:   models.NewMoney(1000),
+		WantsBudget:   models.NewMoney(600),
+		SavingsBudget: models.NewMoney(400),
+	}
+
+	cases := []struct {
+		name        string
+		opts        services.BudgetPlanOptions
+		existing    bool // A budget of 100.00 needs already exists in the second month
+		wantActions []string
+		wantNeeds   []models.Money // Needs of the stored budgets, month by month
+		wantErr     string
+	}{
+		{
+			name:        "creates every month",
+			opts:        services.BudgetPlanOptions{Months: 3, Source: services.BudgetPlanSourceTemplate, Template: template},
+			wantActions: []string{"create", "create", "create"},
+			wantNeeds:   []models.Money{models.NewMoney(1000), models.NewMoney(1000), models.NewMoney(1000)},
+		},
+		{
+			name:        "skips existing budgets",
+			opts:        services.BudgetPlanOptions{Months: 3, Source: services.BudgetPlanSourceTemplate, Template: template, OnConflict: services.BudgetPlanConflictSkip},
+			existing:    true,
+			wantActions: []string{"create", "skip", "create"},
+			wantNeeds:   []models.Money{models.NewMoney(1000), models.NewMoney(100), models.NewMoney(1000)},
+		},
+		{
+			name:        "overwrites existing budgets",
+			opts:        services.BudgetPlanOptions{Months: 3, Source: services.BudgetPlanSourceTemplate, Template: template, OnConflict: services.BudgetPlanConflictOverwrite},
+			existing:    true,
+			wantActions: []string{"create", "overwrite", "create"},
+			wantNeeds:   []models.Money{models.NewMoney(1000), models.NewMoney(1000), models.NewMoney(1000)},
+		},
+		{
+			name:        "fails on existing budgets without saving",
+			opts:        services.BudgetPlanOptions{Months: 3, Source: services.BudgetPlanSourceTemplate, Template: template},
+			existing:    true,
+			wantActions: []string{"create", "conflict", "create"},
+			wantNeeds:   []models.Money{models.NewMoney(100)},
+			wantErr:     services.ErrBudgetPlanConflict.Error(),
+		},
+		{
+			name:    "rejects too many months",
+			opts:    services.BudgetPlanOptions{Months: services.MaxBudgetPlanMonths + 1, Source: services.BudgetPlanSourceTemplate, Template: template},
+			wantErr: "months must be between 1 and 12",
+		},
+		{
+			name:    "rejects an unknown source",
+			opts:    services.BudgetPlanOptions{Months: 1, Source: "copy"},
+			wantErr: "invalid source. Must be one of: template, suggested",
+		},
+		{
+			name:    "rejects a template source without amounts",
+			opts:    services.BudgetPlanOptions{Months: 1, Source: services.BudgetPlanSourceTemplate},
+			wantErr: "template amounts are required for the template source",
+		},
+		{
+			name:    "rejects a suggestion without data",
+			opts:    services.BudgetPlanOptions{Months: 1, Source: services.BudgetPlanSourceSuggested},
+			wantErr: "not enough data to suggest a budget, set a monthly income or use a template",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userID := h.CreateUser(t).ID.String()
+			if tc.existing {
+				existing := &models.Budget{MonthYear: start.AddDate(0, 1, 0), NeedsBudget: models.NewMoney(100)}
+				if err := h.Budgets.Create(userID, existing); err != nil {
+					t.Fatalf("creating budget: %v", err)
+				}
+			}
+
+			opts := tc.opts
+			opts.StartMonth = start
+			plan, err := h.Services.ApplyBudgetPlan(userID, opts)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("applying plan: %v", err)
+			}
+
+			if tc.wantActions != nil {
+				var actions []string
+				for _, month := range plan.Months {
+					actions = append(actions, month.Action)
+				}
+				if !reflect.DeepEqual(actions, tc.wantActions) {
+					t.Errorf("actions = %v, want %v", actions, tc.wantActions)
+				}
+				if plan.Applied != (tc.wantErr == "") {
+					t.Errorf("applied = %t, want %t", plan.Applied, tc.wantErr == "")
+				}
+			}
+
+			var needs []models.Money
+			if err := h.DB.Model(&models.Budget{}).Where("user_id = ?", userID).
+				Order("month_year").Pluck("needs_budget", &needs).Error; err != nil {
+				t.Fatalf("listing budgets: %v", err)
+			}
+			if len(needs) != len(tc.wantNeeds) || (len(needs) > 0 && !reflect.DeepEqual(needs, tc.wantNeeds)) {
+				t.Errorf("stored needs = %v, want %v", needs, tc.wantNeeds)
+			}
+		})
+	}
+}
+
+func TestPreviewBudgetPlanDoesNotSave(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	userID := h.CreateUser(t).ID.String()
+	budget := &models.Budget{MonthYear: models.MonthStart(time.Now().UTC()), NeedsBudget: models.NewMoney(100)}
+	if err := h.Budgets.Create(userID, budget); err != nil {
+		t.Fatalf("creating budget: %v", err)
+	}
+
+	plan, err := h.Services.PreviewBudgetPlan(userID, services.BudgetPlanOptions{
+		StartMonth: budget.MonthYear,
+		Months:     2,
+		Source:     services.BudgetPlanSourceTemplate,
+		Template:   &models.Budget{NeedsBudget: models.NewMoney(500)},
+	})
+	if err != nil {
+		t.Fatalf("previewing plan: %v", err)
+	}
+	if plan.Conflicts != 1 || plan.Applied {
+		t.Errorf("plan has %d conflicts (applied %t), want 1 not applied", plan.Conflicts, plan.Applied)
+	}
+	if plan.Months[0].ExistingBudgetID == nil || *plan.Months[0].ExistingBudgetID != budget.ID.String() {
+		t.Errorf("existing budget = %v, want %s", plan.Months[0].ExistingBudgetID, budget.ID)
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.Budget{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		t.Fatalf("counting budgets: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("preview stored %d budgets, want only the existing one", count)
+	}
+
+	if _, err := h.Services.ApplyBudgetPlan(userID, services.BudgetPlanOptions{
+		StartMonth: budget.MonthYear,
+		Months:     2,
+		Source:     services.BudgetPlanSourceTemplate,
+		Template:   &models.Budget{NeedsBudget: models.NewMoney(500)},
+	}); !errors.Is(err, services.ErrBudgetPlanConflict) {
+		t.Errorf("applying the previewed plan = %v, want the conflict", err)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// Budget review cadences; the empty cadence turns automatic reviews off
+const (
+	BudgetReviewWeekly  = "weekly"
+	BudgetReviewMonthly = "monthly"
+)
+
+// IsValidBudgetReviewCadence checks if a given string is a valid budget review cadence
+func IsValidBudgetReviewCadence(cadence string) bool {
+	return cadence == "" || cadence == BudgetReviewWeekly || cadence == BudgetReviewMonthly
+}
+
+// budgetReviewPeriod returns the last full period before now and the day its review is due
+// (the first day of the period now is in). Weeks and months start on the days of the user's
+// calendar
+func budgetReviewPeriod(cadence string, now time.Time, calendar userCalendar) (start, end, due time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if cadence == BudgetReviewWeekly {
+		due = calendar.weekStart(today)
+		return due.AddDate(0, 0, -7), due.AddDate(0, 0, -1), due
+	}
+	due = calendar.monthStart(today)
+	return due.AddDate(0, -1, 0), due.AddDate(0, 0, -1), due
+}
+
+// budgetReviewLink points to the budget-vs-actual report of the period: the stored compliance
+// of the month, or the spending summary of the week or of a month not starting on the 1st
+func budgetReviewLink(cadence string, start, end time.Time) string {
+	if cadence == BudgetReviewWeekly || start.Day() != 1 {
+		return fmt.Sprintf("/api/v1/expenses/summary?start_date=%s&end_date=%s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	month := start.Format("2006-01")
+	return fmt.Sprintf("/api/v1/budgets/compliance?from=%s&to=%s", month, month)
+}
+
+// GetBudgetReviewStatus returns the review cadence of the user and how up to date the reviews are
+func (s *Services) GetBudgetReviewStatus(userID string) (*dto.BudgetReviewStatus, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error getting budget review status")
+	}
+
+	now := s.UserNow(userID)
+	status := &dto.BudgetReviewStatus{Cadence: preferences.BudgetReviewCadence}
+	if preferences.LastBudgetReviewAt != nil {
+		lastReviewed := preferences.LastBudgetReviewAt.Format(time.RFC3339)
+		days := int(now.Sub(*preferences.LastBudgetReviewAt).Hours() / 24)
+		status.LastReviewedAt = &lastReviewed
+		status.DaysSinceReview = &days
+	}
+	if status.Cadence != "" {
+		_, _, due := budgetReviewPeriod(status.Cadence, now, s.getUserCalendar(userID))
+		if status.Cadence == BudgetReviewWeekly {
+			due = due.AddDate(0, 0, 7)
+		} else {
+			due = due.AddDate(0, 1, 0)
+		}
+		nextDue := due.Format("2006-01-02")
+		status.NextReviewDue = &nextDue
+	}
+
+	if err := s.db.Model(&models.Reminder{}).
+		Where("user_id = ? AND reminder_type = ? AND status IN ? AND is_completed = ? AND due_date <= ?",
+			userID, models.ReminderTypeBudgetReview, models.GetActiveStatuses(), false, now).
+		Count(&status.PendingReviews).Error; err != nil {
+		logger.Error("Error counting pending budget reviews: %v", err)
+		return nil, errors.New("error getting budget review status")
+	}
+	status.Overdue = status.PendingReviews > 0
+
+	return status, nil
+}
+
+// SetBudgetReviewCadence schedules weekly or monthly budget_review reminders, or stops them
+func (s *Services) SetBudgetReviewCadence(userID string, cadence string) error {
+	if !IsValidBudgetReviewCadence(cadence) {
+		return errors.New("invalid cadence: must be weekly, monthly or empty")
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return errors.New("error updating budget review cadence")
+	}
+
+	preferences.BudgetReviewCadence = cadence
+	if err := s.saveUserPreferences(preferences, "budget_review_cadence"); err != nil {
+		logger.Error("Error saving budget review cadence: %v", err)
+		return errors.New("error updating budget review cadence")
+	}
+	return nil
+}
+
+// RecordBudgetReview stores when the user last reviewed their budget, which insights use to
+// tell how up to date the budget is
+func (s *Services) RecordBudgetReview(userID string) error {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return err
+	}
+
+	now := s.UserNow(userID)
+	preferences.LastBudgetReviewAt = &now
+	if err := s.saveUserPreferences(preferences, "last_budget_review_at"); err != nil {
+		logger.Error("Error recording budget review: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateBudgetReviewReminders creates the budget_review reminders of users with a review
+// cadence; it runs periodically as a job
+func (s *Services) CreateBudgetReviewReminders() error {
+	var preferences []models.UserPreferences
+	if err := s.db.Where("budget_review_cadence <> ''").
+		Where("user_id IN (?)", s.db.Model(&models.User{}).Select("id").Where("status = ?", models.StatusActive)).
+		Find(&preferences).Error; err != nil {
+		logger.Error("Error listing users for budget reviews: %v", err)
+		return err
+	}
+
+	for _, preference := range preferences {
+		if err := s.createBudgetReviewReminder(preference.UserID, preference.BudgetReviewCadence); err != nil {
+			logger.Error("Error creating budget review reminder for user %s: %v", preference.UserID, err)
+		}
+	}
+	return nil
+}
+
+// createBudgetReviewReminder creates the reminder to review the last full period, once per
+// period: reminders the user deleted aren't created again
+func (s *Services) createBudgetReviewReminder(userID uuid.UUID, cadence string) error {
+	start, end, due := budgetReviewPeriod(cadence, s.UserNow(userID.String()), s.getUserCalendar(userID.String()))
+
+	var existing int64
+	if err := s.db.Model(&models.Reminder{}).
+		Where("user_id = ? AND reminder_type = ? AND period_start = ?", userID, models.ReminderTypeBudgetReview, start).
+		Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	title := "Monthly budget review: " + start.Format("January 2006")
+	if cadence == BudgetReviewWeekly {
+		title = "Weekly budget review: " + start.Format("Jan 2") + " - " + end.Format("Jan 2, 2006")
+	} else if start.Day() != 1 {
+		// Months starting on another day span two calendar months
+		title = "Monthly budget review: " + start.Format("Jan 2") + " - " + end.Format("Jan 2, 2006")
+	}
+	description := "Compare what you spent with your budget and adjust it for the coming period"
+	link := budgetReviewLink(cadence, start, end)
+	now := time.Now()
+
+	reminder := &models.Reminder{
+		ID:           uuid.New(),
+		UserID:       userID,
+		Title:        title,
+		Description:  &description,
+		DueDate:      due,
+		ReminderType: models.ReminderTypeBudgetReview,
+		Link:         &link,
+		PeriodStart:  &start,
+		Status:       models.StatusActive,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.db.Create(reminder).Error; err != nil {
+		return err
+	}
+
+	logger.Info("Budget review reminder created for user %s: %s", userID, title)
+	return nil
+}
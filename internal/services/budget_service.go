@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/repository"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetService creates, reads and changes the monthly budgets of users on its database
+type BudgetService struct {
+	db      *gorm.DB
+	budgets repository.BudgetRepository
+}
+
+// NewBudgetService returns a budget service using the given database and repository
+func NewBudgetService(database *gorm.DB, budgets repository.BudgetRepository) *BudgetService {
+	return &BudgetService{db: database, budgets: budgets}
+}
+
+// validateBudgetAmounts checks that no budget line is negative and that the budget isn't empty
+func validateBudgetAmounts(budget *models.Budget) error {
+	if budget.NeedsBudget < 0 || budget.WantsBudget < 0 || budget.SavingsBudget < 0 {
+		return errors.New("budget amounts cannot be negative")
+	}
+	if budget.Total() <= 0 {
+		return errors.New("budget total must be positive")
+	}
+	return nil
+}
+
+// budgetEventPayload is the payload of budget domain events
+func budgetEventPayload(budget *models.Budget) map[string]interface{} {
+	return map[string]interface{}{
+		"month_year":     budget.MonthYear.Format("2006-01"),
+		"needs_budget":   budget.NeedsBudget,
+		"wants_budget":   budget.WantsBudget,
+		"savings_budget": budget.SavingsBudget,
+	}
+}
+
+// recordBudgetRevision stores the amounts a budget has from now on
+func recordBudgetRevision(tx *gorm.DB, budgetID uuid.UUID, userID uuid.UUID, budget *models.Budget) error {
+	return tx.Create(&models.BudgetRevision{
+		BudgetID:      budgetID,
+		UserID:        userID,
+		MonthYear:     budget.MonthYear,
+		NeedsBudget:   budget.NeedsBudget,
+		WantsBudget:   budget.WantsBudget,
+		SavingsBudget: budget.SavingsBudget,
+		EffectiveFrom: time.Now().UTC(),
+	}).Error
+}
+
+// Create creates the budget of a month for the user
+func (s *BudgetService) Create(userID string, budget *models.Budget) error {
+	// Force the UserID and Status to prevent manipulation
+	budget.UserID = uuid.MustParse(userID)
+	budget.Status = models.StatusActive
+	budget.MonthYear = models.MonthStart(budget.MonthYear)
+
+	if err := validateBudgetAmounts(budget); err != nil {
+		return err
+	}
+
+	exists, err := s.budgets.ExistsForMonth(userID, budget.MonthYear, nil)
+	if err != nil {
+		logger.Error("Error checking existing budget: %v", err)
+		return err
+	}
+	if exists {
+		return errors.New("a budget already exists for this month")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(budget).Error; err != nil {
+			return err
+		}
+		if err := recordBudgetRevision(tx, budget.ID, budget.UserID, budget); err != nil {
+			return err
+		}
+		return EnqueueEvent(tx, budget.UserID, EventBudgetCreated, "budget", budget.ID, budgetEventPayload(budget))
+	})
+	if err != nil {
+		logger.Error("Error creating budget: %v", err)
+		return err
+	}
+
+	logger.Info("Budget created successfully for %s (user %s)", budget.MonthYear.Format("2006-01"), userID)
+	return nil
+}
+
+// GetByID gets a specific budget of the user
+func (s *BudgetService) GetByID(userID string, id string) (*models.Budget, error) {
+	budget, err := s.budgets.FindByID(userID, id, models.GetVisibleStatuses())
+	if err != nil {
+		logger.Error("Budget not found: %v", err)
+		return nil, errors.New("budget not found or access denied")
+	}
+
+	return budget, nil
+}
+
+// GetByMonth gets the budget of the user for a month
+func (s *BudgetService) GetByMonth(userID string, year int, month time.Month) (*models.Budget, error) {
+	budget, err := s.budgets.FindByMonth(userID, time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), models.GetVisibleStatuses())
+	if err != nil {
+		return nil, errors.New("budget not found for this month")
+	}
+
+	return budget, nil
+}
+
+// GetAll gets the budgets of the user, optionally for a single year
+func (s *BudgetService) GetAll(userID string, year *int, includeDeleted bool, page PageRequest) ([]models.Budget, PageInfo, error) {
+	var budgets []models.Budget
+	query := s.db.Model(&models.Budget{}).Where("user_id = ?", userID)
+
+	if !includeDeleted {
+		query = query.Where("status IN ?", models.GetVisibleStatuses())
+	}
+
+	if year != nil {
+		query = query.Where("EXTRACT(YEAR FROM month_year) = ?", *year)
+	}
+
+	info, err := paginate(query, "month_year DESC, id", page, &budgets, repository.PreloadCategoryBudgets)
+	if err != nil {
+		logger.Error("Error getting budgets: %v", err)
+		return nil, PageInfo{}, err
+	}
+
+	return budgets, info, nil
+}
+
+// Patch updates the amounts of a budget. budget.Version must be the version the change is
+// based on; ErrVersionConflict is returned when the budget was changed since
+func (s *BudgetService) Patch(userID string, id string, budget *models.Budget) (*models.Budget, error) {
+	existingBudget, err := s.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateBudgetAmounts(budget); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(existingBudget).Omit("CategoryBudgets").Where("version = ?", budget.Version).Updates(map[string]interface{}{
+			"needs_budget":   budget.NeedsBudget,
+			"wants_budget":   budget.WantsBudget,
+			"savings_budget": budget.SavingsBudget,
+			"version":        gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		budget.MonthYear = existingBudget.MonthYear
+		if err := recordBudgetRevision(tx, existingBudget.ID, existingBudget.UserID, budget); err != nil {
+			return err
+		}
+		return EnqueueEvent(tx, existingBudget.UserID, EventBudgetUpdated, "budget", existingBudget.ID, budgetEventPayload(budget))
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		logger.Warn("Budget %s changed since version %d was read", id, budget.Version)
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Error updating budget: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Budget updated successfully: %s", id)
+	return s.GetByID(userID, id)
+}
+
+// SoftDelete marks a budget as deleted
+func (s *BudgetService) SoftDelete(userID string, id string) error {
+	existingBudget, err := s.GetByID(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.budgets.UpdateStatus(existingBudget, models.StatusDeleted, time.Now()); err != nil {
+		logger.Error("Error deleting budget: %v", err)
+		return err
+	}
+
+	logger.Info("Budget deleted successfully: %s", id)
+	return nil
+}
+
+// Restore restores a deleted budget if its month is still free
+func (s *BudgetService) Restore(userID string, id string) (*models.Budget, error) {
+	budget, err := s.budgets.FindByID(userID, id, []models.Status{models.StatusDeleted})
+	if err != nil {
+		return nil, errors.New("budget not found, not deleted, or access denied")
+	}
+
+	exists, err := s.budgets.ExistsForMonth(userID, budget.MonthYear, &budget.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("cannot restore: a budget already exists for this month")
+	}
+
+	if err := s.budgets.UpdateStatus(budget, models.StatusActive, time.Now()); err != nil {
+		logger.Error("Error restoring budget: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Budget restored successfully: %s", id)
+	return budget, nil
+}
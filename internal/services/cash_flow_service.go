@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// cashFlowRow is what moved through one account in the month and after it. Amounts dated
+// after the month are already in the current balance and are taken back out of it
+type cashFlowRow struct {
+	BankAccountID uuid.UUID
+	InMonth       models.Money
+	Later         models.Money
+	Refunds       models.Money
+}
+
+// cashFlowAccountMoves accumulates the moves of an account
+type cashFlowAccountMoves struct {
+	inflows, outflows, transfersIn, transfersOut models.Money
+	later                                        models.Money // Net effect of records dated after the month
+}
+
+// transferCashFlowRows sums the transfers leaving (column from_account_id) or entering
+// (to_account_id) each account
+func (s *Services) transferCashFlowRows(ctx context.Context, userID, column string, start, end time.Time) ([]cashFlowRow, error) {
+	var rows []cashFlowRow
+	result := s.db.WithContext(ctx).Model(&models.Transfer{}).
+		Select(column+" as bank_account_id, "+
+			"COALESCE(SUM(amount) FILTER (WHERE date <= ?), 0) as in_month, "+
+			"COALESCE(SUM(amount) FILTER (WHERE date > ?), 0) as later", end, end).
+		Where("user_id = ? AND date >= ? AND status IN ?", userID, start, models.GetActiveStatuses()).
+		Group(column).
+		Scan(&rows)
+	return rows, result.Error
+}
+
+// incomeBySource breaks the income between from and to down by source, largest first.
+// Refunds are left out unless withRefunds
+func (s *Services) incomeBySource(ctx context.Context, userID string, from, to time.Time, withRefunds bool, currency *models.Currency) ([]dto.IncomeSourceAmount, error) {
+	var rows []struct {
+		Source string
+		Amount models.Money
+	}
+	query := s.db.WithContext(ctx).Model(&models.Income{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, from, to, models.GetActiveStatuses())
+	if !withRefunds {
+		query = query.Where("refund_of_expense_id IS NULL")
+	}
+	if err := query.Select("source, COALESCE(SUM(amount), 0) as amount").Group("source").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var total models.Money
+	sources := make([]dto.IncomeSourceAmount, 0, len(rows))
+	for _, row := range rows {
+		amount := currency.RoundMoney(row.Amount)
+		if amount == 0 {
+			continue
+		}
+		total += amount
+		sources = append(sources, dto.IncomeSourceAmount{
+			Source: row.Source,
+			Name:   models.GetIncomeSourceName(row.Source),
+			Amount: amount,
+		})
+	}
+	for i := range sources {
+		sources[i].Percent = roundPercent(sources[i].Amount.Ratio(total) * 100)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Amount != sources[j].Amount {
+			return sources[i].Amount > sources[j].Amount
+		}
+		return sources[i].Source < sources[j].Source
+	})
+	return sources, nil
+}
+
+// GetMonthlyCashFlow combines the incomes, expenses, fixed expenses and transfers of a month
+// into one statement with the opening and closing balance of every account. It is cash basis:
+// expenses count in full and refunds are inflows of the month they were received, so the net
+// change matches what the balances did. Statements are served from the summary cache until
+// the user's data changes
+func (s *Services) GetMonthlyCashFlow(ctx context.Context, userID string, year int, month int) (*dto.CashFlowReport, error) {
+	return cachedSummary(ctx, userID, fmt.Sprintf("cash-flow:%04d-%02d", year, month), func() (*dto.CashFlowReport, error) {
+		return s.buildMonthlyCashFlow(ctx, userID, year, month)
+	})
+}
+
+// buildMonthlyCashFlow computes the cash flow statement of a month
+func (s *Services) buildMonthlyCashFlow(ctx context.Context, userID string, year int, month int) (*dto.CashFlowReport, error) {
+	if month < 1 || month > 12 {
+		return nil, errors.New("invalid month: must be between 1 and 12")
+	}
+	if year < 1900 || year > 9999 {
+		return nil, errors.New("invalid year")
+	}
+	// The month is the user's month period, which may start after the 1st
+	start, end := s.getUserCalendar(userID).monthPeriod(year, time.Month(month))
+
+	var accounts []models.BankAccount
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("account_name").Find(&accounts).Error; err != nil {
+		logger.Error("Error getting bank accounts for cash flow: %v", err)
+		return nil, errors.New("error calculating cash flow")
+	}
+	moves := make(map[uuid.UUID]*cashFlowAccountMoves, len(accounts))
+	for _, account := range accounts {
+		moves[account.ID] = &cashFlowAccountMoves{}
+	}
+	accountMoves := func(id uuid.UUID) *cashFlowAccountMoves {
+		if _, ok := moves[id]; !ok {
+			moves[id] = &cashFlowAccountMoves{}
+		}
+		return moves[id]
+	}
+
+	report := &dto.CashFlowReport{
+		Year:  year,
+		Month: month,
+		From:  start.Format("2006-01-02"),
+		To:    end.Format("2006-01-02"),
+	}
+
+	// Incomes, with refunds apart. Incomes without an account count as inflows of no account
+	var incomeRows []cashFlowRow
+	result := s.db.WithContext(ctx).Model(&models.Income{}).
+		Select("bank_account_id, "+
+			"COALESCE(SUM(amount) FILTER (WHERE date <= ?), 0) as in_month, "+
+			"COALESCE(SUM(amount) FILTER (WHERE date > ?), 0) as later, "+
+			"COALESCE(SUM(amount) FILTER (WHERE date <= ? AND refund_of_expense_id IS NOT NULL), 0) as refunds", end, end, end).
+		Where("user_id = ? AND date >= ? AND status IN ?", userID, start, models.GetActiveStatuses()).
+		Group("bank_account_id").
+		Scan(&incomeRows)
+	if result.Error != nil {
+		logger.Error("Error calculating cash flow incomes: %v", result.Error)
+		return nil, errors.New("error calculating cash flow")
+	}
+	for _, row := range incomeRows {
+		account := accountMoves(row.BankAccountID)
+		account.inflows += row.InMonth
+		account.later += row.Later
+		report.Inflows.Income += row.InMonth - row.Refunds
+		report.Inflows.Refunds += row.Refunds
+	}
+
+	// Expenses, split ones in each account they were paid from
+	var expenseRows []cashFlowRow
+	result = s.db.WithContext(ctx).Table("expenses e").
+		Joins("LEFT JOIN expense_allocations ea ON ea.expense_id = e.id").
+		Select("COALESCE(ea.bank_account_id, e.bank_account_id) as bank_account_id, "+
+			"COALESCE(SUM(COALESCE(ea.amount, e.amount)) FILTER (WHERE e.date <= ?), 0) as in_month, "+
+			"COALESCE(SUM(COALESCE(ea.amount, e.amount)) FILTER (WHERE e.date > ?), 0) as later", end, end).
+		Where("e.user_id = ? AND e.date >= ? AND e.status IN ?", userID, start, models.GetActiveStatuses()).
+		Group("COALESCE(ea.bank_account_id, e.bank_account_id)").
+		Scan(&expenseRows)
+	if result.Error != nil {
+		logger.Error("Error calculating cash flow expenses: %v", result.Error)
+		return nil, errors.New("error calculating cash flow")
+	}
+	for _, row := range expenseRows {
+		account := accountMoves(row.BankAccountID)
+		account.outflows += row.InMonth
+		account.later -= row.Later
+	}
+
+	var typeRows []struct {
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+	}
+	result = s.db.WithContext(ctx).Table("expenses e").
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Select("c.expense_type, COALESCE(SUM(e.amount), 0) as amount").
+		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?", userID, start, end, models.GetActiveStatuses()).
+		Group("c.expense_type").
+		Scan(&typeRows)
+	if result.Error != nil {
+		logger.Error("Error calculating cash flow by expense type: %v", result.Error)
+		return nil, errors.New("error calculating cash flow")
+	}
+	byType := make(map[models.ExpenseType]models.Money, len(typeRows))
+	for _, row := range typeRows {
+		byType[row.ExpenseType] += row.Amount
+	}
+
+	// Transfers move money between accounts and leave the total alone
+	transfersOut, err := s.transferCashFlowRows(ctx, userID, "from_account_id", start, end)
+	if err != nil {
+		logger.Error("Error calculating cash flow transfers: %v", err)
+		return nil, errors.New("error calculating cash flow")
+	}
+	transfersIn, err := s.transferCashFlowRows(ctx, userID, "to_account_id", start, end)
+	if err != nil {
+		logger.Error("Error calculating cash flow transfers: %v", err)
+		return nil, errors.New("error calculating cash flow")
+	}
+	for _, row := range transfersIn {
+		account := accountMoves(row.BankAccountID)
+		account.transfersIn += row.InMonth
+		account.later += row.Later
+	}
+	for _, row := range transfersOut {
+		account := accountMoves(row.BankAccountID)
+		account.transfersOut += row.InMonth
+		account.later -= row.Later
+		report.Transfers += row.InMonth
+	}
+
+	// Posted fixed expenses are already expenses; what is still to come is reported apart
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, year, time.Month(month))
+	if err != nil {
+		return nil, errors.New("error calculating cash flow")
+	}
+	for _, fixedExpense := range fixedExpenses {
+		report.FixedExpenses.Scheduled += fixedExpense.Amount
+		if !fixedExpense.NextDueDate.Before(start) && !fixedExpense.NextDueDate.After(end) {
+			report.FixedExpenses.Pending += fixedExpense.Amount
+		}
+	}
+
+	currency := s.GetUserCurrency(userID)
+	report.Inflows.BySource, err = s.incomeBySource(ctx, userID, start, end, false, currency)
+	if err != nil {
+		logger.Error("Error calculating cash flow income by source: %v", err)
+		return nil, errors.New("error calculating cash flow")
+	}
+	report.Currency = currency.Code
+	report.Inflows.Income = currency.RoundMoney(report.Inflows.Income)
+	report.Inflows.Refunds = currency.RoundMoney(report.Inflows.Refunds)
+	report.Inflows.Total = currency.RoundMoney(report.Inflows.Income + report.Inflows.Refunds)
+	report.Outflows.ByExpenseType = make([]dto.CashFlowTypeAmount, 0, len(models.ValidExpenseTypes()))
+	for _, expenseType := range models.ValidExpenseTypes() {
+		amount := currency.RoundMoney(byType[expenseType])
+		report.Outflows.ByExpenseType = append(report.Outflows.ByExpenseType, dto.CashFlowTypeAmount{
+			ExpenseType: string(expenseType),
+			Name:        models.GetExpenseTypeName(expenseType),
+			Amount:      amount,
+		})
+		report.Outflows.Total += amount
+	}
+	report.Outflows.Total = currency.RoundMoney(report.Outflows.Total)
+	report.FixedExpenses.Scheduled = currency.RoundMoney(report.FixedExpenses.Scheduled)
+	report.FixedExpenses.Pending = currency.RoundMoney(report.FixedExpenses.Pending)
+	report.Transfers = currency.RoundMoney(report.Transfers)
+	report.NetChange = currency.RoundMoney(report.Inflows.Total - report.Outflows.Total)
+
+	report.Accounts = make([]dto.CashFlowAccount, 0, len(accounts))
+	for _, account := range accounts {
+		move := moves[account.ID]
+		hasActivity := move.inflows != 0 || move.outflows != 0 || move.transfersIn != 0 || move.transfersOut != 0
+		if account.Status != models.StatusActive && !hasActivity {
+			continue
+		}
+		statement := dto.CashFlowAccount{
+			BankAccountID: account.ID.String(),
+			AccountName:   account.AccountName,
+			ManualBalance: account.ManualBalance,
+			Inflows:       currency.RoundMoney(move.inflows),
+			Outflows:      currency.RoundMoney(move.outflows),
+			TransfersIn:   currency.RoundMoney(move.transfersIn),
+			TransfersOut:  currency.RoundMoney(move.transfersOut),
+		}
+		statement.NetChange = currency.RoundMoney(move.inflows - move.outflows + move.transfersIn - move.transfersOut)
+		if !account.ManualBalance {
+			closing := currency.RoundMoney(account.Balance - move.later)
+			opening := currency.RoundMoney(closing - statement.NetChange)
+			statement.ClosingBalance = &closing
+			statement.OpeningBalance = &opening
+			report.ClosingBalance += closing
+			report.OpeningBalance += opening
+		}
+		report.Accounts = append(report.Accounts, statement)
+	}
+	report.OpeningBalance = currency.RoundMoney(report.OpeningBalance)
+	report.ClosingBalance = currency.RoundMoney(report.ClosingBalance)
+
+	logger.Info("Cash flow for %d-%02d calculated for user %s", year, month, userID)
+	return report, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// CategoryAppearanceUpdate changes the icon and color of one category. A nil field is left as
+// it is and an empty string clears it, so the category goes back to its expense type visuals
+type CategoryAppearanceUpdate struct {
+	CategoryID string
+	Icon       *string
+	Color      *string
+}
+
+// validateCategoryAppearance checks the icon against the allowed set and the color format.
+// Empty values are accepted, they mean "no custom value"
+func validateCategoryAppearance(icon, color *string) error {
+	if icon != nil && *icon != "" && !models.IsValidCategoryIcon(*icon) {
+		return errors.New("invalid icon: " + *icon)
+	}
+	if color != nil && *color != "" && !models.IsValidCategoryColor(*color) {
+		return errors.New("invalid color: " + *color + ". Must be #RRGGBB")
+	}
+	return nil
+}
+
+// appearanceColumns turns an update into the columns to write, with NULL for cleared values
+func appearanceColumns(update CategoryAppearanceUpdate) map[string]interface{} {
+	columns := make(map[string]interface{}, 2)
+	for column, value := range map[string]*string{"icon": update.Icon, "color": update.Color} {
+		if value == nil {
+			continue
+		}
+		if *value == "" {
+			columns[column] = nil
+		} else {
+			columns[column] = *value
+		}
+	}
+	return columns
+}
+
+// UpdateCategoryAppearances sets the icon and color of several categories at once. Either all
+// updates are applied or none is
+func (s *Services) UpdateCategoryAppearances(userID string, updates []CategoryAppearanceUpdate) ([]models.Category, error) {
+	if len(updates) == 0 {
+		return nil, errors.New("at least one category is required")
+	}
+	for _, update := range updates {
+		if err := validateCategoryAppearance(update.Icon, update.Color); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := make([]models.Category, 0, len(updates))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, update := range updates {
+			var category models.Category
+			if err := tx.Where("id = ? AND user_id = ? AND status IN ?", update.CategoryID, userID, models.GetVisibleStatuses()).
+				First(&category).Error; err != nil {
+				return errors.New("category not found or access denied: " + update.CategoryID)
+			}
+
+			if columns := appearanceColumns(update); len(columns) > 0 {
+				if err := tx.Model(&category).Updates(columns).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("id = ?", category.ID).First(&category).Error; err != nil {
+					return err
+				}
+			}
+			updated = append(updated, category)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error updating category appearances: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Appearance updated for %d categories of user %s", len(updated), userID)
+	return updated, nil
+}
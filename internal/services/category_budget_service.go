@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errCategoryBudgetNotFound = errors.New("category budget not found")
+
+// categoryMonthSpend returns the net amount spent in each category between two dates. Expenses
+// of trips left out of the budget only count when includeExcludedTrips is set, and only what was
+// paid from the accounts counts unless they are nil
+func (s *Services) categoryMonthSpend(userID string, start, end time.Time, categoryIDs []uuid.UUID, includeExcludedTrips bool, accounts []uuid.UUID) (map[uuid.UUID]models.Money, error) {
+	spent := make(map[uuid.UUID]models.Money, len(categoryIDs))
+	if len(categoryIDs) == 0 {
+		return spent, nil
+	}
+
+	query := filterExpensesByAccounts(s.summaryPeriodQuery(userID, start, end), accounts).Where("e.category_id IN ?", categoryIDs)
+	if !includeExcludedTrips {
+		query = query.Where("NOT " + excludedTripExpenseSQL)
+	}
+	var rows []struct {
+		CategoryID uuid.UUID
+		Amount     models.Money
+	}
+	if err := query.Select("e.category_id AS category_id, COALESCE(SUM(" + accountsExpenseAmountSQL(accounts) + "), 0) AS amount").
+		Group("e.category_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		spent[row.CategoryID] = row.Amount
+	}
+	return spent, nil
+}
+
+// categoryBudgetLines compares category budget lines with what was spent in each category
+func categoryBudgetLines(lines []models.CategoryBudget, spent map[uuid.UUID]models.Money, currency *models.Currency) []dto.CategoryBudgetLine {
+	result := make([]dto.CategoryBudgetLine, len(lines))
+	for i, line := range lines {
+		result[i] = dto.CategoryBudgetLine{
+			CategoryID:   line.CategoryID.String(),
+			CategoryName: line.Category.Name,
+			ExpenseType:  string(line.Category.ExpenseType),
+			Budget:       currency.RoundMoney(line.Amount),
+			Spent:        currency.RoundMoney(spent[line.CategoryID]),
+		}
+		result[i].Remaining = currency.RoundMoney(result[i].Budget - result[i].Spent)
+		result[i].Within = result[i].Spent <= result[i].Budget
+		if result[i].Budget > 0 {
+			result[i].UsagePercent = math.Round(result[i].Spent.Ratio(result[i].Budget)*10000) / 100
+		}
+	}
+	return result
+}
+
+func categoryBudgetIDs(lines []models.CategoryBudget) []uuid.UUID {
+	ids := make([]uuid.UUID, len(lines))
+	for i, line := range lines {
+		ids[i] = line.CategoryID
+	}
+	return ids
+}
+
+// SetCategoryBudget sets the budget line of a category in a monthly budget, creating it when
+// missing. It returns whether the line was created
+func (s *Services) SetCategoryBudget(userID string, budgetID string, categoryID string, amount models.Money) (*models.CategoryBudget, bool, error) {
+	if amount <= 0 {
+		return nil, false, errors.New("invalid amount: must be positive")
+	}
+	budget, err := s.Budgets.GetByID(userID, budgetID)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := uuid.Parse(categoryID); err != nil {
+		return nil, false, errors.New("invalid category ID")
+	}
+	category, err := s.GetUserCategoryByID(userID, categoryID)
+	if err != nil {
+		return nil, false, errors.New("category not found")
+	}
+
+	line := models.CategoryBudget{
+		BudgetID:   budget.ID,
+		UserID:     budget.UserID,
+		CategoryID: category.ID,
+		Amount:     amount,
+	}
+	created := true
+	for _, existing := range budget.CategoryBudgets {
+		if existing.CategoryID == category.ID {
+			created = false
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "budget_id"}, {Name: "category_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"amount", "updated_at"}),
+		}).Omit("Category").Create(&line).Error; err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, &models.Budget{}, budget.ID); err != nil {
+			return err
+		}
+		payload := budgetEventPayload(budget)
+		payload["category_id"] = category.ID
+		payload["category_budget"] = amount
+		return EnqueueEvent(tx, budget.UserID, EventBudgetUpdated, "budget", budget.ID, payload)
+	})
+	if err != nil {
+		logger.Error("Error setting category budget: %v", err)
+		return nil, false, errors.New("error setting category budget")
+	}
+
+	line.Category = *category
+	logger.Info("Category budget of %s set to %s in budget %s", category.ID, amount, budget.ID)
+	return &line, created, nil
+}
+
+// DeleteCategoryBudget removes the budget line of a category from a monthly budget
+func (s *Services) DeleteCategoryBudget(userID string, budgetID string, categoryID string) error {
+	budget, err := s.Budgets.GetByID(userID, budgetID)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(categoryID)
+	if err != nil {
+		return errors.New("invalid category ID")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("budget_id = ? AND category_id = ?", budget.ID, id).Delete(&models.CategoryBudget{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCategoryBudgetNotFound
+		}
+		if err := bumpVersion(tx, &models.Budget{}, budget.ID); err != nil {
+			return err
+		}
+		payload := budgetEventPayload(budget)
+		payload["category_id"] = id
+		payload["category_budget"] = nil
+		return EnqueueEvent(tx, budget.UserID, EventBudgetUpdated, "budget", budget.ID, payload)
+	})
+	if err != nil {
+		if errors.Is(err, errCategoryBudgetNotFound) {
+			return err
+		}
+		logger.Error("Error deleting category budget: %v", err)
+		return errors.New("error deleting category budget")
+	}
+	return nil
+}
+
+// GetCategoryBudgetReport compares each category line of a budget with what was spent in the
+// category during the month so far. Like compliance, trips left out of the budget don't count
+func (s *Services) GetCategoryBudgetReport(userID string, budgetID string) (*dto.CategoryBudgetReport, error) {
+	budget, err := s.Budgets.GetByID(userID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := models.MonthStart(budget.MonthYear)
+	end := start.AddDate(0, 1, -1)
+	spent, err := s.categoryMonthSpend(userID, start, end, categoryBudgetIDs(budget.CategoryBudgets), false, nil)
+	if err != nil {
+		logger.Error("Error getting category budget spend: %v", err)
+		return nil, errors.New("error getting category budget report")
+	}
+
+	currency := s.GetUserCurrency(userID)
+	report := &dto.CategoryBudgetReport{
+		BudgetID: budget.ID.String(),
+		Month:    start.Format("2006-01"),
+		Currency: currency.Code,
+		Lines:    categoryBudgetLines(budget.CategoryBudgets, spent, currency),
+	}
+	for _, line := range report.Lines {
+		report.Budgeted += line.Budget
+		report.Spent += line.Spent
+	}
+	report.Budgeted = currency.RoundMoney(report.Budgeted)
+	report.Spent = currency.RoundMoney(report.Spent)
+	return report, nil
+}
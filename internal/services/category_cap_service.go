@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// CategoryCapExceededError is returned when an expense would push a hard-capped
+// category over its monthly cap and no override was given
+type CategoryCapExceededError struct {
+	CategoryID uuid.UUID
+	Cap        models.Money
+	Spent      models.Money // Net spent in the month before the new expense
+	Attempted  models.Money
+}
+
+func (e *CategoryCapExceededError) Error() string {
+	return fmt.Sprintf("category cap exceeded: cap %s, spent %s, attempted %s", e.Cap, e.Spent, e.Attempted)
+}
+
+// Remaining returns how much can still be spent in the category this month
+func (e *CategoryCapExceededError) Remaining() models.Money {
+	if e.Spent >= e.Cap {
+		return 0
+	}
+	return e.Cap - e.Spent
+}
+
+// getCategoryMonthSpent returns the net amount spent in a category during the user's month
+// period containing date
+func (s *Services) getCategoryMonthSpent(userID string, categoryID uuid.UUID, date time.Time) (models.Money, error) {
+	monthStart := s.getUserCalendar(userID).monthStart(date)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	var spent models.Money
+	result := s.db.Table("expenses e").Scopes(joinExpenseRefunds).
+		Select("COALESCE(SUM("+netExpenseAmountSQL()+"), 0)").
+		Where("e.user_id = ? AND e.category_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?",
+			userID, categoryID, monthStart, monthEnd, models.GetActiveStatuses()).
+		Scan(&spent)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return spent, nil
+}
+
+// checkCategoryCap verifies a new expense against the monthly cap of its category.
+// It returns whether the cap is exceeded; in hard mode without override it also returns
+// a *CategoryCapExceededError.
+func (s *Services) checkCategoryCap(userID string, category models.Category, expense *models.Expense, overrideCap bool) (bool, error) {
+	if category.MonthlyCap == nil {
+		return false, nil
+	}
+
+	spent, err := s.getCategoryMonthSpent(userID, category.ID, expense.Date)
+	if err != nil {
+		logger.Error("Error calculating category spending: %v", err)
+		return false, err
+	}
+
+	if spent+expense.Amount <= *category.MonthlyCap {
+		return false, nil
+	}
+
+	if category.CapMode == models.CapModeHard && !overrideCap {
+		logger.Warn("Expense blocked by hard cap of category %s for user %s", category.ID, userID)
+		return true, &CategoryCapExceededError{
+			CategoryID: category.ID,
+			Cap:        *category.MonthlyCap,
+			Spent:      spent,
+			Attempted:  expense.Amount,
+		}
+	}
+
+	logger.Warn("Expense exceeds monthly cap of category %s for user %s (cap %s, spent %s, new %s)",
+		category.ID, userID, *category.MonthlyCap, spent, expense.Amount)
+	return true, nil
+}
+
+// SetCategoryCap configures or removes (cap nil) the monthly cap of a user category
+func (s *Services) SetCategoryCap(userID string, id string, monthlyCap *models.Money, mode models.CapMode) (*models.Category, error) {
+	var category models.Category
+	result := s.db.Where("id = ? AND user_id = ? AND status IN ?", id, userID, models.GetVisibleStatuses()).First(&category)
+	if result.Error != nil {
+		logger.Error("Category not found or access denied: %v", result.Error)
+		return nil, errors.New("category not found or access denied")
+	}
+
+	if monthlyCap != nil && *monthlyCap <= 0 {
+		return nil, errors.New("monthly cap must be positive")
+	}
+
+	if !models.IsValidCapMode(string(mode)) {
+		return nil, errors.New("invalid cap mode. Must be one of: alert, hard")
+	}
+
+	result = s.db.Model(&category).Updates(map[string]interface{}{
+		"monthly_cap": monthlyCap,
+		"cap_mode":    mode,
+	})
+	if result.Error != nil {
+		logger.Error("Error updating category cap: %v", result.Error)
+		return nil, result.Error
+	}
+
+	category.MonthlyCap = monthlyCap
+	category.CapMode = mode
+
+	logger.Info("Category %s cap set to %v (%s) for user %s", id, monthlyCap, mode, userID)
+	return &category, nil
+}
@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestCategoryCapFollowsUserMonth(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	// Months run from the 15th to the 14th of the next; 80.00 is spent on the 20th of a past month
+	month := models.MonthStart(time.Now().UTC()).AddDate(0, -2, 0)
+
+	cases := []struct {
+		name      string
+		date      time.Time
+		wantSpent models.Money // Reported with the cap exceeded, zero when the expense fits
+	}{
+		{
+			name:      "counts what was spent in the same period of the previous calendar month",
+			date:      month.AddDate(0, 1, 9),
+			wantSpent: models.NewMoney(80),
+		},
+		{
+			name: "leaves out what was spent in the same calendar month of the next period",
+			date: month.AddDate(0, 0, 9),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			account := h.CreateBankAccount(t, user, models.NewMoney(1000))
+			category := h.CreateCategory(t, user, "Dining out", models.ExpenseTypeWants)
+			userID := user.ID.String()
+
+			firstDay := 15
+			if _, err := h.Services.UpdateUserSettings(userID, services.UserSettingsUpdate{FirstDayOfMonth: &firstDay}); err != nil {
+				t.Fatalf("updating settings: %v", err)
+			}
+			monthlyCap := models.NewMoney(100)
+			if _, err := h.Services.SetCategoryCap(userID, category.ID.String(), &monthlyCap, models.CapModeHard); err != nil {
+				t.Fatalf("setting the cap: %v", err)
+			}
+			if err := h.Expenses.Create(context.Background(), userID, &models.Expense{
+				CategoryID:    category.ID,
+				BankAccountID: account.ID,
+				Amount:        models.NewMoney(80),
+				Date:          month.AddDate(0, 0, 19),
+			}, false); err != nil {
+				t.Fatalf("creating the first expense: %v", err)
+			}
+
+			err := h.Expenses.Create(context.Background(), userID, &models.Expense{
+				CategoryID:    category.ID,
+				BankAccountID: account.ID,
+				Amount:        models.NewMoney(30),
+				Date:          tc.date,
+			}, false)
+			var capErr *services.CategoryCapExceededError
+			if tc.wantSpent == 0 {
+				if err != nil {
+					t.Fatalf("creating expense: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &capErr) {
+				t.Fatalf("error = %v, want the cap to be exceeded", err)
+			}
+			if capErr.Spent != tc.wantSpent {
+				t.Errorf("spent = %s, want %s", capErr.Spent, tc.wantSpent)
+			}
+		})
+	}
+}
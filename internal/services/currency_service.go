@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// currencies caches the currency table, which only changes with a migration
+var currencies = struct {
+	mu     sync.RWMutex
+	byCode map[string]*models.Currency
+}{}
+
+// loadCurrencies returns the cached currency table, reading it on first use
+func (s *Services) loadCurrencies() map[string]*models.Currency {
+	currencies.mu.RLock()
+	byCode := currencies.byCode
+	currencies.mu.RUnlock()
+	if byCode != nil {
+		return byCode
+	}
+
+	var rows []models.Currency
+	if err := s.db.Order("code ASC").Find(&rows).Error; err != nil || len(rows) == 0 {
+		if err != nil {
+			logger.Error("Error loading currencies: %v", err)
+		}
+		// Fall back to the built-in list without caching it, the table may not be seeded yet
+		rows = models.DefaultCurrencies
+		byCode = make(map[string]*models.Currency, len(rows))
+		for i := range rows {
+			byCode[rows[i].Code] = &rows[i]
+		}
+		return byCode
+	}
+
+	byCode = make(map[string]*models.Currency, len(rows))
+	for i := range rows {
+		byCode[rows[i].Code] = &rows[i]
+	}
+	currencies.mu.Lock()
+	currencies.byCode = byCode
+	currencies.mu.Unlock()
+	return byCode
+}
+
+// GetCurrencies lists the supported currencies
+func (s *Services) GetCurrencies() []models.Currency {
+	byCode := s.loadCurrencies()
+	list := make([]models.Currency, 0, len(byCode))
+	for _, currency := range models.DefaultCurrencies {
+		if c, ok := byCode[currency.Code]; ok {
+			list = append(list, *c)
+		}
+	}
+	// Currencies added to the table by hand go after the built-in ones
+	for code, c := range byCode {
+		if !isDefaultCurrency(code) {
+			list = append(list, *c)
+		}
+	}
+	return list
+}
+
+func isDefaultCurrency(code string) bool {
+	for _, currency := range models.DefaultCurrencies {
+		if currency.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCurrency returns the metadata of a currency by ISO code
+func (s *Services) GetCurrency(code string) (*models.Currency, error) {
+	currency, ok := s.loadCurrencies()[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, errors.New("invalid currency: " + code)
+	}
+	return currency, nil
+}
+
+// defaultCurrency is the currency used when nothing better is known
+func (s *Services) defaultCurrency() *models.Currency {
+	if currency, err := s.GetCurrency(models.DefaultCurrencyCode); err == nil {
+		return currency
+	}
+	return &models.DefaultCurrencies[0]
+}
+
+// GetUserCurrency returns the currency the user's amounts are rounded in
+func (s *Services) GetUserCurrency(userID string) *models.Currency {
+	var user models.User
+	if err := s.db.Select("id", "currency").Where("id = ?", userID).First(&user).Error; err != nil {
+		return s.defaultCurrency()
+	}
+	currency, err := s.GetCurrency(user.Currency)
+	if err != nil {
+		return s.defaultCurrency()
+	}
+	return currency
+}
+
+// SetUserCurrency changes the currency of the user. Stored amounts are not converted
+func (s *Services) SetUserCurrency(userID string, code string) (*models.Currency, error) {
+	currency, err := s.GetCurrency(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeUserCurrency(s.db, userID, currency); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return nil, err
+		}
+		logger.Error("Error updating user currency: %v", err)
+		return nil, errors.New("error updating currency")
+	}
+
+	logger.Info("Currency set to %s for user %s", currency.Code, userID)
+	return currency, nil
+}
+
+// errUserNotFound is returned when writing the settings of a user that doesn't exist
+var errUserNotFound = errors.New("user not found")
+
+// storeUserCurrency sets the currency of the user within tx
+func storeUserCurrency(tx *gorm.DB, userID string, currency *models.Currency) error {
+	result := tx.Model(&models.User{}).Where("id = ?", userID).Update("currency", currency.Code)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errUserNotFound
+	}
+
+	// Summaries are rounded and labelled in the user's currency
+	db.AfterCommit(tx, func() { InvalidateSummaryCache(userID) })
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Dashboard widget types clients know how to render
+const (
+	DashboardWidgetSpendSummary  = "spend_summary"
+	DashboardWidgetSafeToSpend   = "safe_to_spend"
+	DashboardWidgetGoals         = "goals"
+	DashboardWidgetUpcomingBills = "upcoming_bills"
+)
+
+// DashboardConfigVersion changes whenever the widget catalog changes, so clients can
+// tell a layout they have cached apart from a new one
+const DashboardConfigVersion = 1
+
+// defaultDashboardWidgets is the catalog in its default order
+var defaultDashboardWidgets = []DashboardWidget{
+	{Type: DashboardWidgetSpendSummary, Title: "Spending this month", Enabled: true},
+	{Type: DashboardWidgetSafeToSpend, Title: "Safe to spend", Enabled: true},
+	{Type: DashboardWidgetUpcomingBills, Title: "Upcoming bills", Enabled: true},
+	{Type: DashboardWidgetGoals, Title: "Goals", Enabled: true},
+}
+
+// DashboardWidget is one card of the dashboard
+type DashboardWidget struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Enabled  bool   `json:"enabled"`
+	Position int    `json:"position"` // 0-based, set by the server
+}
+
+// DashboardConfig is the ordered list of widgets clients render on the dashboard
+type DashboardConfig struct {
+	Version int               `json:"version"`
+	Widgets []DashboardWidget `json:"widgets"`
+}
+
+// DashboardWidgetPreference is what the user can customize about a widget
+type DashboardWidgetPreference struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+// DashboardPreferences is the stored customization: widgets in the user's order
+type DashboardPreferences struct {
+	Widgets []DashboardWidgetPreference `json:"widgets"`
+}
+
+func dashboardWidgetDefaults() map[string]DashboardWidget {
+	defaults := make(map[string]DashboardWidget, len(defaultDashboardWidgets))
+	for _, widget := range defaultDashboardWidgets {
+		defaults[widget.Type] = widget
+	}
+	return defaults
+}
+
+// validate rejects unknown and repeated widgets
+func (p *DashboardPreferences) validate() error {
+	defaults := dashboardWidgetDefaults()
+	seen := make(map[string]bool, len(p.Widgets))
+	for _, widget := range p.Widgets {
+		if _, ok := defaults[widget.Type]; !ok {
+			return errors.New("invalid dashboard widget: " + widget.Type)
+		}
+		if seen[widget.Type] {
+			return errors.New("invalid dashboard config: duplicated widget " + widget.Type)
+		}
+		seen[widget.Type] = true
+	}
+	return nil
+}
+
+// resolve applies the preferences to the catalog. Widgets the user never placed, such as
+// ones added after they customized the layout, keep their default state at the end
+func (p *DashboardPreferences) resolve() *DashboardConfig {
+	defaults := dashboardWidgetDefaults()
+	config := &DashboardConfig{Version: DashboardConfigVersion, Widgets: []DashboardWidget{}}
+	placed := make(map[string]bool)
+
+	for _, preference := range p.Widgets {
+		widget, ok := defaults[preference.Type]
+		if !ok || placed[preference.Type] {
+			continue // Stored before the widget was retired
+		}
+		widget.Enabled = preference.Enabled
+		config.Widgets = append(config.Widgets, widget)
+		placed[preference.Type] = true
+	}
+	for _, widget := range defaultDashboardWidgets {
+		if !placed[widget.Type] {
+			config.Widgets = append(config.Widgets, widget)
+		}
+	}
+
+	for i := range config.Widgets {
+		config.Widgets[i].Position = i
+	}
+	return config
+}
+
+// GetDashboardConfig returns the dashboard layout of the user
+func (s *Services) GetDashboardConfig(userID string) (*DashboardConfig, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &DashboardPreferences{}
+	if preferences.DashboardConfig != "" {
+		if err := json.Unmarshal([]byte(preferences.DashboardConfig), dashboard); err != nil {
+			logger.Error("Error decoding dashboard config: %v", err)
+		}
+	}
+
+	return dashboard.resolve(), nil
+}
+
+// UpdateDashboardConfig stores the user's widget order and visibility
+func (s *Services) UpdateDashboardConfig(userID string, dashboard *DashboardPreferences) (*DashboardConfig, error) {
+	if err := dashboard.validate(); err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(dashboard)
+	if err != nil {
+		return nil, err
+	}
+	preferences.DashboardConfig = string(encoded)
+
+	if err := s.saveUserPreferences(preferences, "dashboard_config"); err != nil {
+		logger.Error("Error saving dashboard config: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Dashboard config updated for user %s", userID)
+	return dashboard.resolve(), nil
+}
+
+// ResetDashboardConfig drops the user's customization and returns the default layout
+func (s *Services) ResetDashboardConfig(userID string) (*DashboardConfig, error) {
+	return s.UpdateDashboardConfig(userID, &DashboardPreferences{})
+}
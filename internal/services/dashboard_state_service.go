@@ -0,0 +1,281 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/repository"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dashboardEvents are the events that change the dashboard figures
+var dashboardEvents = map[string]bool{
+	EventExpenseCreated: true,
+	EventIncomeCreated:  true,
+	EventBudgetCreated:  true,
+	EventBudgetUpdated:  true,
+	EventGoalFunded:     true,
+}
+
+// dashboardUpcomingDays is how far ahead the upcoming bills widget looks
+const dashboardUpcomingDays = 7
+
+// dashboardStateMaxAge is how old a stored state may be before it is rebuilt when read. Edits and
+// deletions emit no events, so this bounds how long they take to show up
+func dashboardStateMaxAge() time.Duration {
+	return time.Duration(envInt("DASHBOARD_STATE_MAX_AGE_MINUTES", 15)) * time.Minute
+}
+
+// dashboardSubscribers are the dashboard streams open on this instance, per user
+var dashboardSubscribers = struct {
+	sync.Mutex
+	channels map[string]map[chan struct{}]struct{}
+}{channels: make(map[string]map[chan struct{}]struct{})}
+
+// SubscribeDashboard returns a channel signalled whenever this instance refreshes the user's
+// dashboard. Call cancel when done listening
+func SubscribeDashboard(userID string) (updates <-chan struct{}, cancel func()) {
+	channel := make(chan struct{}, 1)
+	dashboardSubscribers.Lock()
+	if dashboardSubscribers.channels[userID] == nil {
+		dashboardSubscribers.channels[userID] = make(map[chan struct{}]struct{})
+	}
+	dashboardSubscribers.channels[userID][channel] = struct{}{}
+	dashboardSubscribers.Unlock()
+
+	return channel, func() {
+		dashboardSubscribers.Lock()
+		defer dashboardSubscribers.Unlock()
+		delete(dashboardSubscribers.channels[userID], channel)
+		if len(dashboardSubscribers.channels[userID]) == 0 {
+			delete(dashboardSubscribers.channels, userID)
+		}
+	}
+}
+
+// publishDashboardUpdate signals the streams of the user; a stream that hasn't caught up with
+// the previous signal just reads the latest state once
+func publishDashboardUpdate(userID string) {
+	dashboardSubscribers.Lock()
+	defer dashboardSubscribers.Unlock()
+	for channel := range dashboardSubscribers.channels[userID] {
+		select {
+		case channel <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// buildDashboardState computes the dashboard figures of the current month from the records.
+// Unless accounts is nil, spend, income and upcoming bills only count those of the accounts;
+// the budget and goals aren't tied to accounts
+func (s *Services) buildDashboardState(userID string, accounts []uuid.UUID) (*dto.DashboardState, time.Time, error) {
+	now := s.UserNow(userID)
+	start := models.MonthStart(now)
+	end := start.AddDate(0, 1, -1)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var spentRows []struct {
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+	}
+	if err := filterExpensesByAccounts(s.summaryPeriodQuery(userID, start, end), accounts).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Select("c.expense_type, COALESCE(SUM(" + accountsExpenseAmountSQL(accounts) + "), 0) as amount").
+		Group("c.expense_type").
+		Scan(&spentRows).Error; err != nil {
+		return nil, start, err
+	}
+	spent := make(map[models.ExpenseType]models.Money, len(spentRows))
+	for _, row := range spentRows {
+		spent[row.ExpenseType] += row.Amount
+	}
+
+	var income models.Money
+	incomeQuery := s.db.Model(&models.Income{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, start, end, models.GetActiveStatuses())
+	if refundsNettedInOriginalMonth() {
+		incomeQuery = incomeQuery.Where("refund_of_expense_id IS NULL")
+	}
+	if accounts != nil {
+		incomeQuery = incomeQuery.Where("bank_account_id IN ?", accounts)
+	}
+	if err := incomeQuery.Select("COALESCE(SUM(amount), 0)").Scan(&income).Error; err != nil {
+		return nil, start, err
+	}
+
+	var budget models.Budget
+	if err := s.db.Where("user_id = ? AND month_year = ? AND status IN ?", userID, start, models.GetActiveStatuses()).
+		Scopes(repository.PreloadCategoryBudgets).Limit(1).Find(&budget).Error; err != nil {
+		return nil, start, err
+	}
+	// Like the expense type lines, category lines count every expense of the month
+	categorySpent, err := s.categoryMonthSpend(userID, start, end, categoryBudgetIDs(budget.CategoryBudgets), true, accounts)
+	if err != nil {
+		return nil, start, err
+	}
+	budgets := map[models.ExpenseType]models.Money{
+		models.ExpenseTypeNeeds:   budget.NeedsBudget,
+		models.ExpenseTypeWants:   budget.WantsBudget,
+		models.ExpenseTypeSavings: budget.SavingsBudget,
+	}
+
+	var bills []models.FixedExpense
+	billsQuery := s.db.Where("user_id = ? AND status = ? AND next_due_date BETWEEN ? AND ?",
+		userID, models.StatusActive, today, today.AddDate(0, 0, dashboardUpcomingDays))
+	if accounts != nil {
+		billsQuery = billsQuery.Where("bank_account_id IN ?", accounts)
+	}
+	if err := billsQuery.Order("next_due_date ASC").Find(&bills).Error; err != nil {
+		return nil, start, err
+	}
+
+	var goals dto.DashboardGoals
+	if err := s.db.Model(&models.Goal{}).
+		Select("COUNT(*) as active, COALESCE(SUM(saved_amount), 0) as saved, COALESCE(SUM(total_amount), 0) as target").
+		Where("user_id = ? AND status = ?", userID, models.StatusActive).
+		Scan(&goals).Error; err != nil {
+		return nil, start, err
+	}
+
+	currency := s.GetUserCurrency(userID)
+	state := &dto.DashboardState{
+		Month:         start.Format("2006-01"),
+		Currency:      currency.Code,
+		Income:        currency.RoundMoney(income),
+		Budget:        currency.RoundMoney(budget.Total()),
+		ByExpenseType: make([]dto.DashboardBudgetLine, 0, len(models.ValidExpenseTypes())),
+		ByCategory:    categoryBudgetLines(budget.CategoryBudgets, categorySpent, currency),
+		UpcomingBills: dto.DashboardUpcomingBills{Days: dashboardUpcomingDays, Count: len(bills)},
+		Goals: dto.DashboardGoals{
+			Active: goals.Active,
+			Saved:  currency.RoundMoney(goals.Saved),
+			Target: currency.RoundMoney(goals.Target),
+		},
+		UpdatedAt: time.Now().UTC(),
+	}
+	for _, expenseType := range models.ValidExpenseTypes() {
+		line := dto.DashboardBudgetLine{
+			ExpenseType: string(expenseType),
+			Name:        models.GetExpenseTypeName(expenseType),
+			Budget:      currency.RoundMoney(budgets[expenseType]),
+			Spent:       currency.RoundMoney(spent[expenseType]),
+		}
+		line.Remaining = currency.RoundMoney(line.Budget - line.Spent)
+		state.ByExpenseType = append(state.ByExpenseType, line)
+		state.Spent += line.Spent
+	}
+	state.Spent = currency.RoundMoney(state.Spent)
+	state.Remaining = currency.RoundMoney(state.Budget - state.Spent)
+	for _, bill := range bills {
+		state.UpcomingBills.Total += bill.Amount
+	}
+	state.UpcomingBills.Total = currency.RoundMoney(state.UpcomingBills.Total)
+	if len(bills) > 0 {
+		next := bills[0].NextDueDate.Format("2006-01-02")
+		state.UpcomingBills.NextDueDate = &next
+	}
+
+	return state, start, nil
+}
+
+// RefreshDashboardState rebuilds the user's dashboard, stores it with the next version and
+// signals the streams open on this instance
+func (s *Services) RefreshDashboardState(userID string) (*dto.DashboardState, error) {
+	owner, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	state, month, err := s.buildDashboardState(userID, nil)
+	if err != nil {
+		logger.Error("Error building dashboard for user %s: %v", userID, err)
+		return nil, errors.New("error building dashboard")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var row models.DashboardState
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", owner).Limit(1).Find(&row)
+		if result.Error != nil {
+			return result.Error
+		}
+		state.Version = row.Version + 1
+		encoded, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return tx.Create(&models.DashboardState{UserID: owner, Month: month, Version: state.Version, State: string(encoded)}).Error
+		}
+		return tx.Model(&models.DashboardState{}).Where("user_id = ?", owner).Updates(map[string]interface{}{
+			"month":      month,
+			"version":    state.Version,
+			"state":      string(encoded),
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		logger.Error("Error storing dashboard for user %s: %v", userID, err)
+		return nil, errors.New("error building dashboard")
+	}
+
+	publishDashboardUpdate(userID)
+	return state, nil
+}
+
+// ProjectDashboardEvent is the outbox handler keeping the dashboard read model up to date.
+// Rebuilding the whole state makes it idempotent
+func (s *Services) ProjectDashboardEvent(event models.OutboxEvent) error {
+	if !dashboardEvents[event.EventType] {
+		return nil
+	}
+	_, err := s.RefreshDashboardState(event.UserID.String())
+	return err
+}
+
+// GetDashboardState returns the stored dashboard of the user. It is rebuilt when missing, from
+// a previous month or older than DASHBOARD_STATE_MAX_AGE_MINUTES
+func (s *Services) GetDashboardState(userID string) (*dto.DashboardState, error) {
+	var row models.DashboardState
+	result := s.db.Where("user_id = ?", userID).Limit(1).Find(&row)
+	if result.Error != nil {
+		logger.Error("Error getting dashboard: %v", result.Error)
+		return nil, errors.New("error getting dashboard")
+	}
+	if result.RowsAffected == 0 || !row.Month.Equal(models.MonthStart(s.UserNow(userID))) ||
+		time.Since(row.UpdatedAt) > dashboardStateMaxAge() {
+		return s.RefreshDashboardState(userID)
+	}
+
+	var state dto.DashboardState
+	if err := json.Unmarshal([]byte(row.State), &state); err != nil {
+		logger.Warn("Invalid dashboard state stored for user %s: %v", userID, err)
+		return s.RefreshDashboardState(userID)
+	}
+	state.Version = row.Version
+	return &state, nil
+}
+
+// GetAccountsDashboardState returns the dashboard of the user counting only the accounts, or the
+// stored one when accounts is nil. The figures are computed on each call; the version is the
+// stored one, so streams still tell changes apart
+func (s *Services) GetAccountsDashboardState(userID string, accounts []uuid.UUID) (*dto.DashboardState, error) {
+	stored, err := s.GetDashboardState(userID)
+	if err != nil || accounts == nil {
+		return stored, err
+	}
+
+	state, _, err := s.buildDashboardState(userID, accounts)
+	if err != nil {
+		logger.Error("Error building dashboard for user %s: %v", userID, err)
+		return nil, errors.New("error building dashboard")
+	}
+	state.Version = stored.Version
+	return state, nil
+}
@@ -0,0 +1,750 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/storage"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrExportTooLarge is returned when an export has to be generated in the background
+var ErrExportTooLarge = errors.New("account too large to export within a request")
+
+// exportBatchSize is how many records are read from the database at once
+const exportBatchSize = 500
+
+// exportRunningTimeout is how long a job may run before it is considered lost with its worker
+const exportRunningTimeout = time.Hour
+
+// exportCancelCheckInterval is how often a running job checks whether it was cancelled from
+// another instance
+const exportCancelCheckInterval = 5 * time.Second
+
+// runningExports are the cancel functions of the export jobs running on this instance
+var runningExports = struct {
+	sync.Mutex
+	cancels map[uuid.UUID]context.CancelFunc
+}{cancels: make(map[uuid.UUID]context.CancelFunc)}
+
+// exportSyncMaxRecords is the largest account exported within the request
+// (EXPORT_SYNC_MAX_RECORDS, default 5000); bigger ones get a background job
+func exportSyncMaxRecords() int64 {
+	return int64(envInt("EXPORT_SYNC_MAX_RECORDS", 5000))
+}
+
+// exportRetention is how long generated exports can be downloaded (EXPORT_RETENTION_HOURS, default 24)
+func exportRetention() time.Duration {
+	return time.Duration(envInt("EXPORT_RETENTION_HOURS", 24)) * time.Hour
+}
+
+// ValidateExportFormat checks the format of a data export
+func ValidateExportFormat(format string) error {
+	if format != models.DataExportCSV && format != models.DataExportJSON {
+		return errors.New("invalid format: must be csv or json")
+	}
+	return nil
+}
+
+// ExportFileName is the name an export of the format is downloaded as
+func ExportFileName(format string) string {
+	if format == models.DataExportJSON {
+		return "fluxio-export.json"
+	}
+	return "fluxio-export.zip"
+}
+
+// ExportContentType is the media type of an export of the format
+func ExportContentType(format string) string {
+	if format == models.DataExportJSON {
+		return "application/json"
+	}
+	return "application/zip"
+}
+
+// exportSection is one kind of exported record: a CSV file in ZIP exports, a list in JSON ones.
+// each calls emit with every record of the user in the database of s, as a JSON object and as a
+// CSV row
+type exportSection struct {
+	name    string
+	columns []string
+	each    func(ctx context.Context, s *Services, userID string, emit func(record interface{}, row []string) error) error
+}
+
+func exportDate(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+func exportTime(at time.Time) string {
+	return at.UTC().Format(time.RFC3339)
+}
+
+func exportOptional(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func exportTagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// exportQuery reads the records of the user that weren't deleted, oldest first
+func (s *Services) exportQuery(ctx context.Context, userID string) *gorm.DB {
+	return s.db.WithContext(ctx).Where("user_id = ? AND status <> ?", userID, models.StatusDeleted).Order("created_at, id")
+}
+
+// Exported records are flattened: related records are named, not nested
+type exportedAccount struct {
+	ID            string       `json:"id"`
+	AccountName   string       `json:"account_name"`
+	Balance       models.Money `json:"balance"`
+	ManualBalance bool         `json:"manual_balance"`
+	Status        string       `json:"status"`
+	CreatedAt     string       `json:"created_at"`
+}
+
+type exportedExpense struct {
+	ID          string       `json:"id"`
+	Date        string       `json:"date"`
+	Amount      models.Money `json:"amount"`
+	Category    string       `json:"category"`
+	ExpenseType string       `json:"expense_type"`
+	Account     string       `json:"account"`
+	Description *string      `json:"description"`
+	Tags        []string     `json:"tags"`
+	Status      string       `json:"status"`
+	CreatedAt   string       `json:"created_at"`
+}
+
+type exportedIncome struct {
+	ID                string       `json:"id"`
+	Date              string       `json:"date"`
+	Amount            models.Money `json:"amount"`
+	Account           string       `json:"account"`
+	Source            string       `json:"source"`
+	Description       *string      `json:"description"`
+	RefundOfExpenseID *string      `json:"refund_of_expense_id"`
+	Tags              []string     `json:"tags"`
+	Status            string       `json:"status"`
+	CreatedAt         string       `json:"created_at"`
+}
+
+type exportedTransfer struct {
+	ID          string       `json:"id"`
+	Date        string       `json:"date"`
+	Amount      models.Money `json:"amount"`
+	FromAccount string       `json:"from_account"`
+	ToAccount   string       `json:"to_account"`
+	Description *string      `json:"description"`
+	Status      string       `json:"status"`
+	CreatedAt   string       `json:"created_at"`
+}
+
+type exportedBudget struct {
+	ID            string       `json:"id"`
+	Month         string       `json:"month"`
+	NeedsBudget   models.Money `json:"needs_budget"`
+	WantsBudget   models.Money `json:"wants_budget"`
+	SavingsBudget models.Money `json:"savings_budget"`
+	Status        string       `json:"status"`
+	CreatedAt     string       `json:"created_at"`
+}
+
+type exportedGoal struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	TotalAmount models.Money `json:"total_amount"`
+	SavedAmount models.Money `json:"saved_amount"`
+	Priority    int          `json:"priority"`
+	APY         *float64     `json:"apy"`
+	Status      string       `json:"status"`
+	CreatedAt   string       `json:"created_at"`
+}
+
+// exportSections are the records exported, in the order they are written
+var exportSections = []exportSection{
+	{
+		name:    "accounts",
+		columns: []string{"id", "account_name", "balance", "manual_balance", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.BankAccount
+			return s.exportQuery(ctx, userID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+				for _, account := range batch {
+					record := exportedAccount{
+						ID:            account.ID.String(),
+						AccountName:   account.AccountName,
+						Balance:       account.Balance,
+						ManualBalance: account.ManualBalance,
+						Status:        account.Status.String(),
+						CreatedAt:     exportTime(account.CreatedAt),
+					}
+					if err := emit(record, []string{record.ID, record.AccountName, record.Balance.String(),
+						strconv.FormatBool(record.ManualBalance), record.Status, record.CreatedAt}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+	},
+	{
+		name:    "expenses",
+		columns: []string{"id", "date", "amount", "category", "expense_type", "account", "description", "tags", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.Expense
+			return s.exportQuery(ctx, userID).Preload("Category").Preload("BankAccount").Preload("Tags").
+				FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+					for _, expense := range batch {
+						record := exportedExpense{
+							ID:          expense.ID.String(),
+							Date:        exportDate(expense.Date),
+							Amount:      expense.Amount,
+							Category:    expense.Category.Name,
+							ExpenseType: string(expense.Category.ExpenseType),
+							Account:     expense.BankAccount.AccountName,
+							Description: expense.Description,
+							Tags:        exportTagNames(expense.Tags),
+							Status:      expense.Status.String(),
+							CreatedAt:   exportTime(expense.CreatedAt),
+						}
+						if err := emit(record, []string{record.ID, record.Date, record.Amount.String(), record.Category, record.ExpenseType,
+							record.Account, exportOptional(record.Description), strings.Join(record.Tags, ";"), record.Status, record.CreatedAt}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+	},
+	{
+		name:    "incomes",
+		columns: []string{"id", "date", "amount", "account", "source", "description", "refund_of_expense_id", "tags", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.Income
+			return s.exportQuery(ctx, userID).Preload("BankAccount").Preload("Tags").
+				FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+					for _, income := range batch {
+						record := exportedIncome{
+							ID:          income.ID.String(),
+							Date:        exportDate(income.Date),
+							Amount:      income.Amount,
+							Source:      income.Source,
+							Description: income.Description,
+							Tags:        exportTagNames(income.Tags),
+							Status:      income.Status.String(),
+							CreatedAt:   exportTime(income.CreatedAt),
+						}
+						if income.BankAccount != nil {
+							record.Account = income.BankAccount.AccountName
+						}
+						if income.RefundOfExpenseID != nil {
+							refundOf := income.RefundOfExpenseID.String()
+							record.RefundOfExpenseID = &refundOf
+						}
+						if err := emit(record, []string{record.ID, record.Date, record.Amount.String(), record.Account,
+							record.Source, exportOptional(record.Description), exportOptional(record.RefundOfExpenseID), strings.Join(record.Tags, ";"), record.Status, record.CreatedAt}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+	},
+	{
+		name:    "transfers",
+		columns: []string{"id", "date", "amount", "from_account", "to_account", "description", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.Transfer
+			return s.exportQuery(ctx, userID).Preload("FromAccount").Preload("ToAccount").
+				FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+					for _, transfer := range batch {
+						record := exportedTransfer{
+							ID:          transfer.ID.String(),
+							Date:        exportDate(transfer.Date),
+							Amount:      transfer.Amount,
+							FromAccount: transfer.FromAccount.AccountName,
+							ToAccount:   transfer.ToAccount.AccountName,
+							Description: transfer.Description,
+							Status:      transfer.Status.String(),
+							CreatedAt:   exportTime(transfer.CreatedAt),
+						}
+						if err := emit(record, []string{record.ID, record.Date, record.Amount.String(), record.FromAccount,
+							record.ToAccount, exportOptional(record.Description), record.Status, record.CreatedAt}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+	},
+	{
+		name:    "budgets",
+		columns: []string{"id", "month", "needs_budget", "wants_budget", "savings_budget", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.Budget
+			return s.exportQuery(ctx, userID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+				for _, budget := range batch {
+					record := exportedBudget{
+						ID:            budget.ID.String(),
+						Month:         budget.MonthYear.Format("2006-01"),
+						NeedsBudget:   budget.NeedsBudget,
+						WantsBudget:   budget.WantsBudget,
+						SavingsBudget: budget.SavingsBudget,
+						Status:        budget.Status.String(),
+						CreatedAt:     exportTime(budget.CreatedAt),
+					}
+					if err := emit(record, []string{record.ID, record.Month, record.NeedsBudget.String(), record.WantsBudget.String(),
+						record.SavingsBudget.String(), record.Status, record.CreatedAt}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+	},
+	{
+		name:    "goals",
+		columns: []string{"id", "name", "total_amount", "saved_amount", "priority", "apy", "status", "created_at"},
+		each: func(ctx context.Context, s *Services, userID string, emit func(interface{}, []string) error) error {
+			var batch []models.Goal
+			return s.exportQuery(ctx, userID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+				for _, goal := range batch {
+					record := exportedGoal{
+						ID:          goal.ID.String(),
+						Name:        goal.Name,
+						TotalAmount: goal.TotalAmount,
+						SavedAmount: goal.SavedAmount,
+						Priority:    goal.Priority,
+						APY:         goal.APY,
+						Status:      goal.Status.String(),
+						CreatedAt:   exportTime(goal.CreatedAt),
+					}
+					apy := ""
+					if goal.APY != nil {
+						apy = strconv.FormatFloat(*goal.APY, 'f', -1, 64)
+					}
+					if err := emit(record, []string{record.ID, record.Name, record.TotalAmount.String(), record.SavedAmount.String(),
+						strconv.Itoa(record.Priority), apy, record.Status, record.CreatedAt}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+	},
+}
+
+// CountExportRecords counts the records an export of the user would hold
+func (s *Services) CountExportRecords(userID string) (int64, error) {
+	var total int64
+	for _, model := range []interface{}{
+		&models.BankAccount{}, &models.Expense{}, &models.Income{}, &models.Transfer{}, &models.Budget{}, &models.Goal{},
+	} {
+		var count int64
+		if err := s.db.Model(model).Where("user_id = ? AND status <> ?", userID, models.StatusDeleted).Count(&count).Error; err != nil {
+			logger.Error("Error counting records to export: %v", err)
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// WriteUserExport writes every record of the user that wasn't deleted: a ZIP with one CSV file
+// per kind of record, or a single JSON document. Records are read in batches, so the export is
+// never held in memory
+func (s *Services) WriteUserExport(ctx context.Context, w io.Writer, userID string, format string) error {
+	if err := ValidateExportFormat(format); err != nil {
+		return err
+	}
+	if format == models.DataExportJSON {
+		return s.writeJSONExport(ctx, w, userID)
+	}
+	return s.writeCSVExport(ctx, w, userID)
+}
+
+func (s *Services) writeCSVExport(ctx context.Context, w io.Writer, userID string) error {
+	archive := zip.NewWriter(w)
+	for _, section := range exportSections {
+		file, err := archive.Create(section.name + ".csv")
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(file)
+		if err := writer.Write(section.columns); err != nil {
+			return err
+		}
+		if err := section.each(ctx, s, userID, func(_ interface{}, row []string) error {
+			return writer.Write(row)
+		}); err != nil {
+			return err
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (s *Services) writeJSONExport(ctx context.Context, w io.Writer, userID string) error {
+	header, err := json.Marshal(map[string]string{
+		"user_id":     userID,
+		"currency":    s.GetUserCurrency(userID).Code,
+		"exported_at": exportTime(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	// The header's closing brace is left off so the sections follow as more members
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	for _, section := range exportSections {
+		if _, err := io.WriteString(w, `,"`+section.name+`":[`); err != nil {
+			return err
+		}
+		first := true
+		if err := section.each(ctx, s, userID, func(record interface{}, _ []string) error {
+			encoded, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			_, err = w.Write(encoded)
+			return err
+		}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// PrepareUserExport checks the export can be streamed within the request. Larger accounts, or
+// any account when background is set, get an export job instead, returned with
+// ErrExportTooLarge unless background was asked for. A job of the same format still pending
+// or running is reused
+func (s *Services) PrepareUserExport(userID string, format string, background bool) (*models.DataExport, error) {
+	if err := ValidateExportFormat(format); err != nil {
+		return nil, err
+	}
+	if !background {
+		count, err := s.CountExportRecords(userID)
+		if err != nil {
+			return nil, errors.New("error preparing export")
+		}
+		if count <= exportSyncMaxRecords() {
+			return nil, nil
+		}
+	}
+
+	uid := uuid.MustParse(userID)
+	var export models.DataExport
+	err := s.db.Where("user_id = ? AND format = ? AND status IN ?", uid, format,
+		[]string{models.DataExportPending, models.DataExportRunning}).First(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		export = models.DataExport{UserID: uid, Format: format, Status: models.DataExportPending}
+		err = s.db.Create(&export).Error
+		if err == nil {
+			logger.Info("Data export %s (%s) queued for user %s", export.ID, format, userID)
+		}
+	}
+	if err != nil {
+		logger.Error("Error creating data export: %v", err)
+		return nil, errors.New("error preparing export")
+	}
+
+	if background {
+		return &export, nil
+	}
+	return &export, ErrExportTooLarge
+}
+
+// GetDataExport returns an export job of the user
+func (s *Services) GetDataExport(userID string, id string) (*models.DataExport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New("export not found")
+	}
+	var export models.DataExport
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&export).Error; err != nil {
+		return nil, errors.New("export not found")
+	}
+	return &export, nil
+}
+
+// OpenDataExport returns the file of a completed export job
+func (s *Services) OpenDataExport(ctx context.Context, userID string, id string) (*models.DataExport, io.ReadCloser, error) {
+	export, err := s.GetDataExport(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.DataExportCompleted {
+		return nil, nil, errors.New("invalid request: the export is " + export.Status)
+	}
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return nil, nil, errors.New("error reading export")
+	}
+	content, err := store.Get(ctx, export.StorageKey)
+	if err != nil {
+		logger.Error("Error reading export %s: %v", export.ID, err)
+		return nil, nil, errors.New("error reading export")
+	}
+	return export, content, nil
+}
+
+// CancelDataExport cancels a pending or running export job of the user. A running job stops
+// generating and whatever it already wrote to storage is deleted
+func (s *Services) CancelDataExport(userID string, id string) (*models.DataExport, error) {
+	export, err := s.GetDataExport(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.DataExportPending && export.Status != models.DataExportRunning {
+		return nil, errors.New("invalid request: the export is " + export.Status)
+	}
+
+	now := time.Now()
+	result := s.db.Model(export).Where("status IN ?", []string{models.DataExportPending, models.DataExportRunning}).
+		Updates(map[string]interface{}{"status": models.DataExportCancelled, "completed_at": now})
+	if result.Error != nil {
+		logger.Error("Error cancelling data export %s: %v", export.ID, result.Error)
+		return nil, errors.New("error cancelling export")
+	}
+	if result.RowsAffected == 0 {
+		// It finished meanwhile
+		return s.GetDataExport(userID, id)
+	}
+
+	export.Status = models.DataExportCancelled
+	export.CompletedAt = &now
+
+	// Jobs running on other instances notice on their next check
+	runningExports.Lock()
+	if cancel, ok := runningExports.cancels[export.ID]; ok {
+		cancel()
+	}
+	runningExports.Unlock()
+
+	logger.Info("Data export %s cancelled by user %s", export.ID, userID)
+	return export, nil
+}
+
+// watchDataExport cancels ctx once the job is no longer running, e.g. cancelled from another
+// instance. It returns when ctx is done
+func (s *Services) watchDataExport(ctx context.Context, cancel context.CancelFunc, id uuid.UUID) {
+	ticker := time.NewTicker(exportCancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var status string
+			if err := s.db.Model(&models.DataExport{}).Where("id = ?", id).Pluck("status", &status).Error; err != nil {
+				logger.Warn("Error checking data export %s: %v", id, err)
+				continue
+			}
+			if status != models.DataExportRunning {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// claimDataExport takes the oldest pending export job; SKIP LOCKED lets several workers run
+func (s *Services) claimDataExport() (*models.DataExport, error) {
+	var export models.DataExport
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.DataExportPending).
+			Order("created_at ASC").
+			Limit(1).
+			Find(&export)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		now := time.Now()
+		export.Status = models.DataExportRunning
+		export.StartedAt = &now
+		return tx.Model(&export).Updates(map[string]interface{}{"status": export.Status, "started_at": now}).Error
+	})
+	if err != nil || export.ID == uuid.Nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// generateDataExport writes the export to a temporary file, then moves it to storage. It stops
+// when ctx is cancelled; the temporary file is always removed
+func (s *Services) generateDataExport(ctx context.Context, export *models.DataExport) error {
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "fluxio-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := s.WriteUserExport(ctx, file, export.UserID.String(), export.Format); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	export.StorageKey = "exports/" + export.UserID.String() + "/" + export.ID.String()
+	if err := store.Put(ctx, export.StorageKey, ExportContentType(export.Format), file, size); err != nil {
+		return err
+	}
+	export.Size = size
+	return nil
+}
+
+// RunDataExports generates the pending export jobs, one after the other
+func (s *Services) RunDataExports() error {
+	for {
+		export, err := s.claimDataExport()
+		if err != nil {
+			logger.Error("Error claiming data export: %v", err)
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runningExports.Lock()
+		runningExports.cancels[export.ID] = cancel
+		runningExports.Unlock()
+		go s.watchDataExport(ctx, cancel, export.ID)
+
+		err = s.generateDataExport(ctx, export)
+		cancelled := ctx.Err() != nil
+		cancel()
+		runningExports.Lock()
+		delete(runningExports.cancels, export.ID)
+		runningExports.Unlock()
+
+		if cancelled {
+			logger.Info("Data export %s stopped after being cancelled", export.ID)
+			deleteDataExportFile(export)
+			continue
+		}
+
+		updates := map[string]interface{}{}
+		if err != nil {
+			logger.Error("Error generating data export %s: %v", export.ID, err)
+			updates["status"] = models.DataExportFailed
+			updates["error"] = "the export could not be generated"
+		} else {
+			now := time.Now()
+			updates["status"] = models.DataExportCompleted
+			updates["storage_key"] = export.StorageKey
+			updates["size"] = export.Size
+			updates["completed_at"] = now
+			updates["expires_at"] = now.Add(exportRetention())
+			logger.Info("Data export %s generated (%d bytes)", export.ID, export.Size)
+		}
+		// The job may have been cancelled meanwhile, by the user or by the account being anonymized
+		result := s.db.Model(export).Where("status = ?", models.DataExportRunning).Updates(updates)
+		if result.Error != nil {
+			logger.Error("Error saving data export %s: %v", export.ID, result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			deleteDataExportFile(export)
+		}
+	}
+}
+
+// deleteDataExportFile removes from storage what a cancelled job wrote, complete or not
+func deleteDataExportFile(export *models.DataExport) {
+	if export.StorageKey == "" {
+		return
+	}
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return
+	}
+	if err := store.Delete(context.Background(), export.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logger.Warn("Error deleting file of cancelled data export %s: %v", export.ID, err)
+	}
+}
+
+// PurgeExpiredDataExports deletes the files of exports past their expiry, forgets failed and
+// cancelled jobs after the retention and fails jobs whose worker stopped mid-run
+func (s *Services) PurgeExpiredDataExports() error {
+	if err := s.db.Model(&models.DataExport{}).
+		Where("status = ? AND started_at < ?", models.DataExportRunning, time.Now().Add(-exportRunningTimeout)).
+		Updates(map[string]interface{}{"status": models.DataExportFailed, "error": "the export timed out"}).Error; err != nil {
+		logger.Error("Error failing stale data exports: %v", err)
+		return err
+	}
+
+	var expired []models.DataExport
+	if err := s.db.Where("(status = ? AND expires_at < ?) OR (status IN ? AND created_at < ?)",
+		models.DataExportCompleted, time.Now(), []string{models.DataExportFailed, models.DataExportCancelled}, time.Now().Add(-exportRetention())).
+		Find(&expired).Error; err != nil {
+		logger.Error("Error listing expired data exports: %v", err)
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return err
+	}
+	for _, export := range expired {
+		if export.StorageKey != "" {
+			if err := store.Delete(context.Background(), export.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				logger.Warn("Error deleting file of data export %s: %v", export.ID, err)
+				continue
+			}
+		}
+		if err := s.db.Delete(&export).Error; err != nil {
+			logger.Error("Error deleting data export %s: %v", export.ID, err)
+			return err
+		}
+	}
+	logger.Info("Purged %d expired data exports", len(expired))
+	return nil
+}
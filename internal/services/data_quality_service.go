@@ -0,0 +1,265 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Data quality issue types
+const (
+	DataQualityUncategorized   = "uncategorized_expense"
+	DataQualityMissingAccount  = "expense_missing_account"
+	DataQualityStalePending    = "stale_pending"
+	DataQualityNegativeBalance = "negative_balance"
+)
+
+// EventDataQualityReport is emitted when a report finds issues, so the user gets notified
+const EventDataQualityReport = "data_quality.report_ready"
+
+const (
+	// dataQualityReportInterval is how old a report gets before the job regenerates it
+	dataQualityReportInterval = 7 * 24 * time.Hour
+	// dataQualityStaleDays is how long a record can stay pending before it is reported
+	dataQualityStaleDays = 14
+	// maxDataQualityIssues caps the issues listed per type; counts are not capped
+	maxDataQualityIssues = 100
+)
+
+// dataQualityRow is the common shape of the records a check reports
+type dataQualityRow struct {
+	ID          uuid.UUID
+	EntityType  string
+	Amount      *models.Money
+	Date        *time.Time
+	Description string
+}
+
+// runDataQualityCheck counts the records of a check and lists the first ones as issues.
+// query must build a fresh statement on every call
+func runDataQualityCheck(issueType string, query func() *gorm.DB, columns string) ([]dto.DataQualityIssue, int, error) {
+	var count int64
+	if err := query().Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if count == 0 {
+		return nil, 0, nil
+	}
+
+	var rows []dataQualityRow
+	if err := query().Select(columns).Limit(maxDataQualityIssues).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	issues := make([]dto.DataQualityIssue, 0, len(rows))
+	for _, row := range rows {
+		issue := dto.DataQualityIssue{
+			Type:        issueType,
+			EntityType:  row.EntityType,
+			EntityID:    row.ID.String(),
+			Description: row.Description,
+			Amount:      row.Amount,
+		}
+		if row.Date != nil {
+			date := row.Date.Format("2006-01-02")
+			issue.Date = &date
+		}
+		issues = append(issues, issue)
+	}
+	return issues, int(count), nil
+}
+
+// stalePendingIssues finds expenses, incomes and transfers left pending, plus import matches
+// nobody resolved, for more than dataQualityStaleDays
+func (s *Services) stalePendingIssues(userID string, cutoff time.Time) ([]dto.DataQualityIssue, int, error) {
+	var all []dto.DataQualityIssue
+	total := 0
+	for _, table := range []struct{ name, entity string }{
+		{"expenses", "expense"}, {"incomes", "income"}, {"transfers", "transfer"},
+	} {
+		table := table
+		issues, count, err := runDataQualityCheck(DataQualityStalePending, func() *gorm.DB {
+			return s.db.Table(table.name).Where("user_id = ? AND status = ? AND created_at < ?", userID, models.StatusPending, cutoff)
+		}, fmt.Sprintf("id, '%s' AS entity_type, amount, date, 'Pending since ' || TO_CHAR(created_at, 'YYYY-MM-DD') AS description", table.entity))
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, issues...)
+		total += count
+	}
+
+	issues, count, err := runDataQualityCheck(DataQualityStalePending, func() *gorm.DB {
+		return s.db.Table("import_matches m").Joins("JOIN imported_transactions t ON t.id = m.imported_transaction_id").
+			Where("m.user_id = ? AND m.resolved_at IS NULL AND m.created_at < ?", userID, cutoff)
+	}, "m.id, 'import_match' AS entity_type, t.amount, t.date, 'Import match waiting to be resolved' AS description")
+	if err != nil {
+		return nil, 0, err
+	}
+	all = append(all, issues...)
+	total += count
+
+	if len(all) > maxDataQualityIssues {
+		all = all[:maxDataQualityIssues]
+	}
+	return all, total, nil
+}
+
+// buildDataQualityReport runs every check for the user
+func (s *Services) buildDataQualityReport(userID string) (*dto.DataQualityReport, error) {
+	report := &dto.DataQualityReport{GeneratedAt: time.Now().UTC(), Issues: []dto.DataQualityIssue{}}
+
+	// Expenses whose category no longer exists or was deleted
+	issues, count, err := runDataQualityCheck(DataQualityUncategorized, func() *gorm.DB {
+		return s.db.Table("expenses e").Joins("LEFT JOIN categories c ON c.id = e.category_id").
+			Where("e.user_id = ? AND e.status IN ?", userID, models.GetVisibleStatuses()).
+			Where("(c.id IS NULL OR c.status NOT IN ?)", models.GetVisibleStatuses())
+	}, "e.id, 'expense' AS entity_type, e.amount, e.date, COALESCE(e.description, 'Expense without a category') AS description")
+	if err != nil {
+		return nil, err
+	}
+	report.UncategorizedExpenses = count
+	report.Issues = append(report.Issues, issues...)
+
+	// Expenses without an account, or whose account was deleted
+	issues, count, err = runDataQualityCheck(DataQualityMissingAccount, func() *gorm.DB {
+		return s.db.Table("expenses e").Joins("LEFT JOIN bank_accounts b ON b.id = e.bank_account_id").
+			Where("e.user_id = ? AND e.status IN ?", userID, models.GetVisibleStatuses()).
+			Where("(b.id IS NULL OR b.status NOT IN ?)", models.GetVisibleStatuses())
+	}, "e.id, 'expense' AS entity_type, e.amount, e.date, COALESCE(e.description, 'Expense without a bank account') AS description")
+	if err != nil {
+		return nil, err
+	}
+	report.ExpensesMissingAccount = count
+	report.Issues = append(report.Issues, issues...)
+
+	issues, count, err = s.stalePendingIssues(userID, s.UserNow(userID).AddDate(0, 0, -dataQualityStaleDays))
+	if err != nil {
+		return nil, err
+	}
+	report.StalePending = count
+	report.Issues = append(report.Issues, issues...)
+
+	issues, count, err = runDataQualityCheck(DataQualityNegativeBalance, func() *gorm.DB {
+		return s.db.Table("bank_accounts").Where("user_id = ? AND status IN ? AND balance < 0", userID, models.GetActiveStatuses())
+	}, "id, 'bank_account' AS entity_type, balance AS amount, account_name AS description")
+	if err != nil {
+		return nil, err
+	}
+	report.NegativeBalances = count
+	report.Issues = append(report.Issues, issues...)
+
+	report.TotalIssues = report.UncategorizedExpenses + report.ExpensesMissingAccount + report.StalePending + report.NegativeBalances
+	return report, nil
+}
+
+// GenerateDataQualityReport checks the user's data now and stores the report in place of the
+// previous one. A report with issues emits a data_quality.report_ready event
+func (s *Services) GenerateDataQualityReport(userID string) (*dto.DataQualityReport, error) {
+	report, err := s.buildDataQualityReport(userID)
+	if err != nil {
+		logger.Error("Error checking data quality for user %s: %v", userID, err)
+		return nil, errors.New("error generating data quality report")
+	}
+
+	issues, err := json.Marshal(report.Issues)
+	if err != nil {
+		return nil, errors.New("error generating data quality report")
+	}
+	row := models.DataQualityReport{
+		UserID:                 uuid.MustParse(userID),
+		UncategorizedExpenses:  report.UncategorizedExpenses,
+		ExpensesMissingAccount: report.ExpensesMissingAccount,
+		StalePending:           report.StalePending,
+		NegativeBalances:       report.NegativeBalances,
+		Issues:                 string(issues),
+		GeneratedAt:            report.GeneratedAt,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"uncategorized_expenses", "expenses_missing_account", "stale_pending", "negative_balances",
+				"issues", "generated_at", "updated_at",
+			}),
+		}).Create(&row).Error; err != nil {
+			return err
+		}
+		if report.TotalIssues == 0 {
+			return nil
+		}
+		return EnqueueEvent(tx, row.UserID, EventDataQualityReport, "user", row.UserID, map[string]interface{}{
+			"uncategorized_expenses":   report.UncategorizedExpenses,
+			"expenses_missing_account": report.ExpensesMissingAccount,
+			"stale_pending":            report.StalePending,
+			"negative_balances":        report.NegativeBalances,
+			"total_issues":             report.TotalIssues,
+			"generated_at":             report.GeneratedAt,
+		})
+	})
+	if err != nil {
+		logger.Error("Error storing data quality report: %v", err)
+		return nil, errors.New("error generating data quality report")
+	}
+
+	return report, nil
+}
+
+// GetDataQualityReport returns the latest report of the user, generating it the first time
+func (s *Services) GetDataQualityReport(userID string) (*dto.DataQualityReport, error) {
+	var row models.DataQualityReport
+	err := s.db.Where("user_id = ?", userID).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.GenerateDataQualityReport(userID)
+	}
+	if err != nil {
+		logger.Error("Error getting data quality report: %v", err)
+		return nil, errors.New("error getting data quality report")
+	}
+
+	report := &dto.DataQualityReport{
+		GeneratedAt:            row.GeneratedAt,
+		UncategorizedExpenses:  row.UncategorizedExpenses,
+		ExpensesMissingAccount: row.ExpensesMissingAccount,
+		StalePending:           row.StalePending,
+		NegativeBalances:       row.NegativeBalances,
+		Issues:                 []dto.DataQualityIssue{},
+	}
+	report.TotalIssues = report.UncategorizedExpenses + report.ExpensesMissingAccount + report.StalePending + report.NegativeBalances
+	if err := json.Unmarshal([]byte(row.Issues), &report.Issues); err != nil {
+		logger.Warn("Invalid data quality issues stored for user %s: %v", userID, err)
+	}
+	return report, nil
+}
+
+// GenerateDueDataQualityReports regenerates the reports older than a week, and creates the
+// missing ones, for every active user. Run periodically as a job, each user gets a new one
+// weekly regardless of restarts
+func (s *Services) GenerateDueDataQualityReports() error {
+	var userIDs []uuid.UUID
+	recent := s.db.Model(&models.DataQualityReport{}).Select("user_id").
+		Where("generated_at > ?", time.Now().Add(-dataQualityReportInterval))
+	if err := s.db.Model(&models.User{}).Where("status = ? AND is_sandbox = ?", models.StatusActive, false).
+		Where("id NOT IN (?)", recent).Pluck("id", &userIDs).Error; err != nil {
+		logger.Error("Error listing users for data quality reports: %v", err)
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if _, err := s.GenerateDataQualityReport(userID.String()); err != nil {
+			logger.Error("Error generating data quality report for user %s: %v", userID, err)
+		}
+	}
+	if len(userIDs) > 0 {
+		logger.Info("Data quality reports generated for %d users", len(userIDs))
+	}
+	return nil
+}
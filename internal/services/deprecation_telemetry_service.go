@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UnknownClientID is recorded for requests without an X-Client-ID header
+const UnknownClientID = "unknown"
+
+// maxClientIDLength is the longest client identifier stored
+const maxClientIDLength = 100
+
+// DeprecatedField is a response field marked with a `deprecated:"..."` struct tag
+type DeprecatedField struct {
+	Path string `json:"field"` // JSON path, nested fields joined with dots
+	Note string `json:"note"`  // Tag value, e.g. what to use instead
+}
+
+// deprecatedFieldKey identifies one client getting one deprecated field on one day
+type deprecatedFieldKey struct {
+	day      string
+	clientID string
+	method   string
+	endpoint string
+	field    string
+}
+
+var (
+	deprecatedFieldsMu sync.RWMutex
+	deprecatedFields   = make(map[string][]DeprecatedField) // By "METHOD endpoint"
+
+	deprecatedUsageMu sync.Mutex
+	deprecatedUsage   = make(map[deprecatedFieldKey]int64)
+)
+
+// RegisterDeprecatedFields records the deprecated fields of the response an endpoint (normalized,
+// e.g. /api/v1/expenses/{id}) returns, so responses carrying them are counted per client
+func RegisterDeprecatedFields(method, endpoint string, response interface{}) {
+	fields := collectDeprecatedFields(reflect.TypeOf(response), "", make(map[reflect.Type]bool))
+	if len(fields) == 0 {
+		return
+	}
+
+	deprecatedFieldsMu.Lock()
+	defer deprecatedFieldsMu.Unlock()
+	deprecatedFields[method+" "+endpoint] = fields
+}
+
+// collectDeprecatedFields walks a response type looking for deprecated tags
+func collectDeprecatedFields(t reflect.Type, prefix string, visiting map[reflect.Type]bool) []DeprecatedField {
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var fields []DeprecatedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if note, ok := field.Tag.Lookup("deprecated"); ok {
+			fields = append(fields, DeprecatedField{Path: path, Note: note})
+			continue
+		}
+		fields = append(fields, collectDeprecatedFields(field.Type, path, visiting)...)
+	}
+	return fields
+}
+
+// DeprecatedFieldsFor returns the deprecated fields registered for an endpoint
+func DeprecatedFieldsFor(method, endpoint string) []DeprecatedField {
+	deprecatedFieldsMu.RLock()
+	defer deprecatedFieldsMu.RUnlock()
+	return deprecatedFields[method+" "+endpoint]
+}
+
+// RecordDeprecatedFieldUse counts the deprecated fields present in a response body sent to a client
+func RecordDeprecatedFieldUse(clientID, method, endpoint string, body []byte) {
+	fields := DeprecatedFieldsFor(method, endpoint)
+	if len(fields) == 0 || len(body) == 0 {
+		return
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return
+	}
+
+	clientID = strings.TrimSpace(clientID)
+	if clientID == "" {
+		clientID = UnknownClientID
+	}
+	if len(clientID) > maxClientIDLength {
+		clientID = clientID[:maxClientIDLength]
+	}
+	day := time.Now().UTC().Format("2006-01-02")
+
+	deprecatedUsageMu.Lock()
+	defer deprecatedUsageMu.Unlock()
+	for _, field := range fields {
+		if jsonHasPath(decoded, strings.Split(field.Path, ".")) {
+			deprecatedUsage[deprecatedFieldKey{day, clientID, method, endpoint, field.Path}]++
+		}
+	}
+}
+
+// jsonHasPath reports whether a decoded JSON value has the path, looking inside arrays
+func jsonHasPath(value interface{}, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		return ok && jsonHasPath(child, path[1:])
+	case []interface{}:
+		for _, item := range v {
+			if jsonHasPath(item, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FlushDeprecatedFieldUsage writes the buffered deprecated field counts into the analytics tables
+func (s *Services) FlushDeprecatedFieldUsage() error {
+	deprecatedUsageMu.Lock()
+	counts := deprecatedUsage
+	deprecatedUsage = make(map[deprecatedFieldKey]int64)
+	deprecatedUsageMu.Unlock()
+
+	for key, count := range counts {
+		day, _ := time.Parse("2006-01-02", key.day)
+		stat := models.DeprecatedFieldStat{
+			Day:          day,
+			ClientID:     key.clientID,
+			Method:       key.method,
+			Endpoint:     key.endpoint,
+			Field:        key.field,
+			RequestCount: count,
+		}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "client_id"}, {Name: "method"}, {Name: "endpoint"}, {Name: "field"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"request_count": gorm.Expr("deprecated_field_stats.request_count + EXCLUDED.request_count"),
+				"updated_at":    time.Now(),
+			}),
+		}).Create(&stat).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(counts) > 0 {
+		logger.Debug("Flushed deprecated field usage: %d rows", len(counts))
+	}
+	return nil
+}
+
+// DeprecatedFieldClient is a client that still got a deprecated field in the period
+type DeprecatedFieldClient struct {
+	ClientID     string `json:"client_id"`
+	RequestCount int64  `json:"request_count"`
+	LastSeen     string `json:"last_seen"`
+}
+
+// DeprecatedFieldReport tells who still gets a deprecated field; no clients means it can go
+type DeprecatedFieldReport struct {
+	Method       string                  `json:"method"`
+	Endpoint     string                  `json:"endpoint"`
+	Field        string                  `json:"field"`
+	Note         string                  `json:"note"`
+	RequestCount int64                   `json:"request_count"`
+	Clients      []DeprecatedFieldClient `json:"clients"`
+}
+
+// GetDeprecatedFieldReport returns every registered deprecated field with the clients that got
+// it in the period, most used first
+func (s *Services) GetDeprecatedFieldReport(startDate, endDate time.Time) ([]DeprecatedFieldReport, error) {
+	var rows []struct {
+		Method       string
+		Endpoint     string
+		Field        string
+		ClientID     string
+		RequestCount int64
+		LastSeen     time.Time
+	}
+	result := s.db.Model(&models.DeprecatedFieldStat{}).
+		Select("method, endpoint, field, client_id, SUM(request_count) as request_count, MAX(day) as last_seen").
+		Where("day BETWEEN ? AND ?", startDate, endDate).
+		Group("method, endpoint, field, client_id").
+		Order("request_count DESC").
+		Scan(&rows)
+	if result.Error != nil {
+		logger.Error("Error getting deprecated field report: %v", result.Error)
+		return nil, result.Error
+	}
+
+	reports := make(map[string]*DeprecatedFieldReport)
+	entryFor := func(method, endpoint, field string) *DeprecatedFieldReport {
+		key := method + " " + endpoint + " " + field
+		if _, ok := reports[key]; !ok {
+			reports[key] = &DeprecatedFieldReport{Method: method, Endpoint: endpoint, Field: field, Clients: []DeprecatedFieldClient{}}
+		}
+		return reports[key]
+	}
+
+	deprecatedFieldsMu.RLock()
+	for route, fields := range deprecatedFields {
+		method, endpoint, _ := strings.Cut(route, " ")
+		for _, field := range fields {
+			entryFor(method, endpoint, field.Path).Note = field.Note
+		}
+	}
+	deprecatedFieldsMu.RUnlock()
+
+	for _, row := range rows {
+		entry := entryFor(row.Method, row.Endpoint, row.Field)
+		entry.RequestCount += row.RequestCount
+		entry.Clients = append(entry.Clients, DeprecatedFieldClient{
+			ClientID:     row.ClientID,
+			RequestCount: row.RequestCount,
+			LastSeen:     row.LastSeen.Format("2006-01-02"),
+		})
+	}
+
+	list := make([]DeprecatedFieldReport, 0, len(reports))
+	for _, entry := range reports {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].RequestCount != list[j].RequestCount {
+			return list[i].RequestCount > list[j].RequestCount
+		}
+		return list[i].Endpoint+list[i].Field < list[j].Endpoint+list[j].Field
+	})
+	return list, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrSplitExpenseLedgerChange is returned when a patch tries to change the amount or account of a split expense
+var ErrSplitExpenseLedgerChange = errors.New("amount and bank account of a split expense can't be patched; delete it and create it again")
+
+// validateExpenseAllocations checks a split expense: at least two distinct active accounts of
+// the user, positive amounts that add up to the expense total. The first allocation becomes
+// the expense's primary BankAccountID.
+func validateExpenseAllocations(tx *gorm.DB, userID string, expense *models.Expense) ([]models.BankAccount, error) {
+	if len(expense.Allocations) < 2 {
+		return nil, errors.New("a split expense needs at least two allocations")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(expense.Allocations))
+	accountIDs := make([]uuid.UUID, 0, len(expense.Allocations))
+	var total models.Money
+	for i := range expense.Allocations {
+		allocation := &expense.Allocations[i]
+		if allocation.Amount <= 0 {
+			return nil, errors.New("allocation amounts must be positive")
+		}
+		if seen[allocation.BankAccountID] {
+			return nil, errors.New("each bank account can only appear once in the allocations")
+		}
+		seen[allocation.BankAccountID] = true
+		accountIDs = append(accountIDs, allocation.BankAccountID)
+		total += allocation.Amount
+
+		// Never trust IDs coming from the client
+		allocation.ID = uuid.Nil
+		allocation.ExpenseID = uuid.Nil
+	}
+
+	if total != expense.Amount {
+		return nil, errors.New("allocations must add up to the expense amount")
+	}
+
+	var accounts []models.BankAccount
+	if err := tx.Where("id IN ? AND user_id = ? AND status IN ?", accountIDs, userID, models.GetActiveStatuses()).
+		Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	if len(accounts) != len(accountIDs) {
+		return nil, errors.New("bank account not found, not active, or access denied")
+	}
+
+	expense.BankAccountID = expense.Allocations[0].BankAccountID
+	return accounts, nil
+}
+
+// expenseLedger returns the amount taken from each bank account by an expense: its
+// allocations when it is split, or its whole amount on its bank account otherwise
+func expenseLedger(expense *models.Expense) []models.ExpenseAllocation {
+	if len(expense.Allocations) > 0 {
+		return expense.Allocations
+	}
+	return []models.ExpenseAllocation{{BankAccountID: expense.BankAccountID, Amount: expense.Amount}}
+}
+
+// loadExpenseAllocations fills the allocations of an expense loaded without them
+func loadExpenseAllocations(tx *gorm.DB, expense *models.Expense) error {
+	return tx.Where("expense_id = ?", expense.ID).Find(&expense.Allocations).Error
+}
+
+// applyExpenseLedger moves the expense amounts out of (sign 1) or back into (sign -1) each account
+func applyExpenseLedger(tx *gorm.DB, expense *models.Expense, sign models.Money) error {
+	for _, entry := range expenseLedger(expense) {
+		if err := adjustAccountBalance(tx, entry.BankAccountID, -sign*entry.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAllocatedAmounts sets, for a listing filtered by account, the portion of each expense
+// paid from that account
+func setAllocatedAmounts(expenses []models.Expense, bankAccountID string) {
+	for i := range expenses {
+		for _, entry := range expenseLedger(&expenses[i]) {
+			if entry.BankAccountID.String() == bankAccountID {
+				amount := entry.Amount
+				expenses[i].AllocatedAmount = &amount
+				break
+			}
+		}
+	}
+}
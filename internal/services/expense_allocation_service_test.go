@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestCreateSplitExpense(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	// Allocations name the accounts by position: 0 and 1 are the user's, 2 is someone else's
+	type allocation struct {
+		account int
+		amount  models.Money
+	}
+
+	cases := []struct {
+		name         string
+		amount       models.Money
+		allocations  []allocation
+		wantBalances [3]models.Money
+		wantErr      string
+	}{
+		{
+			name:         "deducts each allocation from its account",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(60)}, {1, models.NewMoney(40)}},
+			wantBalances: [3]models.Money{models.NewMoney(440), models.NewMoney(460), models.NewMoney(500)},
+		},
+		{
+			name:         "rejects allocations that don't add up",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(60)}, {1, models.NewMoney(30)}},
+			wantBalances: [3]models.Money{models.NewMoney(500), models.NewMoney(500), models.NewMoney(500)},
+			wantErr:      "allocations must add up to the expense amount",
+		},
+		{
+			name:         "rejects a single allocation",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(100)}},
+			wantBalances: [3]models.Money{models.NewMoney(500), models.NewMoney(500), models.NewMoney(500)},
+			wantErr:      "a split expense needs at least two allocations",
+		},
+		{
+			name:         "rejects the same account twice",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(50)}, {0, models.NewMoney(50)}},
+			wantBalances: [3]models.Money{models.NewMoney(500), models.NewMoney(500), models.NewMoney(500)},
+			wantErr:      "each bank account can only appear once in the allocations",
+		},
+		{
+			name:         "rejects a non-positive allocation",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(100)}, {1, 0}},
+			wantBalances: [3]models.Money{models.NewMoney(500), models.NewMoney(500), models.NewMoney(500)},
+			wantErr:      "allocation amounts must be positive",
+		},
+		{
+			name:         "rejects another user's account",
+			amount:       models.NewMoney(100),
+			allocations:  []allocation{{0, models.NewMoney(60)}, {2, models.NewMoney(40)}},
+			wantBalances: [3]models.Money{models.NewMoney(500), models.NewMoney(500), models.NewMoney(500)},
+			wantErr:      "bank account not found, not active, or access denied",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+			accounts := [3]*models.BankAccount{
+				h.CreateBankAccount(t, user, models.NewMoney(500)),
+				h.CreateBankAccount(t, user, models.NewMoney(500)),
+				h.CreateBankAccount(t, h.CreateUser(t), models.NewMoney(500)),
+			}
+
+			expense := &models.Expense{
+				CategoryID: category.ID,
+				Amount:     tc.amount,
+				Date:       time.Now().UTC().Truncate(24 * time.Hour),
+			}
+			for _, a := range tc.allocations {
+				expense.Allocations = append(expense.Allocations, models.ExpenseAllocation{
+					// Client IDs are ignored
+					ID:            uuid.New(),
+					BankAccountID: accounts[a.account].ID,
+					Amount:        a.amount,
+				})
+			}
+
+			err := h.Expenses.Create(context.Background(), user.ID.String(), expense, false)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("creating expense: %v", err)
+			}
+
+			for i, account := range accounts {
+				if balance := accountBalance(t, h, account); balance != tc.wantBalances[i] {
+					t.Errorf("balance of account %d = %s, want %s", i, balance, tc.wantBalances[i])
+				}
+			}
+			if tc.wantErr != "" {
+				return
+			}
+
+			if expense.BankAccountID != accounts[tc.allocations[0].account].ID {
+				t.Errorf("primary account = %s, want the first allocation's", expense.BankAccountID)
+			}
+			var stored int64
+			if err := h.DB.Model(&models.ExpenseAllocation{}).Where("expense_id = ?", expense.ID).Count(&stored).Error; err != nil {
+				t.Fatalf("counting allocations: %v", err)
+			}
+			if stored != int64(len(tc.allocations)) {
+				t.Errorf("%d allocations stored, want %d", stored, len(tc.allocations))
+			}
+		})
+	}
+}
+
+func TestSplitExpenseLedger(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+	checking := h.CreateBankAccount(t, user, models.NewMoney(500))
+	savings := h.CreateBankAccount(t, user, models.NewMoney(500))
+	userID := user.ID.String()
+
+	expense := &models.Expense{
+		CategoryID: category.ID,
+		Amount:     models.NewMoney(100),
+		Date:       time.Now().UTC().Truncate(24 * time.Hour),
+		Allocations: []models.ExpenseAllocation{
+			{BankAccountID: checking.ID, Amount: models.NewMoney(70)},
+			{BankAccountID: savings.ID, Amount: models.NewMoney(30)},
+		},
+	}
+	if err := h.Expenses.Create(context.Background(), userID, expense, false); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+
+	got, err := h.Expenses.GetByID(userID, expense.ID.String())
+	if err != nil {
+		t.Fatalf("getting expense: %v", err)
+	}
+	got.Amount = models.NewMoney(120)
+	if _, err := h.Expenses.Patch(userID, expense.ID.String(), got); !errors.Is(err, services.ErrSplitExpenseLedgerChange) {
+		t.Fatalf("patching the amount = %v, want %v", err, services.ErrSplitExpenseLedgerChange)
+	}
+
+	if err := h.Expenses.SoftDelete(userID, expense.ID.String()); err != nil {
+		t.Fatalf("deleting expense: %v", err)
+	}
+	if checkingBalance, savingsBalance := accountBalance(t, h, checking), accountBalance(t, h, savings); checkingBalance != models.NewMoney(500) || savingsBalance != models.NewMoney(500) {
+		t.Fatalf("balances after delete = %s and %s, want 500.00 each", checkingBalance, savingsBalance)
+	}
+
+	if _, err := h.Expenses.Restore(userID, expense.ID.String()); err != nil {
+		t.Fatalf("restoring expense: %v", err)
+	}
+	if checkingBalance, savingsBalance := accountBalance(t, h, checking), accountBalance(t, h, savings); checkingBalance != models.NewMoney(430) || savingsBalance != models.NewMoney(470) {
+		t.Fatalf("balances after restore = %s and %s, want 430.00 and 470.00", checkingBalance, savingsBalance)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/storage"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// MaxAttachmentsPerExpense caps the receipts kept for a single expense
+const MaxAttachmentsPerExpense = 10
+
+// allowedAttachmentTypes are the receipt formats accepted, as sniffed from the file content
+// rather than trusted from the client
+var allowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+var (
+	attachmentStorageOnce sync.Once
+	attachmentStorage     storage.Storage
+	attachmentStorageErr  error
+)
+
+// getAttachmentStorage returns the storage configured by ATTACHMENT_STORAGE
+func getAttachmentStorage() (storage.Storage, error) {
+	attachmentStorageOnce.Do(func() {
+		attachmentStorage, attachmentStorageErr = storage.FromEnv()
+		if attachmentStorageErr != nil {
+			logger.Error("Error configuring attachment storage: %v", attachmentStorageErr)
+		}
+	})
+	return attachmentStorage, attachmentStorageErr
+}
+
+// MaxAttachmentBytes is the largest receipt accepted (ATTACHMENT_MAX_MB, default 10)
+func MaxAttachmentBytes() int64 {
+	return int64(envInt("ATTACHMENT_MAX_MB", 10)) << 20
+}
+
+func attachmentStorageKey(expenseID, attachmentID uuid.UUID) string {
+	return "expenses/" + expenseID.String() + "/" + attachmentID.String()
+}
+
+// getAttachableExpense checks the expense exists, belongs to the user and isn't deleted
+func (s *Services) getAttachableExpense(userID string, expenseID string) (*models.Expense, error) {
+	var expense models.Expense
+	if err := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, expenseID, models.GetVisibleStatuses()).
+		First(&expense).Error; err != nil {
+		return nil, errors.New("expense not found or access denied")
+	}
+	return &expense, nil
+}
+
+// CreateExpenseAttachment stores a receipt for an expense: a JPEG, PNG, WebP image or a PDF of
+// at most MaxAttachmentBytes
+func (s *Services) CreateExpenseAttachment(ctx context.Context, userID string, expenseID string, fileName string, content io.Reader, size int64) (*models.ExpenseAttachment, error) {
+	expense, err := s.getAttachableExpense(userID, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMonthsOpen(s.db, userID, expense.Date); err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		return nil, errors.New("invalid attachment: the file is empty")
+	}
+	if size > MaxAttachmentBytes() {
+		return nil, fmt.Errorf("invalid attachment: the file is larger than %d MB", MaxAttachmentBytes()>>20)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.ExpenseAttachment{}).Where("expense_id = ?", expense.ID).Count(&count).Error; err != nil {
+		logger.Error("Error counting expense attachments: %v", err)
+		return nil, errors.New("error creating attachment")
+	}
+	if count >= MaxAttachmentsPerExpense {
+		return nil, fmt.Errorf("invalid attachment: an expense can have at most %d attachments", MaxAttachmentsPerExpense)
+	}
+
+	// Sniff the type from the first bytes, then upload them with the rest
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, errors.New("invalid attachment: the file can't be read")
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowedAttachmentTypes[contentType] {
+		return nil, errors.New("invalid attachment: only JPEG, PNG, WebP images and PDF files are accepted")
+	}
+
+	fileName = strings.TrimSpace(filepath.Base(strings.ReplaceAll(fileName, "\\", "/")))
+	if fileName == "" || fileName == "." || fileName == "/" {
+		fileName = "receipt"
+	}
+	if len(fileName) > 255 {
+		fileName = fileName[len(fileName)-255:]
+	}
+
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return nil, errors.New("attachment storage is not configured")
+	}
+
+	attachment := &models.ExpenseAttachment{
+		ID:          uuid.New(),
+		ExpenseID:   expense.ID,
+		UserID:      expense.UserID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+	}
+	attachment.StorageKey = attachmentStorageKey(expense.ID, attachment.ID)
+
+	if err := store.Put(ctx, attachment.StorageKey, contentType, io.MultiReader(bytes.NewReader(head), content), size); err != nil {
+		logger.Error("Error storing attachment of expense %s: %v", expense.ID, err)
+		return nil, errors.New("error storing attachment")
+	}
+	if err := s.db.Create(attachment).Error; err != nil {
+		logger.Error("Error creating attachment: %v", err)
+		if err := store.Delete(ctx, attachment.StorageKey); err != nil {
+			logger.Warn("Error removing orphan attachment %s: %v", attachment.StorageKey, err)
+		}
+		// The month may have been closed meanwhile
+		var closedErr *MonthClosedError
+		if errors.As(err, &closedErr) {
+			return nil, err
+		}
+		return nil, errors.New("error creating attachment")
+	}
+
+	logger.Info("Attachment %s added to expense %s (%s, %d bytes)", attachment.ID, expense.ID, contentType, size)
+	return attachment, nil
+}
+
+// GetExpenseAttachments lists the attachments of an expense of the user
+func (s *Services) GetExpenseAttachments(userID string, expenseID string) ([]models.ExpenseAttachment, error) {
+	expense, err := s.getAttachableExpense(userID, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []models.ExpenseAttachment
+	if err := s.db.Where("expense_id = ?", expense.ID).Order("created_at, id").Find(&attachments).Error; err != nil {
+		logger.Error("Error getting expense attachments: %v", err)
+		return nil, errors.New("error getting attachments")
+	}
+	return attachments, nil
+}
+
+func (s *Services) getExpenseAttachment(userID string, expenseID string, attachmentID string) (*models.ExpenseAttachment, error) {
+	expense, err := s.getAttachableExpense(userID, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment models.ExpenseAttachment
+	if err := s.db.Where("id = ? AND expense_id = ?", attachmentID, expense.ID).First(&attachment).Error; err != nil {
+		return nil, errors.New("attachment not found")
+	}
+	return &attachment, nil
+}
+
+// OpenExpenseAttachment returns an attachment and its content, which the caller must close
+func (s *Services) OpenExpenseAttachment(ctx context.Context, userID string, expenseID string, attachmentID string) (*models.ExpenseAttachment, io.ReadCloser, error) {
+	attachment, err := s.getExpenseAttachment(userID, expenseID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return nil, nil, errors.New("attachment storage is not configured")
+	}
+	content, err := store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		logger.Error("Attachment %s is missing from storage", attachment.ID)
+		return nil, nil, errors.New("attachment file not found")
+	}
+	if err != nil {
+		logger.Error("Error reading attachment %s: %v", attachment.ID, err)
+		return nil, nil, errors.New("error reading attachment")
+	}
+	return attachment, content, nil
+}
+
+// DeleteExpenseAttachment removes an attachment and its file
+func (s *Services) DeleteExpenseAttachment(ctx context.Context, userID string, expenseID string, attachmentID string) error {
+	attachment, err := s.getExpenseAttachment(userID, expenseID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(attachment).Error; err != nil {
+		logger.Error("Error deleting attachment: %v", err)
+		var closedErr *MonthClosedError
+		if errors.As(err, &closedErr) {
+			return err
+		}
+		return errors.New("error deleting attachment")
+	}
+	deleteAttachmentFiles(ctx, []models.ExpenseAttachment{*attachment})
+
+	logger.Info("Attachment %s removed from expense %s", attachment.ID, attachment.ExpenseID)
+	return nil
+}
+
+// deleteAttachmentFiles removes stored files whose rows are gone. Failures only leave orphan
+// files behind, so they are logged
+func deleteAttachmentFiles(ctx context.Context, attachments []models.ExpenseAttachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	store, err := getAttachmentStorage()
+	if err != nil {
+		return
+	}
+	for _, attachment := range attachments {
+		if err := store.Delete(ctx, attachment.StorageKey); err != nil {
+			logger.Warn("Error deleting attachment file %s: %v", attachment.StorageKey, err)
+		}
+	}
+}
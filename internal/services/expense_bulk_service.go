@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxBulkEditExpenses limits how many expenses one bulk edit can touch
+const MaxBulkEditExpenses = 1000
+
+// ExpenseBulkFilter selects the expenses of a bulk edit: either explicit IDs or a query. The
+// query fields are combined with AND
+type ExpenseBulkFilter struct {
+	IDs                 []string
+	CategoryID          *string
+	BankAccountID       *string
+	ImportBatchID       *string
+	DescriptionContains *string
+	From                *time.Time
+	To                  *time.Time
+}
+
+// ExpenseBulkUpdate holds the fields a bulk edit sets; nil fields are left alone
+type ExpenseBulkUpdate struct {
+	CategoryID    *string
+	BankAccountID *string
+}
+
+// bulkEditQuery builds the query over the visible expenses of the user matched by the filter
+func bulkEditQuery(tx *gorm.DB, userID string, filter ExpenseBulkFilter) (*gorm.DB, error) {
+	query := tx.Model(&models.Expense{}).Where("user_id = ? AND status IN ?", userID, models.GetVisibleStatuses())
+
+	if len(filter.IDs) > 0 {
+		if filter.CategoryID != nil || filter.BankAccountID != nil || filter.ImportBatchID != nil ||
+			filter.DescriptionContains != nil || filter.From != nil || filter.To != nil {
+			return nil, errors.New("invalid filter: use either expense IDs or a query, not both")
+		}
+		ids := make([]uuid.UUID, 0, len(filter.IDs))
+		for _, raw := range filter.IDs {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return nil, errors.New("invalid expense ID: " + raw)
+			}
+			ids = append(ids, id)
+		}
+		return query.Where("id IN ?", uniqueUUIDs(ids)), nil
+	}
+
+	empty := true
+	if filter.CategoryID != nil {
+		if _, err := uuid.Parse(*filter.CategoryID); err != nil {
+			return nil, errors.New("invalid category ID: " + *filter.CategoryID)
+		}
+		query = query.Where("category_id = ?", *filter.CategoryID)
+		empty = false
+	}
+	if filter.BankAccountID != nil {
+		if _, err := uuid.Parse(*filter.BankAccountID); err != nil {
+			return nil, errors.New("invalid bank account ID: " + *filter.BankAccountID)
+		}
+		query = query.Where("bank_account_id = ?", *filter.BankAccountID)
+		empty = false
+	}
+	if filter.ImportBatchID != nil {
+		if _, err := uuid.Parse(*filter.ImportBatchID); err != nil {
+			return nil, errors.New("invalid import batch ID: " + *filter.ImportBatchID)
+		}
+		query = query.Where("id IN (?)", tx.Model(&models.ImportedTransaction{}).Select("expense_id").
+			Where("batch_id = ? AND user_id = ? AND expense_id IS NOT NULL", *filter.ImportBatchID, userID))
+		empty = false
+	}
+	if filter.DescriptionContains != nil && strings.TrimSpace(*filter.DescriptionContains) != "" {
+		query = query.Where("description ILIKE ?", "%"+strings.TrimSpace(*filter.DescriptionContains)+"%")
+		empty = false
+	}
+	if filter.From != nil {
+		query = query.Where("date >= ?", *filter.From)
+		empty = false
+	}
+	if filter.To != nil {
+		if filter.From != nil && filter.To.Before(*filter.From) {
+			return nil, errors.New("invalid date range: to is before from")
+		}
+		query = query.Where("date <= ?", *filter.To)
+		empty = false
+	}
+	if empty {
+		// Never edit every expense of the user by accident
+		return nil, errors.New("invalid filter: give expense IDs or at least one query field")
+	}
+	return query, nil
+}
+
+// validateBulkUpdate checks that the new category and bank account are active and belong to the user
+func (s *Services) validateBulkUpdate(userID string, update ExpenseBulkUpdate) (categoryID *uuid.UUID, bankAccountID *uuid.UUID, err error) {
+	if update.CategoryID == nil && update.BankAccountID == nil {
+		return nil, nil, errors.New("invalid update: set category_id or bank_account_id")
+	}
+
+	if update.CategoryID != nil {
+		id, err := uuid.Parse(*update.CategoryID)
+		if err != nil {
+			return nil, nil, errors.New("invalid category ID: " + *update.CategoryID)
+		}
+		var count int64
+		if err := s.db.Model(&models.Category{}).Where("id = ? AND user_id = ? AND status IN ?", id, userID, models.GetActiveStatuses()).
+			Count(&count).Error; err != nil {
+			logger.Error("Error checking bulk edit category: %v", err)
+			return nil, nil, errors.New("error checking category")
+		}
+		if count == 0 {
+			return nil, nil, errors.New("category not found or not active")
+		}
+		categoryID = &id
+	}
+
+	if update.BankAccountID != nil {
+		id, err := uuid.Parse(*update.BankAccountID)
+		if err != nil {
+			return nil, nil, errors.New("invalid bank account ID: " + *update.BankAccountID)
+		}
+		var count int64
+		if err := s.db.Model(&models.BankAccount{}).Where("id = ? AND user_id = ? AND status IN ?", id, userID, models.GetActiveStatuses()).
+			Count(&count).Error; err != nil {
+			logger.Error("Error checking bulk edit bank account: %v", err)
+			return nil, nil, errors.New("error checking bank account")
+		}
+		if count == 0 {
+			return nil, nil, errors.New("bank account not found, not active, or access denied")
+		}
+		bankAccountID = &id
+	}
+	return categoryID, bankAccountID, nil
+}
+
+// planBulkEdit lists what the edit does to each matched expense. Split expenses can't move to
+// another account, the same as with a single patch
+func planBulkEdit(expenses []models.Expense, categoryID *uuid.UUID, bankAccountID *uuid.UUID) (*dto.ExpenseBulkEdit, error) {
+	plan := &dto.ExpenseBulkEdit{Matched: len(expenses), Changes: make([]dto.ExpenseBulkChange, 0, len(expenses))}
+	for _, expense := range expenses {
+		change := dto.ExpenseBulkChange{
+			ExpenseID:     expense.ID.String(),
+			Date:          expense.Date.Format("2006-01-02"),
+			Amount:        expense.Amount,
+			Description:   expense.Description,
+			CategoryID:    expense.CategoryID.String(),
+			BankAccountID: expense.BankAccountID.String(),
+		}
+		if categoryID != nil && *categoryID != expense.CategoryID {
+			newID := categoryID.String()
+			change.NewCategoryID = &newID
+		}
+		if bankAccountID != nil && *bankAccountID != expense.BankAccountID {
+			if len(expense.Allocations) > 0 {
+				return nil, ErrSplitExpenseLedgerChange
+			}
+			newID := bankAccountID.String()
+			change.NewBankAccountID = &newID
+		}
+
+		if change.NewCategoryID == nil && change.NewBankAccountID == nil {
+			plan.Unchanged++
+		} else {
+			plan.Updated++
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	return plan, nil
+}
+
+// BulkEditExpenses sets the category and/or bank account of every expense matched by the
+// filter in one transaction. Moving expenses to another account moves their amounts between
+// the balances. With dryRun nothing is saved and the result shows what would change
+func (s *Services) BulkEditExpenses(userID string, filter ExpenseBulkFilter, update ExpenseBulkUpdate, dryRun bool) (*dto.ExpenseBulkEdit, error) {
+	categoryID, bankAccountID, err := s.validateBulkUpdate(userID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	// findMatched loads the expenses of the filter, locking them when the edit is applied
+	findMatched := func(tx *gorm.DB, lock bool) ([]models.Expense, error) {
+		query, err := bulkEditQuery(tx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		if lock {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var expenses []models.Expense
+		if err := query.Limit(MaxBulkEditExpenses + 1).Order("date ASC, created_at ASC").Find(&expenses).Error; err != nil {
+			logger.Error("Error finding expenses for bulk edit: %v", err)
+			return nil, errors.New("error finding expenses")
+		}
+		if len(expenses) > MaxBulkEditExpenses {
+			return nil, errors.New("invalid filter: too many expenses, the maximum per bulk edit is 1000")
+		}
+		dates := make([]time.Time, 0, len(expenses))
+		for _, expense := range expenses {
+			dates = append(dates, expense.Date)
+		}
+		if err := ensureMonthsOpen(tx, userID, dates...); err != nil {
+			return nil, err
+		}
+		for i := range expenses {
+			if err := loadExpenseAllocations(tx, &expenses[i]); err != nil {
+				logger.Error("Error loading expense allocations: %v", err)
+				return nil, errors.New("error finding expenses")
+			}
+		}
+		return expenses, nil
+	}
+
+	if dryRun {
+		expenses, err := findMatched(s.db, false)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := planBulkEdit(expenses, categoryID, bankAccountID)
+		if err != nil {
+			return nil, err
+		}
+		plan.DryRun = true
+		return plan, nil
+	}
+
+	var plan *dto.ExpenseBulkEdit
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		expenses, err := findMatched(tx, true)
+		if err != nil {
+			return err
+		}
+		plan, err = planBulkEdit(expenses, categoryID, bankAccountID)
+		if err != nil {
+			return err
+		}
+
+		for i, change := range plan.Changes {
+			expense := &expenses[i]
+			updates := map[string]interface{}{}
+			if change.NewCategoryID != nil {
+				updates["category_id"] = *categoryID
+			}
+			if change.NewBankAccountID != nil {
+				// Give the amount back to the old account and take it from the new one
+				if err := applyExpenseLedger(tx, expense, -1); err != nil {
+					return err
+				}
+				expense.BankAccountID = *bankAccountID
+				if err := applyExpenseLedger(tx, expense, 1); err != nil {
+					return err
+				}
+				updates["bank_account_id"] = *bankAccountID
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := tx.Model(&models.Expense{}).Where("id = ?", expense.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var closedErr *MonthClosedError
+		if errors.Is(err, ErrSplitExpenseLedgerChange) || errors.As(err, &closedErr) || strings.HasPrefix(err.Error(), "invalid ") ||
+			strings.HasPrefix(err.Error(), "error ") {
+			return nil, err
+		}
+		logger.Error("Error applying bulk edit: %v", err)
+		return nil, errors.New("error updating expenses")
+	}
+
+	if plan.Updated > 0 {
+		details := map[string]interface{}{"updated": plan.Updated}
+		if categoryID != nil {
+			details["category_id"] = categoryID.String()
+		}
+		if bankAccountID != nil {
+			details["bank_account_id"] = bankAccountID.String()
+		}
+		s.RecordAudit(uuid.MustParse(userID), "expense.bulk_edit", "expense", nil, details)
+	}
+
+	logger.Info("Bulk edit updated %d of %d expenses of user %s", plan.Updated, plan.Matched, userID)
+	return plan, nil
+}
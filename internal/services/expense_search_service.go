@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/repository"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sort orders of an expense search
+const (
+	ExpenseSortRelevance  = "relevance" // Best text matches first; needs a query
+	ExpenseSortDateDesc   = "date_desc"
+	ExpenseSortDateAsc    = "date_asc"
+	ExpenseSortAmountDesc = "amount_desc"
+	ExpenseSortAmountAsc  = "amount_asc"
+)
+
+// MaxExpenseSearchQueryLength caps the free text of a search
+const MaxExpenseSearchQueryLength = 200
+
+// expenseSearchOrders are the ORDER BY of each sort; ties fall back to the newest expense
+var expenseSearchOrders = map[string]string{
+	ExpenseSortRelevance:  "date DESC, created_at DESC, id",
+	ExpenseSortDateDesc:   "date DESC, created_at DESC, id",
+	ExpenseSortDateAsc:    "date, created_at, id",
+	ExpenseSortAmountDesc: "amount DESC, date DESC, id",
+	ExpenseSortAmountAsc:  "amount, date DESC, id",
+}
+
+// expenseDescriptionDocument is the text search document of an expense; it must match the
+// expression of idx_expenses_description_fts for the index to be used
+const expenseDescriptionDocument = "to_tsvector('simple', COALESCE(expenses.description, ''))"
+
+// ExpenseSearch combines the filters of an expense search; zero fields don't filter
+type ExpenseSearch struct {
+	Query          string // Free text over the description: whole words, or any part of it
+	MinAmount      *models.Money
+	MaxAmount      *models.Money
+	CategoryIDs    []string // Expenses in any of these categories
+	BankAccountIDs []string // Expenses paid, fully or in part, from any of these accounts
+	Statuses       []string // Defaults to the visible statuses
+	From           *time.Time
+	To             *time.Time
+	Tags           TagFilter
+	Sort           string // Defaults to relevance with a query and date_desc without
+}
+
+// SearchExpenses finds the expenses of the user matching every filter of the search
+func (s *Services) SearchExpenses(userID string, search ExpenseSearch, page PageRequest) ([]models.Expense, PageInfo, error) {
+	query := s.db.Model(&models.Expense{}).Where("expenses.user_id = ?", userID)
+
+	text := strings.Join(strings.Fields(search.Query), " ")
+	if len(text) > MaxExpenseSearchQueryLength {
+		return nil, PageInfo{}, errors.New("invalid q: at most 200 characters")
+	}
+	if text != "" {
+		query = query.Where(expenseDescriptionDocument+" @@ plainto_tsquery('simple', ?) OR expenses.description ILIKE ?",
+			text, "%"+escapeLike(text)+"%")
+	}
+
+	if search.MinAmount != nil && search.MaxAmount != nil && *search.MinAmount > *search.MaxAmount {
+		return nil, PageInfo{}, errors.New("invalid amount range: min_amount is greater than max_amount")
+	}
+	if search.MinAmount != nil {
+		query = query.Where("expenses.amount >= ?", *search.MinAmount)
+	}
+	if search.MaxAmount != nil {
+		query = query.Where("expenses.amount <= ?", *search.MaxAmount)
+	}
+
+	if len(search.CategoryIDs) > 0 {
+		ids, err := parseUUIDs(search.CategoryIDs, "category_ids")
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		query = query.Where("expenses.category_id IN ?", ids)
+	}
+	if len(search.BankAccountIDs) > 0 {
+		ids, err := parseUUIDs(search.BankAccountIDs, "bank_account_ids")
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		query = query.Where("expenses.bank_account_id IN ? OR expenses.id IN (?)", ids,
+			s.db.Model(&models.ExpenseAllocation{}).Select("expense_id").Where("bank_account_id IN ?", ids))
+	}
+
+	statuses := models.GetVisibleStatuses()
+	if len(search.Statuses) > 0 {
+		statuses = make([]models.Status, len(search.Statuses))
+		for i, status := range search.Statuses {
+			statuses[i] = models.Status(status)
+			if !models.ValidateStatus(statuses[i]) {
+				return nil, PageInfo{}, errors.New("invalid status: " + status)
+			}
+		}
+	}
+	query = query.Where("expenses.status IN ?", statuses)
+
+	if search.From != nil && search.To != nil && search.From.After(*search.To) {
+		return nil, PageInfo{}, errors.New("invalid date range: from is after to")
+	}
+	if search.From != nil {
+		query = query.Where("expenses.date >= ?", *search.From)
+	}
+	if search.To != nil {
+		query = query.Where("expenses.date <= ?", *search.To)
+	}
+
+	query, err := filterByTags(query, userID, "expense_tags", "expense_id", search.Tags)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	sort := search.Sort
+	if sort == "" {
+		sort = ExpenseSortDateDesc
+		if text != "" {
+			sort = ExpenseSortRelevance
+		}
+	}
+	order, ok := expenseSearchOrders[sort]
+	if !ok {
+		return nil, PageInfo{}, errors.New("invalid sort: use relevance, date_desc, date_asc, amount_desc or amount_asc")
+	}
+	if sort == ExpenseSortRelevance {
+		if text == "" {
+			return nil, PageInfo{}, errors.New("invalid sort: relevance needs a q")
+		}
+		// Pagination orders by the columns after the rank; the count drops the ORDER BY
+		query = query.Order(clause.OrderBy{Expression: gorm.Expr(
+			"ts_rank("+expenseDescriptionDocument+", plainto_tsquery('simple', ?)) DESC", text)})
+	}
+
+	var expenses []models.Expense
+	info, err := paginate(query, order, page, &expenses, repository.PreloadExpenseRelations)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			return nil, PageInfo{}, err
+		}
+		logger.Error("Error searching expenses: %v", err)
+		return nil, PageInfo{}, errors.New("error searching expenses")
+	}
+
+	if len(search.BankAccountIDs) == 1 {
+		setAllocatedAmounts(expenses, search.BankAccountIDs[0])
+	}
+	return expenses, info, nil
+}
+
+// parseUUIDs parses a list of IDs of a filter, naming the filter when one is malformed
+func parseUUIDs(values []string, field string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := uuid.Parse(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.New("invalid " + field + ": " + value)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns so user text matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(text string) string {
+	return likeEscaper.Replace(text)
+}
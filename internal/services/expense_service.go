@@ -1,221 +1,347 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/repository"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// CreateExpense creates a new expense for the user
-func CreateExpense(userID string, expense *models.Expense) error {
+// ExpenseService creates, reads and changes the expenses of users. Reads and status changes go
+// through the repository; changes that move balances run in transactions on the database
+type ExpenseService struct {
+	db       *gorm.DB
+	expenses repository.ExpenseRepository
+	services *Services
+}
+
+// NewExpenseService returns an expense service using the database of the services and the given
+// repository, e.g. a test double of it
+func NewExpenseService(services *Services, expenses repository.ExpenseRepository) *ExpenseService {
+	return &ExpenseService{db: services.db, expenses: expenses, services: services}
+}
+
+// Create creates a new expense for the user. overrideCap lets the expense go through
+// a hard category cap; the override is recorded in the audit log.
+// Expenses of a sub-profile above its threshold are held for the parent instead, returning
+// an *ExpenseApprovalRequiredError.
+// The logs and queries of the creation, and the events it emits, carry the request ID of ctx.
+func (s *ExpenseService) Create(ctx context.Context, userID string, expense *models.Expense, overrideCap bool) error {
+	if err := s.services.holdForParentApproval(userID, expense); err != nil {
+		return err
+	}
+	return s.create(ctx, userID, expense, overrideCap)
+}
+
+func (s *ExpenseService) create(ctx context.Context, userID string, expense *models.Expense, overrideCap bool) error {
 	// Force the UserID and Status to prevent manipulation
 	expense.UserID = uuid.MustParse(userID)
 	expense.Status = models.StatusActive
 	
 	// Verify that the category exists and is active
 	var category models.Category
-	result := db.DB.Where("id = ? AND status IN ?", expense.CategoryID, models.GetActiveStatuses()).First(&category)
+	result := s.db.WithContext(ctx).Where("id = ? AND status IN ?", expense.CategoryID, models.GetActiveStatuses()).First(&category)
 	if result.Error != nil {
-		logger.Error("Category not found or not active")
+		logger.ErrorContext(ctx, "Category not found or not active")
 		return errors.New("category not found or not active")
 	}
 	
-	// Validate and verify that the bank account exists, is active and belongs to the user
-	var zeroUUID uuid.UUID
-	if expense.BankAccountID == zeroUUID {
-		logger.Error("Bank account ID is required")
-		return errors.New("bank account ID is required")
+	// Verify that the amount is positive
+	if expense.Amount <= 0 {
+		logger.ErrorContext(ctx, "Expense amount must be positive")
+		return errors.New("expense amount must be positive")
 	}
 	
-	var bankAccount models.BankAccount
-	result = db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
-		expense.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
-	if result.Error != nil {
-		logger.Error("Bank account not found, not active, or doesn't belong to user")
-		return errors.New("bank account not found, not active, or access denied")
+	// Closed months take no new expenses until reopened
+	if err := ensureMonthsOpen(s.db.WithContext(ctx), userID, expense.Date); err != nil {
+		return err
 	}
 	
-	// Verify that the amount is positive
-	if expense.Amount <= 0 {
-		logger.Error("Expense amount must be positive")
-		return errors.New("expense amount must be positive")
+	// Validate and verify that the bank account(s) exist, are active and belong to the user
+	var bankAccounts []models.BankAccount
+	if len(expense.Allocations) > 0 {
+		accounts, err := validateExpenseAllocations(s.db.WithContext(ctx), userID, expense)
+		if err != nil {
+			logger.ErrorContext(ctx, "Invalid expense allocations: %v", err)
+			return err
+		}
+		bankAccounts = accounts
+	} else {
+		var zeroUUID uuid.UUID
+		if expense.BankAccountID == zeroUUID {
+			logger.ErrorContext(ctx, "Bank account ID is required")
+			return errors.New("bank account ID is required")
+		}
+		
+		var bankAccount models.BankAccount
+		result = s.db.WithContext(ctx).Where("id = ? AND user_id = ? AND status IN ?", 
+			expense.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
+		if result.Error != nil {
+			logger.ErrorContext(ctx, "Bank account not found, not active, or doesn't belong to user")
+			return errors.New("bank account not found, not active, or access denied")
+		}
+		bankAccounts = []models.BankAccount{bankAccount}
 	}
 	
 	// Check balance (warning only, allow negative)
-	if bankAccount.Balance < expense.Amount {
-		logger.Warn("Expense will result in negative balance for account %s", bankAccount.ID)
+	for _, entry := range expenseLedger(expense) {
+		for _, bankAccount := range bankAccounts {
+			if bankAccount.ID == entry.BankAccountID && bankAccount.Balance < entry.Amount {
+				logger.WarnContext(ctx, "Expense will result in negative balance for account %s", bankAccount.ID)
+			}
+		}
 	}
 	
-	result = db.DB.Create(expense)
-	if result.Error != nil {
-		logger.Error("Error creating expense: %v", result.Error)
-		return result.Error
+	// Check the monthly cap of the category (hard caps block unless overridden)
+	capExceeded, err := s.services.checkCategoryCap(userID, category, expense, overrideCap)
+	if err != nil {
+		return err
 	}
 	
-	// Update bank account balance (deduct expense amount)
-	if err := db.DB.Model(&bankAccount).
-		Update("balance", gorm.Expr("balance - ?", expense.Amount)).Error; err != nil {
-		logger.Error("Error updating bank account balance: %v", err)
-		return errors.New("error updating bank account balance")
+	// An expense crossing the budget line of its type also emits budget.exceeded
+	exceeded, err := s.services.budgetExceededPayload(userID, category, expense)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error checking the budget of the expense: %v", err)
+		return err
 	}
 	
-	logger.Info("Expense created successfully: %+v", expense)
+	// The expense, its allocations, the balance changes and the domain event are committed together
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Tags are named freely; the missing ones are created for the user
+		if len(expense.Tags) > 0 {
+			tags, err := resolveTags(tx, userID, tagNames(expense.Tags))
+			if err != nil {
+				return err
+			}
+			expense.Tags = tags
+		}
+		
+		if err := tx.Create(expense).Error; err != nil {
+			logger.ErrorContext(ctx, "Error creating expense: %v", err)
+			return err
+		}
+		if err := spendConfirmToken(tx, expense.UserID, expense.ConfirmTokenID); err != nil {
+			return err
+		}
+		
+		// Update bank account balances (deduct each allocated amount)
+		if err := applyExpenseLedger(tx, expense, 1); err != nil {
+			return err
+		}
+		
+		payload := map[string]interface{}{
+			"category_id":     expense.CategoryID,
+			"bank_account_id": expense.BankAccountID,
+			"amount":          expense.Amount,
+			"date":            expense.Date.Format("2006-01-02"),
+		}
+		if len(expense.Allocations) > 0 {
+			allocations := make([]map[string]interface{}, 0, len(expense.Allocations))
+			for _, allocation := range expense.Allocations {
+				allocations = append(allocations, map[string]interface{}{
+					"bank_account_id": allocation.BankAccountID,
+					"amount":          allocation.Amount,
+				})
+			}
+			payload["allocations"] = allocations
+		}
+		if err := EnqueueEvent(tx, expense.UserID, EventExpenseCreated, "expense", expense.ID, payload); err != nil {
+			return err
+		}
+		if exceeded != nil {
+			exceeded["expense_id"] = expense.ID
+			return EnqueueEvent(tx, expense.UserID, EventBudgetExceeded, "budget", exceeded["budget_id"].(uuid.UUID), exceeded)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	if capExceeded && overrideCap && category.CapMode == models.CapModeHard {
+		s.services.RecordAudit(expense.UserID, "expense.cap_override", "expense", &expense.ID, map[string]interface{}{
+			"category_id": category.ID,
+			"monthly_cap": *category.MonthlyCap,
+			"amount":      expense.Amount,
+		})
+	}
+	
+	logger.InfoContext(ctx, "Expense %s created for %s", expense.ID, expense.Amount)
 	return nil
 }
 
-// GetExpenseByID gets a specific expense for the user
-func GetExpenseByID(userID string, id string) (*models.Expense, error) {
-	var expense models.Expense
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
-		Preload("Category").Preload("BankAccount").First(&expense)
-	if result.Error != nil {
-		logger.Error("Error getting expense by id: %v", result.Error)
-		return nil, result.Error
+// getInAnyStatus loads an expense with its relationships whatever its status, e.g. after
+// a status change
+func (s *ExpenseService) getInAnyStatus(userID string, id string) (*models.Expense, error) {
+	expense, err := s.expenses.FindByID(userID, id, nil)
+	if err != nil {
+		logger.Error("Error retrieving updated expense: %v", err)
+		return nil, errors.New("error retrieving updated expense")
+	}
+	return expense, nil
+}
+
+// GetByID gets a specific expense for the user
+func (s *ExpenseService) GetByID(userID string, id string) (*models.Expense, error) {
+	expense, err := s.expenses.FindByID(userID, id, models.GetVisibleStatuses())
+	if err != nil {
+		logger.Error("Error getting expense by id: %v", err)
+		return nil, err
 	}
 	
-	logger.Info("Expense retrieved successfully: %+v", expense)
-	return &expense, nil
+	logger.Info("Expense retrieved successfully: %+v", *expense)
+	return expense, nil
 }
 
-// GetAllExpenses gets all expenses for the user
-func GetAllExpenses(userID string, includeDeleted bool) ([]models.Expense, error) {
+// GetAll gets a page of the expenses of the user, optionally only those with some tags
+func (s *ExpenseService) GetAll(userID string, includeDeleted bool, tags TagFilter, page PageRequest) ([]models.Expense, PageInfo, error) {
 	var expenses []models.Expense
-	query := db.DB.Where("user_id = ?", userID).
-		Preload("Category").Preload("BankAccount")
+	query := s.db.Model(&models.Expense{}).Where("user_id = ?", userID)
 	
 	if !includeDeleted {
 		query = query.Where("status IN ?", models.GetVisibleStatuses())
 	}
+	query, err := filterByTags(query, userID, "expense_tags", "expense_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
 	
-	result := query.Order("date DESC, created_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting all expenses: %v", result.Error)
-		return nil, result.Error
+	info, err := paginate(query, "date DESC, created_at DESC, id", page, &expenses, repository.PreloadExpenseRelations)
+	if err != nil {
+		logger.Error("Error getting all expenses: %v", err)
+		return nil, PageInfo{}, err
 	}
 	
-	logger.Info("All expenses retrieved successfully: %+v", expenses)
-	return expenses, nil
+	logger.Info("All expenses retrieved successfully: %d of %d", len(expenses), info.Total)
+	return expenses, info, nil
 }
 
-// GetActiveExpenses gets all active expenses for the user
-func GetActiveExpenses(userID string) ([]models.Expense, error) {
+// GetActive gets a page of the active expenses of the user, optionally only those with some tags
+func (s *ExpenseService) GetActive(userID string, tags TagFilter, page PageRequest) ([]models.Expense, PageInfo, error) {
 	var expenses []models.Expense
-	result := db.DB.Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses()).
-		Preload("Category").Preload("BankAccount").
-		Order("date DESC, created_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting active expenses: %v", result.Error)
-		return nil, result.Error
+	query := s.db.Model(&models.Expense{}).Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses())
+	query, err := filterByTags(query, userID, "expense_tags", "expense_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	info, err := paginate(query, "date DESC, created_at DESC, id", page, &expenses, repository.PreloadExpenseRelations)
+	if err != nil {
+		logger.Error("Error getting active expenses: %v", err)
+		return nil, PageInfo{}, err
 	}
 	
-	logger.Info("Active expenses retrieved successfully: %+v", expenses)
-	return expenses, nil
+	logger.Info("Active expenses retrieved successfully: %d of %d", len(expenses), info.Total)
+	return expenses, info, nil
 }
 
-// GetDeletedExpenses gets all deleted expenses for the user
-func GetDeletedExpenses(userID string) ([]models.Expense, error) {
+// GetDeleted gets a page of the deleted expenses of the user, optionally only those with some tags
+func (s *ExpenseService) GetDeleted(userID string, tags TagFilter, page PageRequest) ([]models.Expense, PageInfo, error) {
 	var expenses []models.Expense
-	result := db.DB.Where("user_id = ? AND status = ?", userID, models.StatusDeleted).
-		Preload("Category").Preload("BankAccount").
-		Order("status_changed_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting deleted expenses: %v", result.Error)
-		return nil, result.Error
+	query := s.db.Model(&models.Expense{}).Where("user_id = ? AND status = ?", userID, models.StatusDeleted)
+	query, err := filterByTags(query, userID, "expense_tags", "expense_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	info, err := paginate(query, "status_changed_at DESC, id", page, &expenses, repository.PreloadExpenseRelations)
+	if err != nil {
+		logger.Error("Error getting deleted expenses: %v", err)
+		return nil, PageInfo{}, err
 	}
 	
-	logger.Info("Deleted expenses retrieved successfully: %+v", expenses)
-	return expenses, nil
+	logger.Info("Deleted expenses retrieved successfully: %d of %d", len(expenses), info.Total)
+	return expenses, info, nil
 }
 
-// GetExpensesByDateRange gets expenses in a date range for the user
-func GetExpensesByDateRange(userID string, startDate, endDate time.Time, includeDeleted bool) ([]models.Expense, error) {
-	var expenses []models.Expense
-	query := db.DB.Where("user_id = ? AND date BETWEEN ? AND ?", userID, startDate, endDate).
-		Preload("Category").Preload("BankAccount")
-	
-	if !includeDeleted {
-		query = query.Where("status IN ?", models.GetVisibleStatuses())
+// visibleStatuses returns the statuses of the expenses to list; nil lists them all
+func visibleStatuses(includeDeleted bool) []models.Status {
+	if includeDeleted {
+		return nil
 	}
-	
-	result := query.Order("date DESC, created_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting expenses by date range: %v", result.Error)
-		return nil, result.Error
+	return models.GetVisibleStatuses()
+}
+
+// GetByDateRange gets expenses in a date range for the user
+func (s *ExpenseService) GetByDateRange(userID string, startDate, endDate time.Time, includeDeleted bool) ([]models.Expense, error) {
+	expenses, err := s.expenses.ListByDateRange(userID, startDate, endDate, visibleStatuses(includeDeleted))
+	if err != nil {
+		logger.Error("Error getting expenses by date range: %v", err)
+		return nil, err
 	}
 	
 	logger.Info("Expenses by date range retrieved successfully: %+v", expenses)
 	return expenses, nil
 }
 
-// GetExpensesByCategory gets expenses for a specific category for the user
-func GetExpensesByCategory(userID string, categoryID string, includeDeleted bool) ([]models.Expense, error) {
-	var expenses []models.Expense
-	query := db.DB.Where("user_id = ? AND category_id = ?", userID, categoryID).
-		Preload("Category").Preload("BankAccount")
-	
-	if !includeDeleted {
-		query = query.Where("status IN ?", models.GetVisibleStatuses())
-	}
-	
-	result := query.Order("date DESC, created_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting expenses by category: %v", result.Error)
-		return nil, result.Error
+// GetByCategory gets expenses for a specific category for the user
+func (s *ExpenseService) GetByCategory(userID string, categoryID string, includeDeleted bool) ([]models.Expense, error) {
+	expenses, err := s.expenses.ListByCategory(userID, categoryID, visibleStatuses(includeDeleted))
+	if err != nil {
+		logger.Error("Error getting expenses by category: %v", err)
+		return nil, err
 	}
 	
 	logger.Info("Expenses by category retrieved successfully: %+v", expenses)
 	return expenses, nil
 }
 
-// GetExpensesByBankAccount gets expenses for a specific bank account for the user
-func GetExpensesByBankAccount(userID string, bankAccountID string, includeDeleted bool) ([]models.Expense, error) {
-	var expenses []models.Expense
-	query := db.DB.Where("user_id = ? AND bank_account_id = ?", userID, bankAccountID).
-		Preload("Category").Preload("BankAccount")
-	
-	if !includeDeleted {
-		query = query.Where("status IN ?", models.GetVisibleStatuses())
+// GetByBankAccount gets expenses for a specific bank account for the user, including
+// split expenses partly paid from it (AllocatedAmount holds the portion paid from the account)
+func (s *ExpenseService) GetByBankAccount(userID string, bankAccountID string, includeDeleted bool) ([]models.Expense, error) {
+	expenses, err := s.expenses.ListByBankAccount(userID, bankAccountID, visibleStatuses(includeDeleted))
+	if err != nil {
+		logger.Error("Error getting expenses by bank account: %v", err)
+		return nil, err
 	}
 	
-	result := query.Order("date DESC, created_at DESC").Find(&expenses)
-	if result.Error != nil {
-		logger.Error("Error getting expenses by bank account: %v", result.Error)
-		return nil, result.Error
-	}
+	setAllocatedAmounts(expenses, bankAccountID)
 	
 	logger.Info("Expenses by bank account retrieved successfully: %+v", expenses)
 	return expenses, nil
 }
 
-// GetMonthlyExpenses gets expenses for a specific month for the user
-func GetMonthlyExpenses(userID string, year int, month int, includeDeleted bool) ([]models.Expense, error) {
-	// Calcular el rango de fechas del mes
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, -1) // Último día del mes
+// GetMonthly gets expenses for a specific month for the user, over their month period
+func (s *ExpenseService) GetMonthly(userID string, year int, month int, includeDeleted bool) ([]models.Expense, error) {
+	startDate, endDate := s.services.getUserCalendar(userID).monthPeriod(year, time.Month(month))
 	
-	return GetExpensesByDateRange(userID, startDate, endDate, includeDeleted)
+	return s.GetByDateRange(userID, startDate, endDate, includeDeleted)
 }
 
-// PatchExpense updates an expense for the user
-func PatchExpense(userID string, id string, expense *models.Expense) (*models.Expense, error) {
+// Patch updates an expense for the user
+func (s *ExpenseService) Patch(userID string, id string, expense *models.Expense) (*models.Expense, error) {
 	var existingExpense models.Expense
 	
 	// Verificar que el gasto existe, pertenece al usuario y no está eliminado
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingExpense)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingExpense)
 	if result.Error != nil {
 		logger.Error("Expense not found or doesn't belong to user: %v", result.Error)
 		return nil, errors.New("expense not found or access denied")
 	}
 	
+	// Neither the month it's in nor the one it moves to can be closed
+	if err := ensureMonthsOpen(s.db, userID, existingExpense.Date, expense.Date); err != nil {
+		return nil, err
+	}
+	
+	// Split expenses keep their allocations; only the other fields can be patched
+	if err := loadExpenseAllocations(s.db, &existingExpense); err != nil {
+		logger.Error("Error loading expense allocations: %v", err)
+		return nil, err
+	}
+	if len(existingExpense.Allocations) > 0 &&
+		(existingExpense.Amount != expense.Amount || existingExpense.BankAccountID != expense.BankAccountID) {
+		return nil, ErrSplitExpenseLedgerChange
+	}
+	
 	// Verificar que la categoría existe y está activa si se está cambiando
 	if existingExpense.CategoryID != expense.CategoryID {
 		var category models.Category
-		result := db.DB.Where("id = ? AND status IN ?", expense.CategoryID, models.GetActiveStatuses()).First(&category)
+		result := s.db.Where("id = ? AND status IN ?", expense.CategoryID, models.GetActiveStatuses()).First(&category)
 		if result.Error != nil {
 			logger.Error("Category not found or not active")
 			return nil, errors.New("category not found or not active")
@@ -225,7 +351,7 @@ func PatchExpense(userID string, id string, expense *models.Expense) (*models.Ex
 	// Verificar que la cuenta bancaria existe, está activa y pertenece al usuario si se está cambiando
 	if existingExpense.BankAccountID != expense.BankAccountID {
 		var bankAccount models.BankAccount
-		result := db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
+		result := s.db.Where("id = ? AND user_id = ? AND status IN ?", 
 			expense.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
 		if result.Error != nil {
 			logger.Error("Bank account not found, not active, or doesn't belong to user")
@@ -239,41 +365,6 @@ func PatchExpense(userID string, id string, expense *models.Expense) (*models.Ex
 		return nil, errors.New("expense amount must be positive")
 	}
 	
-	// If amount changed, adjust bank account balance
-	if existingExpense.Amount != expense.Amount {
-		var bankAccount models.BankAccount
-		if err := db.DB.Where("id = ?", existingExpense.BankAccountID).First(&bankAccount).Error; err != nil {
-			return nil, errors.New("bank account not found")
-		}
-		
-		// Reverse old expense and apply new expense
-		balanceChange := existingExpense.Amount - expense.Amount
-		if err := db.DB.Model(&bankAccount).
-			Update("balance", gorm.Expr("balance + ?", balanceChange)).Error; err != nil {
-			return nil, errors.New("error updating bank account balance")
-		}
-	}
-	
-	// If bank account changed, move amounts between accounts
-	if existingExpense.BankAccountID != expense.BankAccountID {
-		// Add back to old account
-		if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingExpense.BankAccountID).
-			Update("balance", gorm.Expr("balance + ?", existingExpense.Amount)).Error; err != nil {
-			return nil, errors.New("error updating old bank account")
-		}
-		
-		// Deduct from new account
-		var newAccount models.BankAccount
-		if err := db.DB.Where("id = ?", expense.BankAccountID).First(&newAccount).Error; err != nil {
-			return nil, errors.New("new bank account not found")
-		}
-		
-		if err := db.DB.Model(&newAccount).
-			Update("balance", gorm.Expr("balance - ?", expense.Amount)).Error; err != nil {
-			return nil, errors.New("error updating new bank account")
-		}
-	}
-	
 	// Prevenir modificación de campos protegidos
 	expense.UserID = existingExpense.UserID
 	expense.ID = existingExpense.ID
@@ -283,105 +374,149 @@ func PatchExpense(userID string, id string, expense *models.Expense) (*models.Ex
 	expense.Status = existingExpense.Status
 	expense.StatusChangedAt = existingExpense.StatusChangedAt
 	
-	// Actualizar
-	result = db.DB.Model(&existingExpense).Where("user_id = ? AND id = ?", userID, id).Updates(expense)
-	if result.Error != nil {
-		logger.Error("Error patching expense: %v", result.Error)
-		return nil, result.Error
+	// Actualizar; the old amount goes back to its account and the new one is taken in the same transaction
+	before := existingExpense
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingExpense).Where("user_id = ? AND id = ?", userID, id).Omit("Tags").Updates(expense).Error; err != nil {
+			logger.Error("Error patching expense: %v", err)
+			return err
+		}
+		// Tags given (even none) replace the current ones
+		if expense.Tags != nil {
+			if err := replaceTags(tx, userID, &existingExpense, expense.Tags); err != nil {
+				return err
+			}
+		}
+		if before.Amount == expense.Amount && before.BankAccountID == expense.BankAccountID {
+			return nil
+		}
+		if err := adjustAccountBalance(tx, before.BankAccountID, before.Amount); err != nil {
+			return err
+		}
+		return adjustAccountBalance(tx, expense.BankAccountID, -expense.Amount)
+	})
+	if err != nil {
+		return nil, err
 	}
 	
 	// Obtener el gasto actualizado con relaciones
-	result = db.DB.Where("user_id = ? AND id = ?", userID, id).
-		Preload("Category").Preload("BankAccount").First(&existingExpense)
+	result = s.db.Where("user_id = ? AND id = ?", userID, id).
+		Scopes(repository.PreloadExpenseRelations).First(&existingExpense)
 	if result.Error != nil {
 		logger.Error("Error retrieving updated expense: %v", result.Error)
 		return nil, result.Error
 	}
 	
+	if err := clearChangedProvenance(s.db, &before, &existingExpense); err != nil {
+		logger.Warn("Error clearing provenance of expense %s: %v", id, err)
+	}
+	
 	logger.Info("Expense patched successfully: %+v", existingExpense)
 	return &existingExpense, nil
 }
 
-// SoftDeleteExpense marks an expense as deleted for the user
-func SoftDeleteExpense(userID string, id string) error {
+// SoftDelete marks an expense as deleted for the user
+func (s *ExpenseService) SoftDelete(userID string, id string) error {
 	// Verificar que el gasto existe y pertenece al usuario
 	var existingExpense models.Expense
-	result := db.DB.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingExpense)
+	result := s.db.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingExpense)
 	if result.Error != nil {
 		logger.Error("Expense not found or already deleted: %v", result.Error)
 		return errors.New("expense not found or already deleted")
 	}
-	
-	// Marcar como eliminado
-	now := time.Now()
-	result = db.DB.Model(&existingExpense).Updates(map[string]interface{}{
-		"status": models.StatusDeleted,
-		"status_changed_at": &now,
-	})
-	
-	if result.Error != nil {
-		logger.Error("Error soft deleting expense: %v", result.Error)
-		return result.Error
+	if _, err := checkStatusTransition(models.ExpenseStatusMachine, existingExpense.Status, models.StatusDeleted); err != nil {
+		return err
+	}
+	if err := ensureMonthsOpen(s.db, userID, existingExpense.Date); err != nil {
+		return err
 	}
 	
-	// Restore amount to bank account
-	if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingExpense.BankAccountID).
-		Update("balance", gorm.Expr("balance + ?", existingExpense.Amount)).Error; err != nil {
-		logger.Error("Error restoring balance: %v", err)
+	if err := loadExpenseAllocations(s.db, &existingExpense); err != nil {
+		logger.Error("Error loading expense allocations: %v", err)
 		return errors.New("error restoring bank account balance")
 	}
 	
+	// Marcar como eliminado and give the amount back to the bank account(s) together
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingExpense).Updates(map[string]interface{}{
+			"status": models.StatusDeleted,
+			"status_changed_at": &now,
+		}).Error; err != nil {
+			logger.Error("Error soft deleting expense: %v", err)
+			return err
+		}
+		if err := applyExpenseLedger(tx, &existingExpense, -1); err != nil {
+			logger.Error("Error restoring balance: %v", err)
+			return errors.New("error restoring bank account balance")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
 	logger.Info("Expense soft deleted successfully: %s", id)
 	return nil
 }
 
-// RestoreExpense restores a deleted expense for the user
-func RestoreExpense(userID string, id string) (*models.Expense, error) {
+// Restore restores a deleted expense for the user
+func (s *ExpenseService) Restore(userID string, id string) (*models.Expense, error) {
 	// Verificar que el gasto existe, pertenece al usuario y está eliminado
 	var existingExpense models.Expense
-	result := db.DB.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingExpense)
+	result := s.db.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingExpense)
 	if result.Error != nil {
 		logger.Error("Expense not found, not deleted, or access denied: %v", result.Error)
 		return nil, errors.New("expense not found, not deleted, or access denied")
 	}
+	if err := ensureMonthsOpen(s.db, userID, existingExpense.Date); err != nil {
+		return nil, err
+	}
 	
 	// Verificar que la categoría y cuenta bancaria siguen activas
 	var category models.Category
-	result = db.DB.Where("id = ? AND status IN ?", existingExpense.CategoryID, models.GetActiveStatuses()).First(&category)
+	result = s.db.Where("id = ? AND status IN ?", existingExpense.CategoryID, models.GetActiveStatuses()).First(&category)
 	if result.Error != nil {
 		logger.Error("Cannot restore expense: category is not active")
 		return nil, errors.New("cannot restore expense: category is not active")
 	}
 	
-	var bankAccount models.BankAccount
-	result = db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
-		existingExpense.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
-	if result.Error != nil {
-		logger.Error("Cannot restore expense: bank account is not active")
-		return nil, errors.New("cannot restore expense: bank account is not active")
+	if err := loadExpenseAllocations(s.db, &existingExpense); err != nil {
+		logger.Error("Error loading expense allocations: %v", err)
+		return nil, err
+	}
+	for _, entry := range expenseLedger(&existingExpense) {
+		var bankAccount models.BankAccount
+		result = s.db.Where("id = ? AND user_id = ? AND status IN ?", 
+			entry.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
+		if result.Error != nil {
+			logger.Error("Cannot restore expense: bank account is not active")
+			return nil, errors.New("cannot restore expense: bank account is not active")
+		}
 	}
 	
-	// Restaurar como activo
+	// Restaurar como activo and deduct the amount from the bank account(s) again together
 	now := time.Now()
-	result = db.DB.Model(&existingExpense).Updates(map[string]interface{}{
-		"status": models.StatusActive,
-		"status_changed_at": &now,
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingExpense).Updates(map[string]interface{}{
+			"status": models.StatusActive,
+			"status_changed_at": &now,
+		}).Error; err != nil {
+			logger.Error("Error restoring expense: %v", err)
+			return err
+		}
+		if err := applyExpenseLedger(tx, &existingExpense, 1); err != nil {
+			logger.Error("Error deducting balance: %v", err)
+			return err
+		}
+		return nil
 	})
-	
-	if result.Error != nil {
-		logger.Error("Error restoring expense: %v", result.Error)
-		return nil, result.Error
-	}
-	
-	// Deduct amount from bank account again
-	if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingExpense.BankAccountID).
-		Update("balance", gorm.Expr("balance - ?", existingExpense.Amount)).Error; err != nil {
-		logger.Error("Error deducting balance: %v", err)
-		return nil, errors.New("error updating bank account balance")
+	if err != nil {
+		return nil, err
 	}
 	
 	// Get the updated expense with all relationships
-	updatedExpense, err := GetExpenseByID(userID, id)
+	updatedExpense, err := s.GetByID(userID, id)
 	if err != nil {
 		logger.Error("Error retrieving updated expense: %v", err)
 		return nil, errors.New("error retrieving updated expense")
@@ -391,8 +526,8 @@ func RestoreExpense(userID string, id string) (*models.Expense, error) {
 	return updatedExpense, nil
 }
 
-// ChangeExpenseStatus changes the status of an expense for the user
-func ChangeExpenseStatus(userID string, id string, newStatus models.Status, reason *string) (*models.Expense, error) {
+// ChangeStatus changes the status of an expense for the user
+func (s *ExpenseService) ChangeStatus(userID string, id string, newStatus models.Status, reason *string) (*models.Expense, error) {
 	// Validar que el status es válido
 	if !models.ValidateStatus(newStatus) {
 		return nil, errors.New("invalid status")
@@ -400,7 +535,7 @@ func ChangeExpenseStatus(userID string, id string, newStatus models.Status, reas
 	
 	// Verificar que el gasto existe y pertenece al usuario
 	var existingExpense models.Expense
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).First(&existingExpense)
+	result := s.db.Where("user_id = ? AND id = ?", userID, id).First(&existingExpense)
 	if result.Error != nil {
 		logger.Error("Expense not found: %v", result.Error)
 		return nil, errors.New("expense not found or access denied")
@@ -408,53 +543,81 @@ func ChangeExpenseStatus(userID string, id string, newStatus models.Status, reas
 	
 	// No hacer nada si ya tiene ese status - return current expense
 	if existingExpense.Status == newStatus {
-		updatedExpense, err := GetExpenseByID(userID, id)
-		if err != nil {
-			logger.Error("Error retrieving expense: %v", err)
-			return nil, errors.New("error retrieving expense")
-		}
-		return updatedExpense, nil
+		return s.getInAnyStatus(userID, id)
 	}
 	
-	// Actualizar status
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status": newStatus,
-		"status_changed_at": &now,
+	transition, err := checkStatusTransition(models.ExpenseStatusMachine, existingExpense.Status, newStatus)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMonthsOpen(s.db, userID, existingExpense.Date); err != nil {
+		return nil, err
 	}
 	
-	result = db.DB.Model(&existingExpense).Updates(updates)
-	if result.Error != nil {
-		logger.Error("Error changing expense status: %v", result.Error)
-		return nil, result.Error
+	// Deleting and restoring move the balances, like DELETE and restore do
+	switch {
+	case transition.HasSideEffect(models.SideEffectReverseBalance):
+		if err := s.SoftDelete(userID, id); err != nil {
+			return nil, err
+		}
+	case transition.HasSideEffect(models.SideEffectReapplyBalance):
+		if _, err := s.Restore(userID, id); err != nil {
+			return nil, err
+		}
+	default:
+		if err := s.expenses.UpdateStatus(&existingExpense, newStatus, time.Now()); err != nil {
+			logger.Error("Error changing expense status: %v", err)
+			return nil, err
+		}
 	}
 	
 	// Get the updated expense with all relationships
-	updatedExpense, err := GetExpenseByID(userID, id)
+	updatedExpense, err := s.getInAnyStatus(userID, id)
 	if err != nil {
-		logger.Error("Error retrieving updated expense: %v", err)
-		return nil, errors.New("error retrieving updated expense")
+		return nil, err
 	}
 	
 	logger.Info("Expense status changed to %s successfully: %s", newStatus, id)
 	return updatedExpense, nil
 }
 
-// HardDeleteExpense permanently deletes an expense for the user
-func HardDeleteExpense(userID string, id string) error {
+// HardDelete permanently deletes an expense for the user
+func (s *ExpenseService) HardDelete(userID string, id string) error {
 	// SOLO para casos especiales - elimina permanentemente
+	// The attachment rows go with the expense (cascade); their files are removed afterwards
+	var attachments []models.ExpenseAttachment
+	s.db.Where("expense_id = ? AND user_id = ?", id, userID).Find(&attachments)
+	
+	var existingExpense models.Expense
+	if err := s.db.Select("id", "date").Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&existingExpense).Error; err != nil {
+		logger.Error("Error hard deleting expense: %v", err)
+		return err
+	}
+	if err := ensureMonthsOpen(s.db, userID, existingExpense.Date); err != nil {
+		return err
+	}
+	
+	// Sync clients holding it must resync; recorded first, as a needless resync is harmless
+	if existingExpense.ID != uuid.Nil {
+		if err := recordSyncPurge(s.db, uuid.MustParse(userID), "expenses", time.Now()); err != nil {
+			logger.Error("Error hard deleting expense: %v", err)
+			return err
+		}
+	}
+	
 	// Verificar que el gasto existe y pertenece al usuario
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Expense{})
-	if result.Error != nil {
-		logger.Error("Error hard deleting expense: %v", result.Error)
-		return result.Error
+	deleted, err := s.expenses.Delete(userID, id)
+	if err != nil {
+		logger.Error("Error hard deleting expense: %v", err)
+		return err
 	}
 	
 	// Verificar que realmente se eliminó algo
-	if result.RowsAffected == 0 {
+	if !deleted {
 		logger.Error("Expense not found or doesn't belong to user")
 		return errors.New("expense not found or access denied")
 	}
+	deleteAttachmentFiles(context.Background(), attachments)
 	
 	logger.Info("Expense permanently deleted: %s", id)
 	return nil
@@ -462,126 +625,23 @@ func HardDeleteExpense(userID string, id string) error {
 
 // === ANÁLISIS Y ESTADÍSTICAS ===
 
-// GetExpensesSummaryByPeriod gets expense summary for a period
-func GetExpensesSummaryByPeriod(userID string, startDate, endDate time.Time) (map[string]interface{}, error) {
-	var summary map[string]interface{}
-	summary = make(map[string]interface{})
-	
-	// Total gastado en el período
-	var totalAmount float64
-	result := db.DB.Model(&models.Expense{}).
-		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", 
-			userID, startDate, endDate, models.GetActiveStatuses()).
-		Select("COALESCE(SUM(amount), 0)").Scan(&totalAmount)
-	if result.Error != nil {
-		logger.Error("Error calculating total expenses: %v", result.Error)
-		return nil, result.Error
-	}
-	summary["total_amount"] = totalAmount
-	
-	// Contar total de gastos
-	var totalCount int64
-	db.DB.Model(&models.Expense{}).
-		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", 
-			userID, startDate, endDate, models.GetActiveStatuses()).Count(&totalCount)
-	summary["total_count"] = totalCount
-	
-	// Promedio por gasto
-	if totalCount > 0 {
-		summary["average_amount"] = totalAmount / float64(totalCount)
-	} else {
-		summary["average_amount"] = 0.0
-	}
-	
-	// Gastos por ExpenseType (50/30/20)
-	var expensesByType []struct {
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
-		Count           int64   `json:"count"`
-	}
-	
-	result = db.DB.Table("expenses e").
-		Select(`(CASE 
-			WHEN c.expense_type = 'needs' THEN 'Needs'
-			WHEN c.expense_type = 'wants' THEN 'Wants'
-			WHEN c.expense_type = 'savings' THEN 'Savings'
-			ELSE c.expense_type::text
-		END)::text as expense_type_name, 
-		COALESCE(SUM(e.amount), 0) as total_amount, 
-		COUNT(e.id) as count`).
-		Joins("JOIN categories c ON e.category_id = c.id").
-		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?", 
-			userID, startDate, endDate, models.GetActiveStatuses()).
-		Group("c.expense_type").
-		Order("total_amount DESC").
-		Scan(&expensesByType)
-	
-	if result.Error != nil {
-		logger.Error("Error getting expenses by type: %v", result.Error)
-		return nil, result.Error
-	}
-	summary["by_expense_type"] = expensesByType
-	
-	// Top 10 categorías
-	var expensesByCategory []struct {
-		CategoryName    string  `json:"category_name"`
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
-		Count           int64   `json:"count"`
-	}
-	
-	result = db.DB.Table("expenses e").
-		Select(`c.name as category_name, 
-		(CASE 
-			WHEN c.expense_type = 'needs' THEN 'Needs'
-			WHEN c.expense_type = 'wants' THEN 'Wants'
-			WHEN c.expense_type = 'savings' THEN 'Savings'
-			ELSE c.expense_type::text
-		END)::text as expense_type_name, 
-		COALESCE(SUM(e.amount), 0) as total_amount, 
-		COUNT(e.id) as count`).
-		Joins("JOIN categories c ON e.category_id = c.id").
-		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?", 
-			userID, startDate, endDate, models.GetActiveStatuses()).
-		Group("c.id, c.name, c.expense_type").
-		Order("total_amount DESC").
-		Limit(10).
-		Scan(&expensesByCategory)
-	
-	if result.Error != nil {
-		logger.Error("Error getting top categories: %v", result.Error)
-		return nil, result.Error
-	}
-	summary["top_categories"] = expensesByCategory
-	
-	logger.Info("Expense summary calculated successfully for user %s", userID)
-	return summary, nil
-}
-
-// GetMonthlyExpensesSummary gets monthly expenses summary for the user
-func GetMonthlyExpensesSummary(userID string, year int, month int) (map[string]interface{}, error) {
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, -1) // Último día del mes
-	
-	return GetExpensesSummaryByPeriod(userID, startDate, endDate)
-}
-
 // GetExpensesByExpenseType gets expenses grouped by expense type for budget validation
-func GetExpensesByExpenseType(userID string, startDate, endDate time.Time) (map[string]float64, error) {
+func (s *Services) GetExpensesByExpenseType(userID string, startDate, endDate time.Time) (map[string]models.Money, error) {
 	var results []struct {
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
+		ExpenseTypeName string       `json:"expense_type_name"`
+		TotalAmount     models.Money `json:"total_amount"`
 	}
 	
-	result := db.DB.Table("expenses e").
+	result := s.db.Table("expenses e").
 		Select(`(CASE 
 			WHEN c.expense_type = 'needs' THEN 'Needs'
 			WHEN c.expense_type = 'wants' THEN 'Wants'
 			WHEN c.expense_type = 'savings' THEN 'Savings'
 			ELSE c.expense_type::text
 		END)::text as expense_type_name, 
-		COALESCE(SUM(e.amount), 0) as total_amount`).
+		COALESCE(SUM(` + netExpenseAmountSQL() + `), 0) as total_amount`).
 		Joins("JOIN categories c ON e.category_id = c.id").
+		Scopes(joinExpenseRefunds).
 		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?", 
 			userID, startDate, endDate, models.GetActiveStatuses()).
 		Group("c.expense_type").
@@ -593,7 +653,7 @@ func GetExpensesByExpenseType(userID string, startDate, endDate time.Time) (map[
 	}
 	
 	// Convertir a mapa para fácil acceso
-	expensesByType := make(map[string]float64)
+	expensesByType := make(map[string]models.Money)
 	for _, item := range results {
 		expensesByType[item.ExpenseTypeName] = item.TotalAmount
 	}
@@ -603,110 +663,45 @@ func GetExpensesByExpenseType(userID string, startDate, endDate time.Time) (map[
 }
 
 
-// GetSpendingTrends gets spending trends over time for the user
-func GetSpendingTrends(userID string, months int) (map[string]interface{}, error) {
-	var trends map[string]interface{}
-	trends = make(map[string]interface{})
-	
-	// Calcular fechas
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, -months, 0)
-	
-	// Gastos por mes
-	var monthlyTrends []struct {
-		Month       string  `json:"month"`
-		TotalAmount float64 `json:"total_amount"`
-		Count       int64   `json:"count"`
-	}
-	
-	result := db.DB.Table("expenses").
-		Select("TO_CHAR(date, 'YYYY-MM') as month, COALESCE(SUM(amount), 0) as total_amount, COUNT(id) as count").
-		Where("user_id = ? AND date >= ? AND status IN ?", 
-			userID, startDate, models.GetActiveStatuses()).
-		Group("TO_CHAR(date, 'YYYY-MM')").
-		Order("month ASC").
-		Scan(&monthlyTrends)
-	
-	if result.Error != nil {
-		logger.Error("Error getting monthly trends: %v", result.Error)
-		return nil, result.Error
-	}
-	trends["monthly_trends"] = monthlyTrends
-	
-	// Tendencias por tipo de gasto
-	var typesTrends []struct {
-		Month           string  `json:"month"`
-		ExpenseTypeName string  `json:"expense_type_name"`
-		TotalAmount     float64 `json:"total_amount"`
-	}
-	
-	result = db.DB.Table("expenses e").
-		Select(`TO_CHAR(e.date, 'YYYY-MM') as month, 
-		(CASE 
-			WHEN c.expense_type = 'needs' THEN 'Needs'
-			WHEN c.expense_type = 'wants' THEN 'Wants'
-			WHEN c.expense_type = 'savings' THEN 'Savings'
-			ELSE c.expense_type::text
-		END)::text as expense_type_name, 
-		COALESCE(SUM(e.amount), 0) as total_amount`).
-		Joins("JOIN categories c ON e.category_id = c.id").
-		Where("e.user_id = ? AND e.date >= ? AND e.status IN ?", 
-			userID, startDate, models.GetActiveStatuses()).
-		Group("TO_CHAR(e.date, 'YYYY-MM'), c.expense_type").
-		Order("month ASC, expense_type_name").
-		Scan(&typesTrends)
-	
-	if result.Error != nil {
-		logger.Error("Error getting trends by type: %v", result.Error)
-		return nil, result.Error
-	}
-	trends["trends_by_type"] = typesTrends
-	
-	logger.Info("Spending trends calculated successfully for user %s", userID)
-	return trends, nil
-}
-
 // GetExpenseAnalyticsForML gets data formatted for ML analysis
-func GetExpenseAnalyticsForML(userID string, months int) (map[string]interface{}, error) {
-	var analytics map[string]interface{}
-	analytics = make(map[string]interface{})
-	
-	endDate := time.Now()
+func (s *Services) GetExpenseAnalyticsForML(userID string, months int) (*dto.ExpenseAnalytics, error) {
+	endDate := s.UserToday(userID)
 	startDate := endDate.AddDate(0, -months, 0)
 	
 	// Obtener todos los gastos del período para análisis detallado
-	expenses, err := GetExpensesByDateRange(userID, startDate, endDate, false)
+	expenses, err := s.Expenses.GetByDateRange(userID, startDate, endDate, false)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Preparar datos para ML
-	var mlData []map[string]interface{}
+	mlData := make([]dto.ExpenseAnalyticsRecord, 0, len(expenses))
 	for _, expense := range expenses {
-		mlData = append(mlData, map[string]interface{}{
-			"amount":            expense.Amount,
-			"date":              expense.Date,
-			"day_of_week":       int(expense.Date.Weekday()),
-			"month":             int(expense.Date.Month()),
-			"category_name":     expense.Category.Name,
-			"expense_type_name": models.GetExpenseTypeName(expense.Category.ExpenseType),
-			"description":       expense.Description,
+		mlData = append(mlData, dto.ExpenseAnalyticsRecord{
+			Amount:          expense.Amount,
+			Date:            expense.Date,
+			DayOfWeek:       int(expense.Date.Weekday()),
+			Month:           int(expense.Date.Month()),
+			CategoryName:    expense.Category.Name,
+			ExpenseTypeName: models.GetExpenseTypeName(expense.Category.ExpenseType),
+			Description:     expense.Description,
 		})
 	}
 	
-	analytics["raw_data"] = mlData
-	analytics["total_records"] = len(mlData)
-	analytics["period_start"] = startDate
-	analytics["period_end"] = endDate
-	
-	// Estadísticas agregadas para features
-	analytics["features"] = map[string]interface{}{
-		"avg_daily_spending":   calculateAverageDaily(expenses),
-		"spending_volatility":  calculateSpendingVolatility(expenses),
-		"most_active_day":      getMostActiveDay(expenses),
-		"category_diversity":   getCategoryDiversity(expenses),
-		"largest_expense":      getLargestExpense(expenses),
-		"typical_expense_size": getTypicalExpenseSize(expenses),
+	analytics := &dto.ExpenseAnalytics{
+		RawData:      mlData,
+		TotalRecords: len(mlData),
+		PeriodStart:  startDate,
+		PeriodEnd:    endDate,
+		// Estadísticas agregadas para features
+		Features: dto.ExpenseAnalyticsFeatures{
+			AvgDailySpending:   calculateAverageDaily(expenses),
+			SpendingVolatility: calculateSpendingVolatility(expenses),
+			MostActiveDay:      getMostActiveDay(expenses),
+			CategoryDiversity:  getCategoryDiversity(expenses),
+			LargestExpense:     getLargestExpense(expenses),
+			TypicalExpenseSize: getTypicalExpenseSize(expenses),
+		},
 	}
 	
 	logger.Info("ML analytics prepared successfully for user %s", userID)
@@ -719,7 +714,7 @@ func calculateAverageDaily(expenses []models.Expense) float64 {
 		return 0
 	}
 	
-	total := 0.0
+	var total models.Money
 	for _, expense := range expenses {
 		total += expense.Amount
 	}
@@ -734,7 +729,7 @@ func calculateAverageDaily(expenses []models.Expense) float64 {
 		return 0
 	}
 	
-	return total / float64(len(days))
+	return total.Float64() / float64(len(days))
 }
 
 func calculateSpendingVolatility(expenses []models.Expense) float64 {
@@ -743,15 +738,15 @@ func calculateSpendingVolatility(expenses []models.Expense) float64 {
 	}
 	
 	// Calculate the mean
-	total := 0.0
+	var total models.Money
 	for _, expense := range expenses {
 		total += expense.Amount
 	}
-	mean := total / float64(len(expenses))
+	mean := total.Float64() / float64(len(expenses))
 	
 	variance := 0.0
 	for _, expense := range expenses {
-		variance += (expense.Amount - mean) * (expense.Amount - mean)
+		variance += (expense.Amount.Float64() - mean) * (expense.Amount.Float64() - mean)
 	}
 	variance /= float64(len(expenses))
 	
@@ -784,8 +779,8 @@ func getCategoryDiversity(expenses []models.Expense) int {
 	return len(categories)
 }
 
-func getLargestExpense(expenses []models.Expense) float64 {
-	largest := 0.0
+func getLargestExpense(expenses []models.Expense) models.Money {
+	var largest models.Money
 	for _, expense := range expenses {
 		if expense.Amount > largest {
 			largest = expense.Amount
@@ -802,7 +797,7 @@ func getTypicalExpenseSize(expenses []models.Expense) float64 {
 	// Calculate median as a measure of "typical"
 	amounts := make([]float64, len(expenses))
 	for i, expense := range expenses {
-		amounts[i] = expense.Amount
+		amounts[i] = expense.Amount.Float64()
 	}
 	
 	// Sort to find median (simple implementation)
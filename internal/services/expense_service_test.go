@@ -0,0 +1,149 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func accountBalance(t *testing.T, h *testutil.Harness, account *models.BankAccount) models.Money {
+	t.Helper()
+	var current models.BankAccount
+	if err := h.DB.First(&current, "id = ?", account.ID).Error; err != nil {
+		t.Fatalf("loading bank account: %v", err)
+	}
+	return current.Balance
+}
+
+func TestExpenseCRUD(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(1000))
+	category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+	userID := user.ID.String()
+
+	expense := &models.Expense{
+		CategoryID:    category.ID,
+		BankAccountID: account.ID,
+		Amount:        models.NewMoney(250),
+		Date:          time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if err := h.Expenses.Create(context.Background(), userID, expense, false); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+	if balance := accountBalance(t, h, account); balance != models.NewMoney(750) {
+		t.Fatalf("balance after create = %s, want 750.00", balance)
+	}
+
+	got, err := h.Expenses.GetByID(userID, expense.ID.String())
+	if err != nil {
+		t.Fatalf("getting expense: %v", err)
+	}
+	if got.Amount != expense.Amount || got.CategoryID != category.ID || got.Status != models.StatusActive {
+		t.Fatalf("got expense %+v, want the one created", got)
+	}
+
+	description := "Weekly groceries"
+	got.Amount = models.NewMoney(300)
+	got.Description = &description
+	patched, err := h.Expenses.Patch(userID, expense.ID.String(), got)
+	if err != nil {
+		t.Fatalf("patching expense: %v", err)
+	}
+	if patched.Amount != models.NewMoney(300) || patched.Description == nil || *patched.Description != description {
+		t.Fatalf("patched expense %+v, want amount 300.00 and the new description", patched)
+	}
+	if balance := accountBalance(t, h, account); balance != models.NewMoney(700) {
+		t.Fatalf("balance after patch = %s, want 700.00", balance)
+	}
+
+	if err := h.Expenses.SoftDelete(userID, expense.ID.String()); err != nil {
+		t.Fatalf("deleting expense: %v", err)
+	}
+	if _, err := h.Expenses.GetByID(userID, expense.ID.String()); err == nil {
+		t.Fatal("deleted expense is still returned")
+	}
+	if balance := accountBalance(t, h, account); balance != models.NewMoney(1000) {
+		t.Fatalf("balance after delete = %s, want 1000.00", balance)
+	}
+
+	restored, err := h.Expenses.Restore(userID, expense.ID.String())
+	if err != nil {
+		t.Fatalf("restoring expense: %v", err)
+	}
+	if restored.Status != models.StatusActive {
+		t.Fatalf("restored expense status = %s, want active", restored.Status)
+	}
+	if balance := accountBalance(t, h, account); balance != models.NewMoney(700) {
+		t.Fatalf("balance after restore = %s, want 700.00", balance)
+	}
+}
+
+func TestExpenseOfAnotherUserIsNotVisible(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	owner := h.CreateUser(t)
+	other := h.CreateUser(t)
+	account := h.CreateBankAccount(t, owner, models.NewMoney(100))
+	category := h.CreateCategory(t, owner, "Transport", models.ExpenseTypeNeeds)
+
+	expense := &models.Expense{
+		CategoryID:    category.ID,
+		BankAccountID: account.ID,
+		Amount:        models.NewMoney(20),
+		Date:          time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if err := h.Expenses.Create(context.Background(), owner.ID.String(), expense, false); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+
+	if _, err := h.Expenses.GetByID(other.ID.String(), expense.ID.String()); err == nil {
+		t.Fatal("another user can read the expense")
+	}
+	if err := h.Expenses.SoftDelete(other.ID.String(), expense.ID.String()); err == nil {
+		t.Fatal("another user can delete the expense")
+	}
+}
+
+// TestKWDExpenseKeepsItsThirdDecimal stores an expense of a user paying in Kuwaiti dinars: the
+// amount, the balance it moves and its currency must come back exactly
+func TestKWDExpenseKeepsItsThirdDecimal(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	if err := h.DB.Model(user).Update("currency", "KWD").Error; err != nil {
+		t.Fatalf("setting the user's currency: %v", err)
+	}
+	account := h.CreateBankAccount(t, user, models.NewMoney(100))
+	category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+
+	amount, err := models.ParseMoney("12.345")
+	if err != nil {
+		t.Fatalf("parsing the amount: %v", err)
+	}
+	expense := &models.Expense{
+		CategoryID:    category.ID,
+		BankAccountID: account.ID,
+		Amount:        amount,
+		Date:          time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if err := h.Expenses.Create(context.Background(), user.ID.String(), expense, false); err != nil {
+		t.Fatalf("creating expense: %v", err)
+	}
+
+	// A later change of the user's currency leaves the stored amount in dinars
+	if err := h.DB.Model(user).Update("currency", "USD").Error; err != nil {
+		t.Fatalf("changing the user's currency: %v", err)
+	}
+	var stored models.Expense
+	if err := h.DB.First(&stored, "id = ?", expense.ID).Error; err != nil {
+		t.Fatalf("loading expense: %v", err)
+	}
+	if stored.Amount.String() != "12.345" || stored.Currency != "KWD" {
+		t.Fatalf("stored expense = %s %s, want 12.345 KWD", stored.Amount, stored.Currency)
+	}
+	if balance := accountBalance(t, h, account); balance.String() != "87.655" {
+		t.Fatalf("balance = %s, want 87.655", balance)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 )
@@ -15,11 +16,11 @@ func InitializeExpenseSystem() error {
 }
 
 // SetupNewUser creates default categories for a new user
-func SetupNewUser(userID string) error {
+func (s *Services) SetupNewUser(userID string) error {
 	logger.Info("Setting up default categories for new user: %s", userID)
 	
 	// Creates the default categories for the user
-	if err := CreateDefaultUserCategories(userID); err != nil {
+	if err := s.CreateDefaultUserCategories(userID); err != nil {
 		logger.Error("Error creating default categories for user %s: %v", userID, err)
 		return err
 	}
@@ -29,29 +30,28 @@ func SetupNewUser(userID string) error {
 }
 
 // GetSystemOverview gets an overview of the expense system setup
-func GetSystemOverview() (map[string]interface{}, error) {
-	overview := make(map[string]interface{})
-	
+func GetSystemOverview() (*dto.SystemOverview, error) {
 	// Expense types are now fixed enums
 	expenseTypes := models.ValidExpenseTypes()
-	overview["expense_types_count"] = len(expenseTypes)
+	overview := &dto.SystemOverview{
+		ExpenseTypesCount: len(expenseTypes),
+		ExpenseTypes:      make([]dto.ExpenseTypeInfo, 0, len(expenseTypes)),
+	}
 	
 	// Build expense types info
-	var expenseTypesInfo []map[string]string
 	for _, et := range expenseTypes {
-		expenseTypesInfo = append(expenseTypesInfo, map[string]string{
-			"value": string(et),
-			"name":  models.GetExpenseTypeName(et),
+		overview.ExpenseTypes = append(overview.ExpenseTypes, dto.ExpenseTypeInfo{
+			Value: string(et),
+			Name:  models.GetExpenseTypeName(et),
 		})
 	}
-	overview["expense_types"] = expenseTypesInfo
 	
 	// System info
-	overview["system_info"] = map[string]interface{}{
-		"architecture":   "user_specific_categories",
-		"expense_types":  []string{"Needs (50%)", "Wants (30%)", "Savings (20%)"},
-		"note":           "ExpenseTypes are now fixed enums. Categories are created per user.",
-		"valid_types":    []string{"needs", "wants", "savings"},
+	overview.SystemInfo = dto.SystemInfo{
+		Architecture: "user_specific_categories",
+		ExpenseTypes: []string{"Needs (50%)", "Wants (30%)", "Savings (20%)"},
+		Note:         "ExpenseTypes are now fixed enums. Categories are created per user.",
+		ValidTypes:   []string{"needs", "wants", "savings"},
 	}
 	
 	logger.Info("System overview generated successfully")
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// SummaryGroupBy is the dimension used to group the top entries of an expense summary
+type SummaryGroupBy string
+
+const (
+	SummaryGroupByCategory SummaryGroupBy = "category"
+	SummaryGroupByAccount  SummaryGroupBy = "account"
+	SummaryGroupByPayee    SummaryGroupBy = "payee"
+	SummaryGroupByTag      SummaryGroupBy = "tag"
+)
+
+// summaryGrouping tells the summary query how to join, select and group one dimension
+type summaryGrouping struct {
+	joins   []string
+	key     string // SQL expression identifying the group
+	name    string // SQL expression with the display name of the group
+	extra   string // Optional extra select (must alias expense_type_name)
+	groupBy string
+	share   string // Optional SQL factor attributing part of each expense to the group
+}
+
+// expenseTypeNameSQL maps the category expense type to its display name
+const expenseTypeNameSQL = `(CASE
+			WHEN c.expense_type = 'needs' THEN 'Needs'
+			WHEN c.expense_type = 'wants' THEN 'Wants'
+			WHEN c.expense_type = 'savings' THEN 'Savings'
+			ELSE c.expense_type::text
+		END)::text`
+
+// allocationShareSQL is the share of an expense (aliased as "e") paid by its allocation "ea",
+// or all of it when it isn't split
+const allocationShareSQL = "COALESCE(ea.amount / NULLIF(e.amount, 0), 1)"
+
+// summaryGroupings are the dimensions the summary can be grouped by
+var summaryGroupings = map[SummaryGroupBy]summaryGrouping{
+	SummaryGroupByCategory: {
+		joins:   []string{"JOIN categories c ON e.category_id = c.id"},
+		key:     "c.id::text",
+		name:    "c.name",
+		extra:   expenseTypeNameSQL + " as expense_type_name",
+		groupBy: "c.id, c.name, c.expense_type",
+	},
+	// Split expenses count in every account they were paid from, for the allocated share
+	SummaryGroupByAccount: {
+		joins: []string{
+			"LEFT JOIN expense_allocations ea ON ea.expense_id = e.id",
+			"JOIN bank_accounts ba ON COALESCE(ea.bank_account_id, e.bank_account_id) = ba.id",
+		},
+		key:     "ba.id::text",
+		name:    "ba.account_name",
+		groupBy: "ba.id, ba.account_name",
+		share:   allocationShareSQL,
+	},
+	// Expenses have no payee field; the normalized description is the closest thing
+	SummaryGroupByPayee: {
+		key:     "LOWER(TRIM(COALESCE(e.description, '')))",
+		name:    "COALESCE(MIN(TRIM(e.description)), '')",
+		groupBy: "LOWER(TRIM(COALESCE(e.description, '')))",
+	},
+	// Expenses count in full in every tag they carry, so the groups can add up to more than the
+	// total; untagged expenses are left out
+	SummaryGroupByTag: {
+		joins: []string{
+			"JOIN expense_tags et ON et.expense_id = e.id",
+			"JOIN tags t ON t.id = et.tag_id",
+		},
+		key:     "t.id::text",
+		name:    "t.name",
+		groupBy: "t.id, t.name",
+	},
+}
+
+// IsValidSummaryGroupBy checks if the summary can be grouped by the given dimension
+func IsValidSummaryGroupBy(groupBy string) bool {
+	_, ok := summaryGroupings[SummaryGroupBy(groupBy)]
+	return ok
+}
+
+// ExpenseSummaryOptions configures GetExpensesSummaryByPeriod
+type ExpenseSummaryOptions struct {
+	TopN          int
+	GroupBy       SummaryGroupBy
+	IncludeCounts bool
+}
+
+// DefaultExpenseSummaryOptions returns the options used when none are given
+func DefaultExpenseSummaryOptions() ExpenseSummaryOptions {
+	return ExpenseSummaryOptions{TopN: 10, GroupBy: SummaryGroupByCategory, IncludeCounts: true}
+}
+
+// summaryPeriodQuery selects the active expenses of the user in the period with their refunds
+func (s *Services) summaryPeriodQuery(userID string, startDate, endDate time.Time) *gorm.DB {
+	return s.db.Table("expenses e").Scopes(joinExpenseRefunds).
+		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?",
+			userID, startDate, endDate, models.GetActiveStatuses())
+}
+
+// GetExpensesSummaryByPeriod gets expense summary for a period, from the summary cache when the
+// user's data hasn't changed since it was computed
+func (s *Services) GetExpensesSummaryByPeriod(userID string, startDate, endDate time.Time, opts ExpenseSummaryOptions) (*dto.ExpenseSummary, error) {
+	key := fmt.Sprintf("expense-summary:%s:%s:%s:%d:%t", startDate.Format(time.RFC3339), endDate.Format(time.RFC3339),
+		opts.GroupBy, opts.TopN, opts.IncludeCounts)
+	return cachedSummary(context.Background(), userID, key, func() (*dto.ExpenseSummary, error) {
+		return s.buildExpensesSummary(userID, startDate, endDate, opts)
+	})
+}
+
+// buildExpensesSummary computes the expense summary of a period
+func (s *Services) buildExpensesSummary(userID string, startDate, endDate time.Time, opts ExpenseSummaryOptions) (*dto.ExpenseSummary, error) {
+	grouping, ok := summaryGroupings[opts.GroupBy]
+	if !ok {
+		return nil, errors.New("unsupported group_by: " + string(opts.GroupBy))
+	}
+	if opts.TopN <= 0 {
+		return nil, errors.New("top_n must be positive")
+	}
+
+	summary := &dto.ExpenseSummary{GroupBy: string(opts.GroupBy)}
+
+	// Total gastado en el período (neto de reembolsos) y número de gastos
+	var totals struct {
+		TotalAmount models.Money
+		TotalCount  int64
+	}
+	result := s.summaryPeriodQuery(userID, startDate, endDate).
+		Select("COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) as total_amount, COUNT(e.id) as total_count").
+		Scan(&totals)
+	if result.Error != nil {
+		logger.Error("Error calculating total expenses: %v", result.Error)
+		return nil, result.Error
+	}
+	summary.TotalAmount = totals.TotalAmount
+	summary.TotalCount = totals.TotalCount
+
+	// Promedio por gasto
+	if summary.TotalCount > 0 {
+		summary.AverageAmount = s.GetUserCurrency(userID).RoundMoney(summary.TotalAmount.MulRatio(1 / float64(summary.TotalCount)))
+	}
+
+	// Gastos por ExpenseType (50/30/20)
+	result = s.summaryPeriodQuery(userID, startDate, endDate).
+		Select(expenseTypeNameSQL + ` as expense_type_name,
+		COALESCE(SUM(` + netExpenseAmountSQL() + `), 0) as total_amount,
+		COUNT(e.id) as count`).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Group("c.expense_type").
+		Order("total_amount DESC").
+		Scan(&summary.ByExpenseType)
+	if result.Error != nil {
+		logger.Error("Error getting expenses by type: %v", result.Error)
+		return nil, result.Error
+	}
+
+	// Top N grupos
+	amount := netExpenseAmountSQL()
+	if grouping.share != "" {
+		amount += " * " + grouping.share
+	}
+	selects := []string{
+		grouping.key + " as key",
+		grouping.name + " as name",
+		"COALESCE(SUM(" + amount + "), 0) as total_amount",
+		"COUNT(e.id) as count",
+	}
+	if grouping.extra != "" {
+		selects = append(selects, grouping.extra)
+	}
+
+	query := s.summaryPeriodQuery(userID, startDate, endDate).Select(strings.Join(selects, ", "))
+	for _, join := range grouping.joins {
+		query = query.Joins(join)
+	}
+	result = query.Group(grouping.groupBy).
+		Order("total_amount DESC").
+		Limit(opts.TopN).
+		Scan(&summary.TopGroups)
+	if result.Error != nil {
+		logger.Error("Error getting top %s groups: %v", opts.GroupBy, result.Error)
+		return nil, result.Error
+	}
+
+	logger.Info("Expense summary calculated successfully for user %s", userID)
+	return summary, nil
+}
+
+// GetMonthlyExpensesSummary gets monthly expenses summary for the user, over their month period
+func (s *Services) GetMonthlyExpensesSummary(userID string, year int, month int) (*dto.ExpenseSummary, error) {
+	startDate, endDate := s.getUserCalendar(userID).monthPeriod(year, time.Month(month))
+
+	return s.GetExpensesSummaryByPeriod(userID, startDate, endDate, DefaultExpenseSummaryOptions())
+}
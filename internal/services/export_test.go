@@ -0,0 +1,32 @@
+package services
+
+import (
+	"github.com/Osminalx/fluxio/internal/auth/oidc"
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// RateLimitStore gives the tests of services_test the rate limit stores
+type RateLimitStore = rateLimitStore
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func NewRedisRateLimitStore(rawURL string) (RateLimitStore, error) {
+	return newRedisRateLimitStore(rawURL)
+}
+
+// SummaryCacheStore gives the tests of services_test the summary cache stores
+type SummaryCacheStore = summaryCacheStore
+
+func NewRedisSummaryCache(rawURL string) (SummaryCacheStore, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisSummaryCache{client: client}, nil
+}
+
+func (s *Services) LinkOAuthIdentity(providerName string, identity *oidc.Identity) (*models.User, bool, error) {
+	return s.linkOAuthIdentity(providerName, identity)
+}
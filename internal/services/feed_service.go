@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultFeedLimit is the page size of the feed when the client doesn't ask for one
+const DefaultFeedLimit = 50
+
+// feedCursorPrefix versions the feed cursor. Unlike list cursors it holds the sort key of the
+// last item, so rows added while the user scrolls don't shift the following pages
+const feedCursorPrefix = "k1:"
+
+// feedSources are the statements the feed merges, by item type. Each one selects the same
+// columns so they can be combined with UNION ALL
+var feedSources = map[string]string{
+	dto.FeedTypeExpense: `SELECT 'expense'::text AS type, e.id, e.amount, e.date, e.created_at, e.description,
+		e.bank_account_id AS account_id, a.account_name AS account_name, e.category_id, c.name AS category_name,
+		NULL::uuid AS to_account_id, NULL::text AS to_account_name, NULL::uuid AS refund_of_expense_id
+		FROM expenses e
+		LEFT JOIN bank_accounts a ON a.id = e.bank_account_id
+		LEFT JOIN categories c ON c.id = e.category_id
+		WHERE e.user_id = @user AND e.status = @status`,
+	dto.FeedTypeIncome: `SELECT 'income'::text AS type, i.id, i.amount, i.date, i.created_at, i.description,
+		i.bank_account_id AS account_id, a.account_name AS account_name, NULL::uuid AS category_id, NULL::text AS category_name,
+		NULL::uuid AS to_account_id, NULL::text AS to_account_name, i.refund_of_expense_id
+		FROM incomes i
+		LEFT JOIN bank_accounts a ON a.id = i.bank_account_id
+		WHERE i.user_id = @user AND i.status = @status`,
+	dto.FeedTypeTransfer: `SELECT 'transfer'::text AS type, t.id, t.amount, t.date, t.created_at, t.description,
+		t.from_account_id AS account_id, f.account_name AS account_name, NULL::uuid AS category_id, NULL::text AS category_name,
+		t.to_account_id, d.account_name AS to_account_name, NULL::uuid AS refund_of_expense_id
+		FROM transfers t
+		LEFT JOIN bank_accounts f ON f.id = t.from_account_id
+		LEFT JOIN bank_accounts d ON d.id = t.to_account_id
+		WHERE t.user_id = @user AND t.status = @status`,
+}
+
+// feedTypes is the order item types are merged in
+var feedTypes = []string{dto.FeedTypeExpense, dto.FeedTypeIncome, dto.FeedTypeTransfer}
+
+// FeedRequest selects a page of the transaction feed
+type FeedRequest struct {
+	Limit  int      // Zero uses DefaultFeedLimit
+	Cursor string   // Opaque next_cursor of the previous page
+	Types  []string // Item types to include; empty includes all of them
+}
+
+// feedKey is the sort key of a feed item: date, then creation time, then type and id to
+// break ties
+type feedKey struct {
+	Date      string
+	CreatedAt time.Time
+	Type      string
+	ID        uuid.UUID
+}
+
+type feedRow struct {
+	Type              string
+	ID                uuid.UUID
+	Amount            models.Money
+	Date              time.Time
+	CreatedAt         time.Time
+	Description       *string
+	AccountID         *uuid.UUID
+	AccountName       *string
+	CategoryID        *uuid.UUID
+	CategoryName      *string
+	ToAccountID       *uuid.UUID
+	ToAccountName     *string
+	RefundOfExpenseID *uuid.UUID
+}
+
+func encodeFeedCursor(key feedKey) string {
+	raw := strings.Join([]string{key.Date, key.CreatedAt.UTC().Format(time.RFC3339Nano), key.Type, key.ID.String()}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(feedCursorPrefix + raw))
+}
+
+func decodeFeedCursor(cursor string) (feedKey, error) {
+	invalid := errors.New("invalid cursor")
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), feedCursorPrefix) {
+		return feedKey{}, invalid
+	}
+	parts := strings.Split(strings.TrimPrefix(string(decoded), feedCursorPrefix), "|")
+	if len(parts) != 4 {
+		return feedKey{}, invalid
+	}
+
+	var key feedKey
+	if _, err := time.Parse("2006-01-02", parts[0]); err != nil {
+		return feedKey{}, invalid
+	}
+	key.Date = parts[0]
+	if key.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		return feedKey{}, invalid
+	}
+	if _, ok := feedSources[parts[2]]; !ok {
+		return feedKey{}, invalid
+	}
+	key.Type = parts[2]
+	if key.ID, err = uuid.Parse(parts[3]); err != nil {
+		return feedKey{}, invalid
+	}
+	return key, nil
+}
+
+// Validate checks the feed request
+func (req *FeedRequest) Validate() error {
+	if req.Limit < 0 || req.Limit > MaxPageLimit {
+		return errors.New("invalid limit: must be between 1 and " + strconv.Itoa(MaxPageLimit))
+	}
+	for _, itemType := range req.Types {
+		if _, ok := feedSources[itemType]; !ok {
+			return errors.New("invalid type: must be expense, income or transfer")
+		}
+	}
+	if req.Cursor != "" {
+		if _, err := decodeFeedCursor(req.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFeed returns the user's active expenses, incomes and transfers merged newest first.
+// Pages are keyed on the last item returned, so paging stays stable while new records arrive
+func (s *Services) GetFeed(userID string, req FeedRequest) (*dto.FeedPage, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = DefaultFeedLimit
+	}
+
+	wanted := make(map[string]bool, len(req.Types))
+	for _, itemType := range req.Types {
+		wanted[itemType] = true
+	}
+	var sources []string
+	for _, itemType := range feedTypes {
+		if len(wanted) == 0 || wanted[itemType] {
+			sources = append(sources, feedSources[itemType])
+		}
+	}
+
+	args := map[string]interface{}{"user": userID, "status": models.StatusActive, "limit": limit + 1}
+	query := "SELECT * FROM (" + strings.Join(sources, " UNION ALL ") + ") feed"
+	if req.Cursor != "" {
+		key, _ := decodeFeedCursor(req.Cursor)
+		query += " WHERE (feed.date, feed.created_at, feed.type, feed.id) < (CAST(@date AS date), @created_at, @type, @id)"
+		args["date"] = key.Date
+		args["created_at"] = key.CreatedAt
+		args["type"] = key.Type
+		args["id"] = key.ID
+	}
+	query += " ORDER BY feed.date DESC, feed.created_at DESC, feed.type DESC, feed.id DESC LIMIT @limit"
+
+	var rows []feedRow
+	if err := s.db.Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &dto.FeedPage{Items: make([]dto.FeedItem, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next := encodeFeedCursor(feedKey{Date: last.Date.Format("2006-01-02"), CreatedAt: last.CreatedAt, Type: last.Type, ID: last.ID})
+		page.NextCursor = &next
+	}
+	for _, row := range rows {
+		item := dto.FeedItem{
+			Type:        row.Type,
+			ID:          row.ID.String(),
+			Amount:      row.Amount,
+			Date:        row.Date.Format("2006-01-02"),
+			Description: row.Description,
+			Account:     feedRef(row.AccountID, row.AccountName),
+			Category:    feedRef(row.CategoryID, row.CategoryName),
+			ToAccount:   feedRef(row.ToAccountID, row.ToAccountName),
+		}
+		if row.RefundOfExpenseID != nil {
+			refundOf := row.RefundOfExpenseID.String()
+			item.RefundOf = &refundOf
+		}
+		page.Items = append(page.Items, item)
+	}
+	page.Count = len(page.Items)
+	return page, nil
+}
+
+func feedRef(id *uuid.UUID, name *string) *dto.FeedRef {
+	if id == nil {
+		return nil
+	}
+	ref := &dto.FeedRef{ID: id.String()}
+	if name != nil {
+		ref.Name = *name
+	}
+	return ref
+}
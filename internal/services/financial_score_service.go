@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// ScoreVolatilityMonths is how many months, the scored one included, budget volatility looks at
+const ScoreVolatilityMonths = 6
+
+// linearScore maps value to 0-100: 100 at best or beyond, 0 at worst or beyond. best can be
+// below worst when lower values are better
+func linearScore(value, best, worst float64) float64 {
+	t := (value - worst) / (best - worst)
+	return math.Round(math.Max(0, math.Min(1, t))*10000) / 100
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// budgetVolatility returns the coefficient of variation, in percent, of the budget totals of
+// the months up to month, each weighted over its revisions. ok is false with fewer than two
+// budgeted months
+func (s *Services) budgetVolatility(ctx context.Context, userID string, month time.Time) (float64, bool, error) {
+	var budgets []models.Budget
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND month_year BETWEEN ? AND ? AND status IN ?",
+			userID, month.AddDate(0, -(ScoreVolatilityMonths-1), 0), month, models.GetVisibleStatuses()).
+		Find(&budgets).Error; err != nil {
+		return 0, false, err
+	}
+	if len(budgets) < 2 {
+		return 0, false, nil
+	}
+
+	ids := make([]uuid.UUID, len(budgets))
+	for i, budget := range budgets {
+		ids[i] = budget.ID
+	}
+	var revisions []models.BudgetRevision
+	if err := s.db.WithContext(ctx).Where("budget_id IN ?", ids).Order("effective_from ASC").Find(&revisions).Error; err != nil {
+		return 0, false, err
+	}
+	byBudget := make(map[uuid.UUID][]models.BudgetRevision, len(budgets))
+	for _, revision := range revisions {
+		byBudget[revision.BudgetID] = append(byBudget[revision.BudgetID], revision)
+	}
+
+	totals := make([]float64, len(budgets))
+	var mean float64
+	for i, budget := range budgets {
+		totals[i] = effectiveBudget(budget, byBudget[budget.ID]).Total().Float64()
+		mean += totals[i]
+	}
+	mean /= float64(len(totals))
+	if mean <= 0 {
+		return 0, false, nil
+	}
+	var variance float64
+	for _, total := range totals {
+		variance += (total - mean) * (total - mean)
+	}
+	variance /= float64(len(totals))
+	return math.Sqrt(variance) / mean * 100, true, nil
+}
+
+// GetFinancialScore rates the financial health of a month from 0 to 100, weighing its savings
+// rate, how close spending came to 50/30/20, how much of the income fixed expenses take and how
+// much the budget moved over the last months. Components without data are left out and the
+// others weighed up to fill their share. Scores are served from the summary cache until the
+// user's data changes
+func (s *Services) GetFinancialScore(ctx context.Context, userID string, month time.Time) (*dto.FinancialScore, error) {
+	key := "score:" + models.MonthStart(month).Format("2006-01")
+	return cachedSummary(ctx, userID, key, func() (*dto.FinancialScore, error) {
+		return s.buildFinancialScore(ctx, userID, month)
+	})
+}
+
+// buildFinancialScore computes the financial score of a month
+func (s *Services) buildFinancialScore(ctx context.Context, userID string, month time.Time) (*dto.FinancialScore, error) {
+	start := models.MonthStart(month)
+	end := start.AddDate(0, 1, -1)
+
+	var income models.Money
+	query := s.db.WithContext(ctx).Model(&models.Income{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, start, end, models.GetActiveStatuses())
+	if refundsNettedInOriginalMonth() {
+		query = query.Where("refund_of_expense_id IS NULL")
+	}
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&income).Error; err != nil {
+		logger.Error("Error getting income for financial score: %v", err)
+		return nil, errors.New("error calculating financial score")
+	}
+	spent, err := s.GetExpensesByExpenseType(userID, start, end)
+	if err != nil {
+		return nil, errors.New("error calculating financial score")
+	}
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, start.Year(), start.Month())
+	if err != nil {
+		return nil, errors.New("error calculating financial score")
+	}
+	volatility, hasVolatility, err := s.budgetVolatility(ctx, userID, start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Error("Error getting budget volatility: %v", err)
+		return nil, errors.New("error calculating financial score")
+	}
+
+	currency := s.GetUserCurrency(userID)
+	incomeSources, err := s.incomeBySource(ctx, userID, start, end, !refundsNettedInOriginalMonth(), currency)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Error("Error getting income by source for financial score: %v", err)
+		return nil, errors.New("error calculating financial score")
+	}
+	score := &dto.FinancialScore{
+		Month:    start.Format("2006-01"),
+		Currency: currency.Code,
+		Income:   currency.RoundMoney(income),
+		Needs:    currency.RoundMoney(spent["Needs"]),
+		Wants:    currency.RoundMoney(spent["Wants"]),
+		Savings:  currency.RoundMoney(spent["Savings"]),
+	}
+	score.IncomeSources = incomeSources
+	score.Saved = score.Income - score.Needs - score.Wants
+	for _, fixedExpense := range fixedExpenses {
+		score.FixedExpenses += fixedExpense.Amount
+	}
+	score.FixedExpenses = currency.RoundMoney(score.FixedExpenses)
+
+	type component struct {
+		dto.FinancialScoreComponent
+		weight float64
+	}
+	components := []component{
+		{dto.FinancialScoreComponent{Key: "savings_rate", Name: "Savings rate", Target: ">= 20% of income"}, 35},
+		{dto.FinancialScoreComponent{Key: "rule_50_30_20", Name: "50/30/20 adherence", Target: "needs <= 50%, wants <= 30%, saved >= 20% of income"}, 30},
+		{dto.FinancialScoreComponent{Key: "fixed_expense_load", Name: "Fixed expense load", Target: "<= 35% of income"}, 20},
+		{dto.FinancialScoreComponent{Key: "budget_volatility", Name: "Budget volatility", Target: "Steady budget totals over the last 6 months"}, 15},
+	}
+	set := func(i int, value, componentScore float64) {
+		value = roundPercent(value)
+		components[i].Value = &value
+		components[i].Score = &componentScore
+	}
+	if score.Income > 0 {
+		savedPercent := score.Saved.Ratio(score.Income) * 100
+		set(0, savedPercent, linearScore(savedPercent, 20, 0))
+
+		// Percentage points past the targets, each line on its own
+		needsPercent := score.Needs.Ratio(score.Income) * 100
+		wantsPercent := score.Wants.Ratio(score.Income) * 100
+		deviation := math.Max(0, needsPercent-50) + math.Max(0, wantsPercent-30) + math.Max(0, 20-savedPercent)
+		set(1, deviation, linearScore(deviation, 0, 50))
+
+		loadPercent := score.FixedExpenses.Ratio(score.Income) * 100
+		set(2, loadPercent, linearScore(loadPercent, 35, 70))
+	}
+	if hasVolatility {
+		set(3, volatility, linearScore(volatility, 0, 50))
+	}
+
+	var totalWeight, weighted float64
+	for _, c := range components {
+		if c.Score != nil {
+			totalWeight += c.weight
+			weighted += *c.Score * c.weight
+		}
+	}
+	score.Components = make([]dto.FinancialScoreComponent, 0, len(components))
+	for _, c := range components {
+		if c.Score != nil {
+			c.Weight = roundPercent(c.weight / totalWeight * 100)
+		}
+		score.Components = append(score.Components, c.FinancialScoreComponent)
+	}
+	if totalWeight > 0 {
+		overall := roundPercent(weighted / totalWeight)
+		score.Score = &overall
+		switch {
+		case overall >= 80:
+			score.Rating = "excellent"
+		case overall >= 60:
+			score.Rating = "good"
+		case overall >= 40:
+			score.Rating = "fair"
+		default:
+			score.Rating = "poor"
+		}
+	}
+
+	return score, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventFixedExpenseDrift is emitted when a fixed expense starts drifting to a new amount
+const EventFixedExpenseDrift = "fixed_expense.drift_detected"
+
+const (
+	// fixedExpenseDriftPeriods is how many months in a row the payments must differ
+	fixedExpenseDriftPeriods = 3
+	// fixedExpenseDriftLookbackMonths covers the periods plus the current month, which may not be due yet
+	fixedExpenseDriftLookbackMonths = fixedExpenseDriftPeriods + 1
+	// fixedExpenseDriftMinPercent ignores differences too small to matter for forecasts
+	fixedExpenseDriftMinPercent = 2.0
+)
+
+// fixedExpenseDriftTolerance pairs payments much further from the configured amount than the
+// reconciliation does, since a drifted payment is exactly one that no longer matches it
+var fixedExpenseDriftTolerance = ReconciliationTolerance{AmountPercent: 50, Days: 3}
+
+// driftPayment picks the payment of a period that differs from the configured amount. When the
+// fixed expense was processed and the real charge recorded too, the real one is what counts
+func driftPayment(item FixedExpenseReconciliation, currency *models.Currency) *models.Expense {
+	for i := range item.Matches {
+		if currency.RoundMoney(item.Matches[i].Amount) != currency.RoundMoney(item.FixedExpense.Amount) {
+			return &item.Matches[i]
+		}
+	}
+	return nil
+}
+
+func medianAmount(amounts []models.Money) models.Money {
+	sorted := append([]models.Money(nil), amounts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]).MulRatio(0.5)
+	}
+	return sorted[middle]
+}
+
+// DetectFixedExpenseDrifts finds the monthly fixed expenses whose last payments all differed
+// from the configured amount in the same direction and suggests the median paid amount
+func (s *Services) DetectFixedExpenseDrifts(userID string) ([]dto.FixedExpenseDrift, error) {
+	currency := s.GetUserCurrency(userID)
+	today := s.UserToday(userID)
+	currentMonth := models.MonthStart(today)
+
+	var order []uuid.UUID
+	fixedExpenses := make(map[uuid.UUID]models.FixedExpense)
+	periods := make(map[uuid.UUID][]dto.FixedExpenseDriftPeriod)
+	ended := make(map[uuid.UUID]bool) // A month paid at the configured amount ends the streak
+
+	for i := 0; i < fixedExpenseDriftLookbackMonths; i++ {
+		month := currentMonth.AddDate(0, -i, 0)
+		reconciliation, err := s.GetFixedExpenseReconciliation(userID, month.Year(), month.Month(), fixedExpenseDriftTolerance)
+		if err != nil {
+			return nil, errors.New("error detecting fixed expense drift")
+		}
+
+		for _, item := range reconciliation {
+			fixedExpense := item.FixedExpense
+			if fixedExpense.RecurrenceType != "monthly" {
+				continue
+			}
+			if _, seen := fixedExpenses[fixedExpense.ID]; !seen {
+				fixedExpenses[fixedExpense.ID] = fixedExpense
+				order = append(order, fixedExpense.ID)
+			}
+			if ended[fixedExpense.ID] || len(periods[fixedExpense.ID]) >= fixedExpenseDriftPeriods {
+				continue
+			}
+			if len(item.Matches) == 0 && !item.DueDate.AddDate(0, 0, fixedExpenseDriftTolerance.Days).Before(today) {
+				continue // Not due yet
+			}
+
+			payment := driftPayment(item, currency)
+			if payment == nil {
+				ended[fixedExpense.ID] = true
+				continue
+			}
+			periods[fixedExpense.ID] = append(periods[fixedExpense.ID], dto.FixedExpenseDriftPeriod{
+				Month:     month.Format("2006-01"),
+				DueDate:   item.DueDate.Format("2006-01-02"),
+				ExpenseID: payment.ID.String(),
+				Amount:    payment.Amount,
+			})
+		}
+	}
+
+	drifts := []dto.FixedExpenseDrift{}
+	for _, id := range order {
+		fixedExpense := fixedExpenses[id]
+		paid := periods[id]
+		if len(paid) < fixedExpenseDriftPeriods || fixedExpense.Amount <= 0 {
+			continue
+		}
+
+		amounts := make([]models.Money, 0, len(paid))
+		above, below := 0, 0
+		for _, period := range paid {
+			difference := (period.Amount - fixedExpense.Amount).Ratio(fixedExpense.Amount) * 100
+			switch {
+			case difference >= fixedExpenseDriftMinPercent:
+				above++
+			case difference <= -fixedExpenseDriftMinPercent:
+				below++
+			}
+			amounts = append(amounts, period.Amount)
+		}
+		if above != len(paid) && below != len(paid) {
+			continue // Noise around the configured amount, not a drift
+		}
+
+		suggested := currency.RoundMoney(medianAmount(amounts))
+		drifts = append(drifts, dto.FixedExpenseDrift{
+			FixedExpenseID:  id.String(),
+			Name:            fixedExpense.Name,
+			Amount:          fixedExpense.Amount,
+			SuggestedAmount: suggested,
+			DriftPercent:    math.Round((suggested-fixedExpense.Amount).Ratio(fixedExpense.Amount)*10000) / 100,
+			Periods:         paid,
+		})
+	}
+	return drifts, nil
+}
+
+// AcceptFixedExpenseDrift updates a drifting fixed expense to its suggested amount
+func (s *Services) AcceptFixedExpenseDrift(userID string, id string) (*models.FixedExpense, error) {
+	drifts, err := s.DetectFixedExpenseDrifts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, drift := range drifts {
+		if drift.FixedExpenseID != id {
+			continue
+		}
+		if err := s.db.Model(&models.FixedExpense{}).Where("id = ? AND user_id = ?", id, userID).
+			Updates(map[string]interface{}{"amount": drift.SuggestedAmount, "drift_suggestion": nil}).Error; err != nil {
+			logger.Error("Error accepting fixed expense drift: %v", err)
+			return nil, errors.New("error updating fixed expense")
+		}
+		logger.Info("Fixed expense %s updated from %s to %s after drift", id, drift.Amount, drift.SuggestedAmount)
+		return s.GetFixedExpenseByID(userID, id)
+	}
+	return nil, errors.New("fixed expense drift not found")
+}
+
+// notifyFixedExpenseDrifts emits one event per new suggestion. The suggestion is remembered on
+// the fixed expense so the same one isn't sent every day, and forgotten once the drift is gone
+func (s *Services) notifyFixedExpenseDrifts(userID uuid.UUID) error {
+	drifts, err := s.DetectFixedExpenseDrifts(userID.String())
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]dto.FixedExpenseDrift, len(drifts))
+	for _, drift := range drifts {
+		byID[drift.FixedExpenseID] = drift
+	}
+
+	var fixedExpenses []models.FixedExpense
+	if err := s.db.Where("user_id = ? AND status = ?", userID, models.StatusActive).Find(&fixedExpenses).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, fixedExpense := range fixedExpenses {
+			drift, drifting := byID[fixedExpense.ID.String()]
+			if !drifting {
+				if fixedExpense.DriftSuggestion != nil {
+					if err := tx.Model(&fixedExpense).Update("drift_suggestion", nil).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if fixedExpense.DriftSuggestion != nil && *fixedExpense.DriftSuggestion == drift.SuggestedAmount {
+				continue // Already notified
+			}
+
+			if err := tx.Model(&fixedExpense).Update("drift_suggestion", drift.SuggestedAmount).Error; err != nil {
+				return err
+			}
+			if err := EnqueueEvent(tx, userID, EventFixedExpenseDrift, "fixed_expense", fixedExpense.ID, map[string]interface{}{
+				"fixed_expense_id": fixedExpense.ID,
+				"name":             drift.Name,
+				"amount":           drift.Amount,
+				"suggested_amount": drift.SuggestedAmount,
+				"drift_percent":    drift.DriftPercent,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CheckFixedExpenseDrifts looks for drifts of every regular user with monthly fixed expenses;
+// it runs periodically as a job
+func (s *Services) CheckFixedExpenseDrifts() error {
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.FixedExpense{}).Distinct("user_id").
+		Where("status = ? AND is_recurring = ? AND recurrence_type = ?", models.StatusActive, true, "monthly").
+		Where("user_id IN (?)", s.db.Model(&models.User{}).Select("id").Where("status = ? AND is_sandbox = ?", models.StatusActive, false)).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		logger.Error("Error listing users for fixed expense drift: %v", err)
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.notifyFixedExpenseDrifts(userID); err != nil {
+			logger.Error("Error checking fixed expense drift for user %s: %v", userID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFixedExpenseOccurrenceResolved is returned when confirming a skipped occurrence or skipping a paid one
+var ErrFixedExpenseOccurrenceResolved = errors.New("the occurrence was already confirmed or skipped")
+
+// linkFixedExpenseOccurrence records the expense posted for a period as its pending occurrence,
+// using the posting transaction
+func linkFixedExpenseOccurrence(tx *gorm.DB, fixedExpense *models.FixedExpense, period time.Time, expenseID uuid.UUID) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fixed_expense_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expense_id", "amount", "updated_at"}),
+	}).Create(&models.FixedExpenseOccurrence{
+		FixedExpenseID: fixedExpense.ID,
+		UserID:         fixedExpense.UserID,
+		Period:         period,
+		Status:         models.FixedExpenseOccurrencePending,
+		ExpenseID:      &expenseID,
+		Amount:         fixedExpense.Amount,
+	}).Error
+}
+
+// isFixedExpensePeriod reports whether period is the due date of a cycle of the fixed expense
+func isFixedExpensePeriod(fixedExpense *models.FixedExpense, period time.Time) bool {
+	if period.Equal(fixedExpense.NextDueDate) {
+		return true
+	}
+	return fixedExpense.ShouldApplyForMonth(period.Year(), period.Month()) &&
+		fixedExpense.GetDueDateForMonth(period.Year(), period.Month()).Equal(period)
+}
+
+// findFixedExpenseOccurrence returns the occurrence of a period, nil if it has none yet. Periods
+// posted before occurrences were tracked get theirs from the run history
+func (s *Services) findFixedExpenseOccurrence(fixedExpense *models.FixedExpense, period time.Time) (*models.FixedExpenseOccurrence, error) {
+	var occurrence models.FixedExpenseOccurrence
+	err := s.db.Where("fixed_expense_id = ? AND period = ?", fixedExpense.ID, period).Take(&occurrence).Error
+	if err == nil {
+		return &occurrence, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var run models.FixedExpenseRun
+	err = s.db.Where("fixed_expense_id = ? AND period = ? AND status = ? AND expense_id IS NOT NULL",
+		fixedExpense.ID, period, models.FixedExpenseRunPosted).Take(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := linkFixedExpenseOccurrence(s.db, fixedExpense, period, *run.ExpenseID); err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("fixed_expense_id = ? AND period = ?", fixedExpense.ID, period).Take(&occurrence).Error; err != nil {
+		return nil, err
+	}
+	return &occurrence, nil
+}
+
+func (s *Services) getOccurrenceFixedExpense(userID string, id string) (*models.FixedExpense, error) {
+	var fixedExpense models.FixedExpense
+	if err := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetActiveStatuses()).
+		First(&fixedExpense).Error; err != nil {
+		return nil, errors.New("fixed expense not found")
+	}
+	return &fixedExpense, nil
+}
+
+// ConfirmFixedExpenseOccurrence marks a cycle of a fixed expense as paid. The next due cycle can
+// be confirmed before its due date, which posts its expense right away
+func (s *Services) ConfirmFixedExpenseOccurrence(userID string, id string, period time.Time) (*models.FixedExpenseOccurrence, error) {
+	fixedExpense, err := s.getOccurrenceFixedExpense(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	occurrence, err := s.findFixedExpenseOccurrence(fixedExpense, period)
+	if err != nil {
+		logger.Error("Error getting fixed expense occurrence: %v", err)
+		return nil, errors.New("error confirming occurrence")
+	}
+
+	if occurrence == nil {
+		if !period.Equal(fixedExpense.NextDueDate) {
+			if !isFixedExpensePeriod(fixedExpense, period) {
+				return nil, errors.New("invalid period: not a due date of the fixed expense")
+			}
+			return nil, errors.New("invalid period: only the next due cycle can be confirmed before it is posted")
+		}
+		if fixedExpense.CategoryID == nil {
+			return nil, errors.New("invalid fixed expense: it needs a category to be posted")
+		}
+		if err := s.processFixedExpense(fixedExpense, s.UserNow(userID)); err != nil {
+			return nil, errors.New("error posting occurrence")
+		}
+		if occurrence, err = s.findFixedExpenseOccurrence(fixedExpense, period); err != nil || occurrence == nil {
+			return nil, errors.New("error posting occurrence")
+		}
+	}
+
+	switch occurrence.Status {
+	case models.FixedExpenseOccurrencePaid:
+		return occurrence, nil
+	case models.FixedExpenseOccurrenceSkipped:
+		return nil, ErrFixedExpenseOccurrenceResolved
+	}
+
+	now := time.Now()
+	if err := s.db.Model(occurrence).Updates(map[string]interface{}{
+		"status":      models.FixedExpenseOccurrencePaid,
+		"resolved_at": &now,
+	}).Error; err != nil {
+		logger.Error("Error confirming fixed expense occurrence: %v", err)
+		return nil, errors.New("error confirming occurrence")
+	}
+	return occurrence, nil
+}
+
+// SkipFixedExpenseOccurrence skips a cycle of a fixed expense. The expense already posted for it
+// is deleted, giving the amount back to the account; a cycle not posted yet is never posted, and
+// skipping the next due one moves the due date on
+func (s *Services) SkipFixedExpenseOccurrence(userID string, id string, period time.Time) (*models.FixedExpenseOccurrence, error) {
+	fixedExpense, err := s.getOccurrenceFixedExpense(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	occurrence, err := s.findFixedExpenseOccurrence(fixedExpense, period)
+	if err != nil {
+		logger.Error("Error getting fixed expense occurrence: %v", err)
+		return nil, errors.New("error skipping occurrence")
+	}
+
+	now := time.Now()
+	if occurrence != nil {
+		switch occurrence.Status {
+		case models.FixedExpenseOccurrenceSkipped:
+			return occurrence, nil
+		case models.FixedExpenseOccurrencePaid:
+			return nil, ErrFixedExpenseOccurrenceResolved
+		}
+		if occurrence.ExpenseID != nil {
+			var expense models.Expense
+			if err := s.db.Select("id", "status").Where("id = ?", *occurrence.ExpenseID).Take(&expense).Error; err == nil &&
+				expense.Status != models.StatusDeleted {
+				if err := s.Expenses.SoftDelete(userID, expense.ID.String()); err != nil {
+					logger.Error("Error reversing expense of skipped occurrence: %v", err)
+					return nil, errors.New("error skipping occurrence")
+				}
+			}
+		}
+		if err := s.db.Model(occurrence).Updates(map[string]interface{}{
+			"status":      models.FixedExpenseOccurrenceSkipped,
+			"resolved_at": &now,
+		}).Error; err != nil {
+			logger.Error("Error skipping fixed expense occurrence: %v", err)
+			return nil, errors.New("error skipping occurrence")
+		}
+		return occurrence, nil
+	}
+
+	if !isFixedExpensePeriod(fixedExpense, period) {
+		return nil, errors.New("invalid period: not a due date of the fixed expense")
+	}
+	skipped := &models.FixedExpenseOccurrence{
+		FixedExpenseID: fixedExpense.ID,
+		UserID:         fixedExpense.UserID,
+		Period:         period,
+		Status:         models.FixedExpenseOccurrenceSkipped,
+		Amount:         fixedExpense.Amount,
+		ResolvedAt:     &now,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(skipped).Error; err != nil {
+			return err
+		}
+		// The due cycle moves on now instead of waiting for the processing run
+		var current models.FixedExpense
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", fixedExpense.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if !current.NextDueDate.Equal(period) {
+			return nil
+		}
+		reason := "skipped by the user"
+		if _, err := upsertFixedExpenseRun(tx, &current, period, models.FixedExpenseRunSkipped, &reason, now); err != nil {
+			return err
+		}
+		return tx.Model(&current).Update("next_due_date", calculateNextDueDate(&current)).Error
+	})
+	if err != nil {
+		logger.Error("Error skipping fixed expense occurrence: %v", err)
+		return nil, errors.New("error skipping occurrence")
+	}
+	return skipped, nil
+}
+
+// GetFixedExpenseOccurrencesForMonth returns the cycle of each fixed expense due in the month:
+// the stored occurrence when it was posted, confirmed or skipped, else a pending one to come
+func (s *Services) GetFixedExpenseOccurrencesForMonth(userID string, year int, month time.Month) ([]models.FixedExpenseOccurrence, error) {
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	var stored []models.FixedExpenseOccurrence
+	if err := s.db.Where("user_id = ? AND period BETWEEN ? AND ?", userID, monthStart, monthEnd).
+		Order("period ASC").Find(&stored).Error; err != nil {
+		logger.Error("Error getting fixed expense occurrences: %v", err)
+		return nil, errors.New("error getting fixed expense occurrences")
+	}
+	var runs []models.FixedExpenseRun
+	if err := s.db.Where("user_id = ? AND period BETWEEN ? AND ? AND status = ? AND expense_id IS NOT NULL",
+		userID, monthStart, monthEnd, models.FixedExpenseRunPosted).Find(&runs).Error; err != nil {
+		logger.Error("Error getting fixed expense runs: %v", err)
+		return nil, errors.New("error getting fixed expense occurrences")
+	}
+	byFixedExpense := make(map[uuid.UUID]models.FixedExpenseOccurrence, len(stored))
+	for _, occurrence := range stored {
+		byFixedExpense[occurrence.FixedExpenseID] = occurrence
+	}
+	for _, run := range runs {
+		if _, ok := byFixedExpense[run.FixedExpenseID]; !ok {
+			byFixedExpense[run.FixedExpenseID] = models.FixedExpenseOccurrence{
+				FixedExpenseID: run.FixedExpenseID,
+				UserID:         run.UserID,
+				Period:         run.Period,
+				Status:         models.FixedExpenseOccurrencePending,
+				ExpenseID:      run.ExpenseID,
+				Amount:         run.Amount,
+			}
+		}
+	}
+
+	occurrences := make([]models.FixedExpenseOccurrence, 0, len(fixedExpenses))
+	for _, fixedExpense := range fixedExpenses {
+		occurrence, ok := byFixedExpense[fixedExpense.ID]
+		if !ok {
+			period := fixedExpense.GetDueDateForMonth(year, month)
+			// The next due date may have drifted from the day of month (e.g. after the 31st)
+			if fixedExpense.NextDueDate.Year() == year && fixedExpense.NextDueDate.Month() == month {
+				period = fixedExpense.NextDueDate
+			}
+			occurrence = models.FixedExpenseOccurrence{
+				FixedExpenseID: fixedExpense.ID,
+				UserID:         fixedExpense.UserID,
+				Period:         period,
+				Status:         models.FixedExpenseOccurrencePending,
+				Amount:         fixedExpense.Amount,
+			}
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// ReconciliationStatus describes how a declared fixed expense compares with actual expenses
+type ReconciliationStatus string
+
+const (
+	ReconciliationMatched   ReconciliationStatus = "matched"   // Exactly one payment found
+	ReconciliationDuplicate ReconciliationStatus = "duplicate" // More than one payment found
+	ReconciliationMissed    ReconciliationStatus = "missed"    // No payment and the tolerance window has passed
+	ReconciliationPending   ReconciliationStatus = "pending"   // No payment yet but still within the window
+)
+
+// ReconciliationTolerance controls how loosely actual expenses are paired with fixed expenses
+type ReconciliationTolerance struct {
+	AmountPercent float64 // Allowed deviation from the declared amount, in percent
+	Days          int     // Allowed distance from the due date, in days
+}
+
+// DefaultReconciliationTolerance returns the tolerance used when none is provided
+func DefaultReconciliationTolerance() ReconciliationTolerance {
+	return ReconciliationTolerance{AmountPercent: 5, Days: 3}
+}
+
+// FixedExpenseReconciliation is the reconciliation result for one fixed expense in a month
+type FixedExpenseReconciliation struct {
+	FixedExpense models.FixedExpense
+	DueDate      time.Time
+	Status       ReconciliationStatus
+	Matches      []models.Expense
+}
+
+// GetFixedExpenseReconciliation pairs the fixed expenses that apply to a month with the
+// actual expenses recorded on the same bank account, within the given tolerance
+func (s *Services) GetFixedExpenseReconciliation(userID string, year int, month time.Month, tolerance ReconciliationTolerance) ([]FixedExpenseReconciliation, error) {
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load every candidate expense once, widening the month by the date tolerance
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	windowStart := monthStart.AddDate(0, 0, -tolerance.Days)
+	windowEnd := monthStart.AddDate(0, 1, -1).AddDate(0, 0, tolerance.Days)
+
+	var candidates []models.Expense
+	result := s.db.Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?",
+		userID, windowStart, windowEnd, models.GetActiveStatuses()).
+		Order("date ASC").Find(&candidates)
+	if result.Error != nil {
+		logger.Error("Error getting expenses for reconciliation: %v", result.Error)
+		return nil, result.Error
+	}
+
+	today := s.UserToday(userID)
+	used := make(map[uuid.UUID]bool)
+	reconciliation := make([]FixedExpenseReconciliation, 0, len(fixedExpenses))
+
+	for _, fixedExpense := range fixedExpenses {
+		dueDate := fixedExpense.GetDueDateForMonth(year, month)
+		item := FixedExpenseReconciliation{
+			FixedExpense: fixedExpense,
+			DueDate:      dueDate,
+			Matches:      []models.Expense{},
+		}
+
+		for _, expense := range candidates {
+			if used[expense.ID] || !matchesFixedExpense(fixedExpense, dueDate, expense, tolerance) {
+				continue
+			}
+			item.Matches = append(item.Matches, expense)
+		}
+
+		// Closest payment first so the primary match is always the most likely one
+		sort.Slice(item.Matches, func(i, j int) bool {
+			return daysBetween(item.Matches[i].Date, dueDate) < daysBetween(item.Matches[j].Date, dueDate)
+		})
+
+		for _, match := range item.Matches {
+			used[match.ID] = true
+		}
+
+		switch {
+		case len(item.Matches) == 1:
+			item.Status = ReconciliationMatched
+		case len(item.Matches) > 1:
+			item.Status = ReconciliationDuplicate
+		case dueDate.AddDate(0, 0, tolerance.Days).Before(today):
+			item.Status = ReconciliationMissed
+		default:
+			item.Status = ReconciliationPending
+		}
+
+		reconciliation = append(reconciliation, item)
+	}
+
+	logger.Info("Fixed expense reconciliation for %d-%02d calculated for user %s", year, month, userID)
+	return reconciliation, nil
+}
+
+// matchesFixedExpense reports whether an expense looks like a payment of the fixed expense
+func matchesFixedExpense(fixedExpense models.FixedExpense, dueDate time.Time, expense models.Expense, tolerance ReconciliationTolerance) bool {
+	if expense.BankAccountID != fixedExpense.BankAccountID {
+		return false
+	}
+
+	if fixedExpense.CategoryID != nil && expense.CategoryID != *fixedExpense.CategoryID {
+		return false
+	}
+
+	if daysBetween(expense.Date, dueDate) > tolerance.Days {
+		return false
+	}
+
+	allowed := fixedExpense.Amount.MulRatio(tolerance.AmountPercent / 100)
+	return (expense.Amount - fixedExpense.Amount).Abs() <= allowed
+}
+
+// daysBetween returns the absolute number of whole days between two dates
+func daysBetween(a, b time.Time) int {
+	return int(math.Abs(a.Sub(b).Hours()) / 24)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FixedExpenseRunFilter narrows the run history; zero fields don't filter
+type FixedExpenseRunFilter struct {
+	FixedExpenseID string
+	Status         string
+	From           *time.Time // Periods from this date
+	To             *time.Time // Periods up to this date
+}
+
+// upsertFixedExpenseRun records the outcome of an attempt at one period. A period already posted
+// is never overwritten: it returns false and leaves the row alone
+func upsertFixedExpenseRun(tx *gorm.DB, fixedExpense *models.FixedExpense, period time.Time, status string, reason *string, now time.Time) (bool, error) {
+	run := models.FixedExpenseRun{
+		FixedExpenseID: fixedExpense.ID,
+		UserID:         fixedExpense.UserID,
+		Period:         period,
+		Status:         status,
+		Amount:         fixedExpense.Amount,
+		Reason:         reason,
+		Attempts:       1,
+		ProcessedAt:    now,
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "fixed_expense_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":       status,
+			"amount":       fixedExpense.Amount,
+			"reason":       reason,
+			"attempts":     gorm.Expr("fixed_expense_runs.attempts + 1"),
+			"processed_at": now,
+			"updated_at":   now,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Neq{Column: clause.Column{Table: "fixed_expense_runs", Name: "status"}, Value: models.FixedExpenseRunPosted},
+		}},
+	}).Create(&run)
+	return result.RowsAffected > 0, result.Error
+}
+
+// recordFixedExpenseRun records a period that wasn't posted, outside the posting transaction
+func (s *Services) recordFixedExpenseRun(fixedExpense *models.FixedExpense, period time.Time, status string, reason string, now time.Time) {
+	if _, err := upsertFixedExpenseRun(s.db, fixedExpense, period, status, &reason, now); err != nil {
+		logger.Error("Error recording run of fixed expense %s: %v", fixedExpense.ID, err)
+	}
+}
+
+// GetFixedExpenseRuns lists what processing did with each period of the user's fixed expenses,
+// latest period first, to audit missed or retried occurrences
+func (s *Services) GetFixedExpenseRuns(userID string, filter FixedExpenseRunFilter, page PageRequest) ([]models.FixedExpenseRun, PageInfo, error) {
+	query := s.db.Model(&models.FixedExpenseRun{}).Where("user_id = ?", userID)
+	if filter.FixedExpenseID != "" {
+		id, err := uuid.Parse(filter.FixedExpenseID)
+		if err != nil {
+			return nil, PageInfo{}, errors.New("invalid fixed_expense_id")
+		}
+		query = query.Where("fixed_expense_id = ?", id)
+	}
+	if filter.Status != "" {
+		switch filter.Status {
+		case models.FixedExpenseRunPosted, models.FixedExpenseRunSkipped, models.FixedExpenseRunFailed:
+		default:
+			return nil, PageInfo{}, errors.New("invalid status: use posted, skipped or failed")
+		}
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("period >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("period <= ?", *filter.To)
+	}
+
+	var runs []models.FixedExpenseRun
+	info, err := paginate(query, "period DESC, processed_at DESC, id", page, &runs)
+	if err != nil {
+		logger.Error("Error getting fixed expense runs: %v", err)
+		return nil, PageInfo{}, errors.New("error getting fixed expense runs")
+	}
+	return runs, info, nil
+}
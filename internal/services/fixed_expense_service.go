@@ -4,15 +4,15 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateFixedExpense creates a new fixed expense
-func CreateFixedExpense(userID string,fixedExpense models.FixedExpense)(*models.FixedExpense,error){
+func (s *Services) CreateFixedExpense(userID string,fixedExpense models.FixedExpense)(*models.FixedExpense,error){
 	// Force basic Fields
 	fixedExpense.UserID = uuid.MustParse(userID)
 	fixedExpense.Status = models.StatusActive
@@ -27,7 +27,7 @@ func CreateFixedExpense(userID string,fixedExpense models.FixedExpense)(*models.
 	}
 	
 	var bankAccount models.BankAccount
-	result := db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
+	result := s.db.Where("id = ? AND user_id = ? AND status IN ?", 
 		fixedExpense.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
 	if result.Error != nil {
 		logger.Error("Bank account not found or not active")
@@ -37,7 +37,7 @@ func CreateFixedExpense(userID string,fixedExpense models.FixedExpense)(*models.
 	// Set next due date
 	fixedExpense.NextDueDate = fixedExpense.DueDate
 
-	result = db.DB.Create(&fixedExpense)
+	result = s.db.Create(&fixedExpense)
 	if result.Error != nil {
 		logger.Error("Error creating fixed expense: %v", result.Error)
 		return nil,errors.New("error creating fixed expense")
@@ -47,9 +47,9 @@ func CreateFixedExpense(userID string,fixedExpense models.FixedExpense)(*models.
 }
 
 // GetFixedExpenseByID returns a fixed expense by its ID
-func GetFixedExpenseByID(userID string,id string)(*models.FixedExpense,error){
+func (s *Services) GetFixedExpenseByID(userID string,id string)(*models.FixedExpense,error){
 	var fixedExpense models.FixedExpense
-	result := db.DB.Where("user_id = ? AND id = ?",userID,id).First(&fixedExpense)
+	result := s.db.Where("user_id = ? AND id = ?",userID,id).First(&fixedExpense)
 	if result.Error != nil {
 		logger.Error("Error getting fixed expense: %v", result.Error)
 		return nil,errors.New("error getting fixed expense")
@@ -58,38 +58,38 @@ func GetFixedExpenseByID(userID string,id string)(*models.FixedExpense,error){
 	return &fixedExpense,nil
 }
 
-func GetFixedExpenses(userID string,includeDeleted bool)([]models.FixedExpense,error){
+func (s *Services) GetFixedExpenses(userID string,includeDeleted bool,page PageRequest)([]models.FixedExpense,PageInfo,error){
 	var fixedExpenses []models.FixedExpense
-	query := db.DB.Where("user_id = ?",userID)
+	query := s.db.Model(&models.FixedExpense{}).Where("user_id = ?",userID)
 
 	if !includeDeleted{
 		query = query.Where("status = ?",models.StatusActive)
 	}
 
-	result := query.Find(&fixedExpenses)
-	if result.Error != nil {
-		logger.Error("Error getting fixed expenses: %v", result.Error)
-		return nil,errors.New("error getting fixed expenses")
+	info, err := paginate(query, "due_date, id", page, &fixedExpenses)
+	if err != nil {
+		logger.Error("Error getting fixed expenses: %v", err)
+		return nil,PageInfo{},errors.New("error getting fixed expenses")
 	}
 
-	return fixedExpenses,nil
+	return fixedExpenses,info,nil
 }
 
 // GetCommittedFixedExpensesForAccount returns the total amount of active fixed expenses
 // that are due for the specified year and month for a given bank account.
 // This does not modify balances; it only computes a committed amount for UI/UX.
-func GetCommittedFixedExpensesForAccount(userID string, bankAccountID string, year int, month time.Month) (float64, error) {
+func (s *Services) GetCommittedFixedExpensesForAccount(userID string, bankAccountID string, year int, month time.Month) (models.Money, error) {
     var fixedExpenses []models.FixedExpense
 
     // Query only active fixed expenses for this user and account (exclude NULL bank_account_id)
-    result := db.DB.Where("user_id = ? AND bank_account_id = ? AND status = ? AND bank_account_id IS NOT NULL",
+    result := s.db.Where("user_id = ? AND bank_account_id = ? AND status = ? AND bank_account_id IS NOT NULL",
         userID, bankAccountID, models.StatusActive).Find(&fixedExpenses)
     if result.Error != nil {
         logger.Error("Error querying fixed expenses for committed calculation: %v", result.Error)
         return 0, result.Error
     }
 
-    total := 0.0
+    var total models.Money
     for _, fx := range fixedExpenses {
         if fx.ShouldApplyForMonth(year, month) {
             total += fx.Amount
@@ -99,9 +99,9 @@ func GetCommittedFixedExpensesForAccount(userID string, bankAccountID string, ye
     return total, nil
 }
 
-func UpdateFixedExpense(userID string,id string,fixedExpense models.FixedExpense)(*models.FixedExpense,error){
+func (s *Services) UpdateFixedExpense(userID string,id string,fixedExpense models.FixedExpense)(*models.FixedExpense,error){
 	var existingFixedExpense models.FixedExpense
-	result := db.DB.Where("user_id = ? AND id = ?",userID,id).First(&existingFixedExpense)
+	result := s.db.Where("user_id = ? AND id = ?",userID,id).First(&existingFixedExpense)
 	if result.Error != nil {
 		logger.Error("Error getting fixed expense: %v", result.Error)
 		return nil,errors.New("error getting fixed expense")
@@ -115,9 +115,10 @@ func UpdateFixedExpense(userID string,id string,fixedExpense models.FixedExpense
 	existingFixedExpense.Name = fixedExpense.Name
 	existingFixedExpense.Amount = fixedExpense.Amount
 	existingFixedExpense.DueDate = fixedExpense.DueDate
+	existingFixedExpense.SkipHolidays = fixedExpense.SkipHolidays
 	existingFixedExpense.UpdatedAt = time.Now()
 
-	result = db.DB.Save(&existingFixedExpense)
+	result = s.db.Save(&existingFixedExpense)
 	if result.Error != nil && result.Error != gorm.ErrRecordNotFound{
 		logger.Error("Error updating fixed expense: %v", result.Error)
 		return nil,errors.New("error updating fixed expense")
@@ -126,9 +127,9 @@ func UpdateFixedExpense(userID string,id string,fixedExpense models.FixedExpense
 	return &existingFixedExpense,nil
 }
 
-func DeleteFixedExpense(userID string,id string)(*models.FixedExpense,error){
+func (s *Services) DeleteFixedExpense(userID string,id string)(*models.FixedExpense,error){
 	var existingFixedExpense models.FixedExpense
-	result := db.DB.Where("user_id = ? AND id = ?",userID,id).First(&existingFixedExpense)
+	result := s.db.Where("user_id = ? AND id = ?",userID,id).First(&existingFixedExpense)
 	if result.Error != nil {
 		logger.Error("Error getting fixed expense: %v", result.Error)
 		return nil,errors.New("error getting fixed expense")
@@ -139,7 +140,7 @@ func DeleteFixedExpense(userID string,id string)(*models.FixedExpense,error){
 		return nil,errors.New("fixed expense is deleted")
 	}
 
-	result = db.DB.Model(&existingFixedExpense).Update("status",models.StatusDeleted)
+	result = s.db.Model(&existingFixedExpense).Update("status",models.StatusDeleted)
 	if result.Error != nil && result.Error != gorm.ErrRecordNotFound{
 		logger.Error("Error deleting fixed expense: %v", result.Error)
 		return nil,errors.New("error deleting fixed expense")
@@ -150,9 +151,9 @@ func DeleteFixedExpense(userID string,id string)(*models.FixedExpense,error){
 
 // GetFixedExpensesForMonth returns all fixed expenses that should apply for a specific year/month
 // This includes recurring expenses and handles the logic of whether they should apply
-func GetFixedExpensesForMonth(userID string, year int, month time.Month) ([]models.FixedExpense, error) {
+func (s *Services) GetFixedExpensesForMonth(userID string, year int, month time.Month) ([]models.FixedExpense, error) {
 	var allFixedExpenses []models.FixedExpense
-	result := db.DB.Where("user_id = ? AND status = ? AND is_recurring = ?", 
+	result := s.db.Where("user_id = ? AND status = ? AND is_recurring = ?", 
 		userID, models.StatusActive, true).Find(&allFixedExpenses)
 	
 	if result.Error != nil {
@@ -172,35 +173,35 @@ func GetFixedExpensesForMonth(userID string, year int, month time.Month) ([]mode
 }
 
 // GetCommittedBudgetForMonth calculates the total amount committed to fixed expenses for a month
-func GetCommittedBudgetForMonth(userID string, year int, month time.Month) (float64, error) {
-	fixedExpenses, err := GetFixedExpensesForMonth(userID, year, month)
+func (s *Services) GetCommittedBudgetForMonth(userID string, year int, month time.Month) (models.Money, error) {
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, year, month)
 	if err != nil {
 		return 0, err
 	}
 
-	var total float64
+	var total models.Money
 	for _, expense := range fixedExpenses {
 		total += expense.Amount
 	}
 
-	logger.Info("Committed budget for %d-%02d: $%.2f", year, month, total)
+	logger.Info("Committed budget for %d-%02d: $%s", year, month, total)
 	return total, nil
 }
 
 // GetFixedExpensesByCategoryType returns fixed expenses grouped by their category's expense type (needs/wants/savings)
-func GetFixedExpensesByCategoryType(userID string, year int, month time.Month) (map[string]float64, error) {
-	fixedExpenses, err := GetFixedExpensesForMonth(userID, year, month)
+func (s *Services) GetFixedExpensesByCategoryType(userID string, year int, month time.Month) (map[string]models.Money, error) {
+	fixedExpenses, err := s.GetFixedExpensesForMonth(userID, year, month)
 	if err != nil {
 		return nil, err
 	}
 
 	// If no fixed expenses, return empty map
 	if len(fixedExpenses) == 0 {
-		return make(map[string]float64), nil
+		return make(map[string]models.Money), nil
 	}
 
 	// Group by expense type
-	result := make(map[string]float64)
+	result := make(map[string]models.Money)
 	
 	for _, expense := range fixedExpenses {
 		// If no category, we need to assign to a default (let's use "Wants" as default)
@@ -211,7 +212,7 @@ func GetFixedExpensesByCategoryType(userID string, year int, month time.Month) (
 
 		// Get the category
 		var category models.Category
-		if db.DB.Where("id = ?", expense.CategoryID).First(&category).Error != nil {
+		if s.db.Where("id = ?", expense.CategoryID).First(&category).Error != nil {
 			// If category not found, default to "Wants"
 			result["Wants"] += expense.Amount
 			continue
@@ -237,13 +238,13 @@ func GetFixedExpensesByCategoryType(userID string, year int, month time.Month) (
 }
 
 // GetUpcomingFixedExpenses returns fixed expenses due in the next N days
-func GetUpcomingFixedExpenses(userID string, days int) ([]models.FixedExpense, error) {
+func (s *Services) GetUpcomingFixedExpenses(userID string, days int) ([]models.FixedExpense, error) {
 	var fixedExpenses []models.FixedExpense
-	now := time.Now()
-	futureDate := now.AddDate(0, 0, days)
+	today := s.UserToday(userID)
+	futureDate := today.AddDate(0, 0, days)
 
-	result := db.DB.Where("user_id = ? AND status = ? AND is_recurring = ? AND due_date >= ? AND due_date <= ?",
-		userID, models.StatusActive, true, now, futureDate).
+	result := s.db.Where("user_id = ? AND status = ? AND is_recurring = ? AND due_date > ? AND due_date <= ?",
+		userID, models.StatusActive, true, today, futureDate).
 		Order("due_date ASC").
 		Find(&fixedExpenses)
 
@@ -257,98 +258,166 @@ func GetUpcomingFixedExpenses(userID string, days int) ([]models.FixedExpense, e
 
 // ProcessDueFixedExpenses processes all fixed expenses that are due today
 // This should be called by a scheduled job (cron/task scheduler)
-func ProcessDueFixedExpenses() error {
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-	
+func (s *Services) ProcessDueFixedExpenses() error {
+	_, err := s.processDueFixedExpenses("", time.Now())
+	return err
+}
+
+// processDueFixedExpenses posts the fixed expenses due at now. With an empty userID it covers
+// every regular user; sandbox tenants only move with their own virtual clock
+func (s *Services) processDueFixedExpenses(userID string, now time.Time) (int, error) {
+	// Users ahead of UTC may already be on the next day; each one is checked against the
+	// date of its user's timezone below
+	query := s.db.Where("next_due_date <= ? AND status = ? AND is_recurring = ?",
+		dateOf(now.UTC()).AddDate(0, 0, 1), models.StatusActive, true)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	} else {
+		query = query.Where("user_id NOT IN (?)", s.db.Model(&models.User{}).Select("id").Where("is_sandbox = ?", true))
+	}
+
 	var dueFixedExpenses []models.FixedExpense
-	result := db.DB.Where("next_due_date <= ? AND status = ? AND is_recurring = ?",
-		today, models.StatusActive, true).
-		Preload("BankAccount").
-		Find(&dueFixedExpenses)
+	result := query.Preload("BankAccount").Find(&dueFixedExpenses)
 	
 	if result.Error != nil {
 		logger.Error("Error fetching due fixed expenses: %v", result.Error)
-		return result.Error
+		return 0, result.Error
 	}
 	
+	// Fixed expenses skipping holidays wait for the next business day of their user's calendar
+	userIDs := make([]uuid.UUID, 0, len(dueFixedExpenses))
 	for _, fixedExpense := range dueFixedExpenses {
-		if err := processFixedExpense(&fixedExpense); err != nil {
+		if fixedExpense.SkipHolidays {
+			userIDs = append(userIDs, fixedExpense.UserID)
+		}
+	}
+	countries, err := s.getHolidayCountries(userIDs)
+	if err != nil {
+		logger.Error("Error getting holiday calendars: %v", err)
+		return 0, err
+	}
+	
+	processed := 0
+	for _, fixedExpense := range dueFixedExpenses {
+		today := dateOf(now.In(s.UserLocation(fixedExpense.UserID.String())))
+		if fixedExpense.NextDueDate.After(today) {
+			continue
+		}
+		if fixedExpense.SkipHolidays && !IsBusinessDay(countries[fixedExpense.UserID], today) {
+			continue
+		}
+		if err := s.processFixedExpense(&fixedExpense, now); err != nil {
 			logger.Error("Error processing fixed expense %s: %v", fixedExpense.ID, err)
 			continue // Continue processing others even if one fails
 		}
+		if fixedExpense.CategoryID != nil { // Uncategorized ones are skipped without moving their due date
+			processed++
+		}
 	}
 	
 	logger.Info("Processed %d fixed expenses", len(dueFixedExpenses))
-	return nil
+	return processed, nil
 }
 
-// processFixedExpense creates an expense record and updates bank account
-func processFixedExpense(fixedExpense *models.FixedExpense) error {
-	tx := db.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-	
-	// Check if bank account has sufficient balance (warning only)
-	var bankAccount models.BankAccount
-	if err := tx.Where("id = ?", fixedExpense.BankAccountID).First(&bankAccount).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-	
-	if bankAccount.Balance < fixedExpense.Amount {
-		logger.Warn("Fixed expense %s will cause negative balance in account %s",
-			fixedExpense.Name, bankAccount.AccountName)
-	}
+// processFixedExpense posts the occurrence of a fixed expense due on its next due date: an
+// expense record, the bank account deduction and the move to the next due date. The period is
+// claimed in fixed_expense_runs in the same transaction, so repeated or overlapping runs post it
+// once; a period already posted (e.g. after the due date was moved back) or skipped by the user
+// only moves the due date. The posted expense is linked to the pending occurrence of the period
+func (s *Services) processFixedExpense(fixedExpense *models.FixedExpense, now time.Time) error {
+	period := fixedExpense.NextDueDate
 	
-	// Create an expense record
-	expense := &models.Expense{
-		UserID:        fixedExpense.UserID,
-		Amount:        fixedExpense.Amount,
-		Date:          time.Now().UTC(),
-		BankAccountID: fixedExpense.BankAccountID,
-		Description:   &fixedExpense.Name,
-		Status:        models.StatusActive,
-	}
-	
-	// Handle category if provided
-	if fixedExpense.CategoryID != nil {
-		expense.CategoryID = *fixedExpense.CategoryID
-	} else {
-		// If no category, we need to create a default one or skip
-		// For now, we'll skip creating the expense if no category
-		tx.Rollback()
+	// Without a category no expense can be created; the due date stays so it's retried
+	if fixedExpense.CategoryID == nil {
 		logger.Warn("Fixed expense %s has no category, skipping", fixedExpense.Name)
+		s.recordFixedExpenseRun(fixedExpense, period, models.FixedExpenseRunSkipped, "fixed expense has no category", now)
 		return nil
 	}
 	
-	if err := tx.Create(expense).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-	
-	// Update bank account balance
-	if err := tx.Model(&bankAccount).
-		Update("balance", gorm.Expr("balance - ?", fixedExpense.Amount)).Error; err != nil {
-		tx.Rollback()
+	var expense *models.Expense
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// A concurrent run that already handled this period has moved the due date on
+		var current models.FixedExpense
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", fixedExpense.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if !current.NextDueDate.Equal(period) {
+			return nil
+		}
+		
+		nextDueDate := calculateNextDueDate(fixedExpense)
+		advance := map[string]interface{}{
+			"last_processed_at": &now,
+			"next_due_date":     nextDueDate,
+		}
+		
+		// A cycle the user skipped only moves the due date
+		var skipped int64
+		if err := tx.Model(&models.FixedExpenseOccurrence{}).
+			Where("fixed_expense_id = ? AND period = ? AND status = ?", fixedExpense.ID, period, models.FixedExpenseOccurrenceSkipped).
+			Count(&skipped).Error; err != nil {
+			return err
+		}
+		if skipped > 0 {
+			reason := "skipped by the user"
+			if _, err := upsertFixedExpenseRun(tx, fixedExpense, period, models.FixedExpenseRunSkipped, &reason, now); err != nil {
+				return err
+			}
+			return tx.Model(fixedExpense).Updates(advance).Error
+		}
+		
+		claimed, err := upsertFixedExpenseRun(tx, fixedExpense, period, models.FixedExpenseRunPosted, nil, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			logger.Warn("Period %s of fixed expense %s was already posted, moving on", period.Format("2006-01-02"), fixedExpense.ID)
+			return tx.Model(fixedExpense).Updates(advance).Error
+		}
+		
+		// Check if bank account has sufficient balance (warning only)
+		var bankAccount models.BankAccount
+		if err := tx.Where("id = ?", fixedExpense.BankAccountID).First(&bankAccount).Error; err != nil {
+			return err
+		}
+		if bankAccount.Balance < fixedExpense.Amount {
+			logger.Warn("Fixed expense %s will cause negative balance in account %s",
+				fixedExpense.Name, bankAccount.AccountName)
+		}
+		
+		expense = &models.Expense{
+			UserID:        fixedExpense.UserID,
+			CategoryID:    *fixedExpense.CategoryID,
+			Amount:        fixedExpense.Amount,
+			Date:          dateOf(now.In(s.UserLocation(fixedExpense.UserID.String()))),
+			BankAccountID: fixedExpense.BankAccountID,
+			Description:   &fixedExpense.Name,
+			Status:        models.StatusActive,
+		}
+		if err := tx.Create(expense).Error; err != nil {
+			return err
+		}
+		if err := adjustAccountBalance(tx, bankAccount.ID, -fixedExpense.Amount); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.FixedExpenseRun{}).
+			Where("fixed_expense_id = ? AND period = ?", fixedExpense.ID, period).
+			Update("expense_id", expense.ID).Error; err != nil {
+			return err
+		}
+		if err := linkFixedExpenseOccurrence(tx, fixedExpense, period, expense.ID); err != nil {
+			return err
+		}
+		return tx.Model(fixedExpense).Updates(advance).Error
+	})
+	if err != nil {
+		s.recordFixedExpenseRun(fixedExpense, period, models.FixedExpenseRunFailed, err.Error(), now)
 		return err
 	}
 	
-	// Update fixed expense next due date
-	nextDueDate := calculateNextDueDate(fixedExpense)
-	now := time.Now()
-	if err := tx.Model(fixedExpense).Updates(map[string]interface{}{
-		"last_processed_at": &now,
-		"next_due_date":     nextDueDate,
-	}).Error; err != nil {
-		tx.Rollback()
-		return err
+	if expense != nil {
+		logger.Info("Processed fixed expense: %s, created expense: %s", fixedExpense.Name, expense.ID)
 	}
-	
-	tx.Commit()
-	logger.Info("Processed fixed expense: %s, created expense: %s", fixedExpense.Name, expense.ID)
 	return nil
 }
 
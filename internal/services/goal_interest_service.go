@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GoalFundingInterest marks the contributions posted from the interest of a goal's APY
+const GoalFundingInterest = "interest"
+
+// MaxGoalProjectionMonths is the horizon of goal projections, 50 years
+const MaxGoalProjectionMonths = 600
+
+// MaxGoalAPY caps the APY a goal can have, in percent
+const MaxGoalAPY = 100
+
+// goalMonthlyRate converts an APY in percent to the monthly rate that compounds to it
+func goalMonthlyRate(apy float64) float64 {
+	return math.Pow(1+apy/100, 1.0/12) - 1
+}
+
+// lastMonthEnd returns the last day of the month before the date
+func lastMonthEnd(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+}
+
+func newGoalContribution(goal *models.Goal, amount models.Money, source string, date time.Time) models.GoalContribution {
+	return models.GoalContribution{
+		GoalID:     goal.ID,
+		UserID:     goal.UserID,
+		Amount:     amount,
+		Source:     source,
+		IsInterest: source == GoalFundingInterest,
+		Date:       time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// recordGoalContribution stores money added to a goal using the caller's transaction
+func recordGoalContribution(tx *gorm.DB, goal *models.Goal, amount models.Money, source string, date time.Time) error {
+	contribution := newGoalContribution(goal, amount, source, date)
+	return tx.Create(&contribution).Error
+}
+
+// GetGoalContributions lists the contributions of a goal, newest first
+func (s *Services) GetGoalContributions(userID string, goalID string) ([]models.GoalContribution, error) {
+	goal, err := s.getGoalByID(userID, goalID)
+	if err != nil {
+		return nil, errors.New("goal not found")
+	}
+
+	var contributions []models.GoalContribution
+	if err := s.db.Where("goal_id = ? AND user_id = ?", goal.ID, userID).
+		Order("date DESC, created_at DESC").Find(&contributions).Error; err != nil {
+		logger.Error("Error getting goal contributions: %v", err)
+		return nil, errors.New("error getting goal contributions")
+	}
+	return contributions, nil
+}
+
+// averageMonthlyGoalContribution averages what the user put into a goal over the last 3 full
+// months, leaving interest out
+func (s *Services) averageMonthlyGoalContribution(userID string, goalID uuid.UUID) (models.Money, error) {
+	end := lastMonthEnd(s.UserNow(userID))
+	start := time.Date(end.Year(), end.Month()-2, 1, 0, 0, 0, 0, time.UTC)
+
+	var total models.Money
+	if err := s.db.Model(&models.GoalContribution{}).
+		Where("goal_id = ? AND is_interest = ? AND date BETWEEN ? AND ?", goalID, false, start, end).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total.MulRatio(1.0 / 3), nil
+}
+
+// projectGoalMonths counts the months until the balance reaches the total, adding the interest
+// of the month and then the contribution. It returns -1 when the horizon runs out first
+func projectGoalMonths(saved, total, contribution, rate float64) int {
+	balance := saved
+	for month := 0; month <= MaxGoalProjectionMonths; month++ {
+		if balance >= total {
+			return month
+		}
+		balance += balance*rate + contribution
+	}
+	return -1
+}
+
+// ProjectGoal estimates when a goal completes. With no monthly contribution given it uses the
+// average of the last 3 months
+func (s *Services) ProjectGoal(userID string, goalID string, monthlyContribution *models.Money) (*dto.GoalProjection, error) {
+	if monthlyContribution != nil && *monthlyContribution < 0 {
+		return nil, errors.New("invalid monthly contribution: cannot be negative")
+	}
+
+	goal, err := s.getGoalByID(userID, goalID)
+	if err != nil {
+		return nil, errors.New("goal not found")
+	}
+
+	currency := s.GetUserCurrency(userID)
+	projection := &dto.GoalProjection{
+		GoalID:            goal.ID.String(),
+		TotalAmount:       goal.TotalAmount,
+		SavedAmount:       goal.SavedAmount,
+		ContributionBasis: "request",
+		Schedule:          []dto.GoalProjectionMonth{},
+	}
+	if goal.APY != nil {
+		projection.APY = *goal.APY
+	}
+	if monthlyContribution != nil {
+		projection.MonthlyContribution = currency.RoundMoney(*monthlyContribution)
+	} else {
+		average, err := s.averageMonthlyGoalContribution(userID, goal.ID)
+		if err != nil {
+			logger.Error("Error averaging goal contributions: %v", err)
+			return nil, errors.New("error projecting goal")
+		}
+		projection.MonthlyContribution = currency.RoundMoney(average)
+		projection.ContributionBasis = "history"
+	}
+
+	rate := goalMonthlyRate(projection.APY)
+	start := s.UserNow(userID)
+	completionDate := func(months int) *string {
+		date := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		return &date
+	}
+
+	if months := projectGoalMonths(goal.SavedAmount.Float64(), goal.TotalAmount.Float64(), projection.MonthlyContribution.Float64(), 0); months >= 0 {
+		projection.MonthsWithoutInterest = &months
+		projection.CompletionDateWithoutInterest = completionDate(months)
+	}
+	months := projectGoalMonths(goal.SavedAmount.Float64(), goal.TotalAmount.Float64(), projection.MonthlyContribution.Float64(), rate)
+	if months >= 0 {
+		projection.MonthsToComplete = &months
+		projection.CompletionDate = completionDate(months)
+	} else {
+		months = MaxGoalProjectionMonths
+	}
+
+	balance := goal.SavedAmount
+	for month := 1; month <= months; month++ {
+		interest := currency.RoundMoney(balance.MulRatio(rate))
+		balance = currency.RoundMoney(balance + interest + projection.MonthlyContribution)
+		projection.InterestEarned = currency.RoundMoney(projection.InterestEarned + interest)
+		projection.Schedule = append(projection.Schedule, dto.GoalProjectionMonth{
+			Month:        *completionDate(month),
+			Contribution: projection.MonthlyContribution,
+			Interest:     interest,
+			Balance:      balance,
+		})
+	}
+
+	return projection, nil
+}
+
+// PostGoalInterest posts the interest of the goals with an APY; it runs periodically as a job
+func (s *Services) PostGoalInterest() error {
+	var goals []models.Goal
+	if err := s.db.Where("status = ? AND apy > 0", models.StatusActive).Find(&goals).Error; err != nil {
+		logger.Error("Error listing goals for interest: %v", err)
+		return err
+	}
+
+	for _, goal := range goals {
+		if err := s.postGoalInterestFor(goal.ID, lastMonthEnd(s.UserNow(goal.UserID.String()))); err != nil {
+			logger.Error("Error posting interest of goal %s: %v", goal.ID, err)
+		}
+	}
+	return nil
+}
+
+// postGoalInterestFor posts one interest contribution per month ended since the last one posted,
+// compounding on the saved amount. Months the server missed are caught up in order
+func (s *Services) postGoalInterestFor(goalID uuid.UUID, through time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var goal models.Goal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", goalID).First(&goal).Error; err != nil {
+			return err
+		}
+		if goal.APY == nil || *goal.APY <= 0 {
+			return nil
+		}
+		if goal.InterestThrough == nil {
+			// Interest starts accruing the month the APY was set
+			return tx.Model(&goal).Update("interest_through", through).Error
+		}
+
+		currency := s.GetUserCurrency(goal.UserID.String())
+		rate := goalMonthlyRate(*goal.APY)
+		var posted models.Money
+		monthEnd := *goal.InterestThrough
+		for monthEnd.Before(through) {
+			monthEnd = lastMonthEnd(monthEnd.AddDate(0, 0, 1).AddDate(0, 1, 0))
+			interest := currency.RoundMoney(goal.SavedAmount.MulRatio(rate))
+			if interest <= 0 {
+				continue
+			}
+			if err := recordGoalContribution(tx, &goal, interest, GoalFundingInterest, monthEnd); err != nil {
+				return err
+			}
+			goal.SavedAmount = currency.RoundMoney(goal.SavedAmount + interest)
+			posted += interest
+		}
+
+		if err := tx.Model(&goal).Updates(map[string]interface{}{
+			"saved_amount":     goal.SavedAmount,
+			"interest_through": monthEnd,
+			"updated_at":       time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if posted <= 0 {
+			return nil
+		}
+		if err := checkGoalMilestones(tx, &goal); err != nil {
+			return err
+		}
+
+		payload := map[string]interface{}{
+			"goal_id":      goal.ID,
+			"goal_name":    goal.Name,
+			"source":       GoalFundingInterest,
+			"amount":       posted,
+			"saved_amount": goal.SavedAmount,
+			"total_amount": goal.TotalAmount,
+			"completed":    goal.SavedAmount >= goal.TotalAmount,
+		}
+		return EnqueueEvent(tx, goal.UserID, EventGoalFunded, "goal", goal.ID, payload)
+	})
+}
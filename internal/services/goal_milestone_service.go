@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventGoalMilestoneReached is emitted when a goal's saved amount crosses a milestone
+const EventGoalMilestoneReached = "goal.milestone_reached"
+
+// ErrGoalMilestoneExists is returned when a goal already has a milestone at the same percent or amount
+var ErrGoalMilestoneExists = errors.New("milestone already exists for this goal")
+
+// defaultGoalMilestonePercents are created for every new goal
+var defaultGoalMilestonePercents = []float64{25, 50, 75, 100}
+
+// createDefaultGoalMilestones adds the 25/50/75/100% milestones to a new goal
+func createDefaultGoalMilestones(tx *gorm.DB, goal *models.Goal) error {
+	for _, percent := range defaultGoalMilestonePercents {
+		percent := percent
+		milestone := models.GoalMilestone{
+			GoalID:  goal.ID,
+			UserID:  goal.UserID,
+			Percent: &percent,
+		}
+		if err := tx.Create(&milestone).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGoalMilestones marks every milestone the goal has just crossed and emits one
+// goal.milestone_reached event per crossing, using the caller's transaction.
+// Reached milestones keep their date even if the saved amount later drops.
+func checkGoalMilestones(tx *gorm.DB, goal *models.Goal) error {
+	var pending []models.GoalMilestone
+	if err := tx.Where("goal_id = ? AND reached_at IS NULL", goal.ID).Find(&pending).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, milestone := range pending {
+		target := milestone.TargetAmount(goal.TotalAmount)
+		if goal.SavedAmount < target {
+			continue
+		}
+
+		if err := tx.Model(&models.GoalMilestone{}).Where("id = ?", milestone.ID).
+			Update("reached_at", now).Error; err != nil {
+			return err
+		}
+
+		payload := map[string]interface{}{
+			"goal_id":       goal.ID,
+			"goal_name":     goal.Name,
+			"milestone_id":  milestone.ID,
+			"percent":       milestone.Percent,
+			"amount":        milestone.Amount,
+			"label":         milestone.Label,
+			"target_amount": target,
+			"saved_amount":  goal.SavedAmount,
+			"total_amount":  goal.TotalAmount,
+			"reached_at":    now,
+		}
+		if err := EnqueueEvent(tx, goal.UserID, EventGoalMilestoneReached, "goal", goal.ID, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetGoalMilestones returns the milestones of a goal ordered by target amount
+func (s *Services) GetGoalMilestones(userID string, goalID string) (*dto.GoalMilestones, error) {
+	goal, err := s.getGoalByID(userID, goalID)
+	if err != nil {
+		return nil, errors.New("goal not found")
+	}
+
+	var milestones []models.GoalMilestone
+	if err := s.db.Where("goal_id = ? AND user_id = ?", goal.ID, userID).Find(&milestones).Error; err != nil {
+		logger.Error("Error getting goal milestones: %v", err)
+		return nil, errors.New("error getting goal milestones")
+	}
+
+	result := &dto.GoalMilestones{
+		GoalID:      goal.ID.String(),
+		TotalAmount: goal.TotalAmount,
+		SavedAmount: goal.SavedAmount,
+		Milestones:  make([]dto.GoalMilestone, 0, len(milestones)),
+	}
+	currency := s.GetUserCurrency(userID)
+	for _, milestone := range milestones {
+		result.Milestones = append(result.Milestones, dto.GoalMilestone{
+			ID:           milestone.ID.String(),
+			Percent:      milestone.Percent,
+			Amount:       milestone.Amount,
+			Label:        milestone.Label,
+			TargetAmount: currency.RoundMoney(milestone.TargetAmount(goal.TotalAmount)),
+			Reached:      milestone.ReachedAt != nil,
+			ReachedAt:    milestone.ReachedAt,
+		})
+	}
+	sort.SliceStable(result.Milestones, func(i, j int) bool {
+		return result.Milestones[i].TargetAmount < result.Milestones[j].TargetAmount
+	})
+
+	return result, nil
+}
+
+// AddGoalMilestone defines a custom milestone on a goal, either as a percent or as an amount.
+// A milestone that is already behind the saved amount is recorded as reached without
+// emitting a celebration event.
+func (s *Services) AddGoalMilestone(userID string, goalID string, milestone *models.GoalMilestone) error {
+	if (milestone.Percent == nil) == (milestone.Amount == nil) {
+		return errors.New("exactly one of percent or amount is required")
+	}
+	if milestone.Percent != nil && (*milestone.Percent <= 0 || *milestone.Percent > 100) {
+		return errors.New("percent must be between 0 and 100")
+	}
+	if milestone.Amount != nil && *milestone.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	goal, err := s.getGoalByID(userID, goalID)
+	if err != nil {
+		return errors.New("goal not found")
+	}
+	if milestone.Amount != nil && *milestone.Amount > goal.TotalAmount {
+		return errors.New("amount cannot exceed the goal total amount")
+	}
+
+	milestone.ID = uuid.Nil
+	milestone.GoalID = goal.ID
+	milestone.UserID = goal.UserID
+	milestone.ReachedAt = nil
+	if goal.SavedAmount >= milestone.TargetAmount(goal.TotalAmount) {
+		now := time.Now()
+		milestone.ReachedAt = &now
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.GoalMilestone{}).Where("goal_id = ?", goal.ID)
+		if milestone.Percent != nil {
+			query = query.Where("percent = ?", *milestone.Percent)
+		} else {
+			query = query.Where("amount = ?", *milestone.Amount)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrGoalMilestoneExists
+		}
+
+		return tx.Create(milestone).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrGoalMilestoneExists) {
+			return err
+		}
+		logger.Error("Error creating goal milestone: %v", err)
+		return errors.New("error creating goal milestone")
+	}
+
+	return nil
+}
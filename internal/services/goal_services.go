@@ -4,31 +4,53 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-func createGoal(userID string, goal models.Goal) (*models.Goal, error) {
+func (s *Services) createGoal(userID string, goal models.Goal) (*models.Goal, error) {
 	// Force basic fields
 	goal.UserID = uuid.MustParse(userID)
 	goal.Status = models.StatusActive
 	goal.CreatedAt = time.Now()
 	goal.UpdatedAt = time.Now()
+	goal.InterestThrough = nil
+	if goal.APY != nil && *goal.APY > 0 {
+		// Interest is posted from the current month on
+		through := lastMonthEnd(s.UserNow(userID))
+		goal.InterestThrough = &through
+	} else {
+		goal.APY = nil
+	}
 
-	result := db.DB.Create(&goal)
-	if result.Error != nil {
-		logger.Error("Error creating goal: %v", result.Error)
+	// Goal, default milestones and any milestone already crossed are written together
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// New goals go to the end of the funding order
+		if err := tx.Model(&models.Goal{}).Where("user_id = ?", goal.UserID).
+			Select("COALESCE(MAX(priority), 0) + 1").Scan(&goal.Priority).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&goal).Error; err != nil {
+			return err
+		}
+		if err := createDefaultGoalMilestones(tx, &goal); err != nil {
+			return err
+		}
+		return checkGoalMilestones(tx, &goal)
+	})
+	if err != nil {
+		logger.Error("Error creating goal: %v", err)
 		return nil, errors.New("error creating goal")
 	}
 
 	return &goal, nil
 }
 
-func getGoalByID(userID string, goalID string) (*models.Goal, error) {
+func (s *Services) getGoalByID(userID string, goalID string) (*models.Goal, error) {
 	var goal models.Goal
-	result := db.DB.Where("user_id = ? AND id = ?", userID, goalID).First(&goal)
+	result := s.db.Where("user_id = ? AND id = ?", userID, goalID).First(&goal)
 	if result.Error != nil {
 		logger.Error("Error getting goal: %v", result.Error)
 		return nil, errors.New("error getting goal")
@@ -37,15 +59,15 @@ func getGoalByID(userID string, goalID string) (*models.Goal, error) {
 	return &goal, nil
 }
 
-func GetGoals(userID string, includeDeleted bool) ([]models.Goal, error) {
+func (s *Services) GetGoals(userID string, includeDeleted bool) ([]models.Goal, error) {
 	var goals []models.Goal
-	query := db.DB.Where("user_id = ?", userID)
+	query := s.db.Where("user_id = ?", userID)
 
 	if !includeDeleted {
 		query = query.Where("status = ?", models.StatusActive)
 	}
 
-	result := query.Find(&goals)
+	result := query.Order("priority ASC, created_at ASC").Find(&goals)
 
 	if result.Error != nil {
 		logger.Error("Error getting goals: %v", result.Error)
@@ -55,9 +77,27 @@ func GetGoals(userID string, includeDeleted bool) ([]models.Goal, error) {
 	return goals, nil
 }
 
-func updateGoal(userID string, goalID string, updates models.Goal) (*models.Goal, error) {
+// ListGoals gets a page of the goals of the user in funding order. statuses limits it to goals
+// in those statuses; nil lists them all
+func (s *Services) ListGoals(userID string, statuses []models.Status, page PageRequest) ([]models.Goal, PageInfo, error) {
+	var goals []models.Goal
+	query := s.db.Model(&models.Goal{}).Where("user_id = ?", userID)
+	if statuses != nil {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	info, err := paginate(query, "priority ASC, created_at ASC, id", page, &goals)
+	if err != nil {
+		logger.Error("Error getting goals: %v", err)
+		return nil, PageInfo{}, err
+	}
+
+	return goals, info, nil
+}
+
+func (s *Services) updateGoal(userID string, goalID string, updates models.Goal) (*models.Goal, error) {
 	// Verificar que el goal existe y pertenece al usuario
-	existingGoal, err := getGoalByID(userID, goalID)
+	existingGoal, err := s.getGoalByID(userID, goalID)
 	if err != nil {
 		return nil, err
 	}
@@ -77,16 +117,38 @@ func updateGoal(userID string, goalID string, updates models.Goal) (*models.Goal
 	if updates.SavedAmount >= 0 {
 		updateData["saved_amount"] = updates.SavedAmount
 	}
+	if updates.APY != nil {
+		if *updates.APY > 0 {
+			updateData["apy"] = *updates.APY
+			if existingGoal.APY == nil {
+				updateData["interest_through"] = lastMonthEnd(s.UserNow(userID))
+			}
+		} else {
+			// An APY of 0 stops the interest
+			updateData["apy"] = nil
+			updateData["interest_through"] = nil
+		}
+	}
 
-	// Actualizar en la base de datos
-	result := db.DB.Model(existingGoal).Updates(updateData)
-	if result.Error != nil {
-		logger.Error("Error updating goal: %v", result.Error)
+	// Actualizar en la base de datos y registrar los milestones alcanzados en la misma transacción
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(existingGoal).Updates(updateData).Error; err != nil {
+			return err
+		}
+
+		var goal models.Goal
+		if err := tx.Where("id = ?", existingGoal.ID).First(&goal).Error; err != nil {
+			return err
+		}
+		return checkGoalMilestones(tx, &goal)
+	})
+	if err != nil {
+		logger.Error("Error updating goal: %v", err)
 		return nil, errors.New("error updating goal")
 	}
 
 	// Obtener el goal actualizado
-	updatedGoal, err := getGoalByID(userID, goalID)
+	updatedGoal, err := s.getGoalByID(userID, goalID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,16 +156,16 @@ func updateGoal(userID string, goalID string, updates models.Goal) (*models.Goal
 	return updatedGoal, nil
 }
 
-func deleteGoal(userID string, goalID string) error {
+func (s *Services) deleteGoal(userID string, goalID string) error {
 	// Verificar que el goal existe y pertenece al usuario
-	existingGoal, err := getGoalByID(userID, goalID)
+	existingGoal, err := s.getGoalByID(userID, goalID)
 	if err != nil {
 		return err
 	}
 
 	// Soft delete - cambiar status a deleted
 	now := time.Now()
-	result := db.DB.Model(existingGoal).Updates(map[string]interface{}{
+	result := s.db.Model(existingGoal).Updates(map[string]interface{}{
 		"status":             models.StatusDeleted,
 		"status_changed_at": &now,
 		"updated_at":        now,
@@ -117,10 +179,10 @@ func deleteGoal(userID string, goalID string) error {
 	return nil
 }
 
-func restoreGoal(userID string, goalID string) (*models.Goal, error) {
+func (s *Services) restoreGoal(userID string, goalID string) (*models.Goal, error) {
 	// Verificar que el goal existe y pertenece al usuario
 	var goal models.Goal
-	result := db.DB.Where("user_id = ? AND id = ?", userID, goalID).First(&goal)
+	result := s.db.Where("user_id = ? AND id = ?", userID, goalID).First(&goal)
 	if result.Error != nil {
 		logger.Error("Error finding goal to restore: %v", result.Error)
 		return nil, errors.New("goal not found")
@@ -128,7 +190,7 @@ func restoreGoal(userID string, goalID string) (*models.Goal, error) {
 
 	// Restaurar - cambiar status a active
 	now := time.Now()
-	result = db.DB.Model(&goal).Updates(map[string]interface{}{
+	result = s.db.Model(&goal).Updates(map[string]interface{}{
 		"status":             models.StatusActive,
 		"status_changed_at": &now,
 		"updated_at":        now,
@@ -142,16 +204,22 @@ func restoreGoal(userID string, goalID string) (*models.Goal, error) {
 	return &goal, nil
 }
 
-func changeGoalStatus(userID string, goalID string, newStatus models.Status) (*models.Goal, error) {
+func (s *Services) changeGoalStatus(userID string, goalID string, newStatus models.Status) (*models.Goal, error) {
 	// Verificar que el goal existe y pertenece al usuario
-	existingGoal, err := getGoalByID(userID, goalID)
+	existingGoal, err := s.getGoalByID(userID, goalID)
 	if err != nil {
 		return nil, err
 	}
+	if existingGoal.Status == newStatus {
+		return existingGoal, nil
+	}
+	if _, err := checkStatusTransition(models.GoalStatusMachine, existingGoal.Status, newStatus); err != nil {
+		return nil, err
+	}
 
 	// Actualizar status
 	now := time.Now()
-	result := db.DB.Model(existingGoal).Updates(map[string]interface{}{
+	result := s.db.Model(existingGoal).Updates(map[string]interface{}{
 		"status":             newStatus,
 		"status_changed_at": &now,
 		"updated_at":        now,
@@ -163,7 +231,7 @@ func changeGoalStatus(userID string, goalID string, newStatus models.Status) (*m
 	}
 
 	// Obtener el goal actualizado
-	updatedGoal, err := getGoalByID(userID, goalID)
+	updatedGoal, err := s.getGoalByID(userID, goalID)
 	if err != nil {
 		return nil, err
 	}
@@ -172,26 +240,26 @@ func changeGoalStatus(userID string, goalID string, newStatus models.Status) (*m
 }
 
 // Funciones públicas (exportadas)
-func CreateGoal(userID string, goal models.Goal) (*models.Goal, error) {
-	return createGoal(userID, goal)
+func (s *Services) CreateGoal(userID string, goal models.Goal) (*models.Goal, error) {
+	return s.createGoal(userID, goal)
 }
 
-func GetGoalByID(userID string, goalID string) (*models.Goal, error) {
-	return getGoalByID(userID, goalID)
+func (s *Services) GetGoalByID(userID string, goalID string) (*models.Goal, error) {
+	return s.getGoalByID(userID, goalID)
 }
 
-func UpdateGoal(userID string, goalID string, updates models.Goal) (*models.Goal, error) {
-	return updateGoal(userID, goalID, updates)
+func (s *Services) UpdateGoal(userID string, goalID string, updates models.Goal) (*models.Goal, error) {
+	return s.updateGoal(userID, goalID, updates)
 }
 
-func DeleteGoal(userID string, goalID string) error {
-	return deleteGoal(userID, goalID)
+func (s *Services) DeleteGoal(userID string, goalID string) error {
+	return s.deleteGoal(userID, goalID)
 }
 
-func RestoreGoal(userID string, goalID string) (*models.Goal, error) {
-	return restoreGoal(userID, goalID)
+func (s *Services) RestoreGoal(userID string, goalID string) (*models.Goal, error) {
+	return s.restoreGoal(userID, goalID)
 }
 
-func ChangeGoalStatus(userID string, goalID string, newStatus models.Status) (*models.Goal, error) {
-	return changeGoalStatus(userID, goalID, newStatus)
+func (s *Services) ChangeGoalStatus(userID string, goalID string, newStatus models.Status) (*models.Goal, error) {
+	return s.changeGoalStatus(userID, goalID, newStatus)
 }
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventGoalFunded is emitted for every goal that receives money from the waterfall
+const EventGoalFunded = "goal.funded"
+
+// Where the money funding the waterfall comes from
+const (
+	GoalFundingManual  = "manual"
+	GoalFundingSweep   = "sweep"
+	GoalFundingRoundUp = "round_up"
+)
+
+// ErrGoalWaterfallDisabled is returned when an automatic source tries to fund goals for a user
+// that hasn't turned the waterfall on
+var ErrGoalWaterfallDisabled = errors.New("goal funding waterfall is disabled")
+
+func validGoalFundingSource(source string) bool {
+	switch source {
+	case GoalFundingManual, GoalFundingSweep, GoalFundingRoundUp:
+		return true
+	}
+	return false
+}
+
+// GetGoalWaterfallEnabled reports whether sweeps and round-ups go through the waterfall
+func (s *Services) GetGoalWaterfallEnabled(userID string) (bool, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return false, errors.New("error getting goal waterfall preference")
+	}
+	return preferences.GoalWaterfall, nil
+}
+
+// SetGoalWaterfallEnabled turns the funding waterfall on or off for automatic sources
+func (s *Services) SetGoalWaterfallEnabled(userID string, enabled bool) error {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return errors.New("error updating goal waterfall preference")
+	}
+
+	preferences.GoalWaterfall = enabled
+	if err := s.saveUserPreferences(preferences, "goal_waterfall"); err != nil {
+		logger.Error("Error saving goal waterfall preference: %v", err)
+		return errors.New("error updating goal waterfall preference")
+	}
+	return nil
+}
+
+// ReorderGoalPriorities sets the funding order of the user's active goals. The listed goals
+// come first in the given order; the ones left out keep their relative order after them
+func (s *Services) ReorderGoalPriorities(userID string, goalIDs []string) ([]models.Goal, error) {
+	if len(goalIDs) == 0 {
+		return nil, errors.New("at least one goal ID is required")
+	}
+
+	goals, err := s.GetGoals(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Goal, len(goals))
+	for _, goal := range goals {
+		byID[goal.ID.String()] = goal
+	}
+
+	ordered := make([]models.Goal, 0, len(goals))
+	listed := make(map[string]bool, len(goalIDs))
+	for _, raw := range goalIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("invalid goal ID: " + raw)
+		}
+		goal, ok := byID[id.String()]
+		if !ok {
+			return nil, errors.New("goal not found: " + raw)
+		}
+		if listed[id.String()] {
+			return nil, errors.New("invalid goal order: duplicated goal " + raw)
+		}
+		listed[id.String()] = true
+		ordered = append(ordered, goal)
+	}
+	for _, goal := range goals {
+		if !listed[goal.ID.String()] {
+			ordered = append(ordered, goal)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i, goal := range ordered {
+			if err := tx.Model(&models.Goal{}).Where("id = ?", goal.ID).Update("priority", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error reordering goal priorities: %v", err)
+		return nil, errors.New("error updating goal priorities")
+	}
+
+	return s.GetGoals(userID, false)
+}
+
+// planGoalWaterfall pours the amount into the goals, which must already be in priority order
+func planGoalWaterfall(goals []models.Goal, amount models.Money, currency *models.Currency) *dto.GoalWaterfall {
+	plan := &dto.GoalWaterfall{
+		Amount:      currency.RoundMoney(amount),
+		Allocations: []dto.GoalWaterfallAllocation{},
+	}
+
+	left := plan.Amount
+	for _, goal := range goals {
+		if left <= 0 {
+			break
+		}
+		remaining := currency.RoundMoney(goal.TotalAmount - goal.SavedAmount)
+		if remaining <= 0 {
+			continue // Already complete
+		}
+
+		share := remaining
+		if left < share {
+			share = left
+		}
+		left = currency.RoundMoney(left - share)
+		plan.Allocated = currency.RoundMoney(plan.Allocated + share)
+		plan.Allocations = append(plan.Allocations, dto.GoalWaterfallAllocation{
+			GoalID:     goal.ID.String(),
+			Name:       goal.Name,
+			Priority:   goal.Priority,
+			Remaining:  remaining,
+			Amount:     share,
+			SavedAfter: currency.RoundMoney(goal.SavedAmount + share),
+			Completes:  share == remaining,
+		})
+	}
+	plan.Unallocated = left
+	return plan
+}
+
+// PreviewGoalWaterfall shows how an amount would be split across the goals without saving anything
+func (s *Services) PreviewGoalWaterfall(userID string, amount models.Money) (*dto.GoalWaterfall, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	goals, err := s.GetGoals(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planGoalWaterfall(goals, amount, s.GetUserCurrency(userID))
+	plan.Source = GoalFundingManual
+	return plan, nil
+}
+
+// FundGoalsWaterfall adds the amount to the goals in priority order until each is complete.
+// Sweeps and round-ups only go through here when the user has enabled the waterfall
+func (s *Services) FundGoalsWaterfall(userID string, amount models.Money, source string) (*dto.GoalWaterfall, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+	if source == "" {
+		source = GoalFundingManual
+	}
+	if !validGoalFundingSource(source) {
+		return nil, errors.New("invalid funding source: must be manual, sweep or round_up")
+	}
+	if source != GoalFundingManual {
+		enabled, err := s.GetGoalWaterfallEnabled(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, ErrGoalWaterfallDisabled
+		}
+	}
+
+	currency := s.GetUserCurrency(userID)
+	today := s.UserToday(userID)
+	var plan *dto.GoalWaterfall
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the goals so two fundings at once can't overfill the same goal
+		var goals []models.Goal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND status = ?", userID, models.StatusActive).
+			Order("priority ASC, created_at ASC").Find(&goals).Error; err != nil {
+			return err
+		}
+
+		plan = planGoalWaterfall(goals, amount, currency)
+		plan.Source = source
+		for _, allocation := range plan.Allocations {
+			var goal models.Goal
+			if err := tx.Model(&models.Goal{}).Where("id = ?", allocation.GoalID).
+				Update("saved_amount", allocation.SavedAfter).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id = ?", allocation.GoalID).First(&goal).Error; err != nil {
+				return err
+			}
+			if err := recordGoalContribution(tx, &goal, allocation.Amount, source, today); err != nil {
+				return err
+			}
+			if err := checkGoalMilestones(tx, &goal); err != nil {
+				return err
+			}
+
+			payload := map[string]interface{}{
+				"goal_id":      goal.ID,
+				"goal_name":    goal.Name,
+				"source":       source,
+				"amount":       allocation.Amount,
+				"saved_amount": goal.SavedAmount,
+				"total_amount": goal.TotalAmount,
+				"completed":    allocation.Completes,
+			}
+			if err := EnqueueEvent(tx, goal.UserID, EventGoalFunded, "goal", goal.ID, payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error funding goals: %v", err)
+		return nil, errors.New("error funding goals")
+	}
+
+	plan.Applied = true
+	logger.Info("Waterfall funded %d goals of user %s with %s from %s", len(plan.Allocations), userID, plan.Allocated, source)
+	return plan, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/db"
+)
+
+// shuttingDown is set once the server starts draining, so load balancers stop sending traffic
+// before the listener closes
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes the instance report itself as not ready
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// CheckReadiness reports whether the instance can serve traffic: it is not shutting down and
+// Postgres answers
+func (s *Services) CheckReadiness(ctx context.Context) error {
+	if shuttingDown.Load() {
+		return errors.New("shutting down")
+	}
+	if err := db.Ping(ctx, s.db); err != nil {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+// ShutdownTimeout is how long in-flight requests and job runs get to finish on shutdown
+// (SHUTDOWN_TIMEOUT_SECONDS, 30 by default); keep it below the pod's termination grace period
+func ShutdownTimeout() time.Duration {
+	return time.Duration(envInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second
+}
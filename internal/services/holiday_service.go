@@ -0,0 +1,267 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// MaxUpcomingHolidayDays caps the window of the upcoming holidays
+const MaxUpcomingHolidayDays = 365
+
+// holidayRule dates a holiday every year: a fixed month and day, the nth weekday of a month
+// (nth -1 for the last one) or a number of days from Easter Sunday
+type holidayRule struct {
+	Name         string `json:"name"`
+	Month        int    `json:"month"`
+	Day          int    `json:"day"`
+	Weekday      int    `json:"weekday"` // 0 = Sunday
+	Nth          int    `json:"nth"`
+	EasterOffset *int   `json:"easter_offset"`
+}
+
+type holidayCalendar struct {
+	Name     string        `json:"name"`
+	Holidays []holidayRule `json:"holidays"`
+}
+
+// holiday_calendars.json holds the national bank holidays of each supported country. Days
+// moved by decree or substitute days aren't covered
+//
+//go:embed holiday_calendars.json
+var holidayCalendarsJSON []byte
+
+var (
+	holidayCalendarsOnce sync.Once
+	holidayCalendars     map[string]holidayCalendar
+
+	holidayYearsMu sync.Mutex
+	holidayYears   = make(map[string]map[string]string) // country/year -> YYYY-MM-DD -> name
+)
+
+func getHolidayCalendars() map[string]holidayCalendar {
+	holidayCalendarsOnce.Do(func() {
+		if err := json.Unmarshal(holidayCalendarsJSON, &holidayCalendars); err != nil {
+			logger.Error("Error loading holiday calendars: %v", err)
+			holidayCalendars = map[string]holidayCalendar{}
+		}
+	})
+	return holidayCalendars
+}
+
+// HolidayCalendars lists the countries whose holidays are known, sorted by code
+func HolidayCalendars() []dto.HolidayCalendar {
+	calendars := []dto.HolidayCalendar{}
+	for country, calendar := range getHolidayCalendars() {
+		calendars = append(calendars, dto.HolidayCalendar{Country: country, Name: calendar.Name})
+	}
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].Country < calendars[j].Country })
+	return calendars
+}
+
+// easterSunday computes the date of Easter Sunday in the Gregorian calendar
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func (rule holidayRule) date(year int) time.Time {
+	if rule.EasterOffset != nil {
+		return easterSunday(year).AddDate(0, 0, *rule.EasterOffset)
+	}
+	if rule.Nth == 0 {
+		return time.Date(year, time.Month(rule.Month), rule.Day, 0, 0, 0, 0, time.UTC)
+	}
+	if rule.Nth < 0 {
+		last := time.Date(year, time.Month(rule.Month)+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.AddDate(0, 0, -((int(last.Weekday()) - rule.Weekday + 7) % 7))
+	}
+	first := time.Date(year, time.Month(rule.Month), 1, 0, 0, 0, 0, time.UTC)
+	offset := (rule.Weekday - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(rule.Nth-1))
+}
+
+// holidaysInYear returns the holidays of a country in a year by date (YYYY-MM-DD)
+func holidaysInYear(country string, year int) map[string]string {
+	key := country + "/" + strconv.Itoa(year)
+	holidayYearsMu.Lock()
+	defer holidayYearsMu.Unlock()
+	if holidays, ok := holidayYears[key]; ok {
+		return holidays
+	}
+
+	holidays := make(map[string]string)
+	for _, rule := range getHolidayCalendars()[country].Holidays {
+		holidays[rule.date(year).Format("2006-01-02")] = rule.Name
+	}
+	holidayYears[key] = holidays
+	return holidays
+}
+
+// holidayName returns the name of the holiday on a date, or "" if it isn't one
+func holidayName(country string, date time.Time) string {
+	if country == "" {
+		return ""
+	}
+	return holidaysInYear(country, date.Year())[date.Format("2006-01-02")]
+}
+
+// IsBusinessDay tells if banks open on a date: not a weekend nor a holiday of the country
+func IsBusinessDay(country string, date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return holidayName(country, date) == ""
+}
+
+// NextBusinessDay returns the date itself if it is a business day, or the first one after it
+func NextBusinessDay(country string, date time.Time) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for !IsBusinessDay(country, day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// getHolidayCountries returns the holiday calendar of each user, "" when none is set
+func (s *Services) getHolidayCountries(userIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	if len(userIDs) == 0 {
+		return map[uuid.UUID]string{}, nil
+	}
+	var preferences []models.UserPreferences
+	if err := s.db.Select("user_id, holiday_country").Where("user_id IN ?", userIDs).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	countries := make(map[uuid.UUID]string, len(preferences))
+	for _, preference := range preferences {
+		countries[preference.UserID] = preference.HolidayCountry
+	}
+	return countries, nil
+}
+
+// GetHolidaySettings returns the holiday calendar of the user and the available ones
+func (s *Services) GetHolidaySettings(userID string) (*dto.HolidaySettings, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error getting holiday settings")
+	}
+	return &dto.HolidaySettings{Country: preferences.HolidayCountry, Calendars: HolidayCalendars()}, nil
+}
+
+// SetHolidayCountry sets the holiday calendar scheduled items of the user follow; empty
+// leaves only weekends as non-business days
+func (s *Services) SetHolidayCountry(userID string, country string) (*dto.HolidaySettings, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if _, ok := getHolidayCalendars()[country]; country != "" && !ok {
+		return nil, errors.New("invalid country: no holiday calendar for " + country)
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error updating holiday settings")
+	}
+	preferences.HolidayCountry = country
+	if err := s.saveUserPreferences(preferences, "holiday_country"); err != nil {
+		logger.Error("Error saving holiday country: %v", err)
+		return nil, errors.New("error updating holiday settings")
+	}
+	return &dto.HolidaySettings{Country: country, Calendars: HolidayCalendars()}, nil
+}
+
+// GetUpcomingHolidays lists the holidays of the user's calendar in the next days and the
+// fixed expenses due on each of them
+func (s *Services) GetUpcomingHolidays(userID string, days int) (*dto.UpcomingHolidays, error) {
+	if days <= 0 || days > MaxUpcomingHolidayDays {
+		return nil, errors.New("invalid days: must be between 1 and 365")
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error getting upcoming holidays")
+	}
+	country := preferences.HolidayCountry
+
+	now := s.UserNow(userID)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, days)
+	result := &dto.UpcomingHolidays{
+		Country:  country,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Holidays: []dto.UpcomingHoliday{},
+	}
+	if country == "" {
+		return result, nil
+	}
+
+	var fixedExpenses []models.FixedExpense
+	if err := s.db.Where("user_id = ? AND status = ? AND is_recurring = ? AND next_due_date <= ?",
+		userID, models.StatusActive, true, to).Find(&fixedExpenses).Error; err != nil {
+		logger.Error("Error getting fixed expenses for holidays: %v", err)
+		return nil, errors.New("error getting upcoming holidays")
+	}
+
+	// Every occurrence of each fixed expense in the window, by due date
+	affected := make(map[string][]dto.HolidayAffectedItem)
+	for _, fixedExpense := range fixedExpenses {
+		occurrence := fixedExpense
+		for !occurrence.NextDueDate.After(to) {
+			due := occurrence.NextDueDate
+			if !due.Before(from) && holidayName(country, due) != "" {
+				processedOn := due
+				if fixedExpense.SkipHolidays {
+					processedOn = NextBusinessDay(country, due)
+				}
+				affected[due.Format("2006-01-02")] = append(affected[due.Format("2006-01-02")], dto.HolidayAffectedItem{
+					Type:         "fixed_expense",
+					ID:           fixedExpense.ID.String(),
+					Name:         fixedExpense.Name,
+					Amount:       fixedExpense.Amount,
+					DueDate:      due.Format("2006-01-02"),
+					ProcessedOn:  processedOn.Format("2006-01-02"),
+					SkipHolidays: fixedExpense.SkipHolidays,
+				})
+			}
+			occurrence.NextDueDate = calculateNextDueDate(&occurrence)
+		}
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		name := holidayName(country, day)
+		if name == "" {
+			continue
+		}
+		items := affected[day.Format("2006-01-02")]
+		if items == nil {
+			items = []dto.HolidayAffectedItem{}
+		}
+		result.Holidays = append(result.Holidays, dto.UpcomingHoliday{
+			Date:          day.Format("2006-01-02"),
+			Name:          name,
+			AffectedItems: items,
+		})
+		result.AffectedCount += len(items)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,553 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Strategies to resolve an import match
+const (
+	ImportResolveKeepMine     = "keep-mine"     // Keep the manual expense as is
+	ImportResolveKeepImported = "keep-imported" // Overwrite the manual expense with the imported values
+	ImportResolveMergeFields  = "merge-fields"  // Pick the source of each field
+)
+
+// Fields an import resolution can take from either side
+const (
+	ImportFieldAmount      = "amount"
+	ImportFieldDate        = "date"
+	ImportFieldDescription = "description"
+)
+
+var importFields = []string{ImportFieldAmount, ImportFieldDate, ImportFieldDescription}
+
+const (
+	// MaxImportTransactions limits the rows of one batch
+	MaxImportTransactions = 1000
+	// importMatchWindowDays is how far apart the dates of a match can be
+	importMatchWindowDays = 3
+)
+
+var ErrImportMatchResolved = errors.New("import match already resolved")
+
+// ImportTransactionInput is one bank transaction to import
+type ImportTransactionInput struct {
+	Date        time.Time
+	Amount      models.Money
+	Description *string
+	ExternalID  *string
+	Tags        []string // Given to the records created
+}
+
+// findImportMatch looks for an active manual expense on the same account with the same amount
+// a few days around the imported date, that no other import already claimed
+func (s *Services) findImportMatch(userID string, accountID uuid.UUID, currency *models.Currency, tx ImportTransactionInput) (*models.Expense, float64, error) {
+	var candidates []models.Expense
+	err := s.db.Where("user_id = ? AND bank_account_id = ? AND status IN ? AND date BETWEEN ? AND ?",
+		userID, accountID, models.GetActiveStatuses(),
+		tx.Date.AddDate(0, 0, -importMatchWindowDays), tx.Date.AddDate(0, 0, importMatchWindowDays)).
+		Where("amount = ?", currency.RoundMoney(tx.Amount)).
+		Where("id NOT IN (?)", s.db.Model(&models.ImportedTransaction{}).Select("expense_id").Where("expense_id IS NOT NULL")).
+		Where("id NOT IN (?)", s.db.Model(&models.ImportMatch{}).Select("expense_id").Where("resolved_at IS NULL")).
+		Find(&candidates).Error
+	if err != nil || len(candidates) == 0 {
+		return nil, 0, err
+	}
+
+	var best *models.Expense
+	bestDays := math.MaxFloat64
+	for i := range candidates {
+		days := math.Abs(candidates[i].Date.Sub(tx.Date).Hours() / 24)
+		if days < bestDays {
+			best, bestDays = &candidates[i], days
+		}
+	}
+	// Same day is a near-certain match, every day apart lowers the confidence
+	score := 1 - bestDays*0.15
+	if tx.Description != nil && best.Description != nil &&
+		strings.Contains(strings.ToLower(*tx.Description), strings.ToLower(strings.TrimSpace(*best.Description))) {
+		score = math.Min(1, score+0.1)
+	}
+	return best, math.Round(score*100) / 100, nil
+}
+
+// importedExpenseDescription is the description an imported transaction gives its expense:
+// the merchant name when enrichment recognized one, the raw bank descriptor otherwise
+func importedExpenseDescription(imported *models.ImportedTransaction) *string {
+	if imported.MerchantName != nil {
+		return imported.MerchantName
+	}
+	return imported.Description
+}
+
+// merchantCategoryID finds the user's active category named like the merchant's suggested
+// category. Lookups are memoized per batch in known
+func (s *Services) merchantCategoryID(userID string, merchant *MerchantInfo, known map[string]*uuid.UUID) *uuid.UUID {
+	if merchant == nil || merchant.Category == nil {
+		return nil
+	}
+	key := strings.ToLower(strings.TrimSpace(*merchant.Category))
+	if id, ok := known[key]; ok {
+		return id
+	}
+
+	var category models.Category
+	if err := s.db.Where("user_id = ? AND LOWER(name) = ? AND status IN ?", userID, key, models.GetActiveStatuses()).
+		First(&category).Error; err != nil {
+		known[key] = nil
+		return nil
+	}
+	known[key] = &category.ID
+	return &category.ID
+}
+
+// setExpenseProvenance records that the given fields of an expense now come from an import
+func setExpenseProvenance(tx *gorm.DB, expenseID uuid.UUID, importedID uuid.UUID, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	rows := make([]models.ExpenseFieldProvenance, 0, len(fields))
+	for _, field := range fields {
+		rows = append(rows, models.ExpenseFieldProvenance{
+			ExpenseID:             expenseID,
+			Field:                 field,
+			Source:                models.ProvenanceImport,
+			ImportedTransactionID: &importedID,
+			UpdatedAt:             time.Now(),
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "expense_id"}, {Name: "field"}},
+		DoUpdates: clause.AssignmentColumns([]string{"source", "imported_transaction_id", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+// clearChangedProvenance drops the import provenance of the fields an edit changed, since
+// their values are now the user's
+func clearChangedProvenance(tx *gorm.DB, before *models.Expense, after *models.Expense) error {
+	var changed []string
+	if before.Amount != after.Amount {
+		changed = append(changed, ImportFieldAmount)
+	}
+	if !before.Date.Equal(after.Date) {
+		changed = append(changed, ImportFieldDate)
+	}
+	if (before.Description == nil) != (after.Description == nil) ||
+		(before.Description != nil && *before.Description != *after.Description) {
+		changed = append(changed, ImportFieldDescription)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return tx.Where("expense_id = ? AND field IN ?", before.ID, changed).Delete(&models.ExpenseFieldProvenance{}).Error
+}
+
+// importRun turns the transactions of a batch into expenses and incomes
+type importRun struct {
+	userID             string
+	batch              *models.ImportBatch
+	account            *models.BankAccount
+	currency           *models.Currency
+	merchantCategories map[string]*uuid.UUID // Memoized merchantCategoryID lookups
+	services           *Services
+}
+
+func (s *Services) newImportRun(userID string, batch *models.ImportBatch, account *models.BankAccount) *importRun {
+	return &importRun{
+		userID:             userID,
+		batch:              batch,
+		account:            account,
+		currency:           s.GetUserCurrency(userID),
+		merchantCategories: make(map[string]*uuid.UUID),
+		services:           s,
+	}
+}
+
+// previousImport returns the transaction with the same external ID already imported into the
+// account, if any. Re-uploading an overlapping statement must not create the same records twice
+func (run *importRun) previousImport(externalID *string) (*models.ImportedTransaction, error) {
+	if externalID == nil {
+		return nil, nil
+	}
+	var previous models.ImportedTransaction
+	err := run.services.db.Joins("JOIN import_batches b ON b.id = imported_transactions.batch_id").
+		Where("b.bank_account_id = ? AND imported_transactions.external_id = ? AND imported_transactions.status <> ?",
+			run.account.ID, *externalID, models.ImportedFailed).
+		First(&previous).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+func (run *importRun) newImportedTransaction(input ImportTransactionInput) models.ImportedTransaction {
+	return models.ImportedTransaction{
+		BatchID:     run.batch.ID,
+		UserID:      run.batch.UserID,
+		Date:        input.Date,
+		Amount:      run.currency.RoundMoney(input.Amount),
+		Description: input.Description,
+		ExternalID:  input.ExternalID,
+	}
+}
+
+func namedTags(names []string) []models.Tag {
+	tags := make([]models.Tag, len(names))
+	for i, name := range names {
+		tags[i] = models.Tag{Name: name}
+	}
+	return tags
+}
+
+// importExpense imports money spent: a likely duplicate of a manual expense is held as a match,
+// anything else becomes a new expense
+func (run *importRun) importExpense(ctx context.Context, input ImportTransactionInput) error {
+	imported := run.newImportedTransaction(input)
+	input.Amount = imported.Amount
+
+	var merchant *MerchantInfo
+	if input.Description != nil {
+		merchant = EnrichMerchant(*input.Description)
+	}
+	if merchant != nil {
+		provider := merchant.Provider
+		imported.MerchantName = &merchant.Name
+		imported.MerchantLogoURL = merchant.LogoURL
+		imported.MerchantProvider = &provider
+	}
+
+	previous, err := run.previousImport(input.ExternalID)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		imported.Status = models.ImportedDuplicate
+		imported.ExpenseID = previous.ExpenseID
+		return run.services.db.Create(&imported).Error
+	}
+
+	match, score, err := run.services.findImportMatch(run.userID, run.account.ID, run.currency, input)
+	if err != nil {
+		return err
+	}
+
+	if match != nil {
+		imported.Status = models.ImportedMatched
+		return run.services.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&imported).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.ImportMatch{
+				BatchID:               run.batch.ID,
+				UserID:                run.batch.UserID,
+				ImportedTransactionID: imported.ID,
+				ExpenseID:             match.ID,
+				Score:                 score,
+			}).Error
+		})
+	}
+
+	imported.Status = models.ImportedCreated
+	expense := models.Expense{
+		CategoryID:    run.batch.CategoryID,
+		BankAccountID: run.account.ID,
+		Amount:        imported.Amount,
+		Date:          imported.Date,
+		Description:   importedExpenseDescription(&imported),
+		Tags:          namedTags(input.Tags),
+	}
+	// The merchant's usual category wins over the batch default when the user has it
+	if merchantCategory := run.services.merchantCategoryID(run.userID, merchant, run.merchantCategories); merchantCategory != nil {
+		expense.CategoryID = *merchantCategory
+	}
+	if createErr := run.services.Expenses.Create(ctx, run.userID, &expense, false); createErr != nil {
+		message := createErr.Error()
+		imported.Status = models.ImportedFailed
+		imported.Error = &message
+	} else {
+		imported.ExpenseID = &expense.ID
+	}
+	return run.services.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&imported).Error; err != nil {
+			return err
+		}
+		if imported.ExpenseID == nil {
+			return nil
+		}
+		return setExpenseProvenance(tx, expense.ID, imported.ID, importFields)
+	})
+}
+
+// importIncome imports money coming in, given as a positive amount, as a new income
+func (run *importRun) importIncome(input ImportTransactionInput) error {
+	imported := run.newImportedTransaction(input)
+
+	previous, err := run.previousImport(input.ExternalID)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		imported.Status = models.ImportedDuplicate
+		imported.IncomeID = previous.IncomeID
+		return run.services.db.Create(&imported).Error
+	}
+
+	imported.Status = models.ImportedCreated
+	income := models.Income{
+		BankAccountID: &run.account.ID,
+		Amount:        imported.Amount,
+		Date:          imported.Date,
+		Tags:          namedTags(input.Tags),
+	}
+	if createErr := run.services.CreateIncome(run.userID, &income); createErr != nil {
+		message := createErr.Error()
+		imported.Status = models.ImportedFailed
+		imported.Error = &message
+	} else {
+		imported.IncomeID = &income.ID
+	}
+	return run.services.db.Create(&imported).Error
+}
+
+// CreateImportBatch imports bank transactions into an account. Rows that look like an existing
+// manual expense are held as matches for the user to resolve; the rest become new expenses
+func (s *Services) CreateImportBatch(ctx context.Context, userID string, bankAccountID string, categoryID string, source *string, transactions []ImportTransactionInput) (*dto.ImportBatch, error) {
+	if len(transactions) == 0 {
+		return nil, errors.New("at least one transaction is required")
+	}
+	if len(transactions) > MaxImportTransactions {
+		return nil, errors.New("too many transactions, the maximum per batch is 1000")
+	}
+	for _, transaction := range transactions {
+		if transaction.Amount <= 0 {
+			return nil, errors.New("transaction amounts must be positive")
+		}
+		if transaction.Date.IsZero() {
+			return nil, errors.New("transaction date is required")
+		}
+	}
+
+	account, err := s.GetBankAccountByID(userID, bankAccountID)
+	if err != nil {
+		return nil, errors.New("bank account not found")
+	}
+	var category models.Category
+	if err := s.db.Where("id = ? AND user_id = ? AND status IN ?", categoryID, userID, models.GetActiveStatuses()).First(&category).Error; err != nil {
+		return nil, errors.New("category not found or not active")
+	}
+
+	batch := models.ImportBatch{
+		UserID:        uuid.MustParse(userID),
+		BankAccountID: account.ID,
+		CategoryID:    category.ID,
+		Source:        source,
+	}
+	if err := s.db.Create(&batch).Error; err != nil {
+		logger.Error("Error creating import batch: %v", err)
+		return nil, errors.New("error creating import batch")
+	}
+
+	run := s.newImportRun(userID, &batch, account)
+	for _, input := range transactions {
+		if err := run.importExpense(ctx, input); err != nil {
+			logger.Error("Error storing imported transaction: %v", err)
+			return nil, errors.New("error creating import batch")
+		}
+	}
+
+	logger.Info("Import batch %s created with %d transactions", batch.ID, len(transactions))
+	return s.GetImportBatch(userID, batch.ID.String())
+}
+
+// GetImportBatch returns a batch with its transactions and matches
+func (s *Services) GetImportBatch(userID string, batchID string) (*dto.ImportBatch, error) {
+	var batch models.ImportBatch
+	if err := s.db.Where("id = ? AND user_id = ?", batchID, userID).First(&batch).Error; err != nil {
+		return nil, errors.New("import batch not found")
+	}
+
+	var transactions []models.ImportedTransaction
+	if err := s.db.Where("batch_id = ?", batch.ID).Order("date ASC, created_at ASC").Find(&transactions).Error; err != nil {
+		logger.Error("Error getting imported transactions: %v", err)
+		return nil, errors.New("error getting import batch")
+	}
+	var matches []models.ImportMatch
+	if err := s.db.Where("batch_id = ?", batch.ID).Order("created_at ASC").Find(&matches).Error; err != nil {
+		logger.Error("Error getting import matches: %v", err)
+		return nil, errors.New("error getting import batch")
+	}
+
+	result := &dto.ImportBatch{
+		ID:            batch.ID.String(),
+		BankAccountID: batch.BankAccountID.String(),
+		Source:        batch.Source,
+		Transactions:  transactions,
+		Matches:       matches,
+		CreatedAt:     batch.CreatedAt,
+	}
+	for _, transaction := range transactions {
+		switch transaction.Status {
+		case models.ImportedCreated:
+			result.Created++
+		case models.ImportedFailed:
+			result.Failed++
+		case models.ImportedDuplicate:
+			result.Duplicates++
+		}
+	}
+	for _, match := range matches {
+		if match.ResolvedAt == nil {
+			result.PendingMatches++
+		}
+	}
+	return result, nil
+}
+
+// ResolveImportMatch settles a match between an imported transaction and a manual expense.
+// For merge-fields, fields maps amount, date and description to "mine" or "imported";
+// fields left out keep the manual value
+func (s *Services) ResolveImportMatch(userID string, batchID string, matchID string, strategy string, fields map[string]string) (*dto.ImportMatchResolution, error) {
+	var match models.ImportMatch
+	if err := s.db.Where("id = ? AND batch_id = ? AND user_id = ?", matchID, batchID, userID).First(&match).Error; err != nil {
+		return nil, errors.New("import match not found")
+	}
+	if match.ResolvedAt != nil {
+		return nil, ErrImportMatchResolved
+	}
+
+	var imported models.ImportedTransaction
+	if err := s.db.Where("id = ?", match.ImportedTransactionID).First(&imported).Error; err != nil {
+		return nil, errors.New("imported transaction not found")
+	}
+
+	var takeImported []string
+	switch strategy {
+	case ImportResolveKeepMine:
+	case ImportResolveKeepImported:
+		takeImported = importFields
+	case ImportResolveMergeFields:
+		for field, source := range fields {
+			if field != ImportFieldAmount && field != ImportFieldDate && field != ImportFieldDescription {
+				return nil, errors.New("invalid merge field: " + field)
+			}
+			switch source {
+			case "imported":
+				takeImported = append(takeImported, field)
+			case "mine":
+			default:
+				return nil, errors.New("invalid merge source for " + field + ". Must be mine or imported")
+			}
+		}
+	default:
+		return nil, errors.New("invalid strategy. Must be one of: keep-mine, keep-imported, merge-fields")
+	}
+
+	expense, err := s.Expenses.GetByID(userID, match.ExpenseID.String())
+	if err != nil {
+		return nil, errors.New("matched expense not found")
+	}
+
+	// Amount and date go through the regular patch so balances and split rules are respected
+	if len(takeImported) > 0 {
+		patch := *expense
+		for _, field := range takeImported {
+			switch field {
+			case ImportFieldAmount:
+				patch.Amount = imported.Amount
+			case ImportFieldDate:
+				patch.Date = imported.Date
+			case ImportFieldDescription:
+				patch.Description = importedExpenseDescription(&imported)
+			}
+		}
+		patched, err := s.Expenses.Patch(userID, expense.ID.String(), &patch)
+		if err != nil {
+			return nil, err
+		}
+		expense = patched
+	}
+
+	now := time.Now()
+	status := models.ImportedMerged
+	if len(takeImported) == 0 {
+		status = models.ImportedDuplicate
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ImportMatch{}).Where("id = ? AND resolved_at IS NULL", match.ID).
+			Updates(map[string]interface{}{"strategy": strategy, "resolved_at": &now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrImportMatchResolved
+		}
+		if err := tx.Model(&imported).Updates(map[string]interface{}{"status": status, "expense_id": expense.ID}).Error; err != nil {
+			return err
+		}
+		return setExpenseProvenance(tx, expense.ID, imported.ID, takeImported)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrImportMatchResolved) {
+			logger.Error("Error resolving import match: %v", err)
+		}
+		return nil, err
+	}
+
+	provenance, err := s.GetExpenseProvenance(userID, expense.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Import match %s resolved with %s", match.ID, strategy)
+	return &dto.ImportMatchResolution{
+		MatchID:    match.ID.String(),
+		Strategy:   strategy,
+		Expense:    expense,
+		Provenance: provenance,
+	}, nil
+}
+
+// GetExpenseProvenance returns where each editable field of an expense came from
+func (s *Services) GetExpenseProvenance(userID string, expenseID string) ([]dto.FieldProvenance, error) {
+	expense, err := s.Expenses.GetByID(userID, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []models.ExpenseFieldProvenance
+	if err := s.db.Where("expense_id = ?", expense.ID).Find(&rows).Error; err != nil {
+		logger.Error("Error getting expense provenance: %v", err)
+		return nil, errors.New("error getting expense provenance")
+	}
+	byField := make(map[string]models.ExpenseFieldProvenance, len(rows))
+	for _, row := range rows {
+		byField[row.Field] = row
+	}
+
+	provenance := make([]dto.FieldProvenance, 0, len(importFields))
+	for _, field := range importFields {
+		entry := dto.FieldProvenance{Field: field, Source: models.ProvenanceManual}
+		if row, ok := byField[field]; ok {
+			entry.Source = row.Source
+			entry.UpdatedAt = &row.UpdatedAt
+			if row.ImportedTransactionID != nil {
+				id := row.ImportedTransactionID.String()
+				entry.ImportedTransactionID = &id
+			}
+		}
+		provenance = append(provenance, entry)
+	}
+	return provenance, nil
+}
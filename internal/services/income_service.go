@@ -4,31 +4,30 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func CreateIncome(userID string, income *models.Income) error {
+func (s *Services) CreateIncome(userID string, income *models.Income) error {
 	// Forzar el UserID y Status para que no puedan ser manipulados
 	income.UserID = uuid.MustParse(userID)
 	income.Status = models.StatusActive
 	
-	// Validate and verify that the bank account exists, is active and belongs to the user
-	var zeroUUID uuid.UUID
-	if income.BankAccountID == zeroUUID {
-		logger.Error("Bank account ID is required")
-		return errors.New("bank account ID is required")
+	// The bank account is optional; when given it must exist, be active and belong to the user
+	if income.BankAccountID != nil {
+		var bankAccount models.BankAccount
+		result := s.db.Where("id = ? AND user_id = ? AND status IN ?", 
+			*income.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
+		if result.Error != nil {
+			logger.Error("Bank account not found, not active, or doesn't belong to user")
+			return errors.New("bank account not found, not active, or access denied")
+		}
 	}
 	
-	var bankAccount models.BankAccount
-	result := db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
-		income.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
-	if result.Error != nil {
-		logger.Error("Bank account not found, not active, or doesn't belong to user")
-		return errors.New("bank account not found, not active, or access denied")
+	if err := resolveIncomeSource(income); err != nil {
+		return err
 	}
 	
 	// Verify that the amount is positive
@@ -37,27 +36,78 @@ func CreateIncome(userID string, income *models.Income) error {
 		return errors.New("income amount must be positive")
 	}
 	
-	result = db.DB.Create(income)
-	if result.Error != nil{
-		logger.Error("Error creating income: %v", result.Error)
-		return result.Error
+	// Refunds can never exceed what is left of the original expense
+	if income.RefundOfExpenseID != nil {
+		if err := s.validateRefund(userID, *income.RefundOfExpenseID, income.Amount, nil); err != nil {
+			return err
+		}
 	}
 	
-	// Add income to bank account balance
-	if err := db.DB.Model(&bankAccount).
-		Update("balance", gorm.Expr("balance + ?", income.Amount)).Error; err != nil {
-		logger.Error("Error updating bank account balance: %v", err)
-		return errors.New("error updating bank account balance")
+	// The income, the balance change and the domain event are committed together
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Tags are named freely; the missing ones are created for the user
+		if len(income.Tags) > 0 {
+			tags, err := resolveTags(tx, userID, tagNames(income.Tags))
+			if err != nil {
+				return err
+			}
+			income.Tags = tags
+		}
+		
+		if err := tx.Create(income).Error; err != nil {
+			logger.Error("Error creating income: %v", err)
+			return err
+		}
+		
+		// Add income to bank account balance
+		if err := applyIncomeLedger(tx, income, 1); err != nil {
+			return err
+		}
+		
+		return EnqueueEvent(tx, income.UserID, EventIncomeCreated, "income", income.ID, map[string]interface{}{
+			"bank_account_id":      income.BankAccountID,
+			"amount":               income.Amount,
+			"source":               income.Source,
+			"date":                 income.Date.Format("2006-01-02"),
+			"refund_of_expense_id": income.RefundOfExpenseID,
+		})
+	})
+	if err != nil {
+		return err
 	}
 	
 	logger.Info("Income created successfully: %+v", income)
 	return nil
 }
 
-func GetIncomeByID(userID string, id string) (*models.Income, error) {
+// resolveIncomeSource defaults the source of a new income to other and gives refunds the
+// refund source, which only they can have
+func resolveIncomeSource(income *models.Income) error {
+	if income.RefundOfExpenseID != nil {
+		income.Source = models.IncomeSourceRefund
+		return nil
+	}
+	if income.Source == "" {
+		income.Source = models.IncomeSourceOther
+	}
+	return validateIncomeSource(income.Source)
+}
+
+// validateIncomeSource checks a source given for an income that isn't a refund
+func validateIncomeSource(source string) error {
+	if source == models.IncomeSourceRefund {
+		return errors.New("invalid source: refund is set on incomes refunding an expense")
+	}
+	if !models.IsValidIncomeSource(source) {
+		return errors.New("invalid source: must be salary, freelance, business, dividends, interest, rental, gift or other")
+	}
+	return nil
+}
+
+func (s *Services) GetIncomeByID(userID string, id string) (*models.Income, error) {
     var income models.Income
-    result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
-        Preload("BankAccount").
+    result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
+        Scopes(preloadIncomeRelations).
         First(&income)
 	if result.Error != nil{
 		logger.Error("Error getting income by id: %v", result.Error)
@@ -67,54 +117,106 @@ func GetIncomeByID(userID string, id string) (*models.Income, error) {
 	return &income, nil
 }
 
-func GetAllIncomes(userID string, includeDeleted bool) ([]models.Income, error) {
+func preloadIncomeRelations(query *gorm.DB) *gorm.DB {
+	return query.Preload("BankAccount").Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
+
+// getIncomeInAnyStatus loads an income whatever its status, e.g. after a status change
+func (s *Services) getIncomeInAnyStatus(userID string, id string) (*models.Income, error) {
+	var income models.Income
+	result := s.db.Where("user_id = ? AND id = ?", userID, id).Scopes(preloadIncomeRelations).First(&income)
+	if result.Error != nil {
+		logger.Error("Error retrieving updated income: %v", result.Error)
+		return nil, errors.New("error retrieving updated income")
+	}
+	return &income, nil
+}
+
+func (s *Services) GetAllIncomes(userID string, includeDeleted bool, tags TagFilter, page PageRequest) ([]models.Income, PageInfo, error) {
 	var incomes []models.Income
-    query := db.DB.Where("user_id = ?", userID).Preload("BankAccount")
+	query := s.db.Model(&models.Income{}).Where("user_id = ?", userID)
 	
 	if !includeDeleted {
 		query = query.Where("status IN ?", models.GetVisibleStatuses())
 	}
+	query, err := filterByTags(query, userID, "income_tags", "income_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
 	
-	result := query.Order("date DESC, created_at DESC").Find(&incomes)
-	if result.Error != nil{
-		logger.Error("Error getting all incomes: %v", result.Error)
-		return nil, result.Error
+	info, err := paginate(query, "date DESC, created_at DESC, id", page, &incomes, preloadIncomeRelations)
+	if err != nil{
+		logger.Error("Error getting all incomes: %v", err)
+		return nil, PageInfo{}, err
 	}
-	logger.Info("All incomes retrieved successfully: %+v", incomes)
-	return incomes, nil
+	logger.Info("All incomes retrieved successfully: %d of %d", len(incomes), info.Total)
+	return incomes, info, nil
 }
 
-func GetActiveIncomes(userID string) ([]models.Income, error) {
+func (s *Services) GetActiveIncomes(userID string, tags TagFilter, page PageRequest) ([]models.Income, PageInfo, error) {
 	var incomes []models.Income
-    result := db.DB.Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses()).
-        Preload("BankAccount").
-        Order("date DESC, created_at DESC").Find(&incomes)
-	if result.Error != nil{
-		logger.Error("Error getting active incomes: %v", result.Error)
-		return nil, result.Error
+	query := s.db.Model(&models.Income{}).Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses())
+	query, err := filterByTags(query, userID, "income_tags", "income_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
-	logger.Info("Active incomes retrieved successfully: %+v", incomes)
-	return incomes, nil
+	info, err := paginate(query, "date DESC, created_at DESC, id", page, &incomes, preloadIncomeRelations)
+	if err != nil{
+		logger.Error("Error getting active incomes: %v", err)
+		return nil, PageInfo{}, err
+	}
+	logger.Info("Active incomes retrieved successfully: %d of %d", len(incomes), info.Total)
+	return incomes, info, nil
 }
 
-func GetDeletedIncomes(userID string) ([]models.Income, error) {
+func (s *Services) GetDeletedIncomes(userID string, tags TagFilter, page PageRequest) ([]models.Income, PageInfo, error) {
 	var incomes []models.Income
-    result := db.DB.Where("user_id = ? AND status = ?", userID, models.StatusDeleted).
-        Preload("BankAccount").
-        Order("status_changed_at DESC").Find(&incomes)
-	if result.Error != nil{
-		logger.Error("Error getting deleted incomes: %v", result.Error)
-		return nil, result.Error
+	query := s.db.Model(&models.Income{}).Where("user_id = ? AND status = ?", userID, models.StatusDeleted)
+	query, err := filterByTags(query, userID, "income_tags", "income_id", tags)
+	if err != nil {
+		return nil, PageInfo{}, err
 	}
-	logger.Info("Deleted incomes retrieved successfully: %+v", incomes)
-	return incomes, nil
+	info, err := paginate(query, "status_changed_at DESC, id", page, &incomes, preloadIncomeRelations)
+	if err != nil{
+		logger.Error("Error getting deleted incomes: %v", err)
+		return nil, PageInfo{}, err
+	}
+	logger.Info("Deleted incomes retrieved successfully: %d of %d", len(incomes), info.Total)
+	return incomes, info, nil
 }
 
-func PatchIncome(userID string, id string, income *models.Income) (*models.Income, error) {
+// Income columns PatchIncome can clear; nil fields of the patch are otherwise left as they are
+const (
+	IncomeClearBankAccount = "bank_account_id"
+	IncomeClearDescription = "description"
+)
+
+// PatchIncome updates the non-zero fields of income and sets the clear columns to NULL
+func (s *Services) PatchIncome(userID string, id string, income *models.Income, clear ...string) (*models.Income, error) {
+	clearBankAccount, clearDescription := false, false
+	for _, column := range clear {
+		switch column {
+		case IncomeClearBankAccount:
+			clearBankAccount = true
+		case IncomeClearDescription:
+			clearDescription = true
+		default:
+			return nil, errors.New("invalid field to clear: " + column)
+		}
+	}
+	if clearBankAccount && income.BankAccountID != nil {
+		return nil, errors.New("invalid bank account: can't set and clear it at once")
+	}
+	if clearDescription && income.Description != nil {
+		return nil, errors.New("invalid description: can't set and clear it at once")
+	}
+	
 	var existingIncome models.Income
 	
 	// Verificar que el income existe, pertenece al usuario y no está eliminado
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingIncome)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingIncome)
 	if result.Error != nil {
 		logger.Error("Income not found or doesn't belong to user: %v", result.Error)
 		return nil, errors.New("income not found or access denied")
@@ -122,71 +224,40 @@ func PatchIncome(userID string, id string, income *models.Income) (*models.Incom
 	
 	// Determine which fields are being updated
 	// Note: If field is zero value, it means it wasn't provided in the request
-	var zeroUUID uuid.UUID
 	amountProvided := income.Amount != 0
-	bankAccountProvided := income.BankAccountID != zeroUUID
+	bankAccountProvided := income.BankAccountID != nil
 	
 	amountChanged := amountProvided && income.Amount != existingIncome.Amount
-	bankAccountChanged := bankAccountProvided && income.BankAccountID != existingIncome.BankAccountID
+	bankAccountChanged := bankAccountProvided &&
+		(existingIncome.BankAccountID == nil || *income.BankAccountID != *existingIncome.BankAccountID) ||
+		clearBankAccount && existingIncome.BankAccountID != nil
 	
 	// Validate and verify bank account if provided
 	if bankAccountProvided {
 		var bankAccount models.BankAccount
-		result := db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
-			income.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
+		result := s.db.Where("id = ? AND user_id = ? AND status IN ?", 
+			*income.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
 		if result.Error != nil {
 			logger.Error("Bank account not found, not active, or doesn't belong to user")
 			return nil, errors.New("bank account not found, not active, or access denied")
 		}
 	}
 	
-	// Handle balance updates before updating the income record
-	if amountChanged || bankAccountChanged {
-		// Determine the final values to use
-		finalAmount := existingIncome.Amount
-		finalBankAccountID := existingIncome.BankAccountID
-		
-		if amountProvided {
-			finalAmount = income.Amount
-		}
-		if bankAccountProvided {
-			finalBankAccountID = income.BankAccountID
-		}
-		
-		// If only amount changed on same account
-		if amountChanged && !bankAccountChanged {
-			var bankAccount models.BankAccount
-			if err := db.DB.Where("id = ?", existingIncome.BankAccountID).First(&bankAccount).Error; err != nil {
-				return nil, errors.New("bank account not found")
-			}
-			
-			// Adjust balance: reverse old amount, apply new amount
-			balanceChange := finalAmount - existingIncome.Amount
-			if err := db.DB.Model(&bankAccount).
-				Update("balance", gorm.Expr("balance + ?", balanceChange)).Error; err != nil {
-				logger.Error("Error updating bank account balance: %v", err)
-				return nil, errors.New("error updating bank account balance")
-			}
-		} else if bankAccountChanged {
-			// Bank account changed - move amount between accounts
-			// Remove from old account (reverse the addition)
-			if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingIncome.BankAccountID).
-				Update("balance", gorm.Expr("balance - ?", existingIncome.Amount)).Error; err != nil {
-				logger.Error("Error updating old bank account balance: %v", err)
-				return nil, errors.New("error updating old bank account balance")
-			}
-			
-			// Add to new account
-			var newAccount models.BankAccount
-			if err := db.DB.Where("id = ?", finalBankAccountID).First(&newAccount).Error; err != nil {
-				return nil, errors.New("new bank account not found")
-			}
-			
-			if err := db.DB.Model(&newAccount).
-				Update("balance", gorm.Expr("balance + ?", finalAmount)).Error; err != nil {
-				logger.Error("Error updating new bank account balance: %v", err)
-				return nil, errors.New("error updating new bank account balance")
+	// Refunds keep their source; other incomes can move between the rest
+	if income.Source != "" {
+		if existingIncome.RefundOfExpenseID != nil {
+			if income.Source != models.IncomeSourceRefund {
+				return nil, errors.New("invalid source: refunds always have the refund source")
 			}
+		} else if err := validateIncomeSource(income.Source); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Keep refunds within the original expense amount
+	if amountChanged && existingIncome.RefundOfExpenseID != nil {
+		if err := s.validateRefund(userID, *existingIncome.RefundOfExpenseID, income.Amount, &existingIncome.ID); err != nil {
+			return nil, err
 		}
 	}
 	
@@ -194,35 +265,70 @@ func PatchIncome(userID string, id string, income *models.Income) (*models.Incom
 	if !amountProvided {
 		income.Amount = existingIncome.Amount
 	}
-	// If bank account is zero, it means it wasn't provided, so keep existing bank account
-	if !bankAccountProvided {
+	// If bank account is nil, it means it wasn't provided, so keep existing bank account
+	if !bankAccountProvided && !clearBankAccount {
 		income.BankAccountID = existingIncome.BankAccountID
 	}
 	
 	// Prevenir modificación de campos protegidos
 	income.UserID = existingIncome.UserID
 	income.ID = existingIncome.ID
+	income.RefundOfExpenseID = existingIncome.RefundOfExpenseID
 	income.CreatedAt = existingIncome.CreatedAt
 	
 	// No permitir cambio de status a través de patch normal (usar funciones específicas)
 	income.Status = existingIncome.Status
 	income.StatusChangedAt = existingIncome.StatusChangedAt
 	
-	// Actualizar solo si pertenece al usuario
-	result = db.DB.Model(&existingIncome).Where("user_id = ? AND id = ?", userID, id).Updates(income)
-	if result.Error != nil{
-		logger.Error("Error patching income: %v", result.Error)
-		return nil, result.Error
-	}
-	
-	if result.RowsAffected == 0{
-		logger.Error("Income not found or doesn't belong to user")
-		return nil, errors.New("income not found or access denied")
+	// Actualizar solo si pertenece al usuario; when the amount or account changes, the old amount
+	// leaves its account and the new one is added in the same transaction
+	before := existingIncome
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&existingIncome).Where("user_id = ? AND id = ?", userID, id).Omit("Tags").Updates(income)
+		if result.Error != nil{
+			logger.Error("Error patching income: %v", result.Error)
+			return result.Error
+		}
+		
+		if result.RowsAffected == 0{
+			logger.Error("Income not found or doesn't belong to user")
+			return errors.New("income not found or access denied")
+		}
+		
+		// Updates skips nil fields, so cleared ones are set apart
+		if len(clear) > 0 {
+			nulls := make(map[string]interface{}, len(clear))
+			for _, column := range clear {
+				nulls[column] = nil
+			}
+			if err := tx.Model(&existingIncome).Where("user_id = ? AND id = ?", userID, id).Updates(nulls).Error; err != nil {
+				logger.Error("Error clearing income fields: %v", err)
+				return err
+			}
+		}
+		
+		// Tags given (even none) replace the current ones
+		if income.Tags != nil {
+			if err := replaceTags(tx, userID, &existingIncome, income.Tags); err != nil {
+				return err
+			}
+		}
+		
+		if !amountChanged && !bankAccountChanged {
+			return nil
+		}
+		if err := applyIncomeLedger(tx, &before, -1); err != nil {
+			return err
+		}
+		return applyIncomeLedger(tx, income, 1)
+	})
+	if err != nil {
+		return nil, err
 	}
 	
     // Obtener el income actualizado con relaciones
-    result = db.DB.Where("user_id = ? AND id = ?", userID, id).
-        Preload("BankAccount").
+    result = s.db.Where("user_id = ? AND id = ?", userID, id).
+        Scopes(preloadIncomeRelations).
         First(&existingIncome)
 	if result.Error != nil {
 		logger.Error("Error retrieving updated income: %v", result.Error)
@@ -233,55 +339,55 @@ func PatchIncome(userID string, id string, income *models.Income) (*models.Incom
 	return &existingIncome, nil
 }
 
-func SoftDeleteIncome(userID string, id string) error {
+func (s *Services) SoftDeleteIncome(userID string, id string) error {
 	// Verificar que el income existe y pertenece al usuario
 	var existingIncome models.Income
-	result := db.DB.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingIncome)
+	result := s.db.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingIncome)
 	if result.Error != nil {
 		logger.Error("Income not found or already deleted: %v", result.Error)
 		return errors.New("income not found or already deleted")
 	}
-	
-	// Marcar como eliminado
-	now := time.Now()
-	result = db.DB.Model(&existingIncome).Updates(map[string]interface{}{
-		"status": models.StatusDeleted,
-		"status_changed_at": &now,
-	})
-	
-	if result.Error != nil{
-		logger.Error("Error soft deleting income: %v", result.Error)
-		return result.Error
+	if _, err := checkStatusTransition(models.IncomeStatusMachine, existingIncome.Status, models.StatusDeleted); err != nil {
+		return err
 	}
 	
-	// Restore balance (remove the income amount from bank account)
-	var zeroUUID uuid.UUID
-	if existingIncome.BankAccountID != zeroUUID {
-		if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingIncome.BankAccountID).
-			Update("balance", gorm.Expr("balance - ?", existingIncome.Amount)).Error; err != nil {
+	// Marcar como eliminado and remove the income amount from the bank account together
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingIncome).Updates(map[string]interface{}{
+			"status": models.StatusDeleted,
+			"status_changed_at": &now,
+		}).Error; err != nil{
+			logger.Error("Error soft deleting income: %v", err)
+			return err
+		}
+		if err := applyIncomeLedger(tx, &existingIncome, -1); err != nil {
 			logger.Error("Error restoring bank account balance: %v", err)
 			return errors.New("error restoring bank account balance")
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	
 	logger.Info("Income soft deleted successfully: %s", id)
 	return nil
 }
 
-func RestoreIncome(userID string, id string) (*models.Income, error) {
+func (s *Services) RestoreIncome(userID string, id string) (*models.Income, error) {
 	// Verificar que el income existe, pertenece al usuario y está eliminado
 	var existingIncome models.Income
-	result := db.DB.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingIncome)
+	result := s.db.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingIncome)
 	if result.Error != nil {
 		logger.Error("Income not found, not deleted, or access denied: %v", result.Error)
 		return nil, errors.New("income not found, not deleted, or access denied")
 	}
 	
 	// Verify that the bank account still exists and is active
-	var zeroUUID uuid.UUID
-	if existingIncome.BankAccountID != zeroUUID {
+	if existingIncome.BankAccountID != nil {
 		var bankAccount models.BankAccount
-		result := db.DB.Where("id = ? AND user_id = ? AND status IN ?", 
+		result := s.db.Where("id = ? AND user_id = ? AND status IN ?", 
 			existingIncome.BankAccountID, userID, models.GetActiveStatuses()).First(&bankAccount)
 		if result.Error != nil {
 			logger.Error("Cannot restore income: bank account is not active")
@@ -289,29 +395,31 @@ func RestoreIncome(userID string, id string) (*models.Income, error) {
 		}
 	}
 	
-	// Restaurar como activo
-	now := time.Now()
-	result = db.DB.Model(&existingIncome).Updates(map[string]interface{}{
-		"status": models.StatusActive,
-		"status_changed_at": &now,
-	})
-	
-	if result.Error != nil{
-		logger.Error("Error restoring income: %v", result.Error)
-		return nil, result.Error
+	// A restored refund must still fit within the original expense
+	if existingIncome.RefundOfExpenseID != nil {
+		if err := s.validateRefund(userID, *existingIncome.RefundOfExpenseID, existingIncome.Amount, &existingIncome.ID); err != nil {
+			return nil, err
+		}
 	}
 	
-	// Add balance back (add the income amount to bank account)
-	if existingIncome.BankAccountID != zeroUUID {
-		if err := db.DB.Model(&models.BankAccount{}).Where("id = ?", existingIncome.BankAccountID).
-			Update("balance", gorm.Expr("balance + ?", existingIncome.Amount)).Error; err != nil {
-			logger.Error("Error updating bank account balance: %v", err)
-			return nil, errors.New("error updating bank account balance")
+	// Restaurar como activo and add the income amount back to the bank account together
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingIncome).Updates(map[string]interface{}{
+			"status": models.StatusActive,
+			"status_changed_at": &now,
+		}).Error; err != nil{
+			logger.Error("Error restoring income: %v", err)
+			return err
 		}
+		return applyIncomeLedger(tx, &existingIncome, 1)
+	})
+	if err != nil {
+		return nil, err
 	}
 	
 	// Get the updated income
-	updatedIncome, err := GetIncomeByID(userID, id)
+	updatedIncome, err := s.GetIncomeByID(userID, id)
 	if err != nil {
 		logger.Error("Error retrieving updated income: %v", err)
 		return nil, errors.New("error retrieving updated income")
@@ -321,7 +429,7 @@ func RestoreIncome(userID string, id string) (*models.Income, error) {
 	return updatedIncome, nil
 }
 
-func ChangeIncomeStatus(userID string, id string, newStatus models.Status, reason *string) (*models.Income, error) {
+func (s *Services) ChangeIncomeStatus(userID string, id string, newStatus models.Status, reason *string) (*models.Income, error) {
 	// Validar que el status es válido
 	if !models.ValidateStatus(newStatus) {
 		return nil, errors.New("invalid status")
@@ -329,7 +437,7 @@ func ChangeIncomeStatus(userID string, id string, newStatus models.Status, reaso
 	
 	// Verificar que el income existe y pertenece al usuario
 	var existingIncome models.Income
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).First(&existingIncome)
+	result := s.db.Where("user_id = ? AND id = ?", userID, id).First(&existingIncome)
 	if result.Error != nil {
 		logger.Error("Income not found: %v", result.Error)
 		return nil, errors.New("income not found or access denied")
@@ -337,51 +445,70 @@ func ChangeIncomeStatus(userID string, id string, newStatus models.Status, reaso
 	
 	// No hacer nada si ya tiene ese status - return current income
 	if existingIncome.Status == newStatus {
-		updatedIncome, err := GetIncomeByID(userID, id)
-		if err != nil {
-			logger.Error("Error retrieving income: %v", err)
-			return nil, errors.New("error retrieving income")
-		}
-		return updatedIncome, nil
+		return s.getIncomeInAnyStatus(userID, id)
 	}
 	
-	// Actualizar status
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status": newStatus,
-		"status_changed_at": &now,
+	transition, err := checkStatusTransition(models.IncomeStatusMachine, existingIncome.Status, newStatus)
+	if err != nil {
+		return nil, err
 	}
 	
-	result = db.DB.Model(&existingIncome).Updates(updates)
-	if result.Error != nil{
-		logger.Error("Error changing income status: %v", result.Error)
-		return nil, result.Error
+	// Deleting and restoring move the balance, like DELETE and restore do
+	switch {
+	case transition.HasSideEffect(models.SideEffectReverseBalance):
+		if err := s.SoftDeleteIncome(userID, id); err != nil {
+			return nil, err
+		}
+	case transition.HasSideEffect(models.SideEffectReapplyBalance):
+		if _, err := s.RestoreIncome(userID, id); err != nil {
+			return nil, err
+		}
+	default:
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status": newStatus,
+			"status_changed_at": &now,
+		}
+		
+		result = s.db.Model(&existingIncome).Updates(updates)
+		if result.Error != nil{
+			logger.Error("Error changing income status: %v", result.Error)
+			return nil, result.Error
+		}
 	}
 	
 	// Get the updated income
-	updatedIncome, err := GetIncomeByID(userID, id)
+	updatedIncome, err := s.getIncomeInAnyStatus(userID, id)
 	if err != nil {
-		logger.Error("Error retrieving updated income: %v", err)
-		return nil, errors.New("error retrieving updated income")
+		return nil, err
 	}
 	
 	logger.Info("Income status changed to %s successfully: %s", newStatus, id)
 	return updatedIncome, nil
 }
 
-func HardDeleteIncome(userID string, id string) error {
+func (s *Services) HardDeleteIncome(userID string, id string) error {
 	// SOLO para casos especiales - elimina permanentemente
-	// Verificar que el income existe y pertenece al usuario
-	result := db.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Income{})
-	if result.Error != nil{
-		logger.Error("Error hard deleting income: %v", result.Error)
-		return result.Error
-	}
-	
-	// Verificar que realmente se eliminó algo
-	if result.RowsAffected == 0 {
+	// Verificar que el income existe y pertenece al usuario; sync clients holding it must resync
+	errNotFound := errors.New("income not found or access denied")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Income{})
+		if result.Error != nil {
+			return result.Error
+		}
+		// Verificar que realmente se eliminó algo
+		if result.RowsAffected == 0 {
+			return errNotFound
+		}
+		return recordSyncPurge(tx, uuid.MustParse(userID), "incomes", time.Now())
+	})
+	if errors.Is(err, errNotFound) {
 		logger.Error("Income not found or doesn't belong to user")
-		return errors.New("income not found or access denied")
+		return err
+	}
+	if err != nil {
+		logger.Error("Error hard deleting income: %v", err)
+		return err
 	}
 	
 	logger.Info("Income permanently deleted: %s", id)
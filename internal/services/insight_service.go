@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// Kinds of spending insights
+const (
+	InsightVolatility        = "volatility"
+	InsightMostActiveDay     = "most_active_day"
+	InsightTypicalExpense    = "typical_expense"
+	InsightAverageDaily      = "average_daily"
+	InsightLargestExpense    = "largest_expense"
+	InsightCategoryDiversity = "category_diversity"
+)
+
+// Volatility levels, from the coefficient of variation of expense sizes
+const (
+	VolatilityLow      = "low"
+	VolatilityModerate = "moderate"
+	VolatilityHigh     = "high"
+)
+
+// MaxInsightMonths bounds the period insights and forecasts look back on
+const MaxInsightMonths = 24
+
+// validateInsightMonths checks the number of months insights and forecasts are drawn from
+func validateInsightMonths(months int) error {
+	if months < 1 || months > MaxInsightMonths {
+		return fmt.Errorf("invalid months: must be between 1 and %d", MaxInsightMonths)
+	}
+	return nil
+}
+
+// volatilityLevel grades how spread out expense sizes are: the standard deviation against the
+// mean, so it doesn't depend on the currency or how much the user spends
+func volatilityLevel(variation float64) string {
+	switch {
+	case variation < 0.5:
+		return VolatilityLow
+	case variation < 1:
+		return VolatilityModerate
+	default:
+		return VolatilityHigh
+	}
+}
+
+// GetSpendingInsights turns the features the ML analytics extract from the last months of
+// expenses into plain-language insights. No insights are given without expenses
+func (s *Services) GetSpendingInsights(userID string, months int) (*dto.SpendingInsights, error) {
+	if err := validateInsightMonths(months); err != nil {
+		return nil, err
+	}
+	currency := s.GetUserCurrency(userID)
+	endDate := s.UserToday(userID)
+	startDate := endDate.AddDate(0, -months, 0)
+
+	expenses, err := s.Expenses.GetByDateRange(userID, startDate, endDate, false)
+	if err != nil {
+		return nil, errors.New("error calculating insights")
+	}
+
+	insights := &dto.SpendingInsights{
+		PeriodStart:  startDate.Format("2006-01-02"),
+		PeriodEnd:    endDate.Format("2006-01-02"),
+		Months:       months,
+		Currency:     currency.Code,
+		ExpenseCount: len(expenses),
+		Insights:     []dto.SpendingInsight{},
+	}
+	if len(expenses) == 0 {
+		return insights, nil
+	}
+	amount := func(value float64) string {
+		return currency.Format(value) + " " + currency.Code
+	}
+
+	var total models.Money
+	for _, expense := range expenses {
+		total += expense.Amount
+	}
+	if len(expenses) > 1 && total > 0 {
+		mean := total.Float64() / float64(len(expenses))
+		variation := math.Round(math.Sqrt(calculateSpendingVolatility(expenses))/mean*100) / 100
+		insight := dto.SpendingInsight{Kind: InsightVolatility, Level: volatilityLevel(variation), Value: variation}
+		switch insight.Level {
+		case VolatilityLow:
+			insight.Message = "Your expenses are fairly consistent in size, which makes your spending easy to predict."
+		case VolatilityModerate:
+			insight.Message = "Your expense sizes vary moderately: most are close to your usual amount, with some larger ones."
+		default:
+			insight.Message = "Your expense sizes vary a lot: a few large purchases make up much of your spending."
+		}
+		insights.Insights = append(insights.Insights, insight)
+	}
+
+	day := getMostActiveDay(expenses)
+	insights.Insights = append(insights.Insights, dto.SpendingInsight{
+		Kind:    InsightMostActiveDay,
+		Value:   float64(day),
+		Message: fmt.Sprintf("You record the most expenses on %ss.", time.Weekday(day)),
+	})
+
+	typical := currency.Round(getTypicalExpenseSize(expenses))
+	insights.Insights = append(insights.Insights, dto.SpendingInsight{
+		Kind:    InsightTypicalExpense,
+		Value:   typical,
+		Message: fmt.Sprintf("Your typical expense is %s: half of your expenses are smaller and half are larger.", amount(typical)),
+	})
+
+	daily := currency.Round(calculateAverageDaily(expenses))
+	insights.Insights = append(insights.Insights, dto.SpendingInsight{
+		Kind:    InsightAverageDaily,
+		Value:   daily,
+		Message: fmt.Sprintf("On the days you spend, you spend %s on average.", amount(daily)),
+	})
+
+	largest := getLargestExpense(expenses).Float64()
+	insights.Insights = append(insights.Insights, dto.SpendingInsight{
+		Kind:    InsightLargestExpense,
+		Value:   largest,
+		Message: fmt.Sprintf("Your largest expense since %s was %s.", insights.PeriodStart, amount(largest)),
+	})
+
+	diversity := getCategoryDiversity(expenses)
+	insights.Insights = append(insights.Insights, dto.SpendingInsight{
+		Kind:    InsightCategoryDiversity,
+		Value:   float64(diversity),
+		Message: fmt.Sprintf("Your spending is spread over %d categories.", diversity),
+	})
+
+	return insights, nil
+}
+
+// GetSpendingForecast projects next month's spend per expense type from the last complete
+// months, over the user's month periods. The projection is a weighted average that counts recent
+// months more (the latest month weighs months, the oldest 1); months without spend count as
+// zero. Amounts are net of refunds, and only what was paid from the accounts counts unless they
+// are nil
+func (s *Services) GetSpendingForecast(userID string, months int, accounts []uuid.UUID) (*dto.SpendingForecast, error) {
+	if err := validateInsightMonths(months); err != nil {
+		return nil, err
+	}
+	currency := s.GetUserCurrency(userID)
+	calendar := s.getUserCalendar(userID)
+	thisMonth := calendar.monthStart(s.UserToday(userID))
+	startDate := thisMonth.AddDate(0, -months, 0)
+	endDate := thisMonth.AddDate(0, 0, -1)
+
+	var rows []struct {
+		Month       string
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+	}
+	result := filterExpensesByAccounts(s.summaryPeriodQuery(userID, startDate, endDate), accounts).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		// A period is labelled by the calendar month it starts in
+		Select("TO_CHAR(e.date - make_interval(days => ?), 'YYYY-MM') as month, c.expense_type, COALESCE(SUM("+accountsExpenseAmountSQL(accounts)+"), 0) as amount",
+			calendar.MonthStartDay-1).
+		Group("month, c.expense_type").
+		Scan(&rows)
+	if result.Error != nil {
+		logger.Error("Error calculating spending forecast: %v", result.Error)
+		return nil, errors.New("error calculating spending forecast")
+	}
+
+	spent := make(map[models.ExpenseType]map[string]models.Money)
+	for _, row := range rows {
+		if spent[row.ExpenseType] == nil {
+			spent[row.ExpenseType] = make(map[string]models.Money)
+		}
+		spent[row.ExpenseType][row.Month] += row.Amount
+	}
+
+	forecast := &dto.SpendingForecast{
+		Month:         thisMonth.AddDate(0, 1, 0).Format("2006-01"),
+		Currency:      currency.Code,
+		BasedOnMonths: months,
+		Types:         make([]dto.ExpenseTypeForecast, 0, len(models.ValidExpenseTypes())),
+	}
+	weights := float64(months * (months + 1) / 2)
+	for _, expenseType := range models.ValidExpenseTypes() {
+		item := dto.ExpenseTypeForecast{
+			ExpenseType: string(expenseType),
+			Name:        models.GetExpenseTypeName(expenseType),
+			Trend:       VelocityTrendFlat,
+			History:     make([]dto.MonthlySpend, 0, months),
+		}
+		var total, weighted models.Money
+		for i := 0; i < months; i++ {
+			month := startDate.AddDate(0, i, 0).Format("2006-01")
+			amount := spent[expenseType][month]
+			item.History = append(item.History, dto.MonthlySpend{Month: month, Amount: amount})
+			total += amount
+			weighted += amount.MulRatio(float64(i + 1))
+		}
+		item.Average = currency.RoundMoney(total.MulRatio(1 / float64(months)))
+		item.Projected = currency.RoundMoney(weighted.MulRatio(1 / weights))
+		if item.Average > 0 {
+			change := (item.Projected - item.Average).Ratio(item.Average) * 100
+			if change >= velocityTrendThresholdPercent {
+				item.Trend = VelocityTrendUp
+			} else if change <= -velocityTrendThresholdPercent {
+				item.Trend = VelocityTrendDown
+			}
+		}
+		forecast.Projected += item.Projected
+		forecast.Types = append(forecast.Types, item)
+	}
+
+	return forecast, nil
+}
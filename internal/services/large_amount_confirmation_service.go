@@ -0,0 +1,208 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Entries that can need a confirmation step
+const (
+	ConfirmationExpense  = "expense"
+	ConfirmationTransfer = "transfer"
+)
+
+// ErrConfirmTokenUsed is returned when the confirm token of an entry was spent on another
+// write in the meantime
+var ErrConfirmTokenUsed = errors.New("confirm token already used")
+
+// ConfirmationRequiredError is returned instead of creating an entry above the user's threshold.
+// Sending the same entry again with Token creates it, once
+type ConfirmationRequiredError struct {
+	Kind      string
+	Amount    models.Money
+	Threshold models.Money
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("%s of %s is above the confirmation threshold of %s", e.Kind, e.Amount, e.Threshold)
+}
+
+// largeAmountConfirmTTL is how long a confirm token stays valid
+func largeAmountConfirmTTL() time.Duration {
+	return time.Duration(envInt("LARGE_AMOUNT_CONFIRM_TTL_MINUTES", 10)) * time.Minute
+}
+
+// largeAmountSignature signs an entry and the expiry and ID of its confirm token
+func largeAmountSignature(userID, kind, fingerprint string, expires int64, tokenID uuid.UUID) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("confirm:" + userID + ":" + kind + ":" + fingerprint + ":" + strconv.FormatInt(expires, 10) + ":" + tokenID.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// largeAmountToken parses a confirm token issued for this exact entry and not expired, and
+// returns its ID. Whether it was used is up to the caller
+func largeAmountToken(userID, kind, fingerprint, token string) (uuid.UUID, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return uuid.Nil, false
+	}
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return uuid.Nil, false
+	}
+	tokenID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	expected := largeAmountSignature(userID, kind, fingerprint, expires, tokenID)
+	return tokenID, hmac.Equal([]byte(parts[2]), []byte(expected))
+}
+
+// spendConfirmToken marks the confirm token of an entry as used, in the transaction writing the
+// entry, so it's only spent if the entry is created. Entries confirmed without a token pass
+func spendConfirmToken(tx *gorm.DB, userID uuid.UUID, tokenID *uuid.UUID) error {
+	if tokenID == nil {
+		return nil
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UsedConfirmToken{ID: *tokenID, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConfirmTokenUsed
+	}
+	return nil
+}
+
+// PurgeUsedConfirmTokens forgets the used confirm tokens that have expired since
+func (s *Services) PurgeUsedConfirmTokens() error {
+	result := s.db.Where("created_at < ?", time.Now().Add(-largeAmountConfirmTTL())).Delete(&models.UsedConfirmToken{})
+	if result.Error != nil {
+		logger.Error("Error purging used confirm tokens: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Info("Purged %d used confirm tokens", result.RowsAffected)
+	}
+	return nil
+}
+
+// expenseFingerprint identifies what a confirm token of an expense confirms: amount, category,
+// date and accounts
+func expenseFingerprint(expense *models.Expense) string {
+	accounts := []string{expense.BankAccountID.String()}
+	if len(expense.Allocations) > 0 {
+		accounts = accounts[:0]
+		for _, allocation := range expense.Allocations {
+			accounts = append(accounts, fmt.Sprintf("%s=%s", allocation.BankAccountID, allocation.Amount))
+		}
+		sort.Strings(accounts)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", expense.Amount, expense.CategoryID, expense.Date.Format("2006-01-02"), strings.Join(accounts, ","))
+}
+
+// transferFingerprint identifies what a confirm token of a transfer confirms
+func transferFingerprint(transfer *models.Transfer) string {
+	return fmt.Sprintf("%s|%s|%s|%s", transfer.Amount, transfer.FromAccountID, transfer.ToAccountID, transfer.Date.Format("2006-01-02"))
+}
+
+// RequireExpenseConfirmation checks an expense entered by the user against the confirmation
+// threshold. A confirmed expense spends its token when it's created. Imported and scheduled
+// expenses don't go through it
+func (s *Services) RequireExpenseConfirmation(userID string, expense *models.Expense, confirmToken string) error {
+	tokenID, err := s.requireLargeAmountConfirmation(userID, ConfirmationExpense, expense.Amount, expenseFingerprint(expense), confirmToken)
+	expense.ConfirmTokenID = tokenID
+	return err
+}
+
+// RequireTransferConfirmation checks a transfer entered by the user against the confirmation
+// threshold. A confirmed transfer spends its token when it's created
+func (s *Services) RequireTransferConfirmation(userID string, transfer *models.Transfer, confirmToken string) error {
+	tokenID, err := s.requireLargeAmountConfirmation(userID, ConfirmationTransfer, transfer.Amount, transferFingerprint(transfer), confirmToken)
+	transfer.ConfirmTokenID = tokenID
+	return err
+}
+
+// requireLargeAmountConfirmation returns a *ConfirmationRequiredError when the amount is above
+// the user's threshold for the kind of entry and confirmToken doesn't confirm this entry or was
+// used already. Otherwise it returns the ID of the confirming token, if one was needed
+func (s *Services) requireLargeAmountConfirmation(userID, kind string, amount models.Money, fingerprint, confirmToken string) (*uuid.UUID, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error checking confirmation threshold")
+	}
+	threshold := preferences.ExpenseConfirmAbove
+	if kind == ConfirmationTransfer {
+		threshold = preferences.TransferConfirmAbove
+	}
+	if threshold == nil || amount <= *threshold {
+		return nil, nil
+	}
+	if tokenID, ok := largeAmountToken(userID, kind, fingerprint, confirmToken); ok {
+		var used int64
+		if err := s.db.Model(&models.UsedConfirmToken{}).Where("id = ?", tokenID).Count(&used).Error; err != nil {
+			return nil, errors.New("error checking confirmation threshold")
+		}
+		if used == 0 {
+			logger.Info("Large %s of %s confirmed by user %s", kind, amount, userID)
+			return &tokenID, nil
+		}
+		logger.Warn("Used confirm token sent again by user %s for a %s of %s", userID, kind, amount)
+	}
+
+	expires := time.Now().Add(largeAmountConfirmTTL())
+	tokenID := uuid.New()
+	return nil, &ConfirmationRequiredError{
+		Kind:      kind,
+		Amount:    amount,
+		Threshold: *threshold,
+		Token:     fmt.Sprintf("%d.%s.%s", expires.Unix(), tokenID, largeAmountSignature(userID, kind, fingerprint, expires.Unix(), tokenID)),
+		ExpiresAt: expires,
+	}
+}
+
+// GetConfirmationThresholds returns the amounts above which expenses and transfers need confirming
+func (s *Services) GetConfirmationThresholds(userID string) (*dto.ConfirmationThresholds, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error getting confirmation thresholds")
+	}
+	return &dto.ConfirmationThresholds{Expense: preferences.ExpenseConfirmAbove, Transfer: preferences.TransferConfirmAbove}, nil
+}
+
+// SetConfirmationThresholds replaces both thresholds; nil turns the confirmation off
+func (s *Services) SetConfirmationThresholds(userID string, thresholds dto.ConfirmationThresholds) (*dto.ConfirmationThresholds, error) {
+	for _, threshold := range []*models.Money{thresholds.Expense, thresholds.Transfer} {
+		if threshold != nil && *threshold <= 0 {
+			return nil, errors.New("invalid threshold: must be greater than 0")
+		}
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error updating confirmation thresholds")
+	}
+	preferences.ExpenseConfirmAbove = thresholds.Expense
+	preferences.TransferConfirmAbove = thresholds.Transfer
+	if err := s.saveUserPreferences(preferences, "expense_confirm_above", "transfer_confirm_above"); err != nil {
+		logger.Error("Error saving confirmation thresholds: %v", err)
+		return nil, errors.New("error updating confirmation thresholds")
+	}
+	return &thresholds, nil
+}
@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestConfirmTokenCreatesOneExpense(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	cases := []struct {
+		name string
+		// The replay passes the confirmation before the first write commits
+		race         bool
+		wantNewToken bool // The replay gets a confirmation required again
+		wantErr      error
+	}{
+		{
+			name:         "replay after the write asks for a new confirmation",
+			wantNewToken: true,
+		},
+		{
+			name:    "replay racing the write is rejected",
+			race:    true,
+			wantErr: services.ErrConfirmTokenUsed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			account := h.CreateBankAccount(t, user, models.NewMoney(5000))
+			category := h.CreateCategory(t, user, "Rent", models.ExpenseTypeNeeds)
+			userID := user.ID.String()
+			threshold := models.NewMoney(500)
+			if _, err := h.Services.SetConfirmationThresholds(userID, dto.ConfirmationThresholds{Expense: &threshold}); err != nil {
+				t.Fatalf("setting thresholds: %v", err)
+			}
+
+			newExpense := func() *models.Expense {
+				return &models.Expense{
+					CategoryID:    category.ID,
+					BankAccountID: account.ID,
+					Amount:        models.NewMoney(600),
+					Date:          time.Now().UTC().Truncate(24 * time.Hour),
+				}
+			}
+			var confirmErr *services.ConfirmationRequiredError
+			if err := h.Services.RequireExpenseConfirmation(userID, newExpense(), ""); !errors.As(err, &confirmErr) {
+				t.Fatalf("unconfirmed expense = %v, want a confirmation required", err)
+			}
+			token := confirmErr.Token
+
+			first, replay := newExpense(), newExpense()
+			if err := h.Services.RequireExpenseConfirmation(userID, first, token); err != nil {
+				t.Fatalf("confirming the expense: %v", err)
+			}
+			if tc.race {
+				if err := h.Services.RequireExpenseConfirmation(userID, replay, token); err != nil {
+					t.Fatalf("confirming the replay before the write: %v", err)
+				}
+			}
+			if err := h.Expenses.Create(context.Background(), userID, first, false); err != nil {
+				t.Fatalf("creating the confirmed expense: %v", err)
+			}
+
+			var err error
+			if tc.race {
+				err = h.Expenses.Create(context.Background(), userID, replay, false)
+			} else {
+				err = h.Services.RequireExpenseConfirmation(userID, replay, token)
+			}
+			if tc.wantNewToken {
+				if !errors.As(err, &confirmErr) || confirmErr.Token == token {
+					t.Fatalf("replaying the token = %v, want a confirmation required with a new token", err)
+				}
+			} else if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("replaying the token = %v, want %v", err, tc.wantErr)
+			}
+
+			var expenses int64
+			if err := h.DB.Model(&models.Expense{}).Where("user_id = ?", user.ID).Count(&expenses).Error; err != nil {
+				t.Fatalf("counting expenses: %v", err)
+			}
+			if expenses != 1 {
+				t.Errorf("%d expenses created with one token, want 1", expenses)
+			}
+		})
+	}
+}
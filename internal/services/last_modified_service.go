@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListState is how the user's rows across some tables stand: the latest updated_at, which list
+// endpoints send as Last-Modified, and per table the row count and latest updated_at. A
+// deletion changes the counts, so the state tells apart lists a newest timestamp alone doesn't
+type ListState struct {
+	LastModified *time.Time // Nil when the user has no rows
+	tables       []listTableState
+}
+
+type listTableState struct {
+	Position  int
+	RowCount  int64
+	UpdatedAt *time.Time
+}
+
+// Version identifies the state: it changes with any write or deletion to the rows
+func (l *ListState) Version() string {
+	var version strings.Builder
+	for _, table := range l.tables {
+		version.WriteString(strconv.FormatInt(table.RowCount, 10))
+		version.WriteByte(':')
+		if table.UpdatedAt != nil {
+			version.WriteString(strconv.FormatInt(table.UpdatedAt.UnixMicro(), 10))
+		}
+		version.WriteByte(';')
+	}
+	return version.String()
+}
+
+// GetListState returns the state of the user's rows across the given tables. Tables come from
+// the handlers, never from the request
+func (s *Services) GetListState(userID string, tables ...string) (*ListState, error) {
+	parts := make([]string, 0, len(tables))
+	args := make([]interface{}, 0, len(tables))
+	for i, table := range tables {
+		parts = append(parts, "SELECT "+strconv.Itoa(i)+" AS position, COUNT(*) AS row_count, MAX(updated_at) AS updated_at FROM "+table+" WHERE user_id = ?")
+		args = append(args, userID)
+	}
+
+	state := &ListState{}
+	if err := s.db.Raw(strings.Join(parts, " UNION ALL ")+" ORDER BY position", args...).Scan(&state.tables).Error; err != nil {
+		return nil, err
+	}
+	for _, table := range state.tables {
+		if table.UpdatedAt != nil && (state.LastModified == nil || table.UpdatedAt.After(*state.LastModified)) {
+			state.LastModified = table.UpdatedAt
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// EventBalanceChanged is pushed to live streams after the events that move money, with the
+// balances of the user's accounts. It is derived for the streams, never stored in the outbox
+const EventBalanceChanged = "balance.changed"
+
+// liveEventsChannel is the Postgres channel telling every instance about events for live
+// streams, whichever instance dispatched them
+const liveEventsChannel = "fluxio_live_events"
+
+// liveEventReplayLimit caps the events replayed to a stream resuming from Last-Event-ID
+const liveEventReplayLimit = 100
+
+// liveEventBuffer is how many events a stream can fall behind before new ones are dropped
+const liveEventBuffer = 32
+
+// liveEvents are the outbox events pushed to live streams. Security events are left out: some
+// carry verification codes
+var liveEvents = map[string]bool{
+	EventExpenseCreated:    true,
+	EventIncomeCreated:     true,
+	EventBudgetCreated:     true,
+	EventBudgetUpdated:     true,
+	EventBudgetExceeded:    true,
+	EventTransferCompleted: true,
+	EventReminderDue:       true,
+	EventGoalFunded:        true,
+}
+
+// balanceEvents are the live events followed by a balance.changed event
+var balanceEvents = map[string]bool{
+	EventExpenseCreated:    true,
+	EventIncomeCreated:     true,
+	EventTransferCompleted: true,
+}
+
+// LiveEventTypes returns the event types a live stream can carry
+func LiveEventTypes() []string {
+	return []string{
+		EventExpenseCreated, EventIncomeCreated, EventBudgetCreated, EventBudgetUpdated, EventBudgetExceeded,
+		EventTransferCompleted, EventReminderDue, EventGoalFunded, EventBalanceChanged,
+	}
+}
+
+// liveSubscribers are the live streams open on this instance, per user
+var liveSubscribers = struct {
+	sync.Mutex
+	channels map[string]map[chan dto.LiveEvent]struct{}
+}{channels: make(map[string]map[chan dto.LiveEvent]struct{})}
+
+// streamsClosing is closed when the server shuts down, ending the open event streams, which
+// would otherwise keep the shutdown waiting until they time out
+var (
+	streamsClosing   = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// CloseStreams ends the server-sent event streams open on this instance
+func CloseStreams() {
+	closeStreamsOnce.Do(func() { close(streamsClosing) })
+}
+
+// StreamsClosing is closed once open streams must end
+func StreamsClosing() <-chan struct{} {
+	return streamsClosing
+}
+
+// SubscribeLiveEvents returns a channel receiving the user's live events. Call cancel when done
+// listening
+func SubscribeLiveEvents(userID string) (events <-chan dto.LiveEvent, cancel func()) {
+	channel := make(chan dto.LiveEvent, liveEventBuffer)
+	liveSubscribers.Lock()
+	if liveSubscribers.channels[userID] == nil {
+		liveSubscribers.channels[userID] = make(map[chan dto.LiveEvent]struct{})
+	}
+	liveSubscribers.channels[userID][channel] = struct{}{}
+	liveSubscribers.Unlock()
+
+	return channel, func() {
+		liveSubscribers.Lock()
+		defer liveSubscribers.Unlock()
+		delete(liveSubscribers.channels[userID], channel)
+		if len(liveSubscribers.channels[userID]) == 0 {
+			delete(liveSubscribers.channels, userID)
+		}
+	}
+}
+
+func hasLiveSubscribers(userID string) bool {
+	liveSubscribers.Lock()
+	defer liveSubscribers.Unlock()
+	return len(liveSubscribers.channels[userID]) > 0
+}
+
+// publishLiveEvent hands the event to the user's streams on this instance. A stream too far
+// behind misses it rather than holding up the others
+func publishLiveEvent(userID string, event dto.LiveEvent) {
+	liveSubscribers.Lock()
+	defer liveSubscribers.Unlock()
+	for channel := range liveSubscribers.channels[userID] {
+		select {
+		case channel <- event:
+		default:
+			logger.Warn("Live stream of user %s is behind, dropping %s", userID, event.Type)
+		}
+	}
+}
+
+// isLiveEvent tells whether an outbox event goes to live streams. Reminders are sent once,
+// not again for every channel they escalate to
+func isLiveEvent(event models.OutboxEvent) bool {
+	if !liveEvents[event.EventType] {
+		return false
+	}
+	if event.EventType == EventReminderDue {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err == nil && payload["escalated_from"] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func toLiveEvent(event models.OutboxEvent) dto.LiveEvent {
+	return dto.LiveEvent{
+		ID:            event.ID.String(),
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID.String(),
+		CreatedAt:     event.CreatedAt,
+		Data:          json.RawMessage(event.Payload),
+	}
+}
+
+// liveBalancesEvent builds the balance.changed event with the user's current balances
+func (s *Services) liveBalancesEvent(userID string) (dto.LiveEvent, error) {
+	var accounts []models.BankAccount
+	if err := s.db.Where("user_id = ? AND status = ?", userID, models.StatusActive).
+		Order("account_name ASC").Find(&accounts).Error; err != nil {
+		return dto.LiveEvent{}, err
+	}
+	balances := dto.LiveBalances{Accounts: make([]dto.LiveAccountBalance, 0, len(accounts))}
+	for _, account := range accounts {
+		balances.Accounts = append(balances.Accounts, dto.LiveAccountBalance{
+			BankAccountID: account.ID.String(),
+			AccountName:   account.AccountName,
+			Balance:       account.Balance,
+		})
+	}
+	data, err := json.Marshal(balances)
+	if err != nil {
+		return dto.LiveEvent{}, err
+	}
+	return dto.LiveEvent{Type: EventBalanceChanged, CreatedAt: time.Now(), Data: data}, nil
+}
+
+// NotifyLiveEvent is the outbox handler feeding live streams. It tells every instance through
+// Postgres; live updates are best effort, so failures are logged and don't hold up the event
+func (s *Services) NotifyLiveEvent(event models.OutboxEvent) error {
+	if !isLiveEvent(event) {
+		return nil
+	}
+	if err := s.db.Exec("SELECT pg_notify(?, ?)", liveEventsChannel,
+		event.UserID.String()+" "+event.ID.String()).Error; err != nil {
+		logger.Warn("Error notifying live event %s: %v", event.ID, err)
+	}
+	return nil
+}
+
+// deliverLiveNotification pushes the notified event to the streams of this instance, loading it
+// only when its user has one open
+func (s *Services) deliverLiveNotification(payload string) {
+	userID, eventID, ok := strings.Cut(payload, " ")
+	if !ok || !hasLiveSubscribers(userID) {
+		return
+	}
+
+	var event models.OutboxEvent
+	if err := s.db.Where("id = ? AND user_id = ?", eventID, userID).First(&event).Error; err != nil {
+		logger.Warn("Error loading live event %s: %v", eventID, err)
+		return
+	}
+	publishLiveEvent(userID, toLiveEvent(event))
+	if balanceEvents[event.EventType] {
+		balances, err := s.liveBalancesEvent(userID)
+		if err != nil {
+			logger.Warn("Error getting balances for live stream of user %s: %v", userID, err)
+			return
+		}
+		publishLiveEvent(userID, balances)
+	}
+}
+
+// listenNotifications holds a pool connection listening on the Postgres channel until it fails,
+// passing deliver the payload of every notification. The connection is discarded afterwards
+// rather than returned to the pool still listening
+func (s *Services) listenNotifications(ctx context.Context, channel string, deliver func(payload string)) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	conn.Raw(func(driverConn interface{}) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("unexpected database driver %T", driverConn)
+			return driver.ErrBadConn
+		}
+		pgxConn := stdlibConn.Conn()
+		if _, listenErr = pgxConn.Exec(ctx, "LISTEN "+channel); listenErr != nil {
+			return driver.ErrBadConn
+		}
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+			deliver(notification.Payload)
+		}
+	})
+	return listenErr
+}
+
+// startNotificationListener listens on the channel in the background, reconnecting after
+// failures
+func (s *Services) startNotificationListener(name string, channel string, deliver func(payload string)) {
+	go func() {
+		for {
+			err := s.listenNotifications(context.Background(), channel, deliver)
+			logger.Warn("%s listener stopped, reconnecting: %v", name, err)
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
+
+// StartLiveEventListener receives on this instance the events notified by any instance
+func (s *Services) StartLiveEventListener() {
+	s.startNotificationListener("Live event", liveEventsChannel, s.deliverLiveNotification)
+}
+
+// ListLiveEventsSince returns the user's live events recorded after the event lastEventID, oldest
+// first, for a stream resuming where it left off. balance.changed events aren't replayed
+func (s *Services) ListLiveEventsSince(userID string, lastEventID string) ([]dto.LiveEvent, error) {
+	var last models.OutboxEvent
+	if err := s.db.Where("id = ? AND user_id = ?", lastEventID, userID).First(&last).Error; err != nil {
+		return nil, errors.New("last event not found")
+	}
+
+	types := make([]string, 0, len(liveEvents))
+	for eventType := range liveEvents {
+		types = append(types, eventType)
+	}
+	var events []models.OutboxEvent
+	if err := s.db.Where("user_id = ? AND event_type IN ? AND (created_at, id) > (?, ?)",
+		userID, types, last.CreatedAt, last.ID).
+		Order("created_at ASC, id ASC").Limit(liveEventReplayLimit).Find(&events).Error; err != nil {
+		logger.Error("Error listing live events: %v", err)
+		return nil, errors.New("error listing live events")
+	}
+
+	replay := make([]dto.LiveEvent, 0, len(events))
+	for _, event := range events {
+		if isLiveEvent(event) {
+			replay = append(replay, toLiveEvent(event))
+		}
+	}
+	return replay, nil
+}
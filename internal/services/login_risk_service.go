@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Security domain events, delivered by the notification handlers
+const (
+	EventSecurityNewDeviceLogin = "security.new_device_login"
+)
+
+const (
+	loginHistorySize       = 50
+	loginChallengeTTL      = 10 * time.Minute
+	loginChallengeAttempts = 5
+)
+
+// ErrInvalidLoginChallenge is returned for unknown, expired, exhausted or wrong step-up codes
+var ErrInvalidLoginChallenge = errors.New("invalid or expired verification code")
+
+// LoginContext is the metadata of a login request
+type LoginContext struct {
+	IPAddress string
+	Country   string // ISO country code from the proxy, empty when unknown
+	UserAgent string
+}
+
+// LoginRisk is the result of comparing a login with the user's history
+type LoginRisk struct {
+	Score          int
+	Reasons        []string
+	NewDevice      bool
+	StepUpRequired bool
+}
+
+// loginStepUpThreshold is the risk score from which a login needs step-up verification
+func loginStepUpThreshold() int {
+	return envInt("LOGIN_STEP_UP_THRESHOLD", 2)
+}
+
+// deviceID identifies a device by its user agent
+func deviceID(userAgent string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(userAgent)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// networkOf returns the /24 (IPv4) or /48 (IPv6) network of an address, so a new address
+// from the same provider isn't flagged
+func networkOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func optionalCountry(country string) *string {
+	if country == "" {
+		return nil
+	}
+	upper := strings.ToUpper(country)
+	return &upper
+}
+
+// AssessLoginRisk scores a login against the user's recent successful logins: a new device
+// and a new network add one point each, a new country adds two. The first login of a user
+// has no history to deviate from and is never flagged.
+func (s *Services) AssessLoginRisk(userID uuid.UUID, login LoginContext) (*LoginRisk, error) {
+	var history []models.SecurityEvent
+	if err := s.db.Where("user_id = ? AND event_type IN ?", userID,
+		[]models.SecurityEventType{models.SecurityEventLogin, models.SecurityEventStepUpVerified, models.SecurityEventOAuthLogin}).
+		Order("created_at DESC").Limit(loginHistorySize).Find(&history).Error; err != nil {
+		logger.Error("Error getting login history: %v", err)
+		return nil, err
+	}
+
+	risk := &LoginRisk{}
+	if len(history) == 0 {
+		return risk, nil
+	}
+
+	knownDevice, knownNetwork, knownCountry, anyCountry := false, false, false, false
+	device, network := deviceID(login.UserAgent), networkOf(login.IPAddress)
+	for _, event := range history {
+		knownDevice = knownDevice || event.DeviceID == device
+		knownNetwork = knownNetwork || networkOf(event.IPAddress) == network
+		if event.Country != nil {
+			anyCountry = true
+			knownCountry = knownCountry || strings.EqualFold(*event.Country, login.Country)
+		}
+	}
+
+	if !knownDevice {
+		risk.NewDevice = true
+		risk.Score++
+		risk.Reasons = append(risk.Reasons, "new_device")
+	}
+	if !knownNetwork {
+		risk.Score++
+		risk.Reasons = append(risk.Reasons, "new_network")
+	}
+	if login.Country != "" && anyCountry && !knownCountry {
+		risk.Score += 2
+		risk.Reasons = append(risk.Reasons, "new_country")
+	}
+	risk.StepUpRequired = risk.Score >= loginStepUpThreshold()
+
+	return risk, nil
+}
+
+// recordSecurityEvent stores an authentication event using the given connection or transaction
+func recordSecurityEvent(tx *gorm.DB, userID uuid.UUID, eventType models.SecurityEventType, login LoginContext, risk *LoginRisk) (*models.SecurityEvent, error) {
+	event := models.SecurityEvent{
+		UserID:    userID,
+		EventType: eventType,
+		IPAddress: login.IPAddress,
+		Country:   optionalCountry(login.Country),
+		UserAgent: login.UserAgent,
+		DeviceID:  deviceID(login.UserAgent),
+	}
+	if len(event.UserAgent) > 512 {
+		event.UserAgent = event.UserAgent[:512]
+	}
+	if risk != nil {
+		event.NewDevice = risk.NewDevice
+		event.RiskScore = risk.Score
+		event.RiskReasons = strings.Join(risk.Reasons, ",")
+	}
+
+	if err := tx.Create(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// RecordFailedLogin stores a failed password attempt for a known user
+func (s *Services) RecordFailedLogin(userID uuid.UUID, login LoginContext) {
+	if _, err := recordSecurityEvent(s.db, userID, models.SecurityEventLoginFailed, login, nil); err != nil {
+		logger.Error("Error recording failed login for user %s: %v", userID, err)
+	}
+}
+
+// RecordSuccessfulLogin stores a login and, when it comes from a new device, emits the
+// "new device signed in" notification event in the same transaction
+func (s *Services) RecordSuccessfulLogin(userID uuid.UUID, eventType models.SecurityEventType, login LoginContext, risk *LoginRisk) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		event, err := recordSecurityEvent(tx, userID, eventType, login, risk)
+		if err != nil {
+			return err
+		}
+		if !event.NewDevice {
+			return nil
+		}
+
+		return EnqueueEvent(tx, userID, EventSecurityNewDeviceLogin, "security_event", event.ID, map[string]interface{}{
+			"ip_address": event.IPAddress,
+			"country":    event.Country,
+			"user_agent": event.UserAgent,
+			"at":         event.CreatedAt,
+		})
+	})
+}
+
+func hashLoginCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateLoginChallenge starts a step-up verification for a risky login. The 6-digit code is
+// emailed once the challenge is stored, straight to the mailer rather than through the outbox so
+// it's never persisted; only its hash is kept on the challenge. A code that can't be sent is
+// replaced by signing in again.
+func (s *Services) CreateLoginChallenge(user *models.User, login LoginContext, risk *LoginRisk) (*models.LoginChallenge, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	challenge := models.LoginChallenge{
+		UserID:    user.ID,
+		CodeHash:  hashLoginCode(code),
+		IPAddress: login.IPAddress,
+		Country:   optionalCountry(login.Country),
+		UserAgent: login.UserAgent,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	}
+	if len(challenge.UserAgent) > 512 {
+		challenge.UserAgent = challenge.UserAgent[:512]
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&challenge).Error; err != nil {
+			return err
+		}
+		_, err := recordSecurityEvent(tx, user.ID, models.SecurityEventStepUpRequired, login, risk)
+		return err
+	})
+	if err != nil {
+		logger.Error("Error creating login challenge: %v", err)
+		return nil, errors.New("error creating login challenge")
+	}
+
+	if err := sendStepUpCodeEmail(user.Email, code, challenge.ExpiresAt, login.IPAddress); err != nil {
+		logger.Warn("Login code for challenge %s not emailed: %v", challenge.ID, err)
+	}
+
+	return &challenge, nil
+}
+
+// VerifyLoginChallenge checks the step-up code of a challenge and returns the user to sign in
+func (s *Services) VerifyLoginChallenge(challengeID string, code string) (*models.User, error) {
+	var challenge models.LoginChallenge
+	if err := s.db.Where("id = ? AND verified_at IS NULL", challengeID).First(&challenge).Error; err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= loginChallengeAttempts {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	login := LoginContext{IPAddress: challenge.IPAddress, UserAgent: challenge.UserAgent}
+	if challenge.Country != nil {
+		login.Country = *challenge.Country
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashLoginCode(code)), []byte(challenge.CodeHash)) != 1 {
+		s.db.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1"))
+		if _, err := recordSecurityEvent(s.db, challenge.UserID, models.SecurityEventStepUpFailed, login, nil); err != nil {
+			logger.Error("Error recording failed step-up: %v", err)
+		}
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.LoginChallenge{}).
+		Where("id = ? AND verified_at IS NULL", challenge.ID).
+		Update("verified_at", &now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.GetUserByID(challenge.UserID.String())
+	if err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	// Re-assess so the verified login carries its reasons and triggers the new device notice
+	risk, err := s.AssessLoginRisk(user.ID, login)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.RecordSuccessfulLogin(user.ID, models.SecurityEventStepUpVerified, login, risk); err != nil {
+		logger.Error("Error recording verified login: %v", err)
+	}
+
+	return user, nil
+}
+
+// GetSecurityEvents returns the most recent authentication events of the user
+func (s *Services) GetSecurityEvents(userID string, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		logger.Error("Error getting security events: %v", err)
+		return nil, errors.New("error getting security events")
+	}
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaintenanceStatus describes the read-only maintenance switch of the API
+type MaintenanceStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Reason            string     `json:"reason,omitempty"`
+	RetryAfterSeconds int        `json:"retry_after_seconds"`
+	Since             *time.Time `json:"since,omitempty"`
+}
+
+// maintenanceSettingID is the single row of maintenance_settings
+const maintenanceSettingID = 1
+
+// maintenanceCache keeps the switch, stored in the database so it reaches every instance, under
+// the empty key: the middleware checks it on every request. Changes drop it on every instance
+var maintenanceCache = newUserCache[MaintenanceStatus]()
+
+func toMaintenanceStatus(setting models.MaintenanceSetting) MaintenanceStatus {
+	status := MaintenanceStatus{
+		Enabled:           setting.Enabled,
+		Reason:            setting.Reason,
+		RetryAfterSeconds: setting.RetryAfterSeconds,
+		Since:             setting.Since,
+	}
+	if status.RetryAfterSeconds <= 0 {
+		status.RetryAfterSeconds = envInt("MAINTENANCE_RETRY_AFTER_SECONDS", 300)
+	}
+	return status
+}
+
+// IsMaintenanceMode reports whether the API is currently read-only
+func (s *Services) IsMaintenanceMode() bool {
+	return s.GetMaintenanceStatus().Enabled
+}
+
+// GetMaintenanceStatus returns the current maintenance switch
+func (s *Services) GetMaintenanceStatus() MaintenanceStatus {
+	return maintenanceCache.get("", func() (MaintenanceStatus, bool) {
+		var setting models.MaintenanceSetting
+		if err := s.db.Where("id = ?", maintenanceSettingID).Limit(1).Find(&setting).Error; err != nil {
+			logger.Error("Error loading maintenance mode: %v", err)
+			return toMaintenanceStatus(setting), false
+		}
+		return toMaintenanceStatus(setting), true
+	})
+}
+
+// EnableMaintenanceModeFromEnv turns read-only mode on for every instance when the instance
+// starts with MAINTENANCE_MODE=true, so a deploy can boot straight into it
+func (s *Services) EnableMaintenanceModeFromEnv() {
+	if !strings.EqualFold(os.Getenv("MAINTENANCE_MODE"), "true") || s.IsMaintenanceMode() {
+		return
+	}
+	if _, err := s.saveMaintenanceMode(nil, true, "", 0); err != nil {
+		logger.Error("Error enabling maintenance mode from MAINTENANCE_MODE: %v", err)
+		return
+	}
+	logger.Warn("🚧 Maintenance mode enabled by MAINTENANCE_MODE: the API is read-only")
+}
+
+// SetMaintenanceMode turns read-only mode on or off on every instance. A retryAfterSeconds of 0
+// keeps the current value. The change is audited under the admin who made it.
+func (s *Services) SetMaintenanceMode(adminID string, enabled bool, reason string, retryAfterSeconds int) (MaintenanceStatus, error) {
+	if retryAfterSeconds < 0 {
+		return MaintenanceStatus{}, errors.New("retry after seconds cannot be negative")
+	}
+
+	var admin *uuid.UUID
+	if adminUUID, err := uuid.Parse(adminID); err == nil {
+		admin = &adminUUID
+	}
+	status, err := s.saveMaintenanceMode(admin, enabled, reason, retryAfterSeconds)
+	if err != nil {
+		logger.Error("Error saving maintenance mode: %v", err)
+		return MaintenanceStatus{}, errors.New("error saving maintenance mode")
+	}
+
+	if enabled {
+		logger.Warn("🚧 Maintenance mode enabled: the API is read-only")
+	} else {
+		logger.Info("✅ Maintenance mode disabled")
+	}
+
+	// The switch itself is audited even though the rest of the API is read-only
+	if admin != nil {
+		s.RecordAudit(*admin, "maintenance.updated", "system", nil, map[string]interface{}{
+			"enabled": enabled,
+			"reason":  status.Reason,
+		})
+	}
+
+	return status, nil
+}
+
+// saveMaintenanceMode stores the switch and drops it from the cache of every instance
+func (s *Services) saveMaintenanceMode(admin *uuid.UUID, enabled bool, reason string, retryAfterSeconds int) (MaintenanceStatus, error) {
+	var setting models.MaintenanceSetting
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", maintenanceSettingID).Limit(1).Find(&setting).Error; err != nil {
+			return err
+		}
+		setting.ID = maintenanceSettingID
+		if enabled && !setting.Enabled {
+			now := time.Now()
+			setting.Since = &now
+		}
+		if !enabled {
+			setting.Since = nil
+			reason = ""
+		}
+		setting.Enabled = enabled
+		setting.Reason = reason
+		if retryAfterSeconds > 0 {
+			setting.RetryAfterSeconds = retryAfterSeconds
+		}
+		setting.UpdatedBy = admin
+		if err := tx.Save(&setting).Error; err != nil {
+			return err
+		}
+		return invalidateUserCache(tx, "maintenance", "")
+	})
+	if err != nil {
+		return MaintenanceStatus{}, err
+	}
+	return toMaintenanceStatus(setting), nil
+}
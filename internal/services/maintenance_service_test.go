@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/middleware"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestMaintenanceModeIsStoredAndExemptsOnlySessions(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	admin := h.CreateUser(t)
+
+	if _, err := h.Services.SetMaintenanceMode(admin.ID.String(), true, "upgrade", 60); err != nil {
+		t.Fatalf("enabling maintenance mode: %v", err)
+	}
+	t.Cleanup(func() {
+		h.Services.SetMaintenanceMode(admin.ID.String(), false, "", 0)
+	})
+
+	// Other instances read the switch from the database
+	var setting models.MaintenanceSetting
+	if err := h.DB.First(&setting).Error; err != nil {
+		t.Fatalf("loading the stored switch: %v", err)
+	}
+	if !setting.Enabled || setting.Reason != "upgrade" || setting.RetryAfterSeconds != 60 {
+		t.Fatalf("stored switch = %+v, want enabled for upgrade with a 60s retry", setting)
+	}
+	if !h.Services.IsMaintenanceMode() {
+		t.Fatal("maintenance mode is off right after enabling it")
+	}
+
+	handler := middleware.MaintenanceMiddleware(h.Services, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for path, want := range map[string]int{
+		"/api/v1/auth/login":    http.StatusNoContent,
+		"/api/v1/auth/refresh":  http.StatusNoContent,
+		"/api/v1/auth/logout":   http.StatusNoContent,
+		"/api/v1/auth/register": http.StatusServiceUnavailable,
+		"/api/v1/auth/api-keys": http.StatusServiceUnavailable,
+		"/api/v1/expenses":      http.StatusServiceUnavailable,
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+		if recorder.Code != want {
+			t.Errorf("POST %s during maintenance got %d, want %d", path, recorder.Code, want)
+		}
+	}
+
+	if _, err := h.Services.SetMaintenanceMode(admin.ID.String(), false, "", 0); err != nil {
+		t.Fatalf("disabling maintenance mode: %v", err)
+	}
+	if h.Services.IsMaintenanceMode() {
+		t.Fatal("maintenance mode is still on after disabling it")
+	}
+}
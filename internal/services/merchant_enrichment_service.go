@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// MerchantInfo is what an enrichment provider knows about a bank descriptor
+type MerchantInfo struct {
+	Name     string  `json:"name"`
+	LogoURL  *string `json:"logo_url,omitempty"`
+	Category *string `json:"category,omitempty"` // Suggested category name, matched against the user's categories
+	Provider string  `json:"provider"`
+}
+
+// MerchantEnrichmentProvider turns a raw bank descriptor ("AMZN MKTP US*2K4") into a merchant.
+// Enrich returns nil without error when the descriptor is not recognized
+type MerchantEnrichmentProvider interface {
+	Name() string
+	Enrich(descriptor string) (*MerchantInfo, error)
+}
+
+var errMerchantRateLimited = errors.New("merchant enrichment rate limit reached")
+
+var (
+	descriptorNoisePattern  = regexp.MustCompile(`[#*]?\d{3,}|\s+\d+\b`)
+	descriptorSpacesPattern = regexp.MustCompile(`\s+`)
+	descriptorPrefixes      = []string{"POS ", "PURCHASE ", "COMPRA ", "PAGO ", "SQ *", "TST* ", "PAYPAL *", "PP*"}
+)
+
+// normalizeDescriptor strips card terminal noise so the same merchant gives the same key
+func normalizeDescriptor(descriptor string) string {
+	normalized := strings.ToUpper(strings.TrimSpace(descriptor))
+	for _, prefix := range descriptorPrefixes {
+		normalized = strings.TrimPrefix(normalized, prefix)
+	}
+	normalized = descriptorNoisePattern.ReplaceAllString(normalized, " ")
+	return strings.TrimSpace(descriptorSpacesPattern.ReplaceAllString(normalized, " "))
+}
+
+// === LOCAL DATASET ===
+
+type localMerchant struct {
+	patterns []string // Substrings of the normalized descriptor
+	name     string
+	domain   string
+	category string // One of the default category names
+}
+
+var localMerchants = []localMerchant{
+	{[]string{"AMZN", "AMAZON"}, "Amazon", "amazon.com", "Shopping"},
+	{[]string{"MERCADOLIBRE", "MERCADO LIBRE", "MERCADOPAGO"}, "Mercado Libre", "mercadolibre.com", "Shopping"},
+	{[]string{"WALMART", "WAL-MART", "WM SUPERCENTER"}, "Walmart", "walmart.com", "Alimentación"},
+	{[]string{"COSTCO"}, "Costco", "costco.com", "Alimentación"},
+	{[]string{"SORIANA"}, "Soriana", "soriana.com", "Alimentación"},
+	{[]string{"CARREFOUR"}, "Carrefour", "carrefour.com", "Alimentación"},
+	{[]string{"MERCADONA"}, "Mercadona", "mercadona.es", "Alimentación"},
+	{[]string{"OXXO"}, "OXXO", "oxxo.com", "Alimentación"},
+	{[]string{"UBER EATS", "UBEREATS"}, "Uber Eats", "ubereats.com", "Restaurantes"},
+	{[]string{"RAPPI"}, "Rappi", "rappi.com", "Restaurantes"},
+	{[]string{"STARBUCKS"}, "Starbucks", "starbucks.com", "Restaurantes"},
+	{[]string{"MCDONALD"}, "McDonald's", "mcdonalds.com", "Restaurantes"},
+	{[]string{"UBER"}, "Uber", "uber.com", "Transporte"},
+	{[]string{"DIDI"}, "DiDi", "didiglobal.com", "Transporte"},
+	{[]string{"SHELL"}, "Shell", "shell.com", "Transporte"},
+	{[]string{"PEMEX"}, "Pemex", "pemex.com", "Transporte"},
+	{[]string{"NETFLIX"}, "Netflix", "netflix.com", "Entretenimiento"},
+	{[]string{"SPOTIFY"}, "Spotify", "spotify.com", "Entretenimiento"},
+	{[]string{"DISNEY PLUS", "DISNEYPLUS"}, "Disney+", "disneyplus.com", "Entretenimiento"},
+	{[]string{"STEAM", "STEAMGAMES"}, "Steam", "steampowered.com", "Hobbies"},
+	{[]string{"APPLE.COM", "APPLE COM BILL"}, "Apple", "apple.com", "Entretenimiento"},
+	{[]string{"CFE"}, "CFE", "cfe.mx", "Servicios básicos"},
+	{[]string{"TELMEX"}, "Telmex", "telmex.com", "Servicios básicos"},
+	{[]string{"TELCEL"}, "Telcel", "telcel.com", "Servicios básicos"},
+	{[]string{"AIRBNB"}, "Airbnb", "airbnb.com", "Viajes"},
+	{[]string{"BOOKING.COM", "BOOKING COM"}, "Booking.com", "booking.com", "Viajes"},
+	{[]string{"AEROMEXICO"}, "Aeroméxico", "aeromexico.com", "Viajes"},
+	{[]string{"VIVAAEROBUS", "VIVA AEROBUS"}, "Viva Aerobus", "vivaaerobus.com", "Viajes"},
+	{[]string{"FARMACIA GUADALAJARA", "FARM GUADALAJARA"}, "Farmacias Guadalajara", "farmaciasguadalajara.com", "Salud"},
+	{[]string{"FARMACIAS DEL AHORRO"}, "Farmacias del Ahorro", "fahorro.com", "Salud"},
+}
+
+// localMerchantProvider recognizes well-known merchants from a built-in list
+type localMerchantProvider struct {
+	logoBaseURL string // e.g. https://logos.example.com/ ; empty means no logos
+}
+
+func (p *localMerchantProvider) Name() string { return "local" }
+
+func (p *localMerchantProvider) Enrich(descriptor string) (*MerchantInfo, error) {
+	normalized := normalizeDescriptor(descriptor)
+	for _, merchant := range localMerchants {
+		for _, pattern := range merchant.patterns {
+			if !strings.Contains(normalized, pattern) {
+				continue
+			}
+			category := merchant.category
+			info := &MerchantInfo{Name: merchant.name, Category: &category, Provider: p.Name()}
+			if p.logoBaseURL != "" {
+				logo := strings.TrimSuffix(p.logoBaseURL, "/") + "/" + merchant.domain
+				info.LogoURL = &logo
+			}
+			return info, nil
+		}
+	}
+	return nil, nil
+}
+
+// === REMOTE API ===
+
+// httpMerchantProvider asks an external enrichment API:
+// GET {url}?descriptor=... -> {"name": "...", "logo_url": "...", "category": "..."}, 404 when unknown
+type httpMerchantProvider struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (p *httpMerchantProvider) Name() string { return "remote" }
+
+func (p *httpMerchantProvider) Enrich(descriptor string) (*MerchantInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, p.endpoint+"?descriptor="+url.QueryEscape(descriptor), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, errMerchantRateLimited
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("merchant enrichment API returned %d", resp.StatusCode)
+	}
+
+	var info MerchantInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(info.Name) == "" {
+		return nil, nil
+	}
+	info.Provider = p.Name()
+	return &info, nil
+}
+
+// === CACHE AND RATE LIMIT ===
+
+type merchantCacheEntry struct {
+	info      *MerchantInfo // nil caches "unknown"
+	expiresAt time.Time
+}
+
+// merchantRateLimiter is a token bucket refilled continuously up to perMinute tokens
+type merchantRateLimiter struct {
+	mu        sync.Mutex
+	perMinute float64
+	tokens    float64
+	last      time.Time
+}
+
+func (l *merchantRateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Minutes() * l.perMinute
+	if l.tokens > l.perMinute {
+		l.tokens = l.perMinute
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// maxMerchantCacheEntries bounds the memory used by the descriptor cache
+const maxMerchantCacheEntries = 10000
+
+// merchantEnricher looks descriptors up in the remote provider, when configured and within
+// its rate limit, and falls back to the local dataset. Results are cached by normalized descriptor
+type merchantEnricher struct {
+	remote  MerchantEnrichmentProvider
+	local   MerchantEnrichmentProvider
+	limiter *merchantRateLimiter
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]merchantCacheEntry
+}
+
+var (
+	merchantEnricherOnce     sync.Once
+	defaultMerchantEnricher  *merchantEnricher
+	merchantEnrichmentActive = os.Getenv("MERCHANT_ENRICHMENT") != "off"
+)
+
+// getMerchantEnricher builds the enricher from the environment the first time it is needed
+func getMerchantEnricher() *merchantEnricher {
+	merchantEnricherOnce.Do(func() {
+		perMinute := float64(envInt("MERCHANT_ENRICHMENT_RATE_PER_MINUTE", 60))
+		enricher := &merchantEnricher{
+			local:   &localMerchantProvider{logoBaseURL: os.Getenv("MERCHANT_LOGO_BASE_URL")},
+			limiter: &merchantRateLimiter{perMinute: perMinute, tokens: perMinute, last: time.Now()},
+			ttl:     time.Duration(envInt("MERCHANT_ENRICHMENT_CACHE_HOURS", 24)) * time.Hour,
+			cache:   make(map[string]merchantCacheEntry),
+		}
+		if endpoint := os.Getenv("MERCHANT_ENRICHMENT_URL"); endpoint != "" {
+			enricher.remote = &httpMerchantProvider{
+				endpoint: endpoint,
+				apiKey:   os.Getenv("MERCHANT_ENRICHMENT_API_KEY"),
+				client:   &http.Client{Timeout: 3 * time.Second},
+			}
+		}
+		defaultMerchantEnricher = enricher
+	})
+	return defaultMerchantEnricher
+}
+
+func (e *merchantEnricher) cached(key string) (merchantCacheEntry, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return merchantCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (e *merchantEnricher) store(key string, info *MerchantInfo) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache) >= maxMerchantCacheEntries {
+		e.cache = make(map[string]merchantCacheEntry) // Simple reset; entries are cheap to rebuild
+	}
+	e.cache[key] = merchantCacheEntry{info: info, expiresAt: time.Now().Add(e.ttl)}
+}
+
+func (e *merchantEnricher) enrich(descriptor string) *MerchantInfo {
+	key := normalizeDescriptor(descriptor)
+	if key == "" {
+		return nil
+	}
+	if entry, ok := e.cached(key); ok {
+		return entry.info
+	}
+
+	if e.remote != nil {
+		if !e.limiter.allow() {
+			// Over the limit: answer from the local dataset without caching, so the
+			// descriptor gets a remote lookup next time
+			info, _ := e.local.Enrich(descriptor)
+			return info
+		}
+		info, err := e.remote.Enrich(descriptor)
+		if err == nil {
+			if info == nil {
+				info, _ = e.local.Enrich(descriptor)
+			}
+			e.store(key, info)
+			return info
+		}
+		logger.Warn("Merchant enrichment with %s failed, using local dataset: %v", e.remote.Name(), err)
+		info, _ = e.local.Enrich(descriptor)
+		return info
+	}
+
+	info, _ := e.local.Enrich(descriptor)
+	e.store(key, info)
+	return info
+}
+
+// EnrichMerchant normalizes a bank descriptor into a merchant, or returns nil if it is not
+// recognized or enrichment is turned off (MERCHANT_ENRICHMENT=off)
+func EnrichMerchant(descriptor string) *MerchantInfo {
+	if !merchantEnrichmentActive {
+		return nil
+	}
+	return getMerchantEnricher().enrich(descriptor)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// DefaultLocale is used when the client asks for no locale or one without labels
+const DefaultLocale = "en"
+
+// enumLabels holds the labels of every enum value by locale, keyed "<enum>.<value>"
+var enumLabels = map[string]map[string]string{
+	"en": {
+		"status.active":               "Active",
+		"status.pending":              "Pending",
+		"status.suspended":            "Suspended",
+		"status.archived":             "Archived",
+		"status.locked":               "Locked",
+		"status.deleted":              "Deleted",
+		"expense_type.needs":          "Needs",
+		"expense_type.wants":          "Wants",
+		"expense_type.savings":        "Savings",
+		"recurrence_type.monthly":     "Monthly",
+		"recurrence_type.yearly":      "Yearly",
+		"reminder_type.bill":          "Bill",
+		"reminder_type.goal":          "Goal",
+		"reminder_type.budget_review": "Budget review",
+		"cap_mode.alert":              "Alert only",
+		"cap_mode.hard":               "Block",
+		"income_source.salary":        "Salary",
+		"income_source.freelance":     "Freelance",
+		"income_source.business":      "Business",
+		"income_source.dividends":     "Dividends",
+		"income_source.interest":      "Interest",
+		"income_source.rental":        "Rental",
+		"income_source.gift":          "Gifts",
+		"income_source.refund":        "Refunds",
+		"income_source.other":         "Other",
+	},
+	"es": {
+		"status.active":               "Activo",
+		"status.pending":              "Pendiente",
+		"status.suspended":            "Suspendido",
+		"status.archived":             "Archivado",
+		"status.locked":               "Bloqueado",
+		"status.deleted":              "Eliminado",
+		"expense_type.needs":          "Necesidades",
+		"expense_type.wants":          "Deseos",
+		"expense_type.savings":        "Ahorro",
+		"recurrence_type.monthly":     "Mensual",
+		"recurrence_type.yearly":      "Anual",
+		"reminder_type.bill":          "Factura",
+		"reminder_type.goal":          "Meta",
+		"reminder_type.budget_review": "Revisión de presupuesto",
+		"cap_mode.alert":              "Solo alertar",
+		"cap_mode.hard":               "Bloquear",
+		"income_source.salary":        "Salario",
+		"income_source.freelance":     "Trabajo independiente",
+		"income_source.business":      "Negocio",
+		"income_source.dividends":     "Dividendos",
+		"income_source.interest":      "Intereses",
+		"income_source.rental":        "Rentas",
+		"income_source.gift":          "Regalos",
+		"income_source.refund":        "Reembolsos",
+		"income_source.other":         "Otros",
+	},
+}
+
+// ResolveLocale picks the first supported locale of an Accept-Language style list
+// (e.g. "es-MX,es;q=0.9,en;q=0.8"), falling back to DefaultLocale
+func ResolveLocale(requested string) string {
+	for _, tag := range strings.Split(requested, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), "-")
+		tag = strings.ToLower(tag)
+		if _, ok := enumLabels[tag]; ok {
+			return tag
+		}
+	}
+	return DefaultLocale
+}
+
+// enumLabel returns the label of a value, falling back to the default locale and then the value
+func enumLabel(locale, enum, value string) string {
+	if label, ok := enumLabels[locale][enum+"."+value]; ok {
+		return label
+	}
+	if label, ok := enumLabels[DefaultLocale][enum+"."+value]; ok {
+		return label
+	}
+	return value
+}
+
+func enumValues(locale, enum string, values []string) []dto.EnumValue {
+	result := make([]dto.EnumValue, len(values))
+	for i, value := range values {
+		result[i] = dto.EnumValue{Value: value, Label: enumLabel(locale, enum, value)}
+	}
+	return result
+}
+
+// GetEnums returns the server-known enumerations labelled in the locale
+func GetEnums(locale string) dto.Enums {
+	locale = ResolveLocale(locale)
+
+	statuses := make([]string, 0, len(models.ValidStatuses()))
+	for _, status := range models.ValidStatuses() {
+		statuses = append(statuses, status.String())
+	}
+
+	transitions := make(map[string]map[string][]string)
+	for _, machine := range models.StatusMachines() {
+		byStatus := make(map[string][]string)
+		for _, t := range machine.Transitions {
+			byStatus[t.From.String()] = append(byStatus[t.From.String()], t.To.String())
+		}
+		transitions[machine.Entity] = byStatus
+	}
+
+	expenseTypes := make([]string, 0, len(models.ValidExpenseTypes()))
+	for _, expenseType := range models.ValidExpenseTypes() {
+		expenseTypes = append(expenseTypes, expenseType.String())
+	}
+
+	return dto.Enums{
+		Locale:          locale,
+		Statuses:        enumValues(locale, "status", statuses),
+		ExpenseTypes:    enumValues(locale, "expense_type", expenseTypes),
+		RecurrenceTypes: enumValues(locale, "recurrence_type", models.ValidRecurrenceTypes()),
+		ReminderTypes:   enumValues(locale, "reminder_type", models.ValidReminderTypes()),
+		CapModes:        enumValues(locale, "cap_mode", []string{string(models.CapModeAlert), string(models.CapModeHard)}),
+		IncomeSources:   enumValues(locale, "income_source", models.ValidIncomeSources()),
+
+		StatusTransitions: transitions,
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// monthLockSkip is the statement setting that lets writes through closed months
+const monthLockSkip = "month_lock:skip"
+
+// allowClosedMonths lets the writes made through tx change closed months, for removing the
+// records of an account altogether
+func allowClosedMonths(tx *gorm.DB) *gorm.DB {
+	return tx.Set(monthLockSkip, true)
+}
+
+// monthLockColumns are, for the tables dated on their own, the columns that move the actuals of
+// a month when updated. Writes to other columns, like descriptions, are left alone
+var monthLockColumns = map[string]map[string]bool{
+	"expenses": {"amount": true, "date": true, "category_id": true, "status": true},
+	"incomes":  {"amount": true, "date": true, "status": true, "refund_of_expense_id": true},
+}
+
+// monthLockChildTables hold rows of an expense, locked with the expense's month
+var monthLockChildTables = map[string]bool{
+	"expense_allocations": true,
+	"expense_attachments": true,
+	"expense_tags":        true,
+}
+
+// MonthLockPlugin refuses, with a *MonthClosedError, writes that would change the actuals of a
+// closed month, whichever service makes them: expenses and their allocations, attachments and
+// tags, and incomes, including refunds of expenses dated in a closed month
+type MonthLockPlugin struct{}
+
+// Name identifies the plugin to GORM
+func (MonthLockPlugin) Name() string {
+	return "month_lock"
+}
+
+// Initialize registers the check before each kind of write
+func (MonthLockPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().Before("gorm:create").Register("month_lock:before_create", func(tx *gorm.DB) {
+			checkMonthLock(tx, "create")
+		}),
+		callbacks.Update().Before("gorm:update").Register("month_lock:before_update", func(tx *gorm.DB) {
+			checkMonthLock(tx, "update")
+		}),
+		callbacks.Delete().Before("gorm:delete").Register("month_lock:before_delete", func(tx *gorm.DB) {
+			checkMonthLock(tx, "delete")
+		}),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monthLockRow is what the check reads of the rows a write matches
+type monthLockRow struct {
+	UserID            uuid.UUID
+	Date              time.Time
+	RefundOfExpenseID *uuid.UUID
+}
+
+// checkMonthLock finds the users and months the write touches, before and after it, and fails
+// it when one of them is closed. It runs in the write's transaction, so ensureMonthsOpen holds
+// the month's lock until the write commits and a close can't snapshot around it
+func checkMonthLock(tx *gorm.DB, op string) {
+	if tx.Error != nil {
+		return
+	}
+	if skip, ok := tx.Get(monthLockSkip); ok && skip == true {
+		return
+	}
+	table := tx.Statement.Table
+	if i := strings.IndexByte(table, ' '); i > 0 {
+		table = table[:i]
+	}
+	columns, dated := monthLockColumns[table]
+	if !dated && !monthLockChildTables[table] {
+		return
+	}
+	if op == "update" && dated && !updatesAnyColumn(tx.Statement, columns) {
+		return
+	}
+
+	session := tx.Session(&gorm.Session{NewDB: true})
+	dates := make(map[uuid.UUID][]time.Time)
+	var expenseIDs []uuid.UUID
+
+	if op == "create" {
+		eachRecord(tx.Statement, func(record reflect.Value) {
+			if dated {
+				userID, _ := fieldValue(record, "UserID").(uuid.UUID)
+				date, _ := fieldValue(record, "Date").(time.Time)
+				dates[userID] = append(dates[userID], date)
+				if refunded := refundedExpenseID(fieldValue(record, "RefundOfExpenseID")); refunded != nil {
+					expenseIDs = append(expenseIDs, *refunded)
+				}
+			} else if expenseID, ok := fieldValue(record, "ExpenseID").(uuid.UUID); ok {
+				expenseIDs = append(expenseIDs, expenseID)
+			}
+		})
+	} else {
+		query, ok := matchedRows(session, tx.Statement, table)
+		if !ok {
+			return
+		}
+		if dated {
+			selects := "user_id, date"
+			if table == "incomes" {
+				selects += ", refund_of_expense_id"
+			}
+			if op == "delete" {
+				// Deleted records no longer count in the actuals
+				query = query.Where("status <> ?", models.StatusDeleted)
+			}
+			var rows []monthLockRow
+			if err := query.Select(selects).Scan(&rows).Error; err != nil {
+				tx.AddError(err)
+				return
+			}
+			newDate, _ := assignedValue(tx.Statement, "date", "Date").(time.Time)
+			newRefunded := refundedExpenseID(assignedValue(tx.Statement, "refund_of_expense_id", "RefundOfExpenseID"))
+			for _, row := range rows {
+				dates[row.UserID] = append(dates[row.UserID], row.Date, newDate)
+				if row.RefundOfExpenseID != nil {
+					expenseIDs = append(expenseIDs, *row.RefundOfExpenseID)
+				}
+			}
+			if newRefunded != nil && len(rows) > 0 {
+				expenseIDs = append(expenseIDs, *newRefunded)
+			}
+		} else if err := query.Distinct("expense_id").Pluck("expense_id", &expenseIDs).Error; err != nil {
+			tx.AddError(err)
+			return
+		}
+	}
+
+	if len(expenseIDs) > 0 {
+		var expenses []monthLockRow
+		if err := session.Model(&models.Expense{}).Select("user_id, date").Where("id IN ?", expenseIDs).
+			Scan(&expenses).Error; err != nil {
+			tx.AddError(err)
+			return
+		}
+		for _, expense := range expenses {
+			dates[expense.UserID] = append(dates[expense.UserID], expense.Date)
+		}
+	}
+
+	for userID, userDates := range dates {
+		if userID == uuid.Nil {
+			continue
+		}
+		if err := ensureMonthsOpen(session, userID.String(), userDates...); err != nil {
+			tx.AddError(err)
+			return
+		}
+	}
+}
+
+// matchedRows queries the rows an update or delete applies to: its conditions and the primary
+// keys of the records it was given. It reports false for writes without either
+func matchedRows(session *gorm.DB, statement *gorm.Statement, table string) (*gorm.DB, bool) {
+	query := session.Table(table)
+	conditioned := false
+	if where, ok := statement.Clauses["WHERE"].Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+		query = query.Clauses(where)
+		conditioned = true
+	}
+	var ids []uuid.UUID
+	eachRecord(statement, func(record reflect.Value) {
+		if id, ok := fieldValue(record, "ID").(uuid.UUID); ok && id != uuid.Nil {
+			ids = append(ids, id)
+		}
+	})
+	if len(ids) > 0 {
+		query = query.Where(table+".id IN ?", ids)
+		conditioned = true
+	}
+	return query, conditioned
+}
+
+// updatesAnyColumn tells whether an update sets any of the columns. Updates from structs may
+// set any of them
+func updatesAnyColumn(statement *gorm.Statement, columns map[string]bool) bool {
+	assignments, ok := statement.Dest.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	for column := range assignments {
+		if columns[column] {
+			return true
+		}
+	}
+	return false
+}
+
+// assignedValue is the value an update sets the column to, from a map or a struct, or nil
+func assignedValue(statement *gorm.Statement, column string, field string) interface{} {
+	if assignments, ok := statement.Dest.(map[string]interface{}); ok {
+		return dereference(assignments[column])
+	}
+	return fieldValue(reflect.ValueOf(statement.Dest), field)
+}
+
+// eachRecord calls fn with every record the statement was given
+func eachRecord(statement *gorm.Statement, fn func(record reflect.Value)) {
+	switch value := reflect.Indirect(statement.ReflectValue); value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			fn(value.Index(i))
+		}
+	case reflect.Struct:
+		fn(value)
+	}
+}
+
+// fieldValue is the named field of a struct record, dereferenced, or nil
+func fieldValue(record reflect.Value, name string) interface{} {
+	record = reflect.Indirect(record)
+	if record.Kind() != reflect.Struct {
+		return nil
+	}
+	field := record.FieldByName(name)
+	if !field.IsValid() {
+		return nil
+	}
+	return dereference(field.Interface())
+}
+
+func dereference(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case *time.Time:
+		if typed != nil {
+			return *typed
+		}
+		return nil
+	case *uuid.UUID:
+		if typed != nil {
+			return *typed
+		}
+		return nil
+	}
+	return value
+}
+
+func refundedExpenseID(value interface{}) *uuid.UUID {
+	if id, ok := value.(uuid.UUID); ok && id != uuid.Nil {
+		return &id
+	}
+	return nil
+}
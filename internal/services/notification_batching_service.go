@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBatchingWindowMinutes limits how long a batching rule can hold notifications back
+const maxBatchingWindowMinutes = 24 * 60
+
+// BatchingRule coalesces bursts of similar notifications, e.g. 20 imported expenses, into a
+// single digest per channel
+type BatchingRule struct {
+	EventType     string   `json:"event_type"`           // Exact type, or a prefix ending in * (e.g. expense.*)
+	Channels      []string `json:"channels,omitempty"`   // Channels batched, defaults to all
+	WindowMinutes int      `json:"window_minutes"`       // Notifications within the window of the first one are coalesced
+	MinEvents     int      `json:"min_events,omitempty"` // Smaller bursts are delivered one by one, defaults to 2
+}
+
+// NotificationBatch is what the dispatcher delivers: one notification, or a digest of several
+type NotificationBatch struct {
+	Channel       string
+	EventType     string
+	Severity      string // The highest of the batch
+	Notifications []Notification
+	Coalesced     bool
+	Summary       string    // Digest text, e.g. "20 expense.created notifications"
+	DeliverAt     time.Time // When the window closes; the batch must wait until then
+}
+
+func (rule *BatchingRule) validate() error {
+	if rule.EventType == "" {
+		return errors.New("batching rules require event_type")
+	}
+	if rule.WindowMinutes < 1 || rule.WindowMinutes > maxBatchingWindowMinutes {
+		return fmt.Errorf("invalid batching window: must be between 1 and %d minutes", maxBatchingWindowMinutes)
+	}
+	if rule.MinEvents == 0 {
+		rule.MinEvents = 2
+	}
+	if rule.MinEvents < 2 {
+		return errors.New("invalid batching min_events: must be at least 2")
+	}
+	for _, channel := range rule.Channels {
+		if !notificationChannels[channel] {
+			return errors.New("invalid notification channel: " + channel)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule batches the notification
+func (rule *BatchingRule) matches(notification Notification) bool {
+	if prefix, ok := strings.CutSuffix(rule.EventType, "*"); ok {
+		if !strings.HasPrefix(notification.EventType, prefix) {
+			return false
+		}
+	} else if rule.EventType != notification.EventType {
+		return false
+	}
+
+	if len(rule.Channels) == 0 {
+		return true
+	}
+	for _, channel := range rule.Channels {
+		if channel == notification.Channel {
+			return true
+		}
+	}
+	return false
+}
+
+// batches reports whether a rule of the settings batches the notification
+func (s *NotificationSettings) batches(notification Notification) bool {
+	for i := range s.Batching {
+		if s.Batching[i].matches(notification) {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchNotifications applies the user's batching rules to notifications waiting for delivery.
+// The delivery job calls it with the deliveries held for batching, sends the batches whose
+// DeliverAt has passed and keeps the rest held for the next run
+func (s *Services) BatchNotifications(userID string, notifications []Notification) ([]NotificationBatch, error) {
+	settings, err := s.GetNotificationSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings.batch(notifications), nil
+}
+
+// batch groups notifications by the first rule matching them, channel and event type. The
+// first rule wins, so specific rules should come before prefix ones
+func (s *NotificationSettings) batch(notifications []Notification) []NotificationBatch {
+	sorted := make([]Notification, len(notifications))
+	copy(sorted, notifications)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	type groupKey struct {
+		rule      int
+		channel   string
+		eventType string
+	}
+	var batches []NotificationBatch
+	open := make(map[groupKey]int) // Index in batches of the group still inside its window
+	rules := make(map[int]*BatchingRule)
+
+	for _, notification := range sorted {
+		ruleIndex := -1
+		for i := range s.Batching {
+			if s.Batching[i].matches(notification) {
+				ruleIndex = i
+				break
+			}
+		}
+		if ruleIndex < 0 {
+			batches = append(batches, singleNotificationBatch(notification))
+			continue
+		}
+
+		key := groupKey{rule: ruleIndex, channel: notification.Channel, eventType: notification.EventType}
+		if index, ok := open[key]; ok && notification.CreatedAt.Before(batches[index].DeliverAt) {
+			batch := &batches[index]
+			batch.Notifications = append(batch.Notifications, notification)
+			if notificationSeverityRank[notification.Severity] > notificationSeverityRank[batch.Severity] {
+				batch.Severity = notification.Severity
+			}
+			continue
+		}
+
+		rule := &s.Batching[ruleIndex]
+		rules[len(batches)] = rule
+		open[key] = len(batches)
+		batch := singleNotificationBatch(notification)
+		batch.DeliverAt = notification.CreatedAt.Add(time.Duration(rule.WindowMinutes) * time.Minute)
+		batches = append(batches, batch)
+	}
+
+	// Bursts too small to coalesce go out one by one, once their window closes
+	result := make([]NotificationBatch, 0, len(batches))
+	for i, batch := range batches {
+		rule, batched := rules[i]
+		switch {
+		case !batched:
+			result = append(result, batch)
+		case len(batch.Notifications) >= rule.MinEvents:
+			batch.Coalesced = true
+			batch.Summary = fmt.Sprintf("%d %s notifications", len(batch.Notifications), batch.EventType)
+			result = append(result, batch)
+		default:
+			for _, notification := range batch.Notifications {
+				single := singleNotificationBatch(notification)
+				single.DeliverAt = batch.DeliverAt
+				result = append(result, single)
+			}
+		}
+	}
+	return result
+}
+
+func singleNotificationBatch(notification Notification) NotificationBatch {
+	return NotificationBatch{
+		Channel:       notification.Channel,
+		EventType:     notification.EventType,
+		Severity:      notification.Severity,
+		Notifications: []Notification{notification},
+		DeliverAt:     notification.CreatedAt,
+	}
+}
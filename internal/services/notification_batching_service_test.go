@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+func TestBatchedReminderNotifications(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	cases := []struct {
+		name        string
+		reminders   int
+		elapsed     time.Duration // Since the notifications were held, before the second run
+		wantHeld    int
+		wantDigests int
+		wantSingles int
+	}{
+		{
+			name:      "holds notifications inside the window",
+			reminders: 2,
+			wantHeld:  2,
+		},
+		{
+			name:        "delivers two notifications of a window as one digest",
+			reminders:   2,
+			elapsed:     31 * time.Minute,
+			wantDigests: 1,
+		},
+		{
+			name:        "delivers a lone notification by itself",
+			reminders:   1,
+			elapsed:     31 * time.Minute,
+			wantSingles: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			userID := user.ID.String()
+			if _, err := h.Services.UpdateNotificationSettings(userID, &services.NotificationSettings{
+				Batching: []services.BatchingRule{{EventType: services.EventReminderDue, WindowMinutes: 30}},
+			}); err != nil {
+				t.Fatalf("saving notification settings: %v", err)
+			}
+			for i := 0; i < tc.reminders; i++ {
+				reminder := &models.Reminder{
+					UserID:       user.ID,
+					Title:        fmt.Sprintf("Bill %d", i+1),
+					DueDate:      time.Now().UTC().Truncate(24 * time.Hour),
+					ReminderType: "bill",
+					Status:       models.StatusActive,
+				}
+				if err := h.DB.Create(reminder).Error; err != nil {
+					t.Fatalf("creating reminder: %v", err)
+				}
+			}
+
+			if err := h.Services.SendReminderNotifications(); err != nil {
+				t.Fatalf("sending notifications: %v", err)
+			}
+			if tc.elapsed > 0 {
+				if err := h.DB.Exec("UPDATE notification_deliveries SET created_at = created_at - make_interval(secs => ?) WHERE user_id = ?",
+					tc.elapsed.Seconds(), user.ID).Error; err != nil {
+					t.Fatalf("aging the held notifications: %v", err)
+				}
+				if err := h.Services.SendReminderNotifications(); err != nil {
+					t.Fatalf("releasing notifications: %v", err)
+				}
+			}
+
+			var held int64
+			if err := h.DB.Model(&models.NotificationDelivery{}).Where("user_id = ? AND status = ?", user.ID, models.NotificationStatusHeld).
+				Count(&held).Error; err != nil {
+				t.Fatalf("counting held notifications: %v", err)
+			}
+			if held != int64(tc.wantHeld) {
+				t.Errorf("%d notifications held, want %d", held, tc.wantHeld)
+			}
+
+			var events []models.OutboxEvent
+			if err := h.DB.Where("user_id = ? AND event_type IN ?", user.ID,
+				[]string{services.EventReminderDue, services.EventNotificationDigest}).Find(&events).Error; err != nil {
+				t.Fatalf("listing events: %v", err)
+			}
+			digests, singles := 0, 0
+			for _, event := range events {
+				if event.EventType == services.EventReminderDue {
+					singles++
+					continue
+				}
+				digests++
+				var payload struct {
+					DeliveryIDs []string `json:"delivery_ids"`
+				}
+				if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+					t.Fatalf("decoding digest: %v", err)
+				}
+				if len(payload.DeliveryIDs) != tc.reminders {
+					t.Errorf("digest of %d deliveries, want %d", len(payload.DeliveryIDs), tc.reminders)
+				}
+			}
+			if digests != tc.wantDigests || singles != tc.wantSingles {
+				t.Errorf("%d digests and %d single notifications, want %d and %d", digests, singles, tc.wantDigests, tc.wantSingles)
+			}
+		})
+	}
+}
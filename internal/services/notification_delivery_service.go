@@ -0,0 +1,445 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventReminderDue carries a due reminder to one notification channel. The payload names the
+// channel and the delivery to report back on
+const EventReminderDue = "reminder.due"
+
+// EventNotificationDigest carries several notifications a batching rule coalesced to one
+// channel. The payload names the channel, the summary and the deliveries it stands for
+const EventNotificationDigest = "notification.digest"
+
+// reminderNotifyLookbackDays is how many days overdue a reminder still gets its notification,
+// set by REMINDER_NOTIFY_LOOKBACK_DAYS. It covers reminders that fell due while the job wasn't
+// running without flooding users with old ones
+func reminderNotifyLookbackDays() int {
+	return envInt("REMINDER_NOTIFY_LOOKBACK_DAYS", 7)
+}
+
+// notificationJobBatchSize bounds the reminders sent and deliveries escalated per run
+const notificationJobBatchSize = 500
+
+// errAlreadyEscalated rolls back an escalation another instance already made
+var errAlreadyEscalated = errors.New("delivery already escalated")
+
+// reminderNotificationPayload is what the channel needs to show a reminder, including how
+// long it has been overdue
+func (s *Services) reminderNotificationPayload(reminder *models.Reminder) map[string]interface{} {
+	today := s.UserToday(reminder.UserID.String())
+	dueDate := dateOf(reminder.DueDate)
+	daysOverdue := 0
+	if dueDate.Before(today) {
+		daysOverdue = int(today.Sub(dueDate).Hours() / 24)
+	}
+	return map[string]interface{}{
+		"reminder_id":   reminder.ID,
+		"title":         reminder.Title,
+		"description":   reminder.Description,
+		"reminder_type": reminder.ReminderType,
+		"due_date":      reminder.DueDate.Format("2006-01-02"),
+		"link":          reminder.Link,
+		"overdue":       daysOverdue > 0,
+		"days_overdue":  daysOverdue,
+	}
+}
+
+// sendNotification records a delivery and enqueues the event carrying it to its channel, unless
+// the user's settings suppress it. It returns false without recording anything when quiet hours
+// defer it, so a later run sends it. A delivery a batching rule applies to is recorded as held,
+// and releaseBatchedNotifications sends it when the rule's window closes; escalations aren't
+// batched. When escalating, the delivery given up on is marked in the same transaction
+func (s *Services) sendNotification(delivery *models.NotificationDelivery, payload map[string]interface{}, escalates *models.NotificationDelivery) (bool, error) {
+	now := time.Now()
+	settings, err := s.GetNotificationSettings(delivery.UserID.String())
+	if err != nil {
+		return false, err
+	}
+	notification := Notification{
+		Channel:    delivery.Channel,
+		Severity:   NotificationSeverityNormal,
+		EventType:  delivery.EventType,
+		EntityType: delivery.EntityType,
+		EntityID:   delivery.EntityID.String(),
+		CreatedAt:  now,
+	}
+	decision := settings.evaluate(notification, now)
+	if decision.DeferUntil != nil {
+		return false, nil
+	}
+	held := decision.Deliver && escalates == nil && settings.batches(notification)
+	switch {
+	case held:
+		delivery.Status = models.NotificationStatusHeld
+	case decision.Deliver:
+		delivery.Status = models.NotificationStatusSent
+		delivery.SentAt = &now
+	default:
+		delivery.Status = models.NotificationStatusSuppressed
+		delivery.Reason = &decision.Reason
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if escalates != nil {
+			result := tx.Model(&models.NotificationDelivery{}).Where("id = ? AND escalated_at IS NULL", escalates.ID).
+				Updates(map[string]interface{}{"escalated_at": now, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errAlreadyEscalated
+			}
+		}
+
+		// Another instance may have sent the same occurrence on this channel
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+		if result.Error != nil || result.RowsAffected == 0 || !decision.Deliver || held {
+			return result.Error
+		}
+		payload["delivery_id"] = delivery.ID
+		payload["channel"] = delivery.Channel
+		if escalates != nil {
+			payload["escalated_from"] = escalates.Channel
+		}
+		return EnqueueEvent(tx, delivery.UserID, delivery.EventType, delivery.EntityType, delivery.EntityID, payload)
+	})
+	if errors.Is(err, errAlreadyEscalated) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// releaseBatchedNotifications sends the held deliveries whose batching window closed: the ones
+// batched together as a single digest, the rest one by one. Deliveries still inside their
+// window stay held for a later run
+func (s *Services) releaseBatchedNotifications() error {
+	var held []models.NotificationDelivery
+	if err := s.db.Where("status = ?", models.NotificationStatusHeld).
+		Order("created_at ASC").Limit(notificationJobBatchSize).Find(&held).Error; err != nil {
+		logger.Error("Error listing held notifications: %v", err)
+		return err
+	}
+
+	byUser := make(map[uuid.UUID][]models.NotificationDelivery)
+	for _, delivery := range held {
+		byUser[delivery.UserID] = append(byUser[delivery.UserID], delivery)
+	}
+
+	now := time.Now()
+	released := 0
+	for userID, deliveries := range byUser {
+		byID := make(map[string]*models.NotificationDelivery, len(deliveries))
+		notifications := make([]Notification, 0, len(deliveries))
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			byID[delivery.ID.String()] = delivery
+			notifications = append(notifications, Notification{
+				Channel:    delivery.Channel,
+				Severity:   NotificationSeverityNormal,
+				EventType:  delivery.EventType,
+				EntityType: delivery.EntityType,
+				EntityID:   delivery.EntityID.String(),
+				DeliveryID: delivery.ID.String(),
+				CreatedAt:  delivery.CreatedAt,
+			})
+		}
+
+		batches, err := s.BatchNotifications(userID.String(), notifications)
+		if err != nil {
+			logger.Error("Error batching notifications of user %s: %v", userID, err)
+			continue
+		}
+		for i := range batches {
+			batch := &batches[i]
+			if batch.DeliverAt.After(now) {
+				continue
+			}
+			batched := make([]*models.NotificationDelivery, 0, len(batch.Notifications))
+			for _, notification := range batch.Notifications {
+				batched = append(batched, byID[notification.DeliveryID])
+			}
+			if err := s.sendNotificationBatch(batch, batched, now); err != nil {
+				logger.Error("Error sending batched notifications of user %s: %v", userID, err)
+				continue
+			}
+			released += len(batched)
+		}
+	}
+	if released > 0 {
+		logger.Info("Released %d batched notifications", released)
+	}
+	return nil
+}
+
+// sendNotificationBatch marks the held deliveries of a batch sent and enqueues one event for
+// them: a digest when the batch coalesced several, the notification itself otherwise
+func (s *Services) sendNotificationBatch(batch *NotificationBatch, deliveries []*models.NotificationDelivery, now time.Time) error {
+	ids := make([]uuid.UUID, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+	first := deliveries[0]
+
+	var payload map[string]interface{}
+	if !batch.Coalesced {
+		var reminder models.Reminder
+		if err := s.db.Where("id = ?", first.EntityID).First(&reminder).Error; err != nil {
+			return s.db.Model(&models.NotificationDelivery{}).Where("id = ? AND status = ?", first.ID, models.NotificationStatusHeld).
+				Updates(map[string]interface{}{"status": models.NotificationStatusFailed, "reason": "reminder not found", "updated_at": now}).Error
+		}
+		payload = s.reminderNotificationPayload(&reminder)
+		payload["delivery_id"] = first.ID
+		payload["channel"] = first.Channel
+	} else {
+		payload = map[string]interface{}{
+			"channel":      batch.Channel,
+			"event_type":   batch.EventType,
+			"summary":      batch.Summary,
+			"delivery_ids": ids,
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the deliveries so another instance doesn't send them too
+		result := tx.Model(&models.NotificationDelivery{}).Where("id IN ? AND status = ?", ids, models.NotificationStatusHeld).
+			Updates(map[string]interface{}{"status": models.NotificationStatusSent, "sent_at": now, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return errors.New("notifications already released")
+		}
+		if batch.Coalesced {
+			return EnqueueEvent(tx, first.UserID, EventNotificationDigest, "notification_digest", first.ID, payload)
+		}
+		return EnqueueEvent(tx, first.UserID, first.EventType, first.EntityType, first.EntityID, payload)
+	})
+}
+
+// sendDueReminderNotifications pushes the reminders due today or overdue that weren't notified
+// yet, today being the date in each user's timezone
+func (s *Services) sendDueReminderNotifications() error {
+	// Users ahead of UTC may already be on the next day; the rest wait for theirs below
+	today := dateOf(time.Now().UTC())
+
+	var reminders []models.Reminder
+	if err := s.db.Where("status = ? AND is_completed = ? AND due_date BETWEEN ? AND ?",
+		models.StatusActive, false, today.AddDate(0, 0, -reminderNotifyLookbackDays()-1), today.AddDate(0, 0, 1)).
+		Where("NOT EXISTS (SELECT 1 FROM notification_deliveries d WHERE d.entity_id = reminders.id AND d.due_date = reminders.due_date AND d.channel = ?)",
+			NotificationChannelPush).
+		Order("due_date ASC").Limit(notificationJobBatchSize).Find(&reminders).Error; err != nil {
+		logger.Error("Error listing due reminders: %v", err)
+		return err
+	}
+
+	sent := 0
+	for i := range reminders {
+		reminder := &reminders[i]
+		userToday, dueDate := s.UserToday(reminder.UserID.String()), dateOf(reminder.DueDate)
+		if dueDate.After(userToday) || dueDate.Before(userToday.AddDate(0, 0, -reminderNotifyLookbackDays())) {
+			continue
+		}
+		delivery := &models.NotificationDelivery{
+			UserID:     reminder.UserID,
+			EventType:  EventReminderDue,
+			EntityType: "reminder",
+			EntityID:   reminder.ID,
+			DueDate:    reminder.DueDate,
+			Channel:    NotificationChannelPush,
+		}
+		ok, err := s.sendNotification(delivery, s.reminderNotificationPayload(reminder), nil)
+		if err != nil {
+			logger.Error("Error notifying reminder %s: %v", reminder.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	if sent > 0 {
+		logger.Info("Sent %d due reminder notifications", sent)
+	}
+	return nil
+}
+
+// escalateReminderNotifications resends on the fallback channel the reminder notifications the
+// user's escalation rules consider lost, and right away the ones their channel failed to take.
+// Completed and snoozed reminders aren't escalated
+func (s *Services) escalateReminderNotifications() error {
+	now := time.Now()
+
+	var deliveries []models.NotificationDelivery
+	if err := s.db.Where("entity_type = ? AND escalated_at IS NULL", "reminder").
+		Where("(status IN ? AND sent_at <= ?) OR status = ?",
+			[]string{models.NotificationStatusSent, models.NotificationStatusDelivered}, now.Add(-time.Hour), models.NotificationStatusFailed).
+		Where("EXISTS (SELECT 1 FROM reminders r WHERE r.id = notification_deliveries.entity_id AND r.due_date = notification_deliveries.due_date AND r.is_completed = ? AND r.status = ?)",
+			false, models.StatusActive).
+		Order("sent_at ASC").Limit(notificationJobBatchSize).Find(&deliveries).Error; err != nil {
+		logger.Error("Error listing notifications to escalate: %v", err)
+		return err
+	}
+
+	settingsByUser := make(map[uuid.UUID]*NotificationSettings)
+	escalated := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		settings, ok := settingsByUser[delivery.UserID]
+		if !ok {
+			var err error
+			if settings, err = s.GetNotificationSettings(delivery.UserID.String()); err != nil {
+				continue
+			}
+			settingsByUser[delivery.UserID] = settings
+		}
+		rule := settings.escalationFor(delivery.EventType, delivery.Channel)
+		if rule == nil || !rule.due(delivery, now) {
+			continue
+		}
+
+		var reminder models.Reminder
+		if err := s.db.Where("id = ?", delivery.EntityID).First(&reminder).Error; err != nil {
+			continue
+		}
+		fallback := &models.NotificationDelivery{
+			UserID:          delivery.UserID,
+			EventType:       delivery.EventType,
+			EntityType:      delivery.EntityType,
+			EntityID:        delivery.EntityID,
+			DueDate:         delivery.DueDate,
+			Channel:         rule.To,
+			EscalatedFromID: &delivery.ID,
+		}
+		ok, err := s.sendNotification(fallback, s.reminderNotificationPayload(&reminder), delivery)
+		if err != nil {
+			logger.Error("Error escalating notification %s: %v", delivery.ID, err)
+			continue
+		}
+		if ok {
+			escalated++
+		}
+	}
+	if escalated > 0 {
+		logger.Info("Escalated %d reminder notifications", escalated)
+	}
+	return nil
+}
+
+// SendReminderNotifications notifies due and overdue reminders, releases the ones held by
+// batching rules whose window closed and escalates the notifications that failed or weren't
+// delivered or acknowledged in time. The "notifications" outbox handler does the sending
+func (s *Services) SendReminderNotifications() error {
+	return errors.Join(s.sendDueReminderNotifications(), s.releaseBatchedNotifications(), s.escalateReminderNotifications())
+}
+
+// updateNotificationDelivery moves a delivery of the user forward; deliveries never go back
+// from acknowledged to delivered
+func (s *Services) updateNotificationDelivery(userID string, deliveryID string, status string) (*models.NotificationDelivery, error) {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, errors.New("invalid notification ID")
+	}
+
+	var delivery models.NotificationDelivery
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).First(&delivery).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("notification not found")
+			}
+			return err
+		}
+		if delivery.Status == models.NotificationStatusSuppressed || delivery.Status == models.NotificationStatusFailed ||
+			delivery.Status == models.NotificationStatusHeld {
+			return errors.New("invalid notification: it was never sent")
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"updated_at": now}
+		if delivery.DeliveredAt == nil {
+			delivery.DeliveredAt = &now
+			updates["delivered_at"] = now
+		}
+		if status == models.NotificationStatusAcknowledged && delivery.AcknowledgedAt == nil {
+			delivery.AcknowledgedAt = &now
+			updates["acknowledged_at"] = now
+		}
+		if delivery.Status != models.NotificationStatusAcknowledged {
+			delivery.Status = status
+			updates["status"] = status
+		}
+		return tx.Model(&models.NotificationDelivery{}).Where("id = ?", id).Updates(updates).Error
+	})
+	if err != nil {
+		if err.Error() == "notification not found" || strings.HasPrefix(err.Error(), "invalid ") {
+			return nil, err
+		}
+		logger.Error("Error updating notification %s: %v", deliveryID, err)
+		return nil, errors.New("error updating notification")
+	}
+	return &delivery, nil
+}
+
+// MarkNotificationDelivered records that the device received the notification
+func (s *Services) MarkNotificationDelivered(userID string, deliveryID string) (*models.NotificationDelivery, error) {
+	return s.updateNotificationDelivery(userID, deliveryID, models.NotificationStatusDelivered)
+}
+
+// AcknowledgeNotification records that the user opened or dismissed the notification, which
+// stops its escalation
+func (s *Services) AcknowledgeNotification(userID string, deliveryID string) (*models.NotificationDelivery, error) {
+	return s.updateNotificationDelivery(userID, deliveryID, models.NotificationStatusAcknowledged)
+}
+
+// GetNotificationDeliveries lists the notification center of the user, newest first, optionally
+// filtered by status and channel
+func (s *Services) GetNotificationDeliveries(userID string, status string, channel string, page PageRequest) ([]models.NotificationDelivery, PageInfo, error) {
+	query := s.db.Model(&models.NotificationDelivery{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+
+	var deliveries []models.NotificationDelivery
+	info, err := paginate(query, "created_at DESC, id", page, &deliveries)
+	if err != nil {
+		logger.Error("Error getting notifications: %v", err)
+		return nil, PageInfo{}, errors.New("error getting notifications")
+	}
+	return deliveries, info, nil
+}
+
+// GetReminderDeliveries lists how the notifications of a reminder went, oldest first
+func (s *Services) GetReminderDeliveries(userID string, reminderID string) ([]models.NotificationDelivery, error) {
+	id, err := uuid.Parse(reminderID)
+	if err != nil {
+		return nil, errors.New("invalid reminder ID")
+	}
+	var count int64
+	if err := s.db.Model(&models.Reminder{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		logger.Error("Error getting reminder: %v", err)
+		return nil, errors.New("error getting reminder deliveries")
+	}
+	if count == 0 {
+		return nil, errors.New("reminder not found")
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := s.db.Where("user_id = ? AND entity_type = ? AND entity_id = ?", userID, "reminder", id).
+		Order("created_at ASC").Find(&deliveries).Error; err != nil {
+		logger.Error("Error getting reminder deliveries: %v", err)
+		return nil, errors.New("error getting reminder deliveries")
+	}
+	return deliveries, nil
+}
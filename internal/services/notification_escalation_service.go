@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// What an escalation rule waits for before giving up on a channel
+const (
+	EscalateUnlessDelivered    = "delivered"
+	EscalateUnlessAcknowledged = "acknowledged"
+)
+
+// maxEscalationHours bounds how long a rule can wait, a week
+const maxEscalationHours = 168
+
+// EscalationRule resends a notification on another channel when it isn't delivered or
+// acknowledged in time, e.g. a due reminder push followed by an email
+type EscalationRule struct {
+	EventType  string `json:"event_type"`       // Exact type, or a prefix ending in * (e.g. reminder.*)
+	From       string `json:"from,omitempty"`   // Channel watched, defaults to push
+	To         string `json:"to,omitempty"`     // Channel used instead, defaults to email
+	AfterHours int    `json:"after_hours"`      // How long to wait for the first channel
+	Unless     string `json:"unless,omitempty"` // delivered or acknowledged (the default)
+}
+
+// defaultEscalationRules emails due reminders whose push wasn't acknowledged within
+// REMINDER_ESCALATION_HOURS
+func defaultEscalationRules() []EscalationRule {
+	return []EscalationRule{{
+		EventType:  EventReminderDue,
+		From:       NotificationChannelPush,
+		To:         NotificationChannelEmail,
+		AfterHours: envInt("REMINDER_ESCALATION_HOURS", 4),
+		Unless:     EscalateUnlessAcknowledged,
+	}}
+}
+
+func (rule *EscalationRule) validate() error {
+	if rule.EventType == "" {
+		return errors.New("escalation rules require event_type")
+	}
+	if rule.From == "" {
+		rule.From = NotificationChannelPush
+	}
+	if rule.To == "" {
+		rule.To = NotificationChannelEmail
+	}
+	if rule.Unless == "" {
+		rule.Unless = EscalateUnlessAcknowledged
+	}
+	for _, channel := range []string{rule.From, rule.To} {
+		if !notificationChannels[channel] {
+			return errors.New("invalid notification channel: " + channel)
+		}
+	}
+	if rule.From == rule.To {
+		return errors.New("invalid escalation rule: from and to must be different channels")
+	}
+	if rule.AfterHours < 1 || rule.AfterHours > maxEscalationHours {
+		return fmt.Errorf("invalid escalation after_hours: must be between 1 and %d", maxEscalationHours)
+	}
+	if rule.Unless != EscalateUnlessDelivered && rule.Unless != EscalateUnlessAcknowledged {
+		return errors.New("invalid escalation unless: use delivered or acknowledged")
+	}
+	return nil
+}
+
+// escalationFor returns the first rule watching the delivery's event type and channel
+func (s *NotificationSettings) escalationFor(eventType, channel string) *EscalationRule {
+	for i := range s.Escalation {
+		rule := &s.Escalation[i]
+		if rule.From != channel {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(rule.EventType, "*"); ok {
+			if strings.HasPrefix(eventType, prefix) {
+				return rule
+			}
+		} else if rule.EventType == eventType {
+			return rule
+		}
+	}
+	return nil
+}
+
+// due reports whether a delivery has waited long enough without the expected answer. A failed
+// delivery won't get one, so it doesn't wait
+func (rule *EscalationRule) due(delivery *models.NotificationDelivery, now time.Time) bool {
+	if delivery.Status == models.NotificationStatusFailed {
+		return true
+	}
+	if delivery.SentAt == nil || now.Sub(*delivery.SentAt) < time.Duration(rule.AfterHours)*time.Hour {
+		return false
+	}
+	switch delivery.Status {
+	case models.NotificationStatusSent:
+		return true
+	case models.NotificationStatusDelivered:
+		return rule.Unless == EscalateUnlessAcknowledged
+	}
+	return false
+}
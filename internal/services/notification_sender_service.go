@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/mailer"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/push"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// notificationSendTimeout bounds one push or email
+const notificationSendTimeout = 20 * time.Second
+
+// reminderPushTTL is how long push services keep a reminder for an offline device
+const reminderPushTTL = 24 * time.Hour
+
+var (
+	mailerOnce sync.Once
+	mailSender mailer.Mailer
+	mailerErr  error
+)
+
+// getMailer returns the mailer configured by MAIL_TRANSPORT
+func getMailer() (mailer.Mailer, error) {
+	mailerOnce.Do(func() {
+		mailSender, mailerErr = mailer.FromEnv()
+		if mailerErr != nil && !errors.Is(mailerErr, mailer.ErrNotConfigured) {
+			logger.Error("Error configuring mail: %v", mailerErr)
+		}
+	})
+	return mailSender, mailerErr
+}
+
+// reminderNotification is the payload of a reminder.due event
+type reminderNotification struct {
+	DeliveryID   uuid.UUID `json:"delivery_id"`
+	Channel      string    `json:"channel"`
+	ReminderID   uuid.UUID `json:"reminder_id"`
+	Title        string    `json:"title"`
+	Description  *string   `json:"description"`
+	ReminderType string    `json:"reminder_type"`
+	DueDate      string    `json:"due_date"`
+	Link         *string   `json:"link"`
+	Overdue      bool      `json:"overdue"`
+	DaysOverdue  int       `json:"days_overdue"`
+}
+
+func (n *reminderNotification) subject() string {
+	if n.Overdue {
+		return "Overdue: " + n.Title
+	}
+	return "Reminder: " + n.Title
+}
+
+func (n *reminderNotification) summary() string {
+	switch {
+	case n.DaysOverdue == 1:
+		return "Was due yesterday"
+	case n.Overdue:
+		return fmt.Sprintf("Was due %d days ago, on %s", n.DaysOverdue, n.DueDate)
+	}
+	return "Due today"
+}
+
+// DispatchNotificationEvent is the outbox handler that hands notifications to their channel:
+// reminder pushes to the user's devices, reminder emails and digests of batched reminders. Each delivery records whether the channel took it; failures it can't
+// retry mark the delivery failed so escalation moves on to the fallback channel
+func (s *Services) DispatchNotificationEvent(event models.OutboxEvent) error {
+	switch event.EventType {
+	case EventReminderDue:
+		var payload reminderNotification
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			logger.Error("Invalid reminder notification payload in event %s: %v", event.ID, err)
+			return nil
+		}
+		return s.dispatchReminderNotification(event.UserID, &payload)
+	case EventNotificationDigest:
+		var payload notificationDigest
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			logger.Error("Invalid notification digest payload in event %s: %v", event.ID, err)
+			return nil
+		}
+		return s.dispatchNotificationDigest(event.UserID, &payload)
+	}
+	return nil
+}
+
+func (s *Services) dispatchReminderNotification(userID uuid.UUID, payload *reminderNotification) error {
+	var delivery models.NotificationDelivery
+	if err := s.db.Where("id = ? AND user_id = ?", payload.DeliveryID, userID).First(&delivery).Error; err != nil {
+		// Gone with an erased account
+		return nil
+	}
+	// Already handed over, or answered or escalated in the meantime
+	if delivery.DispatchedAt != nil || delivery.Status != models.NotificationStatusSent || delivery.EscalatedAt != nil {
+		return nil
+	}
+
+	var err error
+	switch payload.Channel {
+	case NotificationChannelPush:
+		err = s.sendReminderPush(userID, payload)
+	case NotificationChannelEmail:
+		err = s.sendReminderEmail(userID, payload)
+	case NotificationChannelInApp:
+		// Shown in the notification center
+	}
+
+	var failure *notificationFailure
+	if errors.As(err, &failure) {
+		return s.failNotificationDelivery(delivery.ID, failure.reason)
+	}
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&models.NotificationDelivery{}).Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{"dispatched_at": time.Now(), "updated_at": time.Now()}).Error
+}
+
+// notificationDigest is the payload of a notification.digest event
+type notificationDigest struct {
+	Channel     string      `json:"channel"`
+	EventType   string      `json:"event_type"`
+	Summary     string      `json:"summary"`
+	DeliveryIDs []uuid.UUID `json:"delivery_ids"`
+}
+
+// dispatchNotificationDigest hands a digest of reminders to its channel as a single push or
+// email listing them, and records every delivery it stands for as dispatched
+func (s *Services) dispatchNotificationDigest(userID uuid.UUID, payload *notificationDigest) error {
+	var deliveries []models.NotificationDelivery
+	if err := s.db.Where("id IN ? AND user_id = ? AND status = ? AND dispatched_at IS NULL AND escalated_at IS NULL",
+		payload.DeliveryIDs, userID, models.NotificationStatusSent).Find(&deliveries).Error; err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(deliveries))
+	reminderIDs := make([]uuid.UUID, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+		reminderIDs = append(reminderIDs, delivery.EntityID)
+	}
+	var titles []string
+	if err := s.db.Model(&models.Reminder{}).Where("id IN ?", reminderIDs).Order("due_date ASC").Pluck("title", &titles).Error; err != nil {
+		return err
+	}
+
+	var err error
+	switch payload.Channel {
+	case NotificationChannelPush:
+		deliveryIDs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			deliveryIDs = append(deliveryIDs, id.String())
+		}
+		err = s.sendPush(userID, push.Message{
+			Title: payload.Summary,
+			Body:  strings.Join(titles, ", "),
+			Data:  map[string]string{"delivery_ids": strings.Join(deliveryIDs, ","), "event_type": payload.EventType},
+			TTL:   reminderPushTTL,
+		})
+	case NotificationChannelEmail:
+		text := payload.Summary + ":\n\n- " + strings.Join(titles, "\n- ") +
+			"\n\nMark them completed or snooze them in Fluxio to stop these notifications.\n"
+		err = s.sendEmail(userID, payload.Summary, text)
+	case NotificationChannelInApp:
+		// Shown in the notification center
+	}
+
+	var failure *notificationFailure
+	if errors.As(err, &failure) {
+		for _, id := range ids {
+			if err := s.failNotificationDelivery(id, failure.reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&models.NotificationDelivery{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"dispatched_at": time.Now(), "updated_at": time.Now()}).Error
+}
+
+// notificationFailure is a send that retrying won't fix
+type notificationFailure struct {
+	reason string
+}
+
+func (f *notificationFailure) Error() string {
+	return f.reason
+}
+
+func (s *Services) failNotificationDelivery(deliveryID uuid.UUID, reason string) error {
+	return s.db.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ?", deliveryID, models.NotificationStatusSent).
+		Updates(map[string]interface{}{"status": models.NotificationStatusFailed, "reason": reason, "updated_at": time.Now()}).Error
+}
+
+// sendReminderPush pushes the reminder to every device of the user
+func (s *Services) sendReminderPush(userID uuid.UUID, payload *reminderNotification) error {
+	data := map[string]string{
+		"delivery_id": payload.DeliveryID.String(),
+		"event_type":  EventReminderDue,
+		"reminder_id": payload.ReminderID.String(),
+		"due_date":    payload.DueDate,
+	}
+	if payload.Link != nil {
+		data["link"] = *payload.Link
+	}
+	return s.sendPush(userID, push.Message{Title: payload.subject(), Body: payload.summary(), Data: data, TTL: reminderPushTTL})
+}
+
+// sendPush pushes a message to every device of the user, forgetting the ones that
+// unsubscribed. It succeeds when at least one device got it
+func (s *Services) sendPush(userID uuid.UUID, message push.Message) error {
+	dispatcher, err := getPushDispatcher()
+	if err != nil {
+		return &notificationFailure{reason: "push is not configured"}
+	}
+	var subscriptions []models.PushSubscription
+	if err := s.db.Where("user_id = ?", userID).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	sent := 0
+	var lastErr error
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !dispatcher.Supports(subscription.Platform) {
+			continue
+		}
+		target := push.Subscription{Platform: subscription.Platform, Token: subscription.Token}
+		if subscription.P256dh != nil && subscription.Auth != nil {
+			target.P256dh, target.Auth = *subscription.P256dh, *subscription.Auth
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+		err := dispatcher.Send(ctx, target, message)
+		cancel()
+		switch {
+		case err == nil:
+			sent++
+			s.touchPushSubscription(subscription.ID, time.Now())
+		case errors.Is(err, push.ErrSubscriptionGone):
+			s.db.Delete(&models.PushSubscription{}, "id = ?", subscription.ID)
+		default:
+			logger.Warn("Error pushing to subscription %s: %v", subscription.ID, err)
+			lastErr = err
+		}
+	}
+
+	switch {
+	case sent > 0:
+		return nil
+	case lastErr != nil:
+		return lastErr
+	}
+	return &notificationFailure{reason: "no push device registered"}
+}
+
+// sendReminderEmail emails the reminder to the user's address
+func (s *Services) sendReminderEmail(userID uuid.UUID, payload *reminderNotification) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n\n%s (%s).\n", payload.Title, payload.summary(), payload.DueDate)
+	if payload.Description != nil && *payload.Description != "" {
+		fmt.Fprintf(&text, "\n%s\n", *payload.Description)
+	}
+	text.WriteString("\nMark it completed or snooze it in Fluxio to stop these notifications.\n")
+	return s.sendEmail(userID, payload.subject(), text.String())
+}
+
+// sendEmail emails the user's address
+func (s *Services) sendEmail(userID uuid.UUID, subject string, text string) error {
+	mail, err := getMailer()
+	if err != nil {
+		return &notificationFailure{reason: "email is not configured"}
+	}
+	var user models.User
+	if err := s.db.Select("id", "email").Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+	return mail.Send(ctx, mailer.Message{To: user.Email, Subject: subject, Text: text})
+}
+
+// sendStepUpCodeEmail emails the verification code of a risky login
+func sendStepUpCodeEmail(email, code string, expiresAt time.Time, ipAddress string) error {
+	mail, err := getMailer()
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Your Fluxio sign-in code is %s.\n\nIt expires at %s. The sign-in attempt came from %s.\n"+
+		"If it wasn't you, change your password.\n", code, expiresAt.UTC().Format("15:04 MST"), ipAddress)
+	ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+	return mail.Send(ctx, mailer.Message{To: email, Subject: "Your Fluxio sign-in code", Text: text})
+}
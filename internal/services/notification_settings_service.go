@@ -0,0 +1,291 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Notification channels
+const (
+	NotificationChannelPush  = "push"
+	NotificationChannelEmail = "email"
+	NotificationChannelInApp = "in_app"
+)
+
+// Notification severities, from least to most important
+const (
+	NotificationSeverityLow    = "low"
+	NotificationSeverityNormal = "normal"
+	NotificationSeverityHigh   = "high"
+)
+
+var notificationSeverityRank = map[string]int{
+	NotificationSeverityLow:    0,
+	NotificationSeverityNormal: 1,
+	NotificationSeverityHigh:   2,
+}
+
+var notificationChannels = map[string]bool{
+	NotificationChannelPush:  true,
+	NotificationChannelEmail: true,
+	NotificationChannelInApp: true,
+}
+
+// QuietHours is a daily window during which notifications on some channels are held back
+type QuietHours struct {
+	Start     string   `json:"start"`    // HH:MM, e.g. 22:00
+	End       string   `json:"end"`      // HH:MM, e.g. 08:00 (may be on the next day)
+	Timezone  string   `json:"timezone"` // IANA name, defaults to UTC
+	Channels  []string `json:"channels"` // Channels affected, defaults to push
+	AllowHigh bool     `json:"allow_high_severity"`
+}
+
+// ChannelSettings controls a single delivery channel
+type ChannelSettings struct {
+	Muted       bool   `json:"muted"`
+	MinSeverity string `json:"min_severity,omitempty"` // Lowest severity still delivered
+}
+
+// EntityMute silences the notifications about one entity, e.g. a budget category
+type EntityMute struct {
+	EntityType string     `json:"entity_type"` // e.g. category, budget, reminder
+	EntityID   string     `json:"entity_id"`
+	Until      *time.Time `json:"until,omitempty"` // nil mutes until removed
+}
+
+// NotificationSettings are the per-user notification rules applied by the dispatcher
+type NotificationSettings struct {
+	QuietHours    *QuietHours                `json:"quiet_hours,omitempty"`
+	Channels      map[string]ChannelSettings `json:"channels"`
+	MutedEntities []EntityMute               `json:"muted_entities"`
+	Batching      []BatchingRule             `json:"batching"`
+	Escalation    []EscalationRule           `json:"escalation"`
+}
+
+// Notification describes a notification about to be delivered
+type Notification struct {
+	Channel    string
+	Severity   string
+	EventType  string // Outbox event that caused it, e.g. expense.created
+	EntityType string
+	EntityID   string
+	DeliveryID string // The delivery tracking it, if any
+	CreatedAt  time.Time
+}
+
+// NotificationDecision tells the dispatcher what to do with a notification
+type NotificationDecision struct {
+	Deliver    bool
+	Reason     string     // Why it was not delivered
+	DeferUntil *time.Time // Set when held back by quiet hours
+}
+
+// parseClock parses an HH:MM time of day into minutes since midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("invalid time of day, use HH:MM")
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// validate checks the settings and fills defaults
+func (s *NotificationSettings) validate() error {
+	if s.Channels == nil {
+		s.Channels = make(map[string]ChannelSettings)
+	}
+	if s.MutedEntities == nil {
+		s.MutedEntities = []EntityMute{}
+	}
+	if s.Batching == nil {
+		s.Batching = []BatchingRule{}
+	}
+
+	for channel, settings := range s.Channels {
+		if !notificationChannels[channel] {
+			return errors.New("invalid notification channel: " + channel)
+		}
+		if _, ok := notificationSeverityRank[settings.MinSeverity]; settings.MinSeverity != "" && !ok {
+			return errors.New("invalid severity: " + settings.MinSeverity)
+		}
+	}
+
+	if quiet := s.QuietHours; quiet != nil {
+		if _, err := parseClock(quiet.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(quiet.End); err != nil {
+			return err
+		}
+		if quiet.Timezone == "" {
+			quiet.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(quiet.Timezone); err != nil {
+			return errors.New("invalid timezone: " + quiet.Timezone)
+		}
+		if len(quiet.Channels) == 0 {
+			quiet.Channels = []string{NotificationChannelPush}
+		}
+		for _, channel := range quiet.Channels {
+			if !notificationChannels[channel] {
+				return errors.New("invalid notification channel: " + channel)
+			}
+		}
+	}
+
+	for _, mute := range s.MutedEntities {
+		if mute.EntityType == "" || mute.EntityID == "" {
+			return errors.New("muted entities require entity_type and entity_id")
+		}
+	}
+
+	for i := range s.Batching {
+		if err := s.Batching[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	// Without rules of their own users get the default fallback; an empty list turns it off
+	if s.Escalation == nil {
+		s.Escalation = defaultEscalationRules()
+	}
+	for i := range s.Escalation {
+		if err := s.Escalation[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetNotificationSettings returns the notification settings of the user
+func (s *Services) GetNotificationSettings(userID string) (*NotificationSettings, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &NotificationSettings{}
+	if preferences.NotificationSettings != "" {
+		if err := json.Unmarshal([]byte(preferences.NotificationSettings), settings); err != nil {
+			logger.Error("Error decoding notification settings: %v", err)
+		}
+	}
+	settings.validate()
+
+	return settings, nil
+}
+
+// UpdateNotificationSettings replaces the notification settings of the user
+func (s *Services) UpdateNotificationSettings(userID string, settings *NotificationSettings) (*NotificationSettings, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setNotificationSettings(preferences, settings); err != nil {
+		return nil, err
+	}
+	if err := s.saveUserPreferences(preferences, "notification_settings"); err != nil {
+		logger.Error("Error saving notification settings: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Notification settings updated for user %s", userID)
+	return settings, nil
+}
+
+// setNotificationSettings encodes the settings into the preferences, to be saved by the caller
+func setNotificationSettings(preferences *models.UserPreferences, settings *NotificationSettings) error {
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	preferences.NotificationSettings = string(encoded)
+	return nil
+}
+
+// EvaluateNotification applies the user's muting rules and quiet hours to a notification.
+// The notification dispatcher must call it before delivering anything.
+func (s *Services) EvaluateNotification(userID string, notification Notification, at time.Time) (NotificationDecision, error) {
+	settings, err := s.GetNotificationSettings(userID)
+	if err != nil {
+		return NotificationDecision{}, err
+	}
+
+	return settings.evaluate(notification, at), nil
+}
+
+// evaluate decides whether a notification goes out now, later or never
+func (s *NotificationSettings) evaluate(notification Notification, at time.Time) NotificationDecision {
+	severity := notificationSeverityRank[notification.Severity]
+
+	if channel, ok := s.Channels[notification.Channel]; ok {
+		if channel.Muted {
+			return NotificationDecision{Reason: "channel muted"}
+		}
+		if channel.MinSeverity != "" && severity < notificationSeverityRank[channel.MinSeverity] {
+			return NotificationDecision{Reason: "below channel minimum severity"}
+		}
+	}
+
+	for _, mute := range s.MutedEntities {
+		if mute.EntityType == notification.EntityType && mute.EntityID == notification.EntityID &&
+			(mute.Until == nil || at.Before(*mute.Until)) {
+			return NotificationDecision{Reason: "entity muted"}
+		}
+	}
+
+	if quiet := s.QuietHours; quiet != nil && !(quiet.AllowHigh && notification.Severity == NotificationSeverityHigh) {
+		for _, channel := range quiet.Channels {
+			if channel != notification.Channel {
+				continue
+			}
+			if until, inside := quiet.endIfInside(at); inside {
+				return NotificationDecision{Reason: "quiet hours", DeferUntil: &until}
+			}
+		}
+	}
+
+	return NotificationDecision{Deliver: true}
+}
+
+// endIfInside reports whether at falls in the quiet window and, if so, when the window ends
+func (q *QuietHours) endIfInside(at time.Time) (time.Time, bool) {
+	location, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	start, _ := parseClock(q.Start)
+	end, _ := parseClock(q.End)
+
+	local := at.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+
+	switch {
+	case start == end:
+		return time.Time{}, false
+	case start < end: // Same-day window, e.g. 13:00-15:00
+		if minute >= start && minute < end {
+			return midnight.Add(time.Duration(end) * time.Minute), true
+		}
+	default: // Overnight window, e.g. 22:00-08:00
+		if minute >= start {
+			return midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute), true
+		}
+		if minute < end {
+			return midnight.Add(time.Duration(end) * time.Minute), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/auth/oidc"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// oauthStateTTL is how long the user has to sign in at the provider
+const oauthStateTTL = 10 * time.Minute
+
+// oauthExchangeTimeout bounds the token exchange and ID token verification of a callback
+const oauthExchangeTimeout = 15 * time.Second
+
+// ErrOAuthEmailInUse is returned when the provider doesn't vouch for an email that already has
+// an account here, so the accounts can't be linked safely
+var ErrOAuthEmailInUse = errors.New("an account with this email already exists; sign in with your password to use it")
+
+var (
+	oauthOnce      sync.Once
+	oauthProviders map[string]*oidc.Provider
+)
+
+// getOAuthProvider returns the identity provider by name when its credentials are configured
+func getOAuthProvider(name string) (*oidc.Provider, error) {
+	oauthOnce.Do(func() {
+		var err error
+		oauthProviders, err = oidc.FromEnv()
+		if err != nil {
+			logger.Error("Error configuring sign-in providers: %v", err)
+		}
+	})
+	provider, ok := oauthProviders[name]
+	if !ok {
+		return nil, oidc.ErrNotConfigured
+	}
+	return provider, nil
+}
+
+// oauthRedirectAllowed checks a client redirect URI against OAUTH_REDIRECT_URIS, a comma separated
+// list of exact URIs (web app callback, mobile deep link) that may receive the tokens
+func oauthRedirectAllowed(redirectURI string) bool {
+	for _, allowed := range strings.Split(os.Getenv("OAUTH_REDIRECT_URIS"), ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && allowed == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// oauthStateBinding is the hash of a sign-in state kept in the browser that started it. The
+// callback must come with it, so a state started by someone else can't sign this browser in
+func oauthStateBinding(stateID string) string {
+	sum := sha256.Sum256([]byte(stateID))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OAuthStart is a sign-in begun with a provider
+type OAuthStart struct {
+	AuthorizationURL string    // Where to send the user to sign in
+	Binding          string    // Goes in a browser cookie and back to CompleteOAuthLogin
+	ExpiresAt        time.Time // When the sign-in state expires
+	FormPost         bool      // The provider posts the callback from its own site
+}
+
+// StartOAuthLogin begins a sign-in with the provider. With a redirect URI the callback forwards
+// the tokens there, otherwise it answers with them
+func (s *Services) StartOAuthLogin(providerName string, redirectURI string) (*OAuthStart, error) {
+	provider, err := getOAuthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := models.OAuthLoginState{
+		ID:        oidc.RandomString(32),
+		Provider:  provider.Name,
+		Nonce:     oidc.RandomString(32),
+		ExpiresAt: time.Now().Add(oauthStateTTL),
+	}
+	if redirectURI != "" {
+		if !oauthRedirectAllowed(redirectURI) {
+			return nil, errors.New("invalid redirect_uri: not in the allowed list")
+		}
+		state.RedirectURI = &redirectURI
+	}
+	verifier, challenge := oidc.NewPKCE()
+	state.CodeVerifier = verifier
+
+	// Abandoned sign-ins are dropped as new ones start
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.OAuthLoginState{}).Error; err != nil {
+		logger.Warn("Error purging expired sign-in states: %v", err)
+	}
+	if err := s.db.Create(&state).Error; err != nil {
+		logger.Error("Error storing sign-in state: %v", err)
+		return nil, err
+	}
+	return &OAuthStart{
+		AuthorizationURL: provider.AuthCodeURL(state.ID, state.Nonce, challenge),
+		Binding:          oauthStateBinding(state.ID),
+		ExpiresAt:        state.ExpiresAt,
+		FormPost:         provider.FormPost,
+	}, nil
+}
+
+// OAuthLoginResult is the user signed in by a provider callback
+type OAuthLoginResult struct {
+	User        *models.User
+	RedirectURI *string // Client URI to forward the tokens to, if the sign-in asked for one
+	Created     bool    // The account was created by this sign-in
+}
+
+// CompleteOAuthLogin handles the provider callback: it consumes the state, exchanges the code
+// for a verified identity and signs in the linked user. binding is the OAuthStart.Binding kept
+// by the browser of the callback; a state started in another browser is refused. An unknown
+// identity is linked to the user with the same email when the provider verified it, or gets a
+// new account otherwise. name is the display name some providers (Apple) only send outside the
+// ID token
+func (s *Services) CompleteOAuthLogin(ctx context.Context, providerName, stateID, binding, code, name string) (*OAuthLoginResult, error) {
+	provider, err := getOAuthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(oauthStateBinding(stateID))) != 1 {
+		return nil, errors.New("invalid sign-in state: start the sign-in again in this browser")
+	}
+
+	// The state is single use: only the callback that deletes it goes on, so a replayed one fails
+	var state models.OAuthLoginState
+	if stateID == "" || code == "" ||
+		s.db.Where("id = ? AND provider = ? AND expires_at > ?", stateID, provider.Name, time.Now()).First(&state).Error != nil {
+		return nil, errors.New("invalid sign-in state: start the sign-in again")
+	}
+	if result := s.db.Delete(&state); result.Error != nil || result.RowsAffected == 0 {
+		return nil, errors.New("invalid sign-in state: start the sign-in again")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oauthExchangeTimeout)
+	defer cancel()
+	identity, err := provider.Exchange(ctx, code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		logger.Warn("Error completing %s sign-in: %v", provider.Name, err)
+		return nil, oidc.ErrInvalidToken
+	}
+	if identity.Name == "" {
+		identity.Name = strings.TrimSpace(name)
+	}
+
+	user, created, err := s.linkOAuthIdentity(provider.Name, identity)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthLoginResult{User: user, RedirectURI: state.RedirectURI, Created: created}, nil
+}
+
+// linkOAuthIdentity finds the user of an identity, linking or creating one on its first sign-in
+func (s *Services) linkOAuthIdentity(providerName string, identity *oidc.Identity) (*models.User, bool, error) {
+	now := time.Now()
+
+	var link models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, identity.Subject).First(&link).Error
+	if err == nil {
+		user, err := s.GetUserByID(link.UserID.String())
+		if err != nil {
+			return nil, false, err
+		}
+		s.db.Model(&link).Update("last_login_at", now)
+		return user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Error("Error getting sign-in identity: %v", err)
+		return nil, false, err
+	}
+
+	// Providers don't agree on the case of addresses; accounts are matched ignoring it
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+	if identity.Email == "" {
+		return nil, false, errors.New("invalid sign-in: the provider didn't share an email address")
+	}
+
+	var user *models.User
+	created := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.User
+		err := tx.Where("LOWER(email) = ?", identity.Email).First(&existing).Error
+		switch {
+		case err == nil:
+			// Anyone can open a provider account with someone else's address; only link verified ones
+			if !identity.EmailVerified {
+				return ErrOAuthEmailInUse
+			}
+			user = &existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// Nobody knows the password: the user signs in through the provider
+			password, err := HashPassword(oidc.RandomString(32))
+			if err != nil {
+				return err
+			}
+			name := identity.Name
+			if name == "" {
+				name, _, _ = strings.Cut(identity.Email, "@")
+			}
+			user = &models.User{Email: identity.Email, Password: password, Name: name}
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    providerName,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOAuthEmailInUse) {
+			logger.Error("Error linking %s identity: %v", providerName, err)
+		}
+		return nil, false, err
+	}
+
+	s.RecordAudit(user.ID, "user.identity_linked", "user", &user.ID, map[string]interface{}{
+		"provider":        providerName,
+		"account_created": created,
+	})
+	return user, created, nil
+}
@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/auth/oidc"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// TestOAuthCallbackNeedsTheStartingBrowser completes sign-ins whose state cookie is missing or
+// belongs to another sign-in: they are refused before the code is exchanged, and the state
+// stays usable by the browser that started it
+func TestOAuthCallbackNeedsTheStartingBrowser(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	t.Setenv("OAUTH_CALLBACK_BASE_URL", "https://api.example.com")
+	t.Setenv("GOOGLE_CLIENT_ID", "client")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "secret")
+
+	start, err := h.Services.StartOAuthLogin("google", "")
+	if err != nil {
+		t.Fatalf("starting the sign-in: %v", err)
+	}
+	other, err := h.Services.StartOAuthLogin("google", "")
+	if err != nil {
+		t.Fatalf("starting another sign-in: %v", err)
+	}
+	authURL, err := url.Parse(start.AuthorizationURL)
+	if err != nil {
+		t.Fatalf("parsing the authorization URL: %v", err)
+	}
+	state := authURL.Query().Get("state")
+
+	cases := []struct {
+		name    string
+		binding string
+	}{
+		{name: "no cookie", binding: ""},
+		{name: "cookie of another sign-in", binding: other.Binding},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := h.Services.CompleteOAuthLogin(context.Background(), "google", state, tc.binding, "code", "")
+			if err == nil || !strings.HasPrefix(err.Error(), "invalid sign-in state") {
+				t.Errorf("error = %v, want an invalid sign-in state", err)
+			}
+		})
+	}
+
+	var remaining int64
+	h.DB.Model(&models.OAuthLoginState{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("%d sign-in states left, want both", remaining)
+	}
+}
+
+// TestOAuthIdentityMatchesEmailsIgnoringCase links provider identities whose email differs from
+// the account's only in case and spacing
+func TestOAuthIdentityMatchesEmailsIgnoringCase(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	local := "Alice-" + uuid.NewString()
+	if err := h.DB.Model(user).Update("email", local+"@Example.com").Error; err != nil {
+		t.Fatalf("setting the email: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		email    string
+		verified bool
+		wantErr  error
+		wantUser bool
+	}{
+		{name: "verified email in another case", email: "  " + strings.ToUpper(local) + "@EXAMPLE.COM ", verified: true, wantUser: true},
+		{name: "unverified email", email: strings.ToLower(local) + "@example.com", verified: false, wantErr: services.ErrOAuthEmailInUse},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			identity := &oidc.Identity{Subject: uuid.NewString(), Email: tc.email, EmailVerified: tc.verified}
+			linked, created, err := h.Services.LinkOAuthIdentity("google", identity)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("linking: %v", err)
+			}
+			if created || linked.ID != user.ID {
+				t.Errorf("linked to %s (created %v), want the existing account %s", linked.ID, created, user.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// MaxRedeliverEvents limits how many failed events one bulk redelivery can requeue
+const MaxRedeliverEvents = 500
+
+// deadLetterRetentionDays is how long failed events are kept after the last attempt before
+// they are purged. Set OUTBOX_DEAD_LETTER_RETENTION_DAYS to change it
+func deadLetterRetentionDays() int {
+	return envInt("OUTBOX_DEAD_LETTER_RETENTION_DAYS", 30)
+}
+
+// GetFailedOutboxEvents lists the events of the user the dispatcher gave up on, latest first,
+// optionally of one event type
+func (s *Services) GetFailedOutboxEvents(userID string, eventType string, limit int) ([]models.OutboxEvent, error) {
+	if limit <= 0 || limit > MaxRedeliverEvents {
+		limit = MaxRedeliverEvents
+	}
+
+	query := s.db.Where("user_id = ? AND status = ?", userID, models.OutboxStatusFailed)
+	if eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	var events []models.OutboxEvent
+	if err := query.Order("created_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		logger.Error("Error getting failed outbox events: %v", err)
+		return nil, errors.New("error getting failed deliveries")
+	}
+	return events, nil
+}
+
+// GetOutboxEvent returns one event of the user with its payload and last error
+func (s *Services) GetOutboxEvent(userID string, eventID string) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	if err := s.db.Where("id = ? AND user_id = ?", eventID, userID).First(&event).Error; err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	return &event, nil
+}
+
+// RedeliverOutboxEvents puts failed events of the user back in the queue with a fresh set of
+// attempts. With no IDs every failed event of the user (up to MaxRedeliverEvents) is requeued.
+// It returns how many events were requeued
+func (s *Services) RedeliverOutboxEvents(userID string, eventIDs []string) (int64, error) {
+	if len(eventIDs) > MaxRedeliverEvents {
+		return 0, fmt.Errorf("invalid ids: at most %d events can be redelivered at once", MaxRedeliverEvents)
+	}
+	ids := make([]uuid.UUID, 0, len(eventIDs))
+	for _, id := range eventIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return 0, errors.New("invalid event ID: " + id)
+		}
+		ids = append(ids, parsed)
+	}
+
+	selected := s.db.Model(&models.OutboxEvent{}).Select("id").
+		Where("user_id = ? AND status = ?", userID, models.OutboxStatusFailed)
+	if len(ids) > 0 {
+		selected = selected.Where("id IN ?", uniqueUUIDs(ids))
+	} else {
+		selected = selected.Order("created_at ASC").Limit(MaxRedeliverEvents)
+	}
+
+	result := s.db.Model(&models.OutboxEvent{}).Where("id IN (?)", selected).Updates(map[string]interface{}{
+		"status":          models.OutboxStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+		"last_error":      nil,
+	})
+	if result.Error != nil {
+		logger.Error("Error redelivering outbox events: %v", result.Error)
+		return 0, errors.New("error redelivering events")
+	}
+
+	if result.RowsAffected > 0 {
+		uid := uuid.MustParse(userID)
+		s.RecordAudit(uid, "outbox.redelivered", "outbox_event", nil, map[string]interface{}{
+			"requested": len(ids),
+			"requeued":  result.RowsAffected,
+		})
+		logger.Info("Requeued %d failed outbox events for user %s", result.RowsAffected, userID)
+	}
+	return result.RowsAffected, nil
+}
+
+// PurgeExpiredDeadLetters removes failed events whose last attempt is older than the dead-letter retention
+func (s *Services) PurgeExpiredDeadLetters() error {
+	// next_attempt_at is pushed past the last attempt on every failure
+	cutoff := time.Now().AddDate(0, 0, -deadLetterRetentionDays())
+	result := s.db.Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusFailed, cutoff).Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		logger.Error("Error purging dead-letter events: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Info("Purged %d dead-letter events past their retention", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/telemetry"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Domain event types
+const (
+	EventExpenseCreated = "expense.created"
+	EventIncomeCreated  = "income.created"
+	EventBudgetCreated  = "budget.created"
+	EventBudgetUpdated  = "budget.updated"
+)
+
+const (
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 10
+)
+
+// EventHandler delivers an outbox event to one destination (webhooks, notifications, SSE...).
+// Handlers must be idempotent: an event can be delivered more than once after a crash.
+type EventHandler func(event models.OutboxEvent) error
+
+var (
+	eventHandlersMu sync.RWMutex
+	eventHandlers   = make(map[string]EventHandler)
+)
+
+// RegisterEventHandler adds a named destination for outbox events
+func RegisterEventHandler(name string, handler EventHandler) {
+	eventHandlersMu.Lock()
+	defer eventHandlersMu.Unlock()
+	eventHandlers[name] = handler
+}
+
+// EnqueueEvent writes a domain event to the outbox using the caller's transaction, so the
+// event exists if and only if the change that caused it is committed
+func EnqueueEvent(tx *gorm.DB, userID uuid.UUID, eventType, aggregateType string, aggregateID uuid.UUID, payload interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := models.OutboxEvent{
+		UserID:        userID,
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       string(encoded),
+		RequestID:     logger.RequestID(tx.Statement.Context),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	return tx.Create(&event).Error
+}
+
+// DispatchOutboxEvents delivers a batch of pending events to every registered handler.
+// Rows are locked with SKIP LOCKED so several workers can run side by side.
+func (s *Services) DispatchOutboxEvents() (int, error) {
+	eventHandlersMu.RLock()
+	handlers := make(map[string]EventHandler, len(eventHandlers))
+	for name, handler := range eventHandlers {
+		handlers[name] = handler
+	}
+	eventHandlersMu.RUnlock()
+
+	dispatched := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var events []models.OutboxEvent
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, time.Now()).
+			Order("created_at ASC").
+			Limit(outboxBatchSize).
+			Find(&events)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, event := range events {
+			// Handler logs are tied to the request that caused the event
+			ctx := logger.WithRequestID(context.Background(), event.RequestID)
+			var deliveryErr error
+			for name, handler := range handlers {
+				_, span := telemetry.Tracer.Start(ctx, "outbox "+name, trace.WithAttributes(attribute.String("event.type", event.EventType)))
+				err := handler(event)
+				telemetry.RecordError(span, err)
+				span.End()
+				if err != nil {
+					logger.WarnContext(ctx, "Outbox handler %s failed for event %s: %v", name, event.ID, err)
+					deliveryErr = err
+				}
+			}
+
+			if err := tx.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).
+				Updates(outboxDeliveryUpdates(event, deliveryErr)).Error; err != nil {
+				return err
+			}
+			if deliveryErr == nil {
+				dispatched++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error dispatching outbox events: %v", err)
+		return dispatched, err
+	}
+
+	return dispatched, nil
+}
+
+// outboxDeliveryUpdates records the result of a delivery attempt, backing off exponentially on failure
+func outboxDeliveryUpdates(event models.OutboxEvent, deliveryErr error) map[string]interface{} {
+	now := time.Now()
+	attempts := event.Attempts + 1
+
+	if deliveryErr == nil {
+		return map[string]interface{}{
+			"status":       models.OutboxStatusDelivered,
+			"attempts":     attempts,
+			"delivered_at": &now,
+			"last_error":   nil,
+		}
+	}
+
+	message := deliveryErr.Error()
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      &message,
+		"next_attempt_at": now.Add(time.Duration(1<<uint(attempts)) * time.Second),
+	}
+	if attempts >= outboxMaxAttempts {
+		updates["status"] = models.OutboxStatusFailed
+	}
+
+	return updates
+}
+
+// StartOutboxDispatcher periodically delivers pending outbox events
+func (s *Services) StartOutboxDispatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// Events stay pending until maintenance is over
+			if s.IsMaintenanceMode() {
+				continue
+			}
+			if dispatched, err := s.DispatchOutboxEvents(); err == nil && dispatched > 0 {
+				logger.Debug("Dispatched %d outbox events", dispatched)
+			}
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestOutboxEvents(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	// Handlers are global, so this one only looks at the events of the users of this test
+	var (
+		mu        sync.Mutex
+		delivered = make(map[uuid.UUID][]string)
+		failures  = make(map[uuid.UUID]error)
+	)
+	services.RegisterEventHandler("outbox-test-"+uuid.NewString(), func(event models.OutboxEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err, ok := failures[event.UserID]; ok {
+			return err
+		}
+		delivered[event.UserID] = append(delivered[event.UserID], event.EventType)
+		return nil
+	})
+
+	errRollback := errors.New("rolled back")
+
+	cases := []struct {
+		name           string
+		rollback       bool
+		handlerErr     error
+		wantEvents     int
+		wantDispatched int
+		wantStatus     models.OutboxStatus
+		wantLastError  string
+	}{
+		{
+			name:           "delivers the event of a committed change",
+			wantEvents:     1,
+			wantDispatched: 1,
+			wantStatus:     models.OutboxStatusDelivered,
+		},
+		{
+			name:     "drops the event of a rolled back change",
+			rollback: true,
+		},
+		{
+			name:          "retries a failed delivery later",
+			handlerErr:    errors.New("webhook unreachable"),
+			wantEvents:    1,
+			wantStatus:    models.OutboxStatusPending,
+			wantLastError: "webhook unreachable",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			if tc.handlerErr != nil {
+				mu.Lock()
+				failures[user.ID] = tc.handlerErr
+				mu.Unlock()
+			}
+
+			err := h.DB.Transaction(func(tx *gorm.DB) error {
+				budget := models.Budget{UserID: user.ID, MonthYear: models.MonthStart(time.Now().UTC()), NeedsBudget: models.NewMoney(100), Status: models.StatusActive}
+				if err := tx.Create(&budget).Error; err != nil {
+					return err
+				}
+				if err := services.EnqueueEvent(tx, user.ID, services.EventBudgetCreated, "budget", budget.ID, map[string]interface{}{"needs_budget": budget.NeedsBudget}); err != nil {
+					return err
+				}
+				if tc.rollback {
+					return errRollback
+				}
+				return nil
+			})
+			if tc.rollback != errors.Is(err, errRollback) {
+				t.Fatalf("saving the change: %v", err)
+			}
+
+			dispatched, err := h.Services.DispatchOutboxEvents()
+			if err != nil {
+				t.Fatalf("dispatching: %v", err)
+			}
+			if dispatched != tc.wantDispatched {
+				t.Errorf("dispatched %d events, want %d", dispatched, tc.wantDispatched)
+			}
+
+			var events []models.OutboxEvent
+			if err := h.DB.Where("user_id = ?", user.ID).Find(&events).Error; err != nil {
+				t.Fatalf("listing events: %v", err)
+			}
+			if len(events) != tc.wantEvents {
+				t.Fatalf("%d events stored, want %d", len(events), tc.wantEvents)
+			}
+			mu.Lock()
+			got := delivered[user.ID]
+			mu.Unlock()
+			if len(got) != tc.wantDispatched {
+				t.Errorf("handler received %v, want %d events", got, tc.wantDispatched)
+			}
+			if tc.wantEvents == 0 {
+				return
+			}
+
+			event := events[0]
+			if event.EventType != services.EventBudgetCreated || event.Status != tc.wantStatus || event.Attempts != 1 {
+				t.Errorf("event %s is %s after %d attempts, want %s after 1", event.EventType, event.Status, event.Attempts, tc.wantStatus)
+			}
+			if tc.wantLastError == "" {
+				if event.LastError != nil || event.DeliveredAt == nil {
+					t.Errorf("delivered event has error %v and delivery time %v", event.LastError, event.DeliveredAt)
+				}
+				return
+			}
+			if event.LastError == nil || *event.LastError != tc.wantLastError {
+				t.Errorf("last error = %v, want %q", event.LastError, tc.wantLastError)
+			}
+			if !event.NextAttemptAt.After(time.Now()) {
+				t.Errorf("next attempt at %s isn't delayed", event.NextAttemptAt)
+			}
+		})
+	}
+}
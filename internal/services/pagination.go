@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxPageLimit is the largest page a list endpoint returns
+const MaxPageLimit = 500
+
+// DefaultPageLimit is the page size used when the request doesn't give one
+const DefaultPageLimit = 50
+
+// cursorPrefix versions the cursor format so it can change without breaking stored cursors
+const cursorPrefix = "o1:"
+
+// PageRequest selects a page of a list. A zero Limit gets a page of DefaultPageLimit rows
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Cursor string // Opaque next_cursor of a previous page; takes precedence over Offset
+}
+
+// PageInfo describes the page returned
+type PageInfo struct {
+	Total      int64   // Rows matching the list, across all pages
+	NextCursor *string // Nil on the last page
+}
+
+// Validate checks the page request, applies the default limit and resolves the cursor into
+// an offset
+func (p *PageRequest) Validate() error {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 1 || p.Limit > MaxPageLimit {
+		return errors.New("invalid limit: must be between 1 and " + strconv.Itoa(MaxPageLimit))
+	}
+	if p.Offset < 0 {
+		return errors.New("invalid offset: must be zero or positive")
+	}
+	if p.Cursor != "" {
+		offset, err := decodeCursor(p.Cursor)
+		if err != nil {
+			return err
+		}
+		p.Offset = offset
+	}
+	return nil
+}
+
+// encodeCursor builds the opaque cursor of the page starting at offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+// paginate counts the rows of the query (which must have its model set) and loads the requested
+// page into dest in the given order. Preloads go in the scopes so they don't run on the count
+func paginate(query *gorm.DB, order string, page PageRequest, dest interface{}, scopes ...func(*gorm.DB) *gorm.DB) (PageInfo, error) {
+	if err := page.Validate(); err != nil {
+		return PageInfo{}, err
+	}
+
+	var info PageInfo
+	if err := query.Session(&gorm.Session{}).Count(&info.Total).Error; err != nil {
+		return PageInfo{}, err
+	}
+
+	paged := query.Session(&gorm.Session{}).Scopes(scopes...).Order(order).Offset(page.Offset).Limit(page.Limit)
+	if err := paged.Find(dest).Error; err != nil {
+		return PageInfo{}, err
+	}
+
+	if int64(page.Offset+page.Limit) < info.Total {
+		next := encodeCursor(page.Offset + page.Limit)
+		info.NextCursor = &next
+	}
+	return info, nil
+}
@@ -0,0 +1,43 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/services"
+)
+
+func TestPageRequestValidate(t *testing.T) {
+	cases := []struct {
+		name      string
+		page      services.PageRequest
+		wantLimit int
+		wantErr   string
+	}{
+		{name: "no limit gets the default page", page: services.PageRequest{}, wantLimit: services.DefaultPageLimit},
+		{name: "limit kept", page: services.PageRequest{Limit: 10}, wantLimit: 10},
+		{name: "largest page", page: services.PageRequest{Limit: services.MaxPageLimit}, wantLimit: services.MaxPageLimit},
+		{name: "negative limit", page: services.PageRequest{Limit: -1}, wantErr: "invalid limit: must be between 1 and 500"},
+		{name: "limit over the maximum", page: services.PageRequest{Limit: services.MaxPageLimit + 1}, wantErr: "invalid limit: must be between 1 and 500"},
+		{name: "negative offset", page: services.PageRequest{Offset: -1}, wantErr: "invalid offset: must be zero or positive"},
+		{name: "bad cursor", page: services.PageRequest{Cursor: "not-a-cursor"}, wantErr: "invalid cursor"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := tc.page
+			err := page.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if page.Limit != tc.wantLimit {
+				t.Errorf("Limit = %d, want %d", page.Limit, tc.wantLimit)
+			}
+		})
+	}
+}
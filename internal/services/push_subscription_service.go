@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/push"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPushSubscriptions bounds the devices of a user; registering another replaces the least
+// recently used one
+const maxPushSubscriptions = 20
+
+// pushClient posts to the push services. Web Push endpoints come from browsers, so like
+// webhooks it never follows redirects nor connects to private addresses
+var pushClient = &http.Client{
+	Timeout: 15 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{
+		Proxy:                 nil,
+		DialContext:           publicDialContext(func() bool { return false }),
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Second,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+	},
+}
+
+var (
+	pushOnce       sync.Once
+	pushDispatcher *push.Dispatcher
+	pushErr        error
+)
+
+// getPushDispatcher returns the push senders configured by FCM_SERVICE_ACCOUNT_FILE and the
+// VAPID_* variables
+func getPushDispatcher() (*push.Dispatcher, error) {
+	pushOnce.Do(func() {
+		pushDispatcher, pushErr = push.FromEnv(pushClient)
+		if pushErr != nil && !errors.Is(pushErr, push.ErrNotConfigured) {
+			logger.Error("Error configuring push notifications: %v", pushErr)
+		}
+	})
+	return pushDispatcher, pushErr
+}
+
+// PushConfig tells clients which platforms they can register for
+type PushConfig struct {
+	Platforms      []string `json:"platforms"`                  // fcm and/or webpush
+	VAPIDPublicKey string   `json:"vapid_public_key,omitempty"` // applicationServerKey for PushManager.subscribe
+}
+
+// GetPushConfig lists the platforms pushes can be sent to; empty when push isn't configured
+func GetPushConfig() PushConfig {
+	config := PushConfig{Platforms: []string{}}
+	dispatcher, err := getPushDispatcher()
+	if err != nil {
+		return config
+	}
+	for _, platform := range []string{push.PlatformFCM, push.PlatformWebPush} {
+		if dispatcher.Supports(platform) {
+			config.Platforms = append(config.Platforms, platform)
+		}
+	}
+	config.VAPIDPublicKey = dispatcher.VAPIDPublicKey()
+	return config
+}
+
+// PushSubscriptionInput registers a device. FCM apps send their registration token; browsers
+// send the PushSubscription JSON (endpoint and keys)
+type PushSubscriptionInput struct {
+	Platform string `json:"platform" example:"webpush"`                                  // fcm or webpush
+	Token    string `json:"token,omitempty" example:"fGx1...:APA91b..."`                 // FCM registration token
+	Endpoint string `json:"endpoint,omitempty" example:"https://fcm.googleapis.com/..."` // Web Push endpoint
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"` // Web Push keys
+	Label *string `json:"label,omitempty" example:"Firefox on laptop"`
+}
+
+// errPushSubscriptionTaken is returned when the token or endpoint is registered to another user
+var errPushSubscriptionTaken = errors.New("push subscription already registered to another account")
+
+// RegisterPushSubscription adds a device of the user, or updates it when the user already
+// registered its token. A token of another user is refused: that user has to remove it first,
+// e.g. by logging out on the shared device
+func (s *Services) RegisterPushSubscription(userID string, input *PushSubscriptionInput) (*models.PushSubscription, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	subscription := models.PushSubscription{UserID: uid, Platform: input.Platform, Label: input.Label}
+	switch input.Platform {
+	case push.PlatformFCM:
+		subscription.Token = strings.TrimSpace(input.Token)
+		if subscription.Token == "" || len(subscription.Token) > 4096 {
+			return nil, errors.New("invalid push subscription: fcm requires token")
+		}
+	case push.PlatformWebPush:
+		if len(input.Endpoint) > 4096 {
+			return nil, errors.New("invalid push subscription: webpush endpoint is too long")
+		}
+		if err := push.ValidateWebPushEndpoint(input.Endpoint); err != nil {
+			return nil, errors.New("invalid push subscription: " + err.Error())
+		}
+		if input.Keys.P256dh == "" || input.Keys.Auth == "" {
+			return nil, errors.New("invalid push subscription: webpush requires keys.p256dh and keys.auth")
+		}
+		subscription.Token = input.Endpoint
+		subscription.P256dh = &input.Keys.P256dh
+		subscription.Auth = &input.Keys.Auth
+	default:
+		return nil, errors.New("invalid push platform: use fcm or webpush")
+	}
+	if subscription.Label != nil && len(*subscription.Label) > 100 {
+		return nil, errors.New("invalid label: at most 100 characters")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The conflict only updates a token of the same user; another user's row is left alone
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Table: "push_subscriptions", Name: "user_id"}, Value: uid}}},
+			DoUpdates: clause.AssignmentColumns([]string{"platform", "p256dh", "auth", "label", "updated_at"}),
+		}).Create(&subscription).Error; err != nil {
+			return err
+		}
+		var stored models.PushSubscription
+		if err := tx.Where("token = ?", subscription.Token).First(&stored).Error; err != nil {
+			return err
+		}
+		if stored.UserID != uid {
+			return errPushSubscriptionTaken
+		}
+		subscription = stored
+
+		// Forget the least recently used devices beyond the limit
+		return tx.Where("user_id = ? AND id NOT IN (?)", uid,
+			tx.Model(&models.PushSubscription{}).Select("id").Where("user_id = ?", uid).
+				Order("COALESCE(last_used_at, created_at) DESC").Limit(maxPushSubscriptions)).
+			Delete(&models.PushSubscription{}).Error
+	})
+	if errors.Is(err, errPushSubscriptionTaken) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Error registering push subscription: %v", err)
+		return nil, errors.New("error registering push subscription")
+	}
+	return &subscription, nil
+}
+
+// GetPushSubscriptions lists the devices of the user, newest first
+func (s *Services) GetPushSubscriptions(userID string) ([]models.PushSubscription, error) {
+	var subscriptions []models.PushSubscription
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		logger.Error("Error getting push subscriptions: %v", err)
+		return nil, errors.New("error getting push subscriptions")
+	}
+	return subscriptions, nil
+}
+
+// DeletePushSubscription stops pushes to a device of the user, e.g. on logout
+func (s *Services) DeletePushSubscription(userID string, subscriptionID string) error {
+	id, err := uuid.Parse(subscriptionID)
+	if err != nil {
+		return errors.New("invalid push subscription ID")
+	}
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		logger.Error("Error deleting push subscription: %v", result.Error)
+		return errors.New("error deleting push subscription")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("push subscription not found")
+	}
+	return nil
+}
+
+// touchPushSubscription records a push the service accepted
+func (s *Services) touchPushSubscription(id uuid.UUID, now time.Time) {
+	s.db.Model(&models.PushSubscription{}).Where("id = ?", id).Update("last_used_at", now)
+}
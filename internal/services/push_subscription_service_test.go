@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/push"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+)
+
+// TestRegisteringAnotherUsersPushTokenIsRefused registers the same device for two users: the
+// second registration must fail and leave the subscription with its owner
+func TestRegisteringAnotherUsersPushTokenIsRefused(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	owner := h.CreateUser(t)
+	attacker := h.CreateUser(t)
+
+	input := &services.PushSubscriptionInput{Platform: push.PlatformFCM, Token: "fcm-token-of-the-owner"}
+	registered, err := h.Services.RegisterPushSubscription(owner.ID.String(), input)
+	if err != nil {
+		t.Fatalf("registering the owner's device: %v", err)
+	}
+	// Registering it again updates the owner's subscription
+	label := "Phone"
+	input.Label = &label
+	updated, err := h.Services.RegisterPushSubscription(owner.ID.String(), input)
+	if err != nil {
+		t.Fatalf("registering the owner's device again: %v", err)
+	}
+	if updated.ID != registered.ID || updated.Label == nil || *updated.Label != label {
+		t.Fatalf("re-registration = %+v, want subscription %s labelled %q", updated, registered.ID, label)
+	}
+
+	if _, err := h.Services.RegisterPushSubscription(attacker.ID.String(), input); err == nil {
+		t.Fatal("another user took over the owner's push token")
+	}
+	subscriptions, err := h.Services.GetPushSubscriptions(owner.ID.String())
+	if err != nil {
+		t.Fatalf("listing the owner's devices: %v", err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].UserID != owner.ID {
+		t.Fatalf("owner's devices = %+v, want the one registered", subscriptions)
+	}
+}
+
+func TestRegisteringWebPushRequiresAKnownPushService(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+
+	input := &services.PushSubscriptionInput{Platform: push.PlatformWebPush, Endpoint: "https://169.254.169.254/latest/meta-data"}
+	input.Keys.P256dh = "BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM"
+	input.Keys.Auth = "tBHItJI5svbpez7KI4CCXg"
+	if _, err := h.Services.RegisterPushSubscription(user.ID.String(), input); err == nil {
+		t.Fatal("a webpush endpoint on a private address was accepted")
+	}
+
+	input.Endpoint = "https://updates.push.services.mozilla.com/wpush/v2/gAAAAABh"
+	if _, err := h.Services.RegisterPushSubscription(user.ID.String(), input); err != nil {
+		t.Fatalf("registering a Mozilla endpoint: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTokenBucketScript takes a token atomically so every instance sees the same bucket. The
+// clock is Redis' own to keep instances with drifting clocks consistent. A bucket that never
+// refills is kept for a day once spent
+var redisTokenBucketScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2]) / 1000
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+elseif rate > 0 then
+  wait = math.ceil((1 - tokens) / rate)
+else
+  wait = tonumber(ARGV[3])
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+if rate > 0 then
+  redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate))
+else
+  redis.call('PEXPIRE', KEYS[1], 86400000)
+end
+return {allowed, wait}
+`)
+
+// redisRateLimitStore keeps the buckets in Redis, shared by every instance
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+func newRedisRateLimitStore(rawURL string) (*redisRateLimitStore, error) {
+	if rawURL == "" {
+		return nil, errors.New("redis rate limit store needs REDIS_URL")
+	}
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisRateLimitStore{client: client}, nil
+}
+
+func (s *redisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	reply, err := redisTokenBucketScript.Run(ctx, s.client, []string{"fluxio:ratelimit:" + key},
+		limit.Burst, strconv.FormatFloat(limit.refillPerSecond(), 'f', -1, 64), noRefillRetryAfter.Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected reply from rate limit script: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	waitMillis, _ := values[1].(int64)
+	return allowed == 1, time.Duration(waitMillis) * time.Millisecond, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// RateLimit is a token bucket: Burst requests at once, refilled at PerMinute. A limit with
+// PerMinute at 0 never refills, so the key is blocked once the burst is spent
+type RateLimit struct {
+	Burst     int
+	PerMinute int
+}
+
+// noRefillRetryAfter is the retry time given for a spent bucket that never refills
+const noRefillRetryAfter = time.Hour
+
+// authRateLimitVariables are the AUTH_RATE_LIMIT_* settings; each must be a positive integer
+var authRateLimitVariables = []string{
+	"AUTH_RATE_LIMIT_IP_BURST",
+	"AUTH_RATE_LIMIT_IP_PER_MINUTE",
+	"AUTH_RATE_LIMIT_ACCOUNT_BURST",
+	"AUTH_RATE_LIMIT_ACCOUNT_PER_MINUTE",
+}
+
+// ValidateAuthRateLimits checks the AUTH_RATE_LIMIT_* settings at startup. A zero or negative
+// refill would otherwise fall back to the default without anyone noticing
+func ValidateAuthRateLimits() error {
+	for _, name := range authRateLimitVariables {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		if value, err := strconv.Atoi(raw); err != nil || value <= 0 {
+			return fmt.Errorf("invalid %s: must be a positive integer, got %q", name, raw)
+		}
+	}
+	return nil
+}
+
+// AuthRateLimits returns the limits on the login, register and refresh endpoints, per client IP
+// and per account (email or refresh token)
+func AuthRateLimits() (perIP RateLimit, perAccount RateLimit) {
+	perIP = RateLimit{
+		Burst:     envInt("AUTH_RATE_LIMIT_IP_BURST", 20),
+		PerMinute: envInt("AUTH_RATE_LIMIT_IP_PER_MINUTE", 10),
+	}
+	perAccount = RateLimit{
+		Burst:     envInt("AUTH_RATE_LIMIT_ACCOUNT_BURST", 5),
+		PerMinute: envInt("AUTH_RATE_LIMIT_ACCOUNT_PER_MINUTE", 2),
+	}
+	return perIP, perAccount
+}
+
+// rateLimitStore keeps the token buckets. Take removes a token from the bucket of key and,
+// when it is empty, returns how long until the next one
+type rateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+var (
+	rateLimitStoreOnce sync.Once
+	rateLimiter        rateLimitStore
+	rateLimiterErr     error
+)
+
+// getRateLimitStore returns the store selected by RATE_LIMIT_STORE: "memory" (the default) keeps
+// the buckets in this instance, "redis" shares them between instances through REDIS_URL
+func getRateLimitStore() (rateLimitStore, error) {
+	rateLimitStoreOnce.Do(func() {
+		switch os.Getenv("RATE_LIMIT_STORE") {
+		case "", "memory":
+			rateLimiter = &memoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+		case "redis":
+			rateLimiter, rateLimiterErr = newRedisRateLimitStore(os.Getenv("REDIS_URL"))
+		default:
+			rateLimiterErr = errors.New("invalid RATE_LIMIT_STORE: must be memory or redis")
+		}
+		if rateLimiterErr != nil {
+			logger.Error("Error configuring rate limit store: %v", rateLimiterErr)
+		}
+	})
+	return rateLimiter, rateLimiterErr
+}
+
+// TakeRateLimitToken spends a request from the bucket of key. When the bucket is empty it returns
+// false and how long until a request is allowed again. Requests are let through if the store
+// can't be reached, so a Redis outage doesn't lock everyone out
+func TakeRateLimitToken(ctx context.Context, key string, limit RateLimit) (bool, time.Duration) {
+	store, err := getRateLimitStore()
+	if err != nil {
+		return true, 0
+	}
+	allowed, retryAfter, err := store.Take(ctx, key, limit)
+	if err != nil {
+		logger.Error("Error checking rate limit for %s: %v", key, err)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
+
+// refillPerSecond is the rate a bucket refills at
+func (limit RateLimit) refillPerSecond() float64 {
+	return float64(limit.PerMinute) / 60
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// memoryRateLimitStore keeps the buckets of this instance only
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	now := time.Now()
+	rate := limit.refillPerSecond()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Full buckets hold nothing worth keeping
+	if len(s.buckets) > 10000 {
+		for bucketKey, bucket := range s.buckets {
+			if bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate >= float64(limit.Burst) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate)
+	bucket.updatedAt = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+	if rate <= 0 {
+		return false, noRefillRetryAfter, nil
+	}
+	wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
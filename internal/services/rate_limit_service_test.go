@@ -0,0 +1,84 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	server := miniredis.RunT(t)
+	stores := map[string]func(t *testing.T) services.RateLimitStore{
+		"memory": func(t *testing.T) services.RateLimitStore { return services.NewMemoryRateLimitStore() },
+		"redis": func(t *testing.T) services.RateLimitStore {
+			store, err := services.NewRedisRateLimitStore("redis://" + server.Addr())
+			if err != nil {
+				t.Fatalf("connecting to redis: %v", err)
+			}
+			return store
+		},
+	}
+	cases := []struct {
+		name    string
+		limit   services.RateLimit
+		allowed int
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{"refilling bucket", services.RateLimit{Burst: 2, PerMinute: 60}, 2, 900 * time.Millisecond, time.Second},
+		{"bucket that never refills", services.RateLimit{Burst: 1, PerMinute: 0}, 1, time.Hour, time.Hour},
+	}
+	for storeName, newStore := range stores {
+		for _, c := range cases {
+			t.Run(storeName+"/"+c.name, func(t *testing.T) {
+				store := newStore(t)
+				key := storeName + ":" + c.name
+				for i := 0; i < c.allowed; i++ {
+					allowed, _, err := store.Take(context.Background(), key, c.limit)
+					if err != nil || !allowed {
+						t.Fatalf("request %d: allowed = %v, err = %v, want it allowed", i+1, allowed, err)
+					}
+				}
+				allowed, wait, err := store.Take(context.Background(), key, c.limit)
+				if err != nil {
+					t.Fatalf("request past the burst: %v", err)
+				}
+				if allowed {
+					t.Fatal("request past the burst was allowed")
+				}
+				if wait < c.minWait || wait > c.maxWait {
+					t.Fatalf("retry after %s, want between %s and %s", wait, c.minWait, c.maxWait)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateAuthRateLimits(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"unset", "", true},
+		{"positive", "5", true},
+		{"zero", "0", false},
+		{"negative", "-1", false},
+		{"not a number", "ten", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("AUTH_RATE_LIMIT_ACCOUNT_PER_MINUTE", c.value)
+			err := services.ValidateAuthRateLimits()
+			if c.valid && err != nil {
+				t.Errorf("ValidateAuthRateLimits() = %v, want nil", err)
+			}
+			if !c.valid && err == nil {
+				t.Error("ValidateAuthRateLimits() accepted the value")
+			}
+		})
+	}
+}
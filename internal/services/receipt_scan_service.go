@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/ocr"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// receiptScanTimeout bounds the OCR of one receipt
+const receiptScanTimeout = time.Minute
+
+// receiptHeaderLines is how many lines from the top are looked up as a known merchant
+const receiptHeaderLines = 6
+
+// receiptHistoryDays is how far back past expenses at the same merchant suggest a category
+const receiptHistoryDays = 365
+
+// scannableReceiptTypes are the image formats sent to OCR, as sniffed from the content
+var scannableReceiptTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+var (
+	ocrOnce     sync.Once
+	ocrProvider ocr.Provider
+	ocrErr      error
+)
+
+// getOCRProvider returns the provider configured by OCR_PROVIDER
+func getOCRProvider() (ocr.Provider, error) {
+	ocrOnce.Do(func() {
+		ocrProvider, ocrErr = ocr.FromEnv()
+		if ocrErr != nil && !errors.Is(ocrErr, ocr.ErrNotConfigured) {
+			logger.Error("Error configuring receipt scanning: %v", ocrErr)
+		}
+	})
+	return ocrProvider, ocrErr
+}
+
+// ScanReceipt reads the merchant, date and total of a receipt image and suggests a category:
+// the merchant's usual one when the user has it, else the one the user picked most often for
+// past expenses there. Nothing is stored; the client confirms the expense with a normal create
+func (s *Services) ScanReceipt(ctx context.Context, userID string, content io.Reader, size int64) (*dto.ReceiptScan, error) {
+	if size <= 0 {
+		return nil, errors.New("invalid receipt: the file is empty")
+	}
+	if size > MaxAttachmentBytes() {
+		return nil, fmt.Errorf("invalid receipt: the file is larger than %d MB", MaxAttachmentBytes()>>20)
+	}
+	image, err := io.ReadAll(io.LimitReader(content, MaxAttachmentBytes()+1))
+	if err != nil {
+		return nil, errors.New("invalid receipt: the file can't be read")
+	}
+	if !scannableReceiptTypes[http.DetectContentType(image)] {
+		return nil, errors.New("invalid receipt: only JPEG, PNG and WebP images can be scanned")
+	}
+
+	provider, err := getOCRProvider()
+	if err != nil {
+		return nil, ocr.ErrNotConfigured
+	}
+	ctx, cancel := context.WithTimeout(ctx, receiptScanTimeout)
+	defer cancel()
+	text, err := provider.Recognize(ctx, image)
+	if err != nil {
+		logger.Error("Error scanning receipt with %s: %v", provider.Name(), err)
+		return nil, errors.New("error reading receipt")
+	}
+
+	// US receipts print numeric dates month first
+	monthFirst := false
+	if preferences, err := s.getUserPreferences(userID); err == nil {
+		monthFirst = preferences.HolidayCountry == "US"
+	}
+	receipt := ocr.ParseReceipt(text, monthFirst)
+
+	scan := &dto.ReceiptScan{Merchant: receipt.Merchant, Total: receipt.Total, Provider: provider.Name(), Text: text}
+	if receipt.Date != nil && !receipt.Date.After(s.UserNow(userID)) {
+		date := receipt.Date.Format("2006-01-02")
+		scan.Date = &date
+	}
+
+	// A known merchant anywhere in the header gives its proper name and usual category
+	var merchant *MerchantInfo
+	for i, line := range receipt.Lines {
+		if i >= receiptHeaderLines {
+			break
+		}
+		if merchant = EnrichMerchant(line); merchant != nil {
+			scan.Merchant = &merchant.Name
+			break
+		}
+	}
+	if categoryID := s.merchantCategoryID(userID, merchant, make(map[string]*uuid.UUID)); categoryID != nil {
+		id := categoryID.String()
+		scan.CategoryID, scan.CategorySource = &id, "merchant"
+	} else if scan.Merchant != nil {
+		if categoryID := s.historyCategoryID(userID, *scan.Merchant); categoryID != nil {
+			id := categoryID.String()
+			scan.CategoryID, scan.CategorySource = &id, "history"
+		}
+	}
+
+	var accountIDs []uuid.UUID
+	if err := s.db.Model(&models.Expense{}).
+		Joins("JOIN bank_accounts ba ON ba.id = expenses.bank_account_id AND ba.status IN ?", models.GetActiveStatuses()).
+		Where("expenses.user_id = ? AND expenses.status IN ?", userID, models.GetActiveStatuses()).
+		Order("expenses.created_at DESC").Limit(1).Pluck("expenses.bank_account_id", &accountIDs).Error; err == nil && len(accountIDs) > 0 {
+		id := accountIDs[0].String()
+		scan.BankAccountID = &id
+	}
+
+	return scan, nil
+}
+
+// historyCategoryID is the active category the user chose most often for expenses whose
+// description mentions the merchant in the last receiptHistoryDays
+func (s *Services) historyCategoryID(userID string, merchant string) *uuid.UUID {
+	var categoryIDs []uuid.UUID
+	if err := s.db.Model(&models.Expense{}).
+		Joins("JOIN categories c ON c.id = expenses.category_id AND c.status IN ?", models.GetActiveStatuses()).
+		Where("expenses.user_id = ? AND expenses.status IN ? AND expenses.date >= ? AND expenses.description ILIKE ?",
+			userID, models.GetActiveStatuses(), s.UserNow(userID).AddDate(0, 0, -receiptHistoryDays), "%"+escapeLike(merchant)+"%").
+		Group("expenses.category_id").Order("COUNT(*) DESC").Limit(1).
+		Pluck("expenses.category_id", &categoryIDs).Error; err != nil || len(categoryIDs) == 0 {
+		return nil
+	}
+	return &categoryIDs[0]
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisCommandTimeout bounds a round trip, so a slow Redis doesn't hold up logins
+const redisCommandTimeout = 2 * time.Second
+
+// newRedisClient returns a pooled client for a redis://[user:password@]host:port/db URL;
+// rediss:// connects over TLS. Broken connections are dropped from the pool and redialed
+func newRedisClient(rawURL string) (*redis.Client, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, errors.New("invalid REDIS_URL: must be redis://[user:password@]host:port/db")
+	}
+	// redis://password@host carries no username, a lone userinfo is the password
+	if options.Password == "" && options.Username != "" {
+		options.Username, options.Password = "", options.Username
+	}
+	options.DialTimeout = redisCommandTimeout
+	options.ReadTimeout = redisCommandTimeout
+	options.WriteTimeout = redisCommandTimeout
+	options.ContextTimeoutEnabled = true
+	return redis.NewClient(options), nil
+}
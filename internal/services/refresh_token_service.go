@@ -6,19 +6,20 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
 	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RefreshTokenService struct {
 	db *gorm.DB
 }
 
-func NewRefreshTokenService() *RefreshTokenService {
+func (s *Services) NewRefreshTokenService() *RefreshTokenService {
 	return &RefreshTokenService{
-		db: db.DB,
+		db: s.db,
 	}
 }
 
@@ -61,6 +62,65 @@ func (s *RefreshTokenService) CreateRefreshToken(userID uuid.UUID, expirationDay
 	return refreshToken, nil
 }
 
+// CreateSlidingRefreshToken creates a refresh token that expires after the configured TTL.
+// When it replaces a previous token of the same session, the family and the absolute expiry
+// are inherited so the session can never be extended past the configured maximum.
+func (s *RefreshTokenService) CreateSlidingRefreshToken(userID uuid.UUID, previous *models.RefreshToken, login LoginContext) (*models.RefreshToken, error) {
+	config := GetSessionConfig()
+	now := time.Now()
+
+	absoluteExpiresAt := now.Add(config.RefreshTokenMaxAge)
+	if previous != nil && previous.AbsoluteExpiresAt != nil {
+		absoluteExpiresAt = *previous.AbsoluteExpiresAt
+	}
+
+	if !now.Before(absoluteExpiresAt) {
+		return nil, errors.New("session has reached its maximum lifetime")
+	}
+
+	expiresAt := now.Add(config.RefreshTokenTTL)
+	if expiresAt.After(absoluteExpiresAt) {
+		expiresAt = absoluteExpiresAt
+	}
+
+	tokenString, err := s.generateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := &models.RefreshToken{
+		ID:                uuid.New(),
+		UserID:            userID,
+		Token:             tokenString,
+		ExpiresAt:         expiresAt,
+		AbsoluteExpiresAt: &absoluteExpiresAt,
+		IPAddress:         login.IPAddress,
+		UserAgent:         login.UserAgent,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		IsRevoked:         false,
+	}
+	refreshToken.FamilyID = refreshToken.ID
+	if previous != nil {
+		refreshToken.FamilyID = tokenFamilyID(previous)
+		if refreshToken.UserAgent == "" {
+			refreshToken.UserAgent = previous.UserAgent
+		}
+		if refreshToken.IPAddress == "" {
+			refreshToken.IPAddress = previous.IPAddress
+		}
+	}
+	if len(refreshToken.UserAgent) > 512 {
+		refreshToken.UserAgent = refreshToken.UserAgent[:512]
+	}
+
+	if err := s.db.Create(refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
 // GetRefreshTokenByToken retrieves a refresh token by its token string
 func (s *RefreshTokenService) GetRefreshTokenByToken(tokenString string) (*models.RefreshToken, error) {
 	var refreshToken models.RefreshToken
@@ -107,22 +167,25 @@ func (s *RefreshTokenService) ValidateRefreshToken(tokenString string) (*models.
 	return &refreshToken.User, nil
 }
 
-// RevokeRefreshToken revokes a refresh token by token string
+// RevokeRefreshToken revokes a refresh token by token string, ending its session
 func (s *RefreshTokenService) RevokeRefreshToken(tokenString string) error {
-	updates := map[string]interface{}{
-		"is_revoked": true,
-		"updated_at": time.Now(),
+	refreshToken, err := s.GetRefreshTokenByToken(tokenString)
+	if err != nil {
+		return err
 	}
 
-	result := s.db.Model(&models.RefreshToken{}).Where("token = ?", tokenString).Updates(updates)
-	if result.Error != nil {
-		return result.Error
+	updates := map[string]interface{}{
+		"is_revoked":     true,
+		"revoked_reason": models.RefreshTokenRevokedLogout,
+		"updated_at":     time.Now(),
 	}
 
-	if result.RowsAffected == 0 {
-		return errors.New("refresh token not found")
+	// Rotated tokens keep their reason so a later replay is still detected
+	if err := s.db.Model(&models.RefreshToken{}).Where("id = ? AND is_revoked = ?", refreshToken.ID, false).Updates(updates).Error; err != nil {
+		return err
 	}
 
+	forgetSessionState(tokenFamilyID(refreshToken).String())
 	return nil
 }
 
@@ -148,11 +211,16 @@ func (s *RefreshTokenService) RevokeRefreshTokenByID(tokenID uuid.UUID) error {
 // RevokeAllUserRefreshTokens revokes all refresh tokens for a specific user
 func (s *RefreshTokenService) RevokeAllUserRefreshTokens(userID uuid.UUID) error {
 	updates := map[string]interface{}{
-		"is_revoked": true,
-		"updated_at": time.Now(),
+		"is_revoked":     true,
+		"revoked_reason": models.RefreshTokenRevokedLogout,
+		"updated_at":     time.Now(),
 	}
 
-	return s.db.Model(&models.RefreshToken{}).Where("user_id = ?", userID).Updates(updates).Error
+	if err := s.db.Model(&models.RefreshToken{}).Where("user_id = ? AND is_revoked = ?", userID, false).Updates(updates).Error; err != nil {
+		return err
+	}
+	forgetUserSessionStates(userID)
+	return nil
 }
 
 // GetUserRefreshTokens retrieves all refresh tokens for a user
@@ -196,65 +264,69 @@ func (s *RefreshTokenService) CleanupRevokedTokens(olderThanDays int) error {
 	return s.db.Where("is_revoked = ? AND updated_at < ?", true, cutoffDate).Delete(&models.RefreshToken{}).Error
 }
 
-// RotateRefreshToken revokes the old token and creates a new one
-func (s *RefreshTokenService) RotateRefreshToken(oldTokenString string, expirationDays int) (*models.RefreshToken, error) {
-	// Validate the old token first
-	user, err := s.ValidateRefreshToken(oldTokenString)
-	if err != nil {
-		return nil, err
-	}
-
-	// Start transaction
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated is presented
+// again. Only one of the clients holding the family can be legitimate, so it is revoked whole
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RotateRefreshToken exchanges a refresh token for the next one of its family. The old token
+// stays stored, revoked and pointing to its replacement, so a replay is told apart from an
+// unknown token
+func (s *RefreshTokenService) RotateRefreshToken(oldTokenString string, login LoginContext) (*models.RefreshToken, *models.User, error) {
+	var next *models.RefreshToken
+	var user models.User
+	var reused *models.RefreshToken
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the token so two refreshes at once can't both rotate it
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", oldTokenString).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("refresh token not found")
+			}
+			return err
 		}
-	}()
-
-	// Revoke the old token
-	updates := map[string]interface{}{
-		"is_revoked": true,
-		"updated_at": time.Now(),
-	}
 
-	if err := tx.Model(&models.RefreshToken{}).Where("token = ?", oldTokenString).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+		if current.IsRevoked {
+			if current.ReplacedByID == nil {
+				return errors.New("refresh token is invalid or expired")
+			}
+			reused = &current
+			return revokeReusedRefreshTokenFamily(tx, &current, login)
+		}
+		if !current.IsValid() {
+			return errors.New("refresh token is invalid or expired")
+		}
 
-	// Create new token
-	if expirationDays <= 0 {
-		expirationDays = 30
-	}
+		if err := tx.Where("id = ?", current.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if !user.IsAccessible() {
+			return errors.New("user account is not accessible")
+		}
 
-	tokenString, err := s.generateSecureToken()
+		created, err := (&RefreshTokenService{db: tx}).CreateSlidingRefreshToken(user.ID, &current, login)
+		if err != nil {
+			return err
+		}
+		next = created
+
+		return tx.Model(&models.RefreshToken{}).Where("id = ?", current.ID).Updates(map[string]interface{}{
+			"is_revoked":     true,
+			"revoked_reason": models.RefreshTokenRevokedRotated,
+			"replaced_by_id": next.ID,
+			"updated_at":     time.Now(),
+		}).Error
+	})
 	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	newRefreshToken := &models.RefreshToken{
-		ID:        uuid.New(),
-		UserID:    user.ID,
-		Token:     tokenString,
-		ExpiresAt: time.Now().AddDate(0, 0, expirationDays),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		IsRevoked: false,
+		return nil, nil, err
 	}
-
-	if err := tx.Create(newRefreshToken).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
+	if reused != nil {
+		forgetSessionState(tokenFamilyID(reused).String())
+		logger.Warn("Refresh token reuse detected for user %s, session %s revoked", reused.UserID, tokenFamilyID(reused))
+		return nil, nil, ErrRefreshTokenReused
 	}
 
-	return newRefreshToken, nil
+	return next, &user, nil
 }
 
 // GetRefreshTokenStats returns statistics about refresh tokens
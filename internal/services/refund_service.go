@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"os"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// refundsNettedInOriginalMonth reports whether refunds reduce the totals of the month
+// of the expense they refund. Set REFUND_NETTING=none to keep refunds as plain income.
+func refundsNettedInOriginalMonth() bool {
+	return os.Getenv("REFUND_NETTING") != "none"
+}
+
+// joinExpenseRefunds joins the refunded total of each expense (aliased as "e") so that
+// netExpenseAmountSQL can be used in aggregations
+func joinExpenseRefunds(query *gorm.DB) *gorm.DB {
+	if !refundsNettedInOriginalMonth() {
+		return query
+	}
+
+	return query.Joins(`LEFT JOIN (
+		SELECT refund_of_expense_id, SUM(amount) AS refunded
+		FROM incomes
+		WHERE refund_of_expense_id IS NOT NULL AND status IN ?
+		GROUP BY refund_of_expense_id
+	) rf ON rf.refund_of_expense_id = e.id`, models.GetActiveStatuses())
+}
+
+// netExpenseAmountSQL returns the SQL expression for an expense amount after refunds
+func netExpenseAmountSQL() string {
+	if !refundsNettedInOriginalMonth() {
+		return "e.amount"
+	}
+	return "(e.amount - COALESCE(rf.refunded, 0))"
+}
+
+// GetRefundedAmount returns the total of active refunds linked to an expense,
+// optionally excluding one income (used when the refund itself is being edited)
+func (s *Services) GetRefundedAmount(expenseID uuid.UUID, excludeIncomeID *uuid.UUID) (models.Money, error) {
+	var refunded models.Money
+	query := s.db.Model(&models.Income{}).
+		Where("refund_of_expense_id = ? AND status IN ?", expenseID, models.GetActiveStatuses())
+
+	if excludeIncomeID != nil {
+		query = query.Where("id <> ?", *excludeIncomeID)
+	}
+
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		logger.Error("Error calculating refunded amount: %v", err)
+		return 0, err
+	}
+
+	return refunded, nil
+}
+
+// validateRefund checks that the refunded expense belongs to the user and that
+// the refunds linked to it never exceed the original amount
+func (s *Services) validateRefund(userID string, expenseID uuid.UUID, amount models.Money, excludeIncomeID *uuid.UUID) error {
+	var expense models.Expense
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, expenseID, models.GetActiveStatuses()).First(&expense)
+	if result.Error != nil {
+		logger.Error("Refunded expense not found or not active: %v", result.Error)
+		return errors.New("refunded expense not found or not active")
+	}
+
+	refunded, err := s.GetRefundedAmount(expenseID, excludeIncomeID)
+	if err != nil {
+		return err
+	}
+
+	if refunded+amount > expense.Amount {
+		logger.Error("Refund of %s exceeds remaining %s for expense %s", amount, expense.Amount-refunded, expenseID)
+		return errors.New("refund exceeds the original expense amount")
+	}
+
+	return nil
+}
+
+// GetExpenseRefunds returns the expense together with the refunds linked to it
+func (s *Services) GetExpenseRefunds(userID string, expenseID string) (*models.Expense, []models.Income, error) {
+	expense, err := s.Expenses.GetByID(userID, expenseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var refunds []models.Income
+	result := s.db.Where("user_id = ? AND refund_of_expense_id = ? AND status IN ?", userID, expense.ID, models.GetVisibleStatuses()).
+		Preload("BankAccount").
+		Order("date DESC, created_at DESC").Find(&refunds)
+	if result.Error != nil {
+		logger.Error("Error getting expense refunds: %v", result.Error)
+		return nil, nil, result.Error
+	}
+
+	logger.Info("Refunds retrieved successfully for expense %s", expenseID)
+	return expense, refunds, nil
+}
@@ -4,20 +4,16 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type ReminderService struct {
-	db *gorm.DB
-}
-
-func NewReminderService() *ReminderService {
-	return &ReminderService{
-		db: db.DB,
-	}
+	db       *gorm.DB
+	services *Services
 }
 
 // CreateReminder creates a new reminder for a user
@@ -99,12 +95,12 @@ func (s *ReminderService) GetUserReminders(userID uuid.UUID, completed *bool, re
 
 // GetUpcomingReminders retrieves reminders due within the specified number of days
 func (s *ReminderService) GetUpcomingReminders(userID uuid.UUID, daysAhead int) ([]*models.Reminder, error) {
-	now := time.Now()
-	futureDate := now.AddDate(0, 0, daysAhead)
+	today := s.services.UserToday(userID.String())
+	futureDate := today.AddDate(0, 0, daysAhead)
 
 	var reminders []*models.Reminder
-	if err := s.db.Where("user_id = ? AND status = ? AND is_completed = ? AND due_date >= ? AND due_date <= ?", 
-		userID, models.StatusActive, false, now, futureDate).
+	if err := s.db.Where("user_id = ? AND status = ? AND is_completed = ? AND due_date > ? AND due_date <= ?", 
+		userID, models.StatusActive, false, today, futureDate).
 		Order("due_date ASC").
 		Find(&reminders).Error; err != nil {
 		return nil, err
@@ -115,11 +111,11 @@ func (s *ReminderService) GetUpcomingReminders(userID uuid.UUID, daysAhead int)
 
 // GetOverdueReminders retrieves reminders that are past due and not completed
 func (s *ReminderService) GetOverdueReminders(userID uuid.UUID) ([]*models.Reminder, error) {
-	now := time.Now()
+	today := s.services.UserToday(userID.String())
 
 	var reminders []*models.Reminder
-	if err := s.db.Where("user_id = ? AND status = ? AND is_completed = ? AND due_date < ?", 
-		userID, models.StatusActive, false, now).
+	if err := s.db.Where("user_id = ? AND status = ? AND is_completed = ? AND due_date <= ?", 
+		userID, models.StatusActive, false, today).
 		Order("due_date ASC").
 		Find(&reminders).Error; err != nil {
 		return nil, err
@@ -151,10 +147,18 @@ func (s *ReminderService) UpdateReminder(userID, reminderID uuid.UUID, updates m
 	// Add updated_at timestamp
 	updates["updated_at"] = time.Now()
 
+	// Completing a budget review counts as reviewing the budget
+	completesReview := reminder.ReminderType == models.ReminderTypeBudgetReview && !reminder.IsCompleted && updates["is_completed"] == true
+
 	// Update reminder
 	if err := s.db.Model(reminder).Updates(updates).Error; err != nil {
 		return nil, err
 	}
+	if completesReview {
+		if err := s.services.RecordBudgetReview(userID.String()); err != nil {
+			logger.Warn("Error recording budget review of user %s: %v", userID, err)
+		}
+	}
 
 	// Return updated reminder
 	return s.GetReminderByID(userID, reminderID)
@@ -223,48 +227,36 @@ func (s *ReminderService) GetRemindersByType(userID uuid.UUID, reminderType stri
 }
 
 // GetReminderStats returns statistics about user's reminders
-func (s *ReminderService) GetReminderStats(userID uuid.UUID) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
+func (s *ReminderService) GetReminderStats(userID uuid.UUID) (*dto.ReminderStats, error) {
+	stats := &dto.ReminderStats{ByType: make(map[string]int64)}
 
 	// Total active reminders
-	var totalCount int64
-	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ?", userID, models.StatusActive).Count(&totalCount)
-	stats["total_reminders"] = totalCount
+	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ?", userID, models.StatusActive).Count(&stats.TotalReminders)
 
 	// Completed reminders
-	var completedCount int64
-	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ?", userID, models.StatusActive, true).Count(&completedCount)
-	stats["completed_reminders"] = completedCount
+	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ?", userID, models.StatusActive, true).Count(&stats.CompletedReminders)
 
 	// Pending reminders
-	var pendingCount int64
-	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ?", userID, models.StatusActive, false).Count(&pendingCount)
-	stats["pending_reminders"] = pendingCount
+	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ?", userID, models.StatusActive, false).Count(&stats.PendingReminders)
 
 	// Overdue reminders
-	now := time.Now()
-	var overdueCount int64
-	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ? AND due_date < ?", 
-		userID, models.StatusActive, false, now).Count(&overdueCount)
-	stats["overdue_reminders"] = overdueCount
+	today := s.services.UserToday(userID.String())
+	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ? AND due_date <= ?", 
+		userID, models.StatusActive, false, today).Count(&stats.OverdueReminders)
 
 	// Upcoming reminders (next 7 days)
-	futureDate := now.AddDate(0, 0, 7)
-	var upcomingCount int64
-	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ? AND due_date >= ? AND due_date <= ?", 
-		userID, models.StatusActive, false, now, futureDate).Count(&upcomingCount)
-	stats["upcoming_reminders"] = upcomingCount
+	futureDate := today.AddDate(0, 0, 7)
+	s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND is_completed = ? AND due_date > ? AND due_date <= ?", 
+		userID, models.StatusActive, false, today, futureDate).Count(&stats.UpcomingReminders)
 
 	// Count by type
-	typeStats := make(map[string]int64)
 	types := []string{"bill", "goal", "budget_review"}
 	for _, reminderType := range types {
 		var count int64
 		s.db.Model(&models.Reminder{}).Where("user_id = ? AND status = ? AND reminder_type = ?", 
 			userID, models.StatusActive, reminderType).Count(&count)
-		typeStats[reminderType] = count
+		stats.ByType[reminderType] = count
 	}
-	stats["by_type"] = typeStats
 
 	return stats, nil
 }
@@ -280,9 +272,25 @@ func (s *ReminderService) BulkCompleteReminders(userID uuid.UUID, reminderIDs []
 		"updated_at":   time.Now(),
 	}
 
-	return s.db.Model(&models.Reminder{}).
+	// Completing a budget review counts as reviewing the budget
+	var pendingReviews int64
+	s.db.Model(&models.Reminder{}).
+		Where("id IN ? AND user_id = ? AND status IN ? AND is_completed = ? AND reminder_type = ?",
+			reminderIDs, userID, models.GetActiveStatuses(), false, models.ReminderTypeBudgetReview).
+		Count(&pendingReviews)
+
+	if err := s.db.Model(&models.Reminder{}).
 		Where("id IN ? AND user_id = ? AND status IN ?", reminderIDs, userID, models.GetActiveStatuses()).
-		Updates(updates).Error
+		Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if pendingReviews > 0 {
+		if err := s.services.RecordBudgetReview(userID.String()); err != nil {
+			logger.Warn("Error recording budget review of user %s: %v", userID, err)
+		}
+	}
+	return nil
 }
 
 // SnoozeReminder postpones a reminder by the specified number of days
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// heavyRequestSlots holds the requests each user has running on expensive endpoints
+type heavyRequestSlots struct {
+	slots   chan struct{}
+	holders int // Requests running or waiting; the entry is dropped at zero
+}
+
+var (
+	heavyRequestsMu sync.Mutex
+	heavyRequests   = make(map[string]*heavyRequestSlots)
+)
+
+// HeavyRequestLimits returns how many expensive requests a user may run at once and how long
+// an extra request waits for a free slot before being rejected
+func HeavyRequestLimits() (int, time.Duration) {
+	return envInt("HEAVY_REQUEST_CONCURRENCY", 2), time.Duration(envInt("HEAVY_REQUEST_QUEUE_SECONDS", 5)) * time.Second
+}
+
+// AcquireHeavyRequestSlot waits until the user has a free slot for an expensive request, up
+// to the queue wait or until ctx is done. When it returns true, release must be called once
+// the request finishes
+func AcquireHeavyRequestSlot(ctx context.Context, userID string) (release func(), ok bool) {
+	limit, wait := HeavyRequestLimits()
+
+	heavyRequestsMu.Lock()
+	entry, exists := heavyRequests[userID]
+	if !exists {
+		entry = &heavyRequestSlots{slots: make(chan struct{}, limit)}
+		heavyRequests[userID] = entry
+	}
+	entry.holders++
+	heavyRequestsMu.Unlock()
+
+	done := func() {
+		heavyRequestsMu.Lock()
+		defer heavyRequestsMu.Unlock()
+		entry.holders--
+		if entry.holders == 0 {
+			delete(heavyRequests, userID)
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case entry.slots <- struct{}{}:
+		return func() {
+			<-entry.slots
+			done()
+		}, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	done()
+	return nil, false
+}
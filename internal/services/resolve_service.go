@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+)
+
+// entityResolver knows how to describe one kind of entity. The SQL expressions are evaluated
+// on the entity's table; empty ones are left out of the descriptor
+type entityResolver struct {
+	entityType string
+	model      interface{}
+	labelSQL   string
+	statusSQL  string
+	amountSQL  string
+	dateSQL    string
+	path       string // Canonical URL, the ID is appended
+}
+
+// entityResolvers are tried in order, the most commonly linked entities first
+var entityResolvers = []entityResolver{
+	{"expense", &models.Expense{}, "COALESCE(description, 'Expense')", "status", "amount", "date", "/api/v1/expenses/"},
+	{"income", &models.Income{}, "'Income'", "status", "amount", "date", "/api/v1/incomes/"},
+	{"transfer", &models.Transfer{}, "COALESCE(description, 'Transfer')", "status", "amount", "date", "/api/v1/transfers/"},
+	{"bank_account", &models.BankAccount{}, "account_name", "status", "balance", "", "/api/v1/bank-accounts/"},
+	{"category", &models.Category{}, "name", "status", "monthly_cap", "", "/api/v1/user-categories/"},
+	{"budget", &models.Budget{}, "'Budget ' || TO_CHAR(month_year, 'YYYY-MM')", "status", "needs_budget + wants_budget + savings_budget", "month_year", "/api/v1/budgets/"},
+	{"fixed_expense", &models.FixedExpense{}, "name", "status", "amount", "next_due_date", "/api/v1/fixed-expenses/"},
+	{"goal", &models.Goal{}, "name", "status", "total_amount", "", "/api/v1/goals/"},
+	{"reminder", &models.Reminder{}, "title", "status", "", "due_date", "/api/v1/reminders/"},
+	{"trip", &models.Trip{}, "name", "", "budget", "start_date", "/api/v1/trips/"},
+	{"account_group", &models.AccountGroup{}, "name", "", "", "", "/api/v1/account-groups/"},
+	{"import_batch", &models.ImportBatch{}, "COALESCE(source, 'Import')", "", "", "", "/api/v1/import/"},
+}
+
+// orNull stands in for the expressions an entity doesn't have
+func orNull(expression string) string {
+	if expression == "" {
+		return "NULL"
+	}
+	return expression
+}
+
+// ResolveEntity finds which of the user's entities a UUID belongs to, deleted ones included,
+// and returns a minimal descriptor with the canonical URL to load it
+func (s *Services) ResolveEntity(userID string, rawID string) (*dto.ResolvedEntity, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, errors.New("invalid ID: " + rawID)
+	}
+
+	for _, resolver := range entityResolvers {
+		var row struct {
+			Label  string
+			Status *string
+			Amount *models.Money
+			Date   *time.Time
+		}
+		result := s.db.Model(resolver.model).
+			Select(resolver.labelSQL+" AS label, "+orNull(resolver.statusSQL)+" AS status, "+
+				orNull(resolver.amountSQL)+" AS amount, "+orNull(resolver.dateSQL)+" AS date").
+			Where("id = ? AND user_id = ?", id, userID).Limit(1).Scan(&row)
+		if result.Error != nil {
+			logger.Error("Error resolving %s %s: %v", resolver.entityType, id, result.Error)
+			return nil, errors.New("error resolving ID")
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		entity := &dto.ResolvedEntity{
+			Type:   resolver.entityType,
+			ID:     id.String(),
+			Label:  row.Label,
+			Status: row.Status,
+			Amount: row.Amount,
+			URL:    resolver.path + id.String(),
+		}
+		if row.Date != nil {
+			date := row.Date.Format("2006-01-02")
+			entity.Date = &date
+		}
+		return entity, nil
+	}
+	return nil, errors.New("entity not found")
+}
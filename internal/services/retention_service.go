@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// retentionEntity describes a soft-deletable table the purge job can clean up
+type retentionEntity struct {
+	table string
+	// referencedBy lists "table.column" pairs that must not point at a purged row
+	referencedBy []string
+}
+
+// retentionEntities are the entity types a retention policy can be set for
+var retentionEntities = map[string]retentionEntity{
+	"expenses":       {table: "expenses", referencedBy: []string{"incomes.refund_of_expense_id"}},
+	"incomes":        {table: "incomes"},
+	"reminders":      {table: "reminders"},
+	"goals":          {table: "goals", referencedBy: []string{"goal_contributions.goal_id", "goal_milestones.goal_id"}},
+	"fixed_expenses": {table: "fixed_expenses"},
+	"bank_accounts": {table: "bank_accounts", referencedBy: []string{
+		"expenses.bank_account_id", "incomes.bank_account_id", "fixed_expenses.bank_account_id", "expense_allocations.bank_account_id",
+		"import_batches.bank_account_id",
+	}},
+	"categories": {table: "categories", referencedBy: []string{
+		"expenses.category_id", "fixed_expenses.category_id", "import_batches.category_id",
+	}},
+}
+
+// defaultRetentionEntities are the entity types the server-wide retention applies to when the
+// user hasn't set a policy of their own
+var defaultRetentionEntities = []string{"expenses", "incomes", "reminders"}
+
+// defaultRetentionDays is how long deleted records of the default entity types are kept unless
+// the user set a policy. Set DELETED_RETENTION_DAYS to change it; 0 keeps them forever
+func defaultRetentionDays() int {
+	return envInt("DELETED_RETENTION_DAYS", 90)
+}
+
+// RetentionPolicies maps an entity type to the days deleted records are kept (nil = forever)
+type RetentionPolicies map[string]*int
+
+// UpcomingPurge is a deleted record that will be permanently removed by the purge job
+type UpcomingPurge struct {
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+// IsValidRetentionEntity checks if a retention policy can be set for the entity type
+func IsValidRetentionEntity(entityType string) bool {
+	_, ok := retentionEntities[entityType]
+	return ok
+}
+
+// storedRetentionPolicies decodes the policies the user set; entity types never set are absent
+func storedRetentionPolicies(raw string) RetentionPolicies {
+	stored := RetentionPolicies{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			logger.Error("Error decoding retention policies: %v", err)
+		}
+	}
+	return stored
+}
+
+// decodeRetentionPolicies fills every known entity type. Those the user never set follow the
+// server-wide retention if it covers them, and otherwise keep deleted records forever
+func decodeRetentionPolicies(raw string) RetentionPolicies {
+	stored := storedRetentionPolicies(raw)
+
+	policies := make(RetentionPolicies, len(retentionEntities))
+	for entityType := range retentionEntities {
+		policies[entityType] = stored[entityType]
+	}
+	if days := defaultRetentionDays(); days > 0 {
+		for _, entityType := range defaultRetentionEntities {
+			if _, set := stored[entityType]; !set {
+				policies[entityType] = &days
+			}
+		}
+	}
+
+	return policies
+}
+
+// GetRetentionPolicies returns the effective retention policy of every entity type for the user
+func (s *Services) GetRetentionPolicies(userID string) (RetentionPolicies, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRetentionPolicies(preferences.RetentionPolicies), nil
+}
+
+// UpdateRetentionPolicies merges the given policies into the user's preferences.
+// A nil value keeps deleted records of the entity type forever, overriding the server default.
+func (s *Services) UpdateRetentionPolicies(userID string, updates RetentionPolicies) (RetentionPolicies, error) {
+	for entityType, days := range updates {
+		if !IsValidRetentionEntity(entityType) {
+			return nil, errors.New("invalid entity type: " + entityType)
+		}
+		if days != nil && *days < 1 {
+			return nil, errors.New("retention days must be at least 1")
+		}
+	}
+
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only what the user set is stored, so the rest keeps following the server default
+	stored := storedRetentionPolicies(preferences.RetentionPolicies)
+	for entityType, days := range updates {
+		stored[entityType] = days
+	}
+
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	preferences.RetentionPolicies = string(encoded)
+
+	if err := s.saveUserPreferences(preferences, "retention_policies"); err != nil {
+		logger.Error("Error saving retention policies: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Retention policies updated for user %s", userID)
+	return decodeRetentionPolicies(preferences.RetentionPolicies), nil
+}
+
+// purgeableQuery selects the deleted records of an entity type older than the cutoff
+func (s *Services) purgeableQuery(userID string, entity retentionEntity, cutoff time.Time) *gorm.DB {
+	query := s.db.Table(entity.table+" t").
+		Where("t.user_id = ? AND t.status = ? AND t.status_changed_at IS NOT NULL AND t.status_changed_at <= ?",
+			userID, models.StatusDeleted, cutoff)
+
+	// Records still referenced by other rows can't be removed without breaking history
+	for _, reference := range entity.referencedBy {
+		table, column, _ := strings.Cut(reference, ".")
+		query = query.Where("NOT EXISTS (SELECT 1 FROM " + table + " r WHERE r." + column + " = t.id)")
+	}
+
+	return query
+}
+
+// GetUpcomingPurges lists the deleted records that will be purged within the next days
+func (s *Services) GetUpcomingPurges(userID string, withinDays int) ([]UpcomingPurge, error) {
+	policies, err := s.GetRetentionPolicies(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	purges := make([]UpcomingPurge, 0)
+	for entityType, days := range policies {
+		if days == nil {
+			continue
+		}
+
+		// A record is purged once deleted for more than `days`, so anything deleted
+		// before now - days + withinDays is due within the window
+		cutoff := now.AddDate(0, 0, withinDays-*days)
+
+		var rows []struct {
+			ID              uuid.UUID
+			StatusChangedAt time.Time
+		}
+		result := s.purgeableQuery(userID, retentionEntities[entityType], cutoff).
+			Select("t.id, t.status_changed_at").Scan(&rows)
+		if result.Error != nil {
+			logger.Error("Error getting upcoming purges for %s: %v", entityType, result.Error)
+			return nil, result.Error
+		}
+
+		for _, row := range rows {
+			purges = append(purges, UpcomingPurge{
+				EntityType: entityType,
+				EntityID:   row.ID,
+				DeletedAt:  row.StatusChangedAt,
+				PurgeAt:    row.StatusChangedAt.AddDate(0, 0, *days),
+			})
+		}
+	}
+
+	sort.Slice(purges, func(i, j int) bool {
+		return purges[i].PurgeAt.Before(purges[j].PurgeAt)
+	})
+
+	return purges, nil
+}
+
+// purgeDeletedRecords permanently removes the deleted records of an entity type deleted up to
+// the cutoff, raising the sync purge watermark with them. The files of purged expense receipts
+// are removed once the rows are gone
+func (s *Services) purgeDeletedRecords(userID string, entityType string, cutoff time.Time) (int64, error) {
+	entity := retentionEntities[entityType]
+	var ids []uuid.UUID
+	if err := s.purgeableQuery(userID, entity, cutoff).Pluck("t.id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var attachments []models.ExpenseAttachment
+	if entity.table == "expenses" {
+		if err := s.db.Where("expense_id IN ?", ids).Find(&attachments).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	// Sync clients that haven't pulled these deletions yet can't learn about them anymore
+	var purged int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var through sql.NullTime
+		if err := tx.Table(entity.table).Where("id IN ?", ids).Select("MAX(updated_at)").Row().Scan(&through); err != nil {
+			return err
+		}
+		if !through.Valid {
+			through.Time = time.Now()
+		}
+		if err := recordSyncPurge(tx, uuid.MustParse(userID), entity.table, through.Time); err != nil {
+			return err
+		}
+		result := tx.Exec("DELETE FROM "+entity.table+" WHERE id IN ?", ids)
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	deleteAttachmentFiles(context.Background(), attachments)
+	return purged, nil
+}
+
+// PurgeExpiredDeletedRecords permanently removes soft-deleted records whose retention expired,
+// following each user's retention policies and the server default
+func (s *Services) PurgeExpiredDeletedRecords() error {
+	// Only users with something in the trash can have anything to purge
+	selects := make([]string, 0, len(retentionEntities))
+	for _, entity := range retentionEntities {
+		selects = append(selects, "SELECT user_id FROM "+entity.table+" WHERE status = '"+string(models.StatusDeleted)+"'")
+	}
+	var userIDs []uuid.UUID
+	if err := s.db.Table("("+strings.Join(selects, " UNION ")+") u").Pluck("user_id", &userIDs).Error; err != nil {
+		logger.Error("Error finding users with deleted records: %v", err)
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var preferences []models.UserPreferences
+	if err := s.db.Where("user_id IN ?", userIDs).Find(&preferences).Error; err != nil {
+		logger.Error("Error loading retention policies: %v", err)
+		return err
+	}
+	rawPolicies := make(map[uuid.UUID]string, len(preferences))
+	for _, preference := range preferences {
+		rawPolicies[preference.UserID] = preference.RetentionPolicies
+	}
+
+	now := time.Now()
+	var purged int64
+	for _, uid := range userIDs {
+		userID := uid.String()
+		for entityType, days := range decodeRetentionPolicies(rawPolicies[uid]) {
+			if days == nil {
+				continue
+			}
+
+			count, err := s.purgeDeletedRecords(userID, entityType, now.AddDate(0, 0, -*days))
+			if err != nil {
+				logger.Error("Error purging %s for user %s: %v", entityType, userID, err)
+				continue
+			}
+			purged += count
+		}
+	}
+
+	if purged > 0 {
+		logger.Info("Purged %d deleted records past their retention", purged)
+	}
+	return nil
+}
+
+// EmptyTrash permanently removes every deleted record of an entity type of the user, whatever
+// its retention. Records other rows still point at are kept
+func (s *Services) EmptyTrash(userID string, entityType string) (int64, error) {
+	if !IsValidRetentionEntity(entityType) {
+		return 0, errors.New("invalid entity type: " + entityType)
+	}
+
+	purged, err := s.purgeDeletedRecords(userID, entityType, time.Now())
+	if err != nil {
+		logger.Error("Error emptying the %s trash of user %s: %v", entityType, userID, err)
+		return 0, errors.New("error emptying trash")
+	}
+
+	uid := uuid.MustParse(userID)
+	s.RecordAudit(uid, "trash.emptied", entityType, nil, map[string]interface{}{"purged": purged})
+	logger.Info("Emptied the %s trash of user %s: %d records purged", entityType, userID, purged)
+	return purged, nil
+}
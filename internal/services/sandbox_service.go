@@ -0,0 +1,232 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxSandboxesPerUser limits how many sandbox tenants a real user can own
+	MaxSandboxesPerUser = 5
+	// MaxSandboxAdvance caps a single advance-time call
+	MaxSandboxAdvance = 366 * 24 * time.Hour
+	// maxSandboxProcessingRounds bounds the catch-up loop when a jump spans many periods
+	maxSandboxProcessingRounds = 400
+)
+
+var (
+	ErrNotSandbox           = errors.New("user is not a sandbox tenant")
+	ErrSandboxNested        = errors.New("sandbox tenants can't create sandboxes")
+	ErrSandboxLimitExceeded = errors.New("sandbox limit exceeded")
+)
+
+// sandboxClocks caches the clock offset of each user. AdvanceSandboxClock invalidates it on
+// every instance
+var sandboxClocks = newUserCache[time.Duration]()
+
+// UserNow returns the current time as seen by the user: the real time for regular users
+// and the virtual time for sandbox tenants, in the user's timezone
+func (s *Services) UserNow(userID string) time.Time {
+	return time.Now().Add(s.userClockOffset(userID)).In(s.UserLocation(userID))
+}
+
+func (s *Services) userClockOffset(userID string) time.Duration {
+	return sandboxClocks.get(userID, func() (time.Duration, bool) {
+		var user models.User
+		if err := s.db.Select("id", "clock_offset_seconds").Where("id = ?", userID).First(&user).Error; err != nil {
+			// Don't cache misses, the user may not exist yet
+			return 0, false
+		}
+		return time.Duration(user.ClockOffsetSeconds) * time.Second, true
+	})
+}
+
+func (s *Services) getSandboxUser(userID string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.IsSandbox {
+		return nil, ErrNotSandbox
+	}
+	return &user, nil
+}
+
+func sandboxClockOf(user *models.User) *dto.SandboxClock {
+	offset := time.Duration(user.ClockOffsetSeconds) * time.Second
+	return &dto.SandboxClock{
+		SandboxUserID: user.ID.String(),
+		RealNow:       time.Now().UTC(),
+		VirtualNow:    time.Now().Add(offset).UTC(),
+		OffsetSeconds: user.ClockOffsetSeconds,
+	}
+}
+
+// CreateSandbox creates a sandbox tenant owned by the user and an API key to act as it.
+// The tenant is a separate user row, so its data is isolated like any other user's
+func (s *Services) CreateSandbox(ownerID string, name string) (*dto.Sandbox, error) {
+	var owner models.User
+	if err := s.db.Where("id = ?", ownerID).First(&owner).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if owner.IsSandbox {
+		return nil, ErrSandboxNested
+	}
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("sandbox_owner_id = ? AND status = ?", owner.ID, models.StatusActive).Count(&count).Error; err != nil {
+		logger.Error("Error counting sandboxes: %v", err)
+		return nil, errors.New("error creating sandbox")
+	}
+	if count >= MaxSandboxesPerUser {
+		return nil, fmt.Errorf("%w: at most %d sandboxes per user", ErrSandboxLimitExceeded, MaxSandboxesPerUser)
+	}
+
+	if name == "" {
+		name = "Sandbox"
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		logger.Error("Error generating sandbox password: %v", err)
+		return nil, errors.New("error creating sandbox")
+	}
+	// Sandboxes are only reachable through their API key, nobody knows this password
+	password, err := HashPassword(hex.EncodeToString(random))
+	if err != nil {
+		return nil, errors.New("error creating sandbox")
+	}
+
+	sandboxID := uuid.New()
+	sandbox := models.User{
+		ID:             sandboxID,
+		Email:          fmt.Sprintf("sandbox+%s@sandbox.invalid", sandboxID),
+		Password:       password,
+		Name:           name,
+		Status:         models.StatusActive,
+		IsSandbox:      true,
+		SandboxOwnerID: &owner.ID,
+		// Test traffic shouldn't skew product analytics
+		AnalyticsOptOut: true,
+	}
+	if err := s.db.Create(&sandbox).Error; err != nil {
+		logger.Error("Error creating sandbox user: %v", err)
+		return nil, errors.New("error creating sandbox")
+	}
+
+	_, raw, err := s.CreateAPIKey(sandboxID.String(), "Sandbox key", models.APIKeyScopeFull)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RecordAudit(owner.ID, "sandbox.created", "user", &sandbox.ID, map[string]interface{}{"name": name})
+	return &dto.Sandbox{
+		SandboxUserID: sandboxID.String(),
+		Name:          sandbox.Name,
+		APIKey:        raw,
+		CreatedAt:     sandbox.CreatedAt,
+	}, nil
+}
+
+// GetSandboxes lists the active sandbox tenants owned by the user
+func (s *Services) GetSandboxes(ownerID string) ([]dto.Sandbox, error) {
+	var users []models.User
+	if err := s.db.Where("sandbox_owner_id = ? AND status = ?", ownerID, models.StatusActive).
+		Order("created_at DESC").Find(&users).Error; err != nil {
+		logger.Error("Error getting sandboxes: %v", err)
+		return nil, errors.New("error getting sandboxes")
+	}
+
+	sandboxes := make([]dto.Sandbox, 0, len(users))
+	for _, user := range users {
+		sandboxes = append(sandboxes, dto.Sandbox{
+			SandboxUserID: user.ID.String(),
+			Name:          user.Name,
+			OffsetSeconds: user.ClockOffsetSeconds,
+			CreatedAt:     user.CreatedAt,
+		})
+	}
+	return sandboxes, nil
+}
+
+// GetSandboxClock returns the virtual clock of a sandbox tenant
+func (s *Services) GetSandboxClock(userID string) (*dto.SandboxClock, error) {
+	user, err := s.getSandboxUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return sandboxClockOf(user), nil
+}
+
+// AdvanceSandboxClock moves the virtual clock of a sandbox tenant forward and runs the
+// scheduler-driven work that became due, as the background jobs would have
+func (s *Services) AdvanceSandboxClock(userID string, by time.Duration) (*dto.SandboxAdvance, error) {
+	if by <= 0 {
+		return nil, errors.New("advance must be positive")
+	}
+	if by > MaxSandboxAdvance {
+		return nil, errors.New("advance must be at most 366 days")
+	}
+
+	user, err := s.getSandboxUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds := int64(by / time.Second)
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Clauses(clause.Returning{Columns: []clause.Column{{Name: "clock_offset_seconds"}}}).
+			Updates(map[string]interface{}{
+				"clock_offset_seconds": gorm.Expr("clock_offset_seconds + ?", seconds),
+				"updated_at":           time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return invalidateUserCache(tx, "sandbox_clock", userID)
+	}); err != nil {
+		logger.Error("Error advancing sandbox clock: %v", err)
+		return nil, errors.New("error advancing sandbox clock")
+	}
+
+	clock := sandboxClockOf(user)
+	result := &dto.SandboxAdvance{SandboxClock: *clock}
+
+	// Each pass posts one period per fixed expense, keep going until the schedule catches up
+	for round := 0; round < maxSandboxProcessingRounds; round++ {
+		processed, err := s.processDueFixedExpenses(userID, clock.VirtualNow)
+		if err != nil {
+			return nil, errors.New("error processing fixed expenses")
+		}
+		if processed == 0 {
+			break
+		}
+		result.FixedExpensesPosted += processed
+	}
+
+	reminders := s.Reminders
+	overdue, err := reminders.GetOverdueReminders(user.ID)
+	if err != nil {
+		return nil, errors.New("error getting reminders")
+	}
+	upcoming, err := reminders.GetUpcomingReminders(user.ID, 7)
+	if err != nil {
+		return nil, errors.New("error getting reminders")
+	}
+	result.OverdueReminders = len(overdue)
+	result.UpcomingReminders = len(upcoming)
+
+	s.RecordAudit(user.ID, "sandbox.clock_advanced", "user", &user.ID, map[string]interface{}{
+		"advanced_seconds": seconds,
+		"offset_seconds":   user.ClockOffsetSeconds,
+	})
+	return result, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"github.com/Osminalx/fluxio/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Services is the service layer working on one database. The server builds it once on the
+// database it connects to, and tests build one per test schema
+type Services struct {
+	db *gorm.DB
+
+	Expenses  *ExpenseService
+	Budgets   *BudgetService
+	Reminders *ReminderService
+}
+
+// New returns the services working on the database
+func New(database *gorm.DB) *Services {
+	s := &Services{db: database}
+	s.Expenses = NewExpenseService(s, repository.NewExpenseRepository(database))
+	s.Budgets = NewBudgetService(database, repository.NewBudgetRepository(database))
+	s.Reminders = &ReminderService{db: database, services: s}
+	return s
+}
+
+// DB returns the database the services work on
+func (s *Services) DB() *gorm.DB {
+	return s.db
+}
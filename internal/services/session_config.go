@@ -0,0 +1,36 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// SessionConfig holds the token lifetimes used for sliding sessions
+type SessionConfig struct {
+	AccessTokenTTL     time.Duration // Lifetime of an access token
+	AccessRenewWindow  time.Duration // Remaining lifetime below which the access token is renewed
+	SlidingEnabled     bool          // Whether access tokens are renewed transparently
+	RefreshTokenTTL    time.Duration // Lifetime granted on each refresh token use
+	RefreshTokenMaxAge time.Duration // Absolute maximum lifetime of a refresh token chain
+}
+
+// GetSessionConfig reads the session configuration from the environment, falling back to defaults
+func GetSessionConfig() SessionConfig {
+	return SessionConfig{
+		AccessTokenTTL:     time.Duration(envInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
+		AccessRenewWindow:  time.Duration(envInt("ACCESS_TOKEN_RENEW_WINDOW_SECONDS", 120)) * time.Second,
+		SlidingEnabled:     os.Getenv("SLIDING_SESSIONS_ENABLED") != "false",
+		RefreshTokenTTL:    time.Duration(envInt("REFRESH_TOKEN_TTL_DAYS", 7)) * 24 * time.Hour,
+		RefreshTokenMaxAge: time.Duration(envInt("REFRESH_TOKEN_MAX_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// envInt reads a positive integer environment variable or returns the default
+func envInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
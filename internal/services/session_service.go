@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventSecurityRefreshTokenReuse is emitted when a rotated refresh token is replayed and its
+// session revoked
+const EventSecurityRefreshTokenReuse = "security.refresh_token_reuse"
+
+// sessionStateTTL is how long the state of a session is trusted before checking the database
+// again. Revocations made by this instance apply at once, other instances see them within it
+const sessionStateTTL = 30 * time.Second
+
+type sessionState struct {
+	active    bool
+	userID    uuid.UUID
+	checkedAt time.Time
+}
+
+// sessionStates is the revocation list access tokens are checked against
+var sessionStates = struct {
+	sync.Mutex
+	entries map[string]sessionState
+}{entries: make(map[string]sessionState)}
+
+// tokenFamilyID returns the session of a refresh token. Tokens issued before families existed
+// are a session of their own
+func tokenFamilyID(token *models.RefreshToken) uuid.UUID {
+	if token.FamilyID == uuid.Nil {
+		return token.ID
+	}
+	return token.FamilyID
+}
+
+// IsSessionActive reports whether the session an access token was issued for still has a live
+// refresh token. Unknown sessions and database errors count as revoked
+func (s *Services) IsSessionActive(sessionID string) bool {
+	sessionStates.Lock()
+	state, ok := sessionStates.entries[sessionID]
+	sessionStates.Unlock()
+	if ok && time.Since(state.checkedAt) < sessionStateTTL {
+		return state.active
+	}
+
+	familyID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false
+	}
+	var live models.RefreshToken
+	result := s.db.Select("id", "user_id").Where("family_id = ? AND is_revoked = ? AND expires_at > ?", familyID, false, time.Now()).
+		Limit(1).Find(&live)
+	if result.Error != nil {
+		logger.Error("Error checking session %s: %v", sessionID, result.Error)
+		return false
+	}
+
+	sessionStates.Lock()
+	defer sessionStates.Unlock()
+	if len(sessionStates.entries) > 10000 {
+		for id, entry := range sessionStates.entries {
+			if time.Since(entry.checkedAt) >= sessionStateTTL {
+				delete(sessionStates.entries, id)
+			}
+		}
+	}
+	sessionStates.entries[sessionID] = sessionState{active: result.RowsAffected > 0, userID: live.UserID, checkedAt: time.Now()}
+	return result.RowsAffected > 0
+}
+
+// forgetSessionState marks a session revoked for this instance's access token checks
+func forgetSessionState(sessionID string) {
+	sessionStates.Lock()
+	defer sessionStates.Unlock()
+	sessionStates.entries[sessionID] = sessionState{active: false, checkedAt: time.Now()}
+}
+
+// forgetUserSessionStates marks every cached session of a user revoked
+func forgetUserSessionStates(userID uuid.UUID) {
+	sessionStates.Lock()
+	defer sessionStates.Unlock()
+	for id, entry := range sessionStates.entries {
+		if entry.userID == userID {
+			sessionStates.entries[id] = sessionState{active: false, userID: userID, checkedAt: time.Now()}
+		}
+	}
+}
+
+// revokeRefreshTokenFamily revokes the live tokens of a session using the caller's transaction
+func revokeRefreshTokenFamily(tx *gorm.DB, userID uuid.UUID, familyID uuid.UUID, reason string) (int64, error) {
+	result := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND (family_id = ? OR id = ?) AND is_revoked = ?", userID, familyID, familyID, false).
+		Updates(map[string]interface{}{
+			"is_revoked":     true,
+			"revoked_reason": reason,
+			"updated_at":     time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// revokeReusedRefreshTokenFamily revokes the session of a replayed token, records the security
+// event and emits the notification event in the caller's transaction
+func revokeReusedRefreshTokenFamily(tx *gorm.DB, token *models.RefreshToken, login LoginContext) error {
+	familyID := tokenFamilyID(token)
+	if _, err := revokeRefreshTokenFamily(tx, token.UserID, familyID, models.RefreshTokenRevokedReuse); err != nil {
+		return err
+	}
+
+	event, err := recordSecurityEvent(tx, token.UserID, models.SecurityEventTokenReuse, login, nil)
+	if err != nil {
+		return err
+	}
+	return EnqueueEvent(tx, token.UserID, EventSecurityRefreshTokenReuse, "security_event", event.ID, map[string]interface{}{
+		"session_id":         familyID,
+		"session_user_agent": token.UserAgent,
+		"ip_address":         event.IPAddress,
+		"country":            event.Country,
+		"user_agent":         event.UserAgent,
+		"at":                 event.CreatedAt,
+	})
+}
+
+// GetUserSessions lists the active sessions of the user, most recently used first.
+// currentSessionID marks the session of the request, if any
+func (s *Services) GetUserSessions(userID string, currentSessionID string) ([]dto.Session, error) {
+	var live []models.RefreshToken
+	if err := s.db.Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Find(&live).Error; err != nil {
+		logger.Error("Error getting sessions: %v", err)
+		return nil, errors.New("error getting sessions")
+	}
+
+	familyIDs := make([]uuid.UUID, 0, len(live))
+	for i := range live {
+		familyIDs = append(familyIDs, tokenFamilyID(&live[i]))
+	}
+
+	// A session started with the first token of its family
+	var starts []struct {
+		FamilyID  uuid.UUID
+		StartedAt time.Time
+	}
+	if len(familyIDs) > 0 {
+		if err := s.db.Model(&models.RefreshToken{}).Select("family_id, MIN(created_at) AS started_at").
+			Where("family_id IN ?", familyIDs).Group("family_id").Scan(&starts).Error; err != nil {
+			logger.Error("Error getting session starts: %v", err)
+			return nil, errors.New("error getting sessions")
+		}
+	}
+	startedAt := make(map[uuid.UUID]time.Time, len(starts))
+	for _, start := range starts {
+		startedAt[start.FamilyID] = start.StartedAt
+	}
+
+	sessions := make([]dto.Session, 0, len(live))
+	for i := range live {
+		token := &live[i]
+		familyID := tokenFamilyID(token)
+		created, ok := startedAt[familyID]
+		if !ok {
+			created = token.CreatedAt
+		}
+		sessions = append(sessions, dto.Session{
+			ID:         familyID.String(),
+			IPAddress:  token.IPAddress,
+			UserAgent:  token.UserAgent,
+			DeviceID:   deviceID(token.UserAgent),
+			CreatedAt:  created,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    familyID.String() == currentSessionID,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+
+	return sessions, nil
+}
+
+// RevokeUserSession signs a device out: its refresh token stops working and the access tokens
+// issued for the session are rejected
+func (s *Services) RevokeUserSession(userID string, sessionID string) error {
+	familyID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return errors.New("invalid session ID")
+	}
+	owner, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	revoked, err := revokeRefreshTokenFamily(s.db, owner, familyID, models.RefreshTokenRevokedSession)
+	if err != nil {
+		logger.Error("Error revoking session: %v", err)
+		return errors.New("error revoking session")
+	}
+	if revoked == 0 {
+		return errors.New("session not found")
+	}
+
+	forgetSessionState(familyID.String())
+	logger.Info("Session %s of user %s revoked", familyID, owner)
+	return nil
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// Budgeting modes: how a suggested budget splits the monthly income between needs, wants and
+// savings, or whether it follows recent spending instead
+const (
+	BudgetingMode503020         = "50_30_20"
+	BudgetingMode702010         = "70_20_10"
+	BudgetingMode602020         = "60_20_20"
+	BudgetingModeRecentSpending = "recent_spending"
+)
+
+// budgetingModeShares are the needs/wants/savings percentages of the income split modes
+var budgetingModeShares = map[string][]float64{
+	BudgetingMode503020: {50, 30, 20},
+	BudgetingMode702010: {70, 20, 10},
+	BudgetingMode602020: {60, 20, 20},
+}
+
+// IsValidBudgetingMode checks if a given string is a valid budgeting mode
+func IsValidBudgetingMode(mode string) bool {
+	_, ok := budgetingModeShares[mode]
+	return ok || mode == BudgetingModeRecentSpending
+}
+
+const (
+	defaultFirstDayOfWeek = "monday"
+	defaultDateFormat     = "YYYY-MM-DD"
+)
+
+// dateFormats are the date formats clients can show dates in
+var dateFormats = map[string]bool{
+	"YYYY-MM-DD": true,
+	"DD/MM/YYYY": true,
+	"MM/DD/YYYY": true,
+	"DD.MM.YYYY": true,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// userCalendar is where the user's weeks and months start
+type userCalendar struct {
+	WeekStart     time.Weekday
+	MonthStartDay int // 1-28
+}
+
+// defaultCalendar has weeks starting on Monday and months on the 1st
+var defaultCalendar = userCalendar{WeekStart: time.Monday, MonthStartDay: 1}
+
+func calendarOf(preferences *models.UserPreferences) userCalendar {
+	calendar := defaultCalendar
+	if weekday, ok := weekdays[preferences.FirstDayOfWeek]; ok {
+		calendar.WeekStart = weekday
+	}
+	if preferences.FirstDayOfMonth >= 1 && preferences.FirstDayOfMonth <= 28 {
+		calendar.MonthStartDay = preferences.FirstDayOfMonth
+	}
+	return calendar
+}
+
+// getUserCalendar returns where the user's weeks and months start, the defaults if unknown
+func (s *Services) getUserCalendar(userID string) userCalendar {
+	return loadUserCalendar(s.db, userID)
+}
+
+// loadUserCalendar is getUserCalendar reading through db, e.g. within a transaction
+func loadUserCalendar(db *gorm.DB, userID string) userCalendar {
+	var preferences models.UserPreferences
+	if err := db.Select("user_id", "first_day_of_week", "first_day_of_month").
+		Where("user_id = ?", userID).Take(&preferences).Error; err != nil {
+		return defaultCalendar
+	}
+	return calendarOf(&preferences)
+}
+
+// weekStart returns the first day of the week containing day
+func (c userCalendar) weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(c.WeekStart) + 7) % 7))
+}
+
+// monthStart returns the first day of the month period containing day, which starts on
+// MonthStartDay and runs until the day before it in the next calendar month
+func (c userCalendar) monthStart(day time.Time) time.Time {
+	start := time.Date(day.Year(), day.Month(), c.MonthStartDay, 0, 0, 0, 0, time.UTC)
+	if day.Day() < c.MonthStartDay {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// monthPeriod returns the first and last day of the month period labelled year and month: it
+// starts on MonthStartDay of that calendar month
+func (c userCalendar) monthPeriod(year int, month time.Month) (start, end time.Time) {
+	start = time.Date(year, month, c.MonthStartDay, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, -1)
+}
+
+// currentMonth returns the label, the first of the calendar month, of the month period
+// containing day
+func (c userCalendar) currentMonth(day time.Time) time.Time {
+	return models.MonthStart(c.monthStart(day))
+}
+
+// UserSettings are the preferences of the user that shape how their data is computed and shown
+type UserSettings struct {
+	Currency            string                `json:"currency"`              // ISO 4217 code amounts are rounded and labelled in
+	Timezone            string                `json:"timezone"`              // IANA name; days and months follow it
+	FirstDayOfWeek      string                `json:"first_day_of_week"`     // monday, sunday...
+	FirstDayOfMonth     int                   `json:"first_day_of_month"`    // 1-28
+	BudgetingMode       string                `json:"budgeting_mode"`        // 50_30_20, 70_20_10, 60_20_20 or recent_spending
+	DateFormat          string                `json:"date_format"`           // YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY or DD.MM.YYYY
+	BudgetReviewCadence string                `json:"budget_review_cadence"` // weekly, monthly or empty
+	Notifications       *NotificationSettings `json:"notifications"`
+}
+
+// UserSettingsUpdate holds the settings to change; nil fields are left as they are
+type UserSettingsUpdate struct {
+	Currency            *string
+	Timezone            *string
+	FirstDayOfWeek      *string
+	FirstDayOfMonth     *int
+	BudgetingMode       *string
+	DateFormat          *string
+	BudgetReviewCadence *string
+	Notifications       *NotificationSettings
+}
+
+// GetUserSettings returns the settings of the user, with the defaults for those never set
+func (s *Services) GetUserSettings(userID string) (*UserSettings, error) {
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error getting settings")
+	}
+	notifications, err := s.GetNotificationSettings(userID)
+	if err != nil {
+		return nil, errors.New("error getting settings")
+	}
+
+	settings := &UserSettings{
+		Currency:            s.GetUserCurrency(userID).Code,
+		Timezone:            s.UserLocation(userID).String(),
+		FirstDayOfWeek:      strings.ToLower(calendarOf(preferences).WeekStart.String()),
+		FirstDayOfMonth:     calendarOf(preferences).MonthStartDay,
+		BudgetingMode:       preferences.BudgetingMode,
+		DateFormat:          preferences.DateFormat,
+		BudgetReviewCadence: preferences.BudgetReviewCadence,
+		Notifications:       notifications,
+	}
+	if !IsValidBudgetingMode(settings.BudgetingMode) {
+		settings.BudgetingMode = BudgetingMode503020
+	}
+	if !dateFormats[settings.DateFormat] {
+		settings.DateFormat = defaultDateFormat
+	}
+	return settings, nil
+}
+
+// UpdateUserSettings changes the given settings. Everything is validated first and then saved
+// in one transaction, so an invalid field or a failed write leaves all of them as they were
+func (s *Services) UpdateUserSettings(userID string, update UserSettingsUpdate) (*UserSettings, error) {
+	var currency *models.Currency
+	if update.Currency != nil {
+		var err error
+		if currency, err = s.GetCurrency(*update.Currency); err != nil {
+			return nil, err
+		}
+	}
+	var timezone string
+	if update.Timezone != nil {
+		var err error
+		if timezone, err = validTimezone(*update.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if update.FirstDayOfWeek != nil {
+		if _, ok := weekdays[strings.ToLower(*update.FirstDayOfWeek)]; !ok {
+			return nil, errors.New("invalid first_day_of_week: use a weekday name such as monday or sunday")
+		}
+	}
+	if update.FirstDayOfMonth != nil && (*update.FirstDayOfMonth < 1 || *update.FirstDayOfMonth > 28) {
+		return nil, errors.New("invalid first_day_of_month: must be between 1 and 28")
+	}
+	if update.BudgetingMode != nil && !IsValidBudgetingMode(*update.BudgetingMode) {
+		return nil, errors.New("invalid budgeting_mode: must be 50_30_20, 70_20_10, 60_20_20 or recent_spending")
+	}
+	if update.DateFormat != nil && !dateFormats[*update.DateFormat] {
+		return nil, errors.New("invalid date_format: must be YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY or DD.MM.YYYY")
+	}
+	if update.BudgetReviewCadence != nil && !IsValidBudgetReviewCadence(*update.BudgetReviewCadence) {
+		return nil, errors.New("invalid budget_review_cadence: must be weekly, monthly or empty")
+	}
+	if update.Notifications != nil {
+		if err := update.Notifications.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if currency != nil {
+			if err := storeUserCurrency(tx, userID, currency); err != nil {
+				return err
+			}
+		}
+
+		preferences, err := loadUserPreferences(tx, userID)
+		if err != nil {
+			return err
+		}
+		var columns []string
+		if update.Timezone != nil {
+			preferences.Timezone = timezone
+			columns = append(columns, "timezone")
+		}
+		if update.Notifications != nil {
+			if err := setNotificationSettings(preferences, update.Notifications); err != nil {
+				return err
+			}
+			columns = append(columns, "notification_settings")
+		}
+		if update.FirstDayOfWeek != nil {
+			preferences.FirstDayOfWeek = strings.ToLower(*update.FirstDayOfWeek)
+			columns = append(columns, "first_day_of_week")
+		}
+		if update.FirstDayOfMonth != nil {
+			preferences.FirstDayOfMonth = *update.FirstDayOfMonth
+			columns = append(columns, "first_day_of_month")
+		}
+		if update.BudgetingMode != nil {
+			preferences.BudgetingMode = *update.BudgetingMode
+			columns = append(columns, "budgeting_mode")
+		}
+		if update.DateFormat != nil {
+			preferences.DateFormat = *update.DateFormat
+			columns = append(columns, "date_format")
+		}
+		if update.BudgetReviewCadence != nil {
+			preferences.BudgetReviewCadence = *update.BudgetReviewCadence
+			columns = append(columns, "budget_review_cadence")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		if err := storeUserPreferences(tx, preferences, columns...); err != nil {
+			return err
+		}
+
+		if update.Timezone != nil {
+			if err := invalidateUserCache(tx, "timezone", userID); err != nil {
+				return err
+			}
+		}
+		// Cached summaries cover the periods of the user's calendar and timezone
+		if update.Timezone != nil || update.FirstDayOfWeek != nil || update.FirstDayOfMonth != nil {
+			db.AfterCommit(tx, func() { InvalidateSummaryCache(userID) })
+		}
+		return nil
+	})
+	if errors.Is(err, errUserNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Error saving settings: %v", err)
+		return nil, errors.New("error updating settings")
+	}
+
+	logger.Info("Settings updated for user %s", userID)
+	return s.GetUserSettings(userID)
+}
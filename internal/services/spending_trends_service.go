@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// MaxTrendMonths bounds how far back a spending trends report goes
+const MaxTrendMonths = 60
+
+// TrendMovingAverageWindow is how many months the moving average of a trend line spans
+const TrendMovingAverageWindow = 3
+
+// trendGroupings are the dimensions spending trends can be split by
+var trendGroupings = map[string]summaryGrouping{
+	"category": summaryGroupings[SummaryGroupByCategory],
+	"account":  summaryGroupings[SummaryGroupByAccount],
+	"type": {
+		joins:   []string{"JOIN categories c ON e.category_id = c.id"},
+		key:     "c.expense_type::text",
+		name:    expenseTypeNameSQL,
+		groupBy: "c.expense_type",
+	},
+}
+
+// spendingTrendLine fills in the moving average and month-over-month change of monthly values,
+// rounding the averages in the currency
+func spendingTrendLine(key, name string, values []models.Money, currency *models.Currency) dto.SpendingTrendLine {
+	line := dto.SpendingTrendLine{
+		Key:           key,
+		Name:          name,
+		Values:        values,
+		MovingAverage: make([]models.Money, len(values)),
+		Change:        make([]models.Money, len(values)),
+		ChangePercent: make([]*float64, len(values)),
+	}
+	var window models.Money
+	for i, value := range values {
+		line.Total += value
+		window += value
+		if i >= TrendMovingAverageWindow {
+			window -= values[i-TrendMovingAverageWindow]
+		}
+		line.MovingAverage[i] = currency.RoundMoney(window.MulRatio(1 / float64(min(i+1, TrendMovingAverageWindow))))
+		if i == 0 {
+			continue
+		}
+		line.Change[i] = value - values[i-1]
+		if values[i-1] != 0 {
+			percent := math.Round(line.Change[i].Ratio(values[i-1].Abs())*10000) / 100
+			line.ChangePercent[i] = &percent
+		}
+	}
+	if len(values) > 0 {
+		line.Average = currency.RoundMoney(line.Total.MulRatio(1 / float64(len(values))))
+	}
+	return line
+}
+
+// GetSpendingTrends returns the spend of each of the last months, the current one included, net
+// of refunds and with a zero for months without spending. groupBy splits it by category, account
+// or expense type, one line per group
+func (s *Services) GetSpendingTrends(ctx context.Context, userID string, months int, groupBy string) (*dto.SpendingTrends, error) {
+	if months < 1 || months > MaxTrendMonths {
+		return nil, fmt.Errorf("invalid months: use 1 to %d", MaxTrendMonths)
+	}
+	grouping, grouped := trendGroupings[groupBy]
+	if groupBy != "" && !grouped {
+		return nil, errors.New("invalid group_by: use category, type or account")
+	}
+
+	end := s.UserToday(userID)
+	start := models.MonthStart(end).AddDate(0, -(months - 1), 0)
+	trends := &dto.SpendingTrends{
+		Months:              make([]string, months),
+		GroupBy:             groupBy,
+		MovingAverageWindow: TrendMovingAverageWindow,
+		Series:              []dto.SpendingTrendLine{},
+	}
+	monthIndex := make(map[string]int, months)
+	for i := range trends.Months {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		trends.Months[i] = month
+		monthIndex[month] = i
+	}
+
+	totals, err := s.spendSeriesRows(ctx, userID, start, end, "")
+	var rows []seriesRow
+	if err == nil && grouped {
+		rows, err = s.groupedSpendRows(ctx, userID, start, end, grouping)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Error("Error getting spending trends: %v", err)
+		return nil, errors.New("error calculating spending trends")
+	}
+
+	currency := s.GetUserCurrency(userID)
+	trends.Currency = currency.Code
+	binned := func(rows []seriesRow, values []models.Money) {
+		for _, row := range rows {
+			if i, ok := monthIndex[row.Date.UTC().Format("2006-01")]; ok {
+				values[i] += row.Amount
+			}
+		}
+		for i := range values {
+			values[i] = currency.RoundMoney(values[i])
+		}
+	}
+
+	totalValues := make([]models.Money, months)
+	binned(totals, totalValues)
+	trends.Total = spendingTrendLine("total", "Total", totalValues, currency)
+
+	groups := make(map[string][]seriesRow)
+	names := make(map[string]string)
+	for _, row := range rows {
+		groups[row.Key] = append(groups[row.Key], row)
+		names[row.Key] = row.Name
+	}
+	for key, groupRows := range groups {
+		values := make([]models.Money, months)
+		binned(groupRows, values)
+		trends.Series = append(trends.Series, spendingTrendLine(key, names[key], values, currency))
+	}
+	// Biggest groups first, so a chart can keep the first few
+	sort.Slice(trends.Series, func(i, j int) bool {
+		if trends.Series[i].Total != trends.Series[j].Total {
+			return trends.Series[i].Total > trends.Series[j].Total
+		}
+		return trends.Series[i].Name < trends.Series[j].Name
+	})
+
+	return trends, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Trend directions of a velocity window
+const (
+	VelocityTrendUp   = "up"
+	VelocityTrendDown = "down"
+	VelocityTrendFlat = "flat"
+)
+
+// velocityWindows are the rolling windows reported, in days
+var velocityWindows = []int{7, 14, 30}
+
+// velocityTrendThresholdPercent is how much the rate must change to count as a trend
+const velocityTrendThresholdPercent = 10.0
+
+// velocitySpend accumulates what was spent in each window and in the window before it
+type velocitySpend struct {
+	current  []models.Money
+	previous []models.Money
+}
+
+func newVelocitySpend() *velocitySpend {
+	return &velocitySpend{
+		current:  make([]models.Money, len(velocityWindows)),
+		previous: make([]models.Money, len(velocityWindows)),
+	}
+}
+
+// add counts an amount spent daysAgo days before the reference day
+func (v *velocitySpend) add(daysAgo int, amount models.Money) {
+	for i, days := range velocityWindows {
+		switch {
+		case daysAgo < days:
+			v.current[i] += amount
+		case daysAgo < 2*days:
+			v.previous[i] += amount
+		}
+	}
+}
+
+func (v *velocitySpend) windows(currency *models.Currency) []dto.VelocityWindow {
+	windows := make([]dto.VelocityWindow, len(velocityWindows))
+	for i, days := range velocityWindows {
+		window := dto.VelocityWindow{
+			Days:              days,
+			Spent:             currency.RoundMoney(v.current[i]),
+			DailyRate:         currency.RoundMoney(v.current[i].MulRatio(1 / float64(days))),
+			PreviousSpent:     currency.RoundMoney(v.previous[i]),
+			PreviousDailyRate: currency.RoundMoney(v.previous[i].MulRatio(1 / float64(days))),
+			Trend:             VelocityTrendFlat,
+		}
+		if window.PreviousSpent > 0 {
+			change := math.Round((window.Spent-window.PreviousSpent).Ratio(window.PreviousSpent)*10000) / 100
+			window.ChangePercent = &change
+			if change >= velocityTrendThresholdPercent {
+				window.Trend = VelocityTrendUp
+			} else if change <= -velocityTrendThresholdPercent {
+				window.Trend = VelocityTrendDown
+			}
+		} else if window.Spent > 0 {
+			window.Trend = VelocityTrendUp
+		}
+		windows[i] = window
+	}
+	return windows
+}
+
+// GetSpendingVelocity returns the 7, 14 and 30 day spend rates of the user up to today, overall,
+// per bucket and per category, each compared with the same number of days just before
+func (s *Services) GetSpendingVelocity(userID string) (*dto.SpendingVelocity, error) {
+	currency := s.GetUserCurrency(userID)
+	today := s.UserToday(userID)
+	longest := velocityWindows[len(velocityWindows)-1]
+	startDate := today.AddDate(0, 0, -2*longest+1)
+
+	// One row per day and category is all the windows need
+	var rows []struct {
+		Date        time.Time
+		CategoryID  string
+		Name        string
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+	}
+	result := s.summaryPeriodQuery(userID, startDate, today).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Select("e.date, c.id::text as category_id, c.name, c.expense_type, COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) as amount").
+		Group("e.date, c.id, c.name, c.expense_type").
+		Scan(&rows)
+	if result.Error != nil {
+		logger.Error("Error calculating spending velocity: %v", result.Error)
+		return nil, errors.New("error calculating spending velocity")
+	}
+
+	total := newVelocitySpend()
+	buckets := make(map[models.ExpenseType]*velocitySpend)
+	for _, expenseType := range models.ValidExpenseTypes() {
+		buckets[expenseType] = newVelocitySpend()
+	}
+	categories := make(map[string]*velocitySpend)
+	categoryNames := make(map[string]string)
+
+	for _, row := range rows {
+		daysAgo := int(today.Sub(row.Date.UTC().Truncate(24*time.Hour)).Hours() / 24)
+		if daysAgo < 0 {
+			continue
+		}
+		total.add(daysAgo, row.Amount)
+		if bucket, ok := buckets[row.ExpenseType]; ok {
+			bucket.add(daysAgo, row.Amount)
+		}
+		if _, ok := categories[row.CategoryID]; !ok {
+			categories[row.CategoryID] = newVelocitySpend()
+			categoryNames[row.CategoryID] = row.Name
+		}
+		categories[row.CategoryID].add(daysAgo, row.Amount)
+	}
+
+	velocity := &dto.SpendingVelocity{
+		AsOf:       today.Format("2006-01-02"),
+		Currency:   currency.Code,
+		Total:      total.windows(currency),
+		Buckets:    make([]dto.VelocityGroup, 0, len(buckets)),
+		Categories: make([]dto.VelocityGroup, 0, len(categories)),
+	}
+	for _, expenseType := range models.ValidExpenseTypes() {
+		velocity.Buckets = append(velocity.Buckets, dto.VelocityGroup{
+			ID:      string(expenseType),
+			Name:    models.GetExpenseTypeName(expenseType),
+			Windows: buckets[expenseType].windows(currency),
+		})
+	}
+	for id, spend := range categories {
+		velocity.Categories = append(velocity.Categories, dto.VelocityGroup{
+			ID:      id,
+			Name:    categoryNames[id],
+			Windows: spend.windows(currency),
+		})
+	}
+
+	// Biggest spenders of the longest window first
+	last := len(velocityWindows) - 1
+	sort.Slice(velocity.Categories, func(i, j int) bool {
+		a, b := velocity.Categories[i].Windows[last], velocity.Categories[j].Windows[last]
+		if a.Spent != b.Spent {
+			return a.Spent > b.Spent
+		}
+		return velocity.Categories[i].Name < velocity.Categories[j].Name
+	})
+
+	return velocity, nil
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Osminalx/fluxio/internal/models"
+)
+
+// checkStatusTransition rejects status changes the entity's status machine doesn't allow
+func checkStatusTransition(machine *models.StatusMachine, from, to models.Status) (models.StatusTransition, error) {
+	transition, ok := machine.Transition(from, to)
+	if !ok {
+		return transition, fmt.Errorf("invalid status transition for %s: %s to %s", machine.Entity, from, to)
+	}
+	return transition, nil
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxSubProfilesPerUser limits how many sub-profiles a parent can have
+const MaxSubProfilesPerUser = 10
+
+// Events about expenses held for approval
+const (
+	EventExpenseApprovalRequested = "expense_approval.requested" // Sent to the parent
+	EventExpenseApprovalDecided   = "expense_approval.decided"   // Sent to the sub-profile
+)
+
+var (
+	ErrSubProfileNested        = errors.New("sub-profiles and sandbox tenants can't have sub-profiles")
+	ErrSubProfileLimitExceeded = errors.New("sub-profile limit exceeded")
+	ErrSubProfileEmailTaken    = errors.New("a user with this email already exists")
+	ErrExpenseApprovalDecided  = errors.New("expense approval was already decided")
+)
+
+// ExpenseApprovalRequiredError is returned instead of creating an expense of a sub-profile
+// above its threshold; the expense waits in Approval until the parent decides
+type ExpenseApprovalRequiredError struct {
+	Approval models.ExpenseApproval
+}
+
+func (e *ExpenseApprovalRequiredError) Error() string {
+	return fmt.Sprintf("expense of %s needs the approval of the parent", e.Approval.Amount)
+}
+
+// SubProfileInput holds the data of a new sub-profile or the settings of an existing one
+type SubProfileInput struct {
+	Name              string
+	Email             string
+	Password          string
+	ApprovalThreshold *models.Money
+}
+
+// SubProfileOverview is what the parent sees of a sub-profile
+type SubProfileOverview struct {
+	Profile   dto.SubProfile
+	Expenses  []models.Expense // Latest first
+	Goals     []models.Goal
+	Approvals []models.ExpenseApproval // Pending ones
+}
+
+// subProfileOverviewExpenses is how many recent expenses the overview includes
+const subProfileOverviewExpenses = 20
+
+// subProfileAccess is what the middleware needs to know about a user on every request
+type subProfileAccess struct {
+	isSubProfile bool
+	active       bool
+}
+
+// subProfileCache caches subProfileAccess per user. It is only changed here, when
+// sub-profiles are created or removed, so cached values stay valid for the process lifetime
+var subProfileCache = struct {
+	mu     sync.RWMutex
+	access map[string]subProfileAccess
+}{access: make(map[string]subProfileAccess)}
+
+func setSubProfileAccess(userID string, access subProfileAccess) {
+	subProfileCache.mu.Lock()
+	subProfileCache.access[userID] = access
+	subProfileCache.mu.Unlock()
+}
+
+// GetSubProfileAccess reports whether the user is a sub-profile and, if so, whether it can still sign in
+func (s *Services) GetSubProfileAccess(userID string) (isSubProfile bool, active bool) {
+	subProfileCache.mu.RLock()
+	access, ok := subProfileCache.access[userID]
+	subProfileCache.mu.RUnlock()
+	if ok {
+		return access.isSubProfile, access.active
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "status").Where("id = ?", userID).First(&user).Error; err != nil {
+		// Don't cache misses, the user may not exist yet
+		return false, false
+	}
+	var count int64
+	if err := s.db.Model(&models.SubProfile{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		logger.Error("Error checking sub-profile %s: %v", userID, err)
+		return false, false
+	}
+	access = subProfileAccess{isSubProfile: count > 0, active: user.IsActive()}
+	setSubProfileAccess(userID, access)
+	return access.isSubProfile, access.active
+}
+
+func validateApprovalThreshold(threshold *models.Money) error {
+	if threshold != nil && *threshold < 0 {
+		return errors.New("invalid approval threshold: must be zero or positive")
+	}
+	return nil
+}
+
+// getSubProfile returns a sub-profile of the parent with its user
+func (s *Services) getSubProfile(parentID string, userID string) (*models.SubProfile, error) {
+	var profile models.SubProfile
+	if err := s.db.Preload("User").Where("user_id = ? AND parent_id = ?", userID, parentID).First(&profile).Error; err != nil {
+		return nil, errors.New("sub-profile not found")
+	}
+	return &profile, nil
+}
+
+func (s *Services) toSubProfileDTO(profile *models.SubProfile) (*dto.SubProfile, error) {
+	result := &dto.SubProfile{
+		UserID:            profile.UserID.String(),
+		Name:              profile.User.Name,
+		Email:             profile.User.Email,
+		Active:            profile.User.IsActive(),
+		WalletAccountID:   profile.WalletAccountID.String(),
+		ApprovalThreshold: profile.ApprovalThreshold,
+		CreatedAt:         profile.CreatedAt,
+	}
+	if err := s.db.Model(&models.BankAccount{}).Where("id = ?", profile.WalletAccountID).
+		Select("COALESCE(balance, 0)").Scan(&result.WalletBalance).Error; err != nil {
+		logger.Error("Error getting sub-profile wallet: %v", err)
+		return nil, errors.New("error getting sub-profile")
+	}
+	if err := s.db.Model(&models.ExpenseApproval{}).Where("user_id = ? AND status = ?", profile.UserID, models.ExpenseApprovalPending).
+		Count(&result.PendingApprovals).Error; err != nil {
+		logger.Error("Error counting sub-profile approvals: %v", err)
+		return nil, errors.New("error getting sub-profile")
+	}
+	return result, nil
+}
+
+// CreateSubProfile creates a restricted user under the parent, with its own login, a wallet
+// account for the allowance and the default categories
+func (s *Services) CreateSubProfile(parentID string, input SubProfileInput) (*dto.SubProfile, error) {
+	var parent models.User
+	if err := s.db.Where("id = ?", parentID).First(&parent).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if isSubProfile, _ := s.GetSubProfileAccess(parentID); isSubProfile || parent.IsSandbox {
+		return nil, ErrSubProfileNested
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	input.Email = strings.TrimSpace(input.Email)
+	if input.Name == "" || input.Email == "" || input.Password == "" {
+		return nil, errors.New("name, email and password are required")
+	}
+	if err := validateApprovalThreshold(input.ApprovalThreshold); err != nil {
+		return nil, err
+	}
+	if existing, _ := s.GetUserByEmail(input.Email); existing != nil {
+		return nil, ErrSubProfileEmailTaken
+	}
+
+	var count int64
+	if err := s.db.Model(&models.SubProfile{}).
+		Joins("JOIN users u ON u.id = sub_profiles.user_id").
+		Where("sub_profiles.parent_id = ? AND u.status = ?", parent.ID, models.StatusActive).
+		Count(&count).Error; err != nil {
+		logger.Error("Error counting sub-profiles: %v", err)
+		return nil, errors.New("error creating sub-profile")
+	}
+	if count >= MaxSubProfilesPerUser {
+		return nil, fmt.Errorf("%w: at most %d sub-profiles per user", ErrSubProfileLimitExceeded, MaxSubProfilesPerUser)
+	}
+
+	password, err := HashPassword(input.Password)
+	if err != nil {
+		return nil, errors.New("error creating sub-profile")
+	}
+
+	child := models.User{
+		ID:       uuid.New(),
+		Email:    input.Email,
+		Password: password,
+		Name:     input.Name,
+		Status:   models.StatusActive,
+		Currency: parent.Currency,
+		// Kids' activity stays out of product analytics
+		AnalyticsOptOut: true,
+	}
+	profile := models.SubProfile{UserID: child.ID, ParentID: parent.ID, ApprovalThreshold: input.ApprovalThreshold}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&child).Error; err != nil {
+			return err
+		}
+		wallet := models.BankAccount{UserID: child.ID, AccountName: "Wallet", Status: models.StatusActive}
+		if err := tx.Create(&wallet).Error; err != nil {
+			return err
+		}
+		profile.WalletAccountID = wallet.ID
+		return tx.Create(&profile).Error
+	})
+	if err != nil {
+		logger.Error("Error creating sub-profile: %v", err)
+		return nil, errors.New("error creating sub-profile")
+	}
+	setSubProfileAccess(child.ID.String(), subProfileAccess{isSubProfile: true, active: true})
+
+	if err := s.CreateDefaultUserCategories(child.ID.String()); err != nil {
+		logger.Warn("Error creating default categories for sub-profile %s: %v", child.ID, err)
+	}
+
+	s.RecordAudit(parent.ID, "sub_profile.created", "user", &child.ID, map[string]interface{}{
+		"approval_threshold": input.ApprovalThreshold,
+	})
+	logger.Info("Sub-profile %s created under user %s", child.ID, parent.ID)
+
+	profile.User = child
+	return s.toSubProfileDTO(&profile)
+}
+
+// GetSubProfiles lists the sub-profiles of the parent, removed ones included
+func (s *Services) GetSubProfiles(parentID string) ([]dto.SubProfile, error) {
+	var profiles []models.SubProfile
+	if err := s.db.Preload("User").Where("parent_id = ?", parentID).Order("created_at ASC").Find(&profiles).Error; err != nil {
+		logger.Error("Error getting sub-profiles: %v", err)
+		return nil, errors.New("error getting sub-profiles")
+	}
+
+	result := make([]dto.SubProfile, 0, len(profiles))
+	for i := range profiles {
+		profile, err := s.toSubProfileDTO(&profiles[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *profile)
+	}
+	return result, nil
+}
+
+// GetSubProfileOverview gives the parent visibility over a sub-profile: its wallet, latest
+// expenses, goals and the expenses waiting for approval
+func (s *Services) GetSubProfileOverview(parentID string, userID string) (*SubProfileOverview, error) {
+	profile, err := s.getSubProfile(parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.toSubProfileDTO(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &SubProfileOverview{Profile: *summary}
+	if err := s.db.Where("user_id = ? AND status IN ?", userID, models.GetVisibleStatuses()).
+		Preload("Category").Preload("BankAccount").
+		Order("date DESC, created_at DESC").Limit(subProfileOverviewExpenses).Find(&overview.Expenses).Error; err != nil {
+		logger.Error("Error getting sub-profile expenses: %v", err)
+		return nil, errors.New("error getting sub-profile")
+	}
+	if overview.Goals, err = s.GetGoals(userID, false); err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ? AND status = ?", userID, models.ExpenseApprovalPending).
+		Order("created_at ASC").Find(&overview.Approvals).Error; err != nil {
+		logger.Error("Error getting sub-profile approvals: %v", err)
+		return nil, errors.New("error getting sub-profile")
+	}
+	return overview, nil
+}
+
+// UpdateSubProfile changes the name and approval threshold of a sub-profile. A nil threshold
+// means its expenses never need approval
+func (s *Services) UpdateSubProfile(parentID string, userID string, input SubProfileInput) (*dto.SubProfile, error) {
+	profile, err := s.getSubProfile(parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateApprovalThreshold(input.ApprovalThreshold); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if name := strings.TrimSpace(input.Name); name != "" {
+			if err := tx.Model(&models.User{}).Where("id = ?", profile.UserID).Update("name", name).Error; err != nil {
+				return err
+			}
+			profile.User.Name = name
+		}
+		profile.ApprovalThreshold = input.ApprovalThreshold
+		return tx.Model(profile).Update("approval_threshold", input.ApprovalThreshold).Error
+	})
+	if err != nil {
+		logger.Error("Error updating sub-profile: %v", err)
+		return nil, errors.New("error updating sub-profile")
+	}
+	return s.toSubProfileDTO(profile)
+}
+
+// RemoveSubProfile deactivates a sub-profile: it can't use the API anymore, its sessions are
+// revoked and its pending expenses are rejected. Its data is kept for the parent
+func (s *Services) RemoveSubProfile(parentID string, userID string) error {
+	profile, err := s.getSubProfile(parentID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reason := "Sub-profile removed"
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", profile.UserID).Updates(map[string]interface{}{
+			"status":     models.StatusDeleted,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).Where("user_id = ?", profile.UserID).
+			Update("is_revoked", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ExpenseApproval{}).Where("user_id = ? AND status = ?", profile.UserID, models.ExpenseApprovalPending).
+			Updates(map[string]interface{}{"status": models.ExpenseApprovalRejected, "reason": reason, "decided_at": now}).Error
+	})
+	if err != nil {
+		logger.Error("Error removing sub-profile: %v", err)
+		return errors.New("error removing sub-profile")
+	}
+	setSubProfileAccess(userID, subProfileAccess{isSubProfile: true, active: false})
+
+	s.RecordAudit(profile.ParentID, "sub_profile.removed", "user", &profile.UserID, nil)
+	logger.Info("Sub-profile %s removed", userID)
+	return nil
+}
+
+// AddSubProfileAllowance pays an allowance into the wallet of a sub-profile, recorded as an
+// income of the sub-profile
+func (s *Services) AddSubProfileAllowance(parentID string, userID string, amount models.Money) (*dto.SubProfile, error) {
+	profile, err := s.getSubProfile(parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.User.IsActive() {
+		return nil, errors.New("sub-profile not found or removed")
+	}
+	if amount <= 0 {
+		return nil, errors.New("invalid amount: must be greater than 0")
+	}
+
+	income := &models.Income{
+		BankAccountID: &profile.WalletAccountID,
+		Source:        models.IncomeSourceGift,
+		Amount:        s.GetUserCurrency(userID).RoundMoney(amount),
+		Date:          s.UserToday(userID),
+	}
+	if err := s.CreateIncome(userID, income); err != nil {
+		logger.Error("Error paying sub-profile allowance: %v", err)
+		return nil, errors.New("error paying allowance")
+	}
+
+	s.RecordAudit(profile.ParentID, "sub_profile.allowance", "income", &income.ID, map[string]interface{}{
+		"sub_profile_id": profile.UserID,
+		"amount":         income.Amount,
+	})
+	return s.toSubProfileDTO(profile)
+}
+
+// holdForParentApproval stops an expense of a sub-profile above its threshold, storing it as
+// an approval request for the parent. It returns nil when the expense can go ahead
+func (s *Services) holdForParentApproval(userID string, expense *models.Expense) error {
+	if isSubProfile, _ := s.GetSubProfileAccess(userID); !isSubProfile {
+		return nil
+	}
+	var profile models.SubProfile
+	if err := s.db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		logger.Error("Error getting sub-profile %s: %v", userID, err)
+		return errors.New("error creating expense")
+	}
+	if profile.ApprovalThreshold == nil || expense.Amount <= *profile.ApprovalThreshold {
+		return nil
+	}
+
+	if len(expense.Allocations) > 0 {
+		return errors.New("split expense allocations aren't available for sub-profiles")
+	}
+	if expense.Amount <= 0 {
+		return errors.New("expense amount must be positive")
+	}
+	var count int64
+	s.db.Model(&models.Category{}).Where("id = ? AND user_id = ? AND status IN ?", expense.CategoryID, userID, models.GetActiveStatuses()).Count(&count)
+	if count == 0 {
+		return errors.New("category not found or not active")
+	}
+	s.db.Model(&models.BankAccount{}).Where("id = ? AND user_id = ? AND status IN ?", expense.BankAccountID, userID, models.GetActiveStatuses()).Count(&count)
+	if count == 0 {
+		return errors.New("bank account not found, not active, or access denied")
+	}
+
+	approval := models.ExpenseApproval{
+		UserID:        profile.UserID,
+		ParentID:      profile.ParentID,
+		CategoryID:    expense.CategoryID,
+		BankAccountID: expense.BankAccountID,
+		Amount:        expense.Amount,
+		Date:          expense.Date,
+		Description:   expense.Description,
+		Status:        models.ExpenseApprovalPending,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&approval).Error; err != nil {
+			return err
+		}
+		return EnqueueEvent(tx, profile.ParentID, EventExpenseApprovalRequested, "expense_approval", approval.ID, map[string]interface{}{
+			"sub_profile_id": profile.UserID,
+			"amount":         approval.Amount,
+			"threshold":      *profile.ApprovalThreshold,
+			"category_id":    approval.CategoryID,
+			"description":    approval.Description,
+		})
+	})
+	if err != nil {
+		logger.Error("Error holding expense for approval: %v", err)
+		return errors.New("error creating expense")
+	}
+
+	logger.Info("Expense of %s of sub-profile %s held for approval %s", approval.Amount, userID, approval.ID)
+	return &ExpenseApprovalRequiredError{Approval: approval}
+}
+
+// GetExpenseApprovals lists the approval requests a user can see: its own for a sub-profile,
+// the ones of all its sub-profiles for a parent. An empty status lists all of them
+func (s *Services) GetExpenseApprovals(userID string, status string) ([]models.ExpenseApproval, error) {
+	query := s.db.Model(&models.ExpenseApproval{})
+	if isSubProfile, _ := s.GetSubProfileAccess(userID); isSubProfile {
+		query = query.Where("user_id = ?", userID)
+	} else {
+		query = query.Where("parent_id = ?", userID)
+	}
+	switch status {
+	case "":
+	case models.ExpenseApprovalPending, models.ExpenseApprovalApproved, models.ExpenseApprovalRejected:
+		query = query.Where("status = ?", status)
+	default:
+		return nil, errors.New("invalid status: must be pending, approved or rejected")
+	}
+
+	var approvals []models.ExpenseApproval
+	if err := query.Order("created_at DESC").Find(&approvals).Error; err != nil {
+		logger.Error("Error getting expense approvals: %v", err)
+		return nil, errors.New("error getting expense approvals")
+	}
+	return approvals, nil
+}
+
+// DecideExpenseApproval approves or rejects an expense held for the parent. Approving creates
+// the expense for the sub-profile; if that fails (e.g. a hard category cap) the request stays pending
+func (s *Services) DecideExpenseApproval(parentID string, approvalID string, approve bool, reason *string) (*models.ExpenseApproval, error) {
+	var approval models.ExpenseApproval
+	if err := s.db.Where("id = ? AND parent_id = ?", approvalID, parentID).First(&approval).Error; err != nil {
+		return nil, errors.New("expense approval not found")
+	}
+
+	// Claim the request first so two decisions at once can't both go through
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.ExpenseApprovalRejected, "reason": reason, "decided_at": now}
+	if approve {
+		updates = map[string]interface{}{"status": models.ExpenseApprovalApproved, "decided_at": now}
+	}
+	result := s.db.Model(&models.ExpenseApproval{}).Where("id = ? AND status = ?", approval.ID, models.ExpenseApprovalPending).Updates(updates)
+	if result.Error != nil {
+		logger.Error("Error deciding expense approval: %v", result.Error)
+		return nil, errors.New("error deciding expense approval")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrExpenseApprovalDecided
+	}
+
+	if approve {
+		expense := &models.Expense{
+			CategoryID:    approval.CategoryID,
+			BankAccountID: approval.BankAccountID,
+			Amount:        approval.Amount,
+			Date:          approval.Date,
+			Description:   approval.Description,
+		}
+		if err := s.Expenses.create(context.Background(), approval.UserID.String(), expense, false); err != nil {
+			s.db.Model(&models.ExpenseApproval{}).Where("id = ?", approval.ID).
+				Updates(map[string]interface{}{"status": models.ExpenseApprovalPending, "decided_at": nil})
+			return nil, err
+		}
+		if err := s.db.Model(&models.ExpenseApproval{}).Where("id = ?", approval.ID).Update("expense_id", expense.ID).Error; err != nil {
+			logger.Error("Error linking approved expense %s: %v", expense.ID, err)
+		}
+	}
+
+	if err := s.db.Where("id = ?", approval.ID).First(&approval).Error; err != nil {
+		return nil, errors.New("error deciding expense approval")
+	}
+	if err := EnqueueEvent(s.db, approval.UserID, EventExpenseApprovalDecided, "expense_approval", approval.ID, map[string]interface{}{
+		"status":     approval.Status,
+		"amount":     approval.Amount,
+		"reason":     approval.Reason,
+		"expense_id": approval.ExpenseID,
+	}); err != nil {
+		logger.Error("Error enqueuing expense approval decision: %v", err)
+	}
+
+	s.RecordAudit(approval.ParentID, "expense_approval."+approval.Status, "expense_approval", &approval.ID, map[string]interface{}{
+		"sub_profile_id": approval.UserID,
+		"amount":         approval.Amount,
+	})
+	return &approval, nil
+}
@@ -0,0 +1,224 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// subProfileFixture is a sub-profile with an allowance of 200.00 in its wallet and a category
+type subProfileFixture struct {
+	parent   *models.User
+	child    *models.User
+	wallet   *models.BankAccount
+	category *models.Category
+}
+
+func newSubProfile(t *testing.T, h *testutil.Harness, threshold models.Money) subProfileFixture {
+	t.Helper()
+	parent := h.CreateUser(t)
+	profile, err := h.Services.CreateSubProfile(parent.ID.String(), services.SubProfileInput{
+		Name:              "Kid",
+		Email:             uuid.NewString() + "@example.com",
+		Password:          "kid-password",
+		ApprovalThreshold: &threshold,
+	})
+	if err != nil {
+		t.Fatalf("creating sub-profile: %v", err)
+	}
+	if _, err := h.Services.AddSubProfileAllowance(parent.ID.String(), profile.UserID, models.NewMoney(200)); err != nil {
+		t.Fatalf("paying allowance: %v", err)
+	}
+
+	child := &models.User{ID: uuid.MustParse(profile.UserID)}
+	return subProfileFixture{
+		parent:   parent,
+		child:    child,
+		wallet:   &models.BankAccount{ID: uuid.MustParse(profile.WalletAccountID)},
+		category: h.CreateCategory(t, child, "Snacks", models.ExpenseTypeWants),
+	}
+}
+
+func TestSubProfileExpenseApprovalThreshold(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	cases := []struct {
+		name         string
+		amount       models.Money
+		split        bool
+		wantApproval bool
+		wantBalance  models.Money
+		wantErr      string
+	}{
+		{
+			name:        "creates an expense within the threshold",
+			amount:      models.NewMoney(30),
+			wantBalance: models.NewMoney(170),
+		},
+		{
+			name:         "holds an expense above the threshold",
+			amount:       models.NewMoney(80),
+			wantApproval: true,
+			wantBalance:  models.NewMoney(200),
+		},
+		{
+			name:        "rejects a split expense above the threshold",
+			amount:      models.NewMoney(80),
+			split:       true,
+			wantBalance: models.NewMoney(200),
+			wantErr:     "split expense allocations aren't available for sub-profiles",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newSubProfile(t, h, models.NewMoney(50))
+			expense := &models.Expense{
+				CategoryID:    f.category.ID,
+				BankAccountID: f.wallet.ID,
+				Amount:        tc.amount,
+				Date:          time.Now().UTC().Truncate(24 * time.Hour),
+			}
+			if tc.split {
+				expense.Allocations = []models.ExpenseAllocation{
+					{BankAccountID: f.wallet.ID, Amount: tc.amount - models.NewMoney(10)},
+					{BankAccountID: uuid.New(), Amount: models.NewMoney(10)},
+				}
+			}
+
+			err := h.Expenses.Create(context.Background(), f.child.ID.String(), expense, false)
+			var approvalErr *services.ExpenseApprovalRequiredError
+			switch {
+			case tc.wantApproval:
+				if !errors.As(err, &approvalErr) {
+					t.Fatalf("error = %v, want the approval to be required", err)
+				}
+				if approvalErr.Approval.Amount != tc.amount || approvalErr.Approval.ParentID != f.parent.ID ||
+					approvalErr.Approval.Status != models.ExpenseApprovalPending {
+					t.Errorf("approval %+v, want a pending one of %s for the parent", approvalErr.Approval, tc.amount)
+				}
+			case tc.wantErr != "":
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("creating expense: %v", err)
+			}
+
+			if balance := accountBalance(t, h, f.wallet); balance != tc.wantBalance {
+				t.Errorf("wallet balance = %s, want %s", balance, tc.wantBalance)
+			}
+			var expenses int64
+			if err := h.DB.Model(&models.Expense{}).Where("user_id = ?", f.child.ID).Count(&expenses).Error; err != nil {
+				t.Fatalf("counting expenses: %v", err)
+			}
+			if wantExpenses := tc.wantErr == "" && !tc.wantApproval; (expenses == 1) != wantExpenses {
+				t.Errorf("%d expenses stored, want one: %t", expenses, wantExpenses)
+			}
+		})
+	}
+}
+
+func TestDecideExpenseApproval(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	reason := "Too expensive"
+
+	cases := []struct {
+		name        string
+		approve     bool
+		otherParent bool
+		decideTwice bool
+		wantStatus  string
+		wantBalance models.Money
+		wantErr     error
+	}{
+		{
+			name:        "approving creates the expense",
+			approve:     true,
+			wantStatus:  models.ExpenseApprovalApproved,
+			wantBalance: models.NewMoney(120),
+		},
+		{
+			name:        "rejecting keeps the wallet",
+			wantStatus:  models.ExpenseApprovalRejected,
+			wantBalance: models.NewMoney(200),
+		},
+		{
+			name:        "a decided approval can't be decided again",
+			approve:     true,
+			decideTwice: true,
+			wantBalance: models.NewMoney(120),
+			wantErr:     services.ErrExpenseApprovalDecided,
+		},
+		{
+			name:        "another parent can't decide",
+			approve:     true,
+			otherParent: true,
+			wantBalance: models.NewMoney(200),
+			wantErr:     errors.New("expense approval not found"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newSubProfile(t, h, models.NewMoney(50))
+			err := h.Expenses.Create(context.Background(), f.child.ID.String(), &models.Expense{
+				CategoryID:    f.category.ID,
+				BankAccountID: f.wallet.ID,
+				Amount:        models.NewMoney(80),
+				Date:          time.Now().UTC().Truncate(24 * time.Hour),
+			}, false)
+			var approvalErr *services.ExpenseApprovalRequiredError
+			if !errors.As(err, &approvalErr) {
+				t.Fatalf("creating expense = %v, want the approval to be required", err)
+			}
+
+			parentID := f.parent.ID.String()
+			if tc.otherParent {
+				parentID = h.CreateUser(t).ID.String()
+			}
+			approvalID := approvalErr.Approval.ID.String()
+			if tc.decideTwice {
+				if _, err := h.Services.DecideExpenseApproval(parentID, approvalID, tc.approve, nil); err != nil {
+					t.Fatalf("deciding the first time: %v", err)
+				}
+			}
+
+			approval, err := h.Services.DecideExpenseApproval(parentID, approvalID, tc.approve, &reason)
+			if tc.wantErr != nil {
+				if err == nil || err.Error() != tc.wantErr.Error() {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("deciding: %v", err)
+			}
+
+			if balance := accountBalance(t, h, f.wallet); balance != tc.wantBalance {
+				t.Errorf("wallet balance = %s, want %s", balance, tc.wantBalance)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			if approval.Status != tc.wantStatus || approval.DecidedAt == nil {
+				t.Errorf("approval is %s (decided at %v), want %s", approval.Status, approval.DecidedAt, tc.wantStatus)
+			}
+			if tc.approve {
+				if approval.ExpenseID == nil {
+					t.Fatal("approved expense isn't linked")
+				}
+				if _, err := h.Expenses.GetByID(f.child.ID.String(), approval.ExpenseID.String()); err != nil {
+					t.Errorf("getting the approved expense: %v", err)
+				}
+			} else if approval.ExpenseID != nil || approval.Reason == nil || *approval.Reason != reason {
+				t.Errorf("rejected approval has expense %v and reason %v, want no expense and %q", approval.ExpenseID, approval.Reason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// summaryCacheMaxEntries bounds the memory store; past it, expired entries are swept and, if
+// that isn't enough, everything is dropped
+const summaryCacheMaxEntries = 10000
+
+// summaryCacheTables are the tables the cached summaries read. A write to any of them
+// invalidates the summaries of the user it belongs to. Tables without user_id are left out:
+// allocations and compliance lines are written with their parent, tags invalidate on their own
+var summaryCacheTables = map[string]bool{
+	"expenses":                  true,
+	"incomes":                   true,
+	"transfers":                 true,
+	"budgets":                   true,
+	"category_budgets":          true,
+	"budget_revisions":          true,
+	"budget_compliances":        true,
+	"categories":                true,
+	"tags":                      true,
+	"bank_accounts":             true,
+	"fixed_expenses":            true,
+	"fixed_expense_occurrences": true,
+}
+
+// summaryCacheTTL is how long a summary is served from the cache. Writes invalidate it before
+// then; the TTL bounds what they can miss
+func summaryCacheTTL() time.Duration {
+	return time.Duration(envInt("SUMMARY_CACHE_TTL_SECONDS", 300)) * time.Second
+}
+
+// summaryCacheStore keeps computed summaries per user. Get returns a generation token along with
+// the value; Set only stores under that token, so a summary computed while a write invalidated
+// the user is never served
+type summaryCacheStore interface {
+	Get(ctx context.Context, userID, key string) (value []byte, token string, found bool, err error)
+	Set(ctx context.Context, userID, key, token string, value []byte, ttl time.Duration) error
+	// Invalidate drops the summaries of the user, or of everyone when userID is empty
+	Invalidate(ctx context.Context, userID string) error
+}
+
+var (
+	summaryCacheOnce sync.Once
+	summaryCache     summaryCacheStore
+)
+
+// getSummaryCache returns the store selected by SUMMARY_CACHE_STORE: "memory" (the default)
+// caches in this instance, "redis" shares the cache between instances through REDIS_URL and
+// "off" disables it. nil means no caching
+func getSummaryCache() summaryCacheStore {
+	summaryCacheOnce.Do(func() {
+		var err error
+		switch os.Getenv("SUMMARY_CACHE_STORE") {
+		case "", "memory":
+			summaryCache = newMemorySummaryCache()
+		case "redis":
+			var client *redis.Client
+			if client, err = newRedisClient(os.Getenv("REDIS_URL")); err == nil {
+				summaryCache = &redisSummaryCache{client}
+			}
+		case "off":
+		default:
+			err = errors.New("invalid SUMMARY_CACHE_STORE: must be memory, redis or off")
+		}
+		if err != nil {
+			logger.Error("Error configuring summary cache, summaries won't be cached: %v", err)
+		}
+	})
+	return summaryCache
+}
+
+// cachedSummary returns the summary cached for the user under key, computing and caching it when
+// missing. Keys name the summary and its period, e.g. "cash-flow:2026-03". The cache is skipped,
+// never failed on, when its store can't be reached
+func cachedSummary[T any](ctx context.Context, userID, key string, compute func() (*T, error)) (*T, error) {
+	store := getSummaryCache()
+	if store == nil {
+		return compute()
+	}
+
+	value, token, found, err := store.Get(ctx, userID, key)
+	if err != nil {
+		logger.Warn("Error reading summary cache for %s: %v", key, err)
+	} else if found {
+		var summary T
+		if err := json.Unmarshal(value, &summary); err == nil {
+			return &summary, nil
+		}
+	}
+
+	summary, err := compute()
+	if err != nil || token == "" {
+		return summary, err
+	}
+	if value, err := json.Marshal(summary); err == nil {
+		if err := store.Set(ctx, userID, key, token, value, summaryCacheTTL()); err != nil {
+			logger.Warn("Error writing summary cache for %s: %v", key, err)
+		}
+	}
+	return summary, nil
+}
+
+// InvalidateSummaryCache drops the cached summaries of the user, for changes the database
+// plugin can't see, like the user's currency
+func InvalidateSummaryCache(userID string) {
+	store := getSummaryCache()
+	if store == nil {
+		return
+	}
+	if err := store.Invalidate(context.Background(), userID); err != nil {
+		logger.Error("Error invalidating summary cache for user %s: %v", userID, err)
+	}
+}
+
+// SummaryCachePlugin invalidates the cached summaries after every write to the tables they
+// read, whichever service makes it. Writes it can't attribute to a user invalidate everyone.
+// Invalidation waits for the write's transaction to commit: a summary computed in between would
+// still read the old rows and be cached under the new generation
+type SummaryCachePlugin struct{}
+
+// Name identifies the plugin to GORM
+func (SummaryCachePlugin) Name() string {
+	return "summary_cache"
+}
+
+// Initialize registers the invalidation after each kind of write
+func (SummaryCachePlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().After("gorm:create").Register("summary_cache:after_create", invalidateSummariesAfterWrite),
+		callbacks.Update().After("gorm:update").Register("summary_cache:after_update", invalidateSummariesAfterWrite),
+		callbacks.Delete().After("gorm:delete").Register("summary_cache:after_delete", invalidateSummariesAfterWrite),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func invalidateSummariesAfterWrite(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.RowsAffected == 0 {
+		return
+	}
+	table := tx.Statement.Table
+	if i := strings.IndexByte(table, ' '); i > 0 {
+		table = table[:i]
+	}
+	if !summaryCacheTables[table] {
+		return
+	}
+	userID := statementUserID(tx.Statement)
+	db.AfterCommit(tx, func() { InvalidateSummaryCache(userID) })
+}
+
+// statementUserID finds the user a write belongs to: the UserID of the records written, or the
+// user_id condition of the query. It's empty when there is no single user
+func statementUserID(statement *gorm.Statement) string {
+	userIDs := make(map[string]bool)
+	collect := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if value.Kind() != reflect.Struct {
+			return
+		}
+		if field := value.FieldByName("UserID"); field.IsValid() {
+			if userID, ok := field.Interface().(uuid.UUID); ok && userID != uuid.Nil {
+				userIDs[userID.String()] = true
+			}
+		}
+	}
+	switch value := reflect.Indirect(statement.ReflectValue); value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collect(value.Index(i))
+		}
+	case reflect.Struct:
+		collect(value)
+	}
+
+	if where, ok := statement.Clauses["WHERE"].Expression.(clause.Where); ok {
+		for _, expression := range where.Exprs {
+			switch condition := expression.(type) {
+			case clause.Expr:
+				// Only when user_id takes the first placeholder, e.g. "user_id = ? AND id = ?"
+				i := strings.Index(condition.SQL, "user_id = ?")
+				if i >= 0 && len(condition.Vars) > 0 && !strings.Contains(condition.SQL[:i], "?") &&
+					(i == 0 || strings.ContainsRune(" .(", rune(condition.SQL[i-1]))) {
+					userIDs[fmt.Sprint(condition.Vars[0])] = true
+				}
+			case clause.Eq:
+				if column, ok := condition.Column.(clause.Column); ok && column.Name == "user_id" {
+					userIDs[fmt.Sprint(condition.Value)] = true
+				} else if name, ok := condition.Column.(string); ok && name == "user_id" {
+					userIDs[fmt.Sprint(condition.Value)] = true
+				}
+			}
+		}
+	}
+
+	if len(userIDs) != 1 {
+		return ""
+	}
+	for userID := range userIDs {
+		return userID
+	}
+	return ""
+}
+
+// memorySummaryCache caches the summaries in this instance. With several instances, writes
+// handled by one don't reach the others' caches until the TTL; use redis there
+type memorySummaryCache struct {
+	mu         sync.Mutex
+	generation int64
+	users      map[string]*memorySummaryUser
+	entries    int
+}
+
+type memorySummaryUser struct {
+	generation int64
+	entries    map[string]memorySummaryEntry
+}
+
+type memorySummaryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func newMemorySummaryCache() *memorySummaryCache {
+	return &memorySummaryCache{users: make(map[string]*memorySummaryUser)}
+}
+
+func (c *memorySummaryCache) token(user *memorySummaryUser) string {
+	return fmt.Sprintf("%d:%d", c.generation, user.generation)
+}
+
+func (c *memorySummaryCache) user(userID string) *memorySummaryUser {
+	user, ok := c.users[userID]
+	if !ok {
+		user = &memorySummaryUser{entries: make(map[string]memorySummaryEntry)}
+		c.users[userID] = user
+	}
+	return user
+}
+
+func (c *memorySummaryCache) Get(_ context.Context, userID, key string) ([]byte, string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user := c.user(userID)
+	entry, ok := user.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, c.token(user), false, nil
+	}
+	return entry.value, c.token(user), true, nil
+}
+
+func (c *memorySummaryCache) Set(_ context.Context, userID, key, token string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user := c.user(userID)
+	if token != c.token(user) {
+		return nil
+	}
+
+	now := time.Now()
+	if c.entries >= summaryCacheMaxEntries {
+		c.entries = 0
+		for _, cached := range c.users {
+			for cachedKey, entry := range cached.entries {
+				if now.After(entry.expiresAt) {
+					delete(cached.entries, cachedKey)
+				}
+			}
+			c.entries += len(cached.entries)
+		}
+		if c.entries >= summaryCacheMaxEntries {
+			for _, cached := range c.users {
+				cached.entries = make(map[string]memorySummaryEntry)
+			}
+			c.entries = 0
+		}
+	}
+	if _, ok := user.entries[key]; !ok {
+		c.entries++
+	}
+	user.entries[key] = memorySummaryEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (c *memorySummaryCache) Invalidate(_ context.Context, userID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if userID == "" {
+		c.generation++
+		c.users = make(map[string]*memorySummaryUser)
+		c.entries = 0
+		return nil
+	}
+	user := c.user(userID)
+	user.generation++
+	c.entries -= len(user.entries)
+	user.entries = make(map[string]memorySummaryEntry)
+	return nil
+}
+
+// redisSummaryGetScript reads the generations of everyone and of the user and the entry stored
+// under them, in one round trip
+var redisSummaryGetScript = redis.NewScript(`
+local token = (redis.call('GET', KEYS[1]) or '0') .. ':' .. (redis.call('GET', KEYS[2]) or '0')
+return {token, redis.call('GET', ARGV[1] .. token .. ':' .. ARGV[2])}
+`)
+
+// redisSummaryCache shares the summaries between instances. Invalidating bumps a generation
+// that is part of every key, so stale entries are never read and expire on their own
+type redisSummaryCache struct {
+	client *redis.Client
+}
+
+const redisSummaryPrefix = "fluxio:summary:"
+
+func (c *redisSummaryCache) Get(ctx context.Context, userID, key string) ([]byte, string, bool, error) {
+	reply, err := redisSummaryGetScript.Run(ctx, c.client,
+		[]string{redisSummaryPrefix + "generation", redisSummaryPrefix + "generation:" + userID},
+		redisSummaryPrefix+userID+":", key).Result()
+	if err != nil {
+		return nil, "", false, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, "", false, fmt.Errorf("unexpected reply from summary cache script: %v", reply)
+	}
+	token, _ := values[0].(string)
+	value, found := values[1].(string)
+	return []byte(value), token, found, nil
+}
+
+func (c *redisSummaryCache) Set(ctx context.Context, userID, key, token string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, redisSummaryPrefix+userID+":"+token+":"+key, value, ttl).Err()
+}
+
+func (c *redisSummaryCache) Invalidate(ctx context.Context, userID string) error {
+	key := redisSummaryPrefix + "generation"
+	if userID != "" {
+		key += ":" + userID
+	}
+	return c.client.Incr(ctx, key).Err()
+}
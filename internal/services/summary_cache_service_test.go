@@ -0,0 +1,51 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// TestRedisSummaryCacheInvalidation caches a summary in Redis and checks that invalidating the
+// user, or everyone, hides it
+func TestRedisSummaryCacheInvalidation(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache, err := services.NewRedisSummaryCache("redis://" + server.Addr())
+	if err != nil {
+		t.Fatalf("connecting to redis: %v", err)
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		invalidate string // User to invalidate; empty invalidates everyone
+	}{
+		{"user", "user-1"},
+		{"everyone", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, token, found, err := cache.Get(ctx, "user-1", "cash-flow:2026-03")
+			if err != nil || found {
+				t.Fatalf("empty cache: found = %v, err = %v", found, err)
+			}
+			if err := cache.Set(ctx, "user-1", "cash-flow:2026-03", token, []byte(`{"net":"10.00"}`), time.Minute); err != nil {
+				t.Fatalf("caching the summary: %v", err)
+			}
+			value, _, found, err := cache.Get(ctx, "user-1", "cash-flow:2026-03")
+			if err != nil || !found || string(value) != `{"net":"10.00"}` {
+				t.Fatalf("cached summary = %q (found %v, err %v), want the stored one", value, found, err)
+			}
+
+			if err := cache.Invalidate(ctx, c.invalidate); err != nil {
+				t.Fatalf("invalidating: %v", err)
+			}
+			if _, _, found, err := cache.Get(ctx, "user-1", "cash-flow:2026-03"); err != nil || found {
+				t.Fatalf("after invalidating: found = %v, err = %v, want a miss", found, err)
+			}
+		})
+	}
+}
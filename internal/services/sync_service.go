@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/repository"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Strategies resolving a pushed change that conflicts with a newer server copy
+const (
+	SyncServerWins = "server_wins"
+	SyncClientWins = "client_wins"
+)
+
+// Operations a client can push
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+)
+
+// Outcomes of a pushed change
+const (
+	SyncStatusApplied         = "applied"
+	SyncStatusConflict        = "conflict"
+	SyncStatusRejected        = "rejected"
+	SyncStatusPendingApproval = "pending_approval"
+)
+
+// syncSettleWindow keeps the rows written in the last seconds out of a pull. Their transactions
+// may still be committing alongside others with earlier timestamps, which the cursor would skip
+const syncSettleWindow = 5 * time.Second
+
+// syncEntities are the entities a pull returns and their tables, in the order a client should
+// apply them: what the others reference comes first
+var syncEntities = []struct {
+	Name  string
+	Table string
+}{
+	{"bank_account", "bank_accounts"},
+	{"category", "categories"},
+	{"budget", "budgets"},
+	{"goal", "goals"},
+	{"fixed_expense", "fixed_expenses"},
+	{"expense", "expenses"},
+	{"income", "incomes"},
+	{"transfer", "transfers"},
+	{"reminder", "reminders"},
+}
+
+// ErrSyncCursorExpired is returned for a cursor from before deletions that were removed for good
+// since, so no tombstone reports them anymore. The client must drop its copy and pull again
+// without a cursor
+var ErrSyncCursorExpired = errors.New("sync cursor expired, sync again without a cursor")
+
+// syncEntityOfTable returns the sync entity stored in the table, or "" when it isn't synced
+func syncEntityOfTable(table string) string {
+	for _, entity := range syncEntities {
+		if entity.Table == table {
+			return entity.Name
+		}
+	}
+	return ""
+}
+
+// recordSyncPurge raises the purge watermark of the user's entity stored in table to through, in
+// the caller's transaction. Rows are removed for good by the retention purge, the trash and hard
+// deletes; through is the latest updated_at of the deleted rows purged, or the time of a hard
+// delete of a row clients may still hold
+func recordSyncPurge(tx *gorm.DB, userID uuid.UUID, table string, through time.Time) error {
+	entity := syncEntityOfTable(table)
+	if entity == "" {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO sync_purges (user_id, entity, purged_through, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, entity) DO UPDATE
+		SET purged_through = GREATEST(sync_purges.purged_through, EXCLUDED.purged_through), updated_at = EXCLUDED.updated_at`,
+		userID, entity, through, time.Now()).Error
+}
+
+// syncPushTables are the entities a client can push changes to
+var syncPushTables = map[string]string{
+	"expense":  "expenses",
+	"income":   "incomes",
+	"category": "categories",
+}
+
+// SyncPushEntities returns the entities a client can push changes to
+func SyncPushEntities() []string {
+	return []string{"expense", "income", "category"}
+}
+
+// syncPosition is where a pull stopped in one entity: the last row returned
+type syncPosition struct {
+	UpdatedAt time.Time `json:"u"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// syncRow is what a pull reads of every changed row before loading the ones still alive
+type syncRow struct {
+	ID              uuid.UUID
+	UpdatedAt       time.Time
+	Status          models.Status
+	StatusChangedAt *time.Time
+}
+
+// SyncPage is a page of the changes since a cursor: the entities changed, oldest first, and the
+// ones deleted. Pull again with Cursor while HasMore
+type SyncPage struct {
+	BankAccounts  []models.BankAccount
+	Categories    []models.Category
+	Budgets       []models.Budget
+	Goals         []models.Goal
+	FixedExpenses []models.FixedExpense
+	Expenses      []models.Expense
+	Incomes       []models.Income
+	Transfers     []models.Transfer
+	Reminders     []models.Reminder
+	Tombstones    []dto.SyncTombstone
+	Cursor        string
+	HasMore       bool
+}
+
+func decodeSyncCursor(cursor string) (map[string]syncPosition, error) {
+	positions := make(map[string]syncPosition)
+	if cursor == "" {
+		return positions, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid sync cursor")
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, errors.New("invalid sync cursor")
+	}
+	return positions, nil
+}
+
+func encodeSyncCursor(positions map[string]syncPosition) string {
+	data, _ := json.Marshal(positions)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// loadSyncRows loads the rows with the given IDs, in the order of the pull
+func loadSyncRows[T any](database *gorm.DB, userID string, ids []uuid.UUID, preload func(*gorm.DB) *gorm.DB) ([]T, error) {
+	rows := []T{}
+	if len(ids) == 0 {
+		return rows, nil
+	}
+	query := database.Where("user_id = ? AND id IN ?", userID, ids)
+	if preload != nil {
+		query = preload(query)
+	}
+	err := query.Order("updated_at ASC, id ASC").Find(&rows).Error
+	return rows, err
+}
+
+// GetSyncChanges returns up to limit changes per entity made after the cursor, which is empty for
+// a first sync. A first sync leaves deleted rows out; later ones return them as tombstones.
+// Rows changed in the last seconds are left for the next pull. A cursor from before deleted rows
+// were removed for good gets ErrSyncCursorExpired
+func (s *Services) GetSyncChanges(userID string, cursor string, limit int) (*SyncPage, error) {
+	positions, err := decodeSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) > 0 {
+		var purges []models.SyncPurge
+		if err := s.db.Where("user_id = ?", userID).Find(&purges).Error; err != nil {
+			logger.Error("Error getting sync purges: %v", err)
+			return nil, errors.New("error listing changes")
+		}
+		for _, purge := range purges {
+			if position, ok := positions[purge.Entity]; ok && position.UpdatedAt.Before(purge.PurgedThrough) {
+				return nil, ErrSyncCursorExpired
+			}
+		}
+	}
+
+	horizon := time.Now().Add(-syncSettleWindow)
+	page := &SyncPage{Tombstones: []dto.SyncTombstone{}}
+	alive := make(map[string][]uuid.UUID)
+	for _, entity := range syncEntities {
+		position, resumed := positions[entity.Name]
+		query := s.db.Table(entity.Table).Select("id, updated_at, status, status_changed_at").
+			Where("user_id = ? AND updated_at < ?", userID, horizon)
+		if resumed {
+			query = query.Where("(updated_at, id) > (?, ?)", position.UpdatedAt, position.ID)
+		} else {
+			query = query.Where("status != ?", models.StatusDeleted)
+		}
+		var rows []syncRow
+		if err := query.Order("updated_at ASC, id ASC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+			logger.Error("Error listing changed %s: %v", entity.Table, err)
+			return nil, errors.New("error listing changes")
+		}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			page.HasMore = true
+		}
+		if len(rows) == 0 {
+			// Every entity gets a position, so deletions after this pull come back as
+			// tombstones even when the client had nothing of it yet
+			if !resumed {
+				positions[entity.Name] = syncPosition{UpdatedAt: horizon}
+			}
+			continue
+		}
+
+		for _, row := range rows {
+			if row.Status != models.StatusDeleted {
+				alive[entity.Name] = append(alive[entity.Name], row.ID)
+				continue
+			}
+			deletedAt := row.UpdatedAt
+			if row.StatusChangedAt != nil {
+				deletedAt = *row.StatusChangedAt
+			}
+			page.Tombstones = append(page.Tombstones, dto.SyncTombstone{
+				Entity: entity.Name, ID: row.ID.String(), DeletedAt: deletedAt,
+			})
+		}
+		last := rows[len(rows)-1]
+		positions[entity.Name] = syncPosition{UpdatedAt: last.UpdatedAt, ID: last.ID}
+	}
+
+	loads := []error{}
+	page.BankAccounts, err = loadSyncRows[models.BankAccount](s.db, userID, alive["bank_account"], nil)
+	loads = append(loads, err)
+	page.Categories, err = loadSyncRows[models.Category](s.db, userID, alive["category"], nil)
+	loads = append(loads, err)
+	page.Budgets, err = loadSyncRows[models.Budget](s.db, userID, alive["budget"], repository.PreloadCategoryBudgets)
+	loads = append(loads, err)
+	page.Goals, err = loadSyncRows[models.Goal](s.db, userID, alive["goal"], nil)
+	loads = append(loads, err)
+	page.FixedExpenses, err = loadSyncRows[models.FixedExpense](s.db, userID, alive["fixed_expense"], nil)
+	loads = append(loads, err)
+	page.Expenses, err = loadSyncRows[models.Expense](s.db, userID, alive["expense"], repository.PreloadExpenseRelations)
+	loads = append(loads, err)
+	page.Incomes, err = loadSyncRows[models.Income](s.db, userID, alive["income"], preloadIncomeRelations)
+	loads = append(loads, err)
+	page.Transfers, err = loadSyncRows[models.Transfer](s.db, userID, alive["transfer"], nil)
+	loads = append(loads, err)
+	page.Reminders, err = loadSyncRows[models.Reminder](s.db, userID, alive["reminder"], nil)
+	loads = append(loads, err)
+	if err := errors.Join(loads...); err != nil {
+		logger.Error("Error loading changed entities: %v", err)
+		return nil, errors.New("error listing changes")
+	}
+
+	page.Cursor = encodeSyncCursor(positions)
+	return page, nil
+}
+
+// SyncChange is one change pushed by a client: an upsert carries the entity in the field matching
+// Entity, with the client's ID. BaseUpdatedAt is the updated_at of the copy the client changed,
+// nil for an entity it created
+type SyncChange struct {
+	Entity        string
+	Op            string
+	ID            uuid.UUID
+	BaseUpdatedAt *time.Time
+	Expense       *models.Expense
+	Income        *models.Income
+	Category      *models.Category
+	OverrideCap   bool   // Lets an expense go through a hard category cap
+	ConfirmToken  string // Confirms an expense above the confirmation threshold
+}
+
+// ApplySyncChanges applies the changes in order, each on its own, and returns their outcomes.
+// A change conflicts when the server copy was updated after BaseUpdatedAt, or exists while the
+// client thought it was new. strategies picks per entity whether the server copy stays
+// (server_wins, the default) or the change overwrites it (client_wins). Deleting is idempotent
+func (s *Services) ApplySyncChanges(ctx context.Context, userID string, changes []SyncChange, strategies map[string]string) []dto.SyncChangeResult {
+	results := make([]dto.SyncChangeResult, 0, len(changes))
+	for _, change := range changes {
+		result := s.applySyncChange(ctx, userID, change, strategies[change.Entity])
+		result.Entity = change.Entity
+		result.ID = change.ID.String()
+		results = append(results, result)
+	}
+	return results
+}
+
+func (s *Services) applySyncChange(ctx context.Context, userID string, change SyncChange, strategy string) dto.SyncChangeResult {
+	table, ok := syncPushTables[change.Entity]
+	if !ok {
+		return dto.SyncChangeResult{Status: SyncStatusRejected, Error: "unsupported entity"}
+	}
+
+	var server syncRow
+	result := s.db.Table(table).Select("id, updated_at, status, status_changed_at").
+		Where("user_id = ? AND id = ?", userID, change.ID).Limit(1).Scan(&server)
+	if result.Error != nil {
+		logger.Error("Error loading %s %s to sync: %v", change.Entity, change.ID, result.Error)
+		return dto.SyncChangeResult{Status: SyncStatusRejected, Error: "error loading the server copy"}
+	}
+	exists := result.RowsAffected > 0
+
+	if change.Op == SyncOpDelete && (!exists || server.Status == models.StatusDeleted) {
+		return dto.SyncChangeResult{Status: SyncStatusApplied}
+	}
+	// Pulls and pushes give clients updated_at at full precision
+	conflict := exists && (change.BaseUpdatedAt == nil || server.UpdatedAt.After(*change.BaseUpdatedAt))
+	if conflict && strategy != SyncClientWins {
+		updatedAt := server.UpdatedAt
+		return dto.SyncChangeResult{Status: SyncStatusConflict, ServerUpdatedAt: &updatedAt}
+	}
+
+	err := s.applySyncOp(ctx, userID, change, exists, server.Status == models.StatusDeleted)
+	if err != nil {
+		var confirmErr *ConfirmationRequiredError
+		var approvalErr *ExpenseApprovalRequiredError
+		switch {
+		case errors.As(err, &confirmErr):
+			return dto.SyncChangeResult{Status: SyncStatusRejected, Error: err.Error(), ConfirmToken: confirmErr.Token}
+		case errors.As(err, &approvalErr):
+			return dto.SyncChangeResult{Status: SyncStatusPendingApproval, Error: err.Error()}
+		}
+		logger.Warn("Sync change to %s %s rejected: %v", change.Entity, change.ID, err)
+		return dto.SyncChangeResult{Status: SyncStatusRejected, Error: err.Error()}
+	}
+
+	var applied syncRow
+	if err := s.db.Table(table).Select("id, updated_at").
+		Where("user_id = ? AND id = ?", userID, change.ID).Limit(1).Scan(&applied).Error; err != nil {
+		return dto.SyncChangeResult{Status: SyncStatusApplied}
+	}
+	return dto.SyncChangeResult{Status: SyncStatusApplied, ServerUpdatedAt: &applied.UpdatedAt}
+}
+
+// applySyncOp makes the change through the services of its entity, so it's validated and moves
+// balances like any other write. An upsert of a deleted entity restores it first
+func (s *Services) applySyncOp(ctx context.Context, userID string, change SyncChange, exists, deleted bool) error {
+	id := change.ID.String()
+	switch change.Entity {
+	case "expense":
+		expenses := s.Expenses
+		if change.Op == SyncOpDelete {
+			return expenses.SoftDelete(userID, id)
+		}
+		if change.Expense == nil {
+			return errors.New("expense data is required")
+		}
+		change.Expense.ID = change.ID
+		if !exists {
+			if err := s.RequireExpenseConfirmation(userID, change.Expense, change.ConfirmToken); err != nil {
+				return err
+			}
+			return expenses.Create(ctx, userID, change.Expense, change.OverrideCap)
+		}
+		if deleted {
+			if _, err := expenses.Restore(userID, id); err != nil {
+				return err
+			}
+		}
+		// Allocations can't be patched; leaving them would have them saved as new rows
+		change.Expense.Allocations = nil
+		_, err := expenses.Patch(userID, id, change.Expense)
+		return err
+	case "income":
+		if change.Op == SyncOpDelete {
+			return s.SoftDeleteIncome(userID, id)
+		}
+		if change.Income == nil {
+			return errors.New("income data is required")
+		}
+		change.Income.ID = change.ID
+		if !exists {
+			return s.CreateIncome(userID, change.Income)
+		}
+		if deleted {
+			if _, err := s.RestoreIncome(userID, id); err != nil {
+				return err
+			}
+		}
+		_, err := s.PatchIncome(userID, id, change.Income)
+		return err
+	case "category":
+		if change.Op == SyncOpDelete {
+			return s.SoftDeleteUserCategory(userID, id)
+		}
+		if change.Category == nil {
+			return errors.New("category data is required")
+		}
+		change.Category.ID = change.ID
+		if !exists {
+			return s.CreateUserCategory(userID, change.Category)
+		}
+		if deleted {
+			if _, err := s.RestoreUserCategory(userID, id); err != nil {
+				return err
+			}
+		}
+		_, err := s.UpdateUserCategory(userID, id, change.Category)
+		return err
+	}
+	return fmt.Errorf("unsupported entity %s", change.Entity)
+}
@@ -0,0 +1,366 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/Osminalx/fluxio/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// settleSyncRows moves the rows of the user an hour back, out of the window pulls leave for
+// transactions still committing
+func settleSyncRows(t *testing.T, h *testutil.Harness, user *models.User) {
+	t.Helper()
+	for _, table := range []string{"bank_accounts", "categories", "expenses"} {
+		if err := h.DB.Exec("UPDATE "+table+" SET updated_at = updated_at - interval '1 hour' WHERE user_id = ?", user.ID).Error; err != nil {
+			t.Fatalf("settling %s: %v", table, err)
+		}
+	}
+}
+
+func TestGetSyncChanges(t *testing.T) {
+	h := testutil.NewPostgres(t)
+	user := h.CreateUser(t)
+	account := h.CreateBankAccount(t, user, models.NewMoney(1000))
+	category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+	userID := user.ID.String()
+
+	createExpense := func() *models.Expense {
+		t.Helper()
+		expense := &models.Expense{
+			CategoryID:    category.ID,
+			BankAccountID: account.ID,
+			Amount:        models.NewMoney(10),
+			Date:          time.Now().UTC().Truncate(24 * time.Hour),
+		}
+		if err := h.Expenses.Create(context.Background(), userID, expense, false); err != nil {
+			t.Fatalf("creating expense: %v", err)
+		}
+		return expense
+	}
+	deleteExpense := func(expense *models.Expense) {
+		t.Helper()
+		if err := h.Expenses.SoftDelete(userID, expense.ID.String()); err != nil {
+			t.Fatalf("deleting expense: %v", err)
+		}
+	}
+
+	// Kept, deleted before the first sync, and deleted after it
+	kept, deletedBefore, deletedAfter := createExpense(), createExpense(), createExpense()
+	deleteExpense(deletedBefore)
+	settleSyncRows(t, h, user)
+	first, err := h.Services.GetSyncChanges(userID, "", 100)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	deleteExpense(deletedAfter)
+	created := createExpense()
+	settleSyncRows(t, h, user)
+
+	cases := []struct {
+		name           string
+		cursor         string
+		limit          int
+		wantExpenses   []uuid.UUID
+		wantTombstones []uuid.UUID
+		wantHasMore    bool
+		wantErr        string
+	}{
+		{
+			name:         "first sync leaves deleted rows out",
+			limit:        100,
+			wantExpenses: []uuid.UUID{kept.ID, created.ID},
+		},
+		{
+			name:           "pull since a cursor returns changes and tombstones",
+			cursor:         first.Cursor,
+			limit:          100,
+			wantExpenses:   []uuid.UUID{created.ID},
+			wantTombstones: []uuid.UUID{deletedAfter.ID},
+		},
+		{
+			name:         "pages by limit",
+			limit:        1,
+			wantExpenses: []uuid.UUID{kept.ID},
+			wantHasMore:  true,
+		},
+		{
+			name:    "rejects an invalid cursor",
+			cursor:  "not a cursor",
+			limit:   100,
+			wantErr: "invalid sync cursor",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := h.Services.GetSyncChanges(userID, tc.cursor, tc.limit)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pulling changes: %v", err)
+			}
+
+			var expenses, tombstones []string
+			for _, expense := range page.Expenses {
+				expenses = append(expenses, expense.ID.String())
+			}
+			for _, tombstone := range page.Tombstones {
+				if tombstone.Entity != "expense" {
+					t.Errorf("tombstone of a %s, want only expenses deleted", tombstone.Entity)
+				}
+				tombstones = append(tombstones, tombstone.ID)
+			}
+			if !sameIDs(expenses, tc.wantExpenses) {
+				t.Errorf("expenses = %v, want %v", expenses, tc.wantExpenses)
+			}
+			if !sameIDs(tombstones, tc.wantTombstones) {
+				t.Errorf("tombstones = %v, want %v", tombstones, tc.wantTombstones)
+			}
+			if page.HasMore != tc.wantHasMore || page.Cursor == "" {
+				t.Errorf("has more = %t with cursor %q, want %t with a cursor", page.HasMore, page.Cursor, tc.wantHasMore)
+			}
+		})
+	}
+}
+
+func sameIDs(got []string, want []uuid.UUID) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	wanted := make([]string, 0, len(want))
+	for _, id := range want {
+		wanted = append(wanted, id.String())
+	}
+	sort.Strings(got)
+	sort.Strings(wanted)
+	for i := range got {
+		if got[i] != wanted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApplySyncChanges(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	cases := []struct {
+		name       string
+		entity     string
+		op         string
+		newID      bool          // Push an entity the server doesn't have
+		baseOffset time.Duration // Base of the change relative to the server copy
+		strategy   string
+		wantStatus string
+		wantError  string
+		wantAmount models.Money // Of the pushed expense after the change
+	}{
+		{
+			name:       "creates a new expense",
+			entity:     "expense",
+			op:         services.SyncOpUpsert,
+			newID:      true,
+			wantStatus: services.SyncStatusApplied,
+			wantAmount: models.NewMoney(75),
+		},
+		{
+			name:       "updates from the current copy",
+			entity:     "expense",
+			op:         services.SyncOpUpsert,
+			wantStatus: services.SyncStatusApplied,
+			wantAmount: models.NewMoney(75),
+		},
+		{
+			name:       "server wins over a stale copy",
+			entity:     "expense",
+			op:         services.SyncOpUpsert,
+			baseOffset: -time.Minute,
+			wantStatus: services.SyncStatusConflict,
+			wantAmount: models.NewMoney(50),
+		},
+		{
+			name:       "client wins over a stale copy when asked",
+			entity:     "expense",
+			op:         services.SyncOpUpsert,
+			baseOffset: -time.Minute,
+			strategy:   services.SyncClientWins,
+			wantStatus: services.SyncStatusApplied,
+			wantAmount: models.NewMoney(75),
+		},
+		{
+			name:       "deleting something unknown is a no-op",
+			entity:     "expense",
+			op:         services.SyncOpDelete,
+			newID:      true,
+			wantStatus: services.SyncStatusApplied,
+		},
+		{
+			name:       "rejects an entity that can't be pushed",
+			entity:     "budget",
+			op:         services.SyncOpUpsert,
+			wantStatus: services.SyncStatusRejected,
+			wantError:  "unsupported entity",
+			wantAmount: models.NewMoney(50),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			account := h.CreateBankAccount(t, user, models.NewMoney(1000))
+			category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+			userID := user.ID.String()
+			date := time.Now().UTC().Truncate(24 * time.Hour)
+
+			server := &models.Expense{CategoryID: category.ID, BankAccountID: account.ID, Amount: models.NewMoney(50), Date: date}
+			if err := h.Expenses.Create(context.Background(), userID, server, false); err != nil {
+				t.Fatalf("creating expense: %v", err)
+			}
+			var stored models.Expense
+			if err := h.DB.First(&stored, "id = ?", server.ID).Error; err != nil {
+				t.Fatalf("loading expense: %v", err)
+			}
+
+			change := services.SyncChange{
+				Entity:  tc.entity,
+				Op:      tc.op,
+				ID:      server.ID,
+				Expense: &models.Expense{CategoryID: category.ID, BankAccountID: account.ID, Amount: models.NewMoney(75), Date: date},
+			}
+			if tc.newID {
+				change.ID = uuid.New()
+			} else {
+				base := stored.UpdatedAt.Add(tc.baseOffset)
+				change.BaseUpdatedAt = &base
+			}
+
+			results := h.Services.ApplySyncChanges(context.Background(), userID, []services.SyncChange{change},
+				map[string]string{tc.entity: tc.strategy})
+			if len(results) != 1 {
+				t.Fatalf("%d results, want 1", len(results))
+			}
+			result := results[0]
+			if result.Status != tc.wantStatus || result.Error != tc.wantError || result.ID != change.ID.String() {
+				t.Fatalf("result %+v, want %s for %s with error %q", result, tc.wantStatus, change.ID, tc.wantError)
+			}
+			if result.Status == services.SyncStatusConflict &&
+				(result.ServerUpdatedAt == nil || !result.ServerUpdatedAt.Equal(stored.UpdatedAt)) {
+				t.Errorf("conflict with %v, want the server copy's %s", result.ServerUpdatedAt, stored.UpdatedAt)
+			}
+			if tc.wantAmount == 0 {
+				return
+			}
+
+			got, err := h.Expenses.GetByID(userID, change.ID.String())
+			if err != nil {
+				t.Fatalf("getting the pushed expense: %v", err)
+			}
+			if got.Amount != tc.wantAmount {
+				t.Errorf("amount = %s, want %s", got.Amount, tc.wantAmount)
+			}
+		})
+	}
+}
+
+func TestSyncCursorAfterPurge(t *testing.T) {
+	h := testutil.NewPostgres(t)
+
+	cases := []struct {
+		name string
+		// Steps around the pull that gives the cursor: the deletion, then the removal for good
+		deleteBeforePull bool
+		hardDelete       bool
+		wantErr          error
+	}{
+		{
+			name:             "cursor that pulled the tombstone survives the purge",
+			deleteBeforePull: true,
+		},
+		{
+			name:    "cursor that missed the deletion expires with the purge",
+			wantErr: services.ErrSyncCursorExpired,
+		},
+		{
+			name:       "cursor holding a hard deleted row expires",
+			hardDelete: true,
+			wantErr:    services.ErrSyncCursorExpired,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := h.CreateUser(t)
+			account := h.CreateBankAccount(t, user, models.NewMoney(1000))
+			category := h.CreateCategory(t, user, "Groceries", models.ExpenseTypeNeeds)
+			userID := user.ID.String()
+
+			expense := &models.Expense{
+				CategoryID:    category.ID,
+				BankAccountID: account.ID,
+				Amount:        models.NewMoney(10),
+				Date:          time.Now().UTC().Truncate(24 * time.Hour),
+			}
+			if err := h.Expenses.Create(context.Background(), userID, expense, false); err != nil {
+				t.Fatalf("creating expense: %v", err)
+			}
+			settleSyncRows(t, h, user)
+			first, err := h.Services.GetSyncChanges(userID, "", 100)
+			if err != nil {
+				t.Fatalf("first sync: %v", err)
+			}
+
+			deleteExpense := func() {
+				t.Helper()
+				if err := h.Expenses.SoftDelete(userID, expense.ID.String()); err != nil {
+					t.Fatalf("deleting expense: %v", err)
+				}
+				settleSyncRows(t, h, user)
+			}
+			cursor := first.Cursor
+			if tc.deleteBeforePull {
+				deleteExpense()
+				page, err := h.Services.GetSyncChanges(userID, cursor, 100)
+				if err != nil {
+					t.Fatalf("pulling the deletion: %v", err)
+				}
+				if len(page.Tombstones) != 1 {
+					t.Fatalf("%d tombstones, want the deleted expense's", len(page.Tombstones))
+				}
+				cursor = page.Cursor
+			}
+
+			if tc.hardDelete {
+				if err := h.Expenses.HardDelete(userID, expense.ID.String()); err != nil {
+					t.Fatalf("hard deleting expense: %v", err)
+				}
+			} else {
+				if !tc.deleteBeforePull {
+					deleteExpense()
+				}
+				if purged, err := h.Services.EmptyTrash(userID, "expenses"); err != nil || purged != 1 {
+					t.Fatalf("emptying the trash purged %d: %v", purged, err)
+				}
+			}
+
+			_, err = h.Services.GetSyncChanges(userID, cursor, 100)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("pulling after the purge = %v, want %v", err, tc.wantErr)
+			}
+			if page, err := h.Services.GetSyncChanges(userID, "", 100); err != nil || len(page.Expenses) != 0 {
+				t.Fatalf("full resync returned %v (%v), want no expenses", page, err)
+			}
+		})
+	}
+}
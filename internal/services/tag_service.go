@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTagExists = errors.New("a tag with this name already exists")
+
+// MaxTagsPerRecord is how many tags one expense or income can carry
+const MaxTagsPerRecord = 20
+
+// TagFilter narrows a listing to the records carrying the given tags; without names it doesn't filter
+type TagFilter struct {
+	Names    []string
+	MatchAll bool // Every tag is required instead of any of them
+}
+
+// normalizeTagName trims, collapses the spaces and lowercases a name, so "Vacation " and
+// "vacation" are the same tag
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		return "", errors.New("invalid tag: the name is required")
+	}
+	if utf8.RuneCountInString(name) > models.MaxTagNameLength {
+		return "", fmt.Errorf("invalid tag %q: at most %d characters", name, models.MaxTagNameLength)
+	}
+	return name, nil
+}
+
+// normalizeTagNames normalizes the names and drops the repeated ones, keeping their order
+func normalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
+
+// tagNames returns the names of the tags
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// resolveTags returns the tags of the user with the given names, creating the missing ones
+func resolveTags(tx *gorm.DB, userID string, names []string) ([]models.Tag, error) {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > MaxTagsPerRecord {
+		return nil, fmt.Errorf("invalid tags: at most %d per record", MaxTagsPerRecord)
+	}
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	uid := uuid.MustParse(userID)
+	missing := make([]models.Tag, len(names))
+	for i, name := range names {
+		missing[i] = models.Tag{UserID: uid, Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoNothing: true,
+	}).Create(&missing).Error; err != nil {
+		logger.Error("Error creating tags: %v", err)
+		return nil, err
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("user_id = ? AND name IN ?", uid, names).Order("name").Find(&tags).Error; err != nil {
+		logger.Error("Error getting tags: %v", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+// replaceTags sets the tags of an expense or income to the named ones; no names clears them
+func replaceTags(tx *gorm.DB, userID string, owner interface{}, tags []models.Tag) error {
+	resolved, err := resolveTags(tx, userID, tagNames(tags))
+	if err != nil {
+		return err
+	}
+	// Links go through join tables the summary cache plugin doesn't watch
+	db.AfterCommit(tx, func() { InvalidateSummaryCache(userID) })
+	if len(resolved) == 0 {
+		return tx.Model(owner).Association("Tags").Clear()
+	}
+	return tx.Model(owner).Association("Tags").Replace(resolved)
+}
+
+// filterByTags keeps the records of the query carrying the tags of the filter. joinTable links
+// them to their tags through column
+func filterByTags(query *gorm.DB, userID string, joinTable, column string, filter TagFilter) (*gorm.DB, error) {
+	if len(filter.Names) == 0 {
+		return query, nil
+	}
+	names, err := normalizeTagNames(filter.Names)
+	if err != nil {
+		return nil, err
+	}
+	required := 1
+	if filter.MatchAll {
+		required = len(names)
+	}
+	return query.Where("id IN (SELECT jt."+column+" FROM "+joinTable+" jt JOIN tags t ON t.id = jt.tag_id"+
+		" WHERE t.user_id = ? AND t.name IN ? GROUP BY jt."+column+" HAVING COUNT(*) >= ?)", userID, names, required), nil
+}
+
+// validateTagColor checks the optional #RRGGBB color of a tag
+func validateTagColor(color *string) error {
+	if color != nil && *color != "" && !models.IsValidCategoryColor(*color) {
+		return errors.New("invalid color: " + *color + ". Must be #RRGGBB")
+	}
+	return nil
+}
+
+// tagNameTaken reports whether the user already has another tag with the name
+func (s *Services) tagNameTaken(userID string, name string, excludeID *uuid.UUID) (bool, error) {
+	query := s.db.Model(&models.Tag{}).Where("user_id = ? AND name = ?", userID, name)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// tagUsageQuery selects the tags of the user with how many visible expenses and incomes carry them
+func (s *Services) tagUsageQuery(userID string) *gorm.DB {
+	return s.db.Table("tags t").
+		Select(`t.id, t.name, t.color, t.created_at,
+		(SELECT COUNT(*) FROM expense_tags et JOIN expenses e ON e.id = et.expense_id
+			WHERE et.tag_id = t.id AND e.status IN ?) as expense_count,
+		(SELECT COUNT(*) FROM income_tags it JOIN incomes i ON i.id = it.income_id
+			WHERE it.tag_id = t.id AND i.status IN ?) as income_count`,
+			models.GetVisibleStatuses(), models.GetVisibleStatuses()).
+		Where("t.user_id = ?", userID)
+}
+
+// GetTags gets a page of the tags of the user by name with their usage; prefix narrows them for
+// autocomplete
+func (s *Services) GetTags(userID string, prefix string, page PageRequest) ([]dto.Tag, PageInfo, error) {
+	query := s.tagUsageQuery(userID)
+	if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+		query = query.Where("LEFT(t.name, ?) = ?", utf8.RuneCountInString(prefix), prefix)
+	}
+	tags := []dto.Tag{}
+	info, err := paginate(query, "t.name, t.id", page, &tags)
+	if err != nil {
+		logger.Error("Error getting tags: %v", err)
+		return nil, PageInfo{}, errors.New("error getting tags")
+	}
+	return tags, info, nil
+}
+
+// GetTag returns a tag of the user with its usage
+func (s *Services) GetTag(userID string, tagID string) (*dto.Tag, error) {
+	if _, err := uuid.Parse(tagID); err != nil {
+		return nil, errors.New("tag not found")
+	}
+	var tags []dto.Tag
+	if err := s.tagUsageQuery(userID).Where("t.id = ?", tagID).Scan(&tags).Error; err != nil {
+		logger.Error("Error getting tag: %v", err)
+		return nil, errors.New("error getting tag")
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("tag not found")
+	}
+	return &tags[0], nil
+}
+
+// CreateTag creates a tag ahead of using it; tags are also created on the fly when an expense
+// or income names them
+func (s *Services) CreateTag(userID string, name string, color *string) (*dto.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTagColor(color); err != nil {
+		return nil, err
+	}
+	if color != nil && *color == "" {
+		color = nil
+	}
+	taken, err := s.tagNameTaken(userID, name, nil)
+	if err != nil {
+		logger.Error("Error checking tag name: %v", err)
+		return nil, errors.New("error creating tag")
+	}
+	if taken {
+		return nil, ErrTagExists
+	}
+
+	tag := models.Tag{UserID: uuid.MustParse(userID), Name: name, Color: color}
+	if err := s.db.Create(&tag).Error; err != nil {
+		logger.Error("Error creating tag: %v", err)
+		return nil, errors.New("error creating tag")
+	}
+
+	logger.Info("Tag %s created for user %s", tag.ID, userID)
+	return s.GetTag(userID, tag.ID.String())
+}
+
+// UpdateTag renames a tag or changes its color; an empty color removes it. Renaming keeps the
+// tag on its expenses and incomes
+func (s *Services) UpdateTag(userID string, tagID string, name *string, color *string) (*dto.Tag, error) {
+	var tag models.Tag
+	if _, err := uuid.Parse(tagID); err != nil {
+		return nil, errors.New("tag not found")
+	}
+	if err := s.db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		return nil, errors.New("tag not found")
+	}
+
+	updates := map[string]interface{}{}
+	if name != nil {
+		normalized, err := normalizeTagName(*name)
+		if err != nil {
+			return nil, err
+		}
+		taken, err := s.tagNameTaken(userID, normalized, &tag.ID)
+		if err != nil {
+			logger.Error("Error checking tag name: %v", err)
+			return nil, errors.New("error updating tag")
+		}
+		if taken {
+			return nil, ErrTagExists
+		}
+		updates["name"] = normalized
+	}
+	if color != nil {
+		if err := validateTagColor(color); err != nil {
+			return nil, err
+		}
+		if *color == "" {
+			updates["color"] = nil
+		} else {
+			updates["color"] = *color
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&tag).Updates(updates).Error; err != nil {
+			logger.Error("Error updating tag: %v", err)
+			return nil, errors.New("error updating tag")
+		}
+	}
+	return s.GetTag(userID, tagID)
+}
+
+// DeleteTag deletes a tag; the join tables cascade, so it comes off every expense and income
+// while the records are kept
+func (s *Services) DeleteTag(userID string, tagID string) error {
+	if _, err := uuid.Parse(tagID); err != nil {
+		return errors.New("tag not found")
+	}
+	result := s.db.Where("id = ? AND user_id = ?", tagID, userID).Delete(&models.Tag{})
+	if result.Error != nil {
+		logger.Error("Error deleting tag: %v", result.Error)
+		return errors.New("error deleting tag")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("tag not found")
+	}
+
+	logger.Info("Tag %s deleted for user %s", tagID, userID)
+	return nil
+}
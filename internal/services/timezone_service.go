@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+)
+
+// userLocations caches the timezone of each user. SetUserTimezone invalidates it on every
+// instance
+var userLocations = newUserCache[*time.Location]()
+
+// UserLocation returns the timezone the user's days and months follow, UTC if none is set
+func (s *Services) UserLocation(userID string) *time.Location {
+	return userLocations.get(userID, func() (*time.Location, bool) {
+		var preferences models.UserPreferences
+		err := s.db.Select("user_id", "timezone").Where("user_id = ?", userID).Take(&preferences).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			// Don't cache failures, the next call may succeed
+			return time.UTC, false
+		}
+		if loaded, err := time.LoadLocation(preferences.Timezone); err == nil && preferences.Timezone != "" {
+			return loaded, true
+		}
+		return time.UTC, true
+	})
+}
+
+// UserToday returns the user's current date at midnight UTC, the form date columns are stored
+// and compared in. Near midnight it differs from the UTC date
+func (s *Services) UserToday(userID string) time.Time {
+	return dateOf(s.UserNow(userID))
+}
+
+// dateOf drops the time of day of t, keeping the date on the calendar of t's location
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// GetTimezoneSettings returns the user's timezone and what time and date it is there
+func (s *Services) GetTimezoneSettings(userID string) (*dto.TimezoneSettings, error) {
+	now := s.UserNow(userID)
+	return &dto.TimezoneSettings{
+		Timezone:  now.Location().String(),
+		UTCOffset: now.Format("-07:00"),
+		LocalTime: now.Format(time.RFC3339),
+		Today:     now.Format("2006-01-02"),
+	}, nil
+}
+
+// SetUserTimezone sets the IANA timezone of the user; empty resets it to UTC. Monthly totals,
+// budgets and due dates follow it from then on; stored dates are left as they are
+func (s *Services) SetUserTimezone(userID string, timezone string) (*dto.TimezoneSettings, error) {
+	timezone, err := validTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	preferences, err := s.getUserPreferences(userID)
+	if err != nil {
+		return nil, errors.New("error updating timezone")
+	}
+	preferences.Timezone = timezone
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return storeUserTimezone(tx, preferences)
+	}); err != nil {
+		logger.Error("Error saving timezone: %v", err)
+		return nil, errors.New("error updating timezone")
+	}
+	return s.GetTimezoneSettings(userID)
+}
+
+// validTimezone trims an IANA timezone and checks it exists; empty stands for UTC
+func validTimezone(timezone string) (string, error) {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" {
+		return "", nil
+	}
+	// "Local" is the server's timezone, not a place
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		return "", errors.New("invalid timezone: use an IANA name such as America/Mexico_City")
+	}
+	return timezone, nil
+}
+
+// storeUserTimezone saves the timezone of the preferences within tx and, once it commits, drops
+// the cached one on every instance and the summaries computed in the old one
+func storeUserTimezone(tx *gorm.DB, preferences *models.UserPreferences) error {
+	userID := preferences.UserID.String()
+	if err := storeUserPreferences(tx, preferences, "timezone"); err != nil {
+		return err
+	}
+	db.AfterCommit(tx, func() { InvalidateSummaryCache(userID) })
+	return invalidateUserCache(tx, "timezone", userID)
+}
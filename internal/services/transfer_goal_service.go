@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GoalFundingTransfer marks the contributions made by transfers linked to a goal
+const GoalFundingTransfer = "transfer"
+
+// applyTransferGoalContribution adds a transfer to the goal it is linked to: the saved amount
+// grows and the contribution ledger records it, using the caller's transaction
+func applyTransferGoalContribution(tx *gorm.DB, transfer *models.Transfer) error {
+	if transfer.GoalID == nil {
+		return nil
+	}
+	var goal models.Goal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ? AND status = ?", *transfer.GoalID, transfer.UserID, models.StatusActive).
+		First(&goal).Error; err != nil {
+		return errors.New("goal not found or not active")
+	}
+
+	goal.SavedAmount += transfer.Amount
+	if err := tx.Model(&goal).Update("saved_amount", goal.SavedAmount).Error; err != nil {
+		return err
+	}
+	contribution := newGoalContribution(&goal, transfer.Amount, GoalFundingTransfer, transfer.Date)
+	contribution.TransferID = &transfer.ID
+	if err := tx.Create(&contribution).Error; err != nil {
+		return err
+	}
+	if err := checkGoalMilestones(tx, &goal); err != nil {
+		return err
+	}
+	return EnqueueEvent(tx, goal.UserID, EventGoalFunded, "goal", goal.ID, map[string]interface{}{
+		"goal_id":      goal.ID,
+		"goal_name":    goal.Name,
+		"source":       GoalFundingTransfer,
+		"transfer_id":  transfer.ID,
+		"amount":       transfer.Amount,
+		"saved_amount": goal.SavedAmount,
+		"total_amount": goal.TotalAmount,
+		"completed":    goal.SavedAmount >= goal.TotalAmount,
+	})
+}
+
+// reverseTransferGoalContribution takes the contribution of a transfer back out of its goal and
+// the ledger, using the caller's transaction. Milestones already reached keep their date
+func reverseTransferGoalContribution(tx *gorm.DB, transfer *models.Transfer) error {
+	var contributions []models.GoalContribution
+	if err := tx.Where("transfer_id = ?", transfer.ID).Find(&contributions).Error; err != nil {
+		return err
+	}
+	for _, contribution := range contributions {
+		if err := tx.Model(&models.Goal{}).Where("id = ?", contribution.GoalID).
+			Update("saved_amount", gorm.Expr("GREATEST(saved_amount - ?, 0)", contribution.Amount)).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&contribution).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTransferGoal links a transfer to a goal, which counts it as a contribution, or unlinks it
+// with a nil goalID, which takes the contribution back
+func (s *Services) SetTransferGoal(userID string, id string, goalID *string) (*models.Transfer, error) {
+	var newGoalID *uuid.UUID
+	if goalID != nil {
+		parsed, err := uuid.Parse(*goalID)
+		if err != nil {
+			return nil, errors.New("invalid goal_id")
+		}
+		newGoalID = &parsed
+	}
+
+	var transfer models.Transfer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusActive).
+			First(&transfer).Error; err != nil {
+			return errors.New("transfer not found or access denied")
+		}
+		if transfer.GoalID == nil && newGoalID == nil ||
+			transfer.GoalID != nil && newGoalID != nil && *transfer.GoalID == *newGoalID {
+			return nil
+		}
+
+		if err := reverseTransferGoalContribution(tx, &transfer); err != nil {
+			return err
+		}
+		transfer.GoalID = newGoalID
+		if err := tx.Model(&transfer).Update("goal_id", newGoalID).Error; err != nil {
+			return err
+		}
+		return applyTransferGoalContribution(tx, &transfer)
+	})
+	if err != nil {
+		logger.Error("Error linking transfer to goal: %v", err)
+		return nil, err
+	}
+
+	logger.Info("Transfer %s goal link updated", id)
+	return s.GetTransferByID(userID, id)
+}
+
+// DeleteTransfer soft deletes a transfer, moving the money back between the accounts and taking
+// back its goal contribution
+func (s *Services) DeleteTransfer(userID string, id string) error {
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transfer models.Transfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusActive).
+			First(&transfer).Error; err != nil {
+			return errors.New("transfer not found or already deleted")
+		}
+		if err := tx.Model(&transfer).Updates(map[string]interface{}{
+			"status":            models.StatusDeleted,
+			"status_changed_at": &now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := adjustAccountBalance(tx, transfer.FromAccountID, transfer.Amount); err != nil {
+			return err
+		}
+		if err := adjustAccountBalance(tx, transfer.ToAccountID, -transfer.Amount); err != nil {
+			return err
+		}
+		return reverseTransferGoalContribution(tx, &transfer)
+	})
+	if err != nil {
+		logger.Error("Error deleting transfer: %v", err)
+		return err
+	}
+
+	logger.Info("Transfer soft deleted successfully: %s", id)
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventTransferCompleted is emitted when a transfer has moved the money between accounts
+const EventTransferCompleted = "transfer.completed"
+
+// DuplicateTransferError is returned when a transfer looks like a double submission and
+// the caller didn't confirm it
+type DuplicateTransferError struct {
+	Duplicates []models.Transfer
+}
+
+func (e *DuplicateTransferError) Error() string {
+	return fmt.Sprintf("possible duplicate transfer (%d similar found)", len(e.Duplicates))
+}
+
+// transferDuplicateWindow is how recent a similar transfer must be to count as a duplicate
+func transferDuplicateWindow() time.Duration {
+	return time.Duration(envInt("TRANSFER_DUPLICATE_WINDOW_MINUTES", 10)) * time.Minute
+}
+
+// findDuplicateTransfers returns recent transfers with the same accounts, amount and date
+func (s *Services) findDuplicateTransfers(userID string, transfer *models.Transfer) ([]models.Transfer, error) {
+	var duplicates []models.Transfer
+	result := s.db.Where("user_id = ? AND from_account_id = ? AND to_account_id = ? AND amount = ? AND date = ? AND status IN ? AND created_at >= ?",
+		userID, transfer.FromAccountID, transfer.ToAccountID, transfer.Amount, transfer.Date,
+		models.GetActiveStatuses(), time.Now().Add(-transferDuplicateWindow())).
+		Order("created_at DESC").Find(&duplicates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return duplicates, nil
+}
+
+// CreateTransfer moves money between two accounts of the user. A transfer that looks like a
+// double submission is rejected with *DuplicateTransferError unless confirmDuplicate is set;
+// confirmed duplicates are recorded in the audit log for review. A transfer with a GoalID
+// also counts as a contribution to that goal.
+func (s *Services) CreateTransfer(userID string, transfer *models.Transfer, confirmDuplicate bool) error {
+	// Force the UserID and Status to prevent manipulation
+	transfer.UserID = uuid.MustParse(userID)
+	transfer.Status = models.StatusActive
+
+	if transfer.Amount <= 0 {
+		return errors.New("transfer amount must be positive")
+	}
+	if transfer.FromAccountID == transfer.ToAccountID {
+		return errors.New("source and destination accounts must be different")
+	}
+
+	var accounts []models.BankAccount
+	result := s.db.Where("id IN ? AND user_id = ? AND status IN ?",
+		[]uuid.UUID{transfer.FromAccountID, transfer.ToAccountID}, userID, models.GetActiveStatuses()).Find(&accounts)
+	if result.Error != nil || len(accounts) != 2 {
+		logger.Error("Transfer accounts not found, not active, or don't belong to user")
+		return errors.New("bank account not found, not active, or access denied")
+	}
+
+	duplicates, err := s.findDuplicateTransfers(userID, transfer)
+	if err != nil {
+		logger.Error("Error checking duplicate transfers: %v", err)
+		return err
+	}
+	if len(duplicates) > 0 && !confirmDuplicate {
+		logger.Warn("Possible duplicate transfer for user %s", userID)
+		return &DuplicateTransferError{Duplicates: duplicates}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+		if err := spendConfirmToken(tx, transfer.UserID, transfer.ConfirmTokenID); err != nil {
+			return err
+		}
+		if err := adjustAccountBalance(tx, transfer.FromAccountID, -transfer.Amount); err != nil {
+			return err
+		}
+		if err := adjustAccountBalance(tx, transfer.ToAccountID, transfer.Amount); err != nil {
+			return err
+		}
+		if err := applyTransferGoalContribution(tx, transfer); err != nil {
+			return err
+		}
+		return EnqueueEvent(tx, transfer.UserID, EventTransferCompleted, "transfer", transfer.ID, map[string]interface{}{
+			"from_account_id": transfer.FromAccountID,
+			"to_account_id":   transfer.ToAccountID,
+			"amount":          transfer.Amount,
+			"date":            transfer.Date.Format("2006-01-02"),
+		})
+	})
+	if err != nil {
+		logger.Error("Error creating transfer: %v", err)
+		return err
+	}
+
+	if len(duplicates) > 0 {
+		s.RecordAudit(transfer.UserID, "transfer.duplicate_confirmed", "transfer", &transfer.ID, map[string]interface{}{
+			"similar_transfer_id": duplicates[0].ID,
+			"amount":              transfer.Amount,
+		})
+	}
+
+	logger.Info("Transfer created successfully: %s", transfer.ID)
+	return nil
+}
+
+// GetTransferByID gets a specific transfer of the user
+func (s *Services) GetTransferByID(userID string, id string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
+		Preload("FromAccount").Preload("ToAccount").First(&transfer)
+	if result.Error != nil {
+		return nil, errors.New("transfer not found or access denied")
+	}
+
+	return &transfer, nil
+}
+
+// GetAllTransfers gets the transfers of the user, newest first
+func (s *Services) GetAllTransfers(userID string, page PageRequest) ([]models.Transfer, PageInfo, error) {
+	var transfers []models.Transfer
+	query := s.db.Model(&models.Transfer{}).Where("user_id = ? AND status IN ?", userID, models.GetVisibleStatuses())
+	info, err := paginate(query, "date DESC, created_at DESC, id", page, &transfers)
+	if err != nil {
+		logger.Error("Error getting transfers: %v", err)
+		return nil, PageInfo{}, err
+	}
+
+	return transfers, info, nil
+}
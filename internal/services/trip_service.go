@@ -0,0 +1,331 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TripUpdate holds the fields of a trip to change; nil fields are left as they are
+type TripUpdate struct {
+	Name              *string
+	StartDate         *time.Time
+	EndDate           *time.Time
+	Budget            *models.Money // Zero removes the budget
+	Currency          *string
+	ExcludeFromBudget *bool
+}
+
+// tripExpensesSQL selects the expenses of a trip: those assigned to it plus the unassigned
+// ones dated inside its window. Overlapping trips share the unassigned expenses
+const tripExpensesSQL = "(e.trip_id = ? OR (e.trip_id IS NULL AND e.date BETWEEN ? AND ?))"
+
+// excludedTripExpenseSQL matches expenses that belong to a trip left out of budget compliance
+const excludedTripExpenseSQL = `EXISTS (SELECT 1 FROM trips t WHERE t.user_id = e.user_id AND t.exclude_from_budget
+	AND (t.id = e.trip_id OR (e.trip_id IS NULL AND e.date BETWEEN t.start_date AND t.end_date)))`
+
+func (s *Services) validateTrip(trip *models.Trip) error {
+	trip.Name = strings.TrimSpace(trip.Name)
+	if trip.Name == "" {
+		return errors.New("trip name is required")
+	}
+	if trip.EndDate.Before(trip.StartDate) {
+		return errors.New("invalid trip dates: end date must not be before start date")
+	}
+	if trip.Budget != nil && *trip.Budget <= 0 {
+		return errors.New("trip budget must be greater than 0")
+	}
+	currency, err := s.GetCurrency(trip.Currency)
+	if err != nil {
+		return err
+	}
+	trip.Currency = currency.Code
+	return nil
+}
+
+// CreateTrip creates a trip; without a currency it uses the user's
+func (s *Services) CreateTrip(userID string, trip models.Trip) (*models.Trip, error) {
+	trip.ID = uuid.Nil
+	trip.UserID = uuid.MustParse(userID)
+	if trip.Currency == "" {
+		trip.Currency = s.GetUserCurrency(userID).Code
+	}
+	if err := s.validateTrip(&trip); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&trip).Error; err != nil {
+		logger.Error("Error creating trip: %v", err)
+		return nil, errors.New("error creating trip")
+	}
+
+	logger.Info("Trip %s created for user %s", trip.ID, userID)
+	return &trip, nil
+}
+
+// GetTrips lists the trips of the user, most recent first
+func (s *Services) GetTrips(userID string) ([]models.Trip, error) {
+	var trips []models.Trip
+	if err := s.db.Where("user_id = ?", userID).Order("start_date DESC").Find(&trips).Error; err != nil {
+		logger.Error("Error getting trips: %v", err)
+		return nil, errors.New("error getting trips")
+	}
+	return trips, nil
+}
+
+// GetTrip returns a trip of the user
+func (s *Services) GetTrip(userID string, tripID string) (*models.Trip, error) {
+	var trip models.Trip
+	if err := s.db.Where("id = ? AND user_id = ?", tripID, userID).First(&trip).Error; err != nil {
+		return nil, errors.New("trip not found")
+	}
+	return &trip, nil
+}
+
+// UpdateTrip changes a trip
+func (s *Services) UpdateTrip(userID string, tripID string, updates TripUpdate) (*models.Trip, error) {
+	trip, err := s.GetTrip(userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if updates.Name != nil {
+		trip.Name = *updates.Name
+	}
+	if updates.StartDate != nil {
+		trip.StartDate = *updates.StartDate
+	}
+	if updates.EndDate != nil {
+		trip.EndDate = *updates.EndDate
+	}
+	if updates.Budget != nil {
+		trip.Budget = updates.Budget
+		if *updates.Budget == 0 {
+			trip.Budget = nil
+		}
+	}
+	if updates.Currency != nil {
+		trip.Currency = *updates.Currency
+	}
+	if updates.ExcludeFromBudget != nil {
+		trip.ExcludeFromBudget = *updates.ExcludeFromBudget
+	}
+	if err := s.validateTrip(trip); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(trip).Select("name", "start_date", "end_date", "budget", "currency", "exclude_from_budget").
+		Updates(trip).Error; err != nil {
+		logger.Error("Error updating trip: %v", err)
+		return nil, errors.New("error updating trip")
+	}
+	return trip, nil
+}
+
+// DeleteTrip removes a trip. Its expenses are kept and lose the manual assignment
+func (s *Services) DeleteTrip(userID string, tripID string) error {
+	trip, err := s.GetTrip(userID, tripID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(trip).Error; err != nil { // trip_id is set to NULL by the foreign key
+		logger.Error("Error deleting trip: %v", err)
+		return errors.New("error deleting trip")
+	}
+	logger.Info("Trip %s deleted", tripID)
+	return nil
+}
+
+// AssignExpensesToTrip ties expenses to a trip, whatever their date. An expense belongs to
+// one trip at most, so this moves it away from any other trip
+func (s *Services) AssignExpensesToTrip(userID string, tripID string, expenseIDs []string) (int64, error) {
+	trip, err := s.GetTrip(userID, tripID)
+	if err != nil {
+		return 0, err
+	}
+	if len(expenseIDs) == 0 {
+		return 0, errors.New("at least one expense ID is required")
+	}
+	ids := make([]uuid.UUID, 0, len(expenseIDs))
+	for _, raw := range expenseIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return 0, errors.New("invalid expense ID: " + raw)
+		}
+		ids = append(ids, id)
+	}
+	ids = uniqueUUIDs(ids)
+
+	var assigned int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Expense{}).Where("id IN ? AND user_id = ? AND status IN ?", ids, userID, models.GetVisibleStatuses()).
+			Update("trip_id", trip.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if int(result.RowsAffected) != len(ids) {
+			return errors.New("expense not found or access denied")
+		}
+		assigned = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return 0, err
+		}
+		logger.Error("Error assigning expenses to trip: %v", err)
+		return 0, errors.New("error assigning expenses to trip")
+	}
+	return assigned, nil
+}
+
+// UnassignExpenseFromTrip removes the manual assignment of an expense. If it is dated inside
+// the trip window it still counts for the trip
+func (s *Services) UnassignExpenseFromTrip(userID string, tripID string, expenseID string) error {
+	trip, err := s.GetTrip(userID, tripID)
+	if err != nil {
+		return err
+	}
+	result := s.db.Model(&models.Expense{}).Where("id = ? AND user_id = ? AND trip_id = ?", expenseID, userID, trip.ID).
+		Update("trip_id", nil)
+	if result.Error != nil {
+		logger.Error("Error unassigning expense from trip: %v", result.Error)
+		return errors.New("error unassigning expense from trip")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("expense not found in trip")
+	}
+	return nil
+}
+
+// tripExpensesQuery starts a query over the active expenses of a trip
+func (s *Services) tripExpensesQuery(trip *models.Trip) *gorm.DB {
+	return s.db.Table("expenses e").Scopes(joinExpenseRefunds).
+		Where("e.user_id = ? AND e.status IN ?", trip.UserID, models.GetActiveStatuses()).
+		Where(tripExpensesSQL, trip.ID, trip.StartDate, trip.EndDate)
+}
+
+// GetTripSummary returns the spend of a trip against its budget with a day by day breakdown
+func (s *Services) GetTripSummary(userID string, tripID string) (*dto.TripSummary, error) {
+	trip, err := s.GetTrip(userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := s.GetCurrency(trip.Currency)
+	if err != nil {
+		currency = s.GetUserCurrency(userID)
+	}
+
+	var days []struct {
+		Day    string
+		Amount models.Money
+		Count  int64
+	}
+	if err := s.tripExpensesQuery(trip).
+		Select("TO_CHAR(e.date, 'YYYY-MM-DD') AS day, COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) AS amount, COUNT(*) AS count").
+		Group("e.date").Scan(&days).Error; err != nil {
+		logger.Error("Error getting trip spend by day: %v", err)
+		return nil, errors.New("error getting trip summary")
+	}
+
+	var categories []struct {
+		CategoryID   string
+		CategoryName string
+		Amount       models.Money
+	}
+	if err := s.tripExpensesQuery(trip).Joins("JOIN categories c ON e.category_id = c.id").
+		Select("c.id::text AS category_id, c.name AS category_name, COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) AS amount").
+		Group("c.id, c.name").Order("amount DESC").Scan(&categories).Error; err != nil {
+		logger.Error("Error getting trip spend by category: %v", err)
+		return nil, errors.New("error getting trip summary")
+	}
+
+	tripDays := int(trip.EndDate.Sub(trip.StartDate).Hours()/24) + 1
+	summary := &dto.TripSummary{
+		TripID:            trip.ID.String(),
+		Name:              trip.Name,
+		StartDate:         trip.StartDate.Format("2006-01-02"),
+		EndDate:           trip.EndDate.Format("2006-01-02"),
+		Days:              tripDays,
+		Currency:          currency.Code,
+		ExcludeFromBudget: trip.ExcludeFromBudget,
+		ByDay:             make([]dto.TripDaySpend, 0, tripDays),
+		ByCategory:        make([]dto.TripCategorySpend, 0, len(categories)),
+	}
+
+	// Every day of the window is listed, with or without spending
+	byDate := make(map[string]*dto.TripDaySpend, tripDays)
+	for day := trip.StartDate; !day.After(trip.EndDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		summary.ByDay = append(summary.ByDay, dto.TripDaySpend{Date: date, InWindow: true})
+	}
+	for i := range summary.ByDay {
+		byDate[summary.ByDay[i].Date] = &summary.ByDay[i]
+	}
+	for _, row := range days {
+		amount := currency.RoundMoney(row.Amount)
+		summary.Spent += amount
+		summary.ExpenseCount += row.Count
+		if day, ok := byDate[row.Day]; ok {
+			day.Amount = amount
+			day.ExpenseCount = row.Count
+			continue
+		}
+		summary.ByDay = append(summary.ByDay, dto.TripDaySpend{Date: row.Day, Amount: amount, ExpenseCount: row.Count})
+	}
+	sort.Slice(summary.ByDay, func(i, j int) bool { return summary.ByDay[i].Date < summary.ByDay[j].Date })
+
+	for _, row := range categories {
+		summary.ByCategory = append(summary.ByCategory, dto.TripCategorySpend{
+			CategoryID:   row.CategoryID,
+			CategoryName: row.CategoryName,
+			Amount:       currency.RoundMoney(row.Amount),
+		})
+	}
+
+	summary.Spent = currency.RoundMoney(summary.Spent)
+	summary.DailyAverage = currency.RoundMoney(summary.Spent.MulRatio(1 / float64(tripDays)))
+	if trip.Budget != nil {
+		budget := *trip.Budget
+		remaining := currency.RoundMoney(budget - summary.Spent)
+		usage := math.Round(summary.Spent.Ratio(budget)*10000) / 100
+		daily := currency.RoundMoney(budget.MulRatio(1 / float64(tripDays)))
+		summary.Budget = &budget
+		summary.Remaining = &remaining
+		summary.UsagePercent = &usage
+		summary.DailyBudget = &daily
+	}
+	return summary, nil
+}
+
+// excludedTripSpendByType returns what the user spent between two dates on trips excluded
+// from budget compliance, keyed by expense type name like GetExpensesByExpenseType
+func (s *Services) excludedTripSpendByType(userID string, startDate, endDate time.Time) (map[string]models.Money, error) {
+	var rows []struct {
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+	}
+	if err := s.db.Table("expenses e").
+		Select("c.expense_type AS expense_type, COALESCE(SUM("+netExpenseAmountSQL()+"), 0) AS amount").
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Scopes(joinExpenseRefunds).
+		Where("e.user_id = ? AND e.date BETWEEN ? AND ? AND e.status IN ?", userID, startDate, endDate, models.GetActiveStatuses()).
+		Where(excludedTripExpenseSQL).
+		Group("c.expense_type").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]models.Money, len(rows))
+	for _, row := range rows {
+		spent[models.GetExpenseTypeName(row.ExpenseType)] = row.Amount
+	}
+	return spent, nil
+}
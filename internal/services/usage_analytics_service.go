@@ -0,0 +1,244 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// usageKey identifies one user hitting one endpoint on one day. It only lives in memory
+// until the next flush; user IDs never reach the analytics tables.
+type usageKey struct {
+	day      string
+	userID   string
+	method   string
+	endpoint string
+	feature  string
+}
+
+// usageRecorder buffers usage events in memory so requests never wait on the analytics store
+type usageRecorder struct {
+	mu     sync.Mutex
+	counts map[usageKey]int64
+	// seenFeatures tracks which users already counted as active for a feature today
+	seenFeatures map[string]bool
+	seenDay      string
+}
+
+var usage = &usageRecorder{
+	counts:       make(map[usageKey]int64),
+	seenFeatures: make(map[string]bool),
+}
+
+// RecordUsage buffers a request of the user to an endpoint (already normalized, e.g. /api/v1/expenses/{id})
+func RecordUsage(userID, method, endpoint, feature string) {
+	if userID == "" || endpoint == "" {
+		return
+	}
+
+	key := usageKey{
+		day:      time.Now().UTC().Format("2006-01-02"),
+		userID:   userID,
+		method:   method,
+		endpoint: endpoint,
+		feature:  feature,
+	}
+
+	usage.mu.Lock()
+	usage.counts[key]++
+	usage.mu.Unlock()
+}
+
+// StartUsageAnalyticsFlusher periodically writes the buffered usage (API key and deprecated field usage too) into the analytics tables
+func (s *Services) StartUsageAnalyticsFlusher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// Usage keeps being buffered in memory until maintenance is over
+			if s.IsMaintenanceMode() {
+				continue
+			}
+			s.FlushAllUsage()
+		}
+	}()
+}
+
+// FlushAllUsage writes every usage buffer, e.g. on the ticks of the flusher and on shutdown
+func (s *Services) FlushAllUsage() {
+	if err := s.FlushUsageAnalytics(); err != nil {
+		logger.Error("Error flushing usage analytics: %v", err)
+	}
+	if err := s.FlushAPIKeyUsage(); err != nil {
+		logger.Error("Error flushing api key usage: %v", err)
+	}
+	if err := s.FlushDeprecatedFieldUsage(); err != nil {
+		logger.Error("Error flushing deprecated field usage: %v", err)
+	}
+}
+
+// FlushUsageAnalytics aggregates the buffered usage by cohort, drops opted-out users and
+// upserts the result into the analytics tables
+func (s *Services) FlushUsageAnalytics() error {
+	usage.mu.Lock()
+	counts := usage.counts
+	usage.counts = make(map[usageKey]int64)
+	usage.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	// Resolve cohorts and opt-outs with a single query
+	userIDs := make([]string, 0)
+	seenUsers := make(map[string]bool)
+	for key := range counts {
+		if !seenUsers[key.userID] {
+			seenUsers[key.userID] = true
+			userIDs = append(userIDs, key.userID)
+		}
+	}
+
+	var users []models.User
+	if err := s.db.Select("id", "created_at", "analytics_opt_out").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+
+	cohorts := make(map[string]string, len(users))
+	for _, user := range users {
+		if user.AnalyticsOptOut {
+			continue
+		}
+		cohorts[user.ID.String()] = user.CreatedAt.UTC().Format("2006-01")
+	}
+
+	type endpointKey struct{ day, method, endpoint, cohort string }
+	type featureKey struct{ day, feature, cohort string }
+	endpointCounts := make(map[endpointKey]int64)
+	featureUsers := make(map[featureKey]int64)
+
+	usage.mu.Lock()
+	for key, count := range counts {
+		cohort, ok := cohorts[key.userID]
+		if !ok {
+			continue
+		}
+
+		endpointCounts[endpointKey{key.day, key.method, key.endpoint, cohort}] += count
+
+		// A user counts once per feature and day
+		if usage.seenDay != key.day {
+			usage.seenDay = key.day
+			usage.seenFeatures = make(map[string]bool)
+		}
+		seen := key.day + "|" + key.feature + "|" + key.userID
+		if key.feature != "" && !usage.seenFeatures[seen] {
+			usage.seenFeatures[seen] = true
+			featureUsers[featureKey{key.day, key.feature, cohort}]++
+		}
+	}
+	usage.mu.Unlock()
+
+	for key, count := range endpointCounts {
+		day, _ := time.Parse("2006-01-02", key.day)
+		stat := models.UsageEndpointStat{Day: day, Method: key.method, Endpoint: key.endpoint, Cohort: key.cohort, RequestCount: count}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "method"}, {Name: "endpoint"}, {Name: "cohort"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"request_count": gorm.Expr("usage_endpoint_stats.request_count + EXCLUDED.request_count"),
+				"updated_at":    time.Now(),
+			}),
+		}).Create(&stat).Error; err != nil {
+			return err
+		}
+	}
+
+	for key, count := range featureUsers {
+		day, _ := time.Parse("2006-01-02", key.day)
+		stat := models.UsageFeatureStat{Day: day, Feature: key.feature, Cohort: key.cohort, ActiveUsers: count}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "feature"}, {Name: "cohort"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"active_users": gorm.Expr("usage_feature_stats.active_users + EXCLUDED.active_users"),
+				"updated_at":   time.Now(),
+			}),
+		}).Create(&stat).Error; err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("Flushed usage analytics: %d endpoint rows, %d feature rows", len(endpointCounts), len(featureUsers))
+	return nil
+}
+
+// EndpointUsageReport is an aggregated row of the endpoint usage report
+type EndpointUsageReport struct {
+	Method       string `json:"method"`
+	Endpoint     string `json:"endpoint"`
+	Cohort       string `json:"cohort"`
+	RequestCount int64  `json:"request_count"`
+}
+
+// FeatureAdoptionReport is an aggregated row of the feature adoption report
+type FeatureAdoptionReport struct {
+	Feature     string `json:"feature"`
+	Cohort      string `json:"cohort"`
+	ActiveDays  int64  `json:"active_days"`
+	ActiveUsers int64  `json:"active_users"` // Sum of daily active users over the period
+}
+
+// GetEndpointUsageReport returns the request counts per endpoint and cohort for a period
+func (s *Services) GetEndpointUsageReport(startDate, endDate time.Time) ([]EndpointUsageReport, error) {
+	var report []EndpointUsageReport
+	result := s.db.Model(&models.UsageEndpointStat{}).
+		Select("method, endpoint, cohort, SUM(request_count) as request_count").
+		Where("day BETWEEN ? AND ?", startDate, endDate).
+		Group("method, endpoint, cohort").
+		Order("request_count DESC").
+		Scan(&report)
+	if result.Error != nil {
+		logger.Error("Error getting endpoint usage report: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return report, nil
+}
+
+// GetFeatureAdoptionReport returns the feature adoption per cohort for a period
+func (s *Services) GetFeatureAdoptionReport(startDate, endDate time.Time) ([]FeatureAdoptionReport, error) {
+	var report []FeatureAdoptionReport
+	result := s.db.Model(&models.UsageFeatureStat{}).
+		Select("feature, cohort, COUNT(DISTINCT day) as active_days, SUM(active_users) as active_users").
+		Where("day BETWEEN ? AND ?", startDate, endDate).
+		Group("feature, cohort").
+		Order("cohort ASC, active_users DESC").
+		Scan(&report)
+	if result.Error != nil {
+		logger.Error("Error getting feature adoption report: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return report, nil
+}
+
+// SetAnalyticsOptOut stores the user's usage analytics preference
+func (s *Services) SetAnalyticsOptOut(userID string, optOut bool) error {
+	result := s.db.Model(&models.User{}).Where("id = ?", userID).Update("analytics_opt_out", optOut)
+	if result.Error != nil {
+		logger.Error("Error updating analytics preference: %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	logger.Info("Analytics opt-out set to %t for user %s", optOut, userID)
+	return nil
+}
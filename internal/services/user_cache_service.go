@@ -0,0 +1,104 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"gorm.io/gorm"
+)
+
+// userCachesChannel is the Postgres channel telling every instance to drop a user's entry from
+// one of its user caches, whichever instance changed the value
+const userCachesChannel = "fluxio_user_caches"
+
+// userCacheTTL bounds how long an entry outlives a change whose notification was missed, while
+// the listener reconnects
+const userCacheTTL = 5 * time.Minute
+
+// userCaches are the per-instance caches of user settings, by the name notifications use.
+// Settings that aren't per user, like the maintenance switch, are kept under the empty user ID
+var userCaches = map[string]interface{ forget(userID string) }{
+	"timezone":      userLocations,
+	"sandbox_clock": sandboxClocks,
+	"maintenance":   maintenanceCache,
+}
+
+type userCacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// userCache keeps a value per user in this instance. Entries expire after userCacheTTL, and
+// invalidateUserCache drops them on every instance as soon as the value changes
+type userCache[V any] struct {
+	mu      sync.RWMutex
+	entries map[string]userCacheEntry[V]
+	// generation counts the entries dropped, so a value loaded across a change isn't stored
+	generation uint64
+}
+
+func newUserCache[V any]() *userCache[V] {
+	return &userCache[V]{entries: make(map[string]userCacheEntry[V])}
+}
+
+// get returns the user's value, loading it on a miss. load reports whether the value may be
+// cached; failures shouldn't be
+func (c *userCache[V]) get(userID string, load func() (V, bool)) V {
+	c.mu.RLock()
+	entry, ok := c.entries[userID]
+	generation := c.generation
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value
+	}
+
+	value, cacheable := load()
+	if !cacheable {
+		return value
+	}
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[userID] = userCacheEntry[V]{value: value, expires: time.Now().Add(userCacheTTL)}
+	}
+	c.mu.Unlock()
+	return value
+}
+
+// forget drops the user's entry
+func (c *userCache[V]) forget(userID string) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// invalidateUserCache drops the user's entry from the named cache on every instance once tx
+// commits. Postgres delivers the notification only on commit, and this instance drops its own
+// entry then too, so it doesn't serve the old value until the notification comes back
+func invalidateUserCache(tx *gorm.DB, cache string, userID string) error {
+	if err := tx.Exec("SELECT pg_notify(?, ?)", userCachesChannel, cache+" "+userID).Error; err != nil {
+		return err
+	}
+	db.AfterCommit(tx, func() {
+		userCaches[cache].forget(userID)
+	})
+	return nil
+}
+
+// deliverUserCacheNotification drops the notified entry on this instance
+func deliverUserCacheNotification(payload string) {
+	cache, userID, ok := strings.Cut(payload, " ")
+	if !ok {
+		return
+	}
+	if userCache, found := userCaches[cache]; found {
+		userCache.forget(userID)
+	}
+}
+
+// StartUserCacheListener drops on this instance the user cache entries changed on any instance
+func (s *Services) StartUserCacheListener() {
+	s.startNotificationListener("User cache", userCachesChannel, deliverUserCacheNotification)
+}
@@ -4,14 +4,15 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/dto"
 	"github.com/Osminalx/fluxio/internal/models"
 	"github.com/Osminalx/fluxio/pkg/utils/logger"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateUserCategory creates a new category for the user
-func CreateUserCategory(userID string, category *models.Category) error {
+func (s *Services) CreateUserCategory(userID string, category *models.Category) error {
 	// Force the UserID and Status to prevent manipulation
 	category.UserID = uuid.MustParse(userID)
 	category.Status = models.StatusActive
@@ -22,16 +23,26 @@ func CreateUserCategory(userID string, category *models.Category) error {
 		return errors.New("invalid expense type. Must be one of: needs, wants, savings")
 	}
 	
+	if err := validateCategoryAppearance(category.Icon, category.Color); err != nil {
+		return err
+	}
+	if category.Icon != nil && *category.Icon == "" {
+		category.Icon = nil
+	}
+	if category.Color != nil && *category.Color == "" {
+		category.Color = nil
+	}
+	
 	// Check if there is another category with the same name for this user in this type
 	var existingCategory models.Category
-	result := db.DB.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND status IN ?", 
+	result := s.db.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND status IN ?", 
 		category.Name, userID, category.ExpenseType, models.GetActiveStatuses()).First(&existingCategory)
 	if result.Error == nil {
 		logger.Error("Category with this name already exists for this user in this expense type")
 		return errors.New("you already have a category with this name in this expense type")
 	}
 	
-	result = db.DB.Create(category)
+	result = s.db.Create(category)
 	if result.Error != nil {
 		logger.Error("Error creating user category: %v", result.Error)
 		return result.Error
@@ -42,9 +53,9 @@ func CreateUserCategory(userID string, category *models.Category) error {
 }
 
 // GetUserCategoryByID gets a specific category for the user
-func GetUserCategoryByID(userID string, id string) (*models.Category, error) {
+func (s *Services) GetUserCategoryByID(userID string, id string) (*models.Category, error) {
 	var category models.Category
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).
 		First(&category)
 	if result.Error != nil {
 		logger.Error("Error getting user category by id: %v", result.Error)
@@ -55,34 +66,37 @@ func GetUserCategoryByID(userID string, id string) (*models.Category, error) {
 	return &category, nil
 }
 
-// GetUserCategories gets all categories for the user
-func GetUserCategories(userID string, includeDeleted bool) ([]models.Category, error) {
-	var categories []models.Category
-	query := db.DB.Where("user_id = ?", userID)
-	
+// userCategoriesQuery selects the categories of the user
+func (s *Services) userCategoriesQuery(userID string, includeDeleted bool) *gorm.DB {
+	query := s.db.Model(&models.Category{}).Where("user_id = ?", userID)
 	if !includeDeleted {
 		query = query.Where("status IN ?", models.GetVisibleStatuses())
 	}
-	
-	result := query.Order("expense_type, name ASC").Find(&categories)
-	if result.Error != nil {
-		logger.Error("Error getting user categories: %v", result.Error)
-		return nil, result.Error
+	return query
+}
+
+// GetUserCategories gets a page of the categories of the user by expense type and name
+func (s *Services) GetUserCategories(userID string, includeDeleted bool, page PageRequest) ([]models.Category, PageInfo, error) {
+	var categories []models.Category
+	info, err := paginate(s.userCategoriesQuery(userID, includeDeleted), "expense_type, name ASC, id", page, &categories)
+	if err != nil {
+		logger.Error("Error getting user categories: %v", err)
+		return nil, PageInfo{}, err
 	}
 	
 	logger.Info("User categories retrieved successfully for user %s", userID)
-	return categories, nil
+	return categories, info, nil
 }
 
 // GetUserCategoriesByExpenseType gets user categories for a specific expense type
-func GetUserCategoriesByExpenseType(userID string, expenseType string, includeDeleted bool) ([]models.Category, error) {
+func (s *Services) GetUserCategoriesByExpenseType(userID string, expenseType string, includeDeleted bool) ([]models.Category, error) {
 	// Validate expense type
 	if !models.IsValidExpenseType(expenseType) {
 		return nil, errors.New("invalid expense type. Must be one of: needs, wants, savings")
 	}
 	
 	var categories []models.Category
-	query := db.DB.Where("user_id = ? AND expense_type = ?", userID, expenseType)
+	query := s.db.Where("user_id = ? AND expense_type = ?", userID, expenseType)
 	
 	if !includeDeleted {
 		query = query.Where("status IN ?", models.GetVisibleStatuses())
@@ -99,7 +113,7 @@ func GetUserCategoriesByExpenseType(userID string, expenseType string, includeDe
 }
 
 // GetUserCategoriesByExpenseTypeName gets user categories for a specific expense type by name (kept for backward compatibility)
-func GetUserCategoriesByExpenseTypeName(userID string, expenseTypeName string) ([]models.Category, error) {
+func (s *Services) GetUserCategoriesByExpenseTypeName(userID string, expenseTypeName string) ([]models.Category, error) {
 	// Convert name to lowercase enum value
 	expenseType := expenseTypeName
 	switch expenseTypeName {
@@ -114,13 +128,14 @@ func GetUserCategoriesByExpenseTypeName(userID string, expenseTypeName string) (
 		expenseType = expenseTypeName
 	}
 	
-	return GetUserCategoriesByExpenseType(userID, expenseType, false)
+	return s.GetUserCategoriesByExpenseType(userID, expenseType, false)
 }
 
 // GetUserCategoriesGroupedByType gets user categories grouped by expense type
-func GetUserCategoriesGroupedByType(userID string) (map[string][]models.Category, error) {
-	categories, err := GetUserCategories(userID, false)
-	if err != nil {
+func (s *Services) GetUserCategoriesGroupedByType(userID string) (map[string][]models.Category, error) {
+	var categories []models.Category
+	if err := s.userCategoriesQuery(userID, false).Order("expense_type, name ASC").Find(&categories).Error; err != nil {
+		logger.Error("Error getting user categories: %v", err)
 		return nil, err
 	}
 	
@@ -135,11 +150,11 @@ func GetUserCategoriesGroupedByType(userID string) (map[string][]models.Category
 }
 
 // UpdateUserCategory updates a user's category
-func UpdateUserCategory(userID string, id string, updatedCategory *models.Category) (*models.Category, error) {
+func (s *Services) UpdateUserCategory(userID string, id string, updatedCategory *models.Category) (*models.Category, error) {
 	var existingCategory models.Category
 	
 	// Verify that the category exists, belongs to the user and is not deleted
-	result := db.DB.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingCategory)
+	result := s.db.Where("user_id = ? AND id = ? AND status IN ?", userID, id, models.GetVisibleStatuses()).First(&existingCategory)
 	if result.Error != nil {
 		logger.Error("User category not found: %v", result.Error)
 		return nil, errors.New("category not found or access denied")
@@ -153,10 +168,27 @@ func UpdateUserCategory(userID string, id string, updatedCategory *models.Catego
 		}
 	}
 	
+	if err := validateCategoryAppearance(updatedCategory.Icon, updatedCategory.Color); err != nil {
+		return nil, err
+	}
+	// Updates skips nil fields, so cleared icon/color are written separately
+	cleared := appearanceColumns(CategoryAppearanceUpdate{Icon: updatedCategory.Icon, Color: updatedCategory.Color})
+	for column, value := range cleared {
+		if value != nil {
+			delete(cleared, column)
+		}
+	}
+	if _, ok := cleared["icon"]; ok {
+		updatedCategory.Icon = nil
+	}
+	if _, ok := cleared["color"]; ok {
+		updatedCategory.Color = nil
+	}
+	
 	// Check if the name is unique in the type for this user if it is being changed
 	if existingCategory.Name != updatedCategory.Name || existingCategory.ExpenseType != updatedCategory.ExpenseType {
 		var duplicateCategory models.Category
-		checkResult := db.DB.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND id != ? AND status IN ?", 
+		checkResult := s.db.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND id != ? AND status IN ?", 
 			updatedCategory.Name, userID, updatedCategory.ExpenseType, id, models.GetActiveStatuses()).First(&duplicateCategory)
 		if checkResult.Error == nil {
 			logger.Error("Category name already exists for this user in this expense type")
@@ -172,14 +204,20 @@ func UpdateUserCategory(userID string, id string, updatedCategory *models.Catego
 	updatedCategory.StatusChangedAt = existingCategory.StatusChangedAt
 	
 	// Update
-	result = db.DB.Model(&existingCategory).Updates(updatedCategory)
+	result = s.db.Model(&existingCategory).Updates(updatedCategory)
 	if result.Error != nil {
 		logger.Error("Error updating user category: %v", result.Error)
 		return nil, result.Error
 	}
+	if len(cleared) > 0 {
+		if err := s.db.Model(&existingCategory).Updates(cleared).Error; err != nil {
+			logger.Error("Error clearing user category appearance: %v", err)
+			return nil, err
+		}
+	}
 	
 	// Get the updated category
-	result = db.DB.Where("user_id = ? AND id = ?", userID, id).First(&existingCategory)
+	result = s.db.Where("user_id = ? AND id = ?", userID, id).First(&existingCategory)
 	if result.Error != nil {
 		logger.Error("Error retrieving updated user category: %v", result.Error)
 		return nil, result.Error
@@ -190,10 +228,10 @@ func UpdateUserCategory(userID string, id string, updatedCategory *models.Catego
 }
 
 // SoftDeleteUserCategory marks a user's category as deleted
-func SoftDeleteUserCategory(userID string, id string) error {
+func (s *Services) SoftDeleteUserCategory(userID string, id string) error {
 	// Check if the category exists, belongs to the user and is not deleted
 	var existingCategory models.Category
-	result := db.DB.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingCategory)
+	result := s.db.Where("user_id = ? AND id = ? AND status != ?", userID, id, models.StatusDeleted).First(&existingCategory)
 	if result.Error != nil {
 		logger.Error("User category not found or already deleted: %v", result.Error)
 		return errors.New("category not found, already deleted, or access denied")
@@ -201,7 +239,7 @@ func SoftDeleteUserCategory(userID string, id string) error {
 	
 	// Check if it has active expenses
 	var activeExpenses int64
-	db.DB.Model(&models.Expense{}).Where("user_id = ? AND category_id = ? AND status IN ?", 
+	s.db.Model(&models.Expense{}).Where("user_id = ? AND category_id = ? AND status IN ?", 
 		userID, id, models.GetActiveStatuses()).Count(&activeExpenses)
 	if activeExpenses > 0 {
 		logger.Error("Cannot delete category with active expenses")
@@ -210,7 +248,7 @@ func SoftDeleteUserCategory(userID string, id string) error {
 	
 	// Mark as deleted
 	now := time.Now()
-	result = db.DB.Model(&existingCategory).Updates(map[string]interface{}{
+	result = s.db.Model(&existingCategory).Updates(map[string]interface{}{
 		"status": models.StatusDeleted,
 		"status_changed_at": &now,
 	})
@@ -225,10 +263,10 @@ func SoftDeleteUserCategory(userID string, id string) error {
 }
 
 // RestoreUserCategory restores a deleted user category
-func RestoreUserCategory(userID string, id string) (*models.Category, error) {
+func (s *Services) RestoreUserCategory(userID string, id string) (*models.Category, error) {
 	// Check if the category exists, belongs to the user and is deleted
 	var existingCategory models.Category
-	result := db.DB.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingCategory)
+	result := s.db.Where("user_id = ? AND id = ? AND status = ?", userID, id, models.StatusDeleted).First(&existingCategory)
 	if result.Error != nil {
 		logger.Error("User category not found, not deleted, or access denied: %v", result.Error)
 		return nil, errors.New("category not found, not deleted, or access denied")
@@ -242,7 +280,7 @@ func RestoreUserCategory(userID string, id string) (*models.Category, error) {
 	
 	// Check if there is a conflict of names
 	var duplicateCategory models.Category
-	checkResult := db.DB.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND id != ? AND status IN ?", 
+	checkResult := s.db.Where("LOWER(name) = LOWER(?) AND user_id = ? AND expense_type = ? AND id != ? AND status IN ?", 
 		existingCategory.Name, userID, existingCategory.ExpenseType, id, models.GetActiveStatuses()).First(&duplicateCategory)
 	if checkResult.Error == nil {
 		logger.Error("Cannot restore: category name already exists for this user in this expense type")
@@ -251,7 +289,7 @@ func RestoreUserCategory(userID string, id string) (*models.Category, error) {
 	
 	// Restore as active
 	now := time.Now()
-	result = db.DB.Model(&existingCategory).Updates(map[string]interface{}{
+	result = s.db.Model(&existingCategory).Updates(map[string]interface{}{
 		"status": models.StatusActive,
 		"status_changed_at": &now,
 	})
@@ -262,7 +300,7 @@ func RestoreUserCategory(userID string, id string) (*models.Category, error) {
 	}
 	
 	// Get the updated category
-	updatedCategory, err := GetUserCategoryByID(userID, id)
+	updatedCategory, err := s.GetUserCategoryByID(userID, id)
 	if err != nil {
 		logger.Error("Error retrieving updated category: %v", err)
 		return nil, errors.New("error retrieving updated category")
@@ -273,7 +311,7 @@ func RestoreUserCategory(userID string, id string) (*models.Category, error) {
 }
 
 // CreateDefaultUserCategories creates default categories for a new user
-func CreateDefaultUserCategories(userID string) error {
+func (s *Services) CreateDefaultUserCategories(userID string) error {
 	// Define default categories for each expense type
 	defaultCategories := map[models.ExpenseType][]string{
 		models.ExpenseTypeNeeds: {
@@ -296,7 +334,7 @@ func CreateDefaultUserCategories(userID string) error {
 			}
 			
 			// Create category (CreateUserCategory already checks for duplicates)
-			if err := CreateUserCategory(userID, &category); err != nil {
+			if err := s.CreateUserCategory(userID, &category); err != nil {
 				logger.Error("Error creating default category %s for user %s: %v", categoryName, userID, err)
 				// Continue with the other categories
 			} else {
@@ -309,28 +347,22 @@ func CreateDefaultUserCategories(userID string) error {
 }
 
 // GetUserCategoryStats gets statistics about user's categories
-func GetUserCategoryStats(userID string) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
+func (s *Services) GetUserCategoryStats(userID string) (*dto.UserCategoryStats, error) {
+	stats := &dto.UserCategoryStats{CategoriesByType: make(map[string]int64)}
 	
 	// Total categories by user
-	var totalCategories int64
-	db.DB.Model(&models.Category{}).Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses()).Count(&totalCategories)
-	stats["total_categories"] = totalCategories
+	s.db.Model(&models.Category{}).Where("user_id = ? AND status IN ?", userID, models.GetActiveStatuses()).Count(&stats.TotalCategories)
 	
 	// Categories by type
-	typeStats := make(map[string]int64)
 	for _, expenseType := range models.ValidExpenseTypes() {
 		var count int64
-		db.DB.Model(&models.Category{}).Where("user_id = ? AND expense_type = ? AND status IN ?", 
+		s.db.Model(&models.Category{}).Where("user_id = ? AND expense_type = ? AND status IN ?", 
 			userID, expenseType, models.GetActiveStatuses()).Count(&count)
-		typeStats[models.GetExpenseTypeName(expenseType)] = count
+		stats.CategoriesByType[models.GetExpenseTypeName(expenseType)] = count
 	}
-	stats["categories_by_type"] = typeStats
 	
 	// Deleted categories
-	var deletedCategories int64
-	db.DB.Model(&models.Category{}).Where("user_id = ? AND status = ?", userID, models.StatusDeleted).Count(&deletedCategories)
-	stats["deleted_categories"] = deletedCategories
+	s.db.Model(&models.Category{}).Where("user_id = ? AND status = ?", userID, models.StatusDeleted).Count(&stats.DeletedCategories)
 	
 	logger.Info("User category stats retrieved successfully for user %s", userID)
 	return stats, nil
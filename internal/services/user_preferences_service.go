@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// getUserPreferences loads the preferences row of the user, returning defaults if none exists
+func (s *Services) getUserPreferences(userID string) (*models.UserPreferences, error) {
+	return loadUserPreferences(s.db, userID)
+}
+
+// loadUserPreferences is getUserPreferences within tx
+func loadUserPreferences(tx *gorm.DB, userID string) (*models.UserPreferences, error) {
+	var preferences models.UserPreferences
+	result := tx.Where("user_id = ?", userID).First(&preferences)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return &models.UserPreferences{
+			UserID:               uuid.MustParse(userID),
+			RetentionPolicies:    "{}",
+			NotificationSettings: "{}",
+			DashboardConfig:      "{}",
+			FirstDayOfWeek:       defaultFirstDayOfWeek,
+			FirstDayOfMonth:      1,
+			BudgetingMode:        BudgetingMode503020,
+			DateFormat:           defaultDateFormat,
+		}, nil
+	}
+	if result.Error != nil {
+		logger.Error("Error getting user preferences: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return &preferences, nil
+}
+
+// saveUserPreferences upserts the preferences row, only overwriting the given columns
+func (s *Services) saveUserPreferences(preferences *models.UserPreferences, columns ...string) error {
+	return storeUserPreferences(s.db, preferences, columns...)
+}
+
+// storeUserPreferences is saveUserPreferences within tx
+func storeUserPreferences(tx *gorm.DB, preferences *models.UserPreferences, columns ...string) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "updated_at")),
+	}).Create(preferences).Error
+}
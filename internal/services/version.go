@@ -0,0 +1,18 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when an update is based on a version of the record other
+// than the current one, i.e. someone else changed it in between
+var ErrVersionConflict = errors.New("version conflict: the record was changed since it was read")
+
+// bumpVersion increments the version of a record changed without going through its update,
+// e.g. when one of its lines changes
+func bumpVersion(tx *gorm.DB, model interface{}, id uuid.UUID) error {
+	return tx.Model(model).Where("id = ?", id).Update("version", gorm.Expr("version + 1")).Error
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	webhookBatchSize       = 20
+	webhookConcurrency     = 5
+	webhookTimeout         = 10 * time.Second
+	webhookLease           = 5 * time.Minute // A claimed delivery is retried after this if the instance dies
+	webhookMaxResponseBody = 1024
+	webhookMaxBackoff      = 6 * time.Hour
+)
+
+// webhookMaxAttempts is how many times a delivery is tried before it is marked failed. With the
+// backoff doubling from 30 seconds, the default 8 attempts span about an hour
+func webhookMaxAttempts() int {
+	return envInt("WEBHOOK_MAX_ATTEMPTS", 8)
+}
+
+// webhookBackoff is the wait after the given failed attempt: 30s, 1m, 2m... up to 6 hours
+func webhookBackoff(attempts int) time.Duration {
+	if attempts > 20 {
+		return webhookMaxBackoff
+	}
+	backoff := 30 * time.Second * time.Duration(1<<uint(attempts-1))
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+// SignWebhookPayload returns the X-Fluxio-Signature header of a body sent at timestamp:
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" with the webhook secret>. Receivers
+// recompute it and should reject old timestamps to stop replays
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// publicDialContext dials with a 5 second timeout and, unless allowPrivate says otherwise,
+// refuses to connect to private addresses whatever the host name resolves to
+func publicDialContext(allowPrivate func() bool) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return (&net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate() {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to connect to private address %s", host)
+			}
+			return nil
+		},
+	}).DialContext
+}
+
+// webhookClient doesn't follow redirects and, unless WEBHOOK_ALLOW_PRIVATE_TARGETS is set,
+// refuses to connect to private addresses whatever the host name resolves to
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{
+		Proxy:                 nil,
+		DialContext:           publicDialContext(webhookPrivateTargetsAllowed),
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: webhookTimeout,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+	},
+}
+
+// claimWebhookDeliveries takes a batch of due deliveries and pushes their next attempt past
+// the lease, so other instances skip them while they are being sent
+func (s *Services) claimWebhookDeliveries() ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at ASC").Limit(webhookBatchSize).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		ids := make([]uuid.UUID, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(webhookLease)).Error
+	})
+	return deliveries, err
+}
+
+// sendWebhookDelivery makes one attempt and records its outcome. A 2xx answer succeeds; a 410
+// Gone fails the delivery and disables the webhook; anything else is retried with backoff
+func (s *Services) sendWebhookDelivery(delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	if err := s.db.Where("id = ?", delivery.WebhookID).First(&webhook).Error; err != nil {
+		logger.Warn("Webhook %s of delivery %s not found: %v", delivery.WebhookID, delivery.ID, err)
+		return
+	}
+
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts, "updated_at": now}
+	// A webhook paused after the event was queued keeps its delivery waiting until it is active
+	if !webhook.Active {
+		updates["attempts"] = delivery.Attempts
+		updates["next_attempt_at"] = now.Add(webhookMaxBackoff)
+		s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+		return
+	}
+
+	body := []byte(delivery.Payload)
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	var response *http.Response
+	if err == nil {
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("User-Agent", "Fluxio-Webhooks/1.0")
+		request.Header.Set("X-Fluxio-Event", delivery.EventType)
+		request.Header.Set("X-Fluxio-Event-Id", delivery.EventID.String())
+		request.Header.Set("X-Fluxio-Delivery", delivery.ID.String())
+		request.Header.Set("X-Fluxio-Signature", SignWebhookPayload(webhook.Secret, now, body))
+		response, err = webhookClient.Do(request)
+	}
+	elapsed := time.Since(now).Milliseconds()
+	updates["duration_ms"] = elapsed
+
+	var deliveryErr error
+	if err != nil {
+		deliveryErr = err
+		updates["response_status"] = nil
+		updates["response_body"] = nil
+	} else {
+		excerpt, _ := io.ReadAll(io.LimitReader(response.Body, webhookMaxResponseBody))
+		response.Body.Close()
+		updates["response_status"] = response.StatusCode
+		updates["response_body"] = string(bytes.ToValidUTF8(excerpt, nil))
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			deliveryErr = fmt.Errorf("receiver answered %d", response.StatusCode)
+		}
+	}
+
+	switch {
+	case deliveryErr == nil:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = now
+		updates["last_error"] = nil
+	case response != nil && response.StatusCode == http.StatusGone:
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = deliveryErr.Error()
+		if err := s.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).
+			Updates(map[string]interface{}{"active": false, "disabled_at": now, "updated_at": now}).Error; err == nil {
+			logger.Warn("Webhook %s disabled: the receiver answered 410 Gone", webhook.ID)
+		}
+	default:
+		updates["last_error"] = deliveryErr.Error()
+		updates["next_attempt_at"] = now.Add(webhookBackoff(attempts))
+		if attempts >= webhookMaxAttempts() {
+			updates["status"] = models.WebhookDeliveryFailed
+		}
+	}
+
+	if err := s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		logger.Error("Error recording webhook delivery %s: %v", delivery.ID, err)
+	}
+	if deliveryErr != nil {
+		logger.Debug("Webhook delivery %s attempt %d failed: %v", delivery.ID, attempts, deliveryErr)
+	}
+}
+
+// SendWebhookDeliveries sends a batch of due deliveries, a few at a time
+func (s *Services) SendWebhookDeliveries() (int, error) {
+	deliveries, err := s.claimWebhookDeliveries()
+	if err != nil {
+		logger.Error("Error claiming webhook deliveries: %v", err)
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookConcurrency)
+	for i := range deliveries {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.sendWebhookDelivery(delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// StartWebhookSender periodically sends the pending webhook deliveries
+func (s *Services) StartWebhookSender(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if s.IsMaintenanceMode() {
+				continue
+			}
+			// Keep going while full batches come back
+			for {
+				sent, err := s.SendWebhookDeliveries()
+				if err != nil || sent < webhookBatchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// RetryWebhookDelivery puts a failed delivery of the user back in the queue with a fresh set of
+// attempts
+func (s *Services) RetryWebhookDelivery(userID string, webhookID string, deliveryID string) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, errors.New("invalid delivery ID")
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.Where("id = ? AND webhook_id = ?", id, webhook.ID).First(&delivery).Error; err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.Status != models.WebhookDeliveryFailed {
+		return nil, errors.New("invalid delivery: only failed deliveries can be retried")
+	}
+	now := time.Now()
+	if err := s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}).Error; err != nil {
+		logger.Error("Error retrying webhook delivery: %v", err)
+		return nil, errors.New("error retrying webhook delivery")
+	}
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	return &delivery, nil
+}
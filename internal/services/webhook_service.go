@@ -0,0 +1,419 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxWebhooksPerUser bounds the webhooks one user can register
+const MaxWebhooksPerUser = 10
+
+// minWebhookSecretLength is the shortest secret a user can choose
+const minWebhookSecretLength = 16
+
+// webhookEvents are the events sent to webhooks. Security events are left out: some carry
+// verification codes
+var webhookEvents = map[string]bool{
+	EventExpenseCreated:       true,
+	EventIncomeCreated:        true,
+	EventBudgetCreated:        true,
+	EventBudgetUpdated:        true,
+	EventBudgetExceeded:       true,
+	EventTransferCompleted:    true,
+	EventReminderDue:          true,
+	EventGoalFunded:           true,
+	EventGoalMilestoneReached: true,
+}
+
+// WebhookEventTypes returns the event types a webhook can subscribe to
+func WebhookEventTypes() []string {
+	return []string{
+		EventExpenseCreated, EventIncomeCreated, EventBudgetCreated, EventBudgetUpdated, EventBudgetExceeded,
+		EventTransferCompleted, EventReminderDue, EventGoalFunded, EventGoalMilestoneReached,
+	}
+}
+
+// WebhookInput holds the fields of a webhook being registered or changed; nil fields are left
+// as they are on update
+type WebhookInput struct {
+	URL         *string
+	Description *string
+	Secret      *string // Generated when empty on create
+	EventTypes  []string
+	Active      *bool
+}
+
+// WebhookBody is what every delivery POSTs. Data is the payload of the event
+type WebhookBody struct {
+	ID            uuid.UUID       `json:"id"` // Event ID, the same on every attempt
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	buffer := make([]byte, 24)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buffer), nil
+}
+
+// webhookPrivateTargetsAllowed lets webhooks reach loopback and private networks, for local
+// development only
+func webhookPrivateTargetsAllowed() bool {
+	return os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") == "true"
+}
+
+// isPublicIP reports whether ip is routable on the internet
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// validateWebhookURL requires an absolute https URL (http only with private targets allowed)
+// that doesn't point at a private address. Host names are checked again when delivering
+func validateWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || parsed.Hostname() == "" {
+		return "", errors.New("invalid url: must be an absolute https URL")
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && webhookPrivateTargetsAllowed()) {
+		return "", errors.New("invalid url: must use https")
+	}
+	if parsed.User != nil {
+		return "", errors.New("invalid url: credentials are not allowed in the URL, use the secret")
+	}
+	if len(raw) > 2048 {
+		return "", errors.New("invalid url: at most 2048 characters")
+	}
+	if !webhookPrivateTargetsAllowed() {
+		host := parsed.Hostname()
+		if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+			return "", errors.New("invalid url: private and loopback addresses are not allowed")
+		}
+	}
+	return raw, nil
+}
+
+// validateWebhookEventTypes requires known event types or prefixes ending in *, and at least one
+func validateWebhookEventTypes(eventTypes []string) ([]string, error) {
+	if len(eventTypes) == 0 {
+		return nil, errors.New("invalid event_types: subscribe to at least one event type")
+	}
+	seen := make(map[string]bool, len(eventTypes))
+	cleaned := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if seen[eventType] {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(eventType, "*"); ok {
+			matches := false
+			for known := range webhookEvents {
+				matches = matches || strings.HasPrefix(known, prefix)
+			}
+			if !matches {
+				return nil, errors.New("invalid event type: " + eventType)
+			}
+		} else if !webhookEvents[eventType] {
+			return nil, errors.New("invalid event type: " + eventType)
+		}
+		seen[eventType] = true
+		cleaned = append(cleaned, eventType)
+	}
+	return cleaned, nil
+}
+
+// WebhookSubscriptions returns the event types a webhook is subscribed to
+func WebhookSubscriptions(webhook *models.Webhook) []string {
+	var eventTypes []string
+	if err := json.Unmarshal([]byte(webhook.EventTypes), &eventTypes); err != nil {
+		logger.Warn("Invalid event types stored for webhook %s: %v", webhook.ID, err)
+	}
+	return eventTypes
+}
+
+// webhookSubscribed reports whether the webhook wants the event type
+func webhookSubscribed(webhook *models.Webhook, eventType string) bool {
+	for _, subscribed := range WebhookSubscriptions(webhook) {
+		if prefix, ok := strings.CutSuffix(subscribed, "*"); ok {
+			if strings.HasPrefix(eventType, prefix) {
+				return true
+			}
+		} else if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhook registers a webhook. It returns the secret, generated when none is given; it
+// is only shown here
+func (s *Services) CreateWebhook(userID string, input WebhookInput) (*models.Webhook, string, error) {
+	if input.URL == nil {
+		return nil, "", errors.New("invalid url: url is required")
+	}
+	endpoint, err := validateWebhookURL(*input.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	eventTypes, err := validateWebhookEventTypes(input.EventTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	var secret string
+	if input.Secret != nil && *input.Secret != "" {
+		if len(*input.Secret) < minWebhookSecretLength {
+			return nil, "", fmt.Errorf("invalid secret: at least %d characters", minWebhookSecretLength)
+		}
+		secret = *input.Secret
+	} else if secret, err = generateWebhookSecret(); err != nil {
+		logger.Error("Error generating webhook secret: %v", err)
+		return nil, "", errors.New("error creating webhook")
+	}
+	encoded, _ := json.Marshal(eventTypes)
+
+	webhook := &models.Webhook{
+		UserID:      uuid.MustParse(userID),
+		URL:         endpoint,
+		Description: input.Description,
+		Secret:      secret,
+		EventTypes:  string(encoded),
+		Active:      input.Active == nil || *input.Active,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxWebhooksPerUser {
+			return fmt.Errorf("invalid request: at most %d webhooks per user", MaxWebhooksPerUser)
+		}
+		return tx.Create(webhook).Error
+	})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			return nil, "", err
+		}
+		logger.Error("Error creating webhook: %v", err)
+		return nil, "", errors.New("error creating webhook")
+	}
+
+	s.RecordAudit(webhook.UserID, "webhook.created", "webhook", &webhook.ID, map[string]interface{}{
+		"url":         webhook.URL,
+		"event_types": eventTypes,
+	})
+	logger.Info("Webhook %s created for user %s", webhook.ID, userID)
+	return webhook, secret, nil
+}
+
+// GetWebhooks lists the webhooks of the user, oldest first
+func (s *Services) GetWebhooks(userID string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		logger.Error("Error getting webhooks: %v", err)
+		return nil, errors.New("error getting webhooks")
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns one webhook of the user
+func (s *Services) GetWebhook(userID string, webhookID string) (*models.Webhook, error) {
+	id, err := uuid.Parse(webhookID)
+	if err != nil {
+		return nil, errors.New("invalid webhook ID")
+	}
+	var webhook models.Webhook
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		logger.Error("Error getting webhook: %v", err)
+		return nil, errors.New("error getting webhook")
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook changes the given fields of a webhook. Reactivating it clears DisabledAt; a new
+// secret applies to the next attempt, including retries
+func (s *Services) UpdateWebhook(userID string, webhookID string, input WebhookInput) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if input.URL != nil {
+		if webhook.URL, err = validateWebhookURL(*input.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = webhook.URL
+	}
+	if input.Description != nil {
+		webhook.Description = input.Description
+		updates["description"] = *input.Description
+	}
+	if input.Secret != nil {
+		if len(*input.Secret) < minWebhookSecretLength {
+			return nil, fmt.Errorf("invalid secret: at least %d characters", minWebhookSecretLength)
+		}
+		webhook.Secret = *input.Secret
+		updates["secret"] = webhook.Secret
+	}
+	if input.EventTypes != nil {
+		eventTypes, err := validateWebhookEventTypes(input.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		encoded, _ := json.Marshal(eventTypes)
+		webhook.EventTypes = string(encoded)
+		updates["event_types"] = webhook.EventTypes
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+		updates["active"] = webhook.Active
+		if webhook.Active {
+			webhook.DisabledAt = nil
+			updates["disabled_at"] = nil
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if input.Active == nil || !webhook.Active {
+			return nil
+		}
+		// Deliveries held while it was paused go out on the next run
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", webhook.ID, models.WebhookDeliveryPending).
+			Update("next_attempt_at", time.Now()).Error
+	})
+	if err != nil {
+		logger.Error("Error updating webhook: %v", err)
+		return nil, errors.New("error updating webhook")
+	}
+	s.RecordAudit(webhook.UserID, "webhook.updated", "webhook", &webhook.ID, map[string]interface{}{
+		"url":            webhook.URL,
+		"active":         webhook.Active,
+		"secret_rotated": input.Secret != nil,
+	})
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook with its delivery log; pending deliveries are dropped
+func (s *Services) DeleteWebhook(userID string, webhookID string) error {
+	webhook, err := s.GetWebhook(userID, webhookID)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, "id = ?", webhook.ID).Error
+	})
+	if err != nil {
+		logger.Error("Error deleting webhook: %v", err)
+		return errors.New("error deleting webhook")
+	}
+	s.RecordAudit(webhook.UserID, "webhook.deleted", "webhook", &webhook.ID, map[string]interface{}{"url": webhook.URL})
+	logger.Info("Webhook %s deleted for user %s", webhook.ID, userID)
+	return nil
+}
+
+// GetWebhookDeliveries lists the delivery log of a webhook, newest first, optionally of one status
+func (s *Services) GetWebhookDeliveries(userID string, webhookID string, status string, page PageRequest) ([]models.WebhookDelivery, PageInfo, error) {
+	webhook, err := s.GetWebhook(userID, webhookID)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	query := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	info, err := paginate(query, "created_at DESC, id", page, &deliveries)
+	if err != nil {
+		logger.Error("Error getting webhook deliveries: %v", err)
+		return nil, PageInfo{}, errors.New("error getting webhook deliveries")
+	}
+	return deliveries, info, nil
+}
+
+// QueueWebhookDeliveries is the outbox handler that queues an event for every active webhook of
+// the user subscribed to it. The unique (webhook, event) index makes it idempotent; the
+// deliveries are sent by the webhook sender, so a slow receiver doesn't hold the outbox
+func (s *Services) QueueWebhookDeliveries(event models.OutboxEvent) error {
+	if !webhookEvents[event.EventType] {
+		return nil
+	}
+	// A reminder is sent once; its escalations to other channels aren't new events
+	if event.EventType == EventReminderDue {
+		var payload struct {
+			EscalatedFrom string `json:"escalated_from"`
+		}
+		if json.Unmarshal([]byte(event.Payload), &payload) == nil && payload.EscalatedFrom != "" {
+			return nil
+		}
+	}
+
+	var webhooks []models.Webhook
+	if err := s.db.Where("user_id = ? AND active = ?", event.UserID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	var deliveries []models.WebhookDelivery
+	for i := range webhooks {
+		if !webhookSubscribed(&webhooks[i], event.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhooks[i].ID,
+			UserID:        event.UserID,
+			EventID:       event.ID,
+			EventType:     event.EventType,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(WebhookBody{
+		ID:            event.ID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		CreatedAt:     event.CreatedAt.UTC(),
+		Data:          json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		deliveries[i].Payload = string(body)
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
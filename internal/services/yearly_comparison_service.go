@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Osminalx/fluxio/internal/dto"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// MaxComparisonYears limits how many years a yearly comparison can include
+const MaxComparisonYears = 10
+
+// GetYearlyComparison puts income, spending by type and category, savings and the net worth
+// change of each of the given calendar years side by side. Its queries stop when ctx is
+// cancelled, e.g. when the client gives up on a long report, returning ctx.Err()
+func (s *Services) GetYearlyComparison(ctx context.Context, userID string, years []int) (*dto.YearlyComparison, error) {
+	if len(years) == 0 {
+		return nil, errors.New("invalid years: at least one year is required")
+	}
+	if len(years) > MaxComparisonYears {
+		return nil, fmt.Errorf("invalid years: at most %d years can be compared", MaxComparisonYears)
+	}
+	currentYear := s.UserNow(userID).Year()
+	seen := make(map[int]bool, len(years))
+	sorted := make([]int, 0, len(years))
+	for _, year := range years {
+		if year < 1900 || year > currentYear {
+			return nil, fmt.Errorf("invalid years: %d is out of range", year)
+		}
+		if !seen[year] {
+			seen[year] = true
+			sorted = append(sorted, year)
+		}
+	}
+	sort.Ints(sorted)
+	years = sorted
+
+	index := make(map[int]int, len(years))
+	for i, year := range years {
+		index[year] = i
+	}
+	startDate := time.Date(years[0], time.January, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(years[len(years)-1], time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	// Net spending per year and category
+	var spendingRows []struct {
+		Year        int
+		CategoryID  string
+		Name        string
+		ExpenseType models.ExpenseType
+		Amount      models.Money
+		Gross       models.Money
+	}
+	result := s.summaryPeriodQuery(userID, startDate, endDate).WithContext(ctx).
+		Joins("JOIN categories c ON e.category_id = c.id").
+		Where("EXTRACT(YEAR FROM e.date)::int IN ?", years).
+		Select("EXTRACT(YEAR FROM e.date)::int as year, c.id::text as category_id, c.name, c.expense_type, " +
+			"COALESCE(SUM(" + netExpenseAmountSQL() + "), 0) as amount, COALESCE(SUM(e.amount), 0) as gross").
+		Group("EXTRACT(YEAR FROM e.date), c.id, c.name, c.expense_type").
+		Scan(&spendingRows)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if result.Error != nil {
+		logger.Error("Error calculating yearly spending: %v", result.Error)
+		return nil, errors.New("error calculating yearly comparison")
+	}
+
+	// Income per year; refunds are counted apart since they may already reduce spending
+	var incomeRows []struct {
+		Year    int
+		Amount  models.Money
+		Refunds models.Money
+	}
+	result = s.db.WithContext(ctx).Model(&models.Income{}).
+		Where("user_id = ? AND date BETWEEN ? AND ? AND status IN ?", userID, startDate, endDate, models.GetActiveStatuses()).
+		Where("EXTRACT(YEAR FROM date)::int IN ?", years).
+		Select("EXTRACT(YEAR FROM date)::int as year, " +
+			"COALESCE(SUM(amount) FILTER (WHERE refund_of_expense_id IS NULL), 0) as amount, " +
+			"COALESCE(SUM(amount) FILTER (WHERE refund_of_expense_id IS NOT NULL), 0) as refunds").
+		Group("EXTRACT(YEAR FROM date)").
+		Scan(&incomeRows)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if result.Error != nil {
+		logger.Error("Error calculating yearly income: %v", result.Error)
+		return nil, errors.New("error calculating yearly comparison")
+	}
+
+	currency := s.GetUserCurrency(userID)
+	summaries := make([]dto.YearSummary, len(years))
+	byType := make([]map[models.ExpenseType]models.Money, len(years))
+	grossSpending := make([]models.Money, len(years))
+	for i, year := range years {
+		summaries[i].Year = year
+		byType[i] = make(map[models.ExpenseType]models.Money)
+	}
+
+	categories := make(map[string]*dto.YearlyCategoryComparison)
+	for _, row := range spendingRows {
+		i, ok := index[row.Year]
+		if !ok {
+			continue
+		}
+		summaries[i].Spending += row.Amount
+		byType[i][row.ExpenseType] += row.Amount
+		grossSpending[i] += row.Gross
+
+		category, ok := categories[row.CategoryID]
+		if !ok {
+			category = &dto.YearlyCategoryComparison{
+				CategoryID:  row.CategoryID,
+				Name:        row.Name,
+				ExpenseType: string(row.ExpenseType),
+				Amounts:     make([]models.Money, len(years)),
+			}
+			categories[row.CategoryID] = category
+		}
+		category.Amounts[i] += row.Amount
+	}
+
+	for _, row := range incomeRows {
+		i, ok := index[row.Year]
+		if !ok {
+			continue
+		}
+		summaries[i].Income = row.Amount
+		if !refundsNettedInOriginalMonth() {
+			summaries[i].Income += row.Refunds
+		}
+		// Transfers stay between the user's accounts, so only incomes and expenses move net worth
+		summaries[i].NetWorthChange = row.Amount + row.Refunds
+	}
+
+	for i := range summaries {
+		summary := &summaries[i]
+		summary.Income = currency.RoundMoney(summary.Income)
+		summary.Spending = currency.RoundMoney(summary.Spending)
+		summary.Savings = currency.RoundMoney(summary.Income - summary.Spending)
+		summary.NetWorthChange = currency.RoundMoney(summary.NetWorthChange - grossSpending[i])
+		if summary.Income > 0 {
+			rate := math.Round(summary.Savings.Ratio(summary.Income)*10000) / 100
+			summary.SavingsRate = &rate
+		}
+		summary.ByExpenseType = make([]dto.YearlyTypeAmount, 0, len(models.ValidExpenseTypes()))
+		for _, expenseType := range models.ValidExpenseTypes() {
+			summary.ByExpenseType = append(summary.ByExpenseType, dto.YearlyTypeAmount{
+				ExpenseType: string(expenseType),
+				Name:        models.GetExpenseTypeName(expenseType),
+				Amount:      currency.RoundMoney(byType[i][expenseType]),
+			})
+		}
+	}
+
+	comparison := &dto.YearlyComparison{
+		Years:      years,
+		Currency:   currency.Code,
+		Summaries:  summaries,
+		Categories: make([]dto.YearlyCategoryComparison, 0, len(categories)),
+	}
+	for _, category := range categories {
+		for i := range category.Amounts {
+			category.Amounts[i] = currency.RoundMoney(category.Amounts[i])
+		}
+		comparison.Categories = append(comparison.Categories, *category)
+	}
+
+	// Biggest categories of the latest year first
+	last := len(years) - 1
+	sort.Slice(comparison.Categories, func(i, j int) bool {
+		a, b := comparison.Categories[i].Amounts[last], comparison.Categories[j].Amounts[last]
+		if a != b {
+			return a > b
+		}
+		return comparison.Categories[i].Name < comparison.Categories[j].Name
+	})
+
+	logger.Info("Yearly comparison calculated for user %s (%d years)", userID, len(years))
+	return comparison, nil
+}
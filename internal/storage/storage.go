@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrNotFound is returned when an object doesn't exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files, e.g. expense receipts. Keys are slash-separated paths chosen
+// by the caller
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, content io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv builds the storage selected by ATTACHMENT_STORAGE: "local" (the default) keeps files
+// under ATTACHMENT_DIR, "s3" in the S3_BUCKET bucket of any S3-compatible service
+func FromEnv() (Storage, error) {
+	switch os.Getenv("ATTACHMENT_STORAGE") {
+	case "", "local":
+		dir := os.Getenv("ATTACHMENT_DIR")
+		if dir == "" {
+			dir = "data/attachments"
+		}
+		return NewLocalStorage(dir), nil
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+	default:
+		return nil, errors.New("invalid ATTACHMENT_STORAGE: must be local or s3")
+	}
+}
@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "db_query_duration_seconds",
+		Help: "Duration of database queries.",
+	}, []string{"operation", "table"})
+	dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Database queries that failed (missing records aren't failures).",
+	}, []string{"operation", "table"})
+)
+
+const (
+	dbStartKey = "telemetry:start"
+	dbSpanKey  = "telemetry:span"
+)
+
+// maxStatementLength caps the SQL recorded on database spans; values are never recorded
+const maxStatementLength = 1000
+
+// GormPlugin times every query and, when the statement carries the context of a traced request
+// (db.WithContext), records it as a client span of that request
+type GormPlugin struct{}
+
+// Name identifies the plugin to GORM
+func (GormPlugin) Name() string {
+	return "telemetry"
+}
+
+// Initialize registers the callbacks around each kind of operation
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().Before("gorm:create").Register("telemetry:before_create", beforeQuery("create")),
+		callbacks.Create().After("gorm:create").Register("telemetry:after_create", afterQuery("create")),
+		callbacks.Query().Before("gorm:query").Register("telemetry:before_query", beforeQuery("query")),
+		callbacks.Query().After("gorm:query").Register("telemetry:after_query", afterQuery("query")),
+		callbacks.Update().Before("gorm:update").Register("telemetry:before_update", beforeQuery("update")),
+		callbacks.Update().After("gorm:update").Register("telemetry:after_update", afterQuery("update")),
+		callbacks.Delete().Before("gorm:delete").Register("telemetry:before_delete", beforeQuery("delete")),
+		callbacks.Delete().After("gorm:delete").Register("telemetry:after_delete", afterQuery("delete")),
+		callbacks.Row().Before("gorm:row").Register("telemetry:before_row", beforeQuery("row")),
+		callbacks.Row().After("gorm:row").Register("telemetry:after_row", afterQuery("row")),
+		callbacks.Raw().Before("gorm:raw").Register("telemetry:before_raw", beforeQuery("raw")),
+		callbacks.Raw().After("gorm:raw").Register("telemetry:after_raw", afterQuery("raw")),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func beforeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(dbStartKey, time.Now())
+		if db.Statement.Context == nil || !trace.SpanContextFromContext(db.Statement.Context).IsValid() {
+			return
+		}
+		_, span := Tracer.Start(db.Statement.Context, "db."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.InstanceSet(dbSpanKey, span)
+	}
+}
+
+func afterQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(dbStartKey)
+		if !ok {
+			return
+		}
+		table := tableLabel(db.Statement.Table)
+		dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		if failed {
+			dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+
+		value, ok = db.InstanceGet(dbSpanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		statement := db.Statement.SQL.String()
+		if len(statement) > maxStatementLength {
+			statement = statement[:maxStatementLength]
+		}
+		span.SetAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+			attribute.String("db.statement", statement),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		if failed {
+			RecordError(span, db.Error)
+		}
+		span.End()
+	}
+}
+
+// tableLabel keeps table labels bounded: aliases and subqueries are dropped
+func tableLabel(table string) string {
+	if table == "" {
+		return "none"
+	}
+	if strings.HasPrefix(table, "(") {
+		return "subquery"
+	}
+	if i := strings.IndexByte(table, ' '); i > 0 {
+		return table[:i]
+	}
+	return table
+}
@@ -0,0 +1,102 @@
+// Package telemetry sets up OpenTelemetry tracing and serves the Prometheus metrics at /metrics.
+// Spans use W3C trace context and are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set, so any OpenTelemetry collector can receive them
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Osminalx/fluxio/pkg/utils/logger"
+)
+
+// Tracer starts the spans of the server. It follows the provider set by Init
+var Tracer = otel.Tracer("github.com/Osminalx/fluxio")
+
+var (
+	provider     *sdktrace.TracerProvider
+	metricsToken string // Bearer token required by /metrics when set
+)
+
+// Init sets up the tracer provider from the OTEL_* settings. Spans are always created, so each
+// request gets a trace ID, and are exported only when an OTLP endpoint is configured
+func Init() {
+	sampleRatio := 1.0
+	if value := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			logger.Warn("Invalid OTEL_TRACES_SAMPLER_ARG %q, sampling every trace", value)
+		} else {
+			sampleRatio = ratio
+		}
+	}
+
+	// The resource and the exporter read OTEL_SERVICE_NAME and OTEL_EXPORTER_OTLP_* themselves
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		exporter, err := otlptracehttp.New(context.Background())
+		if err != nil {
+			logger.Error("Error creating the trace exporter, traces won't be exported: %v", err)
+		} else {
+			options = append(options, sdktrace.WithBatcher(exporter))
+			logger.Info("Exporting traces to %s", endpoint)
+		}
+	} else {
+		logger.Info("Tracing enabled without export (OTEL_EXPORTER_OTLP_ENDPOINT is not set)")
+	}
+
+	metricsToken = os.Getenv("METRICS_TOKEN")
+	provider = sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Shutdown exports the queued spans, until ctx is done
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Extract reads the W3C traceparent header so spans continue the caller's trace
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// RecordError marks the span as failed when err isn't nil
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+var metricsHandler = promhttp.Handler()
+
+// MetricsHandler serves the metrics of the default Prometheus registry. When METRICS_TOKEN is
+// set, scrapers must send it as a bearer token
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if metricsToken != "" && r.Header.Get("Authorization") != "Bearer "+metricsToken {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	metricsHandler.ServeHTTP(w, r)
+}
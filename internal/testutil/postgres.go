@@ -0,0 +1,132 @@
+// Package testutil runs the service layer against a real database in tests. Each test gets
+// its own migrated schema on the Postgres of TEST_DATABASE_URL (docker-compose.test.yml starts
+// one), and is skipped when the variable isn't set
+package testutil
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Osminalx/fluxio/internal/db"
+	"github.com/Osminalx/fluxio/internal/models"
+	"github.com/Osminalx/fluxio/internal/services"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Harness is a migrated database with the services wired to it
+type Harness struct {
+	DB       *gorm.DB
+	Services *services.Services
+	Expenses *services.ExpenseService
+	Budgets  *services.BudgetService
+}
+
+// NewPostgres creates a schema for the test, migrates it and wires the services to it. The
+// schema is dropped on cleanup, and tests using it can run in parallel
+func NewPostgres(t testing.TB) *Harness {
+	t.Helper()
+	database := EmptySchema(t)
+	if _, err := db.MigrateUp(database, 0); err != nil {
+		t.Fatalf("migrating the test schema: %v", err)
+	}
+
+	svc := services.New(database)
+	return &Harness{
+		DB:       database,
+		Services: svc,
+		Expenses: svc.Expenses,
+		Budgets:  svc.Budgets,
+	}
+}
+
+// EmptySchema creates a schema for the test without migrating it, for tests of the migrations
+// themselves. It is dropped on cleanup
+func EmptySchema(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("creating schema %s: %v", schema, err)
+	}
+	if err := admin.Exec(`CREATE EXTENSION IF NOT EXISTS "pgcrypto" WITH SCHEMA public`).Error; err != nil {
+		t.Fatalf("creating the pgcrypto extension: %v", err)
+	}
+
+	database, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("connecting to schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Logf("dropping schema %s: %v", schema, err)
+		}
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return database
+}
+
+// withSearchPath makes every connection of the DSN (URL or key=value form) use the schema first
+func withSearchPath(dsn, schema string) string {
+	searchPath := schema + ",public"
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + url.QueryEscape(searchPath)
+	}
+	return fmt.Sprintf("%s search_path=%s", dsn, searchPath)
+}
+
+// CreateUser stores an active user to own the test data
+func (h *Harness) CreateUser(t testing.TB) *models.User {
+	t.Helper()
+	user := &models.User{
+		Email:    uuid.NewString() + "@example.com",
+		Password: "not-a-real-hash",
+		Name:     "Test User",
+	}
+	if err := h.DB.Create(user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	return user
+}
+
+// CreateBankAccount stores an active bank account of the user with the balance
+func (h *Harness) CreateBankAccount(t testing.TB, user *models.User, balance models.Money) *models.BankAccount {
+	t.Helper()
+	account := &models.BankAccount{UserID: user.ID, AccountName: "Checking", Balance: balance}
+	if err := h.DB.Create(account).Error; err != nil {
+		t.Fatalf("creating bank account: %v", err)
+	}
+	return account
+}
+
+// CreateCategory stores an active category of the user with the expense type
+func (h *Harness) CreateCategory(t testing.TB, user *models.User, name string, expenseType models.ExpenseType) *models.Category {
+	t.Helper()
+	category := &models.Category{UserID: user.ID, Name: name, ExpenseType: expenseType}
+	if err := h.DB.Create(category).Error; err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	return category
+}
@@ -0,0 +1,208 @@
+// Package apitest runs API contract tests against a running Fluxio instance: a client that can
+// call every operation of the OpenAPI spec, golden-file assertions on the responses, and a
+// ready-made suite. From any _test.go file:
+//
+//	func TestContract(t *testing.T) {
+//		apitest.Run(t, os.Getenv("FLUXIO_API_URL"))
+//	}
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Osminalx/fluxio/docs"
+)
+
+// Response is a response read in full
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body into v
+func (r *Response) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Client calls a running Fluxio API. Operations are named like in the spec, e.g.
+// "GET /api/v1/expenses/{id}", and calls to operations the spec doesn't document fail
+type Client struct {
+	BaseURL string
+	Token   string // Bearer token sent with every request once set, see Register and Login
+	HTTP    *http.Client
+
+	operations map[string]bool
+	mu         sync.Mutex
+	called     map[string]bool
+}
+
+// NewClient returns a client for the API at baseURL (e.g. http://localhost:8080) checked
+// against the current spec version
+func NewClient(baseURL string) (*Client, error) {
+	operations, err := specOperations(docs.CurrentSpecVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTP:       &http.Client{Timeout: 30 * time.Second},
+		operations: operations,
+		called:     make(map[string]bool),
+	}, nil
+}
+
+// specOperations lists the "METHOD path" operations a spec version documents
+func specOperations(version string) (map[string]bool, error) {
+	spec, ok := docs.Spec(version)
+	if !ok {
+		return nil, errors.New("spec version not found: " + version)
+	}
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &document); err != nil {
+		return nil, fmt.Errorf("error parsing spec %s: %v", version, err)
+	}
+
+	operations := make(map[string]bool)
+	for path, methods := range document.Paths {
+		for method := range methods {
+			if method != "parameters" {
+				operations[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+	return operations, nil
+}
+
+// Operations lists every operation of the spec, sorted
+func (c *Client) Operations() []string {
+	operations := make([]string, 0, len(c.operations))
+	for operation := range c.operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// Uncalled lists the operations of the spec this client hasn't called yet, to find endpoints
+// a suite doesn't cover
+func (c *Client) Uncalled() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	uncalled := []string{}
+	for _, operation := range c.Operations() {
+		if !c.called[operation] {
+			uncalled = append(uncalled, operation)
+		}
+	}
+	return uncalled
+}
+
+// Call calls an operation of the spec. pathParams fill the {placeholders} of its path, query
+// is added to the URL and body, when not nil, is sent as JSON
+func (c *Client) Call(operation string, pathParams map[string]string, query url.Values, body interface{}) (*Response, error) {
+	if !c.operations[operation] {
+		return nil, errors.New("operation not in the spec: " + operation)
+	}
+	method, path, _ := strings.Cut(operation, " ")
+	for name, value := range pathParams {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	if strings.Contains(path, "{") {
+		return nil, errors.New("missing path parameters for " + operation)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	c.mu.Lock()
+	c.called[operation] = true
+	c.mu.Unlock()
+	return c.Do(method, path, body)
+}
+
+// Do sends a request to a path of the API, documented or not
+func (c *Client) Do(method, path string, body interface{}) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
+
+// Register creates a user and authenticates the client as that user
+func (c *Client) Register(email, password, name string) (*Response, error) {
+	resp, err := c.Call("POST /api/v1/auth/register", nil, nil, map[string]string{
+		"email":    email,
+		"password": password,
+		"name":     name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, c.useToken(resp)
+}
+
+// Login authenticates the client. Logins needing an email code (202) leave the client
+// unauthenticated; finish them with POST /api/v1/auth/login/verify
+func (c *Client) Login(email, password string) (*Response, error) {
+	resp, err := c.Call("POST /api/v1/auth/login", nil, nil, map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	if err != nil || resp.Status == http.StatusAccepted {
+		return resp, err
+	}
+	return resp, c.useToken(resp)
+}
+
+func (c *Client) useToken(resp *Response) error {
+	if resp.Status != http.StatusOK && resp.Status != http.StatusCreated {
+		return fmt.Errorf("authentication failed with status %d: %s", resp.Status, strings.TrimSpace(string(resp.Body)))
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := resp.Decode(&auth); err != nil || auth.Token == "" {
+		return errors.New("authentication response has no token")
+	}
+	c.Token = auth.Token
+	return nil
+}
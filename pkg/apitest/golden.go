@@ -0,0 +1,109 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// GoldenDir is where golden files are read from and written to, relative to the package
+// running the tests
+var GoldenDir = filepath.Join("testdata", "golden")
+
+// update rewrites the golden files from the current responses instead of comparing them,
+// e.g. go test ./pkg/apitest -update
+var update = flag.Bool("update", false, "rewrite the golden files of the contract tests from the current responses")
+
+var (
+	uuidPattern      = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`)
+	datePattern      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// volatileKeys hold values that change on every run whatever their format
+var volatileKeys = map[string]bool{
+	"token":         true,
+	"refresh_token": true,
+	"next_cursor":   true,
+	"etag":          true,
+}
+
+// Normalize pretty-prints a JSON body with sorted keys, replacing the values that change
+// between runs (IDs, timestamps, dates and tokens) with placeholders so responses can be
+// compared. Bodies that aren't JSON are returned as they are
+func Normalize(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	var normalized bytes.Buffer
+	encoder := json.NewEncoder(&normalized)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(normalizeValue("", value))
+	return normalized.Bytes()
+}
+
+func normalizeValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeValue(k, item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(key, item)
+		}
+		return v
+	case string:
+		switch {
+		case volatileKeys[strings.ToLower(key)]:
+			return "<" + key + ">"
+		case uuidPattern.MatchString(v):
+			return "<uuid>"
+		case timestampPattern.MatchString(v):
+			return "<timestamp>"
+		case datePattern.MatchString(v):
+			return "<date>"
+		}
+	}
+	return value
+}
+
+// AssertGolden compares the status and normalized body of a response with the golden file
+// <GoldenDir>/<name>.golden, or writes it when the tests run with -update. A missing golden
+// file fails the test
+func AssertGolden(t testing.TB, name string, resp *Response) {
+	t.Helper()
+	got := append([]byte(fmt.Sprintf("status: %d\n", resp.Status)), Normalize(resp.Body)...)
+	path := filepath.Join(GoldenDir, name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("writing golden file %s: %v", path, err)
+		}
+		t.Logf("wrote golden file %s", path)
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s doesn't exist (rerun with -update to write it)\n--- got\n%s", path, got)
+	}
+	if err != nil {
+		t.Fatalf("reading golden file %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response of %s doesn't match %s (rerun with -update to accept it)\n--- want\n%s\n--- got\n%s",
+			name, path, want, got)
+	}
+}
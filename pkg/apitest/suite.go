@@ -0,0 +1,274 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// contractCase is a request of the suite whose response is compared with a golden file
+type contractCase struct {
+	name      string
+	operation string
+	query     url.Values
+}
+
+// readCases read the (empty) data of a freshly registered user, so their responses are the
+// same on every run
+var readCases = []contractCase{
+	{name: "hello", operation: "GET /api/v1/hello"},
+	{name: "bank_accounts", operation: "GET /api/v1/bank-accounts"},
+	{name: "bank_accounts_active", operation: "GET /api/v1/bank-accounts/active"},
+	{name: "bank_accounts_deleted", operation: "GET /api/v1/bank-accounts/deleted"},
+	{name: "expenses", operation: "GET /api/v1/expenses"},
+	{name: "expenses_page", operation: "GET /api/v1/expenses", query: url.Values{"limit": {"10"}}},
+	{name: "expenses_summary", operation: "GET /api/v1/expenses/summary", query: url.Values{"start_date": {"2024-01-01"}, "end_date": {"2024-01-31"}}},
+	{name: "incomes", operation: "GET /api/v1/incomes"},
+	{name: "fixed_expenses", operation: "GET /api/v1/fixed-expenses"},
+	{name: "goals", operation: "GET /api/v1/goals"},
+	{name: "reminders", operation: "GET /api/v1/reminders"},
+	{name: "reminders_stats", operation: "GET /api/v1/reminders/stats"},
+	{name: "user_categories", operation: "GET /api/v1/user-categories"},
+}
+
+// fixtures are records the write cases refer to, created without checking their responses
+type fixtures struct {
+	BankAccountID string
+	CategoryID    string
+}
+
+// lifecycleStep is a request on the record created by a lifecycle, compared with the golden
+// file <lifecycle>_<step>
+type lifecycleStep struct {
+	name      string
+	operation string
+	body      func(f fixtures) interface{}
+}
+
+// lifecycle creates a record of a resource, then runs its steps on it in order
+type lifecycle struct {
+	name   string
+	create string
+	body   func(f fixtures) interface{}
+	steps  []lifecycleStep
+}
+
+// staticBody returns a request body that doesn't depend on the fixtures
+func staticBody(value map[string]interface{}) func(f fixtures) interface{} {
+	return func(fixtures) interface{} { return value }
+}
+
+var bankAccountLifecycle = lifecycle{
+	name:   "bank_account",
+	create: "POST /api/v1/bank-accounts",
+	body:   staticBody(map[string]interface{}{"account_name": "Contract test account", "balance": 100.50}),
+	steps: []lifecycleStep{
+		{"get", "GET /api/v1/bank-accounts/{id}", nil},
+		{"patch", "PATCH /api/v1/bank-accounts/{id}", staticBody(map[string]interface{}{"account_name": "Renamed account", "version": 1})},
+		{"delete", "DELETE /api/v1/bank-accounts/{id}", nil},
+		{"restore", "POST /api/v1/bank-accounts/{id}/restore", nil},
+	},
+}
+
+// expenseBody is the expense of the expense lifecycle with the given description
+func expenseBody(description string) func(f fixtures) interface{} {
+	return func(f fixtures) interface{} {
+		return map[string]interface{}{
+			"category_id":     f.CategoryID,
+			"bank_account_id": f.BankAccountID,
+			"amount":          25.00,
+			"date":            "2024-01-15",
+			"description":     description,
+		}
+	}
+}
+
+// writeLifecycles go through the write endpoints of every resource. Dates far in the future
+// keep the fixed expense and the reminder away from the scheduled jobs
+var writeLifecycles = []lifecycle{
+	bankAccountLifecycle,
+	{
+		name:   "user_category",
+		create: "POST /api/v1/user-categories",
+		body:   staticBody(map[string]interface{}{"name": "Contract test category", "expense_type": "wants"}),
+		steps: []lifecycleStep{
+			{"update", "PUT /api/v1/user-categories/{id}", staticBody(map[string]interface{}{"name": "Renamed category"})},
+			{"delete", "DELETE /api/v1/user-categories/{id}", nil},
+			{"restore", "POST /api/v1/user-categories/{id}/restore", nil},
+		},
+	},
+	{
+		name:   "expense",
+		create: "POST /api/v1/expenses",
+		body:   expenseBody("Contract test expense"),
+		steps: []lifecycleStep{
+			{"patch", "PATCH /api/v1/expenses/{id}", expenseBody("Updated expense")},
+			{"delete", "DELETE /api/v1/expenses/{id}", nil},
+			{"restore", "POST /api/v1/expenses/{id}/restore", nil},
+		},
+	},
+	{
+		name:   "income",
+		create: "POST /api/v1/incomes",
+		body: staticBody(map[string]interface{}{
+			"amount": 2500.00, "date": "2024-01-31", "source": "salary", "description": "Contract test income",
+		}),
+		steps: []lifecycleStep{
+			{"patch", "PATCH /api/v1/incomes/{id}", staticBody(map[string]interface{}{"amount": 2600.00})},
+			{"delete", "DELETE /api/v1/incomes/{id}", nil},
+			{"restore", "POST /api/v1/incomes/{id}/restore", nil},
+		},
+	},
+	{
+		name:   "fixed_expense",
+		create: "POST /api/v1/fixed-expenses",
+		body: func(f fixtures) interface{} {
+			return map[string]interface{}{
+				"name": "Contract test rent", "amount": 1200.00, "due_date": "2099-01-15", "bank_account_id": f.BankAccountID,
+			}
+		},
+		steps: []lifecycleStep{
+			{"patch", "PATCH /api/v1/fixed-expenses/{id}", staticBody(map[string]interface{}{"amount": 1300.00})},
+			{"delete", "DELETE /api/v1/fixed-expenses/{id}", nil},
+		},
+	},
+	{
+		name:   "goal",
+		create: "POST /api/v1/goals",
+		body:   staticBody(map[string]interface{}{"name": "Contract test goal", "total_amount": 1000.00, "saved_amount": 250.00}),
+		steps: []lifecycleStep{
+			{"patch", "PATCH /api/v1/goals/{id}", staticBody(map[string]interface{}{"name": "Renamed goal", "saved_amount": 500.00})},
+			{"delete", "DELETE /api/v1/goals/{id}", nil},
+			{"restore", "POST /api/v1/goals/{id}/restore", nil},
+		},
+	},
+	{
+		name:   "reminder",
+		create: "POST /api/v1/reminders",
+		body: staticBody(map[string]interface{}{
+			"title": "Contract test reminder", "description": "Pay the contract test bill",
+			"due_date": "2099-01-15T00:00:00Z", "reminder_type": "bill",
+		}),
+		steps: []lifecycleStep{
+			{"patch", "PATCH /api/v1/reminders/{id}", staticBody(map[string]interface{}{"title": "Renamed reminder"})},
+			{"complete", "POST /api/v1/reminders/{id}/complete", nil},
+			{"delete", "DELETE /api/v1/reminders/{id}", nil},
+		},
+	},
+}
+
+// Run registers a new user on the API at baseURL and checks the responses of the suite
+// against the golden files: the reads of the empty account first, then the create, update
+// and delete flow of every resource.
+// It is skipped when baseURL is empty, so it can sit in a regular test run
+func Run(t *testing.T, baseURL string) {
+	if baseURL == "" {
+		t.Skip("no API URL given; set it to run the contract tests against a running instance")
+	}
+
+	client, err := NewClient(baseURL)
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	email := fmt.Sprintf("apitest+%d@example.com", time.Now().UnixNano())
+	if _, err := client.Register(email, "apitest-password-1", "API Test"); err != nil {
+		t.Fatalf("registering test user: %v", err)
+	}
+
+	t.Run("health", func(t *testing.T) {
+		resp, err := client.Do(http.MethodGet, "/health", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertGolden(t, "health", resp)
+	})
+
+	for _, c := range readCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			resp, err := client.Call(c.operation, nil, c.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertGolden(t, c.name, resp)
+		})
+	}
+
+	f := createFixtures(t, client)
+	for _, l := range writeLifecycles {
+		l := l
+		t.Run(l.name+"_lifecycle", func(t *testing.T) {
+			runLifecycle(t, client, l, f)
+		})
+	}
+}
+
+// BankAccountLifecycle creates a bank account, reads, renames and deletes it, checking each
+// response against its golden file
+func BankAccountLifecycle(t *testing.T, client *Client) {
+	t.Helper()
+	runLifecycle(t, client, bankAccountLifecycle, fixtures{})
+}
+
+// createFixtures creates the bank account and the category the write cases refer to
+func createFixtures(t *testing.T, client *Client) fixtures {
+	t.Helper()
+	return fixtures{
+		BankAccountID: createRecord(t, client, "POST /api/v1/bank-accounts", map[string]interface{}{
+			"account_name": "Contract fixtures account", "balance": 500.00,
+		}),
+		CategoryID: createRecord(t, client, "POST /api/v1/user-categories", map[string]interface{}{
+			"name": "Contract fixtures", "expense_type": "needs",
+		}),
+	}
+}
+
+// createRecord calls a create operation and returns the id of the new record
+func createRecord(t *testing.T, client *Client, operation string, body interface{}) string {
+	t.Helper()
+	resp, err := client.Call(operation, nil, nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := resp.Decode(&created); err != nil || created.ID == "" {
+		t.Fatalf("%s returned no id (status %d): %s", operation, resp.Status, resp.Body)
+	}
+	return created.ID
+}
+
+// runLifecycle creates the record of l and runs its steps on it, checking each response
+// against its golden file
+func runLifecycle(t *testing.T, client *Client, l lifecycle, f fixtures) {
+	t.Helper()
+
+	resp, err := client.Call(l.create, nil, nil, l.body(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertGolden(t, l.name+"_create", resp)
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := resp.Decode(&created); err != nil || created.ID == "" {
+		t.Fatalf("create %s returned no id: %s", l.name, resp.Body)
+	}
+	id := map[string]string{"id": created.ID}
+
+	for _, step := range l.steps {
+		var body interface{}
+		if step.body != nil {
+			body = step.body(f)
+		}
+		resp, err := client.Call(step.operation, id, nil, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertGolden(t, l.name+"_"+step.name, resp)
+	}
+}
@@ -0,0 +1,14 @@
+package apitest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Osminalx/fluxio/pkg/apitest"
+)
+
+// TestContract runs the bundled suite against the instance at FLUXIO_API_URL, comparing with
+// the golden files in testdata/golden. It is skipped when the variable isn't set
+func TestContract(t *testing.T) {
+	apitest.Run(t, os.Getenv("FLUXIO_API_URL"))
+}
@@ -1,160 +1,187 @@
+// Package logger writes structured logs through log/slog. LOG_LEVEL picks the lowest level
+// written (DEBUG, INFO, WARN or ERROR) and LOG_FORMAT=json switches from text to JSON lines.
+// Records logged with a context carry the request ID and user of the request that made them
 package logger
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
-type LogLevel int
+// LevelFatal is logged right before the process exits
+const LevelFatal = slog.Level(12)
 
-const (
-	DEBUG LogLevel = iota
-	INFO
-	WARN
-	ERROR
-	FATAL
-)
+type requestIDKey struct{}
 
-type Logger struct {
-	level LogLevel
-	prefix string
-	debugLog *log.Logger
-	infoLog *log.Logger
-	warnLog *log.Logger
-	errorLog *log.Logger
-	fatalLog *log.Logger
-}
-
-// New creates a new logger instance
-func New(level LogLevel, prefix string) *Logger {
-	flags := log.Ldate | log.Ltime | log.Lmicroseconds
-
-	return &Logger{
-		level:    level,
-		prefix:   prefix,
-		debugLog: log.New(os.Stdout, "🐛 DEBUG ", flags),
-		infoLog:  log.New(os.Stdout, "ℹ️  INFO  ", flags),
-		warnLog:  log.New(os.Stdout, "⚠️  WARN  ", flags),
-		errorLog: log.New(os.Stderr, "❌ ERROR ", flags),
-		fatalLog: log.New(os.Stderr, "💀 FATAL ", flags),
-	}
-}
+// userIDKey is the key the auth middleware stores the authenticated user under
+const userIDKey = "userID"
 
-// SetLevel sets the logging level
-func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+// Global is the logger behind the package functions
+var Global *slog.Logger
+
+// Initialize the global logger from the environment
+func init() {
+	Global = New(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	slog.SetDefault(Global)
 }
 
-// Debug logs a debug message
-func (l *Logger) Debug(format string, v ...interface{}) {
-	if l.level <= DEBUG {
-		l.debugLog.Printf(format, v...)
+// New creates a logger writing to stdout at the given level ("INFO" when empty or unknown),
+// as JSON when format is "json" and as key=value text otherwise
+func New(level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level: parseLevel(level),
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.LevelKey && len(groups) == 0 {
+				if lvl, ok := attr.Value.Any().(slog.Level); ok && lvl >= LevelFatal {
+					return slog.String(slog.LevelKey, "FATAL")
+				}
+			}
+			return attr
+		},
 	}
-}
 
-// Info logs an info message
-func (l *Logger) Info(format string, v ...interface{}) {
-	if l.level <= INFO {
-		l.infoLog.Printf(format, v...)
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(os.Stdout, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, options)
 	}
+	return slog.New(contextHandler{handler})
 }
 
-// Warn logs a warning message
-func (l *Logger) Warn(format string, v ...interface{}) {
-	if l.level <= WARN {
-		l.warnLog.Printf(format, v...)
+func parseLevel(level string) slog.Level {
+	switch strings.ToUpper(strings.TrimSpace(level)) {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN", "WARNING":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
 	}
 }
 
-// Error logs an error message
-func (l *Logger) Error(format string, v ...interface{}) {
-	if l.level <= ERROR {
-		l.errorLog.Printf(format, v...)
-	}
+// contextHandler adds the request ID and user of the context to every record
+type contextHandler struct {
+	slog.Handler
 }
 
-// Fatal logs a fatal message and exits
-func (l *Logger) Fatal(format string, v ...interface{}) {
-	if l.level <= FATAL {
-		l.fatalLog.Printf(format, v...)
-		os.Exit(1)
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
 	}
+	if ctx != nil {
+		if userID, ok := ctx.Value(userIDKey).(string); ok && userID != "" {
+			record.AddAttrs(slog.String("user_id", userID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
 }
 
-// HTTPRequest logs an HTTP request
-func (l *Logger) HTTPRequest(method, path, remoteAddr string, statusCode int, duration time.Duration, userAgent string) {
-	var emoji string
-	switch {
-	case statusCode >= 200 && statusCode < 300:
-		emoji = "✅"
-	case statusCode >= 300 && statusCode < 400:
-		emoji = "🔄"
-	case statusCode >= 400 && statusCode < 500:
-		emoji = "⚠️"
-	case statusCode >= 500:
-		emoji = "❌"
-	default:
-		emoji = "❓"
-	}
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
 
-	l.Info("%s %s %s | %d | %v | %s | %s", 
-		emoji, method, path, statusCode, duration, remoteAddr, userAgent)
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
 
-// Database logs database operations
-func (l *Logger) Database(operation, table string, duration time.Duration, err error) {
-	if err != nil {
-		l.Error("🗄️  DB %s on %s failed after %v: %v", operation, table, duration, err)
-	} else {
-		l.Debug("🗄️  DB %s on %s completed in %v", operation, table, duration)
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID of the context, or ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
 	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
 }
 
-// Auth logs authentication events
-func (l *Logger) Auth(event, user string, success bool, details ...interface{}) {
-	var emoji string
-	if success {
-		emoji = "��"
-		l.Info("%s AUTH %s for user %s", emoji, event, user)
-	} else {
-		emoji = "��"
-		l.Warn("%s AUTH %s failed for user %s: %v", emoji, event, user, details)
+// logf formats the message and logs it with the attributes of the context
+func logf(ctx context.Context, level slog.Level, format string, v ...interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !Global.Enabled(ctx, level) {
+		return
 	}
+	Global.Log(ctx, level, fmt.Sprintf(format, v...))
 }
 
-// Global logger instance
-var Global *Logger
+// Printf-style functions for logs without a request
+func Debug(format string, v ...interface{}) {
+	logf(context.Background(), slog.LevelDebug, format, v...)
+}
+func Info(format string, v ...interface{}) { logf(context.Background(), slog.LevelInfo, format, v...) }
+func Warn(format string, v ...interface{}) { logf(context.Background(), slog.LevelWarn, format, v...) }
+func Error(format string, v ...interface{}) {
+	logf(context.Background(), slog.LevelError, format, v...)
+}
 
-// Initialize the global logger
-func init(){
-	// Default to INFO level, can be overridden by environment variable
-	level := INFO
-	if os.Getenv("LOG_LEVEL")  == "DEBUG" {
-		level = DEBUG
-	}
-	
-	Global = New(level, "[FLUXIO]")
+// Fatal logs the message and exits
+func Fatal(format string, v ...interface{}) {
+	logf(context.Background(), LevelFatal, format, v...)
+	os.Exit(1)
 }
 
-// Convenience functions for global logger
-func Debug(format string, v ...interface{}) { Global.Debug(format, v...) }
-func Info(format string, v ...interface{})  { Global.Info(format, v...) }
-func Warn(format string, v ...interface{})  { Global.Warn(format, v...) }
-func Error(format string, v ...interface{}) { Global.Error(format, v...) }
-func Fatal(format string, v ...interface{}) { Global.Fatal(format, v...) }
+// Printf-style functions whose records carry the request ID and user of ctx
+func DebugContext(ctx context.Context, format string, v ...interface{}) {
+	logf(ctx, slog.LevelDebug, format, v...)
+}
+func InfoContext(ctx context.Context, format string, v ...interface{}) {
+	logf(ctx, slog.LevelInfo, format, v...)
+}
+func WarnContext(ctx context.Context, format string, v ...interface{}) {
+	logf(ctx, slog.LevelWarn, format, v...)
+}
+func ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	logf(ctx, slog.LevelError, format, v...)
+}
 
-// HTTPRequest logs an HTTP request using the global logger
-func HTTPRequest(method, path, remoteAddr string, statusCode int, duration time.Duration, userAgent string) {
-	Global.HTTPRequest(method, path, remoteAddr, statusCode, duration, userAgent)
+// HTTPRequest logs a served HTTP request; 5xx responses are logged as errors
+func HTTPRequest(ctx context.Context, method, path, remoteAddr string, statusCode int, duration time.Duration, userAgent string) {
+	level := slog.LevelInfo
+	if statusCode >= 500 {
+		level = slog.LevelError
+	}
+	Global.LogAttrs(ctx, level, "HTTP request",
+		slog.String("method", method),
+		slog.String("path", path),
+		slog.Int("status", statusCode),
+		slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+		slog.String("remote_addr", remoteAddr),
+		slog.String("user_agent", userAgent),
+	)
 }
 
-// Database logs database operations using the global logger
+// Database logs database operations
 func Database(operation, table string, duration time.Duration, err error) {
-	Global.Database(operation, table, duration, err)
+	attrs := []slog.Attr{
+		slog.String("operation", operation),
+		slog.String("table", table),
+		slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+	}
+	if err != nil {
+		Global.LogAttrs(context.Background(), slog.LevelError, "DB operation failed", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	Global.LogAttrs(context.Background(), slog.LevelDebug, "DB operation completed", attrs...)
 }
 
-// Auth logs authentication events using the global logger
+// Auth logs authentication events
 func Auth(event, user string, success bool, details ...interface{}) {
-	Global.Auth(event, user, success, details...)
-}
\ No newline at end of file
+	if success {
+		Global.LogAttrs(context.Background(), slog.LevelInfo, "AUTH "+event, slog.String("user", user))
+		return
+	}
+	Global.LogAttrs(context.Background(), slog.LevelWarn, "AUTH "+event+" failed",
+		slog.String("user", user), slog.String("details", fmt.Sprint(details...)))
+}